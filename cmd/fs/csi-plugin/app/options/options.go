@@ -40,6 +40,24 @@ func (csi *CSIPluginOption) AddFlagSet(fs *pflag.FlagSet) {
 	fs.StringVar(&csiPluginConf.NodeID, "node-id", csiPluginConf.NodeID, "node id")
 	fs.BoolVar(&csiPluginConf.PprofEnable, "pprof-enable", csiPluginConf.PprofEnable, "pprof debug tool")
 	fs.IntVar(&csiPluginConf.PprofPort, "pprof-port", csiPluginConf.PprofPort, "pprof port")
+	fs.BoolVar(&csiPluginConf.MountPod, "mount-pod", csiPluginConf.MountPod,
+		"Run pfs-fuse in a dedicated pod per fs on the node, so that mounts survive plugin restarts")
+	fs.StringVar(&csiPluginConf.MountPodImage, "mount-pod-image", csiPluginConf.MountPodImage,
+		"The image of the mount pods, which contains pfs-fuse")
+	fs.StringVar(&csiPluginConf.MountPodNamespace, "mount-pod-namespace", csiPluginConf.MountPodNamespace,
+		"The namespace of the mount pods")
+	fs.StringVar(&csiPluginConf.MountPodCPURequest, "mount-pod-cpu-request", csiPluginConf.MountPodCPURequest,
+		"The cpu request of the mount pods, e.g. 500m")
+	fs.StringVar(&csiPluginConf.MountPodCPULimit, "mount-pod-cpu-limit", csiPluginConf.MountPodCPULimit,
+		"The cpu limit of the mount pods")
+	fs.StringVar(&csiPluginConf.MountPodMemoryRequest, "mount-pod-memory-request", csiPluginConf.MountPodMemoryRequest,
+		"The memory request of the mount pods, e.g. 1Gi")
+	fs.StringVar(&csiPluginConf.MountPodMemoryLimit, "mount-pod-memory-limit", csiPluginConf.MountPodMemoryLimit,
+		"The memory limit of the mount pods")
+	fs.DurationVar(&csiPluginConf.MountPodReadyTimeout, "mount-pod-ready-timeout", csiPluginConf.MountPodReadyTimeout,
+		"The timeout waiting for the mount pod to be ready")
+	fs.StringVar(&csiPluginConf.MountPodSecret, "mount-pod-secret", csiPluginConf.MountPodSecret,
+		"The secret in the namespace of the mount pods, which holds FUSE_PASSWORD and PFS_PEER_CACHE_TOKEN passed to the mount pods")
 }
 
 func (csi *CSIPluginOption) InitFlag(fs *pflag.FlagSet) {
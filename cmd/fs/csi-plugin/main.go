@@ -69,13 +69,20 @@ func main() {
 		}()
 	}
 
-	stopChan := make(chan struct{})
-	defer close(stopChan)
-	controller := controller.GetMountPointController(config.CSIPluginConf.CSIPlugin.NodeID)
-	go controller.Start(stopChan)
-	defer controller.Stop()
-
 	d := csidriver.NewDriver(config.CSIPluginConf.CSIPlugin.NodeID, config.CSIPluginConf.CSIPlugin.UnixEndpoint)
+	if config.CSIPluginConf.CSIPlugin.MountPod {
+		// the mounts are kept by the mount pods, the plugin only binds them again after the mount pods restart
+		if err := d.EnableMountPod(config.CSIPluginConf.CSIPlugin); err != nil {
+			log.Errorf("enable mount pod failed: %v", err)
+			exitWithError()
+		}
+	} else {
+		stopChan := make(chan struct{})
+		defer close(stopChan)
+		controller := controller.GetMountPointController(config.CSIPluginConf.CSIPlugin.NodeID)
+		go controller.Start(stopChan)
+		defer controller.Stop()
+	}
 	d.Run()
 
 	exitNormal()
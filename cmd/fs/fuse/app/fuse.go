@@ -18,7 +18,10 @@ package app
 
 import (
 	"fmt"
+	"net"
+	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
@@ -34,8 +37,12 @@ import (
 	"paddleflow/pkg/common/http/api"
 	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/cache"
 	"paddleflow/pkg/fs/client/fuse"
+	"paddleflow/pkg/fs/client/meta"
+	"paddleflow/pkg/fs/client/ufs"
 	"paddleflow/pkg/fs/client/vfs"
+	"paddleflow/pkg/fs/utils/common"
 	mountUtil "paddleflow/pkg/fs/utils/mount"
 )
 
@@ -80,6 +87,7 @@ func Init() error {
 	opts.IgnoreSecurityLabels = fuseConf.IgnoreSecurityLabels
 	opts.DisableXAttrs = fuseConf.DisableXAttrs
 	opts.AllowOther = fuseConf.AllowOther
+	opts.EnableLocks = fuseConf.EnableLocks
 	if err := InitVFS(); err != nil {
 		log.Errorf("init vfs failed: %v", err)
 		return err
@@ -141,6 +149,7 @@ func InitVFS() error {
 	var fsMeta base.FSMeta
 	var links map[string]base.FSMeta
 	fuseConf := config.FuseConf.Fuse
+	ufs.HTTPAllowPrivateAddress = fuseConf.AllowPrivateAddress
 	if fuseConf.Local == true {
 		if fuseConf.LocalRoot == "" || fuseConf.LocalRoot == "/" {
 			log.Errorf("invalid localRoot: [%s]", fuseConf.LocalRoot)
@@ -197,6 +206,7 @@ func InitVFS() error {
 			log.Errorf("init client with fs[%s] and server[%s] failed: %v", fuseConf.FsID, fuseConf.Server, err)
 			return err
 		}
+		fuseClient.AccessUser = fuseConf.AccessUser
 		fsMeta, err = fuseClient.GetFSMeta()
 		if err != nil {
 			log.Errorf("get fs[%s] meta from pfs server[%s] failed: %v",
@@ -212,13 +222,53 @@ func InitVFS() error {
 		}
 	}
 
-	vfsConfig := vfs.InitConfig(
+	// bolt db is locked by the process, so each fs has its own db file
+	metaDB := fuseConf.FsID
+	if fuseConf.Local {
+		metaDB = "local"
+	}
+	vfsOptions := []vfs.Option{
 		vfs.WithMemorySize(config.FuseConf.Fuse.MemorySize),
 		vfs.WithMemoryExpire(config.FuseConf.Fuse.MemoryExpire),
 		vfs.WithBlockSize(config.FuseConf.Fuse.BlockSize),
 		vfs.WithDiskCachePath(config.FuseConf.Fuse.DiskCachePath),
 		vfs.WithDiskExpire(config.FuseConf.Fuse.DiskExpire),
-	)
+		vfs.WithDiskMaxSize(int64(config.FuseConf.Fuse.DiskCacheMaxSize) * 1024 * 1024),
+		vfs.WithDiskFreeRatio(config.FuseConf.Fuse.DiskCacheFreeRatio),
+		vfs.WithReadAhead(config.FuseConf.Fuse.ReadAheadWindow, config.FuseConf.Fuse.ReadAheadConcurrency),
+		vfs.WithServerLock(config.FuseConf.Fuse.ServerLock && !config.FuseConf.Fuse.Local),
+		vfs.WithMeta(&meta.Config{
+			Driver:   config.FuseConf.Fuse.MetaDriver,
+			BoltPath: path.Join(config.FuseConf.Fuse.MetaPath, metaDB+".db"),
+			RedisURL: config.FuseConf.Fuse.MetaRedisURL,
+			Expire:   config.FuseConf.Fuse.MetaCacheExpire,
+		}),
+		vfs.WithEntryCache(config.FuseConf.Fuse.EntryCacheExpire, config.FuseConf.Fuse.NegativeEntryCacheExpire),
+	}
+	if config.FuseConf.Fuse.WriteBack {
+		vfsOptions = append(vfsOptions, vfs.WithWriteBack(path.Join(config.FuseConf.Fuse.DiskCachePath, "writeback"),
+			int64(config.FuseConf.Fuse.WriteBackDirtyLimit)*1024*1024))
+	}
+	if len(config.FuseConf.Fuse.PeerCachePeers) > 0 || config.FuseConf.Fuse.PeerCacheService != "" {
+		self := config.FuseConf.Fuse.PeerCacheSelf
+		if self == "" {
+			self = net.JoinHostPort(os.Getenv("POD_IP"), strconv.Itoa(config.FuseConf.Fuse.PeerCachePort))
+		}
+		token := config.FuseConf.Fuse.PeerCacheToken
+		if token == "" {
+			token = os.Getenv(common.PeerCacheTokenEnv)
+		}
+		vfsOptions = append(vfsOptions, vfs.WithPeerCache(&cache.PeerConfig{
+			Listen:  ":" + strconv.Itoa(config.FuseConf.Fuse.PeerCachePort),
+			Self:    self,
+			Peers:   config.FuseConf.Fuse.PeerCachePeers,
+			Service: config.FuseConf.Fuse.PeerCacheService,
+			Port:    config.FuseConf.Fuse.PeerCachePort,
+			FsID:    fuseConf.FsID,
+			Token:   token,
+		}))
+	}
+	vfsConfig := vfs.InitConfig(vfsOptions...)
 
 	if _, err := vfs.InitVFS(fsMeta, links, true, vfsConfig); err != nil {
 		log.Errorf("init vfs failed: %v", err)
@@ -48,6 +48,8 @@ func (f *FuseOption) AddFlagSet(fs *pflag.FlagSet) {
 	fs.StringVar(&fuseConf.DepPath, "dep-path", fuseConf.DepPath, "The dependency path")
 	fs.StringVar(&fuseConf.UserName, "user-name", fuseConf.UserName, "User name")
 	fs.StringVar(&fuseConf.Password, "password", fuseConf.Password, "The fs server password for fsusername")
+	fs.StringVar(&fuseConf.AccessUser, "access-user", fuseConf.AccessUser,
+		"The user whose access to the fs shared by others is enforced by the mount, the user name if empty")
 	fs.IntVar(&fuseConf.AttrTimeout, "attr-timeout", fuseConf.AttrTimeout, "Attribute cache TTL")
 	fs.IntVar(&fuseConf.EntryTimeout, "entry-timeout", fuseConf.EntryTimeout, "Entry cache TTL")
 	fs.Uint32Var(&fuseConf.Uid, "uid", fuseConf.Uid, "The given UID to replace default uid")
@@ -60,14 +62,56 @@ func (f *FuseOption) AddFlagSet(fs *pflag.FlagSet) {
 	fs.BoolVar(&fuseConf.RawOwner, "raw-owner", fuseConf.RawOwner, "Show the same uid and gid to ufs")
 	fs.BoolVar(&fuseConf.PprofEnable, "pprof-enable", fuseConf.PprofEnable, "Enable go pprof")
 	fs.IntVar(&fuseConf.PprofPort, "pprof-port", fuseConf.PprofPort, "Pprof port")
-	fs.IntVar(&fuseConf.LinkUpdateInterval, "link-update-interval", fuseConf.LinkUpdateInterval, "The link update interval")
-	fs.StringVar(&fuseConf.LinkMetaDirPrefix, "link-meta-dir-prefix", fuseConf.LinkMetaDirPrefix, "The link meta dir prefix")
+	fs.IntVar(&fuseConf.LinkUpdateInterval, "link-update-interval", fuseConf.LinkUpdateInterval,
+		"The interval in seconds to retry watching the links on pfs server after failure")
+	var linkMetaDirPrefix string
+	fs.StringVar(&linkMetaDirPrefix, "link-meta-dir-prefix", "", "The link meta dir prefix")
+	_ = fs.MarkDeprecated("link-meta-dir-prefix",
+		"links are watched on pfs server instead of the link meta file, which is no longer written by the server")
 	fs.BoolVar(&fuseConf.SkipCheckLinks, "skip-check-links", fuseConf.SkipCheckLinks, "Skip check links")
+	fs.BoolVar(&fuseConf.EnableLocks, "enable-locks", fuseConf.EnableLocks,
+		"Handle the file locks in pfs-fuse for all the ufs types, otherwise the locks are local to the node kernel")
+	fs.BoolVar(&fuseConf.ServerLock, "server-lock", fuseConf.ServerLock,
+		"Coordinate the file locks by pfs server so that they hold across the mounts of the fs")
+	fs.BoolVar(&fuseConf.AllowPrivateAddress, "allow-private-address", fuseConf.AllowPrivateAddress,
+		"Allow the http and webdav fs on loopback, link-local and private addresses")
 	fs.DurationVar(&fuseConf.MemoryExpire, "mem-cache-expire", fuseConf.MemoryExpire, "The fuse memory data cache expire")
 	fs.IntVar(&fuseConf.MemorySize, "mem-size", fuseConf.MemorySize, "the number of cache item in mem cache")
 	fs.DurationVar(&fuseConf.DiskExpire, "disk-cache-expire", fuseConf.DiskExpire, "The fuse disk data cache expire")
 	fs.IntVar(&fuseConf.BlockSize, "block-size", fuseConf.BlockSize, "The fuse block size")
 	fs.StringVar(&fuseConf.DiskCachePath, "disk-cache-path", fuseConf.DiskCachePath, "The disk cache path")
+	fs.IntVar(&fuseConf.DiskCacheMaxSize, "disk-cache-max-size", fuseConf.DiskCacheMaxSize,
+		"The max size in MB of the disk cache, 0 means unlimited")
+	fs.Float64Var(&fuseConf.DiskCacheFreeRatio, "disk-cache-free-ratio", fuseConf.DiskCacheFreeRatio,
+		"The min free ratio of the cache disk, the cache is evicted by LRU below it")
+	fs.IntVar(&fuseConf.ReadAheadWindow, "read-ahead-window", fuseConf.ReadAheadWindow,
+		"The max number of blocks prefetched for sequential reads, 0 means disabled")
+	fs.IntVar(&fuseConf.ReadAheadConcurrency, "read-ahead-concurrency", fuseConf.ReadAheadConcurrency,
+		"The number of concurrent block prefetching for a file")
+	fs.BoolVar(&fuseConf.WriteBack, "write-back", fuseConf.WriteBack,
+		"Buffer the writes in cache and upload them asynchronously, the journal is kept in disk cache path")
+	fs.IntVar(&fuseConf.WriteBackDirtyLimit, "write-back-dirty-limit", fuseConf.WriteBackDirtyLimit,
+		"The max dirty data in MB waiting for upload in write back mode")
+	fs.StringSliceVar(&fuseConf.PeerCachePeers, "peer-cache-peers", fuseConf.PeerCachePeers,
+		"The nodes sharing the block cache in ip:port, including this node")
+	fs.StringVar(&fuseConf.PeerCacheService, "peer-cache-service", fuseConf.PeerCacheService,
+		"The k8s headless service of the nodes sharing the block cache")
+	fs.IntVar(&fuseConf.PeerCachePort, "peer-cache-port", fuseConf.PeerCachePort, "The port serving the block cache to peers")
+	fs.StringVar(&fuseConf.PeerCacheSelf, "peer-cache-self", fuseConf.PeerCacheSelf,
+		"The address of this node seen by peers, default $POD_IP:peer-cache-port")
+	fs.StringVar(&fuseConf.PeerCacheToken, "peer-cache-token", fuseConf.PeerCacheToken,
+		"The token shared by the nodes caching the fs, default $PFS_PEER_CACHE_TOKEN")
+	fs.StringVar(&fuseConf.MetaDriver, "meta-driver", fuseConf.MetaDriver,
+		"The meta engine, one of mem, bolt and redis, bolt and redis keep the inodes across remounts")
+	fs.StringVar(&fuseConf.MetaPath, "meta-path", fuseConf.MetaPath, "The dir of the bolt meta db")
+	fs.StringVar(&fuseConf.MetaRedisURL, "meta-redis-url", fuseConf.MetaRedisURL,
+		"The redis of the meta, e.g. redis://:password@host:6379/1")
+	fs.DurationVar(&fuseConf.MetaCacheExpire, "meta-cache-expire", fuseConf.MetaCacheExpire,
+		"The expire of the attributes and dir listings cached by bolt and redis meta, 0 means disabled")
+	fs.DurationVar(&fuseConf.EntryCacheExpire, "entry-cache-expire", fuseConf.EntryCacheExpire,
+		"The expire of the dir entries and attributes cached by vfs, 0 means disabled")
+	fs.DurationVar(&fuseConf.NegativeEntryCacheExpire, "negative-entry-cache-expire", fuseConf.NegativeEntryCacheExpire,
+		"The expire of the cached missing dir entries, 0 means disabled")
 }
 
 func (f *FuseOption) InitFlag(fs *pflag.FlagSet) {
@@ -0,0 +1,61 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package app
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"paddleflow/pkg/fs/client/fs"
+)
+
+const WarmupCommand = "warmup"
+
+// Warmup reads the files under a path of a mounted fs, so that the blocks are loaded
+// into the cache of the mount process, usage: pfs-fuse warmup --path=/mnt/data
+func Warmup(args []string) error {
+	flags := pflag.NewFlagSet(WarmupCommand, pflag.ContinueOnError)
+	path := flags.String("path", "", "The path under the mount point to warm up")
+	concurrency := flags.Int("concurrency", fs.DefaultWarmupConcurrency, "The number of files read concurrently")
+	interval := flags.Duration("progress-interval", 5*time.Second, "The interval of progress report")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("warmup path is empty")
+	}
+
+	start := time.Now()
+	last := start
+	var lock sync.Mutex
+	stats, err := fs.WarmupLocal(*path, *concurrency, func(s fs.WarmupStats) {
+		lock.Lock()
+		defer lock.Unlock()
+		if time.Since(last) < *interval {
+			return
+		}
+		last = time.Now()
+		fmt.Printf("warmup %s: files[%d] bytes[%d] failed[%d] elapsed[%v]\n",
+			*path, s.Files, s.Bytes, s.Failed, time.Since(start).Round(time.Second))
+	})
+	fmt.Printf("warmup %s finished: files[%d] bytes[%d] failed[%d] elapsed[%v]\n",
+		*path, stats.Files, stats.Bytes, stats.Failed, time.Since(start).Round(time.Second))
+	return err
+}
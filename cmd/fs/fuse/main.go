@@ -26,10 +26,18 @@ import (
 
 	"paddleflow/cmd/fs/fuse/app"
 	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/client/vfs"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == app.WarmupCommand {
+		if err := app.Warmup(os.Args[2:]); err != nil {
+			log.Errorf("warmup failed: %v", err)
+			os.Exit(-1)
+		}
+		return
+	}
 
 	if err := app.Init(); err != nil {
 		log.Errorf("init fuse failed: %v", err)
@@ -41,7 +49,7 @@ func main() {
 		defer close(stopChan)
 		fuseConf := config.FuseConf.Fuse
 		if !config.FuseConf.Fuse.SkipCheckLinks {
-			go vfs.GetVFS().Meta.LinksMetaUpdateHandler(stopChan, fuseConf.LinkUpdateInterval, fuseConf.LinkMetaDirPrefix)
+			go vfs.GetVFS().Meta.LinksMetaUpdateHandler(stopChan, fuseConf.LinkUpdateInterval, base.Client)
 		}
 	}
 
@@ -64,4 +72,5 @@ func main() {
 		os.Exit(-1)
 	}
 	server.Wait()
+	vfs.GetVFS().Close()
 }
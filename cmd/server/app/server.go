@@ -16,14 +16,17 @@ import (
 	vcclientset "volcano.sh/apis/pkg/client/clientset/versioned"
 
 	"paddleflow/cmd/server/app/options"
+	"paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/apiserver/controller/queue"
 	"paddleflow/pkg/apiserver/controller/run"
+	"paddleflow/pkg/apiserver/controller/webhook"
 	v1 "paddleflow/pkg/apiserver/router/v1"
 	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/common/database"
 	dbinit "paddleflow/pkg/common/database/init"
 	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/common/schema"
+	"paddleflow/pkg/fs/client/ufs"
 	"paddleflow/pkg/fs/utils/k8s"
 	"paddleflow/pkg/job/controller"
 	"paddleflow/pkg/job/submitter"
@@ -77,6 +80,7 @@ func (s *Server) Run() error {
 	stopCh := s.ServerCtx.Done()
 	go queue.GlobalVCQueue.Run(stopCh)
 	go controller.Run(s.kubeConf, stopCh)
+	go webhook.RunDeliveryDispatcher(stopCh)
 
 	if err := k8s.New(s.ServerConf.KubeConfig.ConfigPath, s.ServerConf.KubeConfig.ClientQPS,
 		s.ServerConf.KubeConfig.ClientBurst, s.ServerConf.KubeConfig.ClientTimeout); err != nil {
@@ -125,6 +129,13 @@ func (s *Server) Init() {
 
 	log.Infof("The final server config is: %s ", config.PrettyFormat(s.ServerConf))
 
+	// secrets can not be created until the key is set, but a malformed key is a misconfiguration
+	if err = common.CheckSecretKey(); errors.Is(err, common.ErrSecretKeyNotConfigured) {
+		log.Warningf("%s, creating secrets and webhooks with secret will be refused", err)
+	} else if err != nil {
+		panic(err)
+	}
+
 	dbConf := &s.ServerConf.Database
 
 	database.DB, err = dbinit.InitDatabase(&config.DatabaseConfig{
@@ -152,6 +163,7 @@ func (s *Server) Init() {
 	if err = config.InitDefaultPVC(s.ServerConf.Fs.DefaultPVCPath); err != nil {
 		panic(err)
 	}
+	ufs.HTTPAllowPrivateAddress = s.ServerConf.Fs.AllowPrivateAddress
 
 	s.VolcanoClient = vcclientset.NewForConfigOrDie(s.kubeConf)
 	queue.Init(s.VolcanoClient)
@@ -24,16 +24,20 @@ const (
 	PrefixCache    = "cch-"
 	PrefixGrant    = "grant"
 	PrefixCluster  = "cluster"
+	PrefixWebhook  = "hook"
+	PrefixDelivery = "dlv"
 
 	ResourceTypeRun           = "run"
 	ResourceTypeRunCache      = "run_cache"
 	ResourceTypeArtifactEvent = "artifact_event"
 	ResourceTypeUser          = "user"
+	ResourceTypeGroup         = "group"
 	ResourceTypeQueue         = "queue"
 	ResourceTypeFs            = "fs"
 	ResourceTypeImage         = "image"
 	ResourceTypePipeline      = "pipeline"
 	ResourceTypeCluster       = "cluster"
+	ResourceTypeWebhook       = "webhook"
 
 	HeaderKeyRequestID     = "x-pf-request-id"
 	HeaderKeyUserName      = "x-pf-user-name"
@@ -21,11 +21,16 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/md5"
+	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/common/config"
 )
 
 const (
@@ -54,6 +59,87 @@ func DecryptPk(data string) (int64, error) {
 	return pk, nil
 }
 
+// ErrSecretKeyNotConfigured is returned if the key to encrypt user secrets is not configured
+var ErrSecretKeyNotConfigured = errors.New("apiServer.secretKey is not configured")
+
+// getSecretKey returns the key used to encrypt user secrets. There is no default key, the public AESEncryptKey
+// is refused as well since anyone could decrypt the secrets with it.
+func getSecretKey() (string, error) {
+	var key string
+	if config.GlobalServerConfig != nil {
+		key = config.GlobalServerConfig.ApiServer.SecretKey
+	}
+	if key == "" || key == AESEncryptKey {
+		return "", ErrSecretKeyNotConfigured
+	}
+	switch len(key) {
+	case 16, 24, 32:
+		return key, nil
+	default:
+		return "", fmt.Errorf("the length of secret key must be 16, 24 or 32, but got %d", len(key))
+	}
+}
+
+// CheckSecretKey validates the configured secret key at server startup
+func CheckSecretKey() error {
+	_, err := getSecretKey()
+	return err
+}
+
+// EncryptSecret encrypts the user secrets with AES-GCM, the random nonce is prefixed to the cipher text.
+// AesEncrypt is kept only for the ak/sk of fs, which are stored before.
+func EncryptSecret(value string) (string, error) {
+	key, err := getSecretKey()
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("EncryptSecret value is null")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+func DecryptSecret(encrypted string) (string, error) {
+	key, err := getSecretKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := hex.DecodeString(encrypted)
+	if err != nil {
+		log.Errorf("DecryptSecret decode string error. error:[%s]", err.Error())
+		return "", err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("DecryptSecret failed as input data illegal")
+	}
+	nonce, cipherText := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	orig, err := gcm.Open(nil, nonce, cipherText, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(orig), nil
+}
+
+func newGCM(key string) (cipher.AEAD, error) {
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 func AesEncrypt(orig string, key string) (string, error) {
 	if orig == "" {
 		return "", fmt.Errorf("AesEncrypt orig is null")
@@ -0,0 +1,59 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/common/config"
+)
+
+func TestEncryptSecret(t *testing.T) {
+	config.GlobalServerConfig = &config.ServerConfig{}
+	_, err := EncryptSecret("token")
+	assert.Equal(t, ErrSecretKeyNotConfigured, err)
+
+	config.GlobalServerConfig.ApiServer.SecretKey = "secret-key-test1"
+	encrypted, err := EncryptSecret("token")
+	assert.NoError(t, err)
+	// the nonce is random, so the same value is encrypted differently
+	encryptedAgain, err := EncryptSecret("token")
+	assert.NoError(t, err)
+	assert.NotEqual(t, encrypted, encryptedAgain)
+
+	value, err := DecryptSecret(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "token", value)
+
+	// the cipher text is authenticated
+	tampered := []byte(encrypted)
+	if tampered[len(tampered)-1] == '0' {
+		tampered[len(tampered)-1] = '1'
+	} else {
+		tampered[len(tampered)-1] = '0'
+	}
+	_, err = DecryptSecret(string(tampered))
+	assert.Error(t, err)
+	_, err = DecryptSecret("00")
+	assert.Error(t, err)
+
+	config.GlobalServerConfig.ApiServer.SecretKey = "secret-key-test2"
+	_, err = DecryptSecret(encrypted)
+	assert.Error(t, err)
+}
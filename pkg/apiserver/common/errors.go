@@ -67,15 +67,25 @@ const (
 	GrantAlreadyExist         = "GrantAlreadyExist"
 	GrantRootActionNotSupport = "GrantRootActionNotSupport"
 
-	RunNameDuplicated     = "RunNameDuplicated"
-	RunNotFound           = "RunNotFound"
-	PipelineNotFound      = "PipelineNotFound"
-	RunCacheNotFound      = "RunCacheNotFound"
-	ArtifactEventNotFound = "ArtifactEventNotFound"
+	RunNameDuplicated       = "RunNameDuplicated"
+	RunNotFound             = "RunNotFound"
+	PipelineNotFound        = "PipelineNotFound"
+	PipelineVersionNotFound = "PipelineVersionNotFound"
+	PipelineVersionConflict = "PipelineVersionConflict"
+	InvalidPipeline         = "InvalidPipeline"
+	RunCacheNotFound        = "RunCacheNotFound"
+	ArtifactEventNotFound   = "ArtifactEventNotFound"
 
 	FlavourNotFound = "FlavourNotFound"
 
 	ClusterNameNotFound = "ClusterNameNotFound"
+
+	SecretNameDuplicated   = "SecretNameDuplicated"
+	SecretNotFound         = "SecretNotFound"
+	SecretKeyNotConfigured = "SecretKeyNotConfigured"
+
+	WebhookNotFound = "WebhookNotFound"
+	InvalidWebhook  = "InvalidWebhook"
 )
 
 var errorHTTPStatus = map[string]int{
@@ -117,11 +127,14 @@ var errorHTTPStatus = map[string]int{
 	QueueResourceNotMatch:     http.StatusBadRequest,
 	QueueIsNotClosed:          http.StatusBadRequest,
 
-	RunNameDuplicated:     http.StatusBadRequest,
-	RunNotFound:           http.StatusNotFound,
-	PipelineNotFound:      http.StatusBadRequest,
-	RunCacheNotFound:      http.StatusBadRequest,
-	ArtifactEventNotFound: http.StatusBadRequest,
+	RunNameDuplicated:       http.StatusBadRequest,
+	RunNotFound:             http.StatusNotFound,
+	PipelineNotFound:        http.StatusBadRequest,
+	PipelineVersionNotFound: http.StatusBadRequest,
+	PipelineVersionConflict: http.StatusConflict,
+	InvalidPipeline:         http.StatusBadRequest,
+	RunCacheNotFound:        http.StatusBadRequest,
+	ArtifactEventNotFound:   http.StatusBadRequest,
 
 	GrantResourceTypeNotFound: http.StatusBadRequest,
 	GrantNotFound:             http.StatusBadRequest,
@@ -131,6 +144,13 @@ var errorHTTPStatus = map[string]int{
 	FlavourNotFound: http.StatusBadRequest,
 
 	ClusterNameNotFound: http.StatusBadRequest,
+
+	SecretNameDuplicated:   http.StatusBadRequest,
+	SecretNotFound:         http.StatusNotFound,
+	SecretKeyNotConfigured: http.StatusServiceUnavailable,
+
+	WebhookNotFound: http.StatusNotFound,
+	InvalidWebhook:  http.StatusBadRequest,
 }
 
 var errorMessage = map[string]string{
@@ -170,11 +190,14 @@ var errorMessage = map[string]string{
 	QueueResourceNotMatch:     "Queue resource is not match",
 	QueueIsNotClosed:          "Queue should be closed before delete",
 
-	RunNameDuplicated:     "Run name already exists",
-	RunNotFound:           "RunID not found",
-	PipelineNotFound:      "Pipeline not found",
-	RunCacheNotFound:      "RunCache not found",
-	ArtifactEventNotFound: "ArtifactEvent not found",
+	RunNameDuplicated:       "Run name already exists",
+	RunNotFound:             "RunID not found",
+	PipelineNotFound:        "Pipeline not found",
+	PipelineVersionNotFound: "Pipeline version not found",
+	PipelineVersionConflict: "Pipeline has been updated concurrently, please retry",
+	InvalidPipeline:         "Pipeline is invalid",
+	RunCacheNotFound:        "RunCache not found",
+	ArtifactEventNotFound:   "ArtifactEvent not found",
 
 	GrantResourceTypeNotFound: "This kind of resource is not exist",
 	GrantNotFound:             "Grant not found. check the user and resource",
@@ -182,6 +205,13 @@ var errorMessage = map[string]string{
 	GrantRootActionNotSupport: "Can not delete or create root's grant",
 
 	ClusterNameNotFound: "ClusterName does not exist",
+
+	SecretNameDuplicated:   "The secret name already exists",
+	SecretNotFound:         "Secret not found",
+	SecretKeyNotConfigured: "The secret key of server is not configured, secrets can not be saved",
+
+	WebhookNotFound: "Webhook not found",
+	InvalidWebhook:  "Webhook config is invalid",
 }
 
 type ErrorResponse struct {
@@ -25,10 +25,12 @@ import (
 const (
 	RegPatternQueueName    = "^[a-z0-9][a-z0-9-]{0,8}[a-z0-9]$"
 	RegPatternUserName     = "^[A-Za-z0-9-]{4,16}$"
+	RegPatternGroupName    = "^[A-Za-z0-9-]{1,32}$"
 	RegPatternRunName      = "^[A-Za-z0-9_][A-Za-z0-9-_]{1,49}[A-Za-z0-9_]$"
 	RegPatternResource     = "^[1-9][0-9]*([numkMGTPE]|Ki|Mi|Gi|Ti|Pi|Ei)?$"
 	RegPatternPipelineName = "^[A-Za-z0-9_][A-Za-z0-9-_]{1,49}[A-Za-z0-9_]$"
 	RegPatternClusterName  = "^[A-Za-z0-9_][A-Za-z0-9-_]{0,253}[A-Za-z0-9_]$"
+	RegPatternSecretName   = "^[A-Za-z0-9._-]{1,63}$"
 )
 
 func IsRootUser(userName string) bool {
@@ -45,6 +45,12 @@ type CreatePipelineResponse struct {
 	Name string `json:"name"`
 }
 
+type GetPipelineResponse struct {
+	models.Pipeline
+	// parameter schema of each step, keyed by step name and parameter name
+	ParamSpecs map[string]map[string]pipeline.DictParam `json:"paramSpecs"`
+}
+
 type ListPipelineResponse struct {
 	common.MarkerInfo
 	PipelineList []models.Pipeline `json:"pipelineList"`
@@ -175,20 +181,30 @@ func validatePipeline(ctx *logger.RequestContext, name, md5, fsID string) error
 	}
 }
 
-func GetPipelineByID(ctx *logger.RequestContext, pipelineID string) (models.Pipeline, error) {
+func GetPipelineByID(ctx *logger.RequestContext, pipelineID string) (GetPipelineResponse, error) {
 	ppl, err := models.GetPipelineByID(pipelineID)
 	if err != nil {
 		ctx.ErrorCode = common.InternalError
 		ctx.Logging().Errorf("GetPipeline[%s]. err: %v", pipelineID, err)
-		return models.Pipeline{}, err
+		return GetPipelineResponse{}, err
 	}
 	if !common.IsRootUser(ctx.UserName) && ctx.UserName != ppl.UserName {
 		err := common.NoAccessError(ctx.UserName, common.ResourceTypePipeline, pipelineID)
 		ctx.ErrorCode = common.AccessDenied
 		ctx.Logging().Errorln(err.Error())
-		return models.Pipeline{}, err
+		return GetPipelineResponse{}, err
+	}
+	if err := ppl.Decode(); err != nil {
+		ctx.ErrorCode = common.InternalError
+		return GetPipelineResponse{}, err
+	}
+	wfs := schema.WorkflowSource{}
+	if err := yaml.Unmarshal([]byte(ppl.PipelineYaml), &wfs); err != nil {
+		ctx.ErrorCode = common.MalformedYaml
+		ctx.Logging().Errorf("Unmarshal yaml of pipeline[%s] failed. err:%v", pipelineID, err)
+		return GetPipelineResponse{}, err
 	}
-	return ppl, nil
+	return GetPipelineResponse{Pipeline: ppl, ParamSpecs: pipeline.GetParamSpecs(wfs)}, nil
 }
 
 func ListPipeline(ctx *logger.RequestContext, marker string, maxKeys int, userFilter, fsFilter, nameFilter []string) (ListPipelineResponse, error) {
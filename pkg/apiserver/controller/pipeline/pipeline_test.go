@@ -20,15 +20,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
+	"paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/apiserver/handler"
 	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/database"
 	"paddleflow/pkg/common/database/db_fake"
 	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/pipeline"
 )
 
 const (
@@ -118,4 +122,158 @@ func TestListPipeline(t *testing.T) {
 	b, _ := json.Marshal(resp)
 	println("")
 	fmt.Printf("%s\n", b)
-}
\ No newline at end of file
+}
+
+const (
+	mockPplYamlV1 = `name: myproject
+docker_env: images/training.tgz
+entry_points:
+  preprocess:
+    parameters:
+      data_path: "./data/"
+      lr: 0.1
+    command: "python data_preprocess.py --input {{data_path}}"
+  train:
+    deps: preprocess
+    parameters:
+      epoch: 5
+    command: "python train.py -e {{epoch}}"
+`
+	mockPplYamlV2 = `name: myproject
+docker_env: images/training.tgz
+entry_points:
+  preprocess:
+    parameters:
+      data_path: "./data/"
+      lr: 0.01
+      batch: 32
+    command: "python data_preprocess.py --input {{data_path}}"
+  evaluate:
+    deps: preprocess
+    command: "python evaluate.py"
+`
+)
+
+func TestUpdatePipelineAndDiff(t *testing.T) {
+	db_fake.InitFakeDB()
+	ctx := &logger.RequestContext{UserName: MockRootUser}
+
+	ValidateWorkflowForPipeline = func(ppl models.Pipeline) error { return nil }
+	pplYaml := mockPplYamlV1
+	handler.ReadFileFromFs = func(fsID, runYamlPath string, logEntry *log.Entry) ([]byte, error) {
+		return []byte(pplYaml), nil
+	}
+
+	resp, err := CreatePipeline(ctx, CreatePipelineRequest{FsName: MockFsName})
+	assert.Nil(t, err)
+
+	// update with identical yaml - expect fail
+	_, err = UpdatePipeline(ctx, resp.ID, UpdatePipelineRequest{})
+	assert.NotNil(t, err)
+
+	// the name in yaml must be the one of pipeline
+	pplYaml = strings.Replace(mockPplYamlV2, "name: myproject", "name: otherproject", 1)
+	ctx.ErrorCode = ""
+	_, err = UpdatePipeline(ctx, resp.ID, UpdatePipelineRequest{})
+	assert.NotNil(t, err)
+	assert.Equal(t, common.InvalidPipeline, ctx.ErrorCode)
+
+	pplYaml = mockPplYamlV2
+	updateResp, err := UpdatePipeline(ctx, resp.ID, UpdatePipelineRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, updateResp.Version)
+
+	ppl, err := GetPipelineByID(ctx, resp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, ppl.Version)
+	assert.Equal(t, mockPplYamlV2, ppl.PipelineYaml)
+	assert.Equal(t, pipeline.ParamTypeInt, ppl.ParamSpecs["preprocess"]["batch"].Type)
+
+	versions, err := ListPipelineVersion(ctx, resp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(versions.VersionList))
+	assert.Equal(t, mockPplYamlV1, versions.VersionList[0].PipelineYaml)
+
+	diff, err := DiffPipelineVersion(ctx, resp.ID, 1, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"evaluate"}, diff.StepsAdded)
+	assert.Equal(t, []string{"train"}, diff.StepsRemoved)
+	assert.Equal(t, 1, len(diff.StepsChanged))
+	assert.Equal(t, "preprocess", diff.StepsChanged[0].Name)
+	assert.Equal(t, 32, diff.StepsChanged[0].ParamsAdded["batch"])
+	assert.Equal(t, ValueChange{From: 0.1, To: 0.01}, diff.StepsChanged[0].ParamsChanged["lr"])
+	assert.Equal(t, 0, len(diff.FieldsChanged))
+
+	_, err = DiffPipelineVersion(ctx, resp.ID, 1, 3)
+	assert.NotNil(t, err)
+
+	// the pipeline updated by others after it is read is not overwritten
+	handler.ReadFileFromFs = func(fsID, runYamlPath string, logEntry *log.Entry) ([]byte, error) {
+		ppl, err := models.GetPipelineByID(resp.ID)
+		assert.Nil(t, err)
+		assert.Nil(t, models.UpdatePipelineYaml(logEntry, &ppl, mockPplYamlV1, common.GetMD5Hash([]byte(mockPplYamlV1)),
+			MockRootUser))
+		return []byte(strings.Replace(mockPplYamlV2, "lr: 0.01", "lr: 0.001", 1)), nil
+	}
+	ctx.ErrorCode = ""
+	_, err = UpdatePipeline(ctx, resp.ID, UpdatePipelineRequest{})
+	assert.NotNil(t, err)
+	assert.Equal(t, common.PipelineVersionConflict, ctx.ErrorCode)
+	ppl, err = GetPipelineByID(ctx, resp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, ppl.Version)
+	assert.Equal(t, mockPplYamlV1, ppl.PipelineYaml)
+}
+
+func TestDeletePipelineKeepsVersionsOfRuns(t *testing.T) {
+	db_fake.InitFakeDB()
+	ctx := &logger.RequestContext{UserName: MockRootUser}
+
+	ValidateWorkflowForPipeline = func(ppl models.Pipeline) error { return nil }
+	pplYaml := mockPplYamlV1
+	handler.ReadFileFromFs = func(fsID, runYamlPath string, logEntry *log.Entry) ([]byte, error) {
+		return []byte(pplYaml), nil
+	}
+	resp, err := CreatePipeline(ctx, CreatePipelineRequest{FsName: MockFsName})
+	assert.Nil(t, err)
+	pplYaml = mockPplYamlV2
+	_, err = UpdatePipeline(ctx, resp.ID, UpdatePipelineRequest{})
+	assert.Nil(t, err)
+
+	_, err = models.CreateRun(ctx.Logging(), &models.Run{Name: "run", Source: resp.ID, PplVersion: 1,
+		UserName: MockRootUser, FsID: "fs-root-mockFs", FsName: MockFsName})
+	assert.Nil(t, err)
+
+	assert.Nil(t, DeletePipeline(ctx, resp.ID))
+	pplVersion, err := models.GetPipelineVersion(resp.ID, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, mockPplYamlV1, pplVersion.PipelineYaml)
+	_, err = models.GetPipelineVersion(resp.ID, 2)
+	assert.NotNil(t, err)
+}
+
+func TestBackfillPipelineVersions(t *testing.T) {
+	db_fake.InitFakeDB()
+	ctx := &logger.RequestContext{UserName: MockRootUser}
+
+	ValidateWorkflowForPipeline = func(ppl models.Pipeline) error { return nil }
+	handler.ReadFileFromFs = func(fsID, runYamlPath string, logEntry *log.Entry) ([]byte, error) {
+		return []byte(mockPplYamlV1), nil
+	}
+	resp, err := CreatePipeline(ctx, CreatePipelineRequest{FsName: MockFsName, Name: "mockPplName"})
+	assert.Nil(t, err)
+	// the pipeline created before versioning has no version
+	assert.Nil(t, database.DB.Unscoped().Where("pipeline_id = ?", resp.ID).Delete(&models.PipelineVersion{}).Error)
+
+	count, err := models.BackfillPipelineVersions(database.DB)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	versions, err := ListPipelineVersion(ctx, resp.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(versions.VersionList))
+	assert.Equal(t, mockPplYamlV1, versions.VersionList[0].PipelineYaml)
+
+	count, err = models.BackfillPipelineVersions(database.DB)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
@@ -0,0 +1,286 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/handler"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/common/schema"
+	"paddleflow/pkg/pipeline"
+)
+
+type UpdatePipelineRequest struct {
+	YamlPath string `json:"yamlPath,omitempty"` // optional, use "./run.yaml" if not specified
+}
+
+type UpdatePipelineResponse struct {
+	ID      string `json:"pipelineID"`
+	Version int    `json:"version"`
+}
+
+type ListPipelineVersionResponse struct {
+	PipelineID  string                   `json:"pipelineID"`
+	VersionList []models.PipelineVersion `json:"versionList"`
+}
+
+// ValueChange records the old and new value of a changed field
+type ValueChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type StepDiff struct {
+	Name          string                 `json:"name"`
+	ParamsAdded   map[string]interface{} `json:"paramsAdded,omitempty"`
+	ParamsRemoved map[string]interface{} `json:"paramsRemoved,omitempty"`
+	ParamsChanged map[string]ValueChange `json:"paramsChanged,omitempty"`
+	FieldsChanged map[string]ValueChange `json:"fieldsChanged,omitempty"` // command, deps, env, artifacts, image
+}
+
+type PipelineDiffResponse struct {
+	PipelineID    string                 `json:"pipelineID"`
+	FromVersion   int                    `json:"fromVersion"`
+	ToVersion     int                    `json:"toVersion"`
+	FieldsChanged map[string]ValueChange `json:"fieldsChanged,omitempty"` // docker_env, cache, parallelism
+	StepsAdded    []string               `json:"stepsAdded"`
+	StepsRemoved  []string               `json:"stepsRemoved"`
+	StepsChanged  []StepDiff             `json:"stepsChanged"`
+}
+
+// UpdatePipeline reads pipeline yaml from fs again and records it as a new version of pipeline
+func UpdatePipeline(ctx *logger.RequestContext, pipelineID string, request UpdatePipelineRequest) (UpdatePipelineResponse, error) {
+	ppl, err := getPipelineWithAccess(ctx, pipelineID)
+	if err != nil {
+		return UpdatePipelineResponse{}, err
+	}
+	if request.YamlPath == "" {
+		request.YamlPath = "./run.yaml"
+	}
+	pipelineYaml, err := handler.ReadFileFromFs(ppl.FsID, request.YamlPath, ctx.Logging())
+	if err != nil {
+		ctx.ErrorCode = common.IOOperationFailure
+		ctx.Logging().Errorf("readFileFromFs[%s] from fs[%s] failed. err:%v", request.YamlPath, ppl.FsID, err)
+		return UpdatePipelineResponse{}, err
+	}
+	yamlMd5 := common.GetMD5Hash(pipelineYaml)
+	if yamlMd5 == ppl.PipelineMd5 {
+		ctx.ErrorCode = common.DuplicatedContent
+		err := fmt.Errorf("pipeline[%s] yaml is identical to current version[%d]. No need to update", ppl.ID, ppl.Version)
+		ctx.Logging().Errorln(err.Error())
+		return UpdatePipelineResponse{}, err
+	}
+	if err := validatePipeline(ctx, "", yamlMd5, ppl.FsID); err != nil {
+		ctx.Logging().Errorf("validate pipeline failed. err:%v", err)
+		return UpdatePipelineResponse{}, err
+	}
+	// pipeline name is kept unchanged among versions
+	wfs := schema.WorkflowSource{}
+	if err := yaml.Unmarshal(pipelineYaml, &wfs); err != nil {
+		ctx.ErrorCode = common.MalformedYaml
+		ctx.Logging().Errorf("Unmarshal yaml of pipeline[%s] failed. err:%v", ppl.ID, err)
+		return UpdatePipelineResponse{}, err
+	}
+	if wfs.Name != "" && wfs.Name != ppl.Name {
+		ctx.ErrorCode = common.InvalidPipeline
+		err := fmt.Errorf("name[%s] in yaml differs from pipeline[%s] name[%s]", wfs.Name, ppl.ID, ppl.Name)
+		ctx.Logging().Errorln(err.Error())
+		return UpdatePipelineResponse{}, err
+	}
+	newPpl := ppl
+	newPpl.PipelineYaml = string(pipelineYaml)
+	if err := ValidateWorkflowForPipeline(newPpl); err != nil {
+		ctx.ErrorCode = common.MalformedYaml
+		ctx.Logging().Errorf("validateWorkflowForPipeline failed. err:%v", err)
+		return UpdatePipelineResponse{}, err
+	}
+	if err := models.UpdatePipelineYaml(ctx.Logging(), &ppl, string(pipelineYaml), yamlMd5, ctx.UserName); err != nil {
+		ctx.ErrorCode = common.InternalError
+		if errors.Is(err, models.ErrPipelineVersionConflict) {
+			ctx.ErrorCode = common.PipelineVersionConflict
+		}
+		ctx.Logging().Errorf("update pipeline[%s] failed. error:%v", ppl.ID, err)
+		return UpdatePipelineResponse{}, err
+	}
+	ctx.Logging().Debugf("update pipeline[%s] to version[%d] successful", ppl.ID, ppl.Version)
+	return UpdatePipelineResponse{ID: ppl.ID, Version: ppl.Version}, nil
+}
+
+func ListPipelineVersion(ctx *logger.RequestContext, pipelineID string) (ListPipelineVersionResponse, error) {
+	if _, err := getPipelineWithAccess(ctx, pipelineID); err != nil {
+		return ListPipelineVersionResponse{}, err
+	}
+	versionList, err := models.ListPipelineVersion(ctx.Logging(), pipelineID)
+	if err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("list versions of pipeline[%s] failed. err:%v", pipelineID, err)
+		return ListPipelineVersionResponse{}, err
+	}
+	return ListPipelineVersionResponse{PipelineID: pipelineID, VersionList: versionList}, nil
+}
+
+// DiffPipelineVersion compares two versions of a pipeline structurally
+func DiffPipelineVersion(ctx *logger.RequestContext, pipelineID string, fromVersion, toVersion int) (PipelineDiffResponse, error) {
+	if _, err := getPipelineWithAccess(ctx, pipelineID); err != nil {
+		return PipelineDiffResponse{}, err
+	}
+	fromWfs, err := getPipelineVersionWfs(ctx, pipelineID, fromVersion)
+	if err != nil {
+		return PipelineDiffResponse{}, err
+	}
+	toWfs, err := getPipelineVersionWfs(ctx, pipelineID, toVersion)
+	if err != nil {
+		return PipelineDiffResponse{}, err
+	}
+	diff := DiffWorkflowSource(fromWfs, toWfs)
+	diff.PipelineID = pipelineID
+	diff.FromVersion = fromVersion
+	diff.ToVersion = toVersion
+	return diff, nil
+}
+
+func getPipelineWithAccess(ctx *logger.RequestContext, pipelineID string) (models.Pipeline, error) {
+	ppl, err := models.GetPipelineByID(pipelineID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.ErrorCode = common.PipelineNotFound
+		} else {
+			ctx.ErrorCode = common.InternalError
+		}
+		ctx.Logging().Errorf("get pipeline[%s] failed. err:%v", pipelineID, err)
+		return models.Pipeline{}, err
+	}
+	if !common.IsRootUser(ctx.UserName) && ctx.UserName != ppl.UserName {
+		err := common.NoAccessError(ctx.UserName, common.ResourceTypePipeline, pipelineID)
+		ctx.ErrorCode = common.AccessDenied
+		ctx.Logging().Errorln(err.Error())
+		return models.Pipeline{}, err
+	}
+	return ppl, nil
+}
+
+func getPipelineVersionWfs(ctx *logger.RequestContext, pipelineID string, version int) (schema.WorkflowSource, error) {
+	pplVersion, err := models.GetPipelineVersion(pipelineID, version)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.ErrorCode = common.PipelineVersionNotFound
+		} else {
+			ctx.ErrorCode = common.InternalError
+		}
+		ctx.Logging().Errorf("get pipeline[%s] version[%d] failed. err:%v", pipelineID, version, err)
+		return schema.WorkflowSource{}, err
+	}
+	wfs := schema.WorkflowSource{}
+	if err := yaml.Unmarshal([]byte(pplVersion.PipelineYaml), &wfs); err != nil {
+		ctx.ErrorCode = common.MalformedYaml
+		ctx.Logging().Errorf("Unmarshal yaml of pipeline[%s] version[%d] failed. err:%v", pipelineID, version, err)
+		return schema.WorkflowSource{}, err
+	}
+	return wfs, nil
+}
+
+// DiffWorkflowSource returns steps added or removed, and params or fields changed from one workflow source to another
+func DiffWorkflowSource(from, to schema.WorkflowSource) PipelineDiffResponse {
+	diff := PipelineDiffResponse{
+		FieldsChanged: map[string]ValueChange{},
+		StepsAdded:    []string{},
+		StepsRemoved:  []string{},
+		StepsChanged:  []StepDiff{},
+	}
+	compareField(diff.FieldsChanged, "docker_env", from.DockerEnv, to.DockerEnv)
+	compareField(diff.FieldsChanged, "cache", from.Cache, to.Cache)
+	compareField(diff.FieldsChanged, "parallelism", from.Parallelism, to.Parallelism)
+
+	for _, name := range sortedStepNames(to.EntryPoints) {
+		if _, ok := from.EntryPoints[name]; !ok {
+			diff.StepsAdded = append(diff.StepsAdded, name)
+		}
+	}
+	for _, name := range sortedStepNames(from.EntryPoints) {
+		toStep, ok := to.EntryPoints[name]
+		if !ok {
+			diff.StepsRemoved = append(diff.StepsRemoved, name)
+			continue
+		}
+		if stepDiff, changed := diffStep(name, from.EntryPoints[name], toStep); changed {
+			diff.StepsChanged = append(diff.StepsChanged, stepDiff)
+		}
+	}
+	return diff
+}
+
+func diffStep(name string, from, to *schema.WorkflowSourceStep) (StepDiff, bool) {
+	if from == nil {
+		from = &schema.WorkflowSourceStep{}
+	}
+	if to == nil {
+		to = &schema.WorkflowSourceStep{}
+	}
+	stepDiff := StepDiff{
+		Name:          name,
+		ParamsAdded:   map[string]interface{}{},
+		ParamsRemoved: map[string]interface{}{},
+		ParamsChanged: map[string]ValueChange{},
+		FieldsChanged: map[string]ValueChange{},
+	}
+	for paramName, toVal := range to.Parameters {
+		fromVal, ok := from.Parameters[paramName]
+		if !ok {
+			stepDiff.ParamsAdded[paramName] = pipeline.NormalizeParamValue(toVal)
+			continue
+		}
+		compareField(stepDiff.ParamsChanged, paramName, fromVal, toVal)
+	}
+	for paramName, fromVal := range from.Parameters {
+		if _, ok := to.Parameters[paramName]; !ok {
+			stepDiff.ParamsRemoved[paramName] = pipeline.NormalizeParamValue(fromVal)
+		}
+	}
+	compareField(stepDiff.FieldsChanged, "command", from.Command, to.Command)
+	compareField(stepDiff.FieldsChanged, "deps", from.GetDeps(), to.GetDeps())
+	compareField(stepDiff.FieldsChanged, "env", from.Env, to.Env)
+	compareField(stepDiff.FieldsChanged, "artifacts", from.Artifacts, to.Artifacts)
+	compareField(stepDiff.FieldsChanged, "image", from.Image, to.Image)
+
+	changed := len(stepDiff.ParamsAdded) > 0 || len(stepDiff.ParamsRemoved) > 0 ||
+		len(stepDiff.ParamsChanged) > 0 || len(stepDiff.FieldsChanged) > 0
+	return stepDiff, changed
+}
+
+func compareField(changes map[string]ValueChange, key string, from, to interface{}) {
+	if !reflect.DeepEqual(from, to) {
+		changes[key] = ValueChange{From: pipeline.NormalizeParamValue(from), To: pipeline.NormalizeParamValue(to)}
+	}
+}
+
+func sortedStepNames(steps map[string]*schema.WorkflowSourceStep) []string {
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -19,11 +19,13 @@ package run
 import (
 	"database/sql"
 	"encoding/json"
+	"sort"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
 	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/controller/webhook"
 	"paddleflow/pkg/apiserver/handler"
 	"paddleflow/pkg/apiserver/models"
 	"paddleflow/pkg/common/database"
@@ -37,6 +39,7 @@ var workflowCallbacks = pipeline.WorkflowCallbacks{
 	LogCacheCb:    LogCacheFunc,
 	ListCacheCb:   ListCacheFunc,
 	LogArtifactCb: LogArtifactFunc,
+	LogEventCb:    LogEventFunc,
 }
 
 var (
@@ -44,6 +47,7 @@ var (
 	LogCacheFunc    func(req schema.LogRunCacheRequest) (string, error)                 = LogCache
 	ListCacheFunc   func(firstFp, fsID, step, source string) ([]models.RunCache, error) = ListCacheByFirstFp
 	LogArtifactFunc func(req schema.LogRunArtifactRequest) error                        = LogArtifactEvent
+	LogEventFunc    func(runID string, event *pipeline.WorkflowEvent)                   = LogRunEvent
 )
 
 func UpdateRunByWfEvent(id string, event interface{}) bool {
@@ -104,9 +108,40 @@ func UpdateRunByWfEvent(id string, event interface{}) bool {
 		logging.Errorf("update run[%s] in db failed. error: %v", id, err)
 		return false
 	}
+	webhook.NotifyRunEvents(logging, prevRun, status, getRunEvents(prevRun, status, wfEvent.Message, runtime))
 	return true
 }
 
+// getRunEvents returns events of the run and steps whose status changed, compared with run stored in db
+func getRunEvents(prevRun models.Run, status, message string, runtime schema.RuntimeView) []webhook.Event {
+	events := make([]webhook.Event, 0)
+	if status != prevRun.Status {
+		events = append(events, webhook.Event{Name: webhook.RunEventName(status), Message: message})
+		if common.IsRunFinalStatus(status) {
+			events = append(events, webhook.Event{Name: webhook.EventRunFinished, Message: message})
+		}
+	}
+	stepNames := make([]string, 0, len(runtime))
+	for stepName := range runtime {
+		stepNames = append(stepNames, stepName)
+	}
+	sort.Strings(stepNames)
+	for _, stepName := range stepNames {
+		job := runtime[stepName]
+		if prevJob, ok := prevRun.Runtime[stepName]; job.Status == "" || (ok && prevJob.Status == job.Status) {
+			continue
+		}
+		events = append(events, webhook.Event{
+			Name:       webhook.StepEventName(job.Status),
+			StepName:   stepName,
+			JobID:      job.JobID,
+			StepStatus: string(job.Status),
+			Message:    job.JobMessage,
+		})
+	}
+	return events
+}
+
 func handleImageCallbackFunc(imageInfo handler.ImageInfo, err error) error {
 	runID := imageInfo.RunID
 	logEntry := logger.LoggerForRun(runID)
@@ -0,0 +1,196 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package run
+
+import (
+	"sort"
+	"sync"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/pipeline"
+)
+
+const (
+	// eventBufferSize is the buffer of subscriber channel, slow subscribers whose buffer is full are closed,
+	// so that they reconnect with last event id and replay the missed events, instead of missing them silently
+	eventBufferSize = 100
+	// eventBacklogSize is the number of latest events of each running run kept in memory for replay
+	eventBacklogSize = 1000
+)
+
+// runBacklog keeps the latest events of run in order, which are all events of run with event id after since
+type runBacklog struct {
+	since  int64
+	events []models.RunEvent
+}
+
+func (b *runBacklog) add(event models.RunEvent) {
+	i := sort.Search(len(b.events), func(i int) bool { return b.events[i].Pk > event.Pk })
+	b.events = append(b.events, models.RunEvent{})
+	copy(b.events[i+1:], b.events[i:])
+	b.events[i] = event
+	if n := len(b.events) - eventBacklogSize; n > 0 {
+		b.since = b.events[n-1].Pk
+		b.events = b.events[n:]
+	}
+}
+
+// after returns events after lastEventID, and false if some of them have been evicted from backlog
+func (b *runBacklog) after(lastEventID int64) ([]models.RunEvent, bool) {
+	if lastEventID < b.since {
+		return nil, false
+	}
+	i := sort.Search(len(b.events), func(i int) bool { return b.events[i].Pk > lastEventID })
+	return append([]models.RunEvent{}, b.events[i:]...), true
+}
+
+// eventHub dispatches run events to subscribers of the run
+type eventHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan models.RunEvent]struct{}
+	backlogs    map[string]*runBacklog
+}
+
+var runEventHub = newEventHub()
+
+func newEventHub() *eventHub {
+	return &eventHub{
+		subscribers: make(map[string]map[chan models.RunEvent]struct{}),
+		backlogs:    make(map[string]*runBacklog),
+	}
+}
+
+// subscribe returns the channel of new events, and events after lastEventID if they are all in backlog
+func (h *eventHub) subscribe(runID string, lastEventID int64) (chan models.RunEvent, []models.RunEvent, bool) {
+	ch := make(chan models.RunEvent, eventBufferSize)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[runID]; !ok {
+		h.subscribers[runID] = make(map[chan models.RunEvent]struct{})
+	}
+	h.subscribers[runID][ch] = struct{}{}
+	if backlog, ok := h.backlogs[runID]; ok {
+		history, ok := backlog.after(lastEventID)
+		return ch, history, ok
+	}
+	return ch, nil, false
+}
+
+func (h *eventHub) unsubscribe(runID string, ch chan models.RunEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if subs, ok := h.subscribers[runID]; ok {
+		delete(subs, ch)
+		if len(subs) == 0 {
+			delete(h.subscribers, runID)
+		}
+	}
+}
+
+func (h *eventHub) publish(event models.RunEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if IsRunFinishedEvent(event) {
+		// no more events of run, replay from event log afterwards
+		delete(h.backlogs, event.RunID)
+	} else {
+		backlog, ok := h.backlogs[event.RunID]
+		if !ok {
+			// events of run before this one are not known, they are replayed from event log
+			backlog = &runBacklog{since: event.Pk - 1}
+			h.backlogs[event.RunID] = backlog
+		}
+		backlog.add(event)
+	}
+	subs := h.subscribers[event.RunID]
+	for ch := range subs {
+		select {
+		case ch <- event:
+		default:
+			logger.LoggerForRun(event.RunID).Warnf("subscriber of run is too slow, close it at event[%d]", event.Pk)
+			close(ch)
+			delete(subs, ch)
+		}
+	}
+	if len(subs) == 0 {
+		delete(h.subscribers, event.RunID)
+	}
+}
+
+// LogRunEvent persists workflow event to the event log of run, and publishes it to subscribers
+func LogRunEvent(runID string, wfEvent *pipeline.WorkflowEvent) {
+	logging := logger.LoggerForRun(runID)
+	event := models.RunEvent{
+		RunID:   runID,
+		Type:    string(wfEvent.Type),
+		Event:   string(wfEvent.Event),
+		Message: wfEvent.Message,
+		Extra:   wfEvent.Extra,
+	}
+	if err := models.CreateRunEvent(logging, &event); err != nil {
+		return
+	}
+	runEventHub.publish(event)
+}
+
+// RunEventStream is the events of run for streaming, History contains events after last event id in event log,
+// and Events receives new events. Events is closed if the subscriber falls behind, and the client should reconnect
+// with last event id to replay the missed events. Close must be called when stream is finished.
+type RunEventStream struct {
+	Run     models.Run
+	History []models.RunEvent
+	Events  <-chan models.RunEvent
+	Close   func()
+}
+
+func SubscribeRunEvents(ctx *logger.RequestContext, runID string, lastEventID int64) (*RunEventStream, error) {
+	ctx.Logging().Debugf("begin subscribe events of run[%s] after event[%d]", runID, lastEventID)
+	run, err := GetRunByID(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+	// subscribe before reading event log, so that no event is missed between them
+	ch, history, ok := runEventHub.subscribe(runID, lastEventID)
+	if !ok {
+		history, err = models.ListRunEvent(ctx.Logging(), runID, lastEventID)
+		if err != nil {
+			runEventHub.unsubscribe(runID, ch)
+			ctx.ErrorCode = common.InternalError
+			return nil, err
+		}
+	}
+	stream := &RunEventStream{
+		Run:     run,
+		History: history,
+		Events:  ch,
+		Close: func() {
+			runEventHub.unsubscribe(runID, ch)
+		},
+	}
+	return stream, nil
+}
+
+// IsRunFinishedEvent reports whether event is the run update to final status, after which no event will be sent
+func IsRunFinishedEvent(event models.RunEvent) bool {
+	if event.Event != string(pipeline.WfEventRunUpdate) {
+		return false
+	}
+	status, _ := event.Extra[common.WfEventKeyStatus].(string)
+	return common.IsRunFinalStatus(status)
+}
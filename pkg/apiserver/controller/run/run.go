@@ -51,6 +51,8 @@ type CreateRunRequest struct {
 	RunYamlRaw  string `json:"runYamlRaw,omitempty"`  // optional. one of 3 sources of run. high priority
 	PipelineID  string `json:"pipelineID,omitempty"`  // optional. one of 3 sources of run. medium priority
 	RunYamlPath string `json:"runYamlPath,omitempty"` // optional. one of 3 sources of run. low priority
+	// optional. version of pipeline to run, latest version if not specified
+	PipelineVersion int `json:"pipelineVersion,omitempty"`
 }
 
 type CreateRunResponse struct {
@@ -61,6 +63,7 @@ type RunBrief struct {
 	ID           string `json:"runID"`
 	Name         string `json:"name"`
 	Source       string `json:"source"` // pipelineID or yamlPath
+	PplVersion   int    `json:"pipelineVersion,omitempty"`
 	UserName     string `json:"username"`
 	FsName       string `json:"fsname"`
 	Message      string `json:"runMsg"`
@@ -78,6 +81,7 @@ func (b *RunBrief) modelToListResp(run models.Run) {
 	b.ID = run.ID
 	b.Name = run.Name
 	b.Source = run.Source
+	b.PplVersion = run.PplVersion
 	b.UserName = run.UserName
 	b.FsName = run.FsName
 	b.Message = run.Message
@@ -86,8 +90,9 @@ func (b *RunBrief) modelToListResp(run models.Run) {
 	b.ActivateTime = run.ActivateTime
 }
 
-func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID string) (schema.WorkflowSource, string, string, error) {
+func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID string) (schema.WorkflowSource, string, string, int, error) {
 	var source, runYaml string
+	var pplVersion int
 	// retrieve source and runYaml
 	if req.RunYamlRaw != "" { // high priority: wfs delivered by request
 		// base64 decode
@@ -95,7 +100,7 @@ func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID
 		sDec, err := base64.StdEncoding.DecodeString(req.RunYamlRaw)
 		if err != nil {
 			ctx.Logging().Errorf("Decode raw runyaml is [%s] failed. err:%v", req.RunYamlRaw, err)
-			return schema.WorkflowSource{}, "", "", err
+			return schema.WorkflowSource{}, "", "", 0, err
 		}
 		runYaml = string(sDec)
 		source = common.GetMD5Hash(sDec)
@@ -103,15 +108,28 @@ func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID
 		ppl, err := models.GetPipelineByID(req.PipelineID)
 		if err != nil {
 			ctx.Logging().Errorf("GetPipelineByID[%s] failed. err:%v", req.PipelineID, err)
-			return schema.WorkflowSource{}, "", "", err
+			return schema.WorkflowSource{}, "", "", 0, err
 		}
 		if !common.IsRootUser(ctx.UserName) && ppl.UserName != ctx.UserName {
 			ctx.ErrorCode = common.AccessDenied
 			err := common.NoAccessError(ctx.UserName, common.ResourceTypePipeline, ppl.ID)
 			ctx.Logging().Errorf("buildWorkflowSource[%s] failed. err:%v", req.PipelineID, err)
-			return schema.WorkflowSource{}, "", "", err
+			return schema.WorkflowSource{}, "", "", 0, err
+		}
+		runYaml, pplVersion = ppl.PipelineYaml, ppl.Version
+		if req.PipelineVersion != 0 && req.PipelineVersion != ppl.Version {
+			pv, err := models.GetPipelineVersion(ppl.ID, req.PipelineVersion)
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					ctx.ErrorCode = common.PipelineVersionNotFound
+				} else {
+					ctx.ErrorCode = common.InternalError
+				}
+				ctx.Logging().Errorf("GetPipelineVersion[%s-%d] failed. err:%v", ppl.ID, req.PipelineVersion, err)
+				return schema.WorkflowSource{}, "", "", 0, err
+			}
+			runYaml, pplVersion = pv.PipelineYaml, pv.Version
 		}
-		runYaml = ppl.PipelineYaml
 		source = ppl.ID
 	} else { // low priority: wfs in fs, read from runYamlPath
 		runYamlPath := req.RunYamlPath
@@ -122,7 +140,7 @@ func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID
 		if err != nil {
 			ctx.ErrorCode = common.IOOperationFailure
 			ctx.Logging().Errorf("readFileFromFs from[%s] failed. err:%v", fsID, err)
-			return schema.WorkflowSource{}, "", "", err
+			return schema.WorkflowSource{}, "", "", 0, err
 		}
 		source = runYamlPath
 		runYaml = string(runYamlByte)
@@ -131,9 +149,9 @@ func buildWorkflowSource(ctx *logger.RequestContext, req CreateRunRequest, fsID
 	wfs, err := runYamlAndReqToWfs(ctx, runYaml, req)
 	if err != nil {
 		ctx.Logging().Errorf("runYamlAndReqToWfs failed. err:%v", err)
-		return schema.WorkflowSource{}, "", "", err
+		return schema.WorkflowSource{}, "", "", 0, err
 	}
-	return wfs, source, runYaml, nil
+	return wfs, source, runYaml, pplVersion, nil
 }
 
 func runYamlAndReqToWfs(ctx *logger.RequestContext, runYaml string, req CreateRunRequest) (schema.WorkflowSource, error) {
@@ -167,7 +185,7 @@ func CreateRun(ctx *logger.RequestContext, request *CreateRunRequest) (CreateRun
 	// TODO:// validate flavour
 	// TODO:// validate queue
 
-	wfs, source, runYaml, err := buildWorkflowSource(ctx, *request, fsID)
+	wfs, source, runYaml, pplVersion, err := buildWorkflowSource(ctx, *request, fsID)
 	if err != nil {
 		ctx.Logging().Errorf("buildWorkflowSource failed. error:%v", err)
 		return CreateRunResponse{}, err
@@ -185,6 +203,7 @@ func CreateRun(ctx *logger.RequestContext, request *CreateRunRequest) (CreateRun
 		ID:             "", // to be back filled according to db pk
 		Name:           wfs.Name,
 		Source:         source,
+		PplVersion:     pplVersion,
 		UserName:       ctx.UserName,
 		FsName:         request.FsName,
 		FsID:           fsID,
@@ -196,3 +196,111 @@ func TestCallback(t *testing.T) {
 	assert.False(t, updatedRun.ActivatedAt.Valid)
 	assert.Empty(t, updatedRun.ActivateTime)
 }
+
+func TestGetRunEvents(t *testing.T) {
+	prevRun := models.Run{
+		Status: common.StatusRunRunning,
+		Runtime: schema.RuntimeView{
+			"data_preprocess": schema.JobView{JobID: "job-1", Status: schema.StatusJobSucceeded},
+			"main":            schema.JobView{JobID: "job-2", Status: schema.StatusJobRunning},
+		},
+	}
+	runtimeView := schema.RuntimeView{
+		"data_preprocess": schema.JobView{JobID: "job-1", Status: schema.StatusJobSucceeded},
+		"main":            schema.JobView{JobID: "job-2", Status: schema.StatusJobFailed, JobMessage: "oom"},
+	}
+	events := getRunEvents(prevRun, common.StatusRunFailed, "step main failed", runtimeView)
+	assert.Equal(t, 3, len(events))
+	assert.Equal(t, "run.failed", events[0].Name)
+	assert.Equal(t, "run.finished", events[1].Name)
+	assert.Equal(t, "step.failed", events[2].Name)
+	assert.Equal(t, "main", events[2].StepName)
+	assert.Equal(t, "oom", events[2].Message)
+
+	// nothing changed
+	prevRun.Status = common.StatusRunFailed
+	prevRun.Runtime = runtimeView
+	events = getRunEvents(prevRun, common.StatusRunFailed, "", runtimeView)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestRunEvents(t *testing.T) {
+	db_fake.InitFakeDB()
+	var err error
+	ctx := &logger.RequestContext{UserName: MockRootUser}
+	run1 := getMockRun1()
+	run1.ID, err = models.CreateRun(ctx.Logging(), &run1)
+	assert.Nil(t, err)
+
+	jobEvent := pipeline.NewWorkflowEvent(pipeline.WfEventJobUpdate, "", map[string]interface{}{
+		"jobid":  "job-1",
+		"status": schema.StatusJobRunning,
+	})
+	LogRunEvent(run1.ID, jobEvent)
+
+	stream, err := SubscribeRunEvents(ctx, run1.ID, 0)
+	assert.Nil(t, err)
+	defer stream.Close()
+	assert.Equal(t, 1, len(stream.History))
+	assert.Equal(t, string(pipeline.WfEventJobUpdate), stream.History[0].Event)
+	assert.Equal(t, "job-1", stream.History[0].Extra["jobid"])
+	assert.False(t, IsRunFinishedEvent(stream.History[0]))
+
+	runEvent := pipeline.NewWorkflowEvent(pipeline.WfEventRunUpdate, "", map[string]interface{}{
+		common.WfEventKeyRunID:  run1.ID,
+		common.WfEventKeyStatus: common.StatusRunSucceeded,
+	})
+	LogRunEvent(run1.ID, runEvent)
+	event := <-stream.Events
+	assert.True(t, event.Pk > stream.History[0].Pk)
+	assert.True(t, IsRunFinishedEvent(event))
+
+	// replay from last event id
+	stream2, err := SubscribeRunEvents(ctx, run1.ID, stream.History[0].Pk)
+	assert.Nil(t, err)
+	defer stream2.Close()
+	assert.Equal(t, 1, len(stream2.History))
+	assert.Equal(t, event.Pk, stream2.History[0].Pk)
+
+	// no access for other users
+	_, err = SubscribeRunEvents(&logger.RequestContext{UserName: "non-admin"}, run1.ID, 0)
+	assert.NotNil(t, err)
+}
+
+func TestEventHub(t *testing.T) {
+	hub := newEventHub()
+	runID := "run-000001"
+	ch, history, ok := hub.subscribe(runID, 0)
+	assert.Nil(t, history)
+	assert.False(t, ok)
+
+	// events before the first published one are not in backlog
+	for pk := int64(10); pk < 10+eventBufferSize; pk++ {
+		hub.publish(models.RunEvent{Pk: pk, RunID: runID})
+	}
+	_, _, ok = hub.subscribe(runID, 0)
+	assert.False(t, ok)
+	_, history, ok = hub.subscribe(runID, 9+eventBufferSize-2)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, int64(9+eventBufferSize), history[1].Pk)
+
+	// slow subscriber is closed after buffer is full
+	hub.publish(models.RunEvent{Pk: 10 + eventBufferSize, RunID: runID})
+	received := 0
+	for range ch {
+		received++
+	}
+	assert.Equal(t, eventBufferSize, received)
+	hub.unsubscribe(runID, ch)
+
+	// backlog is bounded
+	for pk := int64(1000); pk < 1000+eventBacklogSize; pk++ {
+		hub.publish(models.RunEvent{Pk: pk, RunID: runID})
+	}
+	_, _, ok = hub.subscribe(runID, 10+eventBufferSize-1)
+	assert.False(t, ok)
+	_, history, ok = hub.subscribe(runID, 10+eventBufferSize)
+	assert.True(t, ok)
+	assert.Equal(t, eventBacklogSize, len(history))
+}
@@ -0,0 +1,204 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/logger"
+)
+
+type CreateSecretRequest struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+type CreateSecretResponse struct {
+	Name string `json:"name"`
+}
+
+type UpdateSecretRequest struct {
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+type ListSecretResponse struct {
+	common.MarkerInfo
+	SecretList []models.Secret `json:"secretList"`
+}
+
+func validateSecretName(ctx *logger.RequestContext, name string) error {
+	if matched, _ := regexp.MatchString(common.RegPatternSecretName, name); !matched {
+		ctx.ErrorCode = common.InvalidNamePattern
+		return fmt.Errorf("secret name[%s] does not comply with regex pattern[%s]", name, common.RegPatternSecretName)
+	}
+	return nil
+}
+
+func CreateSecret(ctx *logger.RequestContext, request *CreateSecretRequest) (*CreateSecretResponse, error) {
+	ctx.Logging().Debugf("begin create secret. name:%s", request.Name)
+	if err := validateSecretName(ctx, request.Name); err != nil {
+		ctx.Logging().Errorf("create secret failed. error:%s", err.Error())
+		return nil, err
+	}
+	if request.Value == "" {
+		ctx.ErrorCode = common.InappropriateJSON
+		ctx.Logging().Errorf("create secret[%s] failed. value is empty", request.Name)
+		return nil, errors.New("secret value is empty")
+	}
+	encrypted, err := common.EncryptSecret(request.Value)
+	if err != nil {
+		ctx.ErrorCode = common.InternalError
+		if errors.Is(err, common.ErrSecretKeyNotConfigured) {
+			ctx.ErrorCode = common.SecretKeyNotConfigured
+		}
+		ctx.Logging().Errorf("encrypt secret[%s] failed. error:%s", request.Name, err.Error())
+		return nil, err
+	}
+	secret := &models.Secret{
+		Name:        request.Name,
+		UserName:    ctx.UserName,
+		Value:       encrypted,
+		Description: request.Description,
+	}
+	if err := models.CreateSecret(ctx, secret); err != nil {
+		if database.GetErrorCode(err) == database.ErrorKeyIsDuplicated {
+			ctx.ErrorCode = common.SecretNameDuplicated
+		} else {
+			ctx.ErrorCode = common.InternalError
+		}
+		ctx.Logging().Errorf("create secret[%s] failed. error:%s", request.Name, err.Error())
+		return nil, err
+	}
+	return &CreateSecretResponse{Name: secret.Name}, nil
+}
+
+func UpdateSecret(ctx *logger.RequestContext, name string, request *UpdateSecretRequest) error {
+	ctx.Logging().Debugf("begin update secret. name:%s", name)
+	secret, err := getSecret(ctx, name)
+	if err != nil {
+		return err
+	}
+	if request.Value != "" {
+		encrypted, err := common.EncryptSecret(request.Value)
+		if err != nil {
+			ctx.ErrorCode = common.InternalError
+			if errors.Is(err, common.ErrSecretKeyNotConfigured) {
+				ctx.ErrorCode = common.SecretKeyNotConfigured
+			}
+			ctx.Logging().Errorf("encrypt secret[%s] failed. error:%s", name, err.Error())
+			return err
+		}
+		secret.Value = encrypted
+	}
+	if request.Description != "" {
+		secret.Description = request.Description
+	}
+	if err := models.UpdateSecret(ctx, secret); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("update secret[%s] failed. error:%s", name, err.Error())
+		return err
+	}
+	return nil
+}
+
+func GetSecret(ctx *logger.RequestContext, name string) (*models.Secret, error) {
+	ctx.Logging().Debugf("begin get secret. name:%s", name)
+	return getSecret(ctx, name)
+}
+
+func DeleteSecret(ctx *logger.RequestContext, name string) error {
+	ctx.Logging().Debugf("begin delete secret. name:%s", name)
+	if _, err := getSecret(ctx, name); err != nil {
+		return err
+	}
+	if err := models.DeleteSecret(ctx, ctx.UserName, name); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("delete secret[%s] failed. error:%s", name, err.Error())
+		return err
+	}
+	return nil
+}
+
+func ListSecret(ctx *logger.RequestContext, marker string, maxKeys int) (ListSecretResponse, error) {
+	ctx.Logging().Debugf("begin list secrets. user:[%s].", ctx.UserName)
+	listSecretResponse := ListSecretResponse{}
+	listSecretResponse.IsTruncated = false
+	listSecretResponse.SecretList = []models.Secret{}
+
+	var pk int64
+	var err error
+	if marker != "" {
+		pk, err = common.DecryptPk(marker)
+		if err != nil {
+			ctx.Logging().Errorf("DecryptPk marker[%s] failed. err:[%s]",
+				marker, err.Error())
+			ctx.ErrorCode = common.InvalidMarker
+			return listSecretResponse, err
+		}
+	}
+
+	secretList, err := models.ListSecret(ctx, pk, maxKeys, ctx.UserName)
+	if err != nil {
+		ctx.Logging().Errorf("models list secret failed. err:[%s]", err.Error())
+		ctx.ErrorCode = common.InternalError
+		return listSecretResponse, err
+	}
+
+	// get next marker
+	if len(secretList) > 0 {
+		secret := secretList[len(secretList)-1]
+		lastSecret, err := models.GetLastSecret(ctx, ctx.UserName)
+		if err == nil && lastSecret.Pk != secret.Pk {
+			nextMarker, err := common.EncryptPk(secret.Pk)
+			if err != nil {
+				ctx.Logging().Errorf("EncryptPk error. pk:[%d] error:[%s]",
+					secret.Pk, err.Error())
+				ctx.ErrorCode = common.InternalError
+				return listSecretResponse, err
+			}
+			listSecretResponse.NextMarker = nextMarker
+			listSecretResponse.IsTruncated = true
+		}
+	}
+	listSecretResponse.MaxKeys = maxKeys
+	listSecretResponse.SecretList = append(listSecretResponse.SecretList, secretList...)
+	return listSecretResponse, nil
+}
+
+// getSecret returns the secret of request user, the secret value is kept encrypted
+func getSecret(ctx *logger.RequestContext, name string) (*models.Secret, error) {
+	secret, err := models.GetSecret(ctx, ctx.UserName, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.ErrorCode = common.SecretNotFound
+		} else {
+			ctx.ErrorCode = common.InternalError
+		}
+		ctx.Logging().Errorf("get secret[%s] of user[%s] failed. error:%s", name, ctx.UserName, err.Error())
+		return nil, err
+	}
+	return secret, nil
+}
@@ -53,6 +53,11 @@ type ListUserResponse struct {
 	Users []models.User `json:"userList"`
 }
 
+type ListUserGroupResponse struct {
+	UserName string   `json:"username"`
+	Groups   []string `json:"groupList"`
+}
+
 var ErrMismatchedPassword = errors.New("password mismatched")
 
 func Login(ctx *logger.RequestContext, userName string, password string, passwordEncoded bool) (*models.User, error) {
@@ -204,9 +209,72 @@ func DeleteUser(ctx *logger.RequestContext, userName string) error {
 		ctx.Logging().Errorf("models delete user failed. delete user's grant  error:%s", err.Error())
 		return err
 	}
+	if err := models.DeleteUserGroupsByUserName(ctx, userName); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("models delete user failed. delete user's groups error:%s", err.Error())
+		return err
+	}
+	return nil
+}
+
+// AddUserToGroup adds the user to the group, the fs shared with the group is shared with the user then
+func AddUserToGroup(ctx *logger.RequestContext, userName, groupName string) error {
+	ctx.Logging().Debugf("begin add user to group. userName:%s, groupName:%s", userName, groupName)
+	if !common.IsRootUser(ctx.UserName) {
+		ctx.ErrorCode = common.OnlyRootAllowed
+		ctx.Logging().Errorln("add user to group failed. root is needed.")
+		return errors.New("add user to group failed")
+	}
+	if !schema.CheckReg(groupName, common.RegPatternGroupName) {
+		ctx.Logging().Errorf("add user to group failed. groupName not allowed. groupName:%v", groupName)
+		ctx.ErrorCode = common.InvalidNamePattern
+		return common.InvalidNamePatternError(groupName, common.ResourceTypeGroup, common.RegPatternGroupName)
+	}
+	if _, err := models.GetUserByName(ctx, userName); err != nil {
+		ctx.ErrorCode = common.UserNotExist
+		ctx.Logging().Errorf("add user to group failed. user:%s not exist", userName)
+		return errors.New("add user to group failed")
+	}
+	if err := models.AddUserToGroup(ctx, userName, groupName); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("models add user to group failed. error:%s", err.Error())
+		return err
+	}
 	return nil
 }
 
+func DeleteUserFromGroup(ctx *logger.RequestContext, userName, groupName string) error {
+	ctx.Logging().Debugf("begin delete user from group. userName:%s, groupName:%s", userName, groupName)
+	if !common.IsRootUser(ctx.UserName) {
+		ctx.ErrorCode = common.OnlyRootAllowed
+		ctx.Logging().Errorln("delete user from group failed. root is needed.")
+		return errors.New("delete user from group failed")
+	}
+	if err := models.DeleteUserFromGroup(ctx, userName, groupName); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("models delete user from group failed. error:%s", err.Error())
+		return err
+	}
+	return nil
+}
+
+func ListUserGroup(ctx *logger.RequestContext, userName string) (*ListUserGroupResponse, error) {
+	ctx.Logging().Debugf("begin list groups of user. userName:%s", userName)
+	// regular user can only list his own groups
+	if !common.IsRootUser(ctx.UserName) && !strings.EqualFold(ctx.UserName, userName) {
+		ctx.ErrorCode = common.AccessDenied
+		ctx.Logging().Errorf("list groups of user failed. regular user can only list himself. userName:%s", ctx.UserName)
+		return nil, errors.New("list groups of user failed")
+	}
+	groups, err := models.ListGroupsOfUser(userName)
+	if err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("models list groups of user failed. error:%s", err.Error())
+		return nil, err
+	}
+	return &ListUserGroupResponse{UserName: userName, Groups: groups}, nil
+}
+
 func ListUser(ctx *logger.RequestContext, marker string, maxKeys int) (*ListUserResponse, error) {
 	ctx.Logging().Debug("begin list user.")
 	if !common.IsRootUser(ctx.UserName) {
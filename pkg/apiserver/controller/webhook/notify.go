@@ -0,0 +1,384 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/common/http/util"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/common/uuid"
+)
+
+const (
+	HeaderKeyEvent     = "X-PaddleFlow-Event"
+	HeaderKeyDelivery  = "X-PaddleFlow-Delivery"
+	HeaderKeySignature = "X-PaddleFlow-Signature"
+
+	defaultMaxRetries    = 3
+	defaultRetryInterval = 5 * time.Second
+	defaultTimeout       = 10 * time.Second
+
+	dispatchInterval = 5 * time.Second
+	dispatchBatch    = 100
+	dispatchWorkers  = 8
+)
+
+// dispatchSignal wakes up the dispatcher once new deliveries are created
+var dispatchSignal = make(chan struct{}, 1)
+
+// Event is a status change of run or one of its steps
+type Event struct {
+	Name       string
+	StepName   string
+	JobID      string
+	StepStatus string
+	Message    string
+}
+
+// Payload is the json body sent to webhooks
+type Payload struct {
+	Event      string `json:"event"`
+	RunID      string `json:"runID"`
+	RunName    string `json:"runName"`
+	Source     string `json:"source"`
+	UserName   string `json:"userName"`
+	RunStatus  string `json:"runStatus"`
+	StepName   string `json:"stepName,omitempty"`
+	JobID      string `json:"jobID,omitempty"`
+	StepStatus string `json:"stepStatus,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Timestamp  string `json:"timestamp"`
+}
+
+// sender delivers payload to the address of webhook, and returns the response code if any
+type sender func(hook *models.Webhook, deliveryID string, payload *Payload, body []byte) (int, error)
+
+var senders = map[string]sender{
+	models.WebhookTypeHTTP:  sendWebhook,
+	models.WebhookTypeIM:    sendIM,
+	models.WebhookTypeEmail: sendEmail,
+}
+
+// NotifyRunEvents sends events of run to subscribed webhooks. Deliveries are saved as pending and then sent
+// by the dispatcher with retry, so that workflow callbacks are never blocked and deliveries survive restart.
+func NotifyRunEvents(logEntry *log.Entry, run models.Run, status string, events []Event) {
+	if len(events) == 0 {
+		return
+	}
+	hooks, err := models.ListWebhookForRun(logEntry, run.UserName, run.Source)
+	if err != nil {
+		logEntry.Errorf("list webhooks of run[%s] failed. error: %v", run.ID, err)
+		return
+	}
+	for i := range hooks {
+		hook := hooks[i]
+		for _, event := range events {
+			if !subscribed(&hook, event.Name) {
+				continue
+			}
+			payload := &Payload{
+				Event:      event.Name,
+				RunID:      run.ID,
+				RunName:    run.Name,
+				Source:     run.Source,
+				UserName:   run.UserName,
+				RunStatus:  status,
+				StepName:   event.StepName,
+				JobID:      event.JobID,
+				StepStatus: event.StepStatus,
+				Message:    event.Message,
+				Timestamp:  time.Now().Format(time.RFC3339),
+			}
+			body, err := json.Marshal(payload)
+			if err != nil {
+				logEntry.Errorf("marshal payload of event[%s] failed. error: %v", event.Name, err)
+				continue
+			}
+			delivery := &models.WebhookDelivery{
+				ID:          uuid.GenerateID(common.PrefixDelivery),
+				WebhookID:   hook.ID,
+				RunID:       run.ID,
+				Event:       event.Name,
+				Payload:     string(body),
+				Status:      models.DeliveryStatusPending,
+				NextRetryAt: time.Now(),
+			}
+			models.CreateWebhookDelivery(logEntry, delivery)
+		}
+	}
+	select {
+	case dispatchSignal <- struct{}{}:
+	default:
+	}
+}
+
+// RunDeliveryDispatcher sends pending deliveries until stopCh is closed, including those left by last run of server.
+// A delivery is claimed in database before sending, so that it is sent by one server at a time.
+func RunDeliveryDispatcher(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(dispatchInterval)
+	defer ticker.Stop()
+	for {
+		dispatchPending(log.WithField("component", "webhook"))
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		case <-dispatchSignal:
+		}
+	}
+}
+
+func dispatchPending(logEntry *log.Entry) {
+	now := time.Now()
+	deliveries, err := models.ListPendingWebhookDelivery(logEntry, now, dispatchBatch)
+	if err != nil {
+		return
+	}
+	// the lease covers one attempt, the delivery is picked up again if server exits while sending
+	lease := now.Add(2 * sendTimeout())
+	workers := make(chan struct{}, dispatchWorkers)
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		if claimed, err := models.ClaimWebhookDelivery(logEntry, delivery, now, lease); err != nil || !claimed {
+			continue
+		}
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+			deliver(logEntry, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+func subscribed(hook *models.Webhook, event string) bool {
+	for _, e := range hook.Events {
+		if e == EventAll || e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver makes one attempt to send the delivery, and schedules the next retry with exponential backoff if failed
+func deliver(logEntry *log.Entry, delivery *models.WebhookDelivery) {
+	hook, err := models.GetWebhookByID(&logger.RequestContext{}, delivery.WebhookID)
+	if err != nil {
+		// retried after the lease expires, unless webhook has been deleted
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			delivery.Status = models.DeliveryStatusFailed
+			delivery.Message = fmt.Sprintf("webhook[%s] not found", delivery.WebhookID)
+			models.UpdateWebhookDelivery(logEntry, delivery)
+		}
+		return
+	}
+	send, ok := senders[hook.Type]
+	if !ok {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.Message = fmt.Sprintf("webhook type[%s] not supported", hook.Type)
+		models.UpdateWebhookDelivery(logEntry, delivery)
+		return
+	}
+	payload := &Payload{}
+	if err := json.Unmarshal([]byte(delivery.Payload), payload); err != nil {
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.Message = fmt.Sprintf("unmarshal payload failed. error: %v", err)
+		models.UpdateWebhookDelivery(logEntry, delivery)
+		return
+	}
+	code, err := send(hook, delivery.ID, payload, []byte(delivery.Payload))
+	delivery.Attempts++
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = models.DeliveryStatusSucceeded
+		delivery.Message = ""
+		models.UpdateWebhookDelivery(logEntry, delivery)
+		return
+	}
+	logEntry.Warnf("deliver event[%s] to webhook[%s] failed, attempts: %d. error: %v",
+		delivery.Event, hook.ID, delivery.Attempts, err)
+	delivery.Message = err.Error()
+	maxRetries, interval := retryPolicy()
+	if delivery.Attempts > maxRetries {
+		delivery.Status = models.DeliveryStatusFailed
+	} else {
+		delivery.NextRetryAt = time.Now().Add(interval << uint(delivery.Attempts-1))
+	}
+	models.UpdateWebhookDelivery(logEntry, delivery)
+}
+
+func retryPolicy() (int, time.Duration) {
+	maxRetries, interval := defaultMaxRetries, defaultRetryInterval
+	if config.GlobalServerConfig != nil {
+		conf := config.GlobalServerConfig.Notification
+		if conf.MaxRetries > 0 {
+			maxRetries = conf.MaxRetries
+		}
+		if conf.RetryIntervalSeconds > 0 {
+			interval = time.Duration(conf.RetryIntervalSeconds) * time.Second
+		}
+	}
+	return maxRetries, interval
+}
+
+func sendTimeout() time.Duration {
+	if config.GlobalServerConfig != nil && config.GlobalServerConfig.Notification.TimeoutSeconds > 0 {
+		return time.Duration(config.GlobalServerConfig.Notification.TimeoutSeconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+func allowPrivateAddress() bool {
+	return config.GlobalServerConfig != nil && config.GlobalServerConfig.Notification.AllowPrivateAddress
+}
+
+// httpClient returns the client to send webhooks, which refuses to connect to private addresses unless allowed,
+// as webhook addresses are given by users and the server should not be used to reach internal services.
+func httpClient() *http.Client {
+	timeout := sendTimeout()
+	if allowPrivateAddress() {
+		return &http.Client{Timeout: timeout}
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: util.PublicDialContext(timeout)},
+	}
+}
+
+// Sign returns the signature of body, which is hex encoded HMAC-SHA256 with webhook secret as key
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func postJSON(url string, body []byte, header map[string]string) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, respBody)
+	}
+	return resp.StatusCode, nil
+}
+
+func sendWebhook(hook *models.Webhook, deliveryID string, payload *Payload, body []byte) (int, error) {
+	header := map[string]string{
+		HeaderKeyEvent:    payload.Event,
+		HeaderKeyDelivery: deliveryID,
+	}
+	if hook.Secret != "" {
+		secret, err := common.DecryptSecret(hook.Secret)
+		if err != nil {
+			return 0, fmt.Errorf("decrypt secret of webhook failed. error: %v", err)
+		}
+		header[HeaderKeySignature] = Sign(secret, body)
+	}
+	return postJSON(hook.Address, body, header)
+}
+
+// sendIM sends text message to IM robot, the message format is compatible with DingTalk and WeCom robots
+func sendIM(hook *models.Webhook, deliveryID string, payload *Payload, body []byte) (int, error) {
+	message := map[string]interface{}{
+		"msgtype": "text",
+		"text": map[string]string{
+			"content": formatMessage(payload),
+		},
+	}
+	messageBody, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+	return postJSON(hook.Address, messageBody, nil)
+}
+
+func sendEmail(hook *models.Webhook, deliveryID string, payload *Payload, body []byte) (int, error) {
+	if config.GlobalServerConfig == nil || config.GlobalServerConfig.Notification.SMTP.Host == "" {
+		return 0, fmt.Errorf("smtp server is not configured")
+	}
+	smtpConf := config.GlobalServerConfig.Notification.SMTP
+	receivers := make([]string, 0)
+	for _, addr := range strings.Split(hook.Address, common.SeparatorComma) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			receivers = append(receivers, addr)
+		}
+	}
+	var auth smtp.Auth
+	if smtpConf.Username != "" {
+		auth = smtp.PlainAuth("", smtpConf.Username, smtpConf.Password, smtpConf.Host)
+	}
+	subject := fmt.Sprintf("[PaddleFlow] %s %s", payload.RunID, payload.Event)
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		smtpConf.From, strings.Join(receivers, ", "), subject, formatMessage(payload))
+	addr := fmt.Sprintf("%s:%d", smtpConf.Host, smtpConf.Port)
+	if err := smtp.SendMail(addr, auth, smtpConf.From, receivers, []byte(msg)); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+func formatMessage(payload *Payload) string {
+	lines := []string{
+		fmt.Sprintf("PaddleFlow event: %s", payload.Event),
+		fmt.Sprintf("run: %s(%s)", payload.RunName, payload.RunID),
+		fmt.Sprintf("user: %s", payload.UserName),
+		fmt.Sprintf("run status: %s", payload.RunStatus),
+	}
+	if payload.StepName != "" {
+		lines = append(lines, fmt.Sprintf("step: %s, job: %s, status: %s", payload.StepName, payload.JobID, payload.StepStatus))
+	}
+	if payload.Message != "" {
+		lines = append(lines, fmt.Sprintf("message: %s", payload.Message))
+	}
+	lines = append(lines, fmt.Sprintf("time: %s", payload.Timestamp))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,313 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/http/util"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/common/schema"
+	"paddleflow/pkg/common/uuid"
+)
+
+const (
+	// EventAll subscribes all run and step events
+	EventAll = "*"
+	// EventRunFinished is sent once run reaches final status, whether succeeded, failed or terminated
+	EventRunFinished = "run.finished"
+
+	eventPrefixRun  = "run."
+	eventPrefixStep = "step."
+)
+
+var (
+	runEventStatus = []string{common.StatusRunRunning, common.StatusRunSucceeded, common.StatusRunFailed,
+		common.StatusRunTerminating, common.StatusRunTerminated}
+	stepEventStatus = []schema.JobStatus{schema.StatusJobRunning, schema.StatusJobSucceeded, schema.StatusJobFailed,
+		schema.StatusJobTerminated, schema.StatusJobCancelled, schema.StatusJobCached}
+)
+
+func RunEventName(status string) string {
+	return eventPrefixRun + status
+}
+
+func StepEventName(status schema.JobStatus) string {
+	return eventPrefixStep + string(status)
+}
+
+func isValidEvent(event string) bool {
+	if event == EventAll || event == EventRunFinished {
+		return true
+	}
+	for _, status := range runEventStatus {
+		if event == RunEventName(status) {
+			return true
+		}
+	}
+	for _, status := range stepEventStatus {
+		if event == StepEventName(status) {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhookRequest struct {
+	PipelineID  string   `json:"pipelineID"`
+	Type        string   `json:"type"`
+	Address     string   `json:"address"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+}
+
+type CreateWebhookResponse struct {
+	WebhookID string `json:"webhookID"`
+}
+
+type ListWebhookResponse struct {
+	common.MarkerInfo
+	WebhookList []models.Webhook `json:"webhookList"`
+}
+
+type ListDeliveryResponse struct {
+	common.MarkerInfo
+	DeliveryList []models.WebhookDelivery `json:"deliveryList"`
+}
+
+func validateWebhook(ctx *logger.RequestContext, request *CreateWebhookRequest) error {
+	if request.Type == "" {
+		request.Type = models.WebhookTypeHTTP
+	}
+	switch request.Type {
+	case models.WebhookTypeHTTP, models.WebhookTypeIM:
+		u, err := url.Parse(request.Address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("address[%s] is not a valid http url", request.Address)
+		}
+		if !allowPrivateAddress() {
+			if err := util.CheckPublicURL(request.Address); err != nil {
+				return fmt.Errorf("address[%s] is not allowed. error: %v", request.Address, err)
+			}
+		}
+	case models.WebhookTypeEmail:
+		if _, err := mail.ParseAddressList(request.Address); err != nil {
+			return fmt.Errorf("address[%s] is not a valid email list. error: %v", request.Address, err)
+		}
+	default:
+		return fmt.Errorf("webhook type[%s] not supported, only support [%s, %s, %s]", request.Type,
+			models.WebhookTypeHTTP, models.WebhookTypeEmail, models.WebhookTypeIM)
+	}
+	if len(request.Events) == 0 {
+		return errors.New("events of webhook is empty")
+	}
+	for _, event := range request.Events {
+		if !isValidEvent(event) {
+			return fmt.Errorf("event[%s] not supported", event)
+		}
+	}
+	if request.PipelineID != "" {
+		ppl, err := models.GetPipelineByID(request.PipelineID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.ErrorCode = common.PipelineNotFound
+			} else {
+				ctx.ErrorCode = common.InternalError
+			}
+			return fmt.Errorf("get pipeline[%s] failed. error: %v", request.PipelineID, err)
+		}
+		if !common.IsRootUser(ctx.UserName) && ctx.UserName != ppl.UserName {
+			ctx.ErrorCode = common.AccessDenied
+			return common.NoAccessError(ctx.UserName, common.ResourceTypePipeline, request.PipelineID)
+		}
+	}
+	return nil
+}
+
+func CreateWebhook(ctx *logger.RequestContext, request *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	ctx.Logging().Debugf("begin create webhook. type:%s, pipelineID:%s", request.Type, request.PipelineID)
+	if err := validateWebhook(ctx, request); err != nil {
+		if ctx.ErrorCode == "" {
+			ctx.ErrorCode = common.InvalidWebhook
+		}
+		ctx.Logging().Errorf("validate webhook failed. error:%s", err.Error())
+		return nil, err
+	}
+	webhook := &models.Webhook{
+		ID:          uuid.GenerateID(common.PrefixWebhook),
+		UserName:    ctx.UserName,
+		PipelineID:  request.PipelineID,
+		Type:        request.Type,
+		Address:     request.Address,
+		Events:      request.Events,
+		Description: request.Description,
+	}
+	if request.Secret != "" {
+		encrypted, err := common.EncryptSecret(request.Secret)
+		if err != nil {
+			ctx.ErrorCode = common.InternalError
+			if errors.Is(err, common.ErrSecretKeyNotConfigured) {
+				ctx.ErrorCode = common.SecretKeyNotConfigured
+			}
+			ctx.Logging().Errorf("encrypt secret of webhook failed. error:%s", err.Error())
+			return nil, err
+		}
+		webhook.Secret = encrypted
+	}
+	if err := models.CreateWebhook(ctx, webhook); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("create webhook failed. error:%s", err.Error())
+		return nil, err
+	}
+	return &CreateWebhookResponse{WebhookID: webhook.ID}, nil
+}
+
+func GetWebhook(ctx *logger.RequestContext, webhookID string) (*models.Webhook, error) {
+	ctx.Logging().Debugf("begin get webhook. webhookID:%s", webhookID)
+	webhook, err := models.GetWebhookByID(ctx, webhookID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.ErrorCode = common.WebhookNotFound
+		} else {
+			ctx.ErrorCode = common.InternalError
+		}
+		ctx.Logging().Errorf("get webhook[%s] failed. error:%s", webhookID, err.Error())
+		return nil, err
+	}
+	if !common.IsRootUser(ctx.UserName) && ctx.UserName != webhook.UserName {
+		err := common.NoAccessError(ctx.UserName, common.ResourceTypeWebhook, webhookID)
+		ctx.ErrorCode = common.AccessDenied
+		ctx.Logging().Errorln(err.Error())
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func DeleteWebhook(ctx *logger.RequestContext, webhookID string) error {
+	ctx.Logging().Debugf("begin delete webhook. webhookID:%s", webhookID)
+	if _, err := GetWebhook(ctx, webhookID); err != nil {
+		return err
+	}
+	if err := models.DeleteWebhook(ctx, webhookID); err != nil {
+		ctx.ErrorCode = common.InternalError
+		ctx.Logging().Errorf("delete webhook[%s] failed. error:%s", webhookID, err.Error())
+		return err
+	}
+	return nil
+}
+
+func ListWebhook(ctx *logger.RequestContext, marker string, maxKeys int) (ListWebhookResponse, error) {
+	ctx.Logging().Debugf("begin list webhooks. user:[%s].", ctx.UserName)
+	listWebhookResponse := ListWebhookResponse{}
+	listWebhookResponse.IsTruncated = false
+	listWebhookResponse.WebhookList = []models.Webhook{}
+
+	pk, err := decodeMarker(ctx, marker)
+	if err != nil {
+		return listWebhookResponse, err
+	}
+	// root is able to see webhooks of all users
+	userName := ctx.UserName
+	if common.IsRootUser(userName) {
+		userName = ""
+	}
+	webhookList, err := models.ListWebhook(ctx, pk, maxKeys, userName)
+	if err != nil {
+		ctx.Logging().Errorf("models list webhook failed. err:[%s]", err.Error())
+		ctx.ErrorCode = common.InternalError
+		return listWebhookResponse, err
+	}
+
+	// get next marker
+	if len(webhookList) > 0 {
+		webhook := webhookList[len(webhookList)-1]
+		lastWebhook, err := models.GetLastWebhook(ctx, userName)
+		if err == nil && lastWebhook.Pk != webhook.Pk {
+			nextMarker, err := common.EncryptPk(webhook.Pk)
+			if err != nil {
+				ctx.Logging().Errorf("EncryptPk error. pk:[%d] error:[%s]",
+					webhook.Pk, err.Error())
+				ctx.ErrorCode = common.InternalError
+				return listWebhookResponse, err
+			}
+			listWebhookResponse.NextMarker = nextMarker
+			listWebhookResponse.IsTruncated = true
+		}
+	}
+	listWebhookResponse.MaxKeys = maxKeys
+	listWebhookResponse.WebhookList = append(listWebhookResponse.WebhookList, webhookList...)
+	return listWebhookResponse, nil
+}
+
+func ListDelivery(ctx *logger.RequestContext, webhookID, marker string, maxKeys int) (ListDeliveryResponse, error) {
+	ctx.Logging().Debugf("begin list deliveries of webhook[%s].", webhookID)
+	listDeliveryResponse := ListDeliveryResponse{}
+	listDeliveryResponse.IsTruncated = false
+	listDeliveryResponse.DeliveryList = []models.WebhookDelivery{}
+
+	if _, err := GetWebhook(ctx, webhookID); err != nil {
+		return listDeliveryResponse, err
+	}
+	pk, err := decodeMarker(ctx, marker)
+	if err != nil {
+		return listDeliveryResponse, err
+	}
+	deliveryList, err := models.ListWebhookDelivery(ctx, webhookID, pk, maxKeys)
+	if err != nil {
+		ctx.Logging().Errorf("models list delivery failed. err:[%s]", err.Error())
+		ctx.ErrorCode = common.InternalError
+		return listDeliveryResponse, err
+	}
+	// a full page indicates that there may be more deliveries
+	if maxKeys > 0 && len(deliveryList) == maxKeys {
+		delivery := deliveryList[len(deliveryList)-1]
+		nextMarker, err := common.EncryptPk(delivery.Pk)
+		if err != nil {
+			ctx.Logging().Errorf("EncryptPk error. pk:[%d] error:[%s]",
+				delivery.Pk, err.Error())
+			ctx.ErrorCode = common.InternalError
+			return listDeliveryResponse, err
+		}
+		listDeliveryResponse.NextMarker = nextMarker
+		listDeliveryResponse.IsTruncated = true
+	}
+	listDeliveryResponse.MaxKeys = maxKeys
+	listDeliveryResponse.DeliveryList = append(listDeliveryResponse.DeliveryList, deliveryList...)
+	return listDeliveryResponse, nil
+}
+
+func decodeMarker(ctx *logger.RequestContext, marker string) (int64, error) {
+	if marker == "" {
+		return 0, nil
+	}
+	pk, err := common.DecryptPk(marker)
+	if err != nil {
+		ctx.Logging().Errorf("DecryptPk marker[%s] failed. err:[%s]",
+			marker, err.Error())
+		ctx.ErrorCode = common.InvalidMarker
+		return 0, err
+	}
+	return pk, nil
+}
@@ -0,0 +1,190 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/database/db_fake"
+	"paddleflow/pkg/common/logger"
+)
+
+const (
+	MockRootUser   = "root"
+	MockNormalUser = "user1"
+	MockSecretKey  = "webhook-test-key"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	db_fake.InitFakeDB()
+	config.GlobalServerConfig = &config.ServerConfig{}
+	ctx := &logger.RequestContext{UserName: MockNormalUser}
+
+	// private address not allowed, which is checked before the secret
+	_, err := CreateWebhook(ctx, &CreateWebhookRequest{Address: "http://169.254.169.254/latest/meta-data",
+		Secret: "abc", Events: []string{"run.failed"}})
+	assert.Error(t, err)
+	assert.Equal(t, common.InvalidWebhook, ctx.ErrorCode)
+
+	// secret key not configured
+	ctx = &logger.RequestContext{UserName: MockNormalUser}
+	_, err = CreateWebhook(ctx, &CreateWebhookRequest{Address: "http://8.8.8.8", Secret: "abc",
+		Events: []string{"run.failed"}})
+	assert.Error(t, err)
+	assert.Equal(t, common.SecretKeyNotConfigured, ctx.ErrorCode)
+	config.GlobalServerConfig.ApiServer.SecretKey = MockSecretKey
+	config.GlobalServerConfig.Notification.AllowPrivateAddress = true
+
+	// invalid address
+	ctx = &logger.RequestContext{UserName: MockNormalUser}
+	_, err = CreateWebhook(ctx, &CreateWebhookRequest{Address: "127.0.0.1", Events: []string{"run.failed"}})
+	assert.Error(t, err)
+	assert.Equal(t, common.InvalidWebhook, ctx.ErrorCode)
+
+	// invalid event
+	ctx = &logger.RequestContext{UserName: MockNormalUser}
+	_, err = CreateWebhook(ctx, &CreateWebhookRequest{Address: "http://127.0.0.1", Events: []string{"run.pending"}})
+	assert.Error(t, err)
+	assert.Equal(t, common.InvalidWebhook, ctx.ErrorCode)
+
+	// pipeline not found
+	ctx = &logger.RequestContext{UserName: MockNormalUser}
+	_, err = CreateWebhook(ctx, &CreateWebhookRequest{Address: "http://127.0.0.1", PipelineID: "ppl-000001",
+		Events: []string{"run.failed"}})
+	assert.Error(t, err)
+	assert.Equal(t, common.PipelineNotFound, ctx.ErrorCode)
+
+	ctx = &logger.RequestContext{UserName: MockNormalUser}
+	resp, err := CreateWebhook(ctx, &CreateWebhookRequest{Address: "http://127.0.0.1", Secret: "abc",
+		Events: []string{EventRunFinished, StepEventName("failed")}})
+	assert.NoError(t, err)
+
+	hook, err := GetWebhook(ctx, resp.WebhookID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.WebhookTypeHTTP, hook.Type)
+	assert.Equal(t, []string{EventRunFinished, "step.failed"}, hook.Events)
+	assert.NotEqual(t, "abc", hook.Secret)
+
+	// other user has no access
+	otherCtx := &logger.RequestContext{UserName: "user2"}
+	_, err = GetWebhook(otherCtx, resp.WebhookID)
+	assert.Error(t, err)
+	assert.Equal(t, common.AccessDenied, otherCtx.ErrorCode)
+
+	listResp, err := ListWebhook(&logger.RequestContext{UserName: MockRootUser}, "", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(listResp.WebhookList))
+
+	err = DeleteWebhook(ctx, resp.WebhookID)
+	assert.NoError(t, err)
+	_, err = GetWebhook(ctx, resp.WebhookID)
+	assert.Equal(t, common.WebhookNotFound, ctx.ErrorCode)
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	db_fake.InitFakeDB()
+	config.GlobalServerConfig = &config.ServerConfig{}
+	config.GlobalServerConfig.ApiServer.SecretKey = MockSecretKey
+	config.GlobalServerConfig.Notification.AllowPrivateAddress = true
+	config.GlobalServerConfig.Notification.MaxRetries = 1
+	ctx := &logger.RequestContext{UserName: MockNormalUser}
+
+	var received []byte
+	responseCode := http.StatusInternalServerError
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ = ioutil.ReadAll(r.Body)
+		signature = r.Header.Get(HeaderKeySignature)
+		assert.Equal(t, "run.failed", r.Header.Get(HeaderKeyEvent))
+		w.WriteHeader(responseCode)
+	}))
+	defer server.Close()
+
+	resp, err := CreateWebhook(ctx, &CreateWebhookRequest{Address: server.URL, Secret: "abc",
+		Events: []string{"run.failed"}})
+	assert.NoError(t, err)
+	hook, err := GetWebhook(ctx, resp.WebhookID)
+	assert.NoError(t, err)
+
+	run := models.Run{ID: "run-000001", Name: "run1", UserName: MockNormalUser}
+	hooks, err := models.ListWebhookForRun(ctx.Logging(), run.UserName, run.Source)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(hooks))
+
+	body := []byte(`{"event":"run.failed"}`)
+	delivery := &models.WebhookDelivery{ID: "dlv-000001", WebhookID: hook.ID, RunID: run.ID,
+		Event: "run.failed", Payload: string(body), Status: models.DeliveryStatusPending, NextRetryAt: time.Now()}
+	assert.NoError(t, models.CreateWebhookDelivery(ctx.Logging(), delivery))
+
+	// failed delivery stays pending until the next retry
+	dispatchPending(ctx.Logging())
+	assert.Equal(t, body, received)
+	assert.Equal(t, Sign("abc", body), signature)
+	deliveryResp, err := ListDelivery(ctx, hook.ID, "", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(deliveryResp.DeliveryList))
+	assert.Equal(t, models.DeliveryStatusPending, deliveryResp.DeliveryList[0].Status)
+	assert.Equal(t, 1, deliveryResp.DeliveryList[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, deliveryResp.DeliveryList[0].ResponseCode)
+
+	// not due yet
+	received = nil
+	dispatchPending(ctx.Logging())
+	assert.Nil(t, received)
+
+	responseCode = http.StatusOK
+	database.DB.Table("webhook_delivery").Where("id = ?", delivery.ID).Update("next_retry_at", time.Now())
+	dispatchPending(ctx.Logging())
+	assert.Equal(t, body, received)
+	deliveryResp, err = ListDelivery(ctx, hook.ID, "", 50)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeliveryStatusSucceeded, deliveryResp.DeliveryList[0].Status)
+	assert.Equal(t, 2, deliveryResp.DeliveryList[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveryResp.DeliveryList[0].ResponseCode)
+}
+
+func TestListWebhookForRun(t *testing.T) {
+	db_fake.InitFakeDB()
+	hooks := []models.Webhook{
+		{ID: "hook-000001", UserName: MockNormalUser, Type: models.WebhookTypeHTTP},
+		{ID: "hook-000002", UserName: MockNormalUser, PipelineID: "ppl-000002", Type: models.WebhookTypeHTTP},
+		{ID: "hook-000003", UserName: MockRootUser, Type: models.WebhookTypeHTTP},
+		{ID: "hook-000004", UserName: MockRootUser, PipelineID: "ppl-000001", Type: models.WebhookTypeHTTP},
+		{ID: "hook-000005", UserName: "user2", Type: models.WebhookTypeHTTP},
+	}
+	for i := range hooks {
+		assert.NoError(t, models.CreateWebhook(&logger.RequestContext{}, &hooks[i]))
+	}
+	result, err := models.ListWebhookForRun(log.NewEntry(log.StandardLogger()), MockNormalUser, "ppl-000001")
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	for _, hook := range result {
+		ids = append(ids, hook.ID)
+	}
+	assert.ElementsMatch(t, []string{"hook-000001", "hook-000004"}, ids)
+}
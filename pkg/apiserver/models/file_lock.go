@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"paddleflow/pkg/common/database"
+)
+
+const (
+	FileLockTableName    = "file_lock"
+	LockSessionTableName = "lock_session"
+
+	Locks = "locks"
+)
+
+// FileLock keeps the locks held on a path of fs in json, the row is locked while the locks are changed,
+// so that the lock requests of the path are serialized across the servers.
+type FileLock struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	FsID      string    `json:"fsID" gorm:"index"`
+	Path      string    `json:"path" gorm:"type:text"`
+	Locks     string    `json:"locks" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updateTime"`
+}
+
+func (FileLock) TableName() string {
+	return FileLockTableName
+}
+
+// LockSession is a mount holding file locks, its locks are released if it is not kept alive.
+type LockSession struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	FsID      string    `json:"fsID"`
+	UserName  string    `json:"userName"`
+	UpdatedAt time.Time `json:"updateTime" gorm:"index"`
+}
+
+func (LockSession) TableName() string {
+	return LockSessionTableName
+}
+
+// FileLockID returns the id of the locks of the path, the path may be too long to be the key.
+func FileLockID(fsID, path string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fsID+":"+path)).String()
+}
+
+// UpdateFileLocks calls update with the locks of the path and saves the returned locks in a transaction,
+// the row is deleted if no lock is left.
+func UpdateFileLocks(fsID, path string, update func(locks string) (string, error)) error {
+	id := FileLockID(fsID, path)
+	return withTransaction(database.DB, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&FileLock{ID: id, FsID: fsID, Path: path}).Error; err != nil {
+			return err
+		}
+		// the update locks the row until committed
+		if err := tx.Model(&FileLock{}).Where(fmt.Sprintf(QueryEqualWithParam, ID), id).
+			Update(UpdatedAt, time.Now()).Error; err != nil {
+			return err
+		}
+		var fileLock FileLock
+		if err := tx.Where(fmt.Sprintf(QueryEqualWithParam, ID), id).First(&fileLock).Error; err != nil {
+			return err
+		}
+		locks, err := update(fileLock.Locks)
+		if err != nil {
+			return err
+		}
+		if locks == "" {
+			return tx.Where(fmt.Sprintf(QueryEqualWithParam, ID), id).Delete(&FileLock{}).Error
+		}
+		return tx.Model(&FileLock{}).Where(fmt.Sprintf(QueryEqualWithParam, ID), id).
+			Update(Locks, locks).Error
+	})
+}
+
+// ListFileLocksOfSession lists the locks of fs containing the ones held by the session.
+func ListFileLocksOfSession(fsID, session string) ([]FileLock, error) {
+	var fileLocks []FileLock
+	result := database.DB.Where(fmt.Sprintf(QueryEqualWithParam, FsID), fsID).
+		Where(fmt.Sprintf(QueryLikeWithParam, Locks), fmt.Sprintf("%%\"session\":\"%s\"%%", session)).
+		Find(&fileLocks)
+	return fileLocks, result.Error
+}
+
+// TouchLockSession creates the session or refreshes its update time.
+func TouchLockSession(session *LockSession) error {
+	session.UpdatedAt = time.Now()
+	return database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: ID}},
+		DoUpdates: clause.AssignmentColumns([]string{UpdatedAt}),
+	}).Create(session).Error
+}
+
+// GetLockSession gets the session by id, the session is empty if not found
+func GetLockSession(id string) (LockSession, error) {
+	var session LockSession
+	result := database.DB.Where(fmt.Sprintf(QueryEqualWithParam, ID), id).Find(&session)
+	return session, result.Error
+}
+
+// ListExpiredLockSessions lists the sessions not refreshed since the time
+func ListExpiredLockSessions(since time.Time) ([]LockSession, error) {
+	var sessions []LockSession
+	result := database.DB.Where(fmt.Sprintf(" (%s < ?) ", UpdatedAt), since).Find(&sessions)
+	return sessions, result.Error
+}
+
+func DeleteLockSession(id string) error {
+	return database.DB.Where(fmt.Sprintf(QueryEqualWithParam, ID), id).Delete(&LockSession{}).Error
+}
@@ -0,0 +1,104 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/common/database"
+)
+
+const (
+	FsShareTableName = "fs_share"
+
+	ShareType = "share_type"
+	ShareTo   = "share_to"
+
+	// FsShareToUser and FsShareToGroup are the types of the grantees of fs shares
+	FsShareToUser  = "user"
+	FsShareToGroup = "group"
+)
+
+// FsShare grants a user or the users in a group the read-only or read-write access to a path of fs
+type FsShare struct {
+	Model
+	FsID       string `json:"fsID" gorm:"index"`
+	FsPath     string `json:"fsPath"`
+	ShareType  string `json:"shareType"`
+	ShareTo    string `json:"shareTo"`
+	Permission string `json:"permission"`
+}
+
+func (FsShare) TableName() string {
+	return FsShareTableName
+}
+
+// SaveFsShare creates the share, or updates the permission of the share with the same fs path and grantee
+func SaveFsShare(share *FsShare) error {
+	return withTransaction(database.DB, func(tx *gorm.DB) error {
+		var existed FsShare
+		result := tx.Where(&FsShare{FsID: share.FsID, FsPath: share.FsPath, ShareType: share.ShareType,
+			ShareTo: share.ShareTo}).Limit(1).Find(&existed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Create(share).Error
+		}
+		share.Model = existed.Model
+		return tx.Model(&existed).Update("permission", share.Permission).Error
+	})
+}
+
+// GetFsShare gets the share of fs by id, the share is empty if not found
+func GetFsShare(fsID, id string) (FsShare, error) {
+	var share FsShare
+	result := database.DB.Where(&FsShare{Model: Model{ID: id}, FsID: fsID}).Find(&share)
+	return share, result.Error
+}
+
+// DeleteFsShare deletes the share of fs by id
+func DeleteFsShare(fsID, id string) error {
+	return database.DB.Where(fmt.Sprintf(QueryEqualWithParam, FsID), fsID).
+		Where(fmt.Sprintf(QueryEqualWithParam, ID), id).Delete(&FsShare{}).Error
+}
+
+// ListFsShares lists the shares of fs sort by create_at desc
+func ListFsShares(fsID string) ([]FsShare, error) {
+	var shares []FsShare
+	result := database.DB.Where(&FsShare{FsID: fsID}).Order(fmt.Sprintf(" %s %s ", CreatedAt, DESC)).Find(&shares)
+	return shares, result.Error
+}
+
+// ListFsSharesTo lists the shares of fs to the user or any of the groups
+func ListFsSharesTo(fsID, userName string, groups []string) ([]FsShare, error) {
+	var shares []FsShare
+	query := database.DB.Where(fmt.Sprintf(QueryEqualWithParam, FsID), fsID)
+	toUser := database.DB.Where(fmt.Sprintf(QueryEqualWithParam, ShareType), FsShareToUser).
+		Where(fmt.Sprintf(QueryEqualWithParam, ShareTo), userName)
+	if len(groups) == 0 {
+		query = query.Where(toUser)
+	} else {
+		toGroups := database.DB.Where(fmt.Sprintf(QueryEqualWithParam, ShareType), FsShareToGroup).
+			Where(fmt.Sprintf(QueryInWithParam, ShareTo), groups)
+		query = query.Where(toUser.Or(toGroups))
+	}
+	result := query.Find(&shares)
+	return shares, result.Error
+}
@@ -115,3 +115,67 @@ func GetLinkWithFsIDFsPathAndUserName(fsID, fsPath, userName string) ([]Link, er
 	result := db.Where(&Link{UserName: userName, FsID: fsID, FsPath: fsPath}).Find(&links)
 	return links, result.Error
 }
+
+// CreateLinkWithVersion creates a link and increases the link version of its fs in a transaction.
+// The update of the version locks the row of the fs until committed, so check is called with the
+// links of the fs which are not changed by other servers meanwhile.
+func CreateLinkWithVersion(link *Link, check func(links []Link) error) (int64, error) {
+	var version int64
+	err := withTransaction(database.DB, func(tx *gorm.DB) error {
+		var err error
+		version, err = increaseLinkVersion(tx, link.FsID)
+		if err != nil {
+			return err
+		}
+		var links []Link
+		if err = tx.Where(&Link{FsID: link.FsID}).Find(&links).Error; err != nil {
+			return err
+		}
+		if check != nil {
+			if err = check(links); err != nil {
+				return err
+			}
+		}
+		return tx.Create(link).Error
+	})
+	return version, err
+}
+
+// DeleteLinkWithVersion deletes a link and increases the link version of its fs in a transaction
+func DeleteLinkWithVersion(fsID, fsPath string) (int64, error) {
+	var version int64
+	err := withTransaction(database.DB, func(tx *gorm.DB) error {
+		var err error
+		version, err = increaseLinkVersion(tx, fsID)
+		if err != nil {
+			return err
+		}
+		return tx.Where(fmt.Sprintf(QueryEqualWithParam, FsID), fsID).Where(fmt.Sprintf(QueryEqualWithParam, FsPath), fsPath).
+			Delete(&Link{}).Error
+	})
+	return version, err
+}
+
+// GetLinkVersion returns the link version of fs
+func GetLinkVersion(fsID string) (int64, error) {
+	var fileSystem FileSystem
+	db := database.DB
+	result := db.Select(LinkVersion).Where(fmt.Sprintf(QueryEqualWithParam, ID), fsID).First(&fileSystem)
+	return fileSystem.LinkVersion, result.Error
+}
+
+func increaseLinkVersion(tx *gorm.DB, fsID string) (int64, error) {
+	result := tx.Model(&FileSystem{}).Where(fmt.Sprintf(QueryEqualWithParam, ID), fsID).
+		UpdateColumn(LinkVersion, gorm.Expr(LinkVersion+" + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, gorm.ErrRecordNotFound
+	}
+	var fileSystem FileSystem
+	if err := tx.Select(LinkVersion).Where(fmt.Sprintf(QueryEqualWithParam, ID), fsID).First(&fileSystem).Error; err != nil {
+		return 0, err
+	}
+	return fileSystem.LinkVersion, nil
+}
@@ -41,6 +41,11 @@ type FileSystem struct {
 	PropertiesJson string            `json:"-" gorm:"column:properties;type:text;default:'{}'"`
 	PropertiesMap  map[string]string `json:"properties" gorm:"-"`
 	UserName       string            `json:"userName"`
+	// MountOptions are the default mount options of the pvs created for the fs
+	MountOptionsJson string            `json:"-" gorm:"column:mount_options;type:text;default:'{}'"`
+	MountOptionsMap  map[string]string `json:"mountOptions" gorm:"-"`
+	// LinkVersion is increased on each change of the links of the fs
+	LinkVersion int64 `json:"linkVersion" gorm:"column:link_version;default:0"`
 }
 
 func (FileSystem) TableName() string {
@@ -56,6 +61,13 @@ func (s *FileSystem) AfterFind(*gorm.DB) error {
 			return err
 		}
 	}
+	if s.MountOptionsJson != "" {
+		s.MountOptionsMap = make(map[string]string)
+		if err := json.Unmarshal([]byte(s.MountOptionsJson), &s.MountOptionsMap); err != nil {
+			log.Errorf("json Unmarshal mountOptionsJson[%s] failed: %v", s.MountOptionsJson, err)
+			return err
+		}
+	}
 	return nil
 }
 
@@ -67,6 +79,12 @@ func (s *FileSystem) BeforeSave(*gorm.DB) error {
 		return err
 	}
 	s.PropertiesJson = string(propertiesJson)
+	mountOptionsJson, err := json.Marshal(&s.MountOptionsMap)
+	if err != nil {
+		log.Errorf("json Marshal mountOptionsMap[%v] failed: %v", s.MountOptionsMap, err)
+		return err
+	}
+	s.MountOptionsJson = string(mountOptionsJson)
 	return nil
 }
 
@@ -109,6 +127,10 @@ func DeleteFileSystem(id string) error {
 		if err := tx.Where(fmt.Sprintf(QueryEqualWithParam, FsID), id).Delete(&Link{}).Error; err != nil {
 			return err
 		}
+
+		if err := tx.Where(fmt.Sprintf(QueryEqualWithParam, FsID), id).Delete(&FsShare{}).Error; err != nil {
+			return err
+		}
 		return nil
 	})
 }
@@ -17,6 +17,7 @@ limitations under the License.
 package models
 
 import (
+	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -35,6 +36,7 @@ type Pipeline struct {
 	UserName     string         `json:"username"             gorm:"type:varchar(60);not null"`
 	PipelineYaml string         `json:"pipelineYaml"         gorm:"type:text;size:65535"`
 	PipelineMd5  string         `json:"pipelineMd5"          gorm:"type:varchar(32);not null;uniqueIndex:idx_fs_md5"`
+	Version      int            `json:"version"              gorm:"type:int;not null;default:1"`
 	CreateTime   string         `json:"createTime"           gorm:"-"`
 	UpdateTime   string         `json:"updateTime,omitempty" gorm:"-"`
 	CreatedAt    time.Time      `json:"-"`
@@ -69,11 +71,74 @@ func CreatePipeline(logEntry *log.Entry, ppl *Pipeline) (string, error) {
 			logEntry.Errorf("back filling pplID failed. pk[%d], error:%v", ppl.Pk, result.Error)
 			return result.Error
 		}
+		// record the first version of pipeline yaml
+		if ppl.Version == 0 {
+			ppl.Version = 1
+		}
+		pplVersion := PipelineVersion{
+			PipelineID:   ppl.ID,
+			Version:      ppl.Version,
+			UserName:     ppl.UserName,
+			PipelineYaml: ppl.PipelineYaml,
+			PipelineMd5:  ppl.PipelineMd5,
+		}
+		result = tx.Model(&PipelineVersion{}).Create(&pplVersion)
+		if result.Error != nil {
+			logEntry.Errorf("create pipeline version failed. pplID[%s], error:%v", ppl.ID, result.Error)
+			return result.Error
+		}
 		return nil
 	})
 	return ppl.ID, err
 }
 
+// ErrPipelineVersionConflict is returned if the pipeline is updated by others after it is read
+var ErrPipelineVersionConflict = errors.New("pipeline has been updated concurrently, please retry")
+
+// UpdatePipelineYaml appends a new version to pipeline and makes it the current definition
+func UpdatePipelineYaml(logEntry *log.Entry, ppl *Pipeline, pipelineYaml, pipelineMd5, userName string) error {
+	logEntry.Debugf("begin update pipeline[%s] yaml, current version[%d]", ppl.ID, ppl.Version)
+	newVersion := ppl.Version + 1
+	err := withTransaction(database.DB, func(tx *gorm.DB) error {
+		// compare and swap on version before the version is appended, in case of concurrent updates
+		result := tx.Model(&Pipeline{}).Where("id = ? AND version = ?", ppl.ID, ppl.Version).Updates(map[string]interface{}{
+			"pipeline_yaml": pipelineYaml,
+			"pipeline_md5":  pipelineMd5,
+			"version":       newVersion,
+		})
+		if result.Error != nil {
+			logEntry.Errorf("update pipeline[%s] yaml failed. error:%v", ppl.ID, result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			logEntry.Errorf("update pipeline[%s] from version[%d] failed. error:%v", ppl.ID, ppl.Version,
+				ErrPipelineVersionConflict)
+			return ErrPipelineVersionConflict
+		}
+		pplVersion := PipelineVersion{
+			PipelineID:   ppl.ID,
+			Version:      newVersion,
+			UserName:     userName,
+			PipelineYaml: pipelineYaml,
+			PipelineMd5:  pipelineMd5,
+		}
+		result = tx.Model(&PipelineVersion{}).Create(&pplVersion)
+		if result.Error != nil {
+			logEntry.Errorf("create pipeline version failed. pplID[%s], version[%d], error:%v",
+				ppl.ID, newVersion, result.Error)
+			return result.Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	ppl.PipelineYaml = pipelineYaml
+	ppl.PipelineMd5 = pipelineMd5
+	ppl.Version = newVersion
+	return nil
+}
+
 func CountPipelineByNameInFs(name, fsID string) (int64, error) {
 	var count int64
 	result := database.DB.Model(&Pipeline{}).Where(&Pipeline{Name: name, FsID: fsID}).Count(&count)
@@ -142,5 +207,14 @@ func GetLastPipeline(logEntry *log.Entry) (Pipeline, error) {
 
 func HardDeletePipeline(logEntry *log.Entry, id string) error {
 	logEntry.Debugf("delete ppl: %s", id)
-	return database.DB.Unscoped().Where("id = ?", id).Delete(&Pipeline{}).Error
+	return withTransaction(database.DB, func(tx *gorm.DB) error {
+		// the versions still referenced by runs are kept, so that the runs can be traced back to their definitions
+		usedVersions := tx.Model(&Run{}).Select("ppl_version").Where("source = ?", id)
+		if err := tx.Unscoped().Where("pipeline_id = ?", id).Where("version NOT IN (?)", usedVersions).
+			Delete(&PipelineVersion{}).Error; err != nil {
+			logEntry.Errorf("delete versions of ppl[%s] failed. error:%v", id, err)
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", id).Delete(&Pipeline{}).Error
+	})
 }
@@ -0,0 +1,106 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/common/database"
+)
+
+// PipelineVersion keeps every revision of a pipeline yaml, so that runs can be traced back to their definitions
+type PipelineVersion struct {
+	Pk           int64          `json:"-"                    gorm:"primaryKey;autoIncrement;not null"`
+	PipelineID   string         `json:"pipelineID"           gorm:"type:varchar(60);not null;uniqueIndex:idx_ppl_version"`
+	Version      int            `json:"version"              gorm:"type:int;not null;uniqueIndex:idx_ppl_version"`
+	UserName     string         `json:"username"             gorm:"type:varchar(60);not null"`
+	PipelineYaml string         `json:"pipelineYaml"         gorm:"type:text;size:65535"`
+	PipelineMd5  string         `json:"pipelineMd5"          gorm:"type:varchar(32);not null"`
+	CreateTime   string         `json:"createTime"           gorm:"-"`
+	CreatedAt    time.Time      `json:"-"`
+	UpdatedAt    time.Time      `json:"-"`
+	DeletedAt    gorm.DeletedAt `json:"-"                    gorm:"index"`
+}
+
+func (PipelineVersion) TableName() string {
+	return "pipeline_version"
+}
+
+func (pv *PipelineVersion) Decode() error {
+	// format time
+	pv.CreateTime = pv.CreatedAt.Format("2006-01-02 15:04:05")
+	return nil
+}
+
+func GetPipelineVersion(pipelineID string, version int) (PipelineVersion, error) {
+	var pplVersion PipelineVersion
+	result := database.DB.Model(&PipelineVersion{}).
+		Where(&PipelineVersion{PipelineID: pipelineID, Version: version}).Last(&pplVersion)
+	if result.Error != nil {
+		return PipelineVersion{}, result.Error
+	}
+	pplVersion.Decode()
+	return pplVersion, nil
+}
+
+func ListPipelineVersion(logEntry *log.Entry, pipelineID string) ([]PipelineVersion, error) {
+	logEntry.Debugf("begin list versions of pipeline[%s]", pipelineID)
+	var versionList []PipelineVersion
+	tx := database.DB.Model(&PipelineVersion{}).Where("pipeline_id = ?", pipelineID).
+		Order("version").Find(&versionList)
+	if tx.Error != nil {
+		logEntry.Errorf("list versions of pipeline[%s] failed. error:%v", pipelineID, tx.Error)
+		return []PipelineVersion{}, tx.Error
+	}
+	for i := range versionList {
+		versionList[i].Decode()
+	}
+	return versionList, nil
+}
+
+// BackfillPipelineVersions records the current yaml of the pipelines created before versioning as their
+// current versions, so that the versions of all pipelines can be listed and diffed
+func BackfillPipelineVersions(db *gorm.DB) (int, error) {
+	var pplList []Pipeline
+	result := db.Model(&Pipeline{}).Where("NOT EXISTS (SELECT 1 FROM pipeline_version " +
+		"WHERE pipeline_version.pipeline_id = pipeline.id AND pipeline_version.version = pipeline.version)").
+		Find(&pplList)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	for _, ppl := range pplList {
+		version := ppl.Version
+		if version == 0 {
+			version = 1
+		}
+		pplVersion := PipelineVersion{
+			PipelineID:   ppl.ID,
+			Version:      version,
+			UserName:     ppl.UserName,
+			PipelineYaml: ppl.PipelineYaml,
+			PipelineMd5:  ppl.PipelineMd5,
+			CreatedAt:    ppl.CreatedAt,
+		}
+		if err := db.Model(&PipelineVersion{}).Create(&pplVersion).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(pplList), nil
+}
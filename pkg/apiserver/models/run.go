@@ -36,6 +36,7 @@ type Run struct {
 	ID             string                 `gorm:"type:varchar(60);not null"         json:"runID"`
 	Name           string                 `gorm:"type:varchar(60);not null"         json:"name"`
 	Source         string                 `gorm:"type:varchar(256);not null"        json:"source"` // pipelineID or yamlPath
+	PplVersion     int                    `gorm:"type:int;default:0"                json:"pipelineVersion,omitempty"`
 	UserName       string                 `gorm:"type:varchar(60);not null"         json:"username"`
 	FsID           string                 `gorm:"type:varchar(60);not null"         json:"-"`
 	FsName         string                 `gorm:"type:varchar(60);not null"         json:"fsname"`
@@ -162,13 +163,19 @@ func UpdateRun(logEntry *log.Entry, runID string, run Run) error {
 
 func DeleteRun(logEntry *log.Entry, runID string) error {
 	logEntry.Debugf("begin delete run. runID:%s", runID)
-	tx := database.DB.Model(&Run{}).Unscoped().Where("id = ?", runID).Delete(&Run{})
-	if tx.Error != nil {
-		logEntry.Errorf("delete run failed. runID:%s, error:%s",
-			runID, tx.Error.Error())
-		return tx.Error
-	}
-	return nil
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RunEvent{}).Where("run_id = ?", runID).Delete(&RunEvent{}).Error; err != nil {
+			logEntry.Errorf("delete events of run failed. runID:%s, error:%s",
+				runID, err.Error())
+			return err
+		}
+		if err := tx.Model(&Run{}).Unscoped().Where("id = ?", runID).Delete(&Run{}).Error; err != nil {
+			logEntry.Errorf("delete run failed. runID:%s, error:%s",
+				runID, err.Error())
+			return err
+		}
+		return nil
+	})
 }
 
 func GetRunByID(logEntry *log.Entry, runID string) (Run, error) {
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/common/database"
+)
+
+// RunEvent is the persisted log of workflow events processed by run, the auto increment pk serves as event id
+type RunEvent struct {
+	Pk         int64                  `json:"id"         gorm:"primaryKey;autoIncrement;not null"`
+	RunID      string                 `json:"runID"      gorm:"type:varchar(60);not null;index"`
+	Type       string                 `json:"type"       gorm:"type:varchar(32);not null"`
+	Event      string                 `json:"event"      gorm:"type:varchar(32);not null"`
+	Message    string                 `json:"message"    gorm:"type:text;size:65535"`
+	ExtraRaw   string                 `json:"-"          gorm:"type:text;size:65535"`
+	Extra      map[string]interface{} `json:"extra"      gorm:"-"`
+	CreateTime string                 `json:"createTime" gorm:"-"`
+	CreatedAt  time.Time              `json:"-"`
+}
+
+func (RunEvent) TableName() string {
+	return "run_event"
+}
+
+func (e *RunEvent) Encode() error {
+	if e.Extra != nil {
+		extraRaw, err := json.Marshal(e.Extra)
+		if err != nil {
+			return err
+		}
+		e.ExtraRaw = string(extraRaw)
+	}
+	return nil
+}
+
+func (e *RunEvent) Decode() error {
+	if len(e.ExtraRaw) > 0 {
+		extra := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(e.ExtraRaw), &extra); err != nil {
+			return err
+		}
+		e.Extra = extra
+	}
+	e.CreateTime = e.CreatedAt.Format("2006-01-02 15:04:05")
+	return nil
+}
+
+func CreateRunEvent(logEntry *log.Entry, event *RunEvent) error {
+	if err := event.Encode(); err != nil {
+		logEntry.Errorf("encode event[%s] of run[%s] failed. error:%v", event.Event, event.RunID, err)
+		return err
+	}
+	tx := database.DB.Model(&RunEvent{}).Create(event)
+	if tx.Error != nil {
+		logEntry.Errorf("create event[%s] of run[%s] failed. error:%s", event.Event, event.RunID, tx.Error.Error())
+		return tx.Error
+	}
+	event.CreateTime = event.CreatedAt.Format("2006-01-02 15:04:05")
+	return nil
+}
+
+// ListRunEvent returns events of run after the event id of lastEventID in order
+func ListRunEvent(logEntry *log.Entry, runID string, lastEventID int64) ([]RunEvent, error) {
+	logEntry.Debugf("begin list events of run[%s] after event[%d]", runID, lastEventID)
+	var events []RunEvent
+	tx := database.DB.Model(&RunEvent{}).Where("run_id = ? and pk > ?", runID, lastEventID).
+		Order("pk").Find(&events)
+	if tx.Error != nil {
+		logEntry.Errorf("list events of run[%s] failed. error:%s", runID, tx.Error.Error())
+		return nil, tx.Error
+	}
+	for i := range events {
+		if err := events[i].Decode(); err != nil {
+			logEntry.Errorf("decode event[%d] of run[%s] failed. error:%v", events[i].Pk, runID, err)
+			return nil, err
+		}
+	}
+	return events, nil
+}
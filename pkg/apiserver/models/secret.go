@@ -0,0 +1,120 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/logger"
+)
+
+// Secret is a user defined secret value, which is stored encrypted and can be referenced by job env as ${secret:name}
+type Secret struct {
+	Pk          int64          `json:"-"                    gorm:"primaryKey;autoIncrement"`
+	Name        string         `json:"name"                 gorm:"type:varchar(63);not null;uniqueIndex:idx_user_secret"`
+	UserName    string         `json:"userName"             gorm:"type:varchar(60);not null;uniqueIndex:idx_user_secret"`
+	Value       string         `json:"-"                    gorm:"type:text;size:65535;not null"`
+	Description string         `json:"description"          gorm:"type:varchar(256)"`
+	CreatedAt   time.Time      `json:"createTime"`
+	UpdatedAt   time.Time      `json:"updateTime,omitempty"`
+	DeletedAt   gorm.DeletedAt `json:"-"                    gorm:"index"`
+}
+
+func (Secret) TableName() string {
+	return "secret"
+}
+
+func CreateSecret(ctx *logger.RequestContext, secret *Secret) error {
+	ctx.Logging().Debugf("model begin create secret. userName:%s, name:%s", secret.UserName, secret.Name)
+	tx := database.DB.Table("secret").Create(secret)
+	if tx.Error != nil {
+		ctx.Logging().Errorf("create secret failed. userName:%s, name:%s, error:%s",
+			secret.UserName, secret.Name, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func UpdateSecret(ctx *logger.RequestContext, secret *Secret) error {
+	ctx.Logging().Debugf("model begin update secret. userName:%s, name:%s", secret.UserName, secret.Name)
+	tx := database.DB.Table("secret").Where("user_name = ? and name = ?", secret.UserName, secret.Name).
+		Updates(map[string]interface{}{"value": secret.Value, "description": secret.Description})
+	if tx.Error != nil {
+		ctx.Logging().Errorf("update secret failed. userName:%s, name:%s, error:%s",
+			secret.UserName, secret.Name, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func DeleteSecret(ctx *logger.RequestContext, userName, name string) error {
+	ctx.Logging().Debugf("model begin delete secret. userName:%s, name:%s", userName, name)
+	tx := database.DB.Unscoped().Table("secret").Where("user_name = ? and name = ?", userName, name).Delete(&Secret{})
+	if tx.Error != nil {
+		ctx.Logging().Errorf("delete secret failed. userName:%s, name:%s, error:%s",
+			userName, name, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func GetSecret(ctx *logger.RequestContext, userName, name string) (*Secret, error) {
+	ctx.Logging().Debugf("model begin get secret. userName:%s, name:%s", userName, name)
+	var secret Secret
+	tx := database.DB.Table("secret").Where("user_name = ? and name = ?", userName, name).First(&secret)
+	if tx.Error != nil {
+		ctx.Logging().Errorf("get secret failed. userName:%s, name:%s, error:%s",
+			userName, name, tx.Error.Error())
+		return nil, tx.Error
+	}
+	return &secret, nil
+}
+
+func ListSecret(ctx *logger.RequestContext, pk int64, maxKeys int, userName string) ([]Secret, error) {
+	ctx.Logging().Debugf("model begin list secrets. userName:%s", userName)
+	query := database.DB.Table("secret")
+	query.Where("pk > ?", pk)
+	if maxKeys > 0 {
+		query.Limit(maxKeys)
+	}
+	if userName != "" {
+		query.Where("user_name = ?", userName)
+	}
+	var secrets []Secret
+	if err := query.Find(&secrets).Error; err != nil {
+		ctx.Logging().Errorf("list secret failed. userName:%s, error:%s", userName, err.Error())
+		return nil, err
+	}
+	return secrets, nil
+}
+
+func GetLastSecret(ctx *logger.RequestContext, userName string) (Secret, error) {
+	ctx.Logging().Debugf("model get last secret. userName:%s", userName)
+	secret := Secret{}
+	query := database.DB.Table("secret")
+	if userName != "" {
+		query.Where("user_name = ?", userName)
+	}
+	if err := query.Last(&secret).Error; err != nil {
+		ctx.Logging().Errorf("get last secret failed. error:%s", err.Error())
+		return Secret{}, err
+	}
+	return secret, nil
+}
@@ -0,0 +1,92 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/logger"
+)
+
+// UserGroup records that a user is a member of a group, the fs can be shared with the users in a group
+type UserGroup struct {
+	Pk        int64     `json:"-" gorm:"primaryKey;autoIncrement"`
+	GroupName string    `json:"groupName" gorm:"uniqueIndex:idx_group_user"`
+	UserName  string    `json:"userName" gorm:"uniqueIndex:idx_group_user"`
+	CreatedAt time.Time `json:"createTime"`
+}
+
+func (UserGroup) TableName() string {
+	return "user_group"
+}
+
+func AddUserToGroup(ctx *logger.RequestContext, userName, groupName string) error {
+	ctx.Logging().Debugf("model begin add user to group. userName:%s, groupName:%s", userName, groupName)
+	var num int64
+	tx := database.DB.Table("user_group").Where("group_name = ? and user_name = ?", groupName, userName).Count(&num)
+	if tx.Error != nil {
+		ctx.Logging().Errorf("model count user group failed. userName:%s, groupName:%s, error:%s",
+			userName, groupName, tx.Error.Error())
+		return tx.Error
+	}
+	if num > 0 {
+		return nil
+	}
+	tx = database.DB.Table("user_group").Create(&UserGroup{GroupName: groupName, UserName: userName})
+	if tx.Error != nil {
+		ctx.Logging().Errorf("model add user to group failed. userName:%s, groupName:%s, error:%s",
+			userName, groupName, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func DeleteUserFromGroup(ctx *logger.RequestContext, userName, groupName string) error {
+	ctx.Logging().Debugf("model begin delete user from group. userName:%s, groupName:%s", userName, groupName)
+	tx := database.DB.Table("user_group").Where("group_name = ? and user_name = ?", groupName, userName).Delete(&UserGroup{})
+	if tx.Error != nil {
+		ctx.Logging().Errorf("model delete user from group failed. userName:%s, groupName:%s, error:%s",
+			userName, groupName, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func DeleteUserGroupsByUserName(ctx *logger.RequestContext, userName string) error {
+	ctx.Logging().Debugf("model begin delete user groups by userName. userName:%s", userName)
+	err := database.DB.Table("user_group").Where("user_name = ?", userName).Delete(&UserGroup{}).Error
+	if err != nil {
+		ctx.Logging().Errorf("model delete user groups failed. userName:%s, error:%s", userName, err.Error())
+		return err
+	}
+	return nil
+}
+
+// ListGroupsOfUser lists the names of the groups which the user is in
+func ListGroupsOfUser(userName string) ([]string, error) {
+	var groups []string
+	err := database.DB.Table("user_group").Where("user_name = ?", userName).Pluck("group_name", &groups).Error
+	return groups, err
+}
+
+// ListUsersOfGroup lists the names of the users in the group
+func ListUsersOfGroup(groupName string) ([]string, error) {
+	var users []string
+	err := database.DB.Table("user_group").Where("group_name = ?", groupName).Pluck("user_name", &users).Error
+	return users, err
+}
@@ -0,0 +1,255 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/logger"
+)
+
+const (
+	WebhookTypeHTTP  = "webhook"
+	WebhookTypeEmail = "email"
+	WebhookTypeIM    = "im"
+
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Webhook subscribes run and step status events of a user, or of a pipeline if PipelineID is set
+type Webhook struct {
+	Pk          int64          `json:"-"                    gorm:"primaryKey;autoIncrement"`
+	ID          string         `json:"webhookID"            gorm:"type:varchar(60);uniqueIndex"`
+	UserName    string         `json:"userName"             gorm:"type:varchar(60);not null;index"`
+	PipelineID  string         `json:"pipelineID,omitempty" gorm:"type:varchar(60)"`
+	Type        string         `json:"type"                 gorm:"type:varchar(32);not null"`
+	Address     string         `json:"address"              gorm:"type:varchar(1024);not null"` // url for webhook and im, receivers for email
+	Secret      string         `json:"-"                    gorm:"type:text;size:65535"`        // encrypted key for signing payload
+	EventsRaw   string         `json:"-"                    gorm:"type:text;size:65535"`
+	Events      []string       `json:"events"               gorm:"-"`
+	Description string         `json:"description"          gorm:"type:varchar(256)"`
+	CreatedAt   time.Time      `json:"createTime"`
+	UpdatedAt   time.Time      `json:"updateTime,omitempty"`
+	DeletedAt   gorm.DeletedAt `json:"-"                    gorm:"index"`
+}
+
+func (Webhook) TableName() string {
+	return "webhook"
+}
+
+func (w *Webhook) Encode() {
+	w.EventsRaw = strings.Join(w.Events, common.SeparatorComma)
+}
+
+func (w *Webhook) Decode() {
+	w.Events = []string{}
+	if w.EventsRaw != "" {
+		w.Events = strings.Split(w.EventsRaw, common.SeparatorComma)
+	}
+}
+
+// WebhookDelivery is the delivery log of an event sent to webhook
+type WebhookDelivery struct {
+	Pk           int64     `json:"-"            gorm:"primaryKey;autoIncrement"`
+	ID           string    `json:"deliveryID"   gorm:"type:varchar(60);uniqueIndex"`
+	WebhookID    string    `json:"webhookID"    gorm:"type:varchar(60);not null;index"`
+	RunID        string    `json:"runID"        gorm:"type:varchar(60);not null"`
+	Event        string    `json:"event"        gorm:"type:varchar(64);not null"`
+	Payload      string    `json:"payload"      gorm:"type:text;size:65535"`
+	Status       string    `json:"status"       gorm:"type:varchar(32);not null"`
+	Attempts     int       `json:"attempts"     gorm:"type:int;default:0"`
+	ResponseCode int       `json:"responseCode" gorm:"type:int;default:0"`
+	Message      string    `json:"message"      gorm:"type:text;size:65535"`
+	NextRetryAt  time.Time `json:"-"            gorm:"index"` // pending delivery is sent after this time
+	CreatedAt    time.Time `json:"createTime"`
+	UpdatedAt    time.Time `json:"updateTime"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
+
+func CreateWebhook(ctx *logger.RequestContext, webhook *Webhook) error {
+	ctx.Logging().Debugf("model begin create webhook. webhookID:%s", webhook.ID)
+	webhook.Encode()
+	tx := database.DB.Table("webhook").Create(webhook)
+	if tx.Error != nil {
+		ctx.Logging().Errorf("create webhook failed. webhookID:%s, error:%s",
+			webhook.ID, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func GetWebhookByID(ctx *logger.RequestContext, webhookID string) (*Webhook, error) {
+	ctx.Logging().Debugf("model begin get webhook. webhookID:%s", webhookID)
+	var webhook Webhook
+	tx := database.DB.Table("webhook").Where("id = ?", webhookID).First(&webhook)
+	if tx.Error != nil {
+		ctx.Logging().Errorf("get webhook failed. webhookID:%s, error:%s",
+			webhookID, tx.Error.Error())
+		return nil, tx.Error
+	}
+	webhook.Decode()
+	return &webhook, nil
+}
+
+func DeleteWebhook(ctx *logger.RequestContext, webhookID string) error {
+	ctx.Logging().Debugf("model begin delete webhook. webhookID:%s", webhookID)
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Table("webhook_delivery").Where("webhook_id = ?", webhookID).Delete(&WebhookDelivery{}).Error; err != nil {
+			ctx.Logging().Errorf("delete deliveries of webhook[%s] failed. error:%s", webhookID, err.Error())
+			return err
+		}
+		if err := tx.Unscoped().Table("webhook").Where("id = ?", webhookID).Delete(&Webhook{}).Error; err != nil {
+			ctx.Logging().Errorf("delete webhook[%s] failed. error:%s", webhookID, err.Error())
+			return err
+		}
+		return nil
+	})
+}
+
+func ListWebhook(ctx *logger.RequestContext, pk int64, maxKeys int, userName string) ([]Webhook, error) {
+	ctx.Logging().Debugf("model begin list webhooks. userName:%s", userName)
+	query := database.DB.Table("webhook")
+	query.Where("pk > ?", pk)
+	if maxKeys > 0 {
+		query.Limit(maxKeys)
+	}
+	if userName != "" {
+		query.Where("user_name = ?", userName)
+	}
+	var webhooks []Webhook
+	if err := query.Find(&webhooks).Error; err != nil {
+		ctx.Logging().Errorf("list webhook failed. userName:%s, error:%s", userName, err.Error())
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Decode()
+	}
+	return webhooks, nil
+}
+
+func GetLastWebhook(ctx *logger.RequestContext, userName string) (Webhook, error) {
+	ctx.Logging().Debugf("model get last webhook. userName:%s", userName)
+	webhook := Webhook{}
+	query := database.DB.Table("webhook")
+	if userName != "" {
+		query.Where("user_name = ?", userName)
+	}
+	if err := query.Last(&webhook).Error; err != nil {
+		ctx.Logging().Errorf("get last webhook failed. error:%s", err.Error())
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// ListWebhookForRun returns webhooks of run owner, which subscribe all pipelines or the pipeline of run.
+// Webhooks of root only receive runs of other users if they subscribe the pipeline of run explicitly.
+func ListWebhookForRun(logEntry *log.Entry, userName, pipelineID string) ([]Webhook, error) {
+	logEntry.Debugf("model begin list webhooks for run. userName:%s, pipelineID:%s", userName, pipelineID)
+	var webhooks []Webhook
+	tx := database.DB.Table("webhook").
+		Where("(user_name = ? and (pipeline_id = '' or pipeline_id = ?)) or (user_name = ? and pipeline_id != '' and pipeline_id = ?)",
+			userName, pipelineID, ROOT, pipelineID).Find(&webhooks)
+	if tx.Error != nil {
+		logEntry.Errorf("list webhooks for run failed. error:%s", tx.Error.Error())
+		return nil, tx.Error
+	}
+	for i := range webhooks {
+		webhooks[i].Decode()
+	}
+	return webhooks, nil
+}
+
+func CreateWebhookDelivery(logEntry *log.Entry, delivery *WebhookDelivery) error {
+	tx := database.DB.Table("webhook_delivery").Create(delivery)
+	if tx.Error != nil {
+		logEntry.Errorf("create delivery of webhook[%s] failed. error:%s", delivery.WebhookID, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+func UpdateWebhookDelivery(logEntry *log.Entry, delivery *WebhookDelivery) error {
+	tx := database.DB.Table("webhook_delivery").Where("id = ?", delivery.ID).
+		Updates(map[string]interface{}{
+			"status":        delivery.Status,
+			"attempts":      delivery.Attempts,
+			"response_code": delivery.ResponseCode,
+			"message":       delivery.Message,
+			"next_retry_at": delivery.NextRetryAt,
+		})
+	if tx.Error != nil {
+		logEntry.Errorf("update delivery[%s] failed. error:%s", delivery.ID, tx.Error.Error())
+		return tx.Error
+	}
+	return nil
+}
+
+// ListPendingWebhookDelivery returns pending deliveries which are due to be sent before now
+func ListPendingWebhookDelivery(logEntry *log.Entry, now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	tx := database.DB.Table("webhook_delivery").Where("status = ? and next_retry_at <= ?", DeliveryStatusPending, now).
+		Order("next_retry_at").Limit(limit).Find(&deliveries)
+	if tx.Error != nil {
+		logEntry.Errorf("list pending deliveries failed. error:%s", tx.Error.Error())
+		return nil, tx.Error
+	}
+	return deliveries, nil
+}
+
+// ClaimWebhookDelivery postpones the next retry of a due delivery to leaseUntil, and returns false if the delivery
+// has been claimed by others already, so that a delivery is sent by one server at a time.
+func ClaimWebhookDelivery(logEntry *log.Entry, delivery *WebhookDelivery, now, leaseUntil time.Time) (bool, error) {
+	tx := database.DB.Table("webhook_delivery").
+		Where("id = ? and status = ? and attempts = ? and next_retry_at <= ?", delivery.ID, DeliveryStatusPending,
+			delivery.Attempts, now).
+		Update("next_retry_at", leaseUntil)
+	if tx.Error != nil {
+		logEntry.Errorf("claim delivery[%s] failed. error:%s", delivery.ID, tx.Error.Error())
+		return false, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.NextRetryAt = leaseUntil
+	return true, nil
+}
+
+func ListWebhookDelivery(ctx *logger.RequestContext, webhookID string, pk int64, maxKeys int) ([]WebhookDelivery, error) {
+	ctx.Logging().Debugf("model begin list deliveries of webhook[%s]", webhookID)
+	query := database.DB.Table("webhook_delivery").Where("webhook_id = ?", webhookID)
+	query.Where("pk > ?", pk)
+	if maxKeys > 0 {
+		query.Limit(maxKeys)
+	}
+	var deliveries []WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		ctx.Logging().Errorf("list deliveries of webhook[%s] failed. error:%s", webhookID, err.Error())
+		return nil, err
+	}
+	return deliveries, nil
+}
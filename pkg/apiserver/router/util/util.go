@@ -36,6 +36,9 @@ const (
 	ParamKeyRunID      = "runID"
 	ParamKeyRunCacheID = "runCacheID"
 	ParamKeyPipelineID = "pipelineID"
+	ParamKeySecretName = "secretName"
+	ParamKeyWebhookID  = "webhookID"
+	ParamKeyGroupName  = "groupName"
 
 	QueryKeyAction   = "action"
 	QueryActionStop  = "stop"
@@ -45,6 +48,12 @@ const (
 	QueryKeyMarker  = "marker"
 	QueryKeyMaxKeys = "maxKeys"
 
+	QueryKeyFromVersion = "from"
+	QueryKeyToVersion   = "to"
+
+	QueryKeyLastEventID  = "lastEventID"
+	HeaderKeyLastEventID = "Last-Event-ID"
+
 	QueryKeyUserFilter = "userFilter"
 	QueryKeyFsFilter   = "fsFilter"
 	QueryKeyNameFilter = "nameFilter"
@@ -66,6 +75,11 @@ const (
 	QueryFsname = "fsname"
 	QueryPath   = "path"
 
+	QueryShareID = "shareID"
+
+	QueryLinkVersion  = "version"
+	QueryWatchTimeout = "timeout"
+
 	// cluster name最大长度
 	ClusterNameMaxLength = 255
 )
@@ -44,6 +44,9 @@ func (pr *PipelineRouter) AddRouter(r chi.Router) {
 	r.Post("/pipeline", pr.createPipeline)
 	r.Get("/pipeline", pr.listPipeline)
 	r.Get("/pipeline/{pipelineID}", pr.getPipeline)
+	r.Put("/pipeline/{pipelineID}", pr.updatePipeline)
+	r.Get("/pipeline/{pipelineID}/version", pr.listPipelineVersion)
+	r.Get("/pipeline/{pipelineID}/diff", pr.diffPipelineVersion)
 	r.Delete("/pipeline/{pipelineID}", pr.deletePipeline)
 }
 
@@ -107,7 +110,7 @@ func (pr *PipelineRouter) createPipeline(w http.ResponseWriter, r *http.Request)
 // @Accept  json
 // @Produce json
 // @Param pipelineID path string true "工作流ID"
-// @Success 201 {object} models.Pipeline "工作流结构体"
+// @Success 200 {object} pipeline.GetPipelineResponse "工作流结构体及参数定义"
 // @Failure 400 {object} common.ErrorResponse "400"
 // @Failure 500 {object} common.ErrorResponse "500"
 // @Router /pipeline/{pipelineID} [GET]
@@ -124,6 +127,108 @@ func (pr *PipelineRouter) getPipeline(w http.ResponseWriter, r *http.Request) {
 	common.Render(w, http.StatusOK, ppl)
 }
 
+// updatePipeline
+// @Summary 更新工作流，生成新版本
+// @Description 重新读取fs中的工作流yaml，作为工作流的新版本，yaml中的name须与工作流名称一致。工作流被并发更新时返回409，需重试
+// @Id updatePipeline
+// @tags Pipeline
+// @Accept  json
+// @Produce json
+// @Param pipelineID path string true "工作流ID"
+// @Param request body pipeline.UpdatePipelineRequest true "更新工作流请求"
+// @Success 200 {object} pipeline.UpdatePipelineResponse "更新工作流响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 409 {object} common.ErrorResponse "409"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /pipeline/{pipelineID} [PUT]
+func (pr *PipelineRouter) updatePipeline(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	pipelineID := chi.URLParam(r, util.ParamKeyPipelineID)
+	var updatePplReq pipeline.UpdatePipelineRequest
+	if err := common.BindJSON(r, &updatePplReq); err != nil {
+		logger.LoggerForRequest(&ctx).Errorf(
+			"update pipeline failed parsing request body:%+v. error:%v", r.Body, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	response, err := pipeline.UpdatePipeline(&ctx, pipelineID, updatePplReq)
+	if err != nil {
+		logger.LoggerForRequest(&ctx).Errorf(
+			"update pipeline[%s] failed. updatePplReq:%v error:%v", pipelineID, updatePplReq, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// listPipelineVersion
+// @Summary 获取工作流的版本列表
+// @Description 获取工作流的版本列表
+// @Id listPipelineVersion
+// @tags Pipeline
+// @Accept  json
+// @Produce json
+// @Param pipelineID path string true "工作流ID"
+// @Success 200 {object} pipeline.ListPipelineVersionResponse "工作流版本列表"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /pipeline/{pipelineID}/version [GET]
+func (pr *PipelineRouter) listPipelineVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	pipelineID := chi.URLParam(r, util.ParamKeyPipelineID)
+	response, err := pipeline.ListPipelineVersion(&ctx, pipelineID)
+	if err != nil {
+		logger.LoggerForRequest(&ctx).Errorf(
+			"list versions of pipeline[%s] failed. error:%v", pipelineID, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// diffPipelineVersion
+// @Summary 比较工作流的两个版本
+// @Description 比较工作流两个版本的结构差异，包括增删的节点与变化的参数
+// @Id diffPipelineVersion
+// @tags Pipeline
+// @Accept  json
+// @Produce json
+// @Param pipelineID path string true "工作流ID"
+// @Param from query int true "起始版本"
+// @Param to query int true "目标版本"
+// @Success 200 {object} pipeline.PipelineDiffResponse "工作流版本差异"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /pipeline/{pipelineID}/diff [GET]
+func (pr *PipelineRouter) diffPipelineVersion(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	pipelineID := chi.URLParam(r, util.ParamKeyPipelineID)
+	fromVersion, err := strconv.Atoi(r.URL.Query().Get(util.QueryKeyFromVersion))
+	if err != nil || fromVersion <= 0 {
+		ctx.ErrorCode = common.InvalidURI
+		err := fmt.Errorf("invalid query from[%s]. should be a positive integer",
+			r.URL.Query().Get(util.QueryKeyFromVersion))
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	toVersion, err := strconv.Atoi(r.URL.Query().Get(util.QueryKeyToVersion))
+	if err != nil || toVersion <= 0 {
+		ctx.ErrorCode = common.InvalidURI
+		err := fmt.Errorf("invalid query to[%s]. should be a positive integer",
+			r.URL.Query().Get(util.QueryKeyToVersion))
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	response, err := pipeline.DiffPipelineVersion(&ctx, pipelineID, fromVersion, toVersion)
+	if err != nil {
+		logger.LoggerForRequest(&ctx).Errorf(
+			"diff pipeline[%s] version[%d-%d] failed. error:%v", pipelineID, fromVersion, toVersion, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
 // listPipeline
 // @Summary 获取工作流列表
 // @Description 获取工作流列表
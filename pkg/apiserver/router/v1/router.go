@@ -66,6 +66,8 @@ func RegisterRouters(r *chi.Mux, debugMode bool) {
 		AddRouter(apiV1Router, &fs.PFSRouter{})
 		AddRouter(apiV1Router, &ClusterRouter{})
 		AddRouter(apiV1Router, &TrackRouter{})
+		AddRouter(apiV1Router, &SecretRouter{})
+		AddRouter(apiV1Router, &WebhookRouter{})
 	})
 }
 
@@ -17,10 +17,12 @@ limitations under the License.
 package v1
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	log "github.com/sirupsen/logrus"
@@ -44,6 +46,7 @@ func (rr *RunRouter) AddRouter(r chi.Router) {
 	r.Post("/run", rr.createRun)
 	r.Get("/run", rr.listRun)
 	r.Get("/run/{runID}", rr.getRunByID)
+	r.Get("/run/{runID}/events", rr.getRunEvents)
 	r.Put("/run/{runID}", rr.updateRun)
 	r.Delete("/run/{runID}", rr.deleteRun)
 }
@@ -179,6 +182,113 @@ func (rr *RunRouter) getRunByID(w http.ResponseWriter, r *http.Request) {
 	common.Render(w, http.StatusOK, runInfo)
 }
 
+// sseHeartbeatInterval is the interval of comment lines sent to keep the event stream alive
+const sseHeartbeatInterval = 15 * time.Second
+
+// getRunEvents
+// @Summary 获取运行事件流
+// @Description 以Server-Sent Events的方式推送运行的事件，支持通过Last-Event-ID请求头或lastEventID参数从事件日志中重放
+// @Id getRunEvents
+// @tags Run
+// @Produce text/event-stream
+// @Param runID path string true "运行ID"
+// @Param lastEventID query int false "最后收到的事件ID，从该事件之后开始推送"
+// @Success 200 {object} models.RunEvent "运行事件"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /run/{runID}/events [GET]
+func (rr *RunRouter) getRunEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	runID := chi.URLParam(r, util.ParamKeyRunID)
+	lastEventIDStr := r.Header.Get(util.HeaderKeyLastEventID)
+	if lastEventIDStr == "" {
+		lastEventIDStr = r.URL.Query().Get(util.QueryKeyLastEventID)
+	}
+	var lastEventID int64
+	if lastEventIDStr != "" {
+		var err error
+		lastEventID, err = strconv.ParseInt(lastEventIDStr, 10, 64)
+		if err != nil || lastEventID < 0 {
+			common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidURI,
+				fmt.Sprintf("last event id[%s] is invalid", lastEventIDStr))
+			return
+		}
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		common.RenderErrWithMessage(w, ctx.RequestID, common.InternalError, "streaming is not supported")
+		return
+	}
+	stream, err := run.SubscribeRunEvents(&ctx, runID, lastEventID)
+	if err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	finished := false
+	for _, event := range stream.History {
+		if err := writeRunEvent(w, event); err != nil {
+			ctx.Logging().Errorf("write event[%d] of run[%s] failed. error:%v", event.Pk, runID, err)
+			return
+		}
+		lastEventID = event.Pk
+		finished = run.IsRunFinishedEvent(event)
+	}
+	flusher.Flush()
+	if finished || (len(stream.History) == 0 && common.IsRunFinalStatus(stream.Run.Status)) {
+		return
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-stream.Events:
+			if !ok {
+				// client falls behind, it reconnects with last event id to replay missed events
+				ctx.Logging().Warnf("events stream of run[%s] is closed at event[%d]", runID, lastEventID)
+				return
+			}
+			// events may be both replayed from event log and received from subscription
+			if event.Pk <= lastEventID {
+				continue
+			}
+			if err := writeRunEvent(w, event); err != nil {
+				ctx.Logging().Errorf("write event[%d] of run[%s] failed. error:%v", event.Pk, runID, err)
+				return
+			}
+			flusher.Flush()
+			lastEventID = event.Pk
+			if run.IsRunFinishedEvent(event) {
+				return
+			}
+		}
+	}
+}
+
+func writeRunEvent(w http.ResponseWriter, event models.RunEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Pk, event.Event, data)
+	return err
+}
+
 // updateRun
 // @Summary 修改运行
 // @Description 修改运行
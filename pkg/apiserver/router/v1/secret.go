@@ -0,0 +1,179 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/controller/secret"
+	"paddleflow/pkg/apiserver/router/util"
+)
+
+type SecretRouter struct{}
+
+func (sr *SecretRouter) Name() string {
+	return "SecretRouter"
+}
+
+func (sr *SecretRouter) AddRouter(r chi.Router) {
+	log.Info("add secret router")
+	r.Post("/secret", sr.createSecret)
+	r.Get("/secret", sr.listSecret)
+	r.Get("/secret/{secretName}", sr.getSecret)
+	r.Put("/secret/{secretName}", sr.updateSecret)
+	r.Delete("/secret/{secretName}", sr.deleteSecret)
+}
+
+// createSecret
+// @Summary 创建密钥
+// @Description 创建密钥，密钥值加密存储，创建后不再返回
+// @Id createSecret
+// @tags Secret
+// @Accept  json
+// @Produce json
+// @Param request body secret.CreateSecretRequest true "创建密钥请求"
+// @Success 200 {object} secret.CreateSecretResponse "创建密钥响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /secret [POST]
+func (sr *SecretRouter) createSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	var request secret.CreateSecretRequest
+	if err := common.BindJSON(r, &request); err != nil {
+		ctx.Logging().Errorf("createSecret bindjson failed. error:%s", err.Error())
+		common.RenderErr(w, ctx.RequestID, common.MalformedJSON)
+		return
+	}
+	response, err := secret.CreateSecret(&ctx, &request)
+	if err != nil {
+		ctx.Logging().Errorf("create secret[%s] failed. error:%s", request.Name, err.Error())
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// listSecret
+// @Summary 获取密钥列表
+// @Description 获取当前用户的密钥列表，不包含密钥值
+// @Id listSecret
+// @tags Secret
+// @Accept  json
+// @Produce json
+// @Param maxKeys query int false "每页包含的最大数量，缺省值为50"
+// @Param marker query string false "批量获取列表的查询的起始位置，是一个由系统生成的字符串"
+// @Success 200 {object} secret.ListSecretResponse "获取密钥列表的响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /secret [GET]
+func (sr *SecretRouter) listSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	marker := r.URL.Query().Get(util.QueryKeyMarker)
+	maxKeys, err := util.GetQueryMaxKeys(&ctx, r)
+	if err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidURI, err.Error())
+		return
+	}
+	response, err := secret.ListSecret(&ctx, marker, maxKeys)
+	if err != nil {
+		ctx.Logging().Errorf("list secrets failed. error:%s.", err.Error())
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// getSecret
+// @Summary 获取密钥详情
+// @Description 获取密钥详情，不包含密钥值
+// @Id getSecret
+// @tags Secret
+// @Accept  json
+// @Produce json
+// @Param secretName path string true "密钥名称"
+// @Success 200 {object} models.Secret "密钥结构体"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /secret/{secretName} [GET]
+func (sr *SecretRouter) getSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	secretName := chi.URLParam(r, util.ParamKeySecretName)
+	response, err := secret.GetSecret(&ctx, secretName)
+	if err != nil {
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// updateSecret
+// @Summary 更新密钥
+// @Description 更新密钥值或描述
+// @Id updateSecret
+// @tags Secret
+// @Accept  json
+// @Produce json
+// @Param secretName path string true "密钥名称"
+// @Param request body secret.UpdateSecretRequest true "更新密钥请求"
+// @Success 200 {string} string "成功更新密钥的响应码"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /secret/{secretName} [PUT]
+func (sr *SecretRouter) updateSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	secretName := chi.URLParam(r, util.ParamKeySecretName)
+	var request secret.UpdateSecretRequest
+	if err := common.BindJSON(r, &request); err != nil {
+		ctx.Logging().Errorf("updateSecret bindjson failed. error:%s", err.Error())
+		common.RenderErr(w, ctx.RequestID, common.MalformedJSON)
+		return
+	}
+	if err := secret.UpdateSecret(&ctx, secretName, &request); err != nil {
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// deleteSecret
+// @Summary 删除密钥
+// @Description 删除密钥
+// @Id deleteSecret
+// @tags Secret
+// @Accept  json
+// @Produce json
+// @Param secretName path string true "密钥名称"
+// @Success 200 {string} string "成功删除密钥的响应码"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /secret/{secretName} [DELETE]
+func (sr *SecretRouter) deleteSecret(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	secretName := chi.URLParam(r, util.ParamKeySecretName)
+	if err := secret.DeleteSecret(&ctx, secretName); err != nil {
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
@@ -42,6 +42,9 @@ func (ur *UserRouter) AddRouter(r chi.Router) {
 	r.Delete("/user/{username}", ur.deleteUser)
 	r.Put("/user/{username}", ur.updateUser)
 	r.Get("/user", ur.listUser)
+	r.Put("/user/{username}/group/{groupName}", ur.addUserToGroup)
+	r.Delete("/user/{username}/group/{groupName}", ur.deleteUserFromGroup)
+	r.Get("/user/{username}/group", ur.listUserGroup)
 
 }
 
@@ -210,6 +213,80 @@ func (ur *UserRouter) listUser(w http.ResponseWriter, r *http.Request) {
 	common.Render(w, http.StatusOK, listUserResponse)
 }
 
+// addUserToGroup
+// @Summary 将用户加入用户组
+// @Description 将用户加入用户组，共享给用户组的文件系统对组内用户可见
+// @Id addUserToGroup
+// @tags User
+// @Accept  json
+// @Produce json
+// @Param username path string true "用户名称"
+// @Param groupName path string true "用户组名称"
+// @Success 200 {string} string "成功加入用户组的响应码"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /user/{username}/group/{groupName} [PUT]
+func (ur *UserRouter) addUserToGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	userName := chi.URLParam(r, util.QueryKeyUserName)
+	groupName := chi.URLParam(r, util.ParamKeyGroupName)
+	if err := user.AddUserToGroup(&ctx, userName, groupName); err != nil {
+		ctx.Logging().Errorf("add user to group failed. userName:%s, groupName:%s, error:%s", userName, groupName, err.Error())
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// deleteUserFromGroup
+// @Summary 将用户移出用户组
+// @Description 将用户移出用户组
+// @Id deleteUserFromGroup
+// @tags User
+// @Accept  json
+// @Produce json
+// @Param username path string true "用户名称"
+// @Param groupName path string true "用户组名称"
+// @Success 200 {string} string "成功移出用户组的响应码"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /user/{username}/group/{groupName} [DELETE]
+func (ur *UserRouter) deleteUserFromGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	userName := chi.URLParam(r, util.QueryKeyUserName)
+	groupName := chi.URLParam(r, util.ParamKeyGroupName)
+	if err := user.DeleteUserFromGroup(&ctx, userName, groupName); err != nil {
+		ctx.Logging().Errorf("delete user from group failed. userName:%s, groupName:%s, error:%s", userName, groupName, err.Error())
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// listUserGroup
+// @Summary 获取用户所在的用户组
+// @Description 获取用户所在的用户组
+// @Id listUserGroup
+// @tags User
+// @Accept  json
+// @Produce json
+// @Param username path string true "用户名称"
+// @Success 200 {object} user.ListUserGroupResponse "获取用户组列表的响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /user/{username}/group [GET]
+func (ur *UserRouter) listUserGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	userName := chi.URLParam(r, util.QueryKeyUserName)
+	response, err := user.ListUserGroup(&ctx, userName)
+	if err != nil {
+		ctx.Logging().Errorf("list groups of user failed. userName:%s, error:%s", userName, err.Error())
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
 func (ur *UserRouter) getUser(w http.ResponseWriter, r *http.Request, username string) {
 	ctx := common.GetRequestContext(r)
 	response, err := user.GetUserByName(&ctx, username)
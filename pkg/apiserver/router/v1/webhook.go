@@ -0,0 +1,182 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/controller/webhook"
+	"paddleflow/pkg/apiserver/router/util"
+)
+
+type WebhookRouter struct{}
+
+func (wr *WebhookRouter) Name() string {
+	return "WebhookRouter"
+}
+
+func (wr *WebhookRouter) AddRouter(r chi.Router) {
+	log.Info("add webhook router")
+	r.Post("/webhook", wr.createWebhook)
+	r.Get("/webhook", wr.listWebhook)
+	r.Get("/webhook/{webhookID}", wr.getWebhook)
+	r.Delete("/webhook/{webhookID}", wr.deleteWebhook)
+	r.Get("/webhook/{webhookID}/delivery", wr.listDelivery)
+}
+
+// createWebhook
+// @Summary 创建事件通知
+// @Description 创建事件通知，支持webhook、email和im类型，订阅run和step的状态变化事件
+// @Id createWebhook
+// @tags Webhook
+// @Accept  json
+// @Produce json
+// @Param request body webhook.CreateWebhookRequest true "创建事件通知请求"
+// @Success 200 {object} webhook.CreateWebhookResponse "创建事件通知响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /webhook [POST]
+func (wr *WebhookRouter) createWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	var request webhook.CreateWebhookRequest
+	if err := common.BindJSON(r, &request); err != nil {
+		ctx.Logging().Errorf("createWebhook bindjson failed. error:%s", err.Error())
+		common.RenderErr(w, ctx.RequestID, common.MalformedJSON)
+		return
+	}
+	response, err := webhook.CreateWebhook(&ctx, &request)
+	if err != nil {
+		ctx.Logging().Errorf("create webhook failed. error:%s", err.Error())
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// listWebhook
+// @Summary 获取事件通知列表
+// @Description 获取事件通知列表，root用户可以获取所有用户的事件通知
+// @Id listWebhook
+// @tags Webhook
+// @Accept  json
+// @Produce json
+// @Param maxKeys query int false "每页包含的最大数量，缺省值为50"
+// @Param marker query string false "批量获取列表的查询的起始位置，是一个由系统生成的字符串"
+// @Success 200 {object} webhook.ListWebhookResponse "获取事件通知列表的响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /webhook [GET]
+func (wr *WebhookRouter) listWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	marker := r.URL.Query().Get(util.QueryKeyMarker)
+	maxKeys, err := util.GetQueryMaxKeys(&ctx, r)
+	if err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidURI, err.Error())
+		return
+	}
+	response, err := webhook.ListWebhook(&ctx, marker, maxKeys)
+	if err != nil {
+		ctx.Logging().Errorf("list webhooks failed. error:%s.", err.Error())
+		common.RenderErr(w, ctx.RequestID, ctx.ErrorCode)
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// getWebhook
+// @Summary 获取事件通知详情
+// @Description 获取事件通知详情
+// @Id getWebhook
+// @tags Webhook
+// @Accept  json
+// @Produce json
+// @Param webhookID path string true "事件通知ID"
+// @Success 200 {object} models.Webhook "事件通知结构体"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /webhook/{webhookID} [GET]
+func (wr *WebhookRouter) getWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	webhookID := chi.URLParam(r, util.ParamKeyWebhookID)
+	response, err := webhook.GetWebhook(&ctx, webhookID)
+	if err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+// deleteWebhook
+// @Summary 删除事件通知
+// @Description 删除事件通知及其投递记录
+// @Id deleteWebhook
+// @tags Webhook
+// @Accept  json
+// @Produce json
+// @Param webhookID path string true "事件通知ID"
+// @Success 200 {string} string "成功删除事件通知的响应码"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /webhook/{webhookID} [DELETE]
+func (wr *WebhookRouter) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	webhookID := chi.URLParam(r, util.ParamKeyWebhookID)
+	if err := webhook.DeleteWebhook(&ctx, webhookID); err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// listDelivery
+// @Summary 获取事件通知的投递记录
+// @Description 获取事件通知的投递记录，包含投递状态、重试次数和响应码
+// @Id listDelivery
+// @tags Webhook
+// @Accept  json
+// @Produce json
+// @Param webhookID path string true "事件通知ID"
+// @Param maxKeys query int false "每页包含的最大数量，缺省值为50"
+// @Param marker query string false "批量获取列表的查询的起始位置，是一个由系统生成的字符串"
+// @Success 200 {object} webhook.ListDeliveryResponse "获取投递记录的响应"
+// @Failure 400 {object} common.ErrorResponse "400"
+// @Failure 404 {object} common.ErrorResponse "404"
+// @Failure 500 {object} common.ErrorResponse "500"
+// @Router /webhook/{webhookID}/delivery [GET]
+func (wr *WebhookRouter) listDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	webhookID := chi.URLParam(r, util.ParamKeyWebhookID)
+	marker := r.URL.Query().Get(util.QueryKeyMarker)
+	maxKeys, err := util.GetQueryMaxKeys(&ctx, r)
+	if err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidURI, err.Error())
+		return
+	}
+	response, err := webhook.ListDelivery(&ctx, webhookID, marker, maxKeys)
+	if err != nil {
+		ctx.Logging().Errorf("list deliveries of webhook[%s] failed. error:%s.", webhookID, err.Error())
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package config
 
 import (
+	"time"
+
 	"paddleflow/pkg/common/logger"
 )
 
@@ -26,6 +28,20 @@ type CSIPlugin struct {
 	NodeID       string `yaml:"nodeID"`
 	PprofEnable  bool   `yaml:"pprofEnable"`
 	PprofPort    int    `yaml:"pprofPort"`
+	// MountPod 开启后pfs-fuse运行在每个节点每个fs独立的mount pod中，csi plugin重启或升级不影响已有挂载
+	MountPod bool `yaml:"mountPod"`
+	// MountPodImage mount pod的镜像，需包含pfs-fuse
+	MountPodImage     string `yaml:"mountPodImage"`
+	MountPodNamespace string `yaml:"mountPodNamespace"`
+	// MountPodCPU/MountPodMemory mount pod的资源request和limit，为空表示不限制
+	MountPodCPURequest    string `yaml:"mountPodCPURequest"`
+	MountPodCPULimit      string `yaml:"mountPodCPULimit"`
+	MountPodMemoryRequest string `yaml:"mountPodMemoryRequest"`
+	MountPodMemoryLimit   string `yaml:"mountPodMemoryLimit"`
+	// MountPodReadyTimeout 等待mount pod挂载完成的超时时间
+	MountPodReadyTimeout time.Duration `yaml:"mountPodReadyTimeout"`
+	// MountPodSecret mount pod所在namespace下的secret，其中的FUSE_PASSWORD和PFS_PEER_CACHE_TOKEN通过secretKeyRef传给mount pod
+	MountPodSecret string `yaml:"mountPodSecret"`
 }
 
 type CSIPluginConfig struct {
@@ -48,6 +64,9 @@ var defaultCSIPluginConfig = CSIPluginConfig{
 		NodeID:       "nodeId",
 		PprofEnable:  false,
 		PprofPort:    6060,
+
+		MountPodNamespace:    "paddleflow",
+		MountPodReadyTimeout: 60 * time.Second,
 	},
 }
 
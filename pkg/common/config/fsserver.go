@@ -36,7 +36,6 @@ type FsServerConfig struct {
 	StateExpireTime             time.Duration `yaml:"stateExpireTime"`
 	DefaultPVPath               string        `yaml:"defaultPVPath"`
 	DefaultPVCPath              string        `yaml:"defaultPVCPath"`
-	LinkMetaDirPrefix           string        `yaml:"linkMetaDirPrefix"`
 	// K8sServiceName K8sServicePort used to create pv/pvc with volumeAttributes point pfs-server pod
 	K8sServiceName   string `yaml:"k8sServiceName"`
 	K8sServicePort   int    `yaml:"k8sServicePort"`
@@ -56,14 +56,34 @@ var defaultFuseConfig = FuseConfig{
 		PprofEnable:          false,
 		PprofPort:            6060,
 		LinkUpdateInterval:   15,
-		LinkMetaDirPrefix:    "",
 		SkipCheckLinks:       false,
+		EnableLocks:          true,
+		ServerLock:           false,
 		Cache: Cache{
 			MemoryExpire:  100 * time.Second,
 			MemorySize:    0, // memorySize * BlockSize才是实际的内存cache大小
 			BlockSize:     0, // BlockSize == 0 表示关闭cache
 			DiskCachePath: "./cache_dir",
 			DiskExpire:    15 * 60 * time.Second,
+			// 0表示不限制磁盘cache大小，单位MB
+			DiskCacheMaxSize:   0,
+			DiskCacheFreeRatio: 0.1,
+			// 顺序读时最多预读的block数，0表示关闭预读
+			ReadAheadWindow:      8,
+			ReadAheadConcurrency: 4,
+			// 开启后写入先缓存并记录journal，flush/close时异步上传
+			WriteBack:           false,
+			WriteBackDirtyLimit: 256,
+			// 配置PeerCachePeers或PeerCacheService后开启节点间共享的block cache
+			PeerCachePort: 8620,
+			// mem表示inode只保存在内存中，bolt和redis会持久化inode并缓存属性和目录列表
+			MetaDriver:      "mem",
+			MetaPath:        "/var/lib/pfs-fuse/meta",
+			MetaCacheExpire: 10 * time.Second,
+
+			// 默认不缓存entry，开启后其他客户端的修改在过期前不可见
+			EntryCacheExpire:         0,
+			NegativeEntryCacheExpire: 0,
 		},
 	},
 }
@@ -85,6 +105,7 @@ type Fuse struct {
 	LinkPath             string `yaml:"linkPath"`
 	DepPath              string `yaml:"depPath"`
 	UserName             string `yaml:"userName"`
+	AccessUser           string `yaml:"accessUser"`
 	EntryTimeout         int    `yaml:"entryTimeout"`
 	AttrTimeout          int    `yaml:"attrTimeout"`
 	Uid                  uint32 `yaml:"uid"`
@@ -96,8 +117,10 @@ type Fuse struct {
 	PprofEnable          bool   `yaml:"pprofEnable"`
 	PprofPort            int    `yaml:"pprofPort"`
 	LinkUpdateInterval   int    `yaml:"linkUpdateInterval"`
-	LinkMetaDirPrefix    string `yaml:"linkMetaDirPrefix"`
 	SkipCheckLinks       bool   `yaml:"skipCheckLinks"`
+	EnableLocks          bool   `yaml:"enableLocks"`
+	ServerLock           bool   `yaml:"serverLock"`
+	AllowPrivateAddress  bool   `yaml:"allowPrivateAddress"`
 	Cache                `yaml:"cache"`
 	Password             string `yaml:"password"`
 }
@@ -108,6 +131,37 @@ type Cache struct {
 	MemoryExpire  time.Duration
 	DiskExpire    time.Duration
 	DiskCachePath string
+	// DiskCacheMaxSize 磁盘cache的上限，单位MB
+	DiskCacheMaxSize int
+	// DiskCacheFreeRatio 磁盘剩余空间比例低于该值时按LRU淘汰cache
+	DiskCacheFreeRatio float64
+	// ReadAheadWindow 顺序读时最多预读的block数
+	ReadAheadWindow      int
+	ReadAheadConcurrency int
+	WriteBack            bool
+	// WriteBackDirtyLimit 未上传的脏数据上限，单位MB
+	WriteBackDirtyLimit int
+	// PeerCachePeers 共享cache的节点列表，格式为ip:port
+	PeerCachePeers []string
+	// PeerCacheService 共享cache节点的k8s headless service，通过dns解析节点列表
+	PeerCacheService string
+	PeerCachePort    int
+	// PeerCacheSelf 本节点对其他节点的地址，默认为$POD_IP:PeerCachePort
+	PeerCacheSelf string
+	// PeerCacheToken 共享cache节点间校验请求的token，同一fs的节点需一致，默认读取环境变量PFS_PEER_CACHE_TOKEN
+	PeerCacheToken string
+	// MetaDriver meta引擎，可选mem、bolt、redis
+	MetaDriver string
+	// MetaPath bolt db文件所在目录，每个fs一个db文件
+	MetaPath string
+	// MetaRedisURL redis地址，格式为redis://[:password@]host:port/db
+	MetaRedisURL string
+	// MetaCacheExpire bolt和redis缓存属性和目录列表的过期时间，0表示不缓存
+	MetaCacheExpire time.Duration
+	// EntryCacheExpire vfs缓存目录项和属性的过期时间，readdirplus的结果也会写入该缓存，0表示不缓存
+	EntryCacheExpire time.Duration
+	// NegativeEntryCacheExpire 不存在的目录项的缓存时间，0表示不缓存
+	NegativeEntryCacheExpire time.Duration
 }
 
 var (
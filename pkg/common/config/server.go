@@ -34,6 +34,7 @@ type ServerConfig struct {
 	Flavour       []schema.Flavour          `yaml:"flavour"`
 	FlavourMap    map[string]schema.Flavour `yaml:"-"`
 	ImageConf     ImageConfig               `yaml:"imageRepository"`
+	Notification  NotificationConfig        `yaml:"notification"`
 }
 
 type ApiServerConfig struct {
@@ -41,6 +42,8 @@ type ApiServerConfig struct {
 	Port                int    `yaml:"port"`
 	PrintVersionAndExit bool   `yaml:"printVersionAndExit"`
 	TokenExpirationHour int    `yaml:"tokenExpirationHour"`
+	// SecretKey is the AES key used to encrypt user secrets, its length must be 16, 24 or 32
+	SecretKey string `yaml:"secretKey"`
 }
 
 type JobConfig struct {
@@ -52,12 +55,16 @@ type JobConfig struct {
 }
 
 type FsServerConf struct {
-	DefaultPVPath     string `yaml:"defaultPVPath"`
-	DefaultPVCPath    string `yaml:"defaultPVCPath"`
-	LinkMetaDirPrefix string `yaml:"linkMetaDirPrefix"`
+	DefaultPVPath  string `yaml:"defaultPVPath"`
+	DefaultPVCPath string `yaml:"defaultPVCPath"`
 	// K8sServiceName K8sServicePort used to create pv/pvc with volumeAttributes point pfs-server pod
 	K8sServiceName string `yaml:"k8sServiceName"`
 	K8sServicePort int    `yaml:"k8sServicePort"`
+	// LocalMountRoots are the dirs on the nodes and the server where the kernel fs like nfs may be mounted
+	// already, the localMountPath of fs must be under one of them, which is not allowed if not set
+	LocalMountRoots []string `yaml:"localMountRoots"`
+	// AllowPrivateAddress allows the http and webdav fs to be served from loopback, link-local and private addresses
+	AllowPrivateAddress bool `yaml:"allowPrivateAddress"`
 }
 
 type ReclaimConfig struct {
@@ -76,6 +83,24 @@ type ImageConfig struct {
 	RemoveLocalImage bool   `yaml:"removeLocalImage"`
 }
 
+// NotificationConfig is config for delivering run and step status events to webhooks, emails and IM robots
+type NotificationConfig struct {
+	MaxRetries           int `yaml:"maxRetries"`
+	RetryIntervalSeconds int `yaml:"retryIntervalSeconds"`
+	TimeoutSeconds       int `yaml:"timeoutSeconds"`
+	// AllowPrivateAddress allows webhooks to be sent to loopback, link-local and private addresses
+	AllowPrivateAddress bool       `yaml:"allowPrivateAddress"`
+	SMTP                SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+}
+
 var (
 	GlobalServerConfig *ServerConfig
 )
@@ -33,13 +33,23 @@ func InitFakeDB() {
 	// Create tables
 	db.AutoMigrate(
 		&models.Pipeline{},
+		&models.PipelineVersion{},
+		&models.Secret{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
 		&models.RunCache{},
 		&models.ArtifactEvent{},
 		&models.User{},
 		&models.Run{},
+		&models.RunEvent{},
 		&models.Queue{},
 		&models.Grant{},
 		&models.Job{},
+		&models.UserGroup{},
+		&models.FileLock{},
+		&models.LockSession{},
+		&models.FileSystem{},
+		&models.Link{},
 	)
 	database.DB = db
 }
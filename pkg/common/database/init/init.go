@@ -67,6 +67,11 @@ func InitDatabase(dbConf *config.DatabaseConfig, gormConf *gorm.Config, logLevel
 	if db == nil {
 		panic(fmt.Errorf("Init database db error\n"))
 	}
+	if count, err := models.BackfillPipelineVersions(db); err != nil {
+		log.Errorf("backfill pipeline versions failed: %v", err)
+	} else if count > 0 {
+		log.Infof("backfill versions of %d pipelines created before versioning", count)
+	}
 
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -105,10 +110,15 @@ func initSQLiteDB(dbConf *config.DatabaseConfig, gormConf *gorm.Config) *gorm.DB
 	// Create tables
 	db.AutoMigrate(
 		&models.Pipeline{},
+		&models.PipelineVersion{},
+		&models.Secret{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
 		&models.RunCache{},
 		&models.ArtifactEvent{},
 		&models.User{},
 		&models.Run{},
+		&models.RunEvent{},
 		&models.Queue{},
 		&models.Grant{},
 		&models.Job{},
@@ -116,6 +126,10 @@ func initSQLiteDB(dbConf *config.DatabaseConfig, gormConf *gorm.Config) *gorm.DB
 		&models.Image{},
 		&models.FileSystem{},
 		&models.Link{},
+		&models.FsShare{},
+		&models.UserGroup{},
+		&models.FileLock{},
+		&models.LockSession{},
 	)
 	// init root user to db, can not be modified by config file currently
 	rootUser := models.User{
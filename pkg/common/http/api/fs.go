@@ -17,12 +17,15 @@ limitations under the License.
 package api
 
 import (
+	"strconv"
+
 	log "github.com/sirupsen/logrus"
 
 	"paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/apiserver/router/util"
 	"paddleflow/pkg/common/http/core"
 	"paddleflow/pkg/common/http/util/http"
+	"paddleflow/pkg/fs/server/api/request"
 	"paddleflow/pkg/fs/server/api/response"
 )
 
@@ -30,7 +33,11 @@ const (
 	Prefix       = util.PaddleflowRouterPrefix + util.PaddleflowRouterVersionV1
 	LoginApi     = Prefix + "/login"
 	GetFsApi     = Prefix + "/fs"
+	FsUsageApi   = "/usage"
 	GetLinksApis = Prefix + "/link"
+	LinksWatch   = "/watch"
+	FileLockApi  = Prefix + "/fs/lock"
+	LockSession  = FileLockApi + "/session"
 )
 
 type LoginParams struct {
@@ -45,6 +52,8 @@ type LoginResponse struct {
 type FsParams struct {
 	FsID  string
 	Token string
+	// Username is the user whose access to the fs is checked, the login user if empty
+	Username string
 }
 
 type LinksParams struct {
@@ -56,6 +65,20 @@ type LinksParams struct {
 	Token    string
 }
 
+type LinksWatchParams struct {
+	FsID    string
+	Version int64
+	// Timeout is the seconds the server waits for the links to change
+	Timeout  int
+	Token    string
+	Username string
+}
+
+type FileLockParams struct {
+	request.FileLockRequest
+	Token string `json:"-"`
+}
+
 type FsResponse response.FileSystemResponse
 
 type LinksResponse response.GetLinkResponse
@@ -80,7 +103,23 @@ func FsRequest(params FsParams, c *core.PFClient) (*FsResponse, error) {
 	resp := &FsResponse{}
 	err := core.NewRequestBuilder(c).
 		WithHeader(common.HeaderKeyAuthorization, params.Token).
-		WithURL(GetFsApi + "/" + params.FsID).
+		WithURL(GetFsApi+"/"+params.FsID).
+		WithQueryParamFilter(util.QueryKeyUserName, params.Username).
+		WithMethod(http.GET).
+		WithResult(resp).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func FsUsageRequest(params FsParams, c *core.PFClient) (*response.FileSystemUsageResponse, error) {
+	resp := &response.FileSystemUsageResponse{}
+	err := core.NewRequestBuilder(c).
+		WithHeader(common.HeaderKeyAuthorization, params.Token).
+		WithURL(GetFsApi+"/"+params.FsID+FsUsageApi).
+		WithQueryParamFilter(util.QueryKeyUserName, params.Username).
 		WithMethod(http.GET).
 		WithResult(resp).
 		Do()
@@ -94,7 +133,25 @@ func LinksRequest(params LinksParams, c *core.PFClient) (*LinksResponse, error)
 	resp := &LinksResponse{}
 	err := core.NewRequestBuilder(c).
 		WithHeader(common.HeaderKeyAuthorization, params.Token).
-		WithURL(GetLinksApis + "/" + params.FsID).
+		WithURL(GetLinksApis+"/"+params.FsID).
+		WithQueryParamFilter(util.QueryKeyUserName, params.Username).
+		WithMethod(http.GET).
+		WithResult(resp).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func LinksWatchRequest(params LinksWatchParams, c *core.PFClient) (*response.WatchLinkResponse, error) {
+	resp := &response.WatchLinkResponse{}
+	err := core.NewRequestBuilder(c).
+		WithHeader(common.HeaderKeyAuthorization, params.Token).
+		WithURL(GetLinksApis+"/"+params.FsID+LinksWatch).
+		WithQueryParam(util.QueryLinkVersion, strconv.FormatInt(params.Version, 10)).
+		WithQueryParam(util.QueryWatchTimeout, strconv.Itoa(params.Timeout)).
+		WithQueryParamFilter(util.QueryKeyUserName, params.Username).
 		WithMethod(http.GET).
 		WithResult(resp).
 		Do()
@@ -103,3 +160,27 @@ func LinksRequest(params LinksParams, c *core.PFClient) (*LinksResponse, error)
 	}
 	return resp, nil
 }
+
+func FileLockRequest(params FileLockParams, c *core.PFClient) (*response.FileLockResponse, error) {
+	resp := &response.FileLockResponse{}
+	err := core.NewRequestBuilder(c).
+		WithHeader(common.HeaderKeyAuthorization, params.Token).
+		WithURL(FileLockApi).
+		WithMethod(http.POST).
+		WithBody(params.FileLockRequest).
+		WithResult(resp).
+		Do()
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LockSessionRequest keeps the lock session alive by PUT, or releases its locks by DELETE
+func LockSessionRequest(session, method, token string, c *core.PFClient) error {
+	return core.NewRequestBuilder(c).
+		WithHeader(common.HeaderKeyAuthorization, token).
+		WithURL(LockSession + "/" + session).
+		WithMethod(method).
+		Do()
+}
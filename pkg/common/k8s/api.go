@@ -31,6 +31,7 @@ import (
 
 var (
 	PodGVK      = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Pod"}
+	SecretGVK   = schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Secret"}
 	VCJobGVK    = schema.GroupVersionKind{Group: "batch.volcano.sh", Version: "v1alpha1", Kind: "Job"}
 	SparkAppGVK = schema.GroupVersionKind{Group: "sparkoperator.k8s.io", Version: "v1beta2", Kind: "SparkApplication"}
 
@@ -18,6 +18,8 @@ package schema
 
 import (
 	"fmt"
+	"regexp"
+
 	batchv1alpha1 "volcano.sh/apis/pkg/apis/batch/v1alpha1"
 
 	sparkoperatorv1beta2 "paddleflow/pkg/apis/spark-operator/sparkoperator.k8s.io/v1beta2"
@@ -38,6 +40,8 @@ const (
 	EnvJobMode      = "PF_JOB_MODE"
 	// EnvJobYamlPath Additional configuration for a specific job
 	EnvJobYamlPath = "PF_JOB_YAML_PATH"
+	// EnvJobSecretName is the name of kubernetes secret which holds secret values referenced by job env
+	EnvJobSecretName = "PF_JOB_SECRET_NAME"
 
 	// EnvJobModePS env
 	EnvJobModePS          = "PS"
@@ -102,6 +106,23 @@ const (
 	DefaultFSMountPath   = "/home/paddleflow/storage/mnt"
 )
 
+// secretRefPattern matches env value which references a user secret, such as ${secret:hf_token}
+var secretRefPattern = regexp.MustCompile(`^\$\{secret:([A-Za-z0-9._-]+)\}$`)
+
+// ParseSecretRef returns the referenced secret name if value is a secret reference
+func ParseSecretRef(value string) (string, bool) {
+	matches := secretRefPattern.FindStringSubmatch(value)
+	if len(matches) != 2 {
+		return "", false
+	}
+	return matches[1], true
+}
+
+// JobSecretName returns the name of kubernetes secret created for job
+func JobSecretName(jobID string) string {
+	return fmt.Sprintf("%s-secret", jobID)
+}
+
 const (
 	Update    ActionOnJob = "update"
 	Delete    ActionOnJob = "delete"
@@ -0,0 +1,107 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	pathlib "path"
+	"strings"
+)
+
+const (
+	AccessReadOnly  = "ro"
+	AccessReadWrite = "rw"
+)
+
+// FsAccess is the permissions of a user to the paths of a fs shared with the user, keyed by the
+// absolute paths in the fs. nil means the full access of the owner.
+type FsAccess map[string]string
+
+func cleanAccessPath(path string) string {
+	return pathlib.Clean("/" + path)
+}
+
+// isUnder reports whether path is dir or in dir, both of them are clean.
+func isUnder(path, dir string) bool {
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
+// Add grants permission of path, read-write wins over read-only if path is granted twice.
+func (a FsAccess) Add(path, permission string) {
+	path = cleanAccessPath(path)
+	if a[path] != AccessReadWrite {
+		a[path] = permission
+	}
+}
+
+// Permission returns the permission of path given by the deepest shared path containing it,
+// "" if path is not shared.
+func (a FsAccess) Permission(path string) string {
+	if a == nil {
+		return AccessReadWrite
+	}
+	path = cleanAccessPath(path)
+	permission, depth := "", -1
+	for dir, p := range a {
+		if isUnder(path, dir) && len(dir) > depth {
+			permission, depth = p, len(dir)
+		}
+	}
+	return permission
+}
+
+// CanRead reports whether path can be read, the ancestors of the shared paths can be looked up
+// so that the shared paths are reachable from the root of fs.
+func (a FsAccess) CanRead(path string) bool {
+	if a.Permission(path) != "" {
+		return true
+	}
+	path = cleanAccessPath(path)
+	for dir := range a {
+		if isUnder(dir, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanWrite reports whether path can be modified.
+func (a FsAccess) CanWrite(path string) bool {
+	return a.Permission(path) == AccessReadWrite
+}
+
+// Root returns the deepest dir containing all the shared paths, and whether all of them are read-only.
+func (a FsAccess) Root() (string, bool) {
+	if a == nil {
+		return "/", false
+	}
+	root, readOnly := "", true
+	for dir, p := range a {
+		if root == "" {
+			root = dir
+		}
+		for !isUnder(dir, root) {
+			root = pathlib.Dir(root)
+		}
+		if p == AccessReadWrite {
+			readOnly = false
+		}
+	}
+	if root == "" {
+		root = "/"
+	}
+	return root, readOnly
+}
@@ -0,0 +1,74 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFsAccess(t *testing.T) {
+	var owner FsAccess
+	assert.True(t, owner.CanRead("/any"))
+	assert.True(t, owner.CanWrite("/any"))
+	root, readOnly := owner.Root()
+	assert.Equal(t, "/", root)
+	assert.False(t, readOnly)
+
+	access := FsAccess{}
+	access.Add("/data/train", AccessReadOnly)
+	access.Add("data/train/", AccessReadOnly)
+	access.Add("/data/train/output", AccessReadWrite)
+	access.Add("/data/test", AccessReadWrite)
+	access.Add("/data/test", AccessReadOnly)
+	assert.Equal(t, 3, len(access))
+
+	assert.Equal(t, AccessReadOnly, access.Permission("/data/train/a.txt"))
+	assert.Equal(t, AccessReadWrite, access.Permission("/data/train/output/model"))
+	assert.Equal(t, AccessReadWrite, access.Permission("/data/test"))
+	assert.Equal(t, "", access.Permission("/data/trainer"))
+	assert.Equal(t, "", access.Permission("/data"))
+
+	// the ancestors of the shared paths can be read but not written
+	assert.True(t, access.CanRead("/"))
+	assert.True(t, access.CanRead("/data"))
+	assert.False(t, access.CanWrite("/data"))
+	assert.False(t, access.CanRead("/other"))
+	assert.False(t, access.CanRead("/data/trainer"))
+	assert.True(t, access.CanRead("/data/train/a.txt"))
+	assert.False(t, access.CanWrite("/data/train/a.txt"))
+	assert.True(t, access.CanWrite("/data/train/output/model"))
+
+	root, readOnly = access.Root()
+	assert.Equal(t, "/data", root)
+	assert.False(t, readOnly)
+
+	dataset := FsAccess{}
+	dataset.Add("/datasets/imagenet", AccessReadOnly)
+	root, readOnly = dataset.Root()
+	assert.Equal(t, "/datasets/imagenet", root)
+	assert.True(t, readOnly)
+
+	dataset.Add("/models", AccessReadOnly)
+	root, readOnly = dataset.Root()
+	assert.Equal(t, "/", root)
+	assert.True(t, readOnly)
+
+	root, _ = FsAccess{}.Root()
+	assert.Equal(t, "/", root)
+}
@@ -17,11 +17,16 @@ limitations under the License.
 package base
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 
 	"paddleflow/pkg/common/http/api"
 	"paddleflow/pkg/common/http/core"
+	"paddleflow/pkg/common/http/util/http"
+	"paddleflow/pkg/fs/server/api/request"
+	"paddleflow/pkg/fs/server/api/response"
 )
 
 const (
@@ -40,6 +45,10 @@ type _Client struct {
 	UserName   string
 	Token      string
 	httpClient *core.PFClient
+	// AccessUser is the user whose access to the fs shared by others is enforced, the login user if empty
+	AccessUser string
+	// linkVersion is the version of the links got last time
+	linkVersion int64
 }
 
 func NewClient(fsID string, c *core.PFClient, userName string, token string) (*_Client, error) {
@@ -57,8 +66,9 @@ func NewClient(fsID string, c *core.PFClient, userName string, token string) (*_
 func (c *_Client) GetFSMeta() (FSMeta, error) {
 	log.Debugf("Http CLient is %v", *c)
 	params := api.FsParams{
-		FsID:  c.FsID,
-		Token: c.Token,
+		FsID:     c.FsID,
+		Token:    c.Token,
+		Username: c.AccessUser,
 	}
 	fsResponseMeta, err := api.FsRequest(params, c.httpClient)
 	if err != nil {
@@ -74,24 +84,57 @@ func (c *_Client) GetFSMeta() (FSMeta, error) {
 		SubPath:       fsResponseMeta.SubPath,
 		Properties:    fsResponseMeta.Properties,
 	}
+	if len(fsResponseMeta.Access) != 0 {
+		fsMeta.Access = fsResponseMeta.Access
+	}
 	return fsMeta, nil
 }
 
 func (c *_Client) GetLinks() (map[string]FSMeta, error) {
 	log.Debugf("http CLient is %v", *c)
 	params := api.LinksParams{
-		FsID:  c.FsID,
-		Token: c.Token,
+		FsID:     c.FsID,
+		Token:    c.Token,
+		Username: c.AccessUser,
 	}
-	result := make(map[string]FSMeta)
-
 	linkResult, err := api.LinksRequest(params, c.httpClient)
 	if err != nil {
 		log.Errorf("links request failed: %v", err)
 		return nil, err
 	}
-	linkList := linkResult.LinkList
+	c.linkVersion = linkResult.Version
+	return linksToFSMeta(linkResult.LinkList), nil
+}
+
+// LinkVersion returns the version of the links got last time
+func (c *_Client) LinkVersion() int64 {
+	return c.linkVersion
+}
+
+// WatchLinks waits on server until the links are changed from version or timeout,
+// the links returned are nil if not changed.
+func (c *_Client) WatchLinks(version int64, timeout time.Duration) (map[string]FSMeta, int64, error) {
+	params := api.LinksWatchParams{
+		FsID:     c.FsID,
+		Version:  version,
+		Timeout:  int(timeout / time.Second),
+		Token:    c.Token,
+		Username: c.AccessUser,
+	}
+	watchResult, err := api.LinksWatchRequest(params, c.httpClient)
+	if err != nil {
+		log.Errorf("links watch request failed: %v", err)
+		return nil, version, err
+	}
+	if !watchResult.Changed {
+		return nil, watchResult.Version, nil
+	}
+	c.linkVersion = watchResult.Version
+	return linksToFSMeta(watchResult.LinkList), watchResult.Version, nil
+}
 
+func linksToFSMeta(linkList []*response.LinkResponse) map[string]FSMeta {
+	result := make(map[string]FSMeta)
 	for _, link := range linkList {
 		result[link.FsPath] = FSMeta{
 			Name:          link.FsName,
@@ -103,5 +146,36 @@ func (c *_Client) GetLinks() (map[string]FSMeta, error) {
 			Type: LinkType,
 		}
 	}
-	return result, nil
+	return result
+}
+
+// GetUsage gets the usage of fs scanned by server
+func (c *_Client) GetUsage() (*response.FileSystemUsageResponse, error) {
+	params := api.FsParams{
+		FsID:     c.FsID,
+		Token:    c.Token,
+		Username: c.AccessUser,
+	}
+	return api.FsUsageRequest(params, c.httpClient)
+}
+
+// FileLock puts or tests the lock on server in the session of this client
+func (c *_Client) FileLock(req request.FileLockRequest) (*response.FileLockResponse, error) {
+	req.FsID = c.FsID
+	req.Session = c.Uuid
+	params := api.FileLockParams{
+		FileLockRequest: req,
+		Token:           c.Token,
+	}
+	return api.FileLockRequest(params, c.httpClient)
+}
+
+// KeepLockSession keeps the locks of this client held on server
+func (c *_Client) KeepLockSession() error {
+	return api.LockSessionRequest(c.Uuid, http.PUT, c.Token, c.httpClient)
+}
+
+// ReleaseLockSession releases all the locks of this client on server
+func (c *_Client) ReleaseLockSession() error {
+	return api.LockSessionRequest(c.Uuid, http.DELETE, c.Token, c.httpClient)
 }
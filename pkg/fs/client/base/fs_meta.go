@@ -16,12 +16,25 @@ limitations under the License.
 
 package base
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	LocalType            = "local"
 	HDFSType             = "hdfs"
 	HDFSWithKerberosType = "hdfsWithKerberos"
 	S3Type               = "s3"
 	SFTPType             = "sftp"
+	GCSType              = "gcs"
+	AzblobType           = "azblob"
+	NFSType              = "nfs"
+	CephFSType           = "cephfs"
+	LustreType           = "lustre"
+	WebDAVType           = "webdav"
+	HTTPType             = "http"
 	MockType             = "mock"
 
 	// common
@@ -52,6 +65,39 @@ const (
 	Endpoint  = "endpoint"
 	Bucket    = "bucket"
 	Region    = "region"
+	// PartSize is the multipart upload part size in MB
+	PartSize          = "partSize"
+	UploadConcurrency = "uploadConcurrency"
+
+	// gcs properties, the credentials is the json key of a service account
+	Credentials        = "credentials"
+	GCSDefaultEndpoint = "https://storage.googleapis.com/storage/v1/"
+
+	// azure blob properties
+	AccountName                 = "accountName"
+	AccountKey                  = "accountKey"
+	AzblobDefaultEndpointFormat = "https://%s.blob.core.windows.net"
+
+	// nfs, cephfs and lustre properties, the options of kernel mount, e.g. vers=4.1,nolock for nfs,
+	// and the path where the fs is mounted already on the nodes and the server, if set, the fs is
+	// used there instead of mounted by pfs
+	KernelMountOptions = "kernelMountOptions"
+	LocalMountPath     = "localMountPath"
+
+	// webdav and http properties, the scheme is http or https, webdav uses the user and password of
+	// sftp properties. The manifest of http is a file under the url listing the files, a "path" or
+	// "path<TAB>size" a line, which is used instead of the directory index pages if set. The timeout
+	// is the seconds a request may take including reading the response.
+	Scheme   = "scheme"
+	Manifest = "manifest"
+	Timeout  = "timeout"
+
+	// quota properties, the quota is the max bytes stored in the fs except its links, a soft quota
+	// is only reported by the usage while a hard quota fails the writes beyond it with EDQUOT
+	Quota     = "quota"
+	QuotaType = "quotaType"
+	SoftQuota = "soft"
+	HardQuota = "hard"
 
 	// sftp properties
 	Address  = "address"
@@ -68,10 +114,6 @@ const (
 	// Link操作
 	ADD    = "ADD"
 	DELETE = "DELETE"
-
-	// Link Meta
-	LinkMetaDir  = ".config"
-	LinkMetaFile = "links_meta"
 )
 
 type FSMeta struct {
@@ -83,4 +125,68 @@ type FSMeta struct {
 	Properties    map[string]string
 	// type: fs 表示是默认的后端存储；link 表示是外部存储
 	Type string
+	// Access is the access of the user mounting the fs shared by others, nil for the owner
+	Access FsAccess
+}
+
+// GetQuota returns the quota in bytes and whether it is hard in the properties, 0 if not set
+func GetQuota(properties map[string]string) (int64, bool) {
+	quota, err := strconv.ParseInt(properties[Quota], 10, 64)
+	if err != nil || quota <= 0 {
+		return 0, false
+	}
+	return quota, properties[QuotaType] != SoftQuota
+}
+
+// kernelFSTypes are the types of mount(8) of the ufs passed through to the kernel, which are
+// mounted directly by the csi plugin rather than served by pfs-fuse
+var kernelFSTypes = map[string]string{
+	NFSType:    "nfs",
+	CephFSType: "ceph",
+	LustreType: "lustre",
+}
+
+// KernelFSType returns the type of mount(8) of ufsType, false if it is not passed through
+func KernelFSType(ufsType string) (string, bool) {
+	fsType, ok := kernelFSTypes[ufsType]
+	return fsType, ok
+}
+
+// KernelMountSource returns the source of mount(8) for the kernel fs, which is
+// server:/path for nfs, mon1:6789,mon2:6789:/path for cephfs and mgs@tcp:/fsname/path for lustre
+func KernelMountSource(serverAddress, subPath string) string {
+	return serverAddress + ":" + subPath
+}
+
+// kernelMountOptions are the mount(8) options allowed in the kernel mount options of fs, the others
+// like suid, dev, exec or the helper and secret file options of mount could be abused on the nodes
+var kernelMountOptions = map[string]bool{
+	// generic
+	"ro": true, "rw": true, "noatime": true, "relatime": true, "nodiratime": true, "sync": true, "async": true,
+	// nfs
+	"vers": true, "nfsvers": true, "minorversion": true, "proto": true, "port": true, "mountproto": true,
+	"mountport": true, "timeo": true, "retrans": true, "hard": true, "soft": true, "intr": true, "nointr": true,
+	"rsize": true, "wsize": true, "ac": true, "noac": true, "actimeo": true, "acregmin": true, "acregmax": true,
+	"acdirmin": true, "acdirmax": true, "lock": true, "nolock": true, "local_lock": true, "nconnect": true,
+	"sec": true, "resvport": true, "noresvport": true, "lookupcache": true, "cto": true, "nocto": true,
+	// cephfs
+	"name": true, "secret": true, "fs": true, "mds_namespace": true, "rasize": true, "recover_session": true,
+	"readdir_max_entries": true, "readdir_max_bytes": true, "caps_max": true,
+	// lustre
+	"flock": true, "localflock": true, "noflock": true, "user_xattr": true, "nouser_xattr": true,
+	"lazystatfs": true, "nolazystatfs": true,
+}
+
+// CheckKernelMountOptions returns an error if any of the comma separated mount(8) options is not allowed
+func CheckKernelMountOptions(options string) error {
+	if options == "" {
+		return nil
+	}
+	for _, option := range strings.Split(options, ",") {
+		name := strings.SplitN(option, "=", 2)[0]
+		if !kernelMountOptions[name] {
+			return fmt.Errorf("mount option[%s] is not allowed", option)
+		}
+	}
+	return nil
 }
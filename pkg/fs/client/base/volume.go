@@ -0,0 +1,169 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the mount options accepted in the volume attributes of pv, the parameters of storage class and
+// the defaults of fs, all of them but MountSubPath and MountReadOnly are passed to pfs-fuse as flags
+const (
+	MountBlockSize         = "pfs.block.size"
+	MountMemorySize        = "pfs.mem.size"
+	MountMemoryCacheExpire = "pfs.mem.cache.expire"
+	MountDiskCacheExpire   = "pfs.disk.cache.expire"
+	MountDiskCacheMaxSize  = "pfs.disk.cache.max.size"
+	MountAttrTimeout       = "pfs.attr.timeout"
+	MountEntryTimeout      = "pfs.entry.timeout"
+	MountEntryCacheExpire  = "pfs.entry.cache.expire"
+	MountReadAheadWindow   = "pfs.read.ahead.window"
+	MountAllowOther        = "pfs.allow.other"
+	MountSubPath           = "pfs.sub.path"
+	// MountReadOnly publishes the volume read-only, MountAccessUser is the user whose access to the fs
+	// shared by others is enforced by pfs-fuse
+	MountReadOnly   = "pfs.read.only"
+	MountAccessUser = "pfs.access.user"
+)
+
+// the volume attributes of the fs passed through to the kernel, such as nfs and cephfs, which is
+// mounted by the csi plugin directly instead of pfs-fuse
+const (
+	KernelVolumeType    = "pfs.kernel.type"
+	KernelVolumeSource  = "pfs.kernel.source"
+	KernelVolumeOptions = "pfs.kernel.options"
+	// KernelVolumeLocalPath is set if the fs is mounted on the nodes already, which is bind mounted then
+	KernelVolumeLocalPath = "pfs.kernel.local.path"
+)
+
+var mountOptionChecks = map[string]func(value string) error{
+	MountBlockSize:         checkNonNegativeInt,
+	MountMemorySize:        checkNonNegativeInt,
+	MountMemoryCacheExpire: checkDuration,
+	MountDiskCacheExpire:   checkDuration,
+	MountDiskCacheMaxSize:  checkNonNegativeInt,
+	MountAttrTimeout:       checkNonNegativeInt,
+	MountEntryTimeout:      checkNonNegativeInt,
+	MountEntryCacheExpire:  checkDuration,
+	MountReadAheadWindow:   checkNonNegativeInt,
+	MountAllowOther:        checkBool,
+	MountSubPath:           checkSubPath,
+	MountReadOnly:          checkBool,
+	MountAccessUser:        checkNotEmpty,
+}
+
+func checkNonNegativeInt(value string) error {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return fmt.Errorf("must be a non-negative integer")
+	}
+	return nil
+}
+
+func checkDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return fmt.Errorf("must be a non-negative duration like 10s")
+	}
+	return nil
+}
+
+func checkBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+func checkNotEmpty(value string) error {
+	if value == "" {
+		return fmt.Errorf("must not be empty")
+	}
+	return nil
+}
+
+func checkSubPath(value string) error {
+	if value == "" || path.IsAbs(value) || strings.HasPrefix(path.Clean(value), "..") {
+		return fmt.Errorf("must be a relative path in the fs")
+	}
+	return nil
+}
+
+// IsReadOnly reports whether the mount options publish the volume read-only
+func IsReadOnly(options map[string]string) bool {
+	readOnly, _ := strconv.ParseBool(options[MountReadOnly])
+	return readOnly
+}
+
+// IsMountOption reports whether key is one of the mount options
+func IsMountOption(key string) bool {
+	_, ok := mountOptionChecks[key]
+	return ok
+}
+
+// ValidateMountOptions checks the values of the mount options in attributes, other attributes are
+// ignored unless strict is true, in which case only the mount options are allowed.
+func ValidateMountOptions(attributes map[string]string, strict bool) error {
+	for key, value := range attributes {
+		check, ok := mountOptionChecks[key]
+		if !ok {
+			if strict {
+				return fmt.Errorf("unknown mount option[%s]", key)
+			}
+			continue
+		}
+		if err := check(value); err != nil {
+			return fmt.Errorf("invalid mount option[%s=%s]: %v", key, value, err)
+		}
+	}
+	return nil
+}
+
+// GetMountOptions picks the mount options from attributes
+func GetMountOptions(attributes map[string]string) map[string]string {
+	options := make(map[string]string)
+	for key, value := range attributes {
+		if IsMountOption(key) {
+			options[key] = value
+		}
+	}
+	return options
+}
+
+// KernelVolumeAttributes returns the volume attributes to pass fsMeta through to the kernel,
+// nil if the type of the fs is served by pfs-fuse
+func KernelVolumeAttributes(fsMeta FSMeta) map[string]string {
+	fsType, ok := KernelFSType(fsMeta.UfsType)
+	if !ok {
+		return nil
+	}
+	attributes := map[string]string{
+		KernelVolumeType:   fsType,
+		KernelVolumeSource: KernelMountSource(fsMeta.ServerAddress, fsMeta.SubPath),
+	}
+	if options := fsMeta.Properties[KernelMountOptions]; options != "" {
+		attributes[KernelVolumeOptions] = options
+	}
+	if localPath := fsMeta.Properties[LocalMountPath]; localPath != "" {
+		attributes[KernelVolumeLocalPath] = localPath
+	}
+	return attributes
+}
@@ -0,0 +1,54 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package base
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateMountOptions(t *testing.T) {
+	attributes := map[string]string{
+		"pfs.fs.id":            "fs-root-a",
+		MountBlockSize:         "4194304",
+		MountMemoryCacheExpire: "10m",
+		MountAllowOther:        "false",
+		MountSubPath:           "data/train",
+	}
+	assert.Nil(t, ValidateMountOptions(attributes, false))
+	assert.NotNil(t, ValidateMountOptions(attributes, true))
+	assert.Equal(t, 4, len(GetMountOptions(attributes)))
+
+	for _, options := range []map[string]string{
+		{MountBlockSize: "-1"},
+		{MountDiskCacheExpire: "10"},
+		{MountAllowOther: "yes"},
+		{MountSubPath: "/data"},
+		{MountSubPath: "../data"},
+		{MountReadOnly: "readonly"},
+		{MountAccessUser: ""},
+	} {
+		assert.NotNil(t, ValidateMountOptions(options, false), options)
+	}
+}
+
+func TestIsReadOnly(t *testing.T) {
+	assert.True(t, IsReadOnly(map[string]string{MountReadOnly: "true"}))
+	assert.False(t, IsReadOnly(map[string]string{MountReadOnly: "false"}))
+	assert.False(t, IsReadOnly(map[string]string{}))
+}
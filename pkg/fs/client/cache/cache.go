@@ -36,6 +36,8 @@ const (
 type store struct {
 	mem  *memCache
 	disk *diskCache
+	wb   *writeBack
+	peer *peerCache
 	conf Config
 	sync.RWMutex
 	meta map[string]string
@@ -45,10 +47,18 @@ type Config struct {
 	Mem       *MemConfig
 	Disk      *DiskConfig
 	BlockSize int
+	// ReadAheadWindow is the max number of blocks prefetched for sequential reads, 0 means disabled
+	ReadAheadWindow      int
+	ReadAheadConcurrency int
+	// WriteBack buffers the writes and uploads them asynchronously, nil means writing through
+	WriteBack *WriteBackConfig
+	// Peer shares the blocks with the other nodes, nil means node local cache only
+	Peer *PeerConfig
 }
 
 type rCache struct {
 	id    string
+	keyID string
 	store *store
 	ufsFh ufs.FileHandle
 }
@@ -67,22 +77,129 @@ func NewCacheStore(config *Config) Store {
 	if config.Disk != nil {
 		cacheStore.disk = NewDiskCache(config.Disk)
 	}
+	if config.WriteBack != nil && config.WriteBack.Dir != "" {
+		wb, err := newWriteBack(config.WriteBack)
+		if err != nil {
+			log.Errorf("init write back with dir[%s] failed: %v", config.WriteBack.Dir, err)
+		} else {
+			cacheStore.wb = wb
+		}
+	}
+	if config.Peer != nil && config.Peer.Listen != "" && (len(config.Peer.Peers) > 0 || config.Peer.Service != "") {
+		peer, err := newPeerCache(config.Peer, cacheStore)
+		if err != nil {
+			log.Errorf("init peer cache on [%s] failed: %v", config.Peer.Listen, err)
+		} else {
+			cacheStore.peer = peer
+		}
+	}
 
 	return cacheStore
 }
 
-func (store *store) NewReader(name string, fh ufs.FileHandle) Reader {
-	return &rCache{id: path.Clean(name), store: store, ufsFh: fh}
+func (store *store) NewReader(name string, version FileVersion, fh ufs.FileHandle) Reader {
+	name = path.Clean(name)
+	keyID := store.keyID(name, version)
+	store.Lock()
+	store.meta[name] = keyID
+	store.Unlock()
+	return &rCache{id: name, keyID: keyID, store: store, ufsFh: fh}
+}
+
+// keyID is derived from the fs, name and version of file, which is the same across restarts and nodes.
+func (store *store) keyID(name string, version FileVersion) string {
+	fsID := ""
+	if store.peer != nil {
+		fsID = store.peer.conf.FsID
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL,
+		[]byte(fmt.Sprintf("%s:%s:%d:%d", fsID, name, version.Mtime, version.Size))).String()
 }
 
 func (store *store) NewWriter(name string, length int, fh ufs.FileHandle) Writer {
-	return nil
+	if store.wb == nil {
+		return nil
+	}
+	w := &wCache{id: path.Clean(name), store: store, ufsFh: fh, length: int64(length)}
+	store.wb.register(w)
+	return w
+}
+
+func (store *store) DirtyLength(name string) (int64, bool) {
+	if store.wb == nil {
+		return 0, false
+	}
+	return store.wb.dirtyLength(path.Clean(name))
+}
+
+func (store *store) Metrics() StoreMetrics {
+	var m StoreMetrics
+	if store.mem != nil {
+		m.Mem = store.mem.Metrics()
+	}
+	if store.disk != nil {
+		m.Disk = store.disk.Metrics()
+	}
+	return m
+}
+
+func (store *store) Close() {
+	if store.disk != nil {
+		store.disk.close()
+	}
+	if store.peer != nil {
+		store.peer.close()
+	}
+}
+
+// loadLocal reads the block from the local memory or disk cache.
+func (store *store) loadLocal(key string) ([]byte, bool) {
+	if store.mem != nil {
+		if mem, ok := store.mem.load(key); ok {
+			data, err := io.ReadAll(mem)
+			return data, err == nil
+		}
+	}
+	if store.disk != nil {
+		if file, ok := store.disk.load(key); ok {
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			return data, err == nil
+		}
+	}
+	return nil, false
+}
+
+func (store *store) saveLocal(key string, data []byte) {
+	if store.mem != nil && store.conf.Mem.CacheSize > 0 {
+		store.mem.save(key, data)
+	}
+	if store.disk != nil {
+		store.disk.save(key, data)
+	}
+}
+
+func (store *store) deleteLocal(key string) {
+	if store.mem != nil {
+		store.mem.delete(key)
+	}
+	if store.disk != nil {
+		store.disk.delete(key)
+	}
+}
+
+func (store *store) Recover(open func(name string) (ufs.FileHandle, error)) error {
+	if store.wb == nil {
+		return nil
+	}
+	return store.wb.recover(open)
 }
 
 func (store *store) InvalidateCache(name string, length int) error {
 	write := 0
 	index := 0
 	name = path.Clean(name)
+	// the blocks of the other versions are not read any more, as the keys change with the version
 	store.RLock()
 	keyID, ok := store.meta[name]
 	store.RUnlock()
@@ -102,6 +219,9 @@ func (store *store) InvalidateCache(name string, length int) error {
 			if store.disk != nil {
 				store.disk.delete(key)
 			}
+			if store.peer != nil {
+				store.peer.delete(key)
+			}
 			write += store.conf.BlockSize
 			index += 1
 		}
@@ -131,7 +251,7 @@ func (r *rCache) ReadAt(buf []byte, off int64) (int, error) {
 	n, ok := r.readCache(buf, key, blockOff)
 	if ok {
 		// 最后一个block大小未填满
-		return n, nil
+		return r.overlay(buf, off, n), nil
 	}
 
 	// todo:: readAheadNum改成可配的
@@ -150,7 +270,48 @@ func (r *rCache) ReadAt(buf []byte, off int64) (int, error) {
 	*/
 	copy(buf, ufsBuf[blockOff:bufSize+blockOff])
 
-	return utils.Min(bufSize, n-blockOff), nil
+	return r.overlay(buf, off, utils.Min(bufSize, n-blockOff)), nil
+}
+
+// overlay keeps read-your-writes consistency with the dirty data not uploaded yet.
+func (r *rCache) overlay(buf []byte, off int64, n int) int {
+	if r.store.wb == nil {
+		return n
+	}
+	return r.store.wb.overlay(r.id, buf, off, n)
+}
+
+// Prefetch reads the block of index from ufs and saves it into cache, cached blocks are skipped.
+func (r *rCache) Prefetch(index int) error {
+	key := r.key(index)
+	if r.cached(key) {
+		return nil
+	}
+	blockSize := r.store.conf.BlockSize
+	buf := make([]byte, blockSize)
+	n, err := r.ufsFh.ReadAt(buf, int64(index*blockSize))
+	// the last block of file is read with io.EOF
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	log.Debugf("prefetch block[%d] of %s", index, r.id)
+	r.setCache(index*blockSize, buf, n)
+	return nil
+}
+
+func (r *rCache) cached(key string) bool {
+	if r.store.mem != nil {
+		if _, ok := r.store.mem.load(key); ok {
+			return true
+		}
+	}
+	if r.store.disk != nil {
+		return r.store.disk.exist(key)
+	}
+	return false
 }
 
 func (r *rCache) index(off int) int {
@@ -162,16 +323,7 @@ func (r *rCache) off(off int) int {
 }
 
 func (r *rCache) key(index int) string {
-	r.store.RLock()
-	keyID := r.store.meta[r.id]
-	r.store.RUnlock()
-	if keyID == "" {
-		r.store.Lock()
-		r.store.meta[r.id] = uuid.NewString()
-		r.store.Unlock()
-	}
-	hash := utils.KeyHash(keyID)
-	return path.Clean(fmt.Sprintf("blocks/%d/%v_%v", hash%256, keyID, index))
+	return r.store.key(r.keyID, index)
 }
 
 func (r *rCache) readCache(buf []byte, key string, off int) (int, bool) {
@@ -202,6 +354,20 @@ func (r *rCache) readCache(buf []byte, key string, off int) (int, bool) {
 			return n, true
 		}
 	}
+
+	// peer cache, the block is kept in local memory after fetched from peer
+	if r.store.peer != nil {
+		data, ok := r.store.peer.get(key)
+		if ok {
+			if r.store.mem != nil && r.store.conf.Mem.CacheSize > 0 {
+				r.store.mem.save(key, data)
+			}
+			if off >= len(data) {
+				return 0, true
+			}
+			return copy(buf, data[off:]), true
+		}
+	}
 	return 0, false
 }
 
@@ -230,6 +396,9 @@ func (r *rCache) setCache(off int, p []byte, n int) {
 		if r.store.disk != nil {
 			r.store.disk.save(key, p[left:right])
 		}
+		if r.store.peer != nil {
+			r.store.peer.save(key, p[left:right])
+		}
 		left += right - left
 	}
 }
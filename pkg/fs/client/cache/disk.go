@@ -19,11 +19,14 @@ package cache
 import (
 	"bufio"
 	"bytes"
+	"container/list"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -31,26 +34,59 @@ import (
 	"paddleflow/pkg/fs/utils/mount"
 )
 
-const CacheDir = "datacache"
+const (
+	CacheDir      = "datacache"
+	cleanInterval = 10 * time.Second
+)
 
 type cacheItem struct {
+	key     string
 	size    int64
 	expTime time.Time
+	elem    *list.Element
+}
+
+// Metrics counts the cache hits, misses and evictions.
+type Metrics struct {
+	Hits        int64
+	Misses      int64
+	Evictions   int64
+	Expirations int64
+}
+
+// StoreMetrics are the counters of memory and disk cache, the memory cache does not count expirations
+// separately, as the expired blocks are evicted on access.
+type StoreMetrics struct {
+	Mem  Metrics
+	Disk Metrics
 }
 
 type diskCache struct {
-	sync.RWMutex
-	dir      string
+	sync.Mutex
+	dir       string
+	maxSize   int64
+	freeRatio float64
+	// capacity and free are the size of the disk, used is the size of the cached blocks
 	capacity int64
+	free     int64
 	used     int64
 	expire   time.Duration
 	keys     map[string]*cacheItem
+	// lru keeps the most recently used block at front
+	lru      *list.List
+	metrics  Metrics
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 type DiskConfig struct {
 	Dir    string
 	Mode   os.FileMode
 	Expire time.Duration
+	// MaxSize is the max bytes of the cached blocks, 0 means unlimited
+	MaxSize int64
+	// FreeRatio is the min free ratio of the disk, blocks are evicted below it
+	FreeRatio float64
 }
 
 func NewDiskCache(config *DiskConfig) *diskCache {
@@ -58,26 +94,85 @@ func NewDiskCache(config *DiskConfig) *diskCache {
 		return nil
 	}
 
-	if config != nil {
-		d := &diskCache{
-			dir:    config.Dir,
-			keys:   make(map[string]*cacheItem),
-			expire: config.Expire,
+	d := &diskCache{
+		dir:       config.Dir,
+		maxSize:   config.MaxSize,
+		freeRatio: config.FreeRatio,
+		keys:      make(map[string]*cacheItem),
+		lru:       list.New(),
+		expire:    config.Expire,
+		stop:      make(chan struct{}),
+	}
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		log.Errorf("create disk cache dir[%s] failed: %v", config.Dir, err)
+		return nil
+	}
+	d.updateCapacity()
+	d.rebuild()
+	go d.run()
+	return d
+}
+
+// run cleans the cache periodically until close is called.
+func (c *diskCache) run() {
+	ticker := time.NewTicker(cleanInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			log.Infof("disk cache[%s] cleaner stopped", c.dir)
+			return
+		case <-ticker.C:
+			c.clean()
 		}
-		// TODO: 报错往上抛
-		os.MkdirAll(config.Dir, 0755)
-		d.updateCapacity()
-		// 后续加stop channel
-		go func() {
-			for {
-				d.clean()
-				time.Sleep(10 * time.Second)
-			}
-		}()
+	}
+}
+
+func (c *diskCache) close() {
+	c.stopOnce.Do(func() {
+		close(c.stop)
+	})
+}
+
+// rebuild restores the index from the cache files left by the last run, the
+// modification time of the files is used for the lru order.
+func (c *diskCache) rebuild() {
+	type cacheFile struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []cacheFile
+	root := filepath.Join(c.dir, CacheDir)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info == nil || info.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, ".tmp") {
+			os.Remove(path)
+			return nil
+		}
+		files = append(files, cacheFile{key: c.getKeyFromCachePath(path), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
 
-		return d
+	now := time.Now()
+	c.Lock()
+	for _, f := range files {
+		expTime := f.modTime.Add(c.expire)
+		if c.expire > 0 && expTime.Before(now) {
+			os.Remove(c.cachePath(f.key))
+			continue
+		}
+		c.add(f.key, f.size, expTime)
 	}
-	return nil
+	removed := c.evict(0)
+	c.Unlock()
+	c.removeFiles(removed)
+	log.Infof("disk cache[%s] rebuilt with %d blocks and %d bytes", c.dir, len(c.keys), c.used)
 }
 
 func (c *diskCache) load(key string) (ReadCloser, bool) {
@@ -85,26 +180,31 @@ func (c *diskCache) load(key string) (ReadCloser, bool) {
 		return nil, false
 	}
 
-	if !c.exist(key) {
-		return nil, false
+	c.Lock()
+	item, ok := c.keys[key]
+	if ok && c.expired(item) {
+		ok = false
 	}
-
-	path := c.cachePath(key)
-	_, err := os.Stat(path)
-
-	if os.IsNotExist(err) {
-		return nil, false
+	if ok {
+		c.lru.MoveToFront(item.elem)
 	}
-
-	if err != nil {
-		log.Errorf("stat cache file[%s] failed: %v", path, err)
+	c.Unlock()
+	if !ok {
+		atomic.AddInt64(&c.metrics.Misses, 1)
 		return nil, false
 	}
 
+	path := c.cachePath(key)
 	f, err := os.Open(path)
 	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Errorf("open cache file[%s] failed: %v", path, err)
+		}
+		c.delete(key)
+		atomic.AddInt64(&c.metrics.Misses, 1)
 		return nil, false
 	}
+	atomic.AddInt64(&c.metrics.Hits, 1)
 	return f, true
 }
 
@@ -113,13 +213,23 @@ func (c *diskCache) save(key string, buf []byte) {
 		return
 	}
 	cacheSize := int64(len(buf))
-	if c.used+cacheSize >= c.capacity {
-		// todo：clean支持带参数，释放多少容量。
-		c.clean()
+	if c.maxSize > 0 && cacheSize > c.maxSize {
+		return
 	}
 
-	// 清理之后还是没有足够的容量，则跳过
-	if c.used+cacheSize >= c.capacity {
+	c.Lock()
+	var removed []string
+	if item, ok := c.keys[key]; ok {
+		c.remove(item)
+	}
+	removed = c.evict(cacheSize)
+	full := c.full(cacheSize)
+	c.Unlock()
+	c.removeFiles(removed)
+
+	// 淘汰之后还是没有足够的容量，则跳过
+	if full {
+		log.Debugf("diskCache is full, skip key[%s]", key)
 		return
 	}
 	path := c.cachePath(key)
@@ -151,10 +261,11 @@ func (c *diskCache) save(key string, buf []byte) {
 	}
 
 	c.Lock()
-	c.keys[key] = &cacheItem{
-		expTime: time.Now().Add(c.expire),
-		size:    cacheSize,
+	if item, ok := c.keys[key]; ok {
+		c.remove(item)
 	}
+	c.add(key, cacheSize, time.Now().Add(c.expire))
+	c.free -= cacheSize
 	c.Unlock()
 	log.Debugf("diskCache save[%s] succeed", key)
 	return
@@ -163,8 +274,8 @@ func (c *diskCache) save(key string, buf []byte) {
 func (c *diskCache) delete(key string) {
 	path := c.cachePath(key)
 	c.Lock()
-	if c.keys[key] != nil {
-		delete(c.keys, key)
+	if item, ok := c.keys[key]; ok {
+		c.remove(item)
 	}
 	c.Unlock()
 	if path != "" {
@@ -173,35 +284,89 @@ func (c *diskCache) delete(key string) {
 }
 
 func (c *diskCache) clean() {
-	// 1. 首先清理掉已过期文件
-	for key, cache := range c.keys {
-		if time.Now().Sub(cache.expTime) >= 0 {
-			c.delete(key)
-		}
-	}
-
 	log.Debugf("the c.dir is [%s]", c.dir)
 	if c.dir == "/" || c.dir == "" {
 		return
 	}
-	// 2. 清理目录下存在，但是c.keys中不存在的的文件,
-	filepath.Walk(filepath.Join(c.dir, CacheDir), func(path string, info os.FileInfo, err error) error {
-		if info == nil {
-			return nil
-		}
-		if info.IsDir() {
-			return nil
+	c.updateCapacity()
+
+	// 1. 首先清理掉已过期文件 2. 超过容量时按lru淘汰
+	var removed []string
+	c.Lock()
+	for key, item := range c.keys {
+		if c.expired(item) {
+			c.remove(item)
+			removed = append(removed, key)
+			atomic.AddInt64(&c.metrics.Expirations, 1)
 		}
+	}
+	removed = append(removed, c.evict(0)...)
+	c.Unlock()
+	c.removeFiles(removed)
 
-		key := c.getKeyFromCachePath(path)
-		log.Debugf("clean dis cache key is %s and path is %s", key, path)
-		if c.keys[key] != nil {
-			return nil
+	m := c.Metrics()
+	log.Debugf("diskCache[%s] used[%d] hits[%d] misses[%d] evictions[%d] expirations[%d]",
+		c.dir, c.used, m.Hits, m.Misses, m.Evictions, m.Expirations)
+}
+
+// Metrics returns a snapshot of the counters.
+func (c *diskCache) Metrics() Metrics {
+	return Metrics{
+		Hits:        atomic.LoadInt64(&c.metrics.Hits),
+		Misses:      atomic.LoadInt64(&c.metrics.Misses),
+		Evictions:   atomic.LoadInt64(&c.metrics.Evictions),
+		Expirations: atomic.LoadInt64(&c.metrics.Expirations),
+	}
+}
+
+func (c *diskCache) expired(item *cacheItem) bool {
+	return c.expire > 0 && time.Now().After(item.expTime)
+}
+
+// full reports whether there is no room for size bytes, caller must hold the lock.
+func (c *diskCache) full(size int64) bool {
+	if c.maxSize > 0 && c.used+size > c.maxSize {
+		return true
+	}
+	if c.freeRatio > 0 && c.capacity > 0 && float64(c.free-size) < c.freeRatio*float64(c.capacity) {
+		return true
+	}
+	return false
+}
+
+// evict removes the least recently used blocks until there is room for size bytes,
+// the keys removed are returned and their files should be deleted without the lock.
+func (c *diskCache) evict(size int64) []string {
+	var removed []string
+	for c.full(size) && c.lru.Len() > 0 {
+		item := c.lru.Back().Value.(*cacheItem)
+		c.remove(item)
+		c.free += item.size
+		removed = append(removed, item.key)
+		atomic.AddInt64(&c.metrics.Evictions, 1)
+	}
+	return removed
+}
+
+func (c *diskCache) add(key string, size int64, expTime time.Time) {
+	item := &cacheItem{key: key, size: size, expTime: expTime}
+	item.elem = c.lru.PushFront(item)
+	c.keys[key] = item
+	c.used += size
+}
+
+func (c *diskCache) remove(item *cacheItem) {
+	c.lru.Remove(item.elem)
+	delete(c.keys, item.key)
+	c.used -= item.size
+}
+
+func (c *diskCache) removeFiles(keys []string) {
+	for _, key := range keys {
+		if err := os.Remove(c.cachePath(key)); err != nil && !os.IsNotExist(err) {
+			log.Errorf("remove cache file of key[%s] failed: %v", key, err)
 		}
-		err = os.Remove(path)
-		return err
-	})
-	c.updateCapacity()
+	}
 }
 
 func (c *diskCache) cachePath(key string) string {
@@ -217,17 +382,18 @@ func (c *diskCache) createDir(dir string) {
 }
 
 func (c *diskCache) exist(key string) bool {
-	c.RLock()
-	defer c.RUnlock()
+	c.Lock()
+	defer c.Unlock()
 	if value, ok := c.keys[key]; !ok {
 		return false
-	} else if value.expTime.Sub(time.Now()) <= 0 {
+	} else if c.expired(value) {
 		log.Debugf("expire key %s", key)
 		return false
 	}
 	return true
 }
 
+// updateCapacity gets the total and available size of the disk.
 func (c *diskCache) updateCapacity() error {
 	output, err := mount.ExecCmdWithTimeout("df", []string{"-k", c.dir})
 	if err != nil {
@@ -253,14 +419,14 @@ func (c *diskCache) updateCapacity() error {
 				log.Errorf("parse str[%s] failed: %v", strSlice[1], err)
 				return err
 			}
-			used, err := strconv.ParseInt(strSlice[2], 10, 64)
+			avail, err := strconv.ParseInt(strSlice[3], 10, 64)
 			if err != nil {
-				log.Errorf("parse str[%s] failed: %v", strSlice[2], err)
+				log.Errorf("parse str[%s] failed: %v", strSlice[3], err)
 				return err
 			}
 			c.Lock()
 			c.capacity = total * 1024
-			c.used = used * 1024
+			c.free = avail * 1024
 			c.Unlock()
 			return nil
 		}
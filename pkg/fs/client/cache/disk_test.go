@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/ufs"
+)
+
+func TestDiskCacheLRU(t *testing.T) {
+	config := &DiskConfig{Dir: t.TempDir(), Expire: time.Minute, MaxSize: 10}
+	c := NewDiskCache(config)
+	assert.NotNil(t, c)
+	defer c.close()
+
+	c.save("blocks/1/a_0", []byte("1234"))
+	c.save("blocks/1/a_1", []byte("5678"))
+	// a_0 is recently used, a_1 is evicted
+	_, ok := c.load("blocks/1/a_0")
+	assert.True(t, ok)
+	c.save("blocks/1/a_2", []byte("abcd"))
+	assert.True(t, c.exist("blocks/1/a_0"))
+	assert.False(t, c.exist("blocks/1/a_1"))
+	assert.True(t, c.exist("blocks/1/a_2"))
+	_, err := os.Stat(c.cachePath("blocks/1/a_1"))
+	assert.True(t, os.IsNotExist(err))
+	assert.Equal(t, int64(8), c.used)
+
+	// larger than max size, nothing is evicted
+	c.save("blocks/1/a_3", []byte("0123456789abc"))
+	assert.False(t, c.exist("blocks/1/a_3"))
+	assert.True(t, c.exist("blocks/1/a_0"))
+
+	_, ok = c.load("blocks/1/a_1")
+	assert.False(t, ok)
+	m := c.Metrics()
+	assert.Equal(t, int64(1), m.Hits)
+	assert.Equal(t, int64(1), m.Misses)
+	assert.Equal(t, int64(1), m.Evictions)
+}
+
+func TestDiskCacheRebuild(t *testing.T) {
+	config := &DiskConfig{Dir: t.TempDir(), Expire: time.Minute}
+	c := NewDiskCache(config)
+	assert.NotNil(t, c)
+	c.save("blocks/1/a_0", []byte("1234"))
+	c.save("blocks/2/b_0", []byte("5678"))
+	c.close()
+	// the temp file of an interrupted save is removed
+	tmp := c.cachePath("blocks/3/c_0") + ".tmp"
+	c.createDir(c.cachePath("blocks/3"))
+	assert.NoError(t, os.WriteFile(tmp, []byte("x"), 0644))
+
+	c = NewDiskCache(config)
+	defer c.close()
+	assert.True(t, c.exist("blocks/1/a_0"))
+	assert.True(t, c.exist("blocks/2/b_0"))
+	assert.Equal(t, int64(8), c.used)
+	_, err := os.Stat(tmp)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStoreKeyAcrossRestart(t *testing.T) {
+	config := &Config{
+		BlockSize: 4,
+		Mem:       &MemConfig{CacheSize: 1},
+		Disk:      &DiskConfig{Dir: t.TempDir(), Expire: time.Minute},
+	}
+	version := FileVersion{Mtime: 1, Size: 8}
+	s := NewCacheStore(config).(*store)
+	r := s.NewReader("/a", version, ufs.FileHandle{}).(*rCache)
+	r.setCache(0, []byte("12345678"), 8)
+	s.Close()
+
+	// the blocks cached before restart are read by the same version of file
+	s = NewCacheStore(config).(*store)
+	defer s.Close()
+	r = s.NewReader("/a", version, ufs.FileHandle{}).(*rCache)
+	buf := make([]byte, 4)
+	n, ok := r.readCache(buf, r.key(1), 0)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("5678"), buf[:n])
+	modified := s.NewReader("/a", FileVersion{Mtime: 2, Size: 8}, ufs.FileHandle{}).(*rCache)
+	assert.NotEqual(t, r.key(1), modified.key(1))
+	assert.False(t, modified.cached(modified.key(1)))
+
+	m := s.Metrics()
+	assert.Equal(t, int64(1), m.Disk.Hits)
+	assert.Equal(t, int64(2), m.Mem.Misses)
+	s.mem.save("x", []byte("1"))
+	s.mem.save("y", []byte("2"))
+	s.mem.delete("y")
+	assert.Equal(t, int64(1), s.Metrics().Mem.Evictions)
+}
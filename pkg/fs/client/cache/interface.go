@@ -24,16 +24,41 @@ import (
 
 type Reader interface {
 	io.ReaderAt
+	// Prefetch loads the block of index from ufs into cache
+	Prefetch(index int) error
 }
 
 type Writer interface {
 	io.WriterAt
+	// Flush uploads the dirty data asynchronously
+	Flush() error
+	// Fsync waits until the dirty data is uploaded
+	Fsync() error
+	// Close uploads the remaining dirty data and releases the ufs handle after uploading
+	Close() error
+}
+
+// FileVersion identifies the content of a file. The blocks are cached by the name and version of file,
+// so that they are reused after restart and by other nodes, until the file is modified.
+type FileVersion struct {
+	// Mtime is the modification time in nanoseconds
+	Mtime int64
+	Size  int64
 }
 
 type Store interface {
-	NewReader(name string, ufsFh ufs.FileHandle) Reader
+	NewReader(name string, version FileVersion, ufsFh ufs.FileHandle) Reader
+	// NewWriter returns nil if write back is disabled
 	NewWriter(name string, length int, ufsFh ufs.FileHandle) Writer
 	InvalidateCache(name string, length int) error
+	// DirtyLength returns the file length including the dirty data not uploaded yet
+	DirtyLength(name string) (int64, bool)
+	// Recover uploads the dirty data journaled before restart
+	Recover(open func(name string) (ufs.FileHandle, error)) error
+	// Metrics returns the counters of memory and disk cache
+	Metrics() StoreMetrics
+	// Close stops the background cleaner, it should be called on umount
+	Close()
 }
 
 type Cache interface {
@@ -18,6 +18,7 @@ package cache
 
 import (
 	"bytes"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluele/gcache"
@@ -25,6 +26,10 @@ import (
 
 type memCache struct {
 	m gcache.Cache
+	// removed and deleted count the blocks removed from cache and those deleted explicitly, the difference
+	// of them are the evictions
+	removed int64
+	deleted int64
 }
 
 type MemConfig struct {
@@ -36,11 +41,15 @@ func NewMemCache(config *MemConfig) *memCache {
 	if config.CacheSize <= 0 {
 		return nil
 	}
-	cacheBuilder := gcache.New(config.CacheSize).LRU()
+	c := &memCache{}
+	cacheBuilder := gcache.New(config.CacheSize).LRU().EvictedFunc(func(key, value interface{}) {
+		atomic.AddInt64(&c.removed, 1)
+	})
 	if config.Expire > 0 {
 		cacheBuilder.Expiration(config.Expire * time.Second)
 	}
-	return &memCache{m: cacheBuilder.Build()}
+	c.m = cacheBuilder.Build()
+	return c
 }
 
 func (c *memCache) load(key string) (ReadCloser, bool) {
@@ -55,9 +64,17 @@ func (c *memCache) save(key string, buf []byte) {
 }
 
 func (c *memCache) delete(key string) {
-	_, ok := c.load(key)
-	if ok {
-		c.m.Remove(key)
+	if c.m.Remove(key) {
+		atomic.AddInt64(&c.deleted, 1)
+	}
+}
+
+// Metrics returns a snapshot of the counters.
+func (c *memCache) Metrics() Metrics {
+	return Metrics{
+		Hits:      int64(c.m.HitCount()),
+		Misses:    int64(c.m.MissCount()),
+		Evictions: atomic.LoadInt64(&c.removed) - atomic.LoadInt64(&c.deleted),
 	}
 }
 
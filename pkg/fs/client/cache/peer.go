@@ -0,0 +1,451 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/utils"
+)
+
+const (
+	peerBlockPath       = "/v1/blocks/"
+	peerVirtualNodes    = 100
+	peerRefreshInterval = 30 * time.Second
+	peerDefaultTimeout  = 3 * time.Second
+	// peerTokenHeader carries the token shared by the nodes caching the same fs
+	peerTokenHeader = "X-PFS-Peer-Token"
+	// the saves and deletes sent to the owners are queued and sent by a fixed number of workers,
+	// they are dropped if the queue is full
+	peerWorkers   = 8
+	peerQueueSize = 1024
+	// a peer failed is skipped for a backoff doubled on every failure
+	peerMinBackoff = time.Second
+	peerMaxBackoff = time.Minute
+)
+
+var ErrPeerTokenRequired = errors.New("peer cache token is required")
+
+type PeerConfig struct {
+	// Listen is the address serving the local cache to peers, e.g. ":8620"
+	Listen string
+	// Self is the address of this node seen by the peers, e.g. "10.0.0.1:8620"
+	Self string
+	// Peers is the static member list including self
+	Peers []string
+	// Service is the kubernetes headless service of the cache nodes, the members are resolved by dns
+	Service string
+	// Port is the port of the members resolved from Service
+	Port int
+	// FsID distinguishes the blocks of different fs
+	FsID string
+	// Token is shared by the nodes caching the fs, the requests without it are rejected
+	Token   string
+	Timeout time.Duration
+}
+
+type peerTask struct {
+	method string
+	owner  string
+	key    string
+	buf    []byte
+}
+
+// peerBackoff is the circuit breaker of a peer, the peer is skipped until the time after failures.
+type peerBackoff struct {
+	failures int
+	until    time.Time
+}
+
+// hashRing locates the owner of a block by consistent hashing, every member has
+// peerVirtualNodes points on the ring to balance the blocks.
+type hashRing struct {
+	hashes []uint64
+	nodes  map[uint64]string
+}
+
+func newHashRing(members []string) *hashRing {
+	r := &hashRing{nodes: make(map[uint64]string)}
+	for _, member := range members {
+		for i := 0; i < peerVirtualNodes; i++ {
+			hash := utils.KeyHash(member + "#" + strconv.Itoa(i))
+			r.hashes = append(r.hashes, hash)
+			r.nodes[hash] = member
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool {
+		return r.hashes[i] < r.hashes[j]
+	})
+	return r
+}
+
+func (r *hashRing) get(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := utils.KeyHash(key)
+	i := sort.Search(len(r.hashes), func(i int) bool {
+		return r.hashes[i] >= hash
+	})
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.nodes[r.hashes[i]]
+}
+
+// peerCache is the cache tier shared by the nodes of a cluster. Every block is owned by
+// one node located by consistent hashing, the blocks are fetched from and saved to the
+// owner over http, and the owner keeps them in its local memory and disk cache.
+type peerCache struct {
+	conf   PeerConfig
+	store  *store
+	client *http.Client
+	server *http.Server
+	sync.RWMutex
+	ring    *hashRing
+	members []string
+	stop    chan struct{}
+	tasks   chan peerTask
+
+	backoffLock sync.Mutex
+	backoffs    map[string]*peerBackoff
+}
+
+func newPeerCache(config *PeerConfig, s *store) (*peerCache, error) {
+	if config.Token == "" {
+		return nil, ErrPeerTokenRequired
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = peerDefaultTimeout
+	}
+	p := &peerCache{
+		conf:     *config,
+		store:    s,
+		client:   &http.Client{Timeout: config.Timeout},
+		stop:     make(chan struct{}),
+		tasks:    make(chan peerTask, peerQueueSize),
+		backoffs: make(map[string]*peerBackoff),
+	}
+	if config.Service != "" {
+		if err := p.refresh(); err != nil {
+			log.Errorf("resolve peers of service[%s] failed: %v", config.Service, err)
+		}
+	} else {
+		p.setMembers(config.Peers)
+	}
+
+	listener, err := net.Listen("tcp", config.Listen)
+	if err != nil {
+		close(p.stop)
+		return nil, err
+	}
+	go p.run()
+	for i := 0; i < peerWorkers; i++ {
+		go p.work()
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(peerBlockPath, p.serveBlock)
+	p.server = &http.Server{Handler: mux}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("peer cache server on [%s] stopped: %v", config.Listen, err)
+		}
+	}()
+	log.Infof("peer cache listen on [%s] as [%s]", config.Listen, config.Self)
+	return p, nil
+}
+
+// run refreshes the members from the kubernetes service periodically.
+func (p *peerCache) run() {
+	ticker := time.NewTicker(peerRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			if p.conf.Service != "" {
+				if err := p.refresh(); err != nil {
+					log.Errorf("resolve peers of service[%s] failed: %v", p.conf.Service, err)
+				}
+			}
+			p.clean()
+		}
+	}
+}
+
+// work sends the queued saves and deletes to the owners.
+func (p *peerCache) work() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case task := <-p.tasks:
+			p.request(task.method, task.owner, task.key, task.buf)
+		}
+	}
+}
+
+func (p *peerCache) refresh() error {
+	addrs, err := net.LookupHost(p.conf.Service)
+	if err != nil {
+		return err
+	}
+	members := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		members = append(members, net.JoinHostPort(addr, strconv.Itoa(p.conf.Port)))
+	}
+	p.setMembers(members)
+	return nil
+}
+
+func (p *peerCache) setMembers(members []string) {
+	members = append([]string{}, members...)
+	sort.Strings(members)
+	p.Lock()
+	defer p.Unlock()
+	if strings.Join(members, ",") == strings.Join(p.members, ",") {
+		return
+	}
+	log.Infof("peer cache members changed to %v", members)
+	p.members = members
+	p.ring = newHashRing(members)
+}
+
+// owner returns the remote owner of key, it is empty if the block is owned by self.
+func (p *peerCache) owner(key string) string {
+	p.RLock()
+	defer p.RUnlock()
+	if p.ring == nil {
+		return ""
+	}
+	owner := p.ring.get(key)
+	if owner == p.conf.Self {
+		return ""
+	}
+	return owner
+}
+
+func (p *peerCache) blockURL(owner, key string) string {
+	return "http://" + owner + peerBlockPath + key
+}
+
+func (p *peerCache) get(key string) ([]byte, bool) {
+	owner := p.owner(key)
+	if owner == "" || !p.available(owner) {
+		return nil, false
+	}
+	req, err := p.newRequest(http.MethodGet, owner, key, nil)
+	if err != nil {
+		log.Errorf("new peer request failed: %v", err)
+		return nil, false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Debugf("get block[%s] from peer[%s] failed: %v", key, owner, err)
+		p.fail(owner)
+		return nil, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		p.fail(owner)
+		return nil, false
+	}
+	if resp.StatusCode != http.StatusOK {
+		p.succeed(owner)
+		return nil, false
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Debugf("read block[%s] from peer[%s] failed: %v", key, owner, err)
+		p.fail(owner)
+		return nil, false
+	}
+	p.succeed(owner)
+	return data, true
+}
+
+// available tells whether the owner is out of the backoff of its failures.
+func (p *peerCache) available(owner string) bool {
+	p.backoffLock.Lock()
+	defer p.backoffLock.Unlock()
+	b, ok := p.backoffs[owner]
+	return !ok || !time.Now().Before(b.until)
+}
+
+func (p *peerCache) fail(owner string) {
+	p.backoffLock.Lock()
+	defer p.backoffLock.Unlock()
+	b, ok := p.backoffs[owner]
+	if !ok {
+		b = &peerBackoff{}
+		p.backoffs[owner] = b
+	}
+	backoff := peerMinBackoff << uint(b.failures)
+	if backoff > peerMaxBackoff || backoff <= 0 {
+		backoff = peerMaxBackoff
+	} else {
+		b.failures++
+	}
+	b.until = time.Now().Add(backoff)
+	log.Debugf("peer[%s] is skipped for %v", owner, backoff)
+}
+
+func (p *peerCache) succeed(owner string) {
+	p.backoffLock.Lock()
+	defer p.backoffLock.Unlock()
+	delete(p.backoffs, owner)
+}
+
+func (p *peerCache) load(key string) (ReadCloser, bool) {
+	data, ok := p.get(key)
+	if !ok {
+		return nil, false
+	}
+	return NewMemReader(data), true
+}
+
+// save sends the block to its owner in background.
+func (p *peerCache) save(key string, buf []byte) {
+	p.enqueue(http.MethodPut, key, buf)
+}
+
+func (p *peerCache) delete(key string) {
+	p.enqueue(http.MethodDelete, key, nil)
+}
+
+// enqueue queues the request to the owner of key, it is dropped if the queue is full
+// or the owner is in backoff, as the peer cache is best effort.
+func (p *peerCache) enqueue(method, key string, buf []byte) {
+	owner := p.owner(key)
+	if owner == "" || !p.available(owner) {
+		return
+	}
+	select {
+	case p.tasks <- peerTask{method: method, owner: owner, key: key, buf: buf}:
+	default:
+		log.Debugf("peer queue is full, drop %s block[%s]", method, key)
+	}
+}
+
+func (p *peerCache) newRequest(method, owner, key string, buf []byte) (*http.Request, error) {
+	req, err := http.NewRequest(method, p.blockURL(owner, key), bytes.NewReader(buf))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(peerTokenHeader, p.conf.Token)
+	return req, nil
+}
+
+func (p *peerCache) request(method, owner, key string, buf []byte) {
+	req, err := p.newRequest(method, owner, key, buf)
+	if err != nil {
+		log.Errorf("new peer request failed: %v", err)
+		return
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		log.Debugf("%s block[%s] to peer[%s] failed: %v", method, key, owner, err)
+		p.fail(owner)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		p.fail(owner)
+	}
+}
+
+// clean drops the backoffs of the peers no longer members, the blocks saved to the
+// peers are evicted by their own memory and disk cache.
+func (p *peerCache) clean() {
+	p.RLock()
+	members := make(map[string]bool, len(p.members))
+	for _, member := range p.members {
+		members[member] = true
+	}
+	p.RUnlock()
+
+	p.backoffLock.Lock()
+	defer p.backoffLock.Unlock()
+	for owner := range p.backoffs {
+		if !members[owner] {
+			delete(p.backoffs, owner)
+		}
+	}
+}
+
+func (p *peerCache) close() {
+	select {
+	case <-p.stop:
+		return
+	default:
+		close(p.stop)
+	}
+	if p.server != nil {
+		p.server.Close()
+	}
+}
+
+// serveBlock serves the blocks owned by this node from the local memory and disk cache.
+func (p *peerCache) serveBlock(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get(peerTokenHeader)
+	if p.conf.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(p.conf.Token)) != 1 {
+		http.Error(w, "invalid peer token", http.StatusUnauthorized)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, peerBlockPath)
+	if key == "" || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		http.Error(w, fmt.Sprintf("invalid block key[%s]", key), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := p.store.loadLocal(key)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		data, err := io.ReadAll(io.LimitReader(r.Body, int64(p.store.conf.BlockSize)+1))
+		if err != nil || len(data) > p.store.conf.BlockSize {
+			http.Error(w, "invalid block", http.StatusBadRequest)
+			return
+		}
+		p.store.saveLocal(key, data)
+	case http.MethodDelete:
+		p.store.deleteLocal(key)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+var _ Cache = &peerCache{}
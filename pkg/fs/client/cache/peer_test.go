@@ -0,0 +1,139 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	members := []string{"10.0.0.1:8620", "10.0.0.2:8620", "10.0.0.3:8620"}
+	ring := newHashRing(members)
+	owners := make(map[string]string)
+	count := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("blocks/%d/a_%d", i%256, i)
+		owners[key] = ring.get(key)
+		count[owners[key]]++
+	}
+	assert.Equal(t, 3, len(count))
+
+	// only the blocks of the removed member are moved
+	ring = newHashRing(members[:2])
+	for key, owner := range owners {
+		if owner != members[2] {
+			assert.Equal(t, owner, ring.get(key))
+		}
+	}
+	assert.Equal(t, "", newHashRing(nil).get("blocks/1/a_0"))
+}
+
+func TestPeerCache(t *testing.T) {
+	ownerStore := NewCacheStore(&Config{
+		BlockSize: 16,
+		Mem:       &MemConfig{CacheSize: 10, Expire: time.Minute},
+	}).(*store)
+	owner := &peerCache{conf: PeerConfig{Token: "token"}, store: ownerStore}
+	server := httptest.NewServer(http.HandlerFunc(owner.serveBlock))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	p := &peerCache{
+		conf:     PeerConfig{Self: "127.0.0.1:0", FsID: "fs-root-test", Token: "token"},
+		client:   &http.Client{Timeout: time.Second},
+		stop:     make(chan struct{}),
+		backoffs: make(map[string]*peerBackoff),
+	}
+	p.setMembers([]string{addr, p.conf.Self})
+	key := "blocks/1/a_0"
+	for i := 0; p.owner(key) != addr; i++ {
+		key = fmt.Sprintf("blocks/1/a_%d", i)
+	}
+
+	_, ok := p.get(key)
+	assert.False(t, ok)
+	p.request(http.MethodPut, addr, key, []byte("1234"))
+	data, ok := p.get(key)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1234"), data)
+
+	// larger than block size
+	p.request(http.MethodPut, addr, "blocks/1/b_0", make([]byte, 17))
+	_, ok = ownerStore.loadLocal("blocks/1/b_0")
+	assert.False(t, ok)
+
+	p.request(http.MethodDelete, addr, key, nil)
+	_, ok = p.get(key)
+	assert.False(t, ok)
+
+	resp, err := http.Get(server.URL + peerBlockPath + "../a_0")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+
+	// the requests without the token are rejected
+	ownerStore.saveLocal(key, []byte("1234"))
+	resp, err = http.Get(server.URL + peerBlockPath + key)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	p.conf.Token = "wrong"
+	_, ok = p.get(key)
+	assert.False(t, ok)
+	p.conf.Token = "token"
+	_, ok = p.get(key)
+	assert.True(t, ok)
+}
+
+func TestPeerCacheBackoff(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	addr := strings.TrimPrefix(server.URL, "http://")
+
+	p := &peerCache{
+		conf:     PeerConfig{Self: "127.0.0.1:0", Token: "token"},
+		client:   &http.Client{Timeout: time.Second},
+		stop:     make(chan struct{}),
+		tasks:    make(chan peerTask, 1),
+		backoffs: make(map[string]*peerBackoff),
+	}
+	p.setMembers([]string{addr})
+	_, ok := p.get("blocks/1/a_0")
+	assert.False(t, ok)
+	assert.False(t, p.available(addr))
+	assert.Equal(t, 1, p.backoffs[addr].failures)
+
+	// the saves to a peer in backoff are dropped
+	p.save("blocks/1/a_0", []byte("1234"))
+	assert.Equal(t, 0, len(p.tasks))
+
+	p.setMembers([]string{p.conf.Self})
+	p.clean()
+	assert.True(t, p.available(addr))
+
+	_, err := newPeerCache(&PeerConfig{Listen: "127.0.0.1:0", Peers: []string{addr}}, nil)
+	assert.Equal(t, ErrPeerTokenRequired, err)
+}
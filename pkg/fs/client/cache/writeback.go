@@ -0,0 +1,525 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/ufs"
+)
+
+const (
+	journalSuffix       = ".journal"
+	uploadRetryTimes    = 3
+	uploadRetryInterval = time.Second
+	// failedRetryInterval is the interval to upload the failed batches of closed writers again
+	failedRetryInterval = 30 * time.Second
+)
+
+type WriteBackConfig struct {
+	// Dir keeps the journals of dirty data, so that the uploads survive a restart
+	Dir string
+	// DirtyLimit is the max bytes of dirty data, writes are blocked until uploaded when exceeded
+	DirtyLimit int64
+}
+
+// writeBack buffers the writes in memory and uploads them to ufs asynchronously.
+type writeBack struct {
+	conf  WriteBackConfig
+	mu    sync.Mutex
+	cond  *sync.Cond
+	dirty int64
+	files map[string][]*wCache
+}
+
+type extent struct {
+	off  int64
+	data []byte
+}
+
+type uploadBatch struct {
+	extents []extent
+	journal string
+	size    int64
+}
+
+// wCache is the write back writer of one file handle. Writes are appended to a journal
+// and kept in memory, Flush uploads them in background by order.
+type wCache struct {
+	id      string
+	store   *store
+	ufsFh   ufs.FileHandle
+	mu      sync.Mutex
+	length  int64
+	extents []extent
+	size    int64
+	journal *os.File
+	// pending are the batches not uploaded yet, including the failed ones, which are kept for reading
+	pending []*uploadBatch
+	// failed are the batches failed to upload by order, they are uploaded again by the next schedule
+	failed []*uploadBatch
+	// lastDone is closed when the last scheduled batch is uploaded
+	lastDone chan struct{}
+	err      error
+	closed   bool
+}
+
+func newWriteBack(config *WriteBackConfig) (*writeBack, error) {
+	if err := os.MkdirAll(config.Dir, 0755); err != nil {
+		return nil, err
+	}
+	wb := &writeBack{
+		conf:  *config,
+		files: make(map[string][]*wCache),
+	}
+	wb.cond = sync.NewCond(&wb.mu)
+	return wb, nil
+}
+
+// acquire reserves n bytes of dirty data. When the dirty limit is exceeded, all the
+// writers are flushed and it waits until the uploads release enough room.
+func (wb *writeBack) acquire(n int64) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	for wb.dirty > 0 && wb.dirty+n > wb.conf.DirtyLimit {
+		var writers []*wCache
+		for _, ws := range wb.files {
+			writers = append(writers, ws...)
+		}
+		wb.mu.Unlock()
+		for _, w := range writers {
+			w.Flush()
+		}
+		wb.mu.Lock()
+		if wb.dirty > 0 && wb.dirty+n > wb.conf.DirtyLimit {
+			wb.cond.Wait()
+		}
+	}
+	wb.dirty += n
+}
+
+func (wb *writeBack) release(n int64) {
+	wb.mu.Lock()
+	wb.dirty -= n
+	wb.mu.Unlock()
+	wb.cond.Broadcast()
+}
+
+func (wb *writeBack) register(w *wCache) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	wb.files[w.id] = append(wb.files[w.id], w)
+}
+
+func (wb *writeBack) unregister(w *wCache) {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	writers := wb.files[w.id]
+	for i, writer := range writers {
+		if writer == w {
+			writers = append(writers[:i], writers[i+1:]...)
+			break
+		}
+	}
+	if len(writers) == 0 {
+		delete(wb.files, w.id)
+	} else {
+		wb.files[w.id] = writers
+	}
+}
+
+func (wb *writeBack) writers(name string) []*wCache {
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+	return append([]*wCache{}, wb.files[name]...)
+}
+
+// overlay copies the dirty data of the file into buf which was read from off, n is the
+// number of bytes already read, the new number of valid bytes is returned.
+func (wb *writeBack) overlay(name string, buf []byte, off int64, n int) int {
+	for _, w := range wb.writers(name) {
+		n = w.overlay(buf, off, n)
+	}
+	return n
+}
+
+// dirtyLength returns the file length including the dirty data which is not uploaded yet.
+func (wb *writeBack) dirtyLength(name string) (int64, bool) {
+	var length int64
+	found := false
+	for _, w := range wb.writers(name) {
+		w.mu.Lock()
+		if len(w.extents) > 0 || len(w.pending) > 0 {
+			found = true
+			if w.length > length {
+				length = w.length
+			}
+		}
+		w.mu.Unlock()
+	}
+	return length, found
+}
+
+func (wb *writeBack) journalPath() string {
+	return filepath.Join(wb.conf.Dir, fmt.Sprintf("%020d_%s%s", time.Now().UnixNano(), uuid.NewString(), journalSuffix))
+}
+
+func (w *wCache) WriteAt(p []byte, off int64) (int, error) {
+	w.store.wb.acquire(int64(len(p)))
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		w.store.wb.release(int64(len(p)))
+		return 0, fmt.Errorf("writer of %s is closed", w.id)
+	}
+	if err := w.appendJournal(p, off); err != nil {
+		w.store.wb.release(int64(len(p)))
+		return 0, err
+	}
+	data := make([]byte, len(p))
+	copy(data, p)
+	w.extents = append(w.extents, extent{off: off, data: data})
+	w.size += int64(len(p))
+	if end := off + int64(len(p)); end > w.length {
+		w.length = end
+	}
+	return len(p), nil
+}
+
+// appendJournal writes the record [off:8][len:4][data] to journal, the journal starts with [len:4][name].
+func (w *wCache) appendJournal(p []byte, off int64) error {
+	if w.journal == nil {
+		f, err := os.OpenFile(w.store.wb.journalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Errorf("create journal of %s failed: %v", w.id, err)
+			return err
+		}
+		header := make([]byte, 4+len(w.id))
+		binary.BigEndian.PutUint32(header, uint32(len(w.id)))
+		copy(header[4:], w.id)
+		if _, err = f.Write(header); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return err
+		}
+		// make the new journal entry durable, the records are synced when the batch is scheduled
+		if err = syncDir(w.store.wb.conf.Dir); err != nil {
+			log.Warningf("sync journal dir of %s failed: %v", w.id, err)
+		}
+		w.journal = f
+	}
+	record := make([]byte, 12+len(p))
+	binary.BigEndian.PutUint64(record, uint64(off))
+	binary.BigEndian.PutUint32(record[8:], uint32(len(p)))
+	copy(record[12:], p)
+	_, err := w.journal.Write(record)
+	if err != nil {
+		log.Errorf("write journal of %s failed: %v", w.id, err)
+	}
+	return err
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Flush schedules the upload of the dirty data and returns the error of the former uploads.
+func (w *wCache) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	w.schedule(nil)
+	return err
+}
+
+// schedule moves the dirty extents into an upload batch, the batches are uploaded one
+// by one in background after the failed ones. after is called when all the scheduled batches are done.
+func (w *wCache) schedule(after func()) {
+	if len(w.extents) == 0 && len(w.failed) == 0 && after == nil {
+		return
+	}
+	var batch *uploadBatch
+	if len(w.extents) > 0 {
+		batch = &uploadBatch{extents: w.extents, size: w.size}
+		if w.journal != nil {
+			batch.journal = w.journal.Name()
+			// the journal must survive a crash until the batch is uploaded
+			if err := w.journal.Sync(); err != nil {
+				log.Errorf("sync journal of %s failed: %v", w.id, err)
+			}
+			w.journal.Close()
+			w.journal = nil
+		}
+		w.extents = nil
+		w.size = 0
+		w.pending = append(w.pending, batch)
+	}
+
+	prev := w.lastDone
+	done := make(chan struct{})
+	w.lastDone = done
+	go func() {
+		defer close(done)
+		if prev != nil {
+			<-prev
+		}
+		// the failed batches are uploaded again before the new one, to keep the order of writes
+		w.mu.Lock()
+		batches := w.failed
+		w.failed = nil
+		w.err = nil
+		w.mu.Unlock()
+		if batch != nil {
+			batches = append(batches, batch)
+		}
+		for _, b := range batches {
+			w.upload(b)
+		}
+		if after != nil {
+			after()
+		}
+	}()
+}
+
+func (w *wCache) upload(batch *uploadBatch) {
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+	// once a batch failed, the later batches are not uploaded either, so that they are uploaded by order later
+	if err == nil {
+		for i := 0; i < uploadRetryTimes; i++ {
+			if err = uploadExtents(w.ufsFh, batch.extents); err == nil {
+				break
+			}
+			log.Errorf("upload dirty data of %s failed %d times: %v", w.id, i+1, err)
+			time.Sleep(uploadRetryInterval * time.Duration(i+1))
+		}
+	}
+
+	w.mu.Lock()
+	if err != nil {
+		// the batch is kept in memory for reading and in journal for restart, until it is uploaded again
+		w.err = err
+		w.failed = append(w.failed, batch)
+		w.mu.Unlock()
+		return
+	}
+	for i, b := range w.pending {
+		if b == batch {
+			w.pending = append(w.pending[:i], w.pending[i+1:]...)
+			break
+		}
+	}
+	w.mu.Unlock()
+
+	if batch.journal != "" {
+		os.Remove(batch.journal)
+	}
+	w.store.wb.release(batch.size)
+	// the blocks cached before uploading are stale
+	w.store.InvalidateCache(w.id, int(w.length))
+}
+
+func uploadExtents(fh ufs.FileHandle, extents []extent) error {
+	for _, e := range extents {
+		if _, err := fh.WriteAt(e.data, e.off); err != nil {
+			return err
+		}
+	}
+	return fh.Flush()
+}
+
+// Fsync waits until all the dirty data is uploaded.
+func (w *wCache) Fsync() error {
+	w.mu.Lock()
+	w.schedule(nil)
+	done := w.lastDone
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Close waits until the remaining dirty data is uploaded and returns the upload error. The ufs handle is
+// released after all data is uploaded, the failed batches are uploaded again in background until then.
+func (w *wCache) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		err := w.err
+		w.mu.Unlock()
+		return err
+	}
+	w.closed = true
+	w.schedule(nil)
+	done := w.lastDone
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+	w.mu.Lock()
+	err := w.err
+	w.mu.Unlock()
+	w.finish()
+	return err
+}
+
+// finish releases the closed writer if all data is uploaded, otherwise uploads the failed batches again later.
+func (w *wCache) finish() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.failed) == 0 {
+		w.store.wb.unregister(w)
+		w.ufsFh.Release()
+		return
+	}
+	log.Warningf("upload dirty data of %s failed, retry in %s: %v", w.id, failedRetryInterval, w.err)
+	time.AfterFunc(failedRetryInterval, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		w.schedule(w.finish)
+	})
+}
+
+func (w *wCache) overlay(buf []byte, off int64, n int) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, b := range w.pending {
+		n = overlayExtents(b.extents, buf, off, n)
+	}
+	return overlayExtents(w.extents, buf, off, n)
+}
+
+func overlayExtents(extents []extent, buf []byte, off int64, n int) int {
+	if n < 0 {
+		n = 0
+	}
+	bufEnd := off + int64(len(buf))
+	for _, e := range extents {
+		start, end := e.off, e.off+int64(len(e.data))
+		if start < off {
+			start = off
+		}
+		if end > bufEnd {
+			end = bufEnd
+		}
+		if start >= end {
+			continue
+		}
+		// the hole between the read data and the dirty data is filled with zero
+		for i := n; i < int(start-off); i++ {
+			buf[i] = 0
+		}
+		copy(buf[start-off:end-off], e.data[start-e.off:end-e.off])
+		if int(end-off) > n {
+			n = int(end - off)
+		}
+	}
+	return n
+}
+
+// recover uploads the journals left by the last run, open returns the ufs handle of the file.
+func (wb *writeBack) recover(open func(name string) (ufs.FileHandle, error)) error {
+	entries, err := os.ReadDir(wb.conf.Dir)
+	if err != nil {
+		return err
+	}
+	var journals []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), journalSuffix) {
+			journals = append(journals, filepath.Join(wb.conf.Dir, entry.Name()))
+		}
+	}
+	// the journal names start with the creation time
+	sort.Strings(journals)
+	for _, journal := range journals {
+		name, extents, err := readJournal(journal)
+		if err != nil {
+			log.Errorf("read journal[%s] failed: %v", journal, err)
+			continue
+		}
+		if len(extents) > 0 {
+			fh, err := open(name)
+			if err != nil {
+				log.Errorf("open file[%s] for journal[%s] failed: %v", name, journal, err)
+				continue
+			}
+			err = uploadExtents(fh, extents)
+			fh.Release()
+			if err != nil {
+				log.Errorf("upload journal[%s] of file[%s] failed: %v", journal, name, err)
+				continue
+			}
+		}
+		log.Infof("journal[%s] of file[%s] recovered", journal, name)
+		os.Remove(journal)
+	}
+	return nil
+}
+
+// readJournal parses the journal, an incomplete record at the end is ignored.
+func readJournal(journal string) (string, []extent, error) {
+	f, err := os.Open(journal)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	lenBuf := make([]byte, 4)
+	if _, err = io.ReadFull(r, lenBuf); err != nil {
+		return "", nil, err
+	}
+	nameBuf := make([]byte, binary.BigEndian.Uint32(lenBuf))
+	if _, err = io.ReadFull(r, nameBuf); err != nil {
+		return "", nil, err
+	}
+
+	var extents []extent
+	head := make([]byte, 12)
+	for {
+		if _, err = io.ReadFull(r, head); err != nil {
+			break
+		}
+		data := make([]byte, binary.BigEndian.Uint32(head[8:]))
+		if _, err = io.ReadFull(r, data); err != nil {
+			break
+		}
+		extents = append(extents, extent{off: int64(binary.BigEndian.Uint64(head)), data: data})
+	}
+	return path.Clean(string(nameBuf)), extents, nil
+}
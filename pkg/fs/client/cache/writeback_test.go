@@ -0,0 +1,165 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/ufs"
+)
+
+func newTestStore(t *testing.T, dir string) *store {
+	s := NewCacheStore(&Config{
+		Mem:       &MemConfig{CacheSize: 10},
+		BlockSize: 4,
+		WriteBack: &WriteBackConfig{Dir: filepath.Join(dir, "writeback"), DirtyLimit: 1024},
+	})
+	assert.NotNil(t, s)
+	return s.(*store)
+}
+
+func TestWriteBack(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := ufs.NewLocalFileSystem(map[string]interface{}{base.SubPath: filepath.Join(dir, "ufs")})
+	assert.NoError(t, err)
+	fd, err := fs.Create("hello", uint32(os.O_WRONLY|os.O_CREATE), 0755)
+	assert.NoError(t, err)
+
+	s := newTestStore(t, dir)
+	w := s.NewWriter("hello", 0, ufs.NewFileHandle(fd))
+	assert.NotNil(t, w)
+	_, err = w.WriteAt([]byte("hello"), 0)
+	assert.NoError(t, err)
+	_, err = w.WriteAt([]byte("world"), 6)
+	assert.NoError(t, err)
+	length, ok := s.DirtyLength("hello")
+	assert.True(t, ok)
+	assert.Equal(t, int64(11), length)
+
+	// read your writes before uploading
+	buf := make([]byte, 4)
+	n := s.wb.overlay("hello", buf, 4, 0)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte{'o', 0, 'w', 'o'}, buf)
+
+	assert.NoError(t, w.Fsync())
+	data, err := os.ReadFile(filepath.Join(dir, "ufs", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello\x00world"), data)
+	_, ok = s.DirtyLength("hello")
+	assert.False(t, ok)
+	journals, _ := os.ReadDir(filepath.Join(dir, "writeback"))
+	assert.Empty(t, journals)
+	assert.NoError(t, w.Close())
+}
+
+func TestWriteBackRecover(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := ufs.NewLocalFileSystem(map[string]interface{}{base.SubPath: filepath.Join(dir, "ufs")})
+	assert.NoError(t, err)
+	fd, err := fs.Create("hello", uint32(os.O_WRONLY|os.O_CREATE), 0755)
+	assert.NoError(t, err)
+	fd.Release()
+
+	// the dirty data is only in journal, as if the client was killed before uploading
+	s := newTestStore(t, dir)
+	w := s.NewWriter("hello", 0, ufs.FileHandle{})
+	_, err = w.WriteAt([]byte("hello"), 0)
+	assert.NoError(t, err)
+	_, err = w.WriteAt([]byte("HE"), 0)
+	assert.NoError(t, err)
+
+	s = newTestStore(t, dir)
+	err = s.Recover(func(name string) (ufs.FileHandle, error) {
+		assert.Equal(t, "hello", name)
+		fd, err := fs.Open(name, uint32(os.O_WRONLY))
+		if err != nil {
+			return ufs.FileHandle{}, err
+		}
+		return ufs.NewFileHandle(fd), nil
+	})
+	assert.NoError(t, err)
+	data, err := os.ReadFile(filepath.Join(dir, "ufs", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("HEllo"), data)
+	journals, _ := os.ReadDir(filepath.Join(dir, "writeback"))
+	assert.Empty(t, journals)
+}
+
+func TestWriteBackUploadFailed(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := ufs.NewLocalFileSystem(map[string]interface{}{base.SubPath: filepath.Join(dir, "ufs")})
+	assert.NoError(t, err)
+	fd, err := fs.Create("hello", uint32(os.O_WRONLY|os.O_CREATE), 0755)
+	assert.NoError(t, err)
+	fd.Release()
+	// uploading with a read only handle fails
+	roFd, err := fs.Open("hello", uint32(os.O_RDONLY))
+	assert.NoError(t, err)
+
+	s := newTestStore(t, dir)
+	w := s.NewWriter("hello", 0, ufs.NewFileHandle(roFd))
+	_, err = w.WriteAt([]byte("hello"), 0)
+	assert.NoError(t, err)
+	assert.Error(t, w.Fsync())
+
+	// the failed data is still readable and kept in journal
+	buf := make([]byte, 5)
+	assert.Equal(t, 5, s.wb.overlay("hello", buf, 0, 0))
+	assert.Equal(t, []byte("hello"), buf)
+	_, ok := s.DirtyLength("hello")
+	assert.True(t, ok)
+	journals, _ := os.ReadDir(filepath.Join(dir, "writeback"))
+	assert.Equal(t, 1, len(journals))
+
+	// uploaded again by the next fsync
+	rwFd, err := fs.Open("hello", uint32(os.O_WRONLY))
+	assert.NoError(t, err)
+	w.(*wCache).ufsFh = ufs.NewFileHandle(rwFd)
+	assert.NoError(t, w.Fsync())
+	data, err := os.ReadFile(filepath.Join(dir, "ufs", "hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("hello"), data)
+	_, ok = s.DirtyLength("hello")
+	assert.False(t, ok)
+	journals, _ = os.ReadDir(filepath.Join(dir, "writeback"))
+	assert.Empty(t, journals)
+	assert.NoError(t, w.Close())
+	roFd.Release()
+}
+
+func TestPrefetchLastBlock(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := ufs.NewLocalFileSystem(map[string]interface{}{base.SubPath: filepath.Join(dir, "ufs")})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "ufs", "hello"), []byte("hello"), 0644))
+	fd, err := fs.Open("hello", uint32(os.O_RDONLY))
+	assert.NoError(t, err)
+
+	s := newTestStore(t, dir)
+	r := s.NewReader("hello", FileVersion{Mtime: 1, Size: 5}, ufs.NewFileHandle(fd)).(*rCache)
+	assert.NoError(t, r.Prefetch(1))
+	assert.True(t, r.cached(r.key(1)))
+	// nothing to prefetch after the end of file
+	assert.NoError(t, r.Prefetch(2))
+	assert.False(t, r.cached(r.key(2)))
+}
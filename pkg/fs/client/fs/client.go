@@ -25,20 +25,16 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/meta"
 )
 
 var defaultPfsServer string
-var linkMetaDirPrefix string
 
 // pfsServer服务地址
 func SetPFSServer(server string) {
 	defaultPfsServer = server
 }
 
-func SetLinkMetaDirPrefix(dirPrefix string) {
-	linkMetaDirPrefix = dirPrefix
-}
-
 var (
 	MemCacheSize    = 1 << 26 // 64M
 	MemCacheExpire  = 60 * time.Second
@@ -80,8 +76,14 @@ type FSClient interface {
 	Copy(srcPath, dstPath string) error
 	Size(path string) (int64, error)
 	Chmod(path string, fm os.FileMode) error
+	Chown(path string, uid, gid int) error
+	SetXAttr(path string, name string, value []byte) error
 	Walk(root string, walkFn filepath.WalkFunc) error
 	Stat(path string) (os.FileInfo, error)
+	// Warmup reads every file under path with bounded parallelism to load the blocks into cache
+	Warmup(path string, concurrency int, progress func(WarmupStats)) (WarmupStats, error)
+	// Close releases the vfs of the client, e.g. the cleaner of the disk cache, the client is not usable after it
+	Close()
 }
 
 func NewFSClientWithServer(server string, fsID string) (FSClient, error) {
@@ -93,23 +95,24 @@ func NewFSClientWithFsID(fsID string) (FSClient, error) {
 }
 
 func NewFSClient(fsMeta base.FSMeta, links map[string]base.FSMeta) (FSClient, error) {
-	return newFSClientWithFsMeta(fsMeta, links, "")
+	return newFSClientWithFsMeta(fsMeta, links, "", nil)
 }
 
 func newFSClient(server, fsID string) (FSClient, error) {
-	fsMeta, links, err := getMetaAndLinks(server, fsID)
+	fsMeta, links, watcher, err := getMetaAndLinks(server, fsID)
 	if err != nil {
 		log.Errorf("get fs meta and links failed: %v", err)
 		return nil, err
 	}
-	return newFSClientWithFsMeta(fsMeta, links, server)
+	return newFSClientWithFsMeta(fsMeta, links, server, watcher)
 }
 
-func newFSClientWithFsMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, server string) (FSClient, error) {
+func newFSClientWithFsMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, server string,
+	watcher meta.LinkWatcher) (FSClient, error) {
 	if fsMeta.UfsType == base.MockType {
 		return &MockClient{pathPrefix: fsMeta.SubPath}, nil
 	}
-	client, err := NewPFSClient(fsMeta, links)
+	client, err := newPFSClient(fsMeta, links, watcher, false)
 	client.fsID = fsMeta.ID
 	client.server = server
 	return client, err
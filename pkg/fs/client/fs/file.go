@@ -245,7 +245,7 @@ func (f *File) ReadAt(b []byte, off int64) (int, error) {
 
 func (f *File) ReadDir(n int) ([]os.DirEntry, error) {
 	ctx := meta.NewEmptyContext()
-	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0)
+	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0, true)
 	if utils.IsError(err) {
 		return []os.DirEntry{}, err
 	}
@@ -279,7 +279,7 @@ func (f *File) ReadFrom(r io.Reader) (n int64, err error) {
 
 func (f *File) Readdir(n int) ([]os.FileInfo, error) {
 	ctx := meta.NewEmptyContext()
-	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0)
+	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0, true)
 	if utils.IsError(err) {
 		return []os.FileInfo{}, err
 	}
@@ -301,7 +301,7 @@ func (f *File) Readdir(n int) ([]os.FileInfo, error) {
 
 func (f *File) Readdirnames(n int) ([]string, error) {
 	ctx := meta.NewEmptyContext()
-	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0)
+	entries, err := f.fs.vfs.ReadDir(ctx, f.inode, f.fh, 0, false)
 	if utils.IsError(err) {
 		return []string{}, err
 	}
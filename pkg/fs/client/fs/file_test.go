@@ -44,7 +44,7 @@ func newPfsTest() (*FileSystem, error) {
 		vfs.WithDiskExpire(DiskCacheExpire),
 		vfs.WithDiskCachePath(DiskCachePath),
 	)
-	pfs, err := NewFileSystem(testFsMeta, nil, true, true, "", vfsConfig)
+	pfs, err := NewFileSystem(testFsMeta, nil, true, true, nil, vfsConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -41,7 +41,7 @@ type FileSystem struct {
 // entryExpire 记录path对应的ino信息，一般不会修改，因此可以不设置过期时间（entryExpire = 0）。
 // attrExpire 记录ino对应节点的attr属性，包括mode、uid、gid和mtime等信息，文件修改时会改变。
 func NewFileSystem(fsMeta base.FSMeta, links map[string]base.FSMeta, skipSub bool, hasCache bool,
-	linkMetaDirPrefix string, config *vfs.Config) (*FileSystem, error) {
+	watcher meta.LinkWatcher, config *vfs.Config) (*FileSystem, error) {
 	fs := FileSystem{fsMeta: fsMeta, stop: make(chan struct{})}
 	// todo:: 客户端增加配置，填充到这里
 	// config := &vfs.Config{Cache: cache}
@@ -55,8 +55,8 @@ func NewFileSystem(fsMeta base.FSMeta, links map[string]base.FSMeta, skipSub boo
 	runtime.SetFinalizer(&fs, func(fs *FileSystem) {
 		close(fs.stop)
 	})
-	if !skipSub {
-		go vfs.Meta.LinksMetaUpdateHandler(fs.stop, meta.DefaultLinkUpdateInterval, linkMetaDirPrefix)
+	if !skipSub && watcher != nil {
+		go vfs.Meta.LinksMetaUpdateHandler(fs.stop, meta.DefaultLinkUpdateInterval, watcher)
 	}
 
 	if hasCache {
@@ -65,6 +65,13 @@ func NewFileSystem(fsMeta base.FSMeta, links map[string]base.FSMeta, skipSub boo
 	return &fs, nil
 }
 
+// Close stops the links update and releases the vfs, it is called once instead of the finalizer
+func (fs *FileSystem) Close() {
+	runtime.SetFinalizer(fs, nil)
+	close(fs.stop)
+	fs.vfs.Close()
+}
+
 func (fs *FileSystem) setCache(entrySize, attrSize, entryExpire, attrExpire int) {
 	fs.cache = NewMetaCache(entrySize, attrSize, entryExpire, attrExpire)
 }
@@ -141,6 +148,19 @@ func (fs *FileSystem) Chown(name string, uid, gid int) error {
 	return nil
 }
 
+func (fs *FileSystem) SetXAttr(name string, attr string, value []byte) error {
+	ctx := meta.NewEmptyContext()
+	_, ino, sysErr := fs.lookup(ctx, name, true)
+	if utils.IsError(sysErr) {
+		return sysErr
+	}
+
+	if err := fs.vfs.SetXAttr(ctx, ino, attr, value, 0); utils.IsError(err) {
+		return err
+	}
+	return nil
+}
+
 func (fs *FileSystem) Stat(path string) (os.FileInfo, error) {
 	ctx := meta.NewEmptyContext()
 	attr, _, err := fs.lookup(ctx, path, true)
@@ -220,6 +240,20 @@ func (fs *FileSystem) Rename(oldPath string, newPath string) error {
 	return nil
 }
 
+// CopyFile copies src to dst by the ufs, syscall.ENOTSUP is returned if the ufs doesn't
+// support server side copy and the data has to be streamed through the client.
+func (fs *FileSystem) CopyFile(src, dst string) error {
+	ctx := meta.NewEmptyContext()
+	err := fs.vfs.CopyFile(ctx, path.Clean(src), path.Clean(dst))
+	if utils.IsError(err) {
+		return err
+	}
+	if fs.cache != nil {
+		fs.cache.RemoveEntryItem(dst)
+	}
+	return nil
+}
+
 func (fs *FileSystem) Unlink(name string) error {
 	ctx := meta.NewEmptyContext()
 	_, ino, err := fs.lookup(ctx, path.Dir(name), true)
@@ -24,6 +24,7 @@ import (
 	"syscall"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 
 	"paddleflow/pkg/fs/client/utils"
 )
@@ -222,6 +223,14 @@ func (c *MockClient) Chmod(path string, fm os.FileMode) error {
 	return os.Chmod(filepath.Join(c.pathPrefix, path), fm)
 }
 
+func (c *MockClient) Chown(path string, uid, gid int) error {
+	return os.Chown(filepath.Join(c.pathPrefix, path), uid, gid)
+}
+
+func (c *MockClient) SetXAttr(path string, name string, value []byte) error {
+	return unix.Setxattr(filepath.Join(c.pathPrefix, path), name, value, 0)
+}
+
 func (c *MockClient) Walk(root string, walkFn filepath.WalkFunc) error {
 	return nil
 }
@@ -234,3 +243,9 @@ func (c *MockClient) Stat(path string) (os.FileInfo, error) {
 	}
 	return attr, nil
 }
+
+func (c *MockClient) Warmup(path string, concurrency int, progress func(WarmupStats)) (WarmupStats, error) {
+	return WarmupLocal(filepath.Join(c.pathPrefix, path), concurrency, progress)
+}
+
+func (c *MockClient) Close() {}
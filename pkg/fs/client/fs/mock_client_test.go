@@ -17,6 +17,7 @@ package fs
 
 import (
 	"os"
+	"sync/atomic"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -92,3 +93,26 @@ func TestMockClient(t *testing.T) {
 	err = client.RemoveAll("Dir1")
 	assert.Equal(t, nil, err)
 }
+
+func TestMockClientWarmup(t *testing.T) {
+	defer os.RemoveAll("./mock")
+	client := getTestMockClient()
+	assert.NoError(t, client.MkdirAll("/data/sub", 0755))
+	_, err := client.CreateFile("/data/a.txt", []byte("hello"))
+	assert.NoError(t, err)
+	_, err = client.CreateFile("/data/sub/b.txt", []byte("world!"))
+	assert.NoError(t, err)
+
+	var calls int64
+	stats, err := client.Warmup("/data", 2, func(WarmupStats) {
+		atomic.AddInt64(&calls, 1)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), stats.Files)
+	assert.Equal(t, int64(11), stats.Bytes)
+	assert.Equal(t, int64(0), stats.Failed)
+	assert.Equal(t, int64(2), calls)
+
+	_, err = client.Warmup("/notexist", 2, nil)
+	assert.Error(t, err)
+}
@@ -36,7 +36,7 @@ type OldFileSystem struct {
 }
 
 func NewOldFileSystem(fsMeta base.FSMeta, links map[string]base.FSMeta, skipSub bool,
-	linkMetaDirPrefix string) (*OldFileSystem, error) {
+	watcher meta.LinkWatcher) (*OldFileSystem, error) {
 	fs := OldFileSystem{fsMeta: fsMeta, stop: make(chan struct{})}
 	vfs, err := vfs.InitOldVFS(fsMeta, links, false)
 	if err != nil {
@@ -48,8 +48,8 @@ func NewOldFileSystem(fsMeta base.FSMeta, links map[string]base.FSMeta, skipSub
 	runtime.SetFinalizer(&fs, func(fs *OldFileSystem) {
 		close(fs.stop)
 	})
-	if !skipSub {
-		go vfs.LinksMetaUpdateHandler(fs.stop, meta.DefaultLinkUpdateInterval, linkMetaDirPrefix)
+	if !skipSub && watcher != nil {
+		go vfs.LinksMetaUpdateHandler(fs.stop, meta.DefaultLinkUpdateInterval, watcher)
 	}
 	return &fs, nil
 }
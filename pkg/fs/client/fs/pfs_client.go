@@ -29,6 +29,7 @@ import (
 	"paddleflow/pkg/client"
 	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/meta"
 	"paddleflow/pkg/fs/client/utils"
 	"paddleflow/pkg/fs/client/vfs"
 	"paddleflow/pkg/fs/utils/common"
@@ -41,8 +42,19 @@ type PFSClient struct {
 }
 
 func NewPFSClient(fsMeta base.FSMeta, links map[string]base.FSMeta) (*PFSClient, error) {
+	return newPFSClient(fsMeta, links, nil, false)
+}
+
+// NewPFSClientWithCache creates a client which caches the data read in the memory and disk cache set by
+// SetMemCache, SetDiskCache and SetBlockSize, it warms up the disk cache shared with the mounts on the node.
+func NewPFSClientWithCache(fsMeta base.FSMeta, links map[string]base.FSMeta) (*PFSClient, error) {
+	return newPFSClient(fsMeta, links, nil, true)
+}
+
+// newPFSClient creates a client whose links are updated by watcher if it is not nil
+func newPFSClient(fsMeta base.FSMeta, links map[string]base.FSMeta, watcher meta.LinkWatcher, cached bool) (*PFSClient, error) {
 	client := &PFSClient{}
-	err := client.initPFS(fsMeta, links)
+	err := client.initPFS(fsMeta, links, watcher, cached)
 	return client, err
 }
 
@@ -54,7 +66,7 @@ func NewFSClientForTest(fsMeta base.FSMeta) (*PFSClient, error) {
 		vfs.WithBlockSize(BlockSize),
 		vfs.WithDiskCachePath(DiskCachePath),
 	)
-	pfs, err := NewFileSystem(fsMeta, nil, true, true, "", vfsConfig)
+	pfs, err := NewFileSystem(fsMeta, nil, true, true, nil, vfsConfig)
 	if err != nil {
 		log.Errorf("new a fileSystem with fsMeta [%+v] failed: %v", fsMeta, err)
 		return nil, err
@@ -65,43 +77,52 @@ func NewFSClientForTest(fsMeta base.FSMeta) (*PFSClient, error) {
 	return &client, nil
 }
 
-func getMetaAndLinks(server string, fsID string) (base.FSMeta, map[string]base.FSMeta, error) {
+func getMetaAndLinks(server string, fsID string) (base.FSMeta, map[string]base.FSMeta, meta.LinkWatcher, error) {
 	fsMeta := base.FSMeta{}
 	httpClient := client.NewHttpClient(server, client.DefaultTimeOut)
 	token, err := common.GetRootToken(&logger.RequestContext{})
 	if err != nil {
 		log.Errorf("get root token failed: %v", err)
-		return fsMeta, nil, err
+		return fsMeta, nil, nil, err
 	}
 	client, err := base.NewClient(fsID, httpClient, base.RootKey, token)
 	if err != nil {
 		log.Errorf("init client with fs[%s] and server[%s] failed: %v", fsID, server, err)
-		return fsMeta, nil, err
+		return fsMeta, nil, nil, err
 	}
 	fsMeta, err = client.GetFSMeta()
 	if err != nil {
 		log.Errorf("get fsMeta from pfs server failed: %v", err)
-		return fsMeta, nil, err
+		return fsMeta, nil, nil, err
 	}
 	if fsMeta.Type == base.MockType {
-		return fsMeta, nil, nil
+		return fsMeta, nil, nil, nil
 	}
 	client.FsName = fsMeta.Name
 	links, err := client.GetLinks()
 	if err != nil {
 		log.Errorf("get links from pfs server failed: %v", err)
-		return fsMeta, nil, err
+		return fsMeta, nil, nil, err
 	}
-	return fsMeta, links, nil
+	return fsMeta, links, client, nil
 }
 
-func (c *PFSClient) initPFS(fsMeta base.FSMeta, links map[string]base.FSMeta) error {
+func (c *PFSClient) initPFS(fsMeta base.FSMeta, links map[string]base.FSMeta, watcher meta.LinkWatcher, cached bool) error {
 	vfsConfig := vfs.InitConfig(
 		vfs.WithMemorySize(0),
 		vfs.WithMemoryExpire(MemCacheExpire),
 		vfs.WithBlockSize(0),
 	)
-	pfs, err := NewFileSystem(fsMeta, links, false, true, linkMetaDirPrefix, vfsConfig)
+	if cached {
+		vfsConfig = vfs.InitConfig(
+			vfs.WithMemorySize(MemCacheSize),
+			vfs.WithMemoryExpire(MemCacheExpire),
+			vfs.WithDiskExpire(DiskCacheExpire),
+			vfs.WithBlockSize(BlockSize),
+			vfs.WithDiskCachePath(DiskCachePath),
+		)
+	}
+	pfs, err := NewFileSystem(fsMeta, links, false, true, watcher, vfsConfig)
 	if err != nil {
 		log.Errorf("new a fileSystem for [%s] failed: %v", fsMeta.ID, err)
 		return err
@@ -111,6 +132,11 @@ func (c *PFSClient) initPFS(fsMeta base.FSMeta, links map[string]base.FSMeta) er
 }
 
 func (c *PFSClient) Create(path string) (io.WriteCloser, error) {
+	var old Usage
+	recording := c.recordingUsage()
+	if recording {
+		old = c.fileUsage(path)
+	}
 	// 参考os.create
 	flags := os.O_RDWR | os.O_CREATE | os.O_TRUNC
 	mode := 0666
@@ -119,6 +145,11 @@ func (c *PFSClient) Create(path string) (io.WriteCloser, error) {
 		log.Errorf("create file[%s] failed: %v", path, err)
 		return nil, err
 	}
+	if recording {
+		// the file is truncated, the usage is the bytes written when closed
+		delta := Usage{Bytes: -old.Bytes, Files: 1 - old.Files}
+		return &usageWriter{WriteCloser: file, client: c, path: path, delta: delta}, nil
+	}
 	return file, nil
 }
 
@@ -163,9 +194,15 @@ func (c *PFSClient) Remove(path string) error {
 		return err
 	}
 	if attr.IsDir() {
-		return c.pfs.Rmdir(path)
+		if err = c.pfs.Rmdir(path); err == nil {
+			c.recordUsage(path, Usage{Dirs: -1})
+		}
+		return err
+	}
+	if err = c.pfs.Unlink(path); err == nil {
+		c.recordUsage(path, Usage{Bytes: -attr.Size(), Files: -1})
 	}
-	return c.pfs.Unlink(path)
+	return err
 }
 
 func (c *PFSClient) RemoveAll(path string) error {
@@ -260,7 +297,11 @@ func (c *PFSClient) IsEmptyDir(path string) (bool, error) {
 }
 
 func (c *PFSClient) Mkdir(path string, perm os.FileMode) error {
-	return c.pfs.Mkdir(path, perm)
+	err := c.pfs.Mkdir(path, perm)
+	if err == nil {
+		c.recordUsage(path, Usage{Dirs: 1})
+	}
+	return err
 }
 
 func (c *PFSClient) MkdirAll(path string, perm os.FileMode) error {
@@ -336,7 +377,17 @@ func (c *PFSClient) Readdirnames(path string, n int) ([]string, error) {
 }
 
 func (c *PFSClient) Rename(srcPath, dstPath string) error {
-	return c.pfs.Rename(srcPath, dstPath)
+	if !c.recordingUsage() {
+		return c.pfs.Rename(srcPath, dstPath)
+	}
+	// the usage of the dirs renamed is moved by the next scan
+	src, dst := c.fileUsage(srcPath), c.fileUsage(dstPath)
+	if err := c.pfs.Rename(srcPath, dstPath); err != nil {
+		return err
+	}
+	c.recordUsage(srcPath, Usage{Bytes: -src.Bytes, Files: -src.Files})
+	c.recordUsage(dstPath, Usage{Bytes: src.Bytes - dst.Bytes, Files: src.Files - dst.Files})
+	return nil
 }
 
 func (c *PFSClient) Copy(srcPath, dstPath string) error {
@@ -382,6 +433,23 @@ func (c *PFSClient) Copy(srcPath, dstPath string) error {
 }
 
 func (c *PFSClient) copyFile(srcPath, dstPath string) error {
+	var old Usage
+	recording := c.recordingUsage()
+	if recording {
+		old = c.fileUsage(dstPath)
+	}
+	err := c.pfs.CopyFile(srcPath, dstPath)
+	if err == nil {
+		if recording {
+			copied := c.fileUsage(dstPath)
+			c.recordUsage(dstPath, Usage{Bytes: copied.Bytes - old.Bytes, Files: copied.Files - old.Files})
+		}
+		return nil
+	}
+	if err != syscall.ENOTSUP {
+		log.Errorf("copy file from [%s] to [%s] in ufs failed: %v", srcPath, dstPath, err)
+		return err
+	}
 	srcFile, err := c.Open(srcPath)
 	if err != nil {
 		log.Errorf("open file[%s] failed: %s", srcPath, err)
@@ -415,6 +483,14 @@ func (c *PFSClient) Chmod(path string, fm os.FileMode) error {
 	return c.pfs.Chmod(path, fm)
 }
 
+func (c *PFSClient) Chown(path string, uid, gid int) error {
+	return c.pfs.Chown(path, uid, gid)
+}
+
+func (c *PFSClient) SetXAttr(path string, name string, value []byte) error {
+	return c.pfs.SetXAttr(path, name, value)
+}
+
 func (c *PFSClient) Walk(root string, walkFn filepath.WalkFunc) error {
 	info, err := c.pfs.Stat(root)
 	if err != nil {
@@ -473,3 +549,58 @@ func (c *PFSClient) Stat(path string) (os.FileInfo, error) {
 	}
 	return attr, nil
 }
+
+// Warmup reads the files under path through the cache of client, which must be created with NewPFSClientWithCache
+func (c *PFSClient) Warmup(path string, concurrency int, progress func(WarmupStats)) (WarmupStats, error) {
+	if c.pfs.vfs.Store == nil {
+		return WarmupStats{}, ErrWarmupWithoutCache
+	}
+	return warmup(path, concurrency, c.Walk, c.Open, progress)
+}
+
+func (c *PFSClient) Close() {
+	c.pfs.Close()
+}
+
+func (c *PFSClient) recordingUsage() bool {
+	return c.fsID != "" && getUsageListener() != nil
+}
+
+// recordUsage notifies the usage listener of the usage changed under path
+func (c *PFSClient) recordUsage(path string, delta Usage) {
+	listener := getUsageListener()
+	if listener == nil || c.fsID == "" || delta == (Usage{}) {
+		return
+	}
+	listener(c.fsID, path, delta)
+}
+
+// fileUsage returns the usage of the regular file of path, which is empty if path is not a file
+func (c *PFSClient) fileUsage(path string) Usage {
+	info, err := c.pfs.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return Usage{}
+	}
+	return Usage{Bytes: info.Size(), Files: 1}
+}
+
+// usageWriter records the usage of the file created when it is closed
+type usageWriter struct {
+	io.WriteCloser
+	client *PFSClient
+	path   string
+	delta  Usage
+}
+
+func (w *usageWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteCloser.Write(p)
+	w.delta.Bytes += int64(n)
+	return n, err
+}
+
+func (w *usageWriter) Close() error {
+	err := w.WriteCloser.Close()
+	w.client.recordUsage(w.path, w.delta)
+	w.delta = Usage{}
+	return err
+}
@@ -616,3 +616,33 @@ func TestFSClient_cache_read(t *testing.T) {
 	os.RemoveAll("./mock")
 	os.RemoveAll("./mock-cache")
 }
+
+func TestPFSClient_Warmup(t *testing.T) {
+	os.RemoveAll("./mock")
+	os.RemoveAll("./mock-cache")
+	defer os.RemoveAll("./mock")
+	defer os.RemoveAll("./mock-cache")
+	os.MkdirAll("./mock/data", 0755)
+	assert.NoError(t, ioutil.WriteFile("./mock/data/a.txt", []byte("hello"), 0644))
+	testFsMeta := base.FSMeta{
+		UfsType:    base.LocalType,
+		Properties: map[string]string{base.RootKey: "./mock"},
+		SubPath:    "./mock",
+	}
+
+	client, err := NewPFSClient(testFsMeta, nil)
+	assert.NoError(t, err)
+	_, err = client.Warmup("/data", 2, nil)
+	assert.Equal(t, ErrWarmupWithoutCache, err)
+
+	SetBlockSize(1 << 20)
+	SetMemCache(1<<20, time.Minute)
+	SetDiskCache("./mock-cache", time.Minute)
+	client, err = NewPFSClientWithCache(testFsMeta, nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, client.pfs.vfs.Store)
+	stats, err := client.Warmup("/data", 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Files)
+	assert.Equal(t, int64(5), stats.Bytes)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Usage is the space used under a path of fs.
+type Usage struct {
+	Bytes int64
+	Files int64
+	Dirs  int64
+}
+
+func (u *Usage) add(delta Usage) {
+	u.Bytes += delta.Bytes
+	u.Files += delta.Files
+	u.Dirs += delta.Dirs
+}
+
+// DirUsage is the usage of a top level dir of fs.
+type DirUsage struct {
+	Path string
+	Usage
+}
+
+// UsageStats is the usage of a fs, the embedded Usage excludes the links, whose usages are in Links
+// by their fs paths. Children are the usages of the top level dirs. It is not safe for concurrent use.
+type UsageStats struct {
+	Usage
+	Children map[string]*Usage
+	Links    map[string]*Usage
+}
+
+func NewUsageStats(links []string) *UsageStats {
+	s := &UsageStats{
+		Children: make(map[string]*Usage),
+		Links:    make(map[string]*Usage),
+	}
+	for _, link := range links {
+		s.Links[filepath.Clean("/"+link)] = &Usage{}
+	}
+	return s
+}
+
+// Add adds delta to the usage of fs or the link holding path.
+func (s *UsageStats) Add(path string, delta Usage) {
+	path = filepath.Clean("/" + path)
+	if path == "/" {
+		return
+	}
+	for link, usage := range s.Links {
+		if path == link || strings.HasPrefix(path, link+"/") {
+			usage.add(delta)
+			return
+		}
+	}
+	s.Usage.add(delta)
+	top := path
+	if i := strings.Index(path[1:], "/"); i >= 0 {
+		top = path[:i+1]
+	} else if delta.Dirs == 0 {
+		// files in the root dir belong to no top level dir
+		return
+	}
+	child, ok := s.Children[top]
+	if !ok {
+		child = &Usage{}
+		s.Children[top] = child
+	}
+	child.add(delta)
+	if top == path && delta.Dirs < 0 {
+		// the top level dir is removed
+		delete(s.Children, top)
+	}
+}
+
+// TopDirs returns at most n top level dirs using the most bytes.
+func (s *UsageStats) TopDirs(n int) []DirUsage {
+	dirs := make([]DirUsage, 0, len(s.Children))
+	for path, usage := range s.Children {
+		dirs = append(dirs, DirUsage{Path: path, Usage: *usage})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].Bytes != dirs[j].Bytes {
+			return dirs[i].Bytes > dirs[j].Bytes
+		}
+		return dirs[i].Path < dirs[j].Path
+	})
+	if n >= 0 && len(dirs) > n {
+		dirs = dirs[:n]
+	}
+	return dirs
+}
+
+// ScanUsage walks the whole fs by client and sums up the usages of the fs and its links.
+func ScanUsage(client FSClient, links []string) (*UsageStats, error) {
+	return scanUsage(client.Walk, links)
+}
+
+func scanUsage(walk func(string, filepath.WalkFunc) error, links []string) (*UsageStats, error) {
+	stats := NewUsageStats(links)
+	err := walk("/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == "/" {
+				return err
+			}
+			log.Errorf("usage walk path[%s] failed: %v", path, err)
+			return nil
+		}
+		if info.IsDir() {
+			stats.Add(path, Usage{Dirs: 1})
+		} else {
+			stats.Add(path, Usage{Bytes: info.Size(), Files: 1})
+		}
+		return nil
+	})
+	return stats, err
+}
+
+// UsageListener is notified of the usage changed by the writes of server side clients, so
+// that the usage scanned is kept up to date between the scans.
+type UsageListener func(fsID, path string, delta Usage)
+
+var (
+	usageListener     UsageListener
+	usageListenerLock sync.RWMutex
+)
+
+func SetUsageListener(listener UsageListener) {
+	usageListenerLock.Lock()
+	defer usageListenerLock.Unlock()
+	usageListener = listener
+}
+
+func getUsageListener() UsageListener {
+	usageListenerLock.RLock()
+	defer usageListenerLock.RUnlock()
+	return usageListener
+}
@@ -0,0 +1,97 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanUsage(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]int{
+		"a/1":        10,
+		"a/b/2":      20,
+		"c/3":        5,
+		"4":          1,
+		"link/5":     100,
+		"link/d/6":   200,
+		"linkless/7": 7,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, make([]byte, size), 0644))
+	}
+	walk := func(_ string, walkFn filepath.WalkFunc) error {
+		return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			return walkFn("/"+strings.TrimPrefix(strings.TrimPrefix(path, root), "/"), info, err)
+		})
+	}
+
+	stats, err := scanUsage(walk, []string{"link"})
+	assert.Nil(t, err)
+	assert.Equal(t, Usage{Bytes: 43, Files: 5, Dirs: 4}, stats.Usage)
+	assert.Equal(t, Usage{Bytes: 300, Files: 2, Dirs: 2}, *stats.Links["/link"])
+	assert.Equal(t, []DirUsage{
+		{Path: "/a", Usage: Usage{Bytes: 30, Files: 2, Dirs: 2}},
+		{Path: "/linkless", Usage: Usage{Bytes: 7, Files: 1, Dirs: 1}},
+	}, stats.TopDirs(2))
+
+	stats.Add("/c/8", Usage{Bytes: 50, Files: 1})
+	stats.Add("/link/9", Usage{Bytes: 1, Files: 1})
+	assert.Equal(t, int64(93), stats.Bytes)
+	assert.Equal(t, int64(301), stats.Links["/link"].Bytes)
+	assert.Equal(t, "/c", stats.TopDirs(1)[0].Path)
+
+	stats.Add("/c/3", Usage{Bytes: -5, Files: -1})
+	stats.Add("/c/8", Usage{Bytes: -50, Files: -1})
+	stats.Add("/c", Usage{Dirs: -1})
+	assert.Len(t, stats.TopDirs(-1), 2)
+	assert.Equal(t, Usage{Bytes: 38, Files: 4, Dirs: 3}, stats.Usage)
+}
+
+func TestPFSClient_recordUsage(t *testing.T) {
+	os.RemoveAll("./mock")
+	client := getTestFSClient(t).(*PFSClient)
+	client.fsID = "fs-root-usage"
+	defer os.RemoveAll("./mock")
+
+	stats := NewUsageStats(nil)
+	SetUsageListener(func(fsID, path string, delta Usage) {
+		assert.Equal(t, client.fsID, fsID)
+		stats.Add(path, delta)
+	})
+	defer SetUsageListener(nil)
+
+	assert.Nil(t, client.MkdirAll("/usage/dir", 0755))
+	_, err := client.CreateFile("/usage/dir/a", []byte("hello"))
+	assert.Nil(t, err)
+	_, err = client.CreateFile("/usage/dir/a", []byte("hello world"))
+	assert.Nil(t, err)
+	assert.Nil(t, client.Rename("/usage/dir/a", "/usage/b"))
+	assert.Nil(t, client.Copy("/usage/b", "/usage/c"))
+	assert.Equal(t, Usage{Bytes: 22, Files: 2, Dirs: 2}, stats.Usage)
+
+	assert.Nil(t, client.RemoveAll("/usage"))
+	assert.Equal(t, Usage{}, stats.Usage)
+	assert.Empty(t, stats.TopDirs(-1))
+}
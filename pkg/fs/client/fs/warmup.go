@@ -0,0 +1,117 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package fs
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultWarmupConcurrency = 8
+	warmupBufferSize         = 1 << 20
+)
+
+// ErrWarmupWithoutCache is returned by warmup of a client without cache, as nothing would be loaded
+var ErrWarmupWithoutCache = errors.New("warmup needs a client with cache")
+
+// WarmupStats is the progress of warmup, Bytes is the size of data loaded into cache.
+type WarmupStats struct {
+	Files  int64
+	Bytes  int64
+	Failed int64
+}
+
+func (s *WarmupStats) snapshot() WarmupStats {
+	return WarmupStats{
+		Files:  atomic.LoadInt64(&s.Files),
+		Bytes:  atomic.LoadInt64(&s.Bytes),
+		Failed: atomic.LoadInt64(&s.Failed),
+	}
+}
+
+// WarmupLocal reads every file under root of a mounted fs, so that the blocks are loaded into the cache of the mount.
+func WarmupLocal(root string, concurrency int, progress func(WarmupStats)) (WarmupStats, error) {
+	open := func(path string) (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+	return warmup(root, concurrency, filepath.Walk, open, progress)
+}
+
+// warmup walks root and reads the regular files by concurrency workers, progress is called after every file.
+func warmup(root string, concurrency int, walk func(string, filepath.WalkFunc) error,
+	open func(string) (io.ReadCloser, error), progress func(WarmupStats)) (WarmupStats, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultWarmupConcurrency
+	}
+	stats := &WarmupStats{}
+	paths := make(chan string, concurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, warmupBufferSize)
+			for path := range paths {
+				n, err := warmupFile(open, path, buf)
+				atomic.AddInt64(&stats.Bytes, n)
+				if err != nil {
+					log.Errorf("warmup file[%s] failed: %v", path, err)
+					atomic.AddInt64(&stats.Failed, 1)
+				} else {
+					atomic.AddInt64(&stats.Files, 1)
+				}
+				if progress != nil {
+					progress(stats.snapshot())
+				}
+			}
+		}()
+	}
+
+	err := walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			log.Errorf("warmup walk path[%s] failed: %v", path, err)
+			atomic.AddInt64(&stats.Failed, 1)
+			return nil
+		}
+		if info.Mode().IsRegular() {
+			paths <- path
+		}
+		return nil
+	})
+	close(paths)
+	wg.Wait()
+	return stats.snapshot(), err
+}
+
+func warmupFile(open func(string) (io.ReadCloser, error), path string, buf []byte) (int64, error) {
+	file, err := open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return io.CopyBuffer(io.Discard, file, buf)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package fuse
 
 import (
+	"math"
 	"time"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -235,17 +236,31 @@ func (fs *PFS) Read(cancel <-chan struct{}, input *fuse.ReadIn, buf []byte) (fus
 	return fuse.ReadResultData(buf[:n]), fuse.OK
 }
 
+// lkFlock is FUSE_LK_FLOCK, the lock request is from flock(2) rather than fcntl(2)
+const lkFlock = 1 << 0
+
 // File locking
 func (fs *PFS) GetLk(cancel <-chan struct{}, input *fuse.LkIn, out *fuse.LkOut) (code fuse.Status) {
-	return fuse.ENOSYS
+	log.Debugf("GetLk: input[%+v]", *input)
+	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
+	out.Lk = input.Lk
+	code = fuse.Status(vfs.GetVFS().GetLk(ctx, vfs.Ino(input.NodeId), input.Fh, input.Owner,
+		&out.Lk.Start, &out.Lk.End, &out.Lk.Typ, &out.Lk.Pid))
+	return code
 }
 
 func (fs *PFS) SetLk(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
-	return fuse.ENOSYS
+	log.Debugf("SetLk: input[%+v]", *input)
+	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
+	return fuse.Status(vfs.GetVFS().SetLk(ctx, vfs.Ino(input.NodeId), input.Fh, input.Owner, input.Lk.Start,
+		input.Lk.End, input.Lk.Typ, input.Lk.Pid, input.LkFlags&lkFlock != 0, false))
 }
 
 func (fs *PFS) SetLkw(cancel <-chan struct{}, input *fuse.LkIn) (code fuse.Status) {
-	return fuse.ENOSYS
+	log.Debugf("SetLkw: input[%+v]", *input)
+	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
+	return fuse.Status(vfs.GetVFS().SetLkw(ctx, vfs.Ino(input.NodeId), input.Fh, input.Owner, input.Lk.Start,
+		input.Lk.End, input.Lk.Typ, input.Lk.Pid, input.LkFlags&lkFlock != 0))
 }
 
 func (fs *PFS) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []byte) (uint32, fuse.Status) {
@@ -261,7 +276,19 @@ func (fs *PFS) Write(cancel <-chan struct{}, input *fuse.WriteIn, data []byte) (
 }
 
 func (fs *PFS) CopyFileRange(cancel <-chan struct{}, input *fuse.CopyFileRangeIn) (written uint32, code fuse.Status) {
-	return 0, fuse.ENOSYS
+	log.Debugf("CopyFileRange: input [%+v]", *input)
+	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
+	// the written bytes are returned in uint32
+	size := input.Len
+	if size > math.MaxUint32 {
+		size = math.MaxUint32
+	}
+	copied, err := vfs.GetVFS().CopyFileRange(ctx, vfs.Ino(input.NodeId), input.FhIn, input.OffIn,
+		vfs.Ino(input.NodeIdOut), input.FhOut, input.OffOut, size, uint32(input.Flags))
+	if err != 0 {
+		return 0, fuse.Status(err)
+	}
+	return uint32(copied), fuse.OK
 }
 
 func (fs *PFS) Flush(cancel <-chan struct{}, input *fuse.FlushIn) fuse.Status {
@@ -294,7 +321,7 @@ func (fs *PFS) OpenDir(cancel <-chan struct{}, input *fuse.OpenIn, out *fuse.Ope
 func (fs *PFS) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
 	log.Debugf("ReadDir: input [%+v]", input)
 	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
-	entries, code := vfs.GetVFS().ReadDir(ctx, vfs.Ino(input.NodeId), input.Fh, input.Offset)
+	entries, code := vfs.GetVFS().ReadDir(ctx, vfs.Ino(input.NodeId), input.Fh, input.Offset, false)
 	if code != 0 {
 		return fuse.Status(code)
 	}
@@ -314,7 +341,7 @@ func (fs *PFS) ReadDir(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.Dir
 func (fs *PFS) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse.DirEntryList) fuse.Status {
 	log.Debugf("ReadDirPlus: input [%+v]", input)
 	ctx := meta.NewContext(cancel, input.Uid, input.Pid, input.Gid)
-	entries, code := vfs.GetVFS().ReadDir(ctx, vfs.Ino(input.NodeId), input.Fh, input.Offset)
+	entries, code := vfs.GetVFS().ReadDir(ctx, vfs.Ino(input.NodeId), input.Fh, input.Offset, true)
 	if code != 0 {
 		return fuse.Status(code)
 	}
@@ -331,6 +358,10 @@ func (fs *PFS) ReadDirPlus(cancel <-chan struct{}, input *fuse.ReadIn, out *fuse
 		if e.Name == "." || e.Name == ".." {
 			continue
 		}
+		// entries without attributes are left for the kernel to look up
+		if e.Ino != 0 {
+			fs.replyEntry(e, eo)
+		}
 	}
 	log.Debugf("ReadDirPlus result %v", code)
 	return fuse.Status(code)
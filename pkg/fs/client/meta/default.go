@@ -17,7 +17,6 @@ limitations under the License.
 package meta
 
 import (
-	"encoding/json"
 	"io"
 	"os"
 	pathlib "path"
@@ -29,10 +28,10 @@ import (
 	"github.com/hanwen/go-fuse/v2/fuse"
 	log "github.com/sirupsen/logrus"
 
-	apicommon "paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/fs/client/base"
 	ufslib "paddleflow/pkg/fs/client/ufs"
 	"paddleflow/pkg/fs/client/utils"
+	"paddleflow/pkg/fs/utils/lock"
 )
 
 const DefaultName = "Mem"
@@ -44,14 +43,19 @@ type DefaultMeta struct {
 	defaultUfs  ufslib.UnderFileStorage
 	ufsMap      *sync.Map
 	ufsMapLock  sync.RWMutex
-	ufsMapUT    int64
 	inodeHandle *InodeHandle
+	locker      Locker
 }
 
-func NewDefaultMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, inodeHandle *InodeHandle) (Meta, error) {
+// NewDefaultMeta returns the meta of fs, the file locks are local to the mount if locker is nil.
+func NewDefaultMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, inodeHandle *InodeHandle, locker Locker) (Meta, error) {
+	if locker == nil {
+		locker = NewLocalLocker()
+	}
 	meta := &DefaultMeta{
 		name:        DefaultName,
 		inodeHandle: inodeHandle,
+		locker:      locker,
 	}
 	ufs, err := newUFS(fsMeta)
 	if err != nil {
@@ -206,6 +210,10 @@ func (m *DefaultMeta) getAttr(name string, attr *Attr) syscall.Errno {
 // SetAttr updates the attributes for given node.
 func (m *DefaultMeta) SetAttr(ctx *Context, inode Ino, set uint32, attr *Attr) syscall.Errno {
 	name := m.inodeHandle.InoToPath(inode)
+	return m.setAttr(name, set, attr)
+}
+
+func (m *DefaultMeta) setAttr(name string, set uint32, attr *Attr) syscall.Errno {
 	ufs, _, _, path := m.GetUFS(name)
 
 	if set&FATTR_UID != 0 || set&FATTR_GID != 0 {
@@ -368,13 +376,53 @@ func (m *DefaultMeta) Link(ctx *Context, inodeSrc, parent Ino, name string, attr
 }
 
 // Readdir returns all entries for given directory, which include attributes if plus is true.
-func (m *DefaultMeta) Readdir(ctx *Context, inode Ino, entries *[]*Entry) syscall.Errno {
-	name := m.inodeHandle.InoToPath(inode)
-	ufs, isLink, _, path := m.GetUFS(name)
-	dirs, err := ufs.ReadDir(path)
-	if err != nil {
-		log.Errorf("[vfs] ReadDir failed: %v", err)
-		return utils.ToSyscallErrno(err)
+func (m *DefaultMeta) Readdir(ctx *Context, inode Ino, plus bool, entries *[]*Entry) syscall.Errno {
+	pnode := m.inodeHandle.toInode(inode)
+	name := m.inodeHandle.InodeToPath(pnode)
+	var listed []*Entry
+	if err := m.readdir(name, plus, &listed); utils.IsError(err) {
+		return err
+	}
+	for _, entry := range listed {
+		// only the entries with attributes are known to the kernel by readdirplus
+		if plus && entry.Attr.Type != 0 {
+			entry.Ino = pnode.GetOrNewChild(entry.Name, entry.Attr.IsDir())
+		}
+		*entries = append(*entries, entry)
+	}
+	return syscall.F_OK
+}
+
+// readdir lists the dir, the attributes of the entries are filled if plus is true and the ufs
+// implements ufslib.DirAttrReader. The entries of the other ufs are not stated one by one, which
+// would be a round trip per entry, they are left for the kernel to look up.
+func (m *DefaultMeta) readdir(name string, plus bool, entries *[]*Entry) syscall.Errno {
+	ufs, isLink, prefix, path := m.GetUFS(name)
+	var dirs []base.DirEntry
+	attrs := make(map[string]*Attr)
+	if reader, ok := ufs.(ufslib.DirAttrReader); ok && plus {
+		infos, err := reader.ReadDirPlus(path)
+		if err != nil {
+			log.Errorf("[vfs] ReadDirPlus failed: %v", err)
+			return utils.ToSyscallErrno(err)
+		}
+		for _, info := range infos {
+			entryName := pathlib.Base(info.Name)
+			if isLink {
+				info.FixLinkPrefix(prefix)
+			}
+			attr := &Attr{}
+			attr.FromFileInfo(info)
+			attrs[entryName] = attr
+			dirs = append(dirs, base.DirEntry{Name: entryName, Mode: attr.Mode})
+		}
+	} else {
+		var err error
+		dirs, err = ufs.ReadDir(path)
+		if err != nil {
+			log.Errorf("[vfs] ReadDir failed: %v", err)
+			return utils.ToSyscallErrno(err)
+		}
 	}
 	// 不存在link嵌套，因此只有非link情况下才需要填充link文件
 	if !isLink {
@@ -402,6 +450,7 @@ func (m *DefaultMeta) Readdir(ctx *Context, inode Ino, entries *[]*Entry) syscal
 						break
 					}
 				}
+				delete(attrs, entry.Name)
 				if !exist {
 					dirs = append(dirs, entry)
 				}
@@ -415,6 +464,9 @@ func (m *DefaultMeta) Readdir(ctx *Context, inode Ino, entries *[]*Entry) syscal
 			Name: dir.Name,
 			Attr: &Attr{Mode: dir.Mode},
 		}
+		if attr, ok := attrs[dir.Name]; ok {
+			entry.Attr = attr
+		}
 		*entries = append(*entries, entry)
 	}
 	return syscall.F_OK
@@ -425,14 +477,10 @@ func (m *DefaultMeta) Create(ctx *Context, parent Ino, name string, mode uint32,
 	flags uint32, inode *Ino, attr *Attr) (ufslib.UnderFileStorage, string, syscall.Errno) {
 	pnode := m.inodeHandle.toInode(parent)
 	path := m.inodeHandle.ParentInodeToPath(pnode, name)
-	ufs, _, _, newPath := m.GetUFS(path)
-	fh, err := ufs.Create(newPath, flags, mode)
-	if err != nil {
-		log.Errorf("Create: name[%s], flags[%d], mode[%d], failed: [%v]",
-			name, flags, mode, err)
-		return nil, newPath, utils.ToSyscallErrno(err)
+	ufs, newPath, err := m.create(path, mode, flags)
+	if utils.IsError(err) {
+		return nil, newPath, err
 	}
-	defer fh.Release()
 	node := pnode.NewChild(name, false)
 	*inode = node.inode
 	errGetAttr := m.getAttr(path, attr)
@@ -442,6 +490,18 @@ func (m *DefaultMeta) Create(ctx *Context, parent Ino, name string, mode uint32,
 	return ufs, newPath, syscall.F_OK
 }
 
+func (m *DefaultMeta) create(path string, mode uint32, flags uint32) (ufslib.UnderFileStorage, string, syscall.Errno) {
+	ufs, _, _, newPath := m.GetUFS(path)
+	fh, err := ufs.Create(newPath, flags, mode)
+	if err != nil {
+		log.Errorf("Create: name[%s], flags[%d], mode[%d], failed: [%v]",
+			path, flags, mode, err)
+		return nil, newPath, utils.ToSyscallErrno(err)
+	}
+	fh.Release()
+	return ufs, newPath, syscall.F_OK
+}
+
 // Open checks permission on a node and track it as open.
 func (m *DefaultMeta) Open(ctx *Context, inode Ino, flags uint32, attr *Attr) (ufslib.UnderFileStorage, string, syscall.Errno) {
 	name := m.InoToPath(inode)
@@ -468,15 +528,36 @@ func (m *DefaultMeta) Write(ctx *Context, inode Ino, indx uint32, off uint32, da
 	return syscall.ENOSYS
 }
 
-// CopyFileRange copies part of a file to another one.
+// CopyFileRange copies part of a file to another one by the ufs, ENOTSUP is returned if the files
+// are not in the same ufs supporting server side copy.
 func (m *DefaultMeta) CopyFileRange(ctx *Context, fin Ino, offIn uint64, fout Ino, offOut uint64,
 	size uint64, flags uint32, copied *uint64) syscall.Errno {
-	return syscall.ENOSYS
+	return m.copyFileRange(m.InoToPath(fin), offIn, m.InoToPath(fout), offOut, size, copied)
+}
+
+func (m *DefaultMeta) copyFileRange(nameIn string, offIn uint64, nameOut string, offOut uint64,
+	size uint64, copied *uint64) syscall.Errno {
+	ufsIn, _, _, pathIn := m.GetUFS(nameIn)
+	ufsOut, _, _, pathOut := m.GetUFS(nameOut)
+	copier, ok := ufsIn.(ufslib.ServerSideCopier)
+	if !ok || ufsIn != ufsOut {
+		return syscall.ENOTSUP
+	}
+	n, err := copier.CopyFileRange(pathIn, offIn, pathOut, offOut, size)
+	if err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	*copied = n
+	return syscall.F_OK
 }
 
 // GetXattr returns the value of extended attribute for given name.
 func (m *DefaultMeta) GetXattr(ctx *Context, inode Ino, attribute string, vbuff *[]byte) syscall.Errno {
 	name := m.inodeHandle.InoToPath(inode)
+	return m.getXattr(name, attribute, vbuff)
+}
+
+func (m *DefaultMeta) getXattr(name string, attribute string, vbuff *[]byte) syscall.Errno {
 	ufs, _, _, path := m.GetUFS(name)
 	if data, err := ufs.GetXAttr(path, attribute); err != nil {
 		return utils.ToSyscallErrno(err)
@@ -489,6 +570,10 @@ func (m *DefaultMeta) GetXattr(ctx *Context, inode Ino, attribute string, vbuff
 // ListXattr returns all extended attributes of a node.
 func (m *DefaultMeta) ListXattr(ctx *Context, inode Ino, dbuff *[]string) syscall.Errno {
 	name := m.inodeHandle.InoToPath(inode)
+	return m.listXattr(name, dbuff)
+}
+
+func (m *DefaultMeta) listXattr(name string, dbuff *[]string) syscall.Errno {
 	ufs, _, _, path := m.GetUFS(name)
 	if data, err := ufs.ListXAttr(path); err != nil {
 		return utils.ToSyscallErrno(err)
@@ -501,6 +586,10 @@ func (m *DefaultMeta) ListXattr(ctx *Context, inode Ino, dbuff *[]string) syscal
 // SetXattr update the extended attribute of a node.
 func (m *DefaultMeta) SetXattr(ctx *Context, inode Ino, attribute string, value []byte, flags uint32) syscall.Errno {
 	name := m.inodeHandle.InoToPath(inode)
+	return m.setXattr(name, attribute, value, flags)
+}
+
+func (m *DefaultMeta) setXattr(name string, attribute string, value []byte, flags uint32) syscall.Errno {
 	ufs, _, _, path := m.GetUFS(name)
 	if err := ufs.SetXAttr(path, attribute, value, int(flags)); err != nil {
 		return utils.ToSyscallErrno(err)
@@ -511,6 +600,10 @@ func (m *DefaultMeta) SetXattr(ctx *Context, inode Ino, attribute string, value
 // RemoveXattr removes the extended attribute of a node.
 func (m *DefaultMeta) RemoveXattr(ctx *Context, inode Ino, attribute string) syscall.Errno {
 	name := m.inodeHandle.InoToPath(inode)
+	return m.removeXattr(name, attribute)
+}
+
+func (m *DefaultMeta) removeXattr(name string, attribute string) syscall.Errno {
 	ufs, _, _, path := m.GetUFS(name)
 	if err := ufs.RemoveXAttr(path, attribute); err != nil {
 		return utils.ToSyscallErrno(err)
@@ -520,17 +613,61 @@ func (m *DefaultMeta) RemoveXattr(ctx *Context, inode Ino, attribute string) sys
 
 // Flock tries to put a lock on given file.
 func (m *DefaultMeta) Flock(ctx *Context, inode Ino, owner uint64, ltype uint32, block bool) syscall.Errno {
-	return syscall.ENOSYS
+	name := m.inodeHandle.InoToPath(inode)
+	return m.flock(ctx, inode, name, owner, ltype, block)
+}
+
+func (m *DefaultMeta) flock(ctx *Context, inode Ino, name string, owner uint64, ltype uint32, block bool) syscall.Errno {
+	if ltype != syscall.F_RDLCK && ltype != syscall.F_WRLCK && ltype != syscall.F_UNLCK {
+		return syscall.EINVAL
+	}
+	return m.waitLock(ctx, block && ltype != syscall.F_UNLCK, func() (bool, error) {
+		return m.locker.Flock(inode, name, owner, ltype)
+	})
 }
 
 // Getlk returns the current lock owner for a range on a file.
 func (m *DefaultMeta) Getlk(ctx *Context, inode Ino, owner uint64, ltype *uint32, start, end *uint64, pid *uint32) syscall.Errno {
-	return syscall.ENOSYS
+	name := m.inodeHandle.InoToPath(inode)
+	return m.getlk(inode, name, owner, ltype, start, end, pid)
+}
+
+func (m *DefaultMeta) getlk(inode Ino, name string, owner uint64, ltype *uint32, start, end *uint64, pid *uint32) syscall.Errno {
+	if *ltype == syscall.F_UNLCK {
+		*start, *end, *pid = 0, 0, 0
+		return syscall.F_OK
+	}
+	if (*ltype != syscall.F_RDLCK && *ltype != syscall.F_WRLCK) || *start > *end {
+		return syscall.EINVAL
+	}
+	held, err := m.locker.Getlk(inode, name, owner, lock.Range{Type: *ltype, Start: *start, End: *end, Pid: *pid})
+	if err != nil {
+		log.Errorf("get lock of [%s] failed: %v", name, err)
+		return syscall.EIO
+	}
+	*ltype = held.Type
+	if held.Type == syscall.F_UNLCK {
+		*start, *end, *pid = 0, 0, 0
+	} else {
+		*start, *end, *pid = held.Start, held.End, held.Pid
+	}
+	return syscall.F_OK
 }
 
 // Setlk sets a file range lock on given file.
 func (m *DefaultMeta) Setlk(ctx *Context, inode Ino, owner uint64, block bool, ltype uint32, start, end uint64, pid uint32) syscall.Errno {
-	return syscall.ENOSYS
+	name := m.inodeHandle.InoToPath(inode)
+	return m.setlk(ctx, inode, name, owner, block, ltype, start, end, pid)
+}
+
+func (m *DefaultMeta) setlk(ctx *Context, inode Ino, name string, owner uint64, block bool, ltype uint32, start, end uint64, pid uint32) syscall.Errno {
+	if (ltype != syscall.F_RDLCK && ltype != syscall.F_WRLCK && ltype != syscall.F_UNLCK) || start > end {
+		return syscall.EINVAL
+	}
+	r := lock.Range{Type: ltype, Start: start, End: end, Pid: pid}
+	return m.waitLock(ctx, block && ltype != syscall.F_UNLCK, func() (bool, error) {
+		return m.locker.Setlk(inode, name, owner, r)
+	})
 }
 
 func (m *DefaultMeta) DumpMeta(w io.Writer) error {
@@ -541,72 +678,12 @@ func (m *DefaultMeta) LoadMeta(r io.Reader) error {
 	return syscall.ENOSYS
 }
 
-func (m *DefaultMeta) LinksMetaUpdateHandler(stopChan chan struct{}, interval int, linkMetaDirPrefix string) error {
-	for {
-		err := m.linksMetaUpdate(linkMetaDirPrefix)
-		if err != nil {
-			log.Debugf("links meta update failed, err[%v]", err)
-		}
-		select {
-		case <-stopChan:
-			log.Info("links meta update handler stopped")
-			return nil
-		default:
-			time.Sleep(time.Duration(interval) * time.Second)
-		}
-	}
+func (m *DefaultMeta) Shutdown() error {
+	return nil
 }
 
-func (m *DefaultMeta) linksMetaUpdate(linkMetaDirPrefix string) error {
-	filePath := pathlib.Join(linkMetaDirPrefix, base.LinkMetaDir, base.LinkMetaFile)
-	attr := &Attr{}
-	errno := m.getAttr(filePath, attr)
-	if utils.IsError(errno) {
-		log.Debugf("GetAttr file[%s] failed: %v", filePath, errno)
-		return errno
-	}
-
-	if attr.Mtime <= m.ufsMapUT {
-		return nil
-	}
-
-	flags := uint32(syscall.O_RDONLY)
-	fileHandle, err := m.defaultUfs.Open(filePath, flags)
-	if err != nil {
-		log.Errorf("open file[%s] failed: %v", filePath, err)
-		return err
-	}
-	buf := make([]byte, attr.Size)
-	r, status := fileHandle.Read(buf, 0)
-	if utils.IsError(syscall.Errno(status)) {
-		log.Errorf("fileHandle Read err[%v]", syscall.Errno(status))
-		return syscall.Errno(status)
-	}
-	content, status := r.Bytes(buf)
-	if utils.IsError(syscall.Errno(status)) {
-		log.Errorf("r.Bytes err[%v]", syscall.Errno(status))
-		return syscall.Errno(status)
-	}
-
-	var result map[string]base.FSMeta
-	if len(content) != 0 {
-		decodedLinksMeta, err := apicommon.AesDecrypt(string(content), apicommon.AESEncryptKey)
-		if err != nil {
-			log.Errorf("aes decrypt links meta json string err[%v]", err)
-			return err
-		}
-
-		if err := json.Unmarshal([]byte(decodedLinksMeta), &result); err != nil {
-			log.Errorf("json unmarshal links meta err[%v]", err)
-			return err
-		}
-	}
-
-	if err := m.UpdateUFSMap(result); err != nil {
-		log.Errorf("update ufs map err[%v]", err)
-		return err
-	}
-	m.ufsMapUT = attr.Mtime
+func (m *DefaultMeta) LinksMetaUpdateHandler(stopChan chan struct{}, interval int, watcher LinkWatcher) error {
+	WatchLinks(stopChan, interval, watcher, m.UpdateUFSMap)
 	return nil
 }
 
@@ -639,7 +716,7 @@ func newUFS(fsMeta base.FSMeta) (ufslib.UnderFileStorage, error) {
 		properties[base.NameNodeAddress] = fsMeta.ServerAddress
 	case base.HDFSWithKerberosType:
 		properties[base.NameNodeAddress] = fsMeta.ServerAddress
-	case base.SFTPType:
+	case base.SFTPType, base.NFSType, base.CephFSType, base.LustreType, base.WebDAVType, base.HTTPType:
 		properties[base.Address] = fsMeta.ServerAddress
 	}
 	return ufslib.NewUFS(fsMeta.UfsType, properties)
@@ -146,6 +146,14 @@ func (n *Inode) NewChild(name string, isDir bool) *Inode {
 	return node
 }
 
+// GetOrNewChild returns the inode of the child, which is created if it doesn't exist.
+func (n *Inode) GetOrNewChild(name string, isDir bool) Ino {
+	if ino := n.GetChild(name); ino != 0 {
+		return ino
+	}
+	return n.NewChild(name, isDir).inode
+}
+
 func (n *Inode) AddChild(name string, child *Inode) {
 	n.Lock()
 	n.child[name] = child.inode
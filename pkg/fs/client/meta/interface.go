@@ -126,7 +126,7 @@ type Meta interface {
 	// Link creates an entry for node.
 	Link(ctx *Context, inodeSrc, parent Ino, name string, attr *Attr) syscall.Errno
 	// Readdir returns all entries for given directory, which include attributes if plus is true.
-	Readdir(ctx *Context, inode Ino, entries *[]*Entry) syscall.Errno
+	Readdir(ctx *Context, inode Ino, plus bool, entries *[]*Entry) syscall.Errno
 	// Create creates a file in a directory with given name.
 	Create(ctx *Context, parent Ino, name string, mode uint32, cumask uint16, flags uint32, inode *Ino, attr *Attr) (ufslib.UnderFileStorage, string, syscall.Errno)
 	// Open checks permission on a node and track it as open.
@@ -159,7 +159,11 @@ type Meta interface {
 	DumpMeta(w io.Writer) error
 	LoadMeta(r io.Reader) error
 
-	LinksMetaUpdateHandler(stopChan chan struct{}, interval int, linkMetaDirPrefix string) error
+	// LinksMetaUpdateHandler updates the links watched until stopChan is closed.
+	LinksMetaUpdateHandler(stopChan chan struct{}, interval int, watcher LinkWatcher) error
+
+	// Shutdown releases the resources of the meta on umount.
+	Shutdown() error
 }
 
 func (a *Attr) IsDir() bool {
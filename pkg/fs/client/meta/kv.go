@@ -0,0 +1,34 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import "time"
+
+// kvClient is the storage of the persistent meta, the keys of different file systems are separated by the client.
+type kvClient interface {
+	name() string
+	// get returns nil if the key does not exist.
+	get(key string) ([]byte, error)
+	// set sets the key which expires after expire, 0 means the key never expires.
+	set(key string, value []byte, expire time.Duration) error
+	// setNX sets the key only if it does not exist and reports whether it is set.
+	setNX(key string, value []byte) (bool, error)
+	// incr increases the counter by one and returns the new value.
+	incr(key string) (uint64, error)
+	del(keys ...string) error
+	close() error
+}
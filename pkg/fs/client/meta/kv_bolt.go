@@ -0,0 +1,198 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	BoltName = "bolt"
+	// boltSweepInterval is the interval to delete the expired keys
+	boltSweepInterval = time.Minute
+)
+
+// boltClient keeps the meta in a local bolt db, each file system has its own bucket.
+// The deadlines of the keys set with expire are kept in another bucket, the expired keys
+// are ignored by get and deleted by the sweeper.
+type boltClient struct {
+	db      *bolt.DB
+	bucket  []byte
+	expires []byte
+	stop    chan struct{}
+}
+
+func newBoltClient(path, bucket string) (kvClient, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	// the db file is locked by the process, fail fast if it is used by another mount
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	c := &boltClient{db: db, bucket: []byte(bucket), expires: []byte(bucket + ".expires"), stop: make(chan struct{})}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(c.bucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(c.expires)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	go c.sweep(boltSweepInterval)
+	return c, nil
+}
+
+func (c *boltClient) sweep(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-ticker.C:
+			if err := c.deleteExpired(time.Now()); err != nil {
+				log.Errorf("delete expired keys of bucket[%s] failed: %v", c.bucket, err)
+			}
+		}
+	}
+}
+
+func (c *boltClient) deleteExpired(now time.Time) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, e := tx.Bucket(c.bucket), tx.Bucket(c.expires)
+		var expired [][]byte
+		err := e.ForEach(func(k, v []byte) error {
+			if expiredAt(v, now) {
+				expired = append(expired, append([]byte{}, k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+			if err = e.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func expiredAt(deadline []byte, now time.Time) bool {
+	return len(deadline) == 8 && int64(binary.BigEndian.Uint64(deadline)) <= now.UnixNano()
+}
+
+func (c *boltClient) name() string {
+	return BoltName
+}
+
+func (c *boltClient) get(key string) ([]byte, error) {
+	var value []byte
+	err := c.db.View(func(tx *bolt.Tx) error {
+		if expiredAt(tx.Bucket(c.expires).Get([]byte(key)), time.Now()) {
+			return nil
+		}
+		if v := tx.Bucket(c.bucket).Get([]byte(key)); v != nil {
+			// the value is only valid in the transaction
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+	return value, err
+}
+
+func (c *boltClient) set(key string, value []byte, expire time.Duration) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(c.bucket).Put([]byte(key), value); err != nil {
+			return err
+		}
+		if expire <= 0 {
+			return tx.Bucket(c.expires).Delete([]byte(key))
+		}
+		deadline := make([]byte, 8)
+		binary.BigEndian.PutUint64(deadline, uint64(time.Now().Add(expire).UnixNano()))
+		return tx.Bucket(c.expires).Put([]byte(key), deadline)
+	})
+}
+
+func (c *boltClient) setNX(key string, value []byte) (bool, error) {
+	ok := false
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		if b.Get([]byte(key)) != nil && !expiredAt(tx.Bucket(c.expires).Get([]byte(key)), time.Now()) {
+			return nil
+		}
+		if err := tx.Bucket(c.expires).Delete([]byte(key)); err != nil {
+			return err
+		}
+		ok = true
+		return b.Put([]byte(key), value)
+	})
+	return ok, err
+}
+
+func (c *boltClient) incr(key string) (uint64, error) {
+	var n uint64
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(c.bucket)
+		if v := b.Get([]byte(key)); v != nil {
+			var err error
+			if n, err = strconv.ParseUint(string(v), 10, 64); err != nil {
+				return err
+			}
+		}
+		n++
+		return b.Put([]byte(key), []byte(strconv.FormatUint(n, 10)))
+	})
+	return n, err
+}
+
+func (c *boltClient) del(keys ...string) error {
+	return c.db.Update(func(tx *bolt.Tx) error {
+		b, e := tx.Bucket(c.bucket), tx.Bucket(c.expires)
+		for _, key := range keys {
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+			if err := e.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (c *boltClient) close() error {
+	close(c.stop)
+	return c.db.Close()
+}
@@ -0,0 +1,754 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"encoding/json"
+	"fmt"
+	pathlib "path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+	ufslib "paddleflow/pkg/fs/client/ufs"
+	"paddleflow/pkg/fs/client/utils"
+)
+
+// the keys of kv meta:
+// nextInode is the last allocated inode,
+// i<ino> is the node of the inode, i.e. its parent and name,
+// d<parent>/<name> is the inode of the dir entry,
+// a<ino> is the cached attr of the inode,
+// l<ino> is the cached listing of the dir.
+const kvNextInode = "nextInode"
+
+func inodeKey(inode Ino) string {
+	return fmt.Sprintf("i%d", inode)
+}
+
+func entryKey(parent Ino, name string) string {
+	return fmt.Sprintf("d%d/%s", parent, name)
+}
+
+func attrKey(inode Ino) string {
+	return fmt.Sprintf("a%d", inode)
+}
+
+func listingKey(inode Ino) string {
+	return fmt.Sprintf("l%d", inode)
+}
+
+type kvNode struct {
+	Parent Ino    `json:"parent"`
+	Name   string `json:"name"`
+}
+
+// cachedNode is a node cached in memory until expire.
+type cachedNode struct {
+	kvNode
+	expire time.Time
+}
+
+type kvAttr struct {
+	Attr   *Attr `json:"attr"`
+	Expire int64 `json:"expire"`
+}
+
+type kvDirEntry struct {
+	Name string `json:"name"`
+	Mode uint32 `json:"mode"`
+}
+
+type kvListing struct {
+	Entries []kvDirEntry `json:"entries"`
+	Expire  int64        `json:"expire"`
+}
+
+// kvMeta persists the inode numbers in a kv store so that they keep the same across remounts,
+// and caches the attributes and dir listings of the ufs, which are revalidated after expire.
+type kvMeta struct {
+	*DefaultMeta
+	client kvClient
+	expire time.Duration
+	// nodes caches the nodes to resolve the paths without accessing the kv, they are
+	// revalidated after expire as the entries may be renamed by other mounts sharing the kv
+	nodes     map[Ino]cachedNode
+	nodesLock sync.RWMutex
+	// writers counts the open handles writing the inode, the attr of which is not cached
+	writers     map[Ino]int
+	writersLock sync.Mutex
+}
+
+func newKVMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, locker Locker, client kvClient, expire time.Duration) (Meta, error) {
+	m, err := NewDefaultMeta(fsMeta, links, nil, locker)
+	if err != nil {
+		return nil, err
+	}
+	return &kvMeta{
+		DefaultMeta: m.(*DefaultMeta),
+		client:      client,
+		expire:      expire,
+		nodes:       make(map[Ino]cachedNode),
+		writers:     make(map[Ino]int),
+	}, nil
+}
+
+func (m *kvMeta) Name() string {
+	return m.client.name()
+}
+
+func (m *kvMeta) node(inode Ino) (kvNode, bool) {
+	m.nodesLock.RLock()
+	cached, ok := m.nodes[inode]
+	m.nodesLock.RUnlock()
+	if ok && time.Now().Before(cached.expire) {
+		return cached.kvNode, true
+	}
+	n := kvNode{}
+	data, err := m.client.get(inodeKey(inode))
+	if err != nil {
+		log.Errorf("get node of inode[%d] failed: %v", inode, err)
+		return n, false
+	}
+	if data == nil {
+		return n, false
+	}
+	if err = json.Unmarshal(data, &n); err != nil {
+		log.Errorf("unmarshal node of inode[%d] failed: %v", inode, err)
+		return n, false
+	}
+	m.cacheNode(inode, n)
+	return n, true
+}
+
+func (m *kvMeta) cacheNode(inode Ino, n kvNode) {
+	if m.expire <= 0 {
+		return
+	}
+	m.nodesLock.Lock()
+	m.nodes[inode] = cachedNode{kvNode: n, expire: time.Now().Add(m.expire)}
+	m.nodesLock.Unlock()
+}
+
+func (m *kvMeta) setNode(inode Ino, n kvNode) error {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	if err = m.client.set(inodeKey(inode), data, 0); err != nil {
+		return err
+	}
+	m.cacheNode(inode, n)
+	return nil
+}
+
+func (m *kvMeta) path(inode Ino) (string, syscall.Errno) {
+	var segments []string
+	for inode != rootInodeID {
+		n, ok := m.node(inode)
+		if !ok {
+			log.Debugf("inode[%d] is not found in %s meta", inode, m.client.name())
+			return "", syscall.ENOENT
+		}
+		segments = append(segments, n.Name)
+		inode = n.Parent
+	}
+	var builder strings.Builder
+	for i := len(segments) - 1; i >= 0; i-- {
+		builder.WriteString("/")
+		builder.WriteString(segments[i])
+	}
+	if builder.Len() == 0 {
+		return "/", syscall.F_OK
+	}
+	return builder.String(), syscall.F_OK
+}
+
+func (m *kvMeta) childPath(parent Ino, name string) (string, syscall.Errno) {
+	parentPath, err := m.path(parent)
+	if utils.IsError(err) {
+		return "", err
+	}
+	return pathlib.Join(parentPath, name), syscall.F_OK
+}
+
+func (m *kvMeta) InoToPath(inode Ino) string {
+	path, err := m.path(inode)
+	if utils.IsError(err) {
+		log.Errorf("path of inode[%d] failed: %v", inode, err)
+	}
+	return path
+}
+
+// PathToIno returns 0 if the path has not been looked up.
+func (m *kvMeta) PathToIno(path string) Ino {
+	inode := rootInodeID
+	for _, name := range strings.Split(path, "/") {
+		if len(name) == 0 {
+			continue
+		}
+		child, err := m.getEntry(inode, name)
+		if err != nil || child == 0 {
+			return 0
+		}
+		inode = child
+	}
+	return inode
+}
+
+func (m *kvMeta) getEntry(parent Ino, name string) (Ino, error) {
+	data, err := m.client.get(entryKey(parent, name))
+	if err != nil || data == nil {
+		return 0, err
+	}
+	inode, err := strconv.ParseUint(string(data), 10, 64)
+	return Ino(inode), err
+}
+
+// newEntry returns the inode of the entry, a new inode is allocated if the entry does not exist.
+func (m *kvMeta) newEntry(parent Ino, name string) (Ino, error) {
+	inode, err := m.getEntry(parent, name)
+	if err != nil || inode != 0 {
+		return inode, err
+	}
+	n, err := m.client.incr(kvNextInode)
+	if err != nil {
+		return 0, err
+	}
+	inode = Ino(n) + rootInodeID
+	if err = m.setNode(inode, kvNode{Parent: parent, Name: name}); err != nil {
+		return 0, err
+	}
+	ok, err := m.client.setNX(entryKey(parent, name), []byte(strconv.FormatUint(uint64(inode), 10)))
+	if err != nil || !ok {
+		// the entry is created by another mount sharing the meta
+		m.forget(inode)
+		if err != nil {
+			return 0, err
+		}
+		return m.getEntry(parent, name)
+	}
+	return inode, nil
+}
+
+// removeEntry removes the entry and its inode.
+func (m *kvMeta) removeEntry(parent Ino, name string) {
+	inode, err := m.getEntry(parent, name)
+	if err != nil {
+		log.Errorf("get entry[%s] of inode[%d] failed: %v", name, parent, err)
+	}
+	m.invalidate(entryKey(parent, name))
+	if inode != 0 {
+		m.forget(inode)
+	}
+}
+
+func (m *kvMeta) forget(inode Ino) {
+	m.invalidate(inodeKey(inode), attrKey(inode), listingKey(inode))
+	m.nodesLock.Lock()
+	delete(m.nodes, inode)
+	m.nodesLock.Unlock()
+}
+
+func (m *kvMeta) invalidate(keys ...string) {
+	if err := m.client.del(keys...); err != nil {
+		log.Errorf("delete keys%v from %s meta failed: %v", keys, m.client.name(), err)
+	}
+}
+
+func (m *kvMeta) writing(inode Ino) bool {
+	m.writersLock.Lock()
+	defer m.writersLock.Unlock()
+	return m.writers[inode] > 0
+}
+
+func (m *kvMeta) cachedAttr(inode Ino, attr *Attr) bool {
+	if m.expire <= 0 || m.writing(inode) {
+		return false
+	}
+	data, err := m.client.get(attrKey(inode))
+	if err != nil || data == nil {
+		return false
+	}
+	cached := kvAttr{}
+	if err = json.Unmarshal(data, &cached); err != nil || cached.Attr == nil {
+		return false
+	}
+	if cached.Expire < time.Now().UnixNano() {
+		return false
+	}
+	*attr = *cached.Attr
+	return true
+}
+
+func (m *kvMeta) cacheAttr(inode Ino, attr *Attr) {
+	if m.expire <= 0 {
+		return
+	}
+	data, err := json.Marshal(kvAttr{Attr: attr, Expire: time.Now().Add(m.expire).UnixNano()})
+	if err == nil {
+		err = m.client.set(attrKey(inode), data, m.expire)
+	}
+	if err != nil {
+		log.Errorf("cache attr of inode[%d] failed: %v", inode, err)
+	}
+}
+
+// Access checks the access permission on given inode.
+func (m *kvMeta) Access(ctx *Context, inode Ino, mask uint32, attr *Attr) syscall.Errno {
+	if ctx.Uid == 0 {
+		return 0
+	}
+
+	if attr == nil {
+		attr = &Attr{}
+		err := m.GetAttr(ctx, inode, attr)
+		if utils.IsError(err) {
+			return err
+		}
+	}
+
+	if !utils.HasAccess(ctx.Uid, ctx.Gid, attr.Uid, attr.Gid, attr.Mode, mask) {
+		return syscall.Errno(fuse.EACCES)
+	}
+	return syscall.F_OK
+}
+
+// Lookup returns the inode and attributes for the given entry in a directory.
+func (m *kvMeta) Lookup(ctx *Context, parent Ino, name string) (Ino, *Attr, syscall.Errno) {
+	absolutePath, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return 0, nil, errno
+	}
+	inode, err := m.getEntry(parent, name)
+	if err != nil {
+		log.Errorf("get entry[%s] failed: %v", absolutePath, err)
+		return 0, nil, syscall.EIO
+	}
+	attr := &Attr{}
+	if inode != 0 && m.cachedAttr(inode, attr) {
+		return inode, attr, syscall.F_OK
+	}
+	if errno = m.getAttr(absolutePath, attr); utils.IsError(errno) {
+		if errno == syscall.ENOENT && inode != 0 {
+			m.removeEntry(parent, name)
+		}
+		return 0, nil, errno
+	}
+	if inode == 0 {
+		if inode, err = m.newEntry(parent, name); err != nil {
+			log.Errorf("new entry[%s] failed: %v", absolutePath, err)
+			return 0, nil, syscall.EIO
+		}
+	}
+	m.cacheAttr(inode, attr)
+	return inode, attr, syscall.F_OK
+}
+
+// GetAttr returns the attributes for given node.
+func (m *kvMeta) GetAttr(ctx *Context, inode Ino, attr *Attr) syscall.Errno {
+	if m.cachedAttr(inode, attr) {
+		return syscall.F_OK
+	}
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	if err = m.getAttr(name, attr); utils.IsError(err) {
+		return err
+	}
+	m.cacheAttr(inode, attr)
+	return syscall.F_OK
+}
+
+// SetAttr updates the attributes for given node.
+func (m *kvMeta) SetAttr(ctx *Context, inode Ino, set uint32, attr *Attr) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	if err = m.setAttr(name, set, attr); utils.IsError(err) {
+		m.invalidate(attrKey(inode))
+		return err
+	}
+	m.cacheAttr(inode, attr)
+	return syscall.F_OK
+}
+
+// Truncate changes the length for given file.
+func (m *kvMeta) Truncate(ctx *Context, inode Ino, size uint64) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	m.invalidate(attrKey(inode))
+	return m.truncate(name, size)
+}
+
+// Mknod creates a node in a directory with given name, type and permissions.
+func (m *kvMeta) Mknod(ctx *Context, parent Ino, name string, mode uint32, rdev uint32, inode *Ino, attr *Attr) syscall.Errno {
+	path, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return errno
+	}
+	ufs, _, _, newPath := m.GetUFS(path)
+	if err := ufs.Mknod(newPath, mode, rdev); err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	return m.addEntry(parent, name, path, inode, attr)
+}
+
+// Mkdir creates a sub-directory with given name and mode.
+func (m *kvMeta) Mkdir(ctx *Context, parent Ino, name string, mode uint32, inode *Ino, attr *Attr) syscall.Errno {
+	path, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return errno
+	}
+	ufs, _, _, newPath := m.GetUFS(path)
+	if err := ufs.Mkdir(newPath, mode); err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	return m.addEntry(parent, name, path, inode, attr)
+}
+
+// addEntry records the entry created in the ufs.
+func (m *kvMeta) addEntry(parent Ino, name, path string, inode *Ino, attr *Attr) syscall.Errno {
+	m.invalidate(listingKey(parent))
+	ino, err := m.newEntry(parent, name)
+	if err != nil {
+		log.Errorf("new entry[%s] failed: %v", path, err)
+		return syscall.EIO
+	}
+	*inode = ino
+	if errno := m.getAttr(path, attr); utils.IsError(errno) {
+		return errno
+	}
+	m.cacheAttr(ino, attr)
+	return syscall.F_OK
+}
+
+// Unlink removes a file entry from a directory.
+func (m *kvMeta) Unlink(ctx *Context, parent Ino, name string) syscall.Errno {
+	path, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return errno
+	}
+	ufs, _, _, path := m.GetUFS(path)
+	if err := ufs.Unlink(path); err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	m.invalidate(listingKey(parent))
+	m.removeEntry(parent, name)
+	return syscall.F_OK
+}
+
+// Rmdir removes an empty sub-directory.
+func (m *kvMeta) Rmdir(ctx *Context, parent Ino, name string) syscall.Errno {
+	path, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return errno
+	}
+	ufs, _, _, path := m.GetUFS(path)
+	if err := ufs.Rmdir(path); err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	m.invalidate(listingKey(parent))
+	m.removeEntry(parent, name)
+	return syscall.F_OK
+}
+
+// Rename move an entry from a source directory to another with given name, the inode of
+// the source is kept.
+func (m *kvMeta) Rename(ctx *Context, parentSrc Ino, nameSrc string, parentDst Ino,
+	nameDst string, flags uint32, inode *Ino, attr *Attr) syscall.Errno {
+	pathSrc, errno := m.childPath(parentSrc, nameSrc)
+	if utils.IsError(errno) {
+		return errno
+	}
+	pathDst, errno := m.childPath(parentDst, nameDst)
+	if utils.IsError(errno) {
+		return errno
+	}
+	ufsSrc, _, _, newPathSrc := m.GetUFS(pathSrc)
+	ufsDst, _, _, newPathDst := m.GetUFS(pathDst)
+	// todo：跨底层存储rename暂不支持
+	if ufsSrc != ufsDst {
+		log.Errorf("Rename between two ufs is not supported")
+		return syscall.ENOSYS
+	}
+	if err := ufsSrc.Rename(newPathSrc, newPathDst); err != nil {
+		return utils.ToSyscallErrno(err)
+	}
+	m.invalidate(listingKey(parentSrc), listingKey(parentDst))
+	if errno = m.getAttr(pathDst, attr); utils.IsError(errno) {
+		return errno
+	}
+
+	ino, err := m.getEntry(parentSrc, nameSrc)
+	if err == nil && pathSrc != pathDst {
+		err = m.moveEntry(ino, parentSrc, nameSrc, parentDst, nameDst)
+	}
+	if err == nil && ino == 0 {
+		ino, err = m.newEntry(parentDst, nameDst)
+	}
+	if err != nil {
+		log.Errorf("rename entry[%s] to [%s] failed: %v", pathSrc, pathDst, err)
+		return syscall.EIO
+	}
+	*inode = ino
+	m.cacheAttr(ino, attr)
+	return syscall.F_OK
+}
+
+func (m *kvMeta) moveEntry(inode Ino, parentSrc Ino, nameSrc string, parentDst Ino, nameDst string) error {
+	// the overwritten target is gone
+	if dst, err := m.getEntry(parentDst, nameDst); err != nil {
+		return err
+	} else if dst != 0 && dst != inode {
+		m.removeEntry(parentDst, nameDst)
+	}
+	if inode == 0 {
+		m.invalidate(entryKey(parentSrc, nameSrc))
+		return nil
+	}
+	if err := m.setNode(inode, kvNode{Parent: parentDst, Name: nameDst}); err != nil {
+		return err
+	}
+	if err := m.client.set(entryKey(parentDst, nameDst), []byte(strconv.FormatUint(uint64(inode), 10)), 0); err != nil {
+		return err
+	}
+	return m.client.del(entryKey(parentSrc, nameSrc))
+}
+
+// Readdir returns all entries for given directory, which include attributes if plus is true.
+// The listing is cached until expire, the entries are filled by the cached attributes then.
+func (m *kvMeta) Readdir(ctx *Context, inode Ino, plus bool, entries *[]*Entry) syscall.Errno {
+	if cached, ok := m.cachedListing(inode); ok {
+		for _, e := range cached {
+			entry := &Entry{Name: e.Name, Attr: &Attr{Mode: e.Mode}}
+			if plus {
+				if ino, err := m.getEntry(inode, e.Name); err == nil && ino != 0 && m.cachedAttr(ino, entry.Attr) {
+					entry.Ino = ino
+				}
+			}
+			*entries = append(*entries, entry)
+		}
+		return syscall.F_OK
+	}
+	name, errno := m.path(inode)
+	if utils.IsError(errno) {
+		return errno
+	}
+	var listed []*Entry
+	if errno = m.readdir(name, plus, &listed); utils.IsError(errno) {
+		return errno
+	}
+	for _, entry := range listed {
+		if !plus || entry.Attr.Type == 0 {
+			continue
+		}
+		ino, err := m.newEntry(inode, entry.Name)
+		if err != nil {
+			log.Errorf("new entry[%s] of [%s] failed: %v", entry.Name, name, err)
+			continue
+		}
+		entry.Ino = ino
+		m.cacheAttr(ino, entry.Attr)
+	}
+	*entries = append(*entries, listed...)
+	m.cacheListing(inode, listed)
+	return syscall.F_OK
+}
+
+func (m *kvMeta) cachedListing(inode Ino) ([]kvDirEntry, bool) {
+	if m.expire <= 0 {
+		return nil, false
+	}
+	data, err := m.client.get(listingKey(inode))
+	if err != nil || data == nil {
+		return nil, false
+	}
+	cached := kvListing{}
+	if err = json.Unmarshal(data, &cached); err != nil || cached.Expire < time.Now().UnixNano() {
+		return nil, false
+	}
+	return cached.Entries, true
+}
+
+func (m *kvMeta) cacheListing(inode Ino, listed []*Entry) {
+	if m.expire <= 0 {
+		return
+	}
+	listing := kvListing{Entries: make([]kvDirEntry, 0, len(listed)), Expire: time.Now().Add(m.expire).UnixNano()}
+	for _, e := range listed {
+		listing.Entries = append(listing.Entries, kvDirEntry{Name: e.Name, Mode: e.Attr.Mode})
+	}
+	data, err := json.Marshal(listing)
+	if err == nil {
+		err = m.client.set(listingKey(inode), data, m.expire)
+	}
+	if err != nil {
+		log.Errorf("cache listing of inode[%d] failed: %v", inode, err)
+	}
+}
+
+// Create creates a file in a directory with given name.
+func (m *kvMeta) Create(ctx *Context, parent Ino, name string, mode uint32, cumask uint16,
+	flags uint32, inode *Ino, attr *Attr) (ufslib.UnderFileStorage, string, syscall.Errno) {
+	path, errno := m.childPath(parent, name)
+	if utils.IsError(errno) {
+		return nil, "", errno
+	}
+	ufs, newPath, errno := m.create(path, mode, flags)
+	if utils.IsError(errno) {
+		return nil, newPath, errno
+	}
+	if errno = m.addEntry(parent, name, path, inode, attr); utils.IsError(errno) {
+		return nil, newPath, errno
+	}
+	m.open(*inode, flags)
+	return ufs, newPath, syscall.F_OK
+}
+
+// Open revalidates the attr of the file, which is not cached until closed if it is opened for write.
+func (m *kvMeta) Open(ctx *Context, inode Ino, flags uint32, attr *Attr) (ufslib.UnderFileStorage, string, syscall.Errno) {
+	name, errno := m.path(inode)
+	if utils.IsError(errno) {
+		return nil, "", errno
+	}
+	if errno = m.getAttr(name, attr); utils.IsError(errno) {
+		return nil, "", errno
+	}
+	m.cacheAttr(inode, attr)
+	m.open(inode, flags)
+	ufs, _, _, path := m.GetUFS(name)
+	return ufs, path, syscall.F_OK
+}
+
+// CopyFileRange copies part of a file to another one by the ufs, the cached attr of fout is dropped.
+func (m *kvMeta) CopyFileRange(ctx *Context, fin Ino, offIn uint64, fout Ino, offOut uint64,
+	size uint64, flags uint32, copied *uint64) syscall.Errno {
+	nameIn, err := m.path(fin)
+	if utils.IsError(err) {
+		return err
+	}
+	nameOut, err := m.path(fout)
+	if utils.IsError(err) {
+		return err
+	}
+	err = m.copyFileRange(nameIn, offIn, nameOut, offOut, size, copied)
+	if err == syscall.F_OK {
+		m.invalidate(attrKey(fout))
+	}
+	return err
+}
+
+func (m *kvMeta) open(inode Ino, flags uint32) {
+	if flags&syscall.O_ACCMODE == syscall.O_RDONLY {
+		return
+	}
+	m.writersLock.Lock()
+	m.writers[inode]++
+	m.writersLock.Unlock()
+}
+
+// Close is called when a handle writing the file is released.
+func (m *kvMeta) Close(ctx *Context, inode Ino) syscall.Errno {
+	m.writersLock.Lock()
+	if m.writers[inode] > 1 {
+		m.writers[inode]--
+	} else {
+		delete(m.writers, inode)
+	}
+	m.writersLock.Unlock()
+	m.invalidate(attrKey(inode))
+	return syscall.F_OK
+}
+
+// GetXattr returns the value of extended attribute for given name.
+func (m *kvMeta) GetXattr(ctx *Context, inode Ino, attribute string, vbuff *[]byte) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.getXattr(name, attribute, vbuff)
+}
+
+// ListXattr returns all extended attributes of a node.
+func (m *kvMeta) ListXattr(ctx *Context, inode Ino, dbuff *[]string) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.listXattr(name, dbuff)
+}
+
+// SetXattr update the extended attribute of a node.
+func (m *kvMeta) SetXattr(ctx *Context, inode Ino, attribute string, value []byte, flags uint32) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.setXattr(name, attribute, value, flags)
+}
+
+// RemoveXattr removes the extended attribute of a node.
+func (m *kvMeta) RemoveXattr(ctx *Context, inode Ino, attribute string) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.removeXattr(name, attribute)
+}
+
+// Flock tries to put a lock on given file.
+func (m *kvMeta) Flock(ctx *Context, inode Ino, owner uint64, ltype uint32, block bool) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.flock(ctx, inode, name, owner, ltype, block)
+}
+
+// Getlk returns the current lock owner for a range on a file.
+func (m *kvMeta) Getlk(ctx *Context, inode Ino, owner uint64, ltype *uint32, start, end *uint64, pid *uint32) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.getlk(inode, name, owner, ltype, start, end, pid)
+}
+
+// Setlk sets a file range lock on given file.
+func (m *kvMeta) Setlk(ctx *Context, inode Ino, owner uint64, block bool, ltype uint32, start, end uint64, pid uint32) syscall.Errno {
+	name, err := m.path(inode)
+	if utils.IsError(err) {
+		return err
+	}
+	return m.setlk(ctx, inode, name, owner, block, ltype, start, end, pid)
+}
+
+// Shutdown closes the kv client.
+func (m *kvMeta) Shutdown() error {
+	return m.client.close()
+}
@@ -0,0 +1,122 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestBoltMeta(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	assert.Nil(t, os.MkdirAll(root, 0755))
+	fsMeta := base.FSMeta{
+		ID:      "fs-root-test",
+		UfsType: base.LocalType,
+		Properties: map[string]string{
+			base.RootKey: root,
+		},
+		SubPath: root,
+	}
+	config := &Config{Driver: BoltName, BoltPath: filepath.Join(dir, "meta.db"), Expire: time.Minute}
+	m, err := NewMeta(fsMeta, nil, nil, config)
+	assert.Nil(t, err)
+	assert.Equal(t, BoltName, m.Name())
+
+	ctx := NewEmptyContext()
+	var dirIno, fileIno Ino
+	attr := &Attr{}
+	assert.Equal(t, syscall.Errno(0), m.Mkdir(ctx, rootInodeID, "a", 0755, &dirIno, attr))
+	_, _, errno := m.Create(ctx, dirIno, "f", 0644, 0, syscall.O_WRONLY, &fileIno, attr)
+	assert.Equal(t, syscall.Errno(0), errno)
+	assert.Equal(t, syscall.Errno(0), m.Close(ctx, fileIno))
+	assert.Equal(t, "/a/f", m.InoToPath(fileIno))
+	assert.Equal(t, fileIno, m.PathToIno("/a/f"))
+
+	ino, _, errno := m.Lookup(ctx, dirIno, "f")
+	assert.Equal(t, syscall.Errno(0), errno)
+	assert.Equal(t, fileIno, ino)
+
+	// the listing is cached until expire
+	var entries []*Entry
+	assert.Equal(t, syscall.Errno(0), m.Readdir(ctx, dirIno, false, &entries))
+	assert.Equal(t, 1, len(entries))
+	assert.Nil(t, os.WriteFile(filepath.Join(root, "a", "g"), []byte("g"), 0644))
+	entries = nil
+	assert.Equal(t, syscall.Errno(0), m.Readdir(ctx, dirIno, false, &entries))
+	assert.Equal(t, 1, len(entries))
+
+	// rename keeps the inode
+	assert.Equal(t, syscall.Errno(0), m.Rename(ctx, dirIno, "f", rootInodeID, "h", 0, &ino, attr))
+	assert.Equal(t, fileIno, ino)
+	assert.Equal(t, "/h", m.InoToPath(fileIno))
+	_, _, errno = m.Lookup(ctx, dirIno, "f")
+	assert.Equal(t, syscall.ENOENT, errno)
+	entries = nil
+	assert.Equal(t, syscall.Errno(0), m.Readdir(ctx, dirIno, false, &entries))
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "g", entries[0].Name)
+
+	// the inodes are kept across remounts
+	assert.Nil(t, m.Shutdown())
+	m, err = NewMeta(fsMeta, nil, nil, config)
+	assert.Nil(t, err)
+	ino, _, errno = m.Lookup(ctx, rootInodeID, "a")
+	assert.Equal(t, syscall.Errno(0), errno)
+	assert.Equal(t, dirIno, ino)
+	ino, _, errno = m.Lookup(ctx, rootInodeID, "h")
+	assert.Equal(t, syscall.Errno(0), errno)
+	assert.Equal(t, fileIno, ino)
+
+	assert.Equal(t, syscall.Errno(0), m.Unlink(ctx, rootInodeID, "h"))
+	assert.Equal(t, "", m.InoToPath(fileIno))
+	assert.Nil(t, m.Shutdown())
+}
+
+func TestBoltExpire(t *testing.T) {
+	c, err := newBoltClient(filepath.Join(t.TempDir(), "meta.db"), "fs-root-test")
+	assert.Nil(t, err)
+	client := c.(*boltClient)
+	defer client.close()
+
+	assert.Nil(t, client.set("a", []byte("a"), time.Millisecond))
+	assert.Nil(t, client.set("b", []byte("b"), 0))
+	time.Sleep(10 * time.Millisecond)
+	value, err := client.get("a")
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+	ok, err := client.setNX("a", []byte("c"))
+	assert.Nil(t, err)
+	assert.True(t, ok)
+
+	assert.Nil(t, client.set("d", []byte("d"), time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	assert.Nil(t, client.deleteExpired(time.Now()))
+	for key, expected := range map[string][]byte{"a": []byte("c"), "b": []byte("b"), "d": nil} {
+		value, err = client.get(key)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, value)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const RedisName = "redis"
+
+// redisClient keeps the meta in redis which can be shared by the mounts of the file system,
+// the keys of each file system are prefixed with its id.
+type redisClient struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// newRedisClient connects the redis addressed by url, e.g. redis://:password@host:6379/1
+func newRedisClient(url, prefix string) (kvClient, error) {
+	opt, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opt)
+	if err = rdb.Ping(context.Background()).Err(); err != nil {
+		rdb.Close()
+		return nil, err
+	}
+	return &redisClient{rdb: rdb, prefix: "pfs:" + prefix + ":"}, nil
+}
+
+func (c *redisClient) name() string {
+	return RedisName
+}
+
+func (c *redisClient) get(key string) ([]byte, error) {
+	value, err := c.rdb.Get(context.Background(), c.prefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (c *redisClient) set(key string, value []byte, expire time.Duration) error {
+	return c.rdb.Set(context.Background(), c.prefix+key, value, expire).Err()
+}
+
+func (c *redisClient) setNX(key string, value []byte) (bool, error) {
+	return c.rdb.SetNX(context.Background(), c.prefix+key, value, 0).Result()
+}
+
+func (c *redisClient) incr(key string) (uint64, error) {
+	n, err := c.rdb.Incr(context.Background(), c.prefix+key).Result()
+	return uint64(n), err
+}
+
+func (c *redisClient) del(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.prefix + key
+	}
+	return c.rdb.Del(context.Background(), prefixed...).Err()
+}
+
+func (c *redisClient) close() error {
+	return c.rdb.Close()
+}
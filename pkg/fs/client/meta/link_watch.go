@@ -0,0 +1,67 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// LinkWatchTimeout is the max time a watch of the links waits on pfs server
+var LinkWatchTimeout = 60 * time.Second
+
+// LinkWatcher watches the links of fs stored by pfs server
+type LinkWatcher interface {
+	// LinkVersion returns the version of the links got on mount
+	LinkVersion() int64
+	// WatchLinks waits until the version of the links is different from version or timeout,
+	// and returns the links with their version, the links are nil if timeout.
+	WatchLinks(version int64, timeout time.Duration) (map[string]base.FSMeta, int64, error)
+}
+
+// WatchLinks updates the links by update once they are changed on pfs server until stopChan is closed,
+// the watch is retried after interval seconds if failed.
+func WatchLinks(stopChan chan struct{}, interval int, watcher LinkWatcher, update func(map[string]base.FSMeta) error) {
+	version := watcher.LinkVersion()
+	for {
+		select {
+		case <-stopChan:
+			log.Info("links watcher stopped")
+			return
+		default:
+		}
+		links, newVersion, err := watcher.WatchLinks(version, LinkWatchTimeout)
+		if err == nil && newVersion != version {
+			if err = update(links); err == nil {
+				log.Infof("links updated from version[%d] to [%d]", version, newVersion)
+				version = newVersion
+			}
+		}
+		if err != nil {
+			log.Errorf("watch links from version[%d] failed: %v", version, err)
+			select {
+			case <-stopChan:
+				log.Info("links watcher stopped")
+				return
+			case <-time.After(time.Duration(interval) * time.Second):
+			}
+		}
+	}
+}
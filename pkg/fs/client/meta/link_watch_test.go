@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+type watchResult struct {
+	links   map[string]base.FSMeta
+	version int64
+	err     error
+}
+
+type fakeLinkWatcher struct {
+	sync.Mutex
+	results  []watchResult
+	versions []int64
+	done     chan struct{}
+}
+
+func (w *fakeLinkWatcher) LinkVersion() int64 {
+	return 1
+}
+
+func (w *fakeLinkWatcher) WatchLinks(version int64, timeout time.Duration) (map[string]base.FSMeta, int64, error) {
+	w.Lock()
+	defer w.Unlock()
+	w.versions = append(w.versions, version)
+	if len(w.results) == 0 {
+		close(w.done)
+		w.done = make(chan struct{})
+		time.Sleep(10 * time.Millisecond)
+		return nil, version, nil
+	}
+	result := w.results[0]
+	w.results = w.results[1:]
+	return result.links, result.version, result.err
+}
+
+func TestWatchLinks(t *testing.T) {
+	links := map[string]base.FSMeta{"/link": {Name: "link", UfsType: base.LocalType, Type: base.LinkType}}
+	done := make(chan struct{})
+	watcher := &fakeLinkWatcher{
+		results: []watchResult{
+			{version: 1, err: errors.New("server unavailable")},
+			{version: 1},
+			{links: links, version: 2},
+			{links: map[string]base.FSMeta{}, version: 3},
+			{links: map[string]base.FSMeta{}, version: 4},
+		},
+		done: done,
+	}
+	var updated []map[string]base.FSMeta
+	update := func(fsMetas map[string]base.FSMeta) error {
+		if len(fsMetas) == 0 && len(updated) == 1 {
+			updated = append(updated, nil)
+			return errors.New("update failed")
+		}
+		updated = append(updated, fsMetas)
+		return nil
+	}
+
+	stopChan := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		WatchLinks(stopChan, 0, watcher, update)
+		close(stopped)
+	}()
+	<-done
+	close(stopChan)
+	<-stopped
+
+	watcher.Lock()
+	defer watcher.Unlock()
+	// the version is kept if the watch or the update failed
+	assert.Equal(t, []int64{1, 1, 1, 2, 2, 4}, watcher.versions[:6])
+	assert.Equal(t, 3, len(updated))
+	assert.Equal(t, links, updated[0])
+	assert.Nil(t, updated[1])
+	assert.Equal(t, map[string]base.FSMeta{}, updated[2])
+}
@@ -0,0 +1,177 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/server/api/request"
+	"paddleflow/pkg/fs/server/api/response"
+	"paddleflow/pkg/fs/utils/lock"
+)
+
+const (
+	lockRetryInterval     = 100 * time.Millisecond
+	lockHeartbeatInterval = 20 * time.Second
+)
+
+// LockClient is the pfs server coordinating the locks across mounts, see base.Client.
+type LockClient interface {
+	FileLock(req request.FileLockRequest) (*response.FileLockResponse, error)
+	KeepLockSession() error
+	ReleaseLockSession() error
+}
+
+// Locker keeps the flock and posix locks of files. The locks are identified by inode
+// on the local mount, or by path when coordinated by server.
+type Locker interface {
+	Flock(inode Ino, name string, owner uint64, ltype uint32) (bool, error)
+	Getlk(inode Ino, name string, owner uint64, r lock.Range) (lock.Range, error)
+	Setlk(inode Ino, name string, owner uint64, r lock.Range) (bool, error)
+	// Changed is closed when a lock is released, nil means the waiters have to poll.
+	Changed() <-chan struct{}
+	Close()
+}
+
+type localLocker struct {
+	table *lock.Table
+}
+
+// NewLocalLocker returns a locker holding locks only among the processes on this mount.
+func NewLocalLocker() Locker {
+	return &localLocker{table: lock.NewTable()}
+}
+
+func localKey(inode Ino) string {
+	return strconv.FormatUint(uint64(inode), 10)
+}
+
+func (l *localLocker) Flock(inode Ino, name string, owner uint64, ltype uint32) (bool, error) {
+	return l.table.Flock(localKey(inode), lock.Owner{ID: owner}, ltype), nil
+}
+
+func (l *localLocker) Getlk(inode Ino, name string, owner uint64, r lock.Range) (lock.Range, error) {
+	return l.table.Getlk(localKey(inode), lock.Owner{ID: owner}, r), nil
+}
+
+func (l *localLocker) Setlk(inode Ino, name string, owner uint64, r lock.Range) (bool, error) {
+	return l.table.Setlk(localKey(inode), lock.Owner{ID: owner}, r), nil
+}
+
+func (l *localLocker) Changed() <-chan struct{} {
+	return l.table.Changed()
+}
+
+func (l *localLocker) Close() {
+}
+
+type serverLocker struct {
+	client LockClient
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewServerLocker returns a locker coordinated by pfs server, so that the locks hold
+// across all the mounts of the fs. The session of mount is kept alive by heartbeat.
+func NewServerLocker(client LockClient) Locker {
+	l := &serverLocker{client: client, stop: make(chan struct{})}
+	go l.heartbeat()
+	return l
+}
+
+func (l *serverLocker) heartbeat() {
+	ticker := time.NewTicker(lockHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.client.KeepLockSession(); err != nil {
+				log.Errorf("keep lock session failed: %v", err)
+			}
+		}
+	}
+}
+
+func (l *serverLocker) Flock(inode Ino, name string, owner uint64, ltype uint32) (bool, error) {
+	resp, err := l.client.FileLock(request.FileLockRequest{Path: name, Owner: owner, Flock: true, Type: ltype})
+	if err != nil {
+		return false, err
+	}
+	return resp.Granted, nil
+}
+
+func (l *serverLocker) Getlk(inode Ino, name string, owner uint64, r lock.Range) (lock.Range, error) {
+	resp, err := l.client.FileLock(request.FileLockRequest{Path: name, Owner: owner, Test: true,
+		Type: r.Type, Start: r.Start, End: r.End, Pid: r.Pid})
+	if err != nil {
+		return lock.Range{}, err
+	}
+	return lock.Range{Type: resp.Type, Start: resp.Start, End: resp.End, Pid: resp.Pid}, nil
+}
+
+func (l *serverLocker) Setlk(inode Ino, name string, owner uint64, r lock.Range) (bool, error) {
+	resp, err := l.client.FileLock(request.FileLockRequest{Path: name, Owner: owner,
+		Type: r.Type, Start: r.Start, End: r.End, Pid: r.Pid})
+	if err != nil {
+		return false, err
+	}
+	return resp.Granted, nil
+}
+
+func (l *serverLocker) Changed() <-chan struct{} {
+	return nil
+}
+
+func (l *serverLocker) Close() {
+	l.once.Do(func() {
+		close(l.stop)
+		if err := l.client.ReleaseLockSession(); err != nil {
+			log.Errorf("release lock session failed: %v", err)
+		}
+	})
+}
+
+// waitLock retries try until the lock is granted, block is false or ctx is canceled.
+func (m *DefaultMeta) waitLock(ctx *Context, block bool, try func() (bool, error)) syscall.Errno {
+	for {
+		changed := m.locker.Changed()
+		ok, err := try()
+		if err != nil {
+			log.Errorf("lock failed: %v", err)
+			return syscall.EIO
+		}
+		if ok {
+			return syscall.F_OK
+		}
+		if !block {
+			return syscall.EAGAIN
+		}
+		select {
+		case <-ctx.cancel:
+			return syscall.EINTR
+		case <-changed:
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package meta
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// Config selects the meta engine of the mount.
+type Config struct {
+	// Driver is one of mem, bolt and redis, the inodes of mem are lost after umount
+	Driver string
+	// BoltPath is the db file of bolt
+	BoltPath string
+	// RedisURL addresses the redis, e.g. redis://:password@host:6379/1
+	RedisURL string
+	// Expire is the ttl of the attributes and dir listings cached by bolt and redis, 0 disables the cache
+	Expire time.Duration
+}
+
+// NewMeta returns the meta selected by config, which is mem if config is nil.
+func NewMeta(fsMeta base.FSMeta, links map[string]base.FSMeta, locker Locker, config *Config) (Meta, error) {
+	if config == nil {
+		config = &Config{}
+	}
+	// the keys of each file system are separated by its id
+	id := fsMeta.ID
+	if id == "" {
+		id = fsMeta.Name
+	}
+	if id == "" {
+		id = "default"
+	}
+
+	var client kvClient
+	var err error
+	switch strings.ToLower(config.Driver) {
+	case "", strings.ToLower(DefaultName):
+		inodeHandle := NewInodeHandle()
+		inodeHandle.InitRootNode()
+		return NewDefaultMeta(fsMeta, links, inodeHandle, locker)
+	case BoltName:
+		client, err = newBoltClient(config.BoltPath, id)
+	case RedisName:
+		client, err = newRedisClient(config.RedisURL, id)
+	default:
+		return nil, fmt.Errorf("meta driver[%s] is not supported", config.Driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("init %s meta failed: %v", config.Driver, err)
+	}
+	m, err := newKVMeta(fsMeta, links, locker, client, config.Expire)
+	if err != nil {
+		client.close()
+		return nil, err
+	}
+	return m, nil
+}
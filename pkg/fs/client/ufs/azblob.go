@@ -0,0 +1,280 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/fs/client/base"
+)
+
+const (
+	// the block size limit of the blob service
+	AzblobMaxPartSize              = 4000 * 1024 * 1024
+	AzblobDefaultUploadConcurrency = 4
+	azblobMaxRetryRequests         = 3
+	azblobCopyPollInterval         = 100 * time.Millisecond
+)
+
+type azblobStorage struct {
+	container   azblob.ContainerURL
+	partSize    int
+	concurrency int
+}
+
+var _ objectStorage = &azblobStorage{}
+
+func (s *azblobStorage) String() string {
+	return base.AzblobType
+}
+
+// the head requests have no body, so the status code is checked rather than the service code
+func isAzblobNotFound(err error) bool {
+	if rerr, ok := err.(azblob.ResponseError); ok && rerr.Response() != nil {
+		return rerr.Response().StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+func azblobError(err error) error {
+	if err != nil && isAzblobNotFound(err) {
+		return syscall.ENOENT
+	}
+	return err
+}
+
+func (s *azblobStorage) head(key string) (objectInfo, error) {
+	resp, err := s.container.NewBlobURL(key).GetProperties(context.Background(),
+		azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return objectInfo{}, azblobError(err)
+	}
+	return objectInfo{key: key, size: resp.ContentLength(), mtime: resp.LastModified()}, nil
+}
+
+func toObjectInfo(item azblob.BlobItemInternal) objectInfo {
+	info := objectInfo{key: item.Name, mtime: item.Properties.LastModified}
+	if item.Properties.ContentLength != nil {
+		info.size = *item.Properties.ContentLength
+	}
+	return info
+}
+
+func (s *azblobStorage) list(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	ctx := context.Background()
+	var infos []objectInfo
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		options := azblob.ListBlobsSegmentOptions{Prefix: prefix}
+		if limit > 0 {
+			options.MaxResults = int32(limit - len(infos))
+		}
+		if recursive {
+			resp, err := s.container.ListBlobsFlatSegment(ctx, marker, options)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range resp.Segment.BlobItems {
+				infos = append(infos, toObjectInfo(item))
+			}
+			marker = resp.NextMarker
+		} else {
+			resp, err := s.container.ListBlobsHierarchySegment(ctx, marker, Delimiter, options)
+			if err != nil {
+				return nil, err
+			}
+			for _, item := range resp.Segment.BlobItems {
+				infos = append(infos, toObjectInfo(item))
+			}
+			for _, p := range resp.Segment.BlobPrefixes {
+				infos = append(infos, objectInfo{key: p.Name, isPrefix: true})
+			}
+			marker = resp.NextMarker
+		}
+		if limit > 0 && len(infos) >= limit {
+			break
+		}
+	}
+	return infos, nil
+}
+
+func (s *azblobStorage) get(key string, off, size int64) (io.ReadCloser, error) {
+	count := int64(azblob.CountToEnd)
+	if size >= 0 {
+		count = size
+	}
+	resp, err := s.container.NewBlobURL(key).Download(context.Background(), off, count,
+		azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, azblobError(err)
+	}
+	return resp.Body(azblob.RetryReaderOptions{MaxRetryRequests: azblobMaxRetryRequests}), nil
+}
+
+func (s *azblobStorage) put(key string, data io.Reader) error {
+	blob := s.container.NewBlockBlobURL(key)
+	if data == nil {
+		_, err := blob.Upload(context.Background(), bytes.NewReader(nil), azblob.BlobHTTPHeaders{},
+			azblob.Metadata{}, azblob.BlobAccessConditions{}, azblob.AccessTierNone, nil, azblob.ClientProvidedKeyOptions{})
+		return err
+	}
+	_, err := azblob.UploadStreamToBlockBlob(context.Background(), data, blob, s.uploadOptions())
+	return err
+}
+
+func (s *azblobStorage) uploadOptions() azblob.UploadStreamToBlockBlobOptions {
+	return azblob.UploadStreamToBlockBlobOptions{
+		BufferSize: s.partSize,
+		MaxBuffers: s.concurrency,
+	}
+}
+
+// newWriter stages the blocks from a pipe in background, the blocks are committed on Close
+func (s *azblobStorage) newWriter(key string) objectWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader, writer := io.Pipe()
+	w := &azblobWriter{PipeWriter: writer, cancel: cancel, done: make(chan error, 1)}
+	go func() {
+		_, err := azblob.UploadStreamToBlockBlob(ctx, reader, s.container.NewBlockBlobURL(key), s.uploadOptions())
+		if err != nil {
+			log.Errorf("azblob upload [%s] failed: %v", key, err)
+		}
+		// unblock the writes if the upload fails
+		reader.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+// copy starts a server side copy and waits until it completes
+func (s *azblobStorage) copy(src, dst string) error {
+	ctx := context.Background()
+	blob := s.container.NewBlobURL(dst)
+	resp, err := blob.StartCopyFromURL(ctx, s.container.NewBlobURL(src).URL(), azblob.Metadata{},
+		azblob.ModifiedAccessConditions{}, azblob.BlobAccessConditions{}, azblob.AccessTierNone, nil)
+	if err != nil {
+		return azblobError(err)
+	}
+	status := resp.CopyStatus()
+	for status == azblob.CopyStatusPending {
+		time.Sleep(azblobCopyPollInterval)
+		props, err := blob.GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+		if err != nil {
+			return err
+		}
+		status = props.CopyStatus()
+	}
+	if status != azblob.CopyStatusSuccess {
+		return fmt.Errorf("azblob copy [%s] to [%s] %s", src, dst, status)
+	}
+	return nil
+}
+
+func (s *azblobStorage) delete(key string) error {
+	_, err := s.container.NewBlobURL(key).Delete(context.Background(),
+		azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+	return azblobError(err)
+}
+
+type azblobWriter struct {
+	*io.PipeWriter
+	cancel context.CancelFunc
+	done   chan error
+}
+
+func (w *azblobWriter) Close() error {
+	defer w.cancel()
+	w.PipeWriter.Close()
+	return <-w.done
+}
+
+// Abort cancels the upload before the blocks are committed, so that the blob is not changed
+func (w *azblobWriter) Abort() {
+	w.cancel()
+	w.PipeWriter.CloseWithError(context.Canceled)
+	<-w.done
+}
+
+// NewAzblobFileSystem connects to the blob service endpoint in properties, or the endpoint of the
+// account in azure if not set, e.g. http://127.0.0.1:10000/devstoreaccount1 for Azurite.
+func NewAzblobFileSystem(properties map[string]interface{}) (UnderFileStorage, error) {
+	accountName := getStringProperty(properties, base.AccountName)
+	accountKey := getStringProperty(properties, base.AccountKey)
+	endpoint := getStringProperty(properties, base.Endpoint)
+	container := strings.TrimSuffix(getStringProperty(properties, base.Bucket), "/")
+	subpath := getStringProperty(properties, base.SubPath)
+
+	if endpoint == "" {
+		endpoint = fmt.Sprintf(base.AzblobDefaultEndpointFormat, accountName)
+	}
+	containerURL, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + container)
+	if err != nil {
+		return nil, fmt.Errorf("invalid azblob endpoint[%s]: %v", endpoint, err)
+	}
+
+	var credential azblob.Credential = azblob.NewAnonymousCredential()
+	if accountKey != "" {
+		accountKey, err := common.AesDecrypt(accountKey, common.AESEncryptKey)
+		if err != nil {
+			return nil, err
+		}
+		credential, err = azblob.NewSharedKeyCredential(accountName, accountKey)
+		if err != nil {
+			return nil, fmt.Errorf("Fail to create azblob credential: %s", err)
+		}
+	}
+
+	partSize, err := getObjectPartSize(properties, AzblobMaxPartSize)
+	if err != nil {
+		return nil, err
+	}
+	concurrency, err := getIntProperty(properties, base.UploadConcurrency, AzblobDefaultUploadConcurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &azblobStorage{
+		container:   azblob.NewContainerURL(*containerURL, azblob.NewPipeline(credential, azblob.PipelineOptions{})),
+		partSize:    int(partSize),
+		concurrency: int(concurrency),
+	}
+
+	if _, err := s.container.GetProperties(context.Background(), azblob.LeaseAccessConditions{}); err != nil {
+		if isAzblobNotFound(err) {
+			return nil, errors.New("BucketNotExist")
+		}
+		log.Errorf("get azblob container[%s] failed: %v", container, err)
+		return nil, err
+	}
+	return newObjectFileSystem(s, subpath, properties)
+}
+
+func init() {
+	RegisterUFS(base.AzblobType, NewAzblobFileSystem)
+}
@@ -0,0 +1,244 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/fs/client/base"
+)
+
+// TestAzblob runs against Azurite if AZBLOB_ENDPOINT is set, e.g.
+// docker run -p 10000:10000 mcr.microsoft.com/azure-storage/azurite azurite-blob --blobHost 0.0.0.0,
+// with AZBLOB_ENDPOINT=http://127.0.0.1:10000/devstoreaccount1 and the well known account of Azurite
+func TestAzblob(t *testing.T) {
+	if os.Getenv("AZBLOB_ENDPOINT") == "" {
+		t.Skip("AZBLOB_ENDPOINT is not set")
+	}
+	accountKey, err := common.AesEncrypt(os.Getenv("AZBLOB_ACCOUNT_KEY"), common.AESEncryptKey)
+	assert.NoError(t, err)
+
+	properties := make(map[string]interface{})
+	properties[base.Endpoint] = os.Getenv("AZBLOB_ENDPOINT")
+	properties[base.AccountName] = os.Getenv("AZBLOB_ACCOUNT_NAME")
+	properties[base.AccountKey] = accountKey
+	properties[base.Bucket] = os.Getenv("AZBLOB_CONTAINER")
+	properties[base.SubPath] = "/ufs/test"
+
+	fs, err := NewAzblobFileSystem(properties)
+	assert.NoError(t, err)
+	testObjectUFS(t, fs)
+}
+
+// fakeAzblobServer serves the subset of the blob service REST api used by azblobStorage
+type fakeAzblobServer struct {
+	sync.Mutex
+	container string
+	blobs     map[string][]byte
+	mtimes    map[string]time.Time
+	blocks    map[string][]byte
+}
+
+func newFakeAzblobServer(container string) *fakeAzblobServer {
+	return &fakeAzblobServer{
+		container: container,
+		blobs:     make(map[string][]byte),
+		mtimes:    make(map[string]time.Time),
+		blocks:    make(map[string][]byte),
+	}
+}
+
+func (s *fakeAzblobServer) notFound(w http.ResponseWriter, code string) {
+	w.Header().Set("x-ms-error-code", code)
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"utf-8\"?><Error><Code>%s</Code><Message>%s</Message></Error>", code, code)
+}
+
+func (s *fakeAzblobServer) putBlob(key string, data []byte) {
+	s.blobs[key] = data
+	s.mtimes[key] = time.Now()
+}
+
+func (s *fakeAzblobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	query := r.URL.Query()
+	if path != s.container && !strings.HasPrefix(path, s.container+"/") {
+		s.notFound(w, "ContainerNotFound")
+		return
+	}
+	key := strings.TrimPrefix(strings.TrimPrefix(path, s.container), "/")
+	if key == "" {
+		if query.Get("comp") == "list" {
+			s.list(w, query)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		switch query.Get("comp") {
+		case "block":
+			data, _ := ioutil.ReadAll(r.Body)
+			s.blocks[key+"/"+query.Get("blockid")] = data
+		case "blocklist":
+			var list struct {
+				Latest []string `xml:"Latest"`
+			}
+			if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var data []byte
+			for _, id := range list.Latest {
+				data = append(data, s.blocks[key+"/"+id]...)
+				delete(s.blocks, key+"/"+id)
+			}
+			s.putBlob(key, data)
+		default:
+			if source := r.Header.Get("x-ms-copy-source"); source != "" {
+				u, _ := url.Parse(source)
+				src := strings.TrimPrefix(u.Path, "/"+s.container+"/")
+				data, ok := s.blobs[src]
+				if !ok {
+					s.notFound(w, "CannotVerifyCopySource")
+					return
+				}
+				s.putBlob(key, data)
+				w.Header().Set("x-ms-copy-status", "success")
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			data, _ := ioutil.ReadAll(r.Body)
+			s.putBlob(key, data)
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodHead, http.MethodGet:
+		data, ok := s.blobs[key]
+		if !ok {
+			s.notFound(w, "BlobNotFound")
+			return
+		}
+		w.Header().Set("Last-Modified", s.mtimes[key].UTC().Format(http.TimeFormat))
+		w.Header().Set("x-ms-blob-type", "BlockBlob")
+		status := http.StatusOK
+		var start, end int64 = 0, int64(len(data))
+		if rng := r.Header.Get("x-ms-range"); rng != "" && r.Method == http.MethodGet {
+			var last string
+			parts := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+			start, _ = strconv.ParseInt(parts[0], 10, 64)
+			if len(parts) == 2 {
+				last = parts[1]
+			}
+			if last != "" {
+				n, _ := strconv.ParseInt(last, 10, 64)
+				if n+1 < end {
+					end = n + 1
+				}
+			}
+			if start > end {
+				start = end
+			}
+			status = http.StatusPartialContent
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(end-start, 10))
+		w.WriteHeader(status)
+		if r.Method == http.MethodGet {
+			w.Write(data[start:end])
+		}
+	case http.MethodDelete:
+		if _, ok := s.blobs[key]; !ok {
+			s.notFound(w, "BlobNotFound")
+			return
+		}
+		delete(s.blobs, key)
+		delete(s.mtimes, key)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeAzblobServer) list(w http.ResponseWriter, query url.Values) {
+	prefix, delimiter := query.Get("prefix"), query.Get("delimiter")
+	keys := make([]string, 0, len(s.blobs))
+	for key := range s.blobs {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"utf-8\"?><EnumerationResults><Blobs>")
+	prefixes := make(map[string]bool)
+	for _, key := range keys {
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				p := key[:len(prefix)+i+len(delimiter)]
+				if !prefixes[p] {
+					prefixes[p] = true
+					fmt.Fprintf(&buf, "<BlobPrefix><Name>%s</Name></BlobPrefix>", p)
+				}
+				continue
+			}
+		}
+		fmt.Fprintf(&buf, "<Blob><Name>%s</Name><Properties><Last-Modified>%s</Last-Modified>"+
+			"<Content-Length>%d</Content-Length><BlobType>BlockBlob</BlobType></Properties></Blob>",
+			key, s.mtimes[key].UTC().Format(http.TimeFormat), len(s.blobs[key]))
+	}
+	buf.WriteString("</Blobs><NextMarker /></EnumerationResults>")
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(buf.Bytes())
+}
+
+func TestAzblobFakeServer(t *testing.T) {
+	server := httptest.NewServer(newFakeAzblobServer("container"))
+	defer server.Close()
+
+	properties := make(map[string]interface{})
+	properties[base.Endpoint] = server.URL
+	properties[base.AccountName] = "account"
+	properties[base.Bucket] = "container"
+	properties[base.SubPath] = "/ufs/test"
+
+	fs, err := NewAzblobFileSystem(properties)
+	assert.NoError(t, err)
+	testObjectUFS(t, fs)
+
+	properties[base.Bucket] = "missing"
+	_, err = NewAzblobFileSystem(properties)
+	assert.Error(t, err)
+}
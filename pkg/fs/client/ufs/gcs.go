@@ -0,0 +1,192 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+
+	"cloud.google.com/go/storage"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/fs/client/base"
+)
+
+// the chunk size of gcs resumable upload must be a multiple of 256KB, which the MB sizes are
+const GCSMaxPartSize = 1024 * 1024 * 1024
+
+type gcsStorage struct {
+	bucket    *storage.BucketHandle
+	chunkSize int
+}
+
+var _ objectStorage = &gcsStorage{}
+
+func (s *gcsStorage) String() string {
+	return base.GCSType
+}
+
+func gcsError(err error) error {
+	if err == storage.ErrObjectNotExist {
+		return syscall.ENOENT
+	}
+	return err
+}
+
+func (s *gcsStorage) head(key string) (objectInfo, error) {
+	attrs, err := s.bucket.Object(key).Attrs(context.Background())
+	if err != nil {
+		return objectInfo{}, gcsError(err)
+	}
+	return objectInfo{key: attrs.Name, size: attrs.Size, mtime: attrs.Updated}, nil
+}
+
+func (s *gcsStorage) list(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	query := &storage.Query{Prefix: prefix}
+	if !recursive {
+		query.Delimiter = Delimiter
+	}
+	it := s.bucket.Objects(context.Background(), query)
+
+	var infos []objectInfo
+	for limit <= 0 || len(infos) < limit {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// common prefix has only the Prefix field set
+		if attrs.Prefix != "" {
+			infos = append(infos, objectInfo{key: attrs.Prefix, isPrefix: true})
+			continue
+		}
+		infos = append(infos, objectInfo{key: attrs.Name, size: attrs.Size, mtime: attrs.Updated})
+	}
+	return infos, nil
+}
+
+func (s *gcsStorage) get(key string, off, size int64) (io.ReadCloser, error) {
+	reader, err := s.bucket.Object(key).NewRangeReader(context.Background(), off, size)
+	if err != nil {
+		return nil, gcsError(err)
+	}
+	return reader, nil
+}
+
+func (s *gcsStorage) put(key string, data io.Reader) error {
+	writer := s.bucket.Object(key).NewWriter(context.Background())
+	if data != nil {
+		if _, err := io.Copy(writer, data); err != nil {
+			writer.CloseWithError(err)
+			return err
+		}
+	}
+	return writer.Close()
+}
+
+func (s *gcsStorage) newWriter(key string) objectWriter {
+	ctx, cancel := context.WithCancel(context.Background())
+	writer := s.bucket.Object(key).NewWriter(ctx)
+	writer.ChunkSize = s.chunkSize
+	return &gcsWriter{Writer: writer, cancel: cancel}
+}
+
+func (s *gcsStorage) copy(src, dst string) error {
+	_, err := s.bucket.Object(dst).CopierFrom(s.bucket.Object(src)).Run(context.Background())
+	return gcsError(err)
+}
+
+func (s *gcsStorage) delete(key string) error {
+	return gcsError(s.bucket.Object(key).Delete(context.Background()))
+}
+
+// gcsWriter uploads a chunk once ChunkSize bytes are written, the object is created on Close
+type gcsWriter struct {
+	*storage.Writer
+	cancel context.CancelFunc
+}
+
+func (w *gcsWriter) Close() error {
+	defer w.cancel()
+	return w.Writer.Close()
+}
+
+// Abort cancels the upload, so that the object is not created
+func (w *gcsWriter) Abort() {
+	w.cancel()
+	w.Writer.Close()
+}
+
+// NewGCSFileSystem connects to the gcs endpoint in properties, or the endpoint of google cloud if not set.
+// Without the credentials, the application default credentials are used for google cloud, and the
+// requests are not authenticated for the other endpoints such as fake-gcs-server.
+func NewGCSFileSystem(properties map[string]interface{}) (UnderFileStorage, error) {
+	endpoint := getStringProperty(properties, base.Endpoint)
+	credentials := getStringProperty(properties, base.Credentials)
+	bucket := strings.TrimSuffix(getStringProperty(properties, base.Bucket), "/")
+	subpath := getStringProperty(properties, base.SubPath)
+
+	var opts []option.ClientOption
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	if credentials != "" {
+		credentials, err := common.AesDecrypt(credentials, common.AESEncryptKey)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, option.WithCredentialsJSON([]byte(credentials)))
+	} else if endpoint != "" && endpoint != base.GCSDefaultEndpoint {
+		opts = append(opts, option.WithoutAuthentication())
+	}
+
+	partSize, err := getObjectPartSize(properties, GCSMaxPartSize)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("Fail to create gcs client: %s", err)
+	}
+	s := &gcsStorage{
+		bucket:    client.Bucket(bucket),
+		chunkSize: int(partSize),
+	}
+
+	if _, err := s.bucket.Attrs(context.Background()); err != nil {
+		if err == storage.ErrBucketNotExist {
+			return nil, errors.New("BucketNotExist")
+		}
+		log.Errorf("get gcs bucket[%s] failed: %v", bucket, err)
+		return nil, err
+	}
+	return newObjectFileSystem(s, subpath, properties)
+}
+
+func init() {
+	RegisterUFS(base.GCSType, NewGCSFileSystem)
+}
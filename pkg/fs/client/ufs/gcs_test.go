@@ -0,0 +1,42 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// TestGCS runs against fake-gcs-server if GCS_ENDPOINT is set, e.g.
+// docker run -p 4443:4443 fsouza/fake-gcs-server -scheme http, with GCS_ENDPOINT=http://127.0.0.1:4443/storage/v1/
+func TestGCS(t *testing.T) {
+	if os.Getenv("GCS_ENDPOINT") == "" {
+		t.Skip("GCS_ENDPOINT is not set")
+	}
+	properties := make(map[string]interface{})
+	properties[base.Endpoint] = os.Getenv("GCS_ENDPOINT")
+	properties[base.Bucket] = os.Getenv("GCS_BUCKET")
+	properties[base.SubPath] = "/ufs/test"
+
+	fs, err := NewGCSFileSystem(properties)
+	assert.NoError(t, err)
+	testObjectUFS(t, fs)
+}
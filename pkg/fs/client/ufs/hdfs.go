@@ -45,6 +45,9 @@ const (
 	DefaultReplication = 3
 )
 
+// hdfsFileSystem doesn't implement ServerSideCopier, so the copies are streamed through the client. HDFS
+// has no copy on the namenode, and concat moves the blocks of the sources rather than copying them, which
+// is not exposed by the client either.
 type hdfsFileSystem struct {
 	client      *hdfs.Client
 	subpath     string
@@ -505,7 +508,7 @@ func (fh *hdfsFileHandle) Fsync(flags int) (code fuse.Status) {
 	return fuse.ToStatus(fh.writer.Flush())
 }
 
-// not support
+// the file locks are kept by vfs for all the ufs types, see vfs.SetLk
 func (fh *hdfsFileHandle) GetLk(owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) (code fuse.Status) {
 	return fuse.ENOSYS
 }
@@ -518,10 +521,6 @@ func (fh *hdfsFileHandle) SetLkw(owner uint64, lk *fuse.FileLock, flags uint32)
 	return fuse.ENOSYS
 }
 
-func (fh *hdfsFileHandle) setLock(owner uint64, lk *fuse.FileLock, flags uint32, blocking bool) (code fuse.Status) {
-	return fuse.ENOSYS
-}
-
 func (fh *hdfsFileHandle) Truncate(size uint64) fuse.Status {
 	var err error
 	if fh.writer != nil && size == 0 {
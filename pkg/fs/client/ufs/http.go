@@ -0,0 +1,502 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/common/http/util"
+	"paddleflow/pkg/fs/client/base"
+)
+
+const (
+	HTTPDefaultScheme = "https"
+	// HTTPHeadConcurrency is the number of HEAD requests to stat the files of a directory index
+	HTTPHeadConcurrency = 16
+	// HTTPDefaultTimeout is the default seconds of a request, which is long enough to download a file
+	// to the temp file for random writes
+	HTTPDefaultTimeout = 600
+	httpDialTimeout    = 10 * time.Second
+)
+
+// HTTPAllowPrivateAddress allows the http and webdav fs to connect to loopback, link-local and private
+// addresses. It is refused by default, as the addresses are given by users and the server and the
+// mount pods should not be used to reach internal services like the cloud metadata service.
+var HTTPAllowPrivateAddress bool
+
+// hrefPattern matches the links in the directory index pages generated by nginx, apache and
+// http.FileServer
+var hrefPattern = regexp.MustCompile(`(?i)<a\s[^>]*href\s*=\s*["']([^"']+)["']`)
+
+// httpEndpoint sends the requests of the storages over http, like webdav
+type httpEndpoint struct {
+	url      string // scheme://host[:port]
+	user     string
+	password string
+	client   *http.Client
+}
+
+func newHTTPEndpoint(properties map[string]interface{}) (*httpEndpoint, error) {
+	scheme := getStringProperty(properties, base.Scheme)
+	if scheme == "" {
+		scheme = HTTPDefaultScheme
+	}
+	if scheme != "http" && scheme != "https" {
+		return nil, fmt.Errorf("invalid %s[%s]", base.Scheme, scheme)
+	}
+	e := &httpEndpoint{
+		url:  scheme + "://" + getStringProperty(properties, base.Address),
+		user: getStringProperty(properties, base.UserKey),
+	}
+	if password := getStringProperty(properties, base.Password); password != "" {
+		password, err := common.AesDecrypt(password, common.AESEncryptKey)
+		if err != nil {
+			return nil, err
+		}
+		e.password = password
+	}
+	timeout, err := getIntProperty(properties, base.Timeout, HTTPDefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !HTTPAllowPrivateAddress {
+		// the addresses are checked on dialing, so that the redirects and proxies are checked as well
+		transport.Proxy = nil
+		transport.DialContext = util.PublicDialContext(httpDialTimeout)
+	}
+	e.client = &http.Client{
+		Transport: transport,
+		Timeout:   time.Duration(timeout) * time.Second,
+		// the redirect of "dir" to "dir/" tells a directory, others like the ones to cdn are followed
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Path == via[0].URL.Path+Delimiter {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	return e, nil
+}
+
+// objectURL returns the url of key, which is the path relative to the root of the server
+func (e *httpEndpoint) objectURL(key string) string {
+	u := url.URL{Path: Delimiter + key}
+	return e.url + u.EscapedPath()
+}
+
+// keyOfURL returns the key of the absolute or relative url u in the response of key, "" if u
+// is not on the server
+func (e *httpEndpoint) keyOfURL(key, u string) (string, bool) {
+	baseURL, err := url.Parse(e.objectURL(key))
+	if err != nil {
+		return "", false
+	}
+	ref, err := url.Parse(u)
+	if err != nil {
+		return "", false
+	}
+	ref = baseURL.ResolveReference(ref)
+	if ref.Host != baseURL.Host || ref.RawQuery != "" {
+		return "", false
+	}
+	return strings.TrimPrefix(ref.Path, Delimiter), true
+}
+
+func (e *httpEndpoint) do(method, key string, body io.Reader, header map[string]string) (*http.Response, error) {
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequest(method, e.objectURL(key), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if e.user != "" {
+		req.SetBasicAuth(e.user, e.password)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Errorf("%s %s failed: %v", method, req.URL, err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// doAndClose sends the request and checks the status of response, whose body is dropped
+func (e *httpEndpoint) doAndClose(method, key string, body io.Reader, header map[string]string, okStatus ...int) error {
+	resp, err := e.do(method, key, body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	for _, status := range okStatus {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	return httpError(method, key, resp.StatusCode)
+}
+
+// get reads size bytes of key from off, or to the end if size < 0
+func (e *httpEndpoint) get(key string, off, size int64) (io.ReadCloser, error) {
+	header := map[string]string{}
+	if size >= 0 {
+		if size == 0 {
+			return ioutil.NopCloser(strings.NewReader("")), nil
+		}
+		header["Range"] = fmt.Sprintf("bytes=%d-%d", off, off+size-1)
+	} else if off > 0 {
+		header["Range"] = fmt.Sprintf("bytes=%d-", off)
+	}
+	resp, err := e.do(http.MethodGet, key, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK:
+		// the server ignores the range
+		if off > 0 {
+			if _, err := io.CopyN(ioutil.Discard, resp.Body, off); err != nil {
+				resp.Body.Close()
+				if err == io.EOF {
+					return ioutil.NopCloser(strings.NewReader("")), nil
+				}
+				return nil, err
+			}
+		}
+		if size >= 0 {
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.LimitReader(resp.Body, size), resp.Body}, nil
+		}
+		return resp.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return ioutil.NopCloser(strings.NewReader("")), nil
+	default:
+		defer resp.Body.Close()
+		return nil, httpError(http.MethodGet, key, resp.StatusCode)
+	}
+}
+
+func httpError(method, key string, status int) error {
+	switch status {
+	case http.StatusNotFound, http.StatusGone:
+		return syscall.ENOENT
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return syscall.EACCES
+	case http.StatusInsufficientStorage:
+		return syscall.ENOSPC
+	}
+	log.Errorf("%s %s failed: %d %s", method, key, status, http.StatusText(status))
+	return fmt.Errorf("%s %s failed: %d %s", method, key, status, http.StatusText(status))
+}
+
+// httpStorage is a read-only storage of the files under an url, the directories are listed by
+// parsing the index pages generated by the server or from the manifest file.
+type httpStorage struct {
+	*httpEndpoint
+	// files and dirs are loaded from the manifest, nil if the index pages are parsed
+	files map[string]objectInfo
+	dirs  map[string]bool
+}
+
+func (s *httpStorage) String() string {
+	return base.HTTPType
+}
+
+// loadManifest reads the files from the manifest under subpath, the paths are relative to subpath
+func (s *httpStorage) loadManifest(subpath, manifest string) error {
+	body, err := s.get(path.Join(subpath, manifest), 0, -1)
+	if err != nil {
+		log.Errorf("get manifest[%s] under [%s] failed: %v", manifest, subpath, err)
+		return err
+	}
+	defer body.Close()
+
+	s.files = make(map[string]objectInfo)
+	s.dirs = make(map[string]bool)
+	if subpath != "" {
+		s.dirs[subpath+Delimiter] = true
+	}
+	now := time.Now()
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "\t", 2)
+		key := strings.TrimPrefix(path.Join(subpath, path.Clean(Delimiter+fields[0])), Delimiter)
+		info := objectInfo{key: key, size: -1, mtime: now}
+		if len(fields) == 2 {
+			if info.size, err = strconv.ParseInt(strings.TrimSpace(fields[1]), 10, 64); err != nil {
+				return fmt.Errorf("invalid size in manifest line[%s]", line)
+			}
+		}
+		s.files[key] = info
+		for dir := path.Dir(key); dir != "." && dir != subpath; dir = path.Dir(dir) {
+			s.dirs[dir+Delimiter] = true
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *httpStorage) head(key string) (objectInfo, error) {
+	if s.files != nil {
+		if strings.HasSuffix(key, Delimiter) {
+			if s.dirs[key] {
+				return objectInfo{key: key, isPrefix: true}, nil
+			}
+			return objectInfo{}, syscall.ENOENT
+		}
+		info, ok := s.files[key]
+		if !ok {
+			return objectInfo{}, syscall.ENOENT
+		}
+		if info.size >= 0 {
+			return info, nil
+		}
+	}
+
+	resp, err := s.do(http.MethodHead, key, nil, nil)
+	if err != nil {
+		return objectInfo{}, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		// redirected to the directory
+		return objectInfo{}, syscall.ENOENT
+	}
+	if resp.StatusCode != http.StatusOK {
+		return objectInfo{}, httpError(http.MethodHead, key, resp.StatusCode)
+	}
+	info := objectInfo{key: key, size: resp.ContentLength, isPrefix: strings.HasSuffix(key, Delimiter)}
+	if info.size < 0 {
+		info.size = 0
+	}
+	info.mtime = time.Now()
+	if mtime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.mtime = mtime
+	}
+	return info, nil
+}
+
+func (s *httpStorage) list(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	if s.files != nil {
+		return s.listManifest(prefix, recursive, limit)
+	}
+
+	var result []objectInfo
+	dirs := []string{prefix}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		infos, err := s.listIndex(dir)
+		if err == syscall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if info.isPrefix && recursive {
+				dirs = append(dirs, info.key)
+			}
+			result = append(result, info)
+			if limit > 0 && len(result) >= limit {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *httpStorage) listManifest(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	keys := make([]string, 0, len(s.files))
+	for key := range s.files {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var result []objectInfo
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		info := s.files[key]
+		if i := strings.Index(key[len(prefix):], Delimiter); i >= 0 && !recursive {
+			dir := key[:len(prefix)+i+1]
+			if !seen[dir] {
+				seen[dir] = true
+				result = append(result, objectInfo{key: dir, isPrefix: true})
+			}
+			continue
+		}
+		if info.size < 0 {
+			head, err := s.head(key)
+			if err != nil {
+				return nil, err
+			}
+			info = head
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+// listIndex parses the links to the direct children of dir in its index page, and stats the
+// files concurrently as the page tells neither the sizes nor the mtimes reliably
+func (s *httpStorage) listIndex(dir string) ([]objectInfo, error) {
+	resp, err := s.do(http.MethodGet, dir, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpError(http.MethodGet, dir, resp.StatusCode)
+	}
+	page, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []objectInfo
+	seen := make(map[string]bool)
+	for _, match := range hrefPattern.FindAllSubmatch(page, -1) {
+		key, ok := s.keyOfURL(dir, string(match[1]))
+		if !ok || !strings.HasPrefix(key, dir) || key == dir || seen[key] {
+			continue
+		}
+		name := strings.TrimSuffix(key[len(dir):], Delimiter)
+		if strings.Contains(name, Delimiter) {
+			continue
+		}
+		seen[key] = true
+		infos = append(infos, objectInfo{key: key, isPrefix: strings.HasSuffix(key, Delimiter)})
+	}
+
+	var wg sync.WaitGroup
+	var headErr error
+	var errLock sync.Mutex
+	sem := make(chan struct{}, HTTPHeadConcurrency)
+	for i := range infos {
+		if infos[i].isPrefix {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(info *objectInfo) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			head, err := s.head(info.key)
+			if err != nil {
+				errLock.Lock()
+				headErr = err
+				errLock.Unlock()
+				return
+			}
+			*info = head
+		}(&infos[i])
+	}
+	wg.Wait()
+	return infos, headErr
+}
+
+func (s *httpStorage) get(key string, off, size int64) (io.ReadCloser, error) {
+	return s.httpEndpoint.get(key, off, size)
+}
+
+func (s *httpStorage) put(key string, data io.Reader) error {
+	return syscall.EROFS
+}
+
+func (s *httpStorage) newWriter(key string) objectWriter {
+	return readOnlyWriter{}
+}
+
+func (s *httpStorage) copy(src, dst string) error {
+	return syscall.EROFS
+}
+
+func (s *httpStorage) delete(key string) error {
+	return syscall.EROFS
+}
+
+type readOnlyWriter struct{}
+
+func (readOnlyWriter) Write(p []byte) (int, error) {
+	return 0, syscall.EROFS
+}
+
+func (readOnlyWriter) Close() error {
+	return syscall.EROFS
+}
+
+func (readOnlyWriter) Abort() {}
+
+// NewHTTPFileSystem maps the files under http(s)://address/subpath to a read-only fs
+func NewHTTPFileSystem(properties map[string]interface{}) (UnderFileStorage, error) {
+	endpoint, err := newHTTPEndpoint(properties)
+	if err != nil {
+		return nil, err
+	}
+	subpath := strings.Trim(getStringProperty(properties, base.SubPath), Delimiter)
+	s := &httpStorage{httpEndpoint: endpoint}
+	if manifest := getStringProperty(properties, base.Manifest); manifest != "" {
+		if err := s.loadManifest(subpath, manifest); err != nil {
+			return nil, err
+		}
+	}
+	return newObjectFileSystem(s, subpath, properties)
+}
+
+func init() {
+	RegisterUFS(base.HTTPType, NewHTTPFileSystem)
+}
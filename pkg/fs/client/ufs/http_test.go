@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestHTTP(t *testing.T) {
+	root, err := ioutil.TempDir("", "ufs-http")
+	assert.NoError(t, err)
+	defer os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "data", "train", "sub dir"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "data", "train", "a.txt"), []byte("hello world"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "data", "train", "sub dir", "b.txt"), []byte("b"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(root, "data", "MANIFEST"), []byte("train/a.txt\t11\ntrain/sub dir/b.txt\n"), 0644))
+	server := httptest.NewServer(http.FileServer(http.Dir(root)))
+	defer server.Close()
+
+	// the test server is on loopback, which is refused unless private addresses are allowed
+	_, err = NewUFS(base.HTTPType, map[string]interface{}{
+		base.Address: strings.TrimPrefix(server.URL, "http://"),
+		base.Scheme:  "http",
+		base.SubPath: "/data",
+	})
+	assert.Error(t, err)
+	HTTPAllowPrivateAddress = true
+	defer func() { HTTPAllowPrivateAddress = false }()
+
+	for _, manifest := range []string{"", "MANIFEST"} {
+		properties := map[string]interface{}{
+			base.Address:  strings.TrimPrefix(server.URL, "http://"),
+			base.Scheme:   "http",
+			base.SubPath:  "/data",
+			base.Manifest: manifest,
+		}
+		fs, err := NewUFS(base.HTTPType, properties)
+		assert.NoError(t, err, manifest)
+
+		finfo, err := fs.GetAttr("train")
+		assert.NoError(t, err, manifest)
+		assert.True(t, finfo.IsDir)
+		finfo, err = fs.GetAttr("train/a.txt")
+		assert.NoError(t, err, manifest)
+		assert.Equal(t, int64(11), finfo.Size)
+		_, err = fs.GetAttr("train/c.txt")
+		assert.Equal(t, syscall.ENOENT, err, manifest)
+
+		infos, err := fs.(DirAttrReader).ReadDirPlus("train")
+		assert.NoError(t, err, manifest)
+		if assert.Equal(t, 2, len(infos), manifest) {
+			assert.Equal(t, "train/a.txt", infos[0].Name)
+			assert.Equal(t, int64(11), infos[0].Size)
+			assert.Equal(t, "train/sub dir", infos[1].Name)
+			assert.True(t, infos[1].IsDir)
+		}
+		assert.Equal(t, "hello world", readAll(t, fs, "train/a.txt"))
+		assert.Equal(t, "b", readAll(t, fs, "train/sub dir/b.txt"))
+
+		// read-only
+		assert.Equal(t, syscall.EROFS, fs.Mkdir("dir", 0755))
+		_, err = fs.Create("train/c.txt", uint32(os.O_WRONLY|os.O_CREATE), 0644)
+		assert.Equal(t, syscall.EROFS, err)
+	}
+}
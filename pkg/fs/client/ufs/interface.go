@@ -76,6 +76,23 @@ type UnderFileStorage interface {
 	StatFs(name string) *base.StatfsOut
 }
 
+// ServerSideCopier is implemented by the ufs able to copy files without moving the
+// data through the client. The callers fall back to streaming the data if the ufs
+// doesn't implement it or syscall.ENOTSUP is returned.
+type ServerSideCopier interface {
+	// CopyFile copies the whole file src to dst, dst is created or overwritten.
+	CopyFile(src, dst string) error
+	// CopyFileRange copies at most size bytes from offIn of src to offOut of dst and
+	// returns the bytes copied, which is less than size at the end of src.
+	CopyFileRange(src string, offIn uint64, dst string, offOut uint64, size uint64) (uint64, error)
+}
+
+// Refresher is implemented by the file handles caching the size or content of file, Refresh
+// reloads them after the file is changed in ufs not through the handle, e.g. by server side copy.
+type Refresher interface {
+	Refresh() error
+}
+
 type Creator func(properties map[string]interface{}) (UnderFileStorage, error)
 
 var ufs = make(map[string]Creator)
@@ -91,3 +108,11 @@ func NewUFS(_type string, properties map[string]interface{}) (UnderFileStorage,
 	}
 	return nil, fmt.Errorf("unknow ufs")
 }
+
+// DirAttrReader is implemented by the ufs able to list a dir with the attributes of the
+// entries in the same call, so that the callers don't stat the entries one by one.
+type DirAttrReader interface {
+	// ReadDirPlus returns the attributes of the entries, the Name of which is the path of
+	// the entry as given to GetAttr.
+	ReadDirPlus(name string) ([]*base.FileInfo, error)
+}
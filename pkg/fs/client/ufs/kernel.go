@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/utils/mount"
+)
+
+// kernelMountDir is the dir under os.TempDir() where the kernel fs are mounted by pfs
+const kernelMountDir = "pfs-kernel-mounts"
+
+// kernelMountLock serializes the kernel mounts, the fs clients of the same fs share one mount
+var kernelMountLock sync.Mutex
+
+// kernelFileSystem is a POSIX network fs like nfs, cephfs and lustre mounted by the kernel, which
+// is accessed through the mount point as a local fs. The csi plugin mounts it by the kernel as well
+// instead of pfs-fuse.
+type kernelFileSystem struct {
+	*localFileSystem
+	ufsType string
+}
+
+var _ UnderFileStorage = &kernelFileSystem{}
+
+func (fs *kernelFileSystem) String() string {
+	return fs.ufsType
+}
+
+// NewKernelFileSystem uses the fs at the local mount path in properties if set, otherwise mounts
+// server:subpath at a dir of its own if not mounted yet. The mount is kept for the life of process.
+func NewKernelFileSystem(ufsType string, properties map[string]interface{}) (UnderFileStorage, error) {
+	root, _ := properties[base.LocalMountPath].(string)
+	if root == "" {
+		var err error
+		if root, err = kernelMount(ufsType, properties); err != nil {
+			return nil, err
+		}
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		log.Errorf("stat root[%s] of %s failed: %v", root, ufsType, err)
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("root[%s] of %s is not a directory", root, ufsType)
+	}
+	return &kernelFileSystem{
+		localFileSystem: &localFileSystem{subpath: root},
+		ufsType:         ufsType,
+	}, nil
+}
+
+func kernelMount(ufsType string, properties map[string]interface{}) (string, error) {
+	fsType, ok := base.KernelFSType(ufsType)
+	if !ok {
+		return "", fmt.Errorf("%s is not mounted by the kernel", ufsType)
+	}
+	address, _ := properties[base.Address].(string)
+	subpath, _ := properties[base.SubPath].(string)
+	options, _ := properties[base.KernelMountOptions].(string)
+	if err := base.CheckKernelMountOptions(options); err != nil {
+		return "", err
+	}
+	source := base.KernelMountSource(address, subpath)
+	sum := sha256.Sum256([]byte(source + " " + options))
+	mountPoint := filepath.Join(os.TempDir(), kernelMountDir, ufsType+"-"+hex.EncodeToString(sum[:])[:16])
+
+	kernelMountLock.Lock()
+	defer kernelMountLock.Unlock()
+	isMountPoint, err := mount.IsMountPoint(mountPoint)
+	if isMountPoint && err == nil {
+		return mountPoint, nil
+	}
+	if isMountPoint {
+		// the server of the former mount is gone
+		if err := mount.ForceUnmount(mountPoint); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(mountPoint, 0755); err != nil {
+		return "", err
+	}
+	// the fs of users must not bring setuid programs or devices to the server
+	mountOptions := "nosuid,nodev"
+	if options != "" {
+		mountOptions += "," + options
+	}
+	args := []string{"-t", fsType, "-o", mountOptions}
+	args = append(args, source, mountPoint)
+	if output, err := mount.ExecCmdWithTimeout(mount.MountCmdName, args); err != nil {
+		log.Errorf("mount %s[%s] at [%s] failed: %v, output[%s]", ufsType, source, mountPoint, err, string(output))
+		return "", fmt.Errorf("mount %s[%s] failed: %s", ufsType, source, string(output))
+	}
+	log.Infof("mount %s[%s] at [%s]", ufsType, source, mountPoint)
+	return mountPoint, nil
+}
+
+func newKernelFileSystemCreator(ufsType string) Creator {
+	return func(properties map[string]interface{}) (UnderFileStorage, error) {
+		return NewKernelFileSystem(ufsType, properties)
+	}
+}
+
+func init() {
+	RegisterUFS(base.NFSType, newKernelFileSystemCreator(base.NFSType))
+	RegisterUFS(base.CephFSType, newKernelFileSystemCreator(base.CephFSType))
+	RegisterUFS(base.LustreType, newKernelFileSystemCreator(base.LustreType))
+}
@@ -0,0 +1,54 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestKernelFileSystemLocalMountPath(t *testing.T) {
+	root := "/tmp/ufs/nfs"
+	os.RemoveAll(root)
+	assert.NoError(t, os.MkdirAll(root, 0755))
+
+	properties := map[string]interface{}{
+		base.Address:        "192.168.1.2",
+		base.SubPath:        "/export/data",
+		base.LocalMountPath: root,
+	}
+	fs, err := NewUFS(base.NFSType, properties)
+	assert.NoError(t, err)
+	assert.Equal(t, base.NFSType, fs.String())
+	assert.NoError(t, fs.Mkdir("dir", 0755))
+	finfo, err := fs.GetAttr("dir")
+	assert.NoError(t, err)
+	assert.True(t, finfo.IsDir)
+	_, err = os.Stat(root + "/dir")
+	assert.NoError(t, err)
+
+	_, ok := fs.(ServerSideCopier)
+	assert.True(t, ok)
+
+	properties[base.LocalMountPath] = root + "/not-exist"
+	_, err = NewUFS(base.NFSType, properties)
+	assert.Error(t, err)
+}
@@ -189,3 +189,49 @@ func NewLocalFileSystem(properties map[string]interface{}) (UnderFileStorage, er
 func init() {
 	RegisterUFS(base.LocalType, NewLocalFileSystem)
 }
+
+// CopyFile copies src to dst in kernel by copy_file_range(2), dst keeps the mode of src.
+func (fs *localFileSystem) CopyFile(src, dst string) error {
+	in, err := os.Open(fs.GetPath(src))
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return syscall.EISDIR
+	}
+	out, err := os.OpenFile(fs.GetPath(dst), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	n, err := copyFileRange(in, out, 0, 0, info.Size())
+	if err != nil {
+		return err
+	}
+	if n < info.Size() {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (fs *localFileSystem) CopyFileRange(src string, offIn uint64, dst string, offOut uint64, size uint64) (uint64, error) {
+	in, err := os.Open(fs.GetPath(src))
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(fs.GetPath(dst), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	n, err := copyFileRange(in, out, int64(offIn), int64(offOut), int64(size))
+	return uint64(n), err
+}
+
+var _ ServerSideCopier = &localFileSystem{}
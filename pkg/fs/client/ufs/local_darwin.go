@@ -17,6 +17,7 @@ limitations under the License.
 package ufs
 
 import (
+	"os"
 	"syscall"
 	"time"
 
@@ -113,3 +114,7 @@ func (fs *localFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Ti
 	})
 	return syscall.Utimes(fs.GetPath(name), tv)
 }
+
+func copyFileRange(in, out *os.File, offIn, offOut, size int64) (int64, error) {
+	return 0, syscall.ENOTSUP
+}
@@ -18,9 +18,12 @@ package ufs
 
 import (
 	"fmt"
+	"os"
 	"syscall"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/client/utils"
 )
@@ -120,3 +123,24 @@ func (fs *localFileSystem) Utimens(name string, Atime *time.Time, Mtime *time.Ti
 	})
 	return syscall.Utimes(fs.GetPath(name), tv)
 }
+
+// copyFileRange copies the data in kernel without passing through user space, the
+// data may be reflinked by the filesystem like xfs and btrfs.
+func copyFileRange(in, out *os.File, offIn, offOut, size int64) (int64, error) {
+	var copied int64
+	for copied < size {
+		n, err := unix.CopyFileRange(int(in.Fd()), &offIn, int(out.Fd()), &offOut, int(size-copied), 0)
+		if err != nil {
+			// not supported by the kernel or across filesystems
+			if copied == 0 && (err == unix.ENOSYS || err == unix.EXDEV || err == unix.EOPNOTSUPP) {
+				return 0, syscall.ENOTSUP
+			}
+			return copied, err
+		}
+		if n == 0 {
+			break
+		}
+		copied += int64(n)
+	}
+	return copied, nil
+}
@@ -18,6 +18,7 @@ package ufs
 
 import (
 	"os"
+	"syscall"
 	"testing"
 
 	"github.com/hanwen/go-fuse/v2/fuse"
@@ -81,3 +82,34 @@ func TestFile(t *testing.T) {
 	assert.NoError(t, err)
 	assert.LessOrEqual(t, 1, len(entries))
 }
+
+func TestLocalCopy(t *testing.T) {
+	root := t.TempDir()
+	properties := map[string]interface{}{base.SubPath: root}
+	fs, err := NewLocalFileSystem(properties)
+	assert.NoError(t, err)
+	copier, ok := fs.(ServerSideCopier)
+	assert.True(t, ok)
+
+	content := []byte("hello paddleflow")
+	assert.NoError(t, os.WriteFile(root+"/src", content, 0644))
+	err = copier.CopyFile("src", "dst")
+	if err == syscall.ENOTSUP {
+		t.Skip("copy_file_range is not supported")
+	}
+	assert.NoError(t, err)
+	data, err := os.ReadFile(root + "/dst")
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	// copy "paddleflow" to the head of dst, the size is truncated at the end of src
+	n, err := copier.CopyFileRange("src", 6, "dst", 0, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10), n)
+	data, err = os.ReadFile(root + "/dst")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("paddleflowleflow"), data)
+
+	_, err = copier.CopyFileRange("notexist", 0, "dst", 0, 1)
+	assert.Error(t, err)
+}
@@ -0,0 +1,750 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/hanwen/go-fuse/v2/fuse/nodefs"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/utils"
+)
+
+const (
+	ObjectDefaultPartSize = 16 * 1024 * 1024
+	ObjectMinPartSize     = 1024 * 1024
+)
+
+// objectInfo is an object or a common prefix listed from an object storage
+type objectInfo struct {
+	key      string
+	size     int64
+	mtime    time.Time
+	isPrefix bool
+}
+
+// objectWriter streams the data of an object, which is visible after Close
+type objectWriter interface {
+	io.Writer
+	Close() error
+	// Abort drops the written data
+	Abort()
+}
+
+// objectStorage is the object storage under objectFileSystem, which emulates the directories
+// with the "/" suffixed objects and the common prefixes, the same as s3FileSystem does.
+type objectStorage interface {
+	String() string
+	// head returns syscall.ENOENT if the object does not exist
+	head(key string) (objectInfo, error)
+	// list returns the objects under prefix, the objects in sub directories are returned
+	// if recursive, otherwise their common prefixes. All are returned if limit <= 0.
+	list(prefix string, recursive bool, limit int) ([]objectInfo, error)
+	// get reads size bytes of the object from off, or to the end if size < 0
+	get(key string, off, size int64) (io.ReadCloser, error)
+	// put creates or overwrites the object with data, which may be nil for an empty object
+	put(key string, data io.Reader) error
+	newWriter(key string) objectWriter
+	// copy copies the object src to dst in the storage
+	copy(src, dst string) error
+	delete(key string) error
+}
+
+type objectFileSystem struct {
+	storage     objectStorage
+	subpath     string // bucket:subpath/name
+	defaultTime time.Time
+	sync.Mutex
+}
+
+var _ UnderFileStorage = &objectFileSystem{}
+var _ DirAttrReader = &objectFileSystem{}
+
+// newObjectFileSystem creates the subpath in storage if not exists
+func newObjectFileSystem(storage objectStorage, subpath string, properties map[string]interface{}) (*objectFileSystem, error) {
+	fs := &objectFileSystem{
+		storage:     storage,
+		subpath:     strings.TrimPrefix(subpath, Delimiter),
+		defaultTime: time.Now(),
+	}
+
+	if fs.subpath != "" {
+		exist, err := fs.exists("")
+		if err != nil {
+			log.Debugf("%s exists err: %v", storage, err)
+			return nil, err
+		}
+		if !exist {
+			if err := fs.createEmptyDir(fs.subpath); err != nil {
+				log.Debugf("%s create empty dir err: %v", storage, err)
+				return nil, err
+			}
+		}
+	}
+
+	Owner, Group = "root", "root"
+	if owner, ok := properties[base.Owner]; ok {
+		Owner = owner.(string)
+	}
+	if group, ok := properties[base.Group]; ok {
+		Group = group.(string)
+	}
+	return fs, nil
+}
+
+// Used for pretty printing.
+func (fs *objectFileSystem) String() string {
+	return fs.storage.String()
+}
+
+func (fs *objectFileSystem) getFullPath(name string) string {
+	if strings.HasPrefix(name, Delimiter) && name != Delimiter {
+		name = strings.TrimPrefix(name, Delimiter)
+	}
+	// will remove suffix "/"
+	path := filepath.Join(fs.subpath, name)
+	// keep '/'
+	if strings.HasSuffix(name, Delimiter) {
+		path += Delimiter
+	}
+	return path
+}
+
+// getRootDirAttr return root dir info of filesystem, all the directories share it
+func (fs *objectFileSystem) getRootDirAttr() *base.FileInfo {
+	aTime := fuse.UtimeToTimespec(&fs.defaultTime)
+	var perm uint32
+	perm = syscall.S_IFDIR | 0777
+	uid := uint32(utils.LookupUser(Owner))
+	gid := uint32(utils.LookupGroup(Group))
+
+	st := fillStat(1, perm, uid, gid, 4096, 4096, 8, aTime, aTime, aTime)
+
+	return &base.FileInfo{
+		Name:  "",
+		Path:  "",
+		Size:  4096,
+		Mtime: uint64(fs.defaultTime.Unix()),
+		IsDir: true,
+		Owner: Owner,
+		Group: Group,
+		Mode:  utils.StatModeToFileMode(int(perm)),
+		Sys:   st,
+	}
+}
+
+func (fs *objectFileSystem) getFileAttr(name, path string, size int64, mtime time.Time) *base.FileInfo {
+	aTime := fuse.UtimeToTimespec(&mtime)
+	mode := syscall.S_IFREG | 0666
+	uid := uint32(utils.LookupUser(Owner))
+	gid := uint32(utils.LookupGroup(Group))
+	st := fillStat(1, uint32(mode), uid, gid, size, 4096, size/512, aTime, aTime, aTime)
+
+	return &base.FileInfo{
+		Name:  name,
+		Path:  path,
+		Size:  size,
+		Mtime: uint64(mtime.Unix()),
+		Owner: Owner,
+		Group: Group,
+		Mode:  utils.StatModeToFileMode(mode),
+		Sys:   st,
+	}
+}
+
+// getDirAttr checks the empty directory object first, then the objects under the directory
+func (fs *objectFileSystem) getDirAttr(name string) (*base.FileInfo, error) {
+	if !strings.HasSuffix(name, Delimiter) {
+		name += Delimiter
+	}
+	path := fs.getFullPath(name)
+
+	if _, err := fs.storage.head(path); err != nil {
+		if err != syscall.ENOENT {
+			return nil, err
+		}
+		infos, err := fs.storage.list(path, true, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(infos) == 0 {
+			return nil, syscall.ENOENT
+		}
+	}
+
+	finfo := fs.getRootDirAttr()
+	finfo.Name = name
+	finfo.Path = path
+	return finfo, nil
+}
+
+func (fs *objectFileSystem) GetAttr(name string) (*base.FileInfo, error) {
+	path := fs.getFullPath(name)
+	if path == "" {
+		return fs.getRootDirAttr(), nil
+	}
+	if strings.HasSuffix(path, Delimiter) {
+		return fs.getDirAttr(name)
+	}
+
+	info, err := fs.storage.head(path)
+	if err == syscall.ENOENT {
+		return fs.getDirAttr(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fs.getFileAttr(name, path, info.size, info.mtime), nil
+}
+
+// the object storages don't support chmod, chown and utimens, but returning an error fails tar
+func (fs *objectFileSystem) Chmod(name string, mode uint32) error {
+	return nil
+}
+
+func (fs *objectFileSystem) Chown(name string, uid uint32, gid uint32) error {
+	return nil
+}
+
+func (fs *objectFileSystem) Utimens(name string, atime *time.Time, mtime *time.Time) error {
+	return nil
+}
+
+func (fs *objectFileSystem) Truncate(name string, size uint64) error {
+	if size == 0 {
+		if err := fs.Unlink(name); err != nil {
+			return err
+		}
+		return fs.createEmptyFile(name)
+	}
+	return syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) Access(name string, mode, callerUid, callerGid uint32) error {
+	return nil
+}
+
+// Tree structure
+func (fs *objectFileSystem) Link(oldName string, newName string) error {
+	return syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) exists(name string) (bool, error) {
+	finfo, err := fs.GetAttr(name)
+	if err != nil && err != syscall.ENOENT {
+		return false, err
+	}
+	return finfo != nil, nil
+}
+
+func (fs *objectFileSystem) createEmptyDir(dir string) error {
+	if !strings.HasSuffix(dir, Delimiter) {
+		dir += Delimiter
+	}
+	return fs.storage.put(dir, nil)
+}
+
+func (fs *objectFileSystem) Mkdir(name string, mode uint32) error {
+	if !strings.HasSuffix(name, Delimiter) {
+		name += Delimiter
+	}
+	exist, err := fs.exists(name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return syscall.EEXIST
+	}
+	return fs.createEmptyDir(fs.getFullPath(name))
+}
+
+func (fs *objectFileSystem) Mknod(name string, mode uint32, dev uint32) error {
+	return syscall.ENOSYS
+}
+
+// Rename file oldName to newName by copy and delete, directories are not supported
+func (fs *objectFileSystem) Rename(oldName string, newName string) error {
+	finfo, err := fs.GetAttr(oldName)
+	if err != nil {
+		return err
+	}
+	if finfo.IsDir {
+		return syscall.ENOSYS
+	}
+
+	if err := fs.storage.copy(fs.getFullPath(oldName), fs.getFullPath(newName)); err != nil {
+		return err
+	}
+	return fs.Unlink(oldName)
+}
+
+func (fs *objectFileSystem) Rmdir(name string) error {
+	if !strings.HasSuffix(name, Delimiter) {
+		name = name + Delimiter
+	}
+	path := fs.getFullPath(name)
+
+	infos, err := fs.storage.list(path, true, 2)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if info.key != path {
+			return syscall.ENOTEMPTY
+		}
+	}
+	if len(infos) == 0 {
+		// the directory only exists as the prefix of its objects
+		return syscall.ENOENT
+	}
+	return fs.Unlink(name)
+}
+
+func (fs *objectFileSystem) Unlink(name string) error {
+	return fs.storage.delete(fs.getFullPath(name))
+}
+
+// Extended attributes.
+func (fs *objectFileSystem) GetXAttr(name string, attribute string) (data []byte, err error) {
+	return nil, syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) ListXAttr(name string) (attributes []string, err error) {
+	return nil, syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) RemoveXAttr(name string, attr string) error {
+	return syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) SetXAttr(name string, attr string, data []byte, flags int) error {
+	return syscall.ENOSYS
+}
+
+// File handling.  If opening for writing, the file's mtime
+// should be updated too.
+func (fs *objectFileSystem) Open(name string, flags uint32) (fd base.FileHandle, err error) {
+	finfo, err := fs.GetAttr(name)
+	if err != nil {
+		return nil, err
+	}
+	return &objectFileHandle{
+		name:  name,
+		size:  finfo.Size,
+		fs:    fs,
+		flags: flags,
+	}, nil
+}
+
+func (fs *objectFileSystem) createEmptyFile(name string) error {
+	exist, err := fs.exists(name)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return syscall.EEXIST
+	}
+	return fs.storage.put(fs.getFullPath(name), nil)
+}
+
+func (fs *objectFileSystem) Create(name string, flags uint32, mode uint32) (fd base.FileHandle, err error) {
+	fs.Lock()
+	defer fs.Unlock()
+	if flags&syscall.O_CREAT != 0 || flags&syscall.O_EXCL != 0 {
+		// create empty file, make GetAttr work
+		if err := fs.createEmptyFile(name); err != nil {
+			return nil, err
+		}
+		return &objectFileHandle{
+			name:  name,
+			fs:    fs,
+			flags: flags,
+		}, nil
+	}
+	return nil, syscall.ENOSYS
+}
+
+// Directory handling
+func (fs *objectFileSystem) ReadDir(name string) (stream []base.DirEntry, err error) {
+	infos, err := fs.listDir(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range infos {
+		mode := syscall.S_IFREG | 0666
+		if info.isPrefix {
+			mode = int(utils.StatModeToFileMode(syscall.S_IFDIR | 0777))
+		}
+		stream = append(stream, base.DirEntry{
+			Mode: uint32(mode),
+			Name: fs.getBaseName(info.key),
+		})
+	}
+	return stream, nil
+}
+
+// ReadDirPlus fills the attributes of the entries from the listing.
+func (fs *objectFileSystem) ReadDirPlus(name string) ([]*base.FileInfo, error) {
+	infos, err := fs.listDir(name)
+	if err != nil {
+		return nil, err
+	}
+	finfos := make([]*base.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		var finfo *base.FileInfo
+		if info.isPrefix {
+			// the same as getDirAttr
+			finfo = fs.getRootDirAttr()
+		} else {
+			finfo = fs.getFileAttr("", "", info.size, info.mtime)
+		}
+		finfo.Name = filepath.Join(name, fs.getBaseName(info.key))
+		finfo.Path = info.key
+		finfos = append(finfos, finfo)
+	}
+	return finfos, nil
+}
+
+// listDir lists the files and sub directories of name, the directory object itself is skipped,
+// and the sub directory existing as both an empty directory object and a prefix is listed once.
+func (fs *objectFileSystem) listDir(name string) ([]objectInfo, error) {
+	path := fs.getFullPath(name)
+	if path != "" && !strings.HasSuffix(path, Delimiter) {
+		path += Delimiter
+	}
+	infos, err := fs.storage.list(path, false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]objectInfo, 0, len(infos))
+	seen := make(map[string]bool, len(infos))
+	for _, info := range infos {
+		if strings.HasSuffix(info.key, Delimiter) {
+			info.isPrefix = true
+		}
+		if info.key == path || seen[info.key] {
+			continue
+		}
+		seen[info.key] = true
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+func (fs *objectFileSystem) getBaseName(key string) string {
+	return filepath.Base(strings.TrimSuffix(key, Delimiter))
+}
+
+// Symlinks.
+func (fs *objectFileSystem) Symlink(value string, linkName string) error {
+	return syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) Readlink(name string) (string, error) {
+	return "", syscall.ENOSYS
+}
+
+func (fs *objectFileSystem) StatFs(name string) *base.StatfsOut {
+	// 256 T
+	return &base.StatfsOut{
+		Blocks:  0x1000000,
+		Bfree:   0x1000000,
+		Bavail:  0x1000000,
+		Ffree:   0x1000000,
+		Bsize:   0x1000000,
+		NameLen: 1023,
+	}
+}
+
+// objectFileHandle streams the sequential writes from offset 0 with the writer of the storage,
+// random writes fall back to a temp file holding the whole object, which is uploaded on flush.
+type objectFileHandle struct {
+	name           string
+	size           int64
+	flags          uint32
+	writeTmpfile   *os.File
+	canWrite       chan struct{}
+	writeSrcReader io.ReadCloser
+	writer         objectWriter
+	writerOffset   int64
+	dirty          bool
+	mu             sync.Mutex
+	fs             *objectFileSystem
+}
+
+var _ base.FileHandle = &objectFileHandle{}
+
+func (fh *objectFileHandle) String() string {
+	return fmt.Sprintf("objectFileHandle(%s:%s)", fh.fs.storage, fh.name)
+}
+
+func (fh *objectFileHandle) SetInode(*nodefs.Inode) {
+}
+
+func (fh *objectFileHandle) InnerFile() nodefs.File {
+	return nil
+}
+
+func (fh *objectFileHandle) Read(buf []byte, off int64) (res fuse.ReadResult, code fuse.Status) {
+	if off >= fh.size || len(buf) == 0 {
+		return fuse.ReadResultData(buf[0:0]), fuse.OK
+	}
+	size := int64(len(buf))
+	if off+size > fh.size {
+		size = fh.size - off
+	}
+
+	reader, err := fh.fs.storage.get(fh.fs.getFullPath(fh.name), off, size)
+	if err != nil {
+		return nil, fuse.ToStatus(err)
+	}
+	defer reader.Close()
+	n, err := io.ReadFull(reader, buf[:size])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, fuse.ToStatus(err)
+	}
+	return fuse.ReadResultData(buf[:n]), fuse.OK
+}
+
+func (fh *objectFileHandle) Write(data []byte, off int64) (uint32, fuse.Status) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.writeTmpfile == nil {
+		if fh.writer == nil && fh.size == 0 && off == 0 {
+			fh.writer = fh.fs.storage.newWriter(fh.fs.getFullPath(fh.name))
+			fh.writerOffset = 0
+		}
+		if fh.writer != nil && off == fh.writerOffset {
+			n, err := fh.writer.Write(data)
+			fh.writerOffset += int64(n)
+			if err != nil {
+				return uint32(n), fuse.ToStatus(err)
+			}
+			return uint32(n), fuse.OK
+		}
+		if err := fh.switchToTmpfile(); err != nil {
+			return 0, fuse.ToStatus(err)
+		}
+	}
+
+	if fh.canWrite != nil {
+		<-fh.canWrite
+	}
+	n, err := fh.writeTmpfile.WriteAt(data, off)
+	fh.dirty = true
+	return uint32(n), fuse.ToStatus(err)
+}
+
+// closeWriter completes the streaming upload, caller must hold fh.mu.
+func (fh *objectFileHandle) closeWriter() error {
+	err := fh.writer.Close()
+	fh.writer = nil
+	if err != nil {
+		log.Errorf("%s upload [%s] failed: %v", fh.fs.storage, fh.name, err)
+		return err
+	}
+	fh.size = fh.writerOffset
+	return nil
+}
+
+// switchToTmpfile completes the streaming upload if any, then downloads the object into
+// a temp file which serves the random writes.
+func (fh *objectFileHandle) switchToTmpfile() error {
+	log.Debugf("%s random write, switch to temp file: fh.name[%s]", fh.fs.storage, fh.name)
+	if fh.writer != nil {
+		if err := fh.closeWriter(); err != nil {
+			return err
+		}
+	}
+
+	os.MkdirAll(TmpPath, 0755)
+	tmpfile, err := ioutil.TempFile(TmpPath, uuid.New().String())
+	if err != nil {
+		return syscall.ENOSYS
+	}
+	fh.writeTmpfile = tmpfile
+	// the temp file is removed after created, the fd is still available
+	defer os.Remove(tmpfile.Name())
+	fh.canWrite = nil
+	if fh.size == 0 {
+		return nil
+	}
+
+	reader, err := fh.fs.storage.get(fh.fs.getFullPath(fh.name), 0, -1)
+	if err != nil {
+		log.Errorf("%s get object [%s] failed: %v", fh.fs.storage, fh.name, err)
+		return err
+	}
+	fh.canWrite = make(chan struct{})
+	fh.writeSrcReader = reader
+	go func() {
+		defer close(fh.canWrite)
+		if _, err := io.Copy(fh.writeTmpfile, reader); err != nil {
+			log.Debugf("copy failed: %v", err)
+		}
+		reader.Close()
+	}()
+	return nil
+}
+
+// flush uploads the written data, caller must hold fh.mu.
+func (fh *objectFileHandle) flush() error {
+	if fh.writer != nil {
+		return fh.closeWriter()
+	}
+	if fh.writeTmpfile == nil || !fh.dirty {
+		return nil
+	}
+	if fh.canWrite != nil {
+		<-fh.canWrite
+	}
+	fh.writeTmpfile.Seek(0, 0)
+	if err := fh.fs.storage.put(fh.fs.getFullPath(fh.name), fh.writeTmpfile); err != nil {
+		log.Errorf("%s put object [%s] failed: %v", fh.fs.storage, fh.name, err)
+		return err
+	}
+	if info, err := fh.writeTmpfile.Stat(); err == nil {
+		fh.size = info.Size()
+	}
+	fh.dirty = false
+	return nil
+}
+
+func (fh *objectFileHandle) Release() {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if err := fh.flush(); err != nil {
+		log.Errorf("%s Release: upload [%s] failed: %v", fh.fs.storage, fh.name, err)
+	}
+	if fh.writeTmpfile != nil {
+		fh.writeTmpfile.Close()
+		fh.writeTmpfile = nil
+	}
+}
+
+func (fh *objectFileHandle) Flush() fuse.Status {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fuse.ToStatus(fh.flush())
+}
+
+func (fh *objectFileHandle) Fsync(flags int) (code fuse.Status) {
+	return fuse.OK
+}
+
+// the file locks are kept by vfs for all the ufs types, see vfs.SetLk
+func (fh *objectFileHandle) GetLk(owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) (code fuse.Status) {
+	return fuse.ENOSYS
+}
+
+func (fh *objectFileHandle) SetLk(owner uint64, lk *fuse.FileLock, flags uint32) (code fuse.Status) {
+	return fuse.ENOSYS
+}
+
+func (fh *objectFileHandle) SetLkw(owner uint64, lk *fuse.FileLock, flags uint32) (code fuse.Status) {
+	return fuse.ENOSYS
+}
+
+func (fh *objectFileHandle) Truncate(size uint64) fuse.Status {
+	if size != 0 {
+		return fuse.ENOSYS
+	}
+
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	// drop the written data before the object is recreated
+	if fh.writer != nil {
+		fh.writer.Abort()
+		fh.writer = nil
+	}
+	if err := fh.fs.Truncate(fh.name, size); err != nil {
+		return fuse.ToStatus(err)
+	}
+	fh.size = 0
+	if fh.writeTmpfile != nil {
+		if fh.writeSrcReader != nil {
+			// closing the reader ends the io.Copy
+			fh.writeSrcReader.Close()
+		}
+		if fh.canWrite != nil {
+			<-fh.canWrite
+		}
+		fh.writeTmpfile.Truncate(0)
+		fh.writeTmpfile.Seek(0, 0)
+		fh.dirty = true
+	}
+	return fuse.OK
+}
+
+func (fh *objectFileHandle) Chmod(mode uint32) fuse.Status {
+	return fuse.ToStatus(fh.fs.Chmod(fh.name, mode))
+}
+
+func (fh *objectFileHandle) Chown(uid uint32, gid uint32) fuse.Status {
+	return fuse.ToStatus(fh.fs.Chown(fh.name, uid, gid))
+}
+
+func (fh *objectFileHandle) GetAttr(a *fuse.Attr) fuse.Status {
+	finfo, err := fh.fs.GetAttr(fh.name)
+	if err != nil {
+		return fuse.ToStatus(err)
+	}
+
+	stat_t := finfo.Sys.(syscall.Stat_t)
+	a.FromStat(&stat_t)
+	return fuse.OK
+}
+
+func (fh *objectFileHandle) Utimens(atime *time.Time, mtime *time.Time) fuse.Status {
+	return fuse.ToStatus(fh.fs.Utimens(fh.name, atime, mtime))
+}
+
+func (fh *objectFileHandle) Allocate(off uint64, size uint64, mode uint32) (code fuse.Status) {
+	return fuse.ENOSYS
+}
+
+func getStringProperty(properties map[string]interface{}, key string) string {
+	value, _ := properties[key].(string)
+	return value
+}
+
+// getObjectPartSize returns the part size in bytes of the streaming writes
+func getObjectPartSize(properties map[string]interface{}, maxPartSize int64) (int64, error) {
+	partSizeMB, err := getIntProperty(properties, base.PartSize, ObjectDefaultPartSize/1024/1024)
+	if err != nil {
+		return 0, err
+	}
+	partSize := partSizeMB * 1024 * 1024
+	if partSize < ObjectMinPartSize || partSize > maxPartSize {
+		return 0, fmt.Errorf("%s[%dMB] must be between %dMB and %dMB", base.PartSize, partSizeMB,
+			ObjectMinPartSize/1024/1024, maxPartSize/1024/1024)
+	}
+	return partSize, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// memStorage is an in memory objectStorage listing the keys in order as the object storages do
+type memStorage struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func newMemStorage() *memStorage {
+	return &memStorage{objects: make(map[string][]byte)}
+}
+
+func (s *memStorage) String() string {
+	return "mem"
+}
+
+func (s *memStorage) head(key string) (objectInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return objectInfo{}, syscall.ENOENT
+	}
+	return objectInfo{key: key, size: int64(len(data)), mtime: time.Now()}, nil
+}
+
+func (s *memStorage) list(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+	var keys []string
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var infos []objectInfo
+	prefixes := make(map[string]bool)
+	for _, key := range keys {
+		if limit > 0 && len(infos) >= limit {
+			break
+		}
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if !recursive {
+			if i := strings.Index(key[len(prefix):], Delimiter); i >= 0 && len(prefix)+i+1 < len(key) {
+				p := key[:len(prefix)+i+1]
+				if !prefixes[p] {
+					prefixes[p] = true
+					infos = append(infos, objectInfo{key: p, isPrefix: true})
+				}
+				continue
+			}
+		}
+		infos = append(infos, objectInfo{key: key, size: int64(len(s.objects[key])), mtime: time.Now()})
+	}
+	return infos, nil
+}
+
+func (s *memStorage) get(key string, off, size int64) (io.ReadCloser, error) {
+	s.Lock()
+	defer s.Unlock()
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, syscall.ENOENT
+	}
+	data = data[off:]
+	if size >= 0 && size < int64(len(data)) {
+		data = data[:size]
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *memStorage) put(key string, data io.Reader) error {
+	var content []byte
+	if data != nil {
+		var err error
+		if content, err = ioutil.ReadAll(data); err != nil {
+			return err
+		}
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.objects[key] = content
+	return nil
+}
+
+func (s *memStorage) newWriter(key string) objectWriter {
+	return &memWriter{storage: s, key: key}
+}
+
+func (s *memStorage) copy(src, dst string) error {
+	s.Lock()
+	defer s.Unlock()
+	data, ok := s.objects[src]
+	if !ok {
+		return syscall.ENOENT
+	}
+	s.objects[dst] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *memStorage) delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.objects[key]; !ok {
+		return syscall.ENOENT
+	}
+	delete(s.objects, key)
+	return nil
+}
+
+type memWriter struct {
+	storage *memStorage
+	key     string
+	buf     bytes.Buffer
+}
+
+func (w *memWriter) Write(data []byte) (int, error) {
+	return w.buf.Write(data)
+}
+
+func (w *memWriter) Close() error {
+	return w.storage.put(w.key, &w.buf)
+}
+
+func (w *memWriter) Abort() {
+	w.buf.Reset()
+}
+
+func readAll(t *testing.T, fs UnderFileStorage, name string) string {
+	fh, err := fs.Open(name, uint32(os.O_RDONLY))
+	assert.NoError(t, err)
+	defer fh.Release()
+	buf := make([]byte, 1024)
+	r, code := fh.Read(buf, 0)
+	assert.Equal(t, fuse.OK, code)
+	data, _ := r.Bytes(buf)
+	return string(data)
+}
+
+// testObjectUFS checks the directory emulation and the write paths of an object storage ufs
+func testObjectUFS(t *testing.T, fs UnderFileStorage) {
+	// clean up the last run
+	fs.Unlink("dir/hello")
+	fs.Unlink("dir/world")
+	fs.Rmdir("dir")
+
+	assert.NoError(t, fs.Mkdir("dir", 0755))
+	assert.Equal(t, syscall.EEXIST, fs.Mkdir("dir", 0755))
+	finfo, err := fs.GetAttr("dir")
+	assert.NoError(t, err)
+	assert.True(t, finfo.IsDir)
+
+	// sequential writes are streamed
+	fh, err := fs.Create("dir/hello", uint32(os.O_WRONLY|os.O_CREATE), 0644)
+	assert.NoError(t, err)
+	_, code := fh.Write([]byte("hello "), 0)
+	assert.Equal(t, fuse.OK, code)
+	_, code = fh.Write([]byte("world"), 6)
+	assert.Equal(t, fuse.OK, code)
+	assert.Equal(t, fuse.OK, fh.Flush())
+	fh.Release()
+	assert.Equal(t, "hello world", readAll(t, fs, "dir/hello"))
+
+	finfo, err = fs.GetAttr("dir/hello")
+	assert.NoError(t, err)
+	assert.False(t, finfo.IsDir)
+	assert.Equal(t, int64(11), finfo.Size)
+
+	// random writes fall back to the temp file with the content of the object
+	fh, err = fs.Open("dir/hello", uint32(os.O_WRONLY))
+	assert.NoError(t, err)
+	_, code = fh.Write([]byte("W"), 6)
+	assert.Equal(t, fuse.OK, code)
+	fh.Release()
+	assert.Equal(t, "hello World", readAll(t, fs, "dir/hello"))
+
+	entries, err := fs.ReadDir("dir")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, "hello", entries[0].Name)
+
+	infos, err := fs.(DirAttrReader).ReadDirPlus("dir")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(infos))
+	assert.Equal(t, "dir/hello", infos[0].Name)
+	assert.Equal(t, int64(11), infos[0].Size)
+
+	assert.NoError(t, fs.Rename("dir/hello", "dir/world"))
+	_, err = fs.GetAttr("dir/hello")
+	assert.Equal(t, syscall.ENOENT, err)
+	assert.Equal(t, "hello World", readAll(t, fs, "dir/world"))
+	assert.Equal(t, syscall.ENOSYS, fs.Rename("dir", "dir2"))
+
+	assert.Equal(t, syscall.ENOTEMPTY, fs.Rmdir("dir"))
+	assert.NoError(t, fs.Unlink("dir/world"))
+	assert.NoError(t, fs.Rmdir("dir"))
+	_, err = fs.GetAttr("dir")
+	assert.Equal(t, syscall.ENOENT, err)
+}
+
+func TestObjectFileSystem(t *testing.T) {
+	storage := newMemStorage()
+	fs, err := newObjectFileSystem(storage, "/ufs/test", map[string]interface{}{})
+	assert.NoError(t, err)
+	_, ok := storage.objects["ufs/test/"]
+	assert.True(t, ok)
+
+	testObjectUFS(t, fs)
+
+	// a directory only existing as the prefix of its objects
+	assert.NoError(t, fs.Mkdir("dir", 0755))
+	assert.NoError(t, storage.put("ufs/test/implicit/a", strings.NewReader("a")))
+	finfo, err := fs.GetAttr("implicit")
+	assert.NoError(t, err)
+	assert.True(t, finfo.IsDir)
+
+	entries, err := fs.ReadDir("")
+	assert.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name)
+	}
+	assert.Equal(t, []string{"dir", "implicit"}, names)
+}
+
+func TestObjectFileHandleTruncate(t *testing.T) {
+	fs, err := newObjectFileSystem(newMemStorage(), "", map[string]interface{}{})
+	assert.NoError(t, err)
+
+	fh, err := fs.Create("file", uint32(os.O_WRONLY|os.O_CREATE), 0644)
+	assert.NoError(t, err)
+	_, code := fh.Write([]byte("dropped"), 0)
+	assert.Equal(t, fuse.OK, code)
+	assert.Equal(t, fuse.OK, fh.Truncate(0))
+	_, code = fh.Write([]byte("kept"), 0)
+	assert.Equal(t, fuse.OK, code)
+	fh.Release()
+	assert.Equal(t, "kept", readAll(t, fs, "file"))
+}
+
+func TestGetObjectPartSize(t *testing.T) {
+	maxPartSize := int64(1024 * 1024 * 1024)
+	partSize, err := getObjectPartSize(map[string]interface{}{}, maxPartSize)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(ObjectDefaultPartSize), partSize)
+
+	partSize, err = getObjectPartSize(map[string]interface{}{base.PartSize: "1"}, maxPartSize)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1024*1024), partSize)
+
+	_, err = getObjectPartSize(map[string]interface{}{base.PartSize: "2048"}, maxPartSize)
+	assert.Error(t, err)
+}
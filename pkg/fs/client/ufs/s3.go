@@ -17,7 +17,6 @@ limitations under the License.
 package ufs
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -54,11 +53,13 @@ var Owner string
 var Group string
 
 type s3FileSystem struct {
-	bucket      string
-	subpath     string // bucket:subpath/name
-	sess        *session.Session
-	s3          *s3.S3
-	defaultTime time.Time
+	bucket            string
+	subpath           string // bucket:subpath/name
+	sess              *session.Session
+	s3                *s3.S3
+	defaultTime       time.Time
+	partSize          int64
+	uploadConcurrency int
 	sync.Mutex
 }
 
@@ -401,7 +402,7 @@ func (fs *s3FileSystem) Mknod(name string, mode uint32, dev uint32) error {
 
 // Rename file oldName to newName, only support the file in the same bucket
 func (fs *s3FileSystem) Rename(oldName string, newName string) error {
-	oldPath := fs.bucket + "/" + fs.getFullPath(oldName)
+	oldPath := fs.getFullPath(oldName)
 	newPath := fs.getFullPath(newName)
 
 	finfo, err := fs.GetAttr(oldName)
@@ -412,13 +413,8 @@ func (fs *s3FileSystem) Rename(oldName string, newName string) error {
 		return syscall.ENOSYS
 	}
 
-	request := &s3.CopyObjectInput{
-		Bucket:     &fs.bucket,
-		Key:        &newPath,
-		CopySource: &oldPath,
-	}
-	_, err = fs.s3.CopyObject(request)
-
+	// objects larger than 5GB can't be copied by a single CopyObject
+	err = fs.copyObject(oldPath, newPath, 0, finfo.Size, finfo.Size)
 	if err != nil {
 		return err
 	}
@@ -568,8 +564,17 @@ func (fs *s3FileSystem) Create(name string, flags uint32, mode uint32) (fd base.
 	return nil, syscall.ENOSYS
 }
 
+// openForWrite prepares the handle for writing. Sequential writes from offset 0 are
+// streamed to s3 by multipart upload, so the temp file is only created on the first
+// random write or when the object is not empty.
 func (fs *s3FileSystem) openForWrite(fh *s3FileHandle) error {
 	log.Debugf("S3 OpenForWrite: fh.name[%s]", fh.name)
+	return nil
+}
+
+// openTmpfileForWrite downloads the object into a temp file which serves the random writes.
+func (fs *s3FileSystem) openTmpfileForWrite(fh *s3FileHandle) error {
+	log.Debugf("S3 openTmpfileForWrite: fh.name[%s]", fh.name)
 	fullPath := fh.fs.getFullPath(fh.name)
 	filename := uuid.New().String()
 	os.MkdirAll(TmpPath, 0755)
@@ -639,6 +644,51 @@ func (fs *s3FileSystem) ReadDir(name string) (stream []base.DirEntry, err error)
 	return
 }
 
+// ReadDirPlus fills the attributes of the entries from the listing, which saves a HeadObject per entry.
+func (fs *s3FileSystem) ReadDirPlus(name string) ([]*base.FileInfo, error) {
+	path := fs.getFullPath(name)
+	if !strings.HasSuffix(path, Delimiter) {
+		path += Delimiter
+	}
+
+	ch, err := fs.iterate(path, false)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := uint32(utils.LookupUser(Owner))
+	gid := uint32(utils.LookupGroup(Group))
+	var infos []*base.FileInfo
+	for finfo := range ch {
+		subName := strings.TrimSuffix(finfo.Name, Delimiter)
+		if subName == "" {
+			continue
+		}
+		var info *base.FileInfo
+		if finfo.IsDir {
+			// the same as getDirAttr
+			info = fs.getRootDirAttr()
+		} else {
+			mtime := time.Unix(int64(finfo.Mtime), 0)
+			aTime := fuse.UtimeToTimespec(&mtime)
+			mode := syscall.S_IFREG | 0666
+			st := fillStat(1, uint32(mode), uid, gid, finfo.Size, 4096, finfo.Size/512, aTime, aTime, aTime)
+			info = &base.FileInfo{
+				Size:  finfo.Size,
+				Mtime: finfo.Mtime,
+				Owner: Owner,
+				Group: Group,
+				Mode:  utils.StatModeToFileMode(mode),
+				Sys:   st,
+			}
+		}
+		info.Name = filepath.Join(name, subName)
+		info.Path = finfo.Path
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
 // Symlinks.
 func (fs *s3FileSystem) Symlink(value string, linkName string) error {
 	return syscall.ENOSYS
@@ -668,10 +718,15 @@ type s3FileHandle struct {
 	writeTmpfile   *os.File
 	canWrite       chan struct{}
 	writeSrcReader io.ReadCloser
-	fs             *s3FileSystem
+	// writer streams sequential writes, writeTmpfile takes over on random writes
+	writer *s3MultipartWriter
+	dirty  bool
+	mu     sync.Mutex
+	fs     *s3FileSystem
 }
 
 var _ base.FileHandle = &s3FileHandle{}
+var _ Refresher = &s3FileHandle{}
 
 func (fh *s3FileHandle) String() string {
 	return fmt.Sprintf("s3FileHandle(%s)", fh.name)
@@ -720,65 +775,136 @@ func (fh *s3FileHandle) Read(buf []byte, off int64) (res fuse.ReadResult, code f
 	return fuse.ReadResultData(data), fuse.OK
 }
 
-// s3 do not support random write
+// s3 do not support random write, sequential writes are streamed by multipart upload and
+// random writes fall back to a temp file which is uploaded on flush
 func (fh *s3FileHandle) Write(data []byte, off int64) (uint32, fuse.Status) {
 	log.Debugf("S3 Write: fh.name[%s]", fh.name)
-	fullPath := fh.fs.getFullPath(fh.name)
-	if fh.writeTmpfile != nil {
-		if fh.canWrite != nil {
-			select {
-			case <-fh.canWrite:
-				break
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.writeTmpfile == nil {
+		if fh.writer == nil && fh.size == 0 && off == 0 {
+			fh.writer = newS3MultipartWriter(fh.fs, fh.fs.getFullPath(fh.name))
+		}
+		if fh.writer != nil && off == fh.writer.Offset() {
+			if err := fh.writer.Write(data); err != nil {
+				return 0, fuse.ToStatus(err)
 			}
+			return uint32(len(data)), fuse.OK
+		}
+		if err := fh.switchToTmpfile(); err != nil {
+			return 0, fuse.ToStatus(err)
+		}
+	}
+
+	if fh.canWrite != nil {
+		select {
+		case <-fh.canWrite:
+			break
 		}
-		n, err := fh.writeTmpfile.WriteAt(data, off)
-		return uint32(n), fuse.ToStatus(err)
 	}
+	n, err := fh.writeTmpfile.WriteAt(data, off)
+	fh.dirty = true
+	return uint32(n), fuse.ToStatus(err)
+}
+
+// switchToTmpfile completes the streaming upload if any, then continues with a temp file.
+func (fh *s3FileHandle) switchToTmpfile() error {
+	log.Debugf("S3 random write, switch to temp file: fh.name[%s]", fh.name)
+	if fh.writer != nil {
+		err := fh.writer.Close()
+		fh.size = fh.writer.Offset()
+		fh.writer = nil
+		if err != nil {
+			return err
+		}
+	}
+	return fh.fs.openTmpfileForWrite(fh)
+}
 
-	body := bytes.NewReader(data)
+// flush uploads the written data, caller must hold fh.mu.
+func (fh *s3FileHandle) flush() error {
+	if fh.writer != nil {
+		err := fh.writer.Close()
+		fh.size = fh.writer.Offset()
+		fh.writer = nil
+		return err
+	}
+	if fh.writeTmpfile == nil || !fh.dirty {
+		return nil
+	}
+	if fh.canWrite != nil {
+		select {
+		case <-fh.canWrite:
+			break
+		}
+	}
+	fullPath := fh.fs.getFullPath(fh.name)
+	fh.writeTmpfile.Seek(0, 0)
 	request := &s3.PutObjectInput{
 		Bucket: &fh.bucket,
 		Key:    &fullPath,
-		Body:   body,
+		Body:   fh.writeTmpfile,
 	}
 	_, err := fh.fs.s3.PutObject(request)
-	return uint32(len(data)), fuse.ToStatus(err)
+	if err != nil {
+		log.Errorf("put object[%s] error: [%+v]", fh.name, err)
+		return err
+	}
+	if info, err := fh.writeTmpfile.Stat(); err == nil {
+		fh.size = info.Size()
+	}
+	fh.dirty = false
+	return nil
 }
 
-func (fh *s3FileHandle) Release() {
-	log.Debugf("S3 Release: fh.name[%s]", fh.name)
+// Refresh drops the temp file of random writes and reloads the size of object, the written
+// data must have been flushed.
+func (fh *s3FileHandle) Refresh() error {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if fh.writer != nil || fh.dirty {
+		return syscall.EBUSY
+	}
 	if fh.writeTmpfile != nil {
 		if fh.canWrite != nil {
-			select {
-			case <-fh.canWrite:
-				break
-			}
-		}
-		fullPath := fh.fs.getFullPath(fh.name)
-		fh.writeTmpfile.Seek(0, 0)
-		request := &s3.PutObjectInput{
-			Bucket: &fh.bucket,
-			Key:    &fullPath,
-			Body:   fh.writeTmpfile,
-		}
-		_, err := fh.fs.s3.PutObject(request)
-		if err != nil {
-			log.Debugf("put object error: [%+v]", err)
+			<-fh.canWrite
+			fh.canWrite = nil
 		}
 		fh.writeTmpfile.Close()
 		fh.writeTmpfile = nil
 	}
+	info, err := fh.fs.GetAttr(fh.name)
+	if err != nil {
+		return err
+	}
+	fh.size = info.Size
+	return nil
+}
+
+func (fh *s3FileHandle) Release() {
+	log.Debugf("S3 Release: fh.name[%s]", fh.name)
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	if err := fh.flush(); err != nil {
+		log.Errorf("S3 Release: upload [%s] failed: %v", fh.name, err)
+	}
+	if fh.writeTmpfile != nil {
+		fh.writeTmpfile.Close()
+		fh.writeTmpfile = nil
+	}
 }
 
 func (fh *s3FileHandle) Flush() fuse.Status {
-	return fuse.OK
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fuse.ToStatus(fh.flush())
 }
 
 func (fh *s3FileHandle) Fsync(flags int) (code fuse.Status) {
 	return fuse.OK
 }
 
-// not support
+// the file locks are kept by vfs for all the ufs types, see vfs.SetLk
 func (fh *s3FileHandle) GetLk(owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) (code fuse.Status) {
 	return fuse.ENOSYS
 }
@@ -791,10 +917,6 @@ func (fh *s3FileHandle) SetLkw(owner uint64, lk *fuse.FileLock, flags uint32) (c
 	return fuse.ENOSYS
 }
 
-func (fh *s3FileHandle) setLock(owner uint64, lk *fuse.FileLock, flags uint32, blocking bool) (code fuse.Status) {
-	return fuse.ENOSYS
-}
-
 func (fh *s3FileHandle) Truncate(size uint64) fuse.Status {
 	if size != 0 {
 		return fuse.ENOSYS
@@ -805,7 +927,14 @@ func (fh *s3FileHandle) Truncate(size uint64) fuse.Status {
 		return fuse.ToStatus(err)
 	}
 
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	fh.size = 0
 	// 已经打开写，则清空已写内容
+	if fh.writer != nil {
+		fh.writer.Abort()
+		fh.writer = nil
+	}
 	if fh.writeTmpfile != nil {
 		if fh.writeSrcReader != nil {
 			// 关闭reader会导致io.copy结束
@@ -880,12 +1009,19 @@ func NewS3FileSystem(properties map[string]interface{}) (UnderFileStorage, error
 		subpath = strings.TrimPrefix(subpath, Delimiter)
 	}
 
+	partSize, uploadConcurrency, err := getS3WriteOptions(properties)
+	if err != nil {
+		return nil, err
+	}
+
 	fs := &s3FileSystem{
-		bucket:      bucket,
-		subpath:     subpath,
-		sess:        sess,
-		s3:          s3.New(sess),
-		defaultTime: time.Now(),
+		bucket:            bucket,
+		subpath:           subpath,
+		sess:              sess,
+		s3:                s3.New(sess),
+		defaultTime:       time.Now(),
+		partSize:          partSize,
+		uploadConcurrency: uploadConcurrency,
 	}
 
 	exist, err := fs.isBucketExists(bucket)
@@ -0,0 +1,247 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// s3CopyPartSize is the part size of UploadPartCopy, it's larger than the upload part size
+	// since no data is transferred through the client
+	s3CopyPartSize = 256 * 1024 * 1024
+	s3MaxParts     = 10000
+)
+
+// CopyFile copies the object in s3 without downloading it.
+func (fs *s3FileSystem) CopyFile(src, dst string) error {
+	info, err := fs.GetAttr(src)
+	if err != nil {
+		return err
+	}
+	if info.IsDir {
+		return syscall.EISDIR
+	}
+	return fs.copyObject(fs.getFullPath(src), fs.getFullPath(dst), 0, info.Size, info.Size)
+}
+
+// CopyFileRange copies a range of src to dst in s3. Objects can't be modified in place, so dst is
+// rewritten by the parts copied from the head of dst, the range of src and the tail of dst. It's not
+// supported if a part other than the last one is smaller than the min part size of s3, or the range
+// is copied beyond the end of dst.
+func (fs *s3FileSystem) CopyFileRange(src string, offIn uint64, dst string, offOut uint64, size uint64) (uint64, error) {
+	info, err := fs.GetAttr(src)
+	if err != nil {
+		return 0, err
+	}
+	if info.IsDir {
+		return 0, syscall.EISDIR
+	}
+	if offIn >= uint64(info.Size) || size == 0 {
+		return 0, nil
+	}
+	if size > uint64(info.Size)-offIn {
+		size = uint64(info.Size) - offIn
+	}
+	var dstSize uint64
+	dstInfo, err := fs.GetAttr(dst)
+	if err != nil && err != syscall.ENOENT {
+		return 0, err
+	}
+	if err == nil {
+		dstSize = uint64(dstInfo.Size)
+	}
+	if offOut > dstSize {
+		return 0, syscall.ENOTSUP
+	}
+	if offOut == 0 && size >= dstSize {
+		err = fs.copyObject(fs.getFullPath(src), fs.getFullPath(dst), int64(offIn), int64(size), info.Size)
+		if err != nil {
+			return 0, err
+		}
+		return size, nil
+	}
+
+	srcPath, dstPath := fs.getFullPath(src), fs.getFullPath(dst)
+	var segments []s3CopySegment
+	if offOut > 0 {
+		segments = append(segments, s3CopySegment{key: dstPath, off: 0, size: int64(offOut)})
+	}
+	segments = append(segments, s3CopySegment{key: srcPath, off: int64(offIn), size: int64(size)})
+	if end := offOut + size; end < dstSize {
+		segments = append(segments, s3CopySegment{key: dstPath, off: int64(end), size: int64(dstSize - end)})
+	}
+	parts, ok := s3CopyParts(segments)
+	if !ok {
+		return 0, syscall.ENOTSUP
+	}
+	if err = fs.copyParts(dstPath, parts); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// s3CopySegment is the range [off, off+size) of the object key.
+type s3CopySegment struct {
+	key  string
+	off  int64
+	size int64
+}
+
+// s3CopyParts splits the segments into the parts of UploadPartCopy, ok is false if any part other
+// than the last one is smaller than S3MinPartSize.
+func s3CopyParts(segments []s3CopySegment) (parts []s3CopySegment, ok bool) {
+	var total int64
+	for _, segment := range segments {
+		total += segment.size
+	}
+	partSize := int64(s3CopyPartSize)
+	if total/partSize >= s3MaxParts {
+		partSize = total/s3MaxParts + 1
+	}
+	for _, segment := range segments {
+		for start := segment.off; start < segment.off+segment.size; start += partSize {
+			end := start + partSize
+			if end > segment.off+segment.size {
+				end = segment.off + segment.size
+			}
+			parts = append(parts, s3CopySegment{key: segment.key, off: start, size: end - start})
+		}
+	}
+	if len(parts) > s3MaxParts {
+		return nil, false
+	}
+	for _, part := range parts[:len(parts)-1] {
+		if part.size < S3MinPartSize {
+			return nil, false
+		}
+	}
+	return parts, true
+}
+
+// copyObject copies [off, off+size) of the object src to dst. The whole object up to 5GB
+// is copied by CopyObject, otherwise the range is copied by UploadPartCopy in parallel.
+func (fs *s3FileSystem) copyObject(src, dst string, off, size, srcSize int64) error {
+	source := fs.bucket + "/" + src
+	if off == 0 && size == srcSize && size <= S3MaxPartSize {
+		_, err := fs.s3.CopyObject(&s3.CopyObjectInput{
+			Bucket:     &fs.bucket,
+			Key:        &dst,
+			CopySource: &source,
+		})
+		if err != nil {
+			log.Errorf("s3 copy object[%s] to [%s] failed: %v", src, dst, err)
+		}
+		return err
+	}
+	if size == 0 {
+		_, err := fs.s3.PutObject(&s3.PutObjectInput{
+			Bucket: &fs.bucket,
+			Key:    &dst,
+			Body:   bytes.NewReader(nil),
+		})
+		return err
+	}
+
+	parts, _ := s3CopyParts([]s3CopySegment{{key: src, off: off, size: size}})
+	return fs.copyParts(dst, parts)
+}
+
+// copyParts writes dst by the parts copied from the objects in parallel.
+func (fs *s3FileSystem) copyParts(dst string, copyParts []s3CopySegment) error {
+	resp, err := fs.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+		Bucket: &fs.bucket,
+		Key:    &dst,
+	})
+	if err != nil {
+		log.Errorf("s3 create multipart upload[%s] failed: %v", dst, err)
+		return err
+	}
+	uploadID := resp.UploadId
+
+	var parts []*s3.CompletedPart
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var copyErr error
+	sem := make(chan struct{}, fs.uploadConcurrency)
+	for _, copyPart := range copyParts {
+		part := &s3.CompletedPart{PartNumber: aws.Int64(int64(len(parts) + 1))}
+		parts = append(parts, part)
+		source := fs.bucket + "/" + copyPart.key
+		copyRange := fmt.Sprintf("bytes=%d-%d", copyPart.off, copyPart.off+copyPart.size-1)
+
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			resp, err := fs.s3.UploadPartCopy(&s3.UploadPartCopyInput{
+				Bucket:          &fs.bucket,
+				Key:             &dst,
+				UploadId:        uploadID,
+				PartNumber:      part.PartNumber,
+				CopySource:      &source,
+				CopySourceRange: &copyRange,
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				log.Errorf("s3 upload part copy[%d] of [%s] failed: %v", *part.PartNumber, dst, err)
+				if copyErr == nil {
+					copyErr = err
+				}
+				return
+			}
+			part.ETag = resp.CopyPartResult.ETag
+		}()
+	}
+	wg.Wait()
+
+	if copyErr == nil {
+		_, copyErr = fs.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+			Bucket:          &fs.bucket,
+			Key:             &dst,
+			UploadId:        uploadID,
+			MultipartUpload: &s3.CompletedMultipartUpload{Parts: parts},
+		})
+		if copyErr != nil {
+			log.Errorf("s3 complete multipart upload[%s] failed: %v", dst, copyErr)
+		}
+	}
+	if copyErr != nil {
+		_, err := fs.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+			Bucket:   &fs.bucket,
+			Key:      &dst,
+			UploadId: uploadID,
+		})
+		if err != nil {
+			log.Errorf("s3 abort multipart upload[%s] failed: %v", dst, err)
+		}
+	}
+	return copyErr
+}
+
+var _ ServerSideCopier = &s3FileSystem{}
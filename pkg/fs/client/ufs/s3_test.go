@@ -81,3 +81,52 @@ func TestS3(t *testing.T) {
 	assert.Less(t, 0, len(entries))
 
 }
+
+func TestGetS3WriteOptions(t *testing.T) {
+	partSize, concurrency, err := getS3WriteOptions(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(S3DefaultPartSize), partSize)
+	assert.Equal(t, S3DefaultUploadConcurrency, concurrency)
+
+	properties := map[string]interface{}{
+		base.PartSize:          "32",
+		base.UploadConcurrency: "8",
+	}
+	partSize, concurrency, err = getS3WriteOptions(properties)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(32*1024*1024), partSize)
+	assert.Equal(t, 8, concurrency)
+
+	properties[base.PartSize] = "1"
+	_, _, err = getS3WriteOptions(properties)
+	assert.Error(t, err)
+
+	properties[base.PartSize] = "16"
+	properties[base.UploadConcurrency] = "abc"
+	_, _, err = getS3WriteOptions(properties)
+	assert.Error(t, err)
+}
+
+func TestS3CopyParts(t *testing.T) {
+	const mb = 1024 * 1024
+	// the range in the middle of dst, the head and tail of dst are copied back
+	parts, ok := s3CopyParts([]s3CopySegment{
+		{key: "dst", off: 0, size: 8 * mb},
+		{key: "src", off: 100, size: 300 * mb},
+		{key: "dst", off: 308 * mb, size: 1},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, []s3CopySegment{
+		{key: "dst", off: 0, size: 8 * mb},
+		{key: "src", off: 100, size: s3CopyPartSize},
+		{key: "src", off: 100 + s3CopyPartSize, size: 300*mb - s3CopyPartSize},
+		{key: "dst", off: 308 * mb, size: 1},
+	}, parts)
+
+	// the head of dst is smaller than the min part size
+	_, ok = s3CopyParts([]s3CopySegment{
+		{key: "dst", off: 0, size: 1},
+		{key: "src", off: 0, size: 8 * mb},
+	})
+	assert.False(t, ok)
+}
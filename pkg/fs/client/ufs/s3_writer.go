@@ -0,0 +1,282 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+const (
+	S3MinPartSize              = 5 * 1024 * 1024
+	S3MaxPartSize              = 5 * 1024 * 1024 * 1024
+	S3DefaultPartSize          = 16 * 1024 * 1024
+	S3DefaultUploadConcurrency = 4
+	// part size is doubled every s3PartSizeGrowStep parts, so that large files never exceed the 10000 parts limit
+	s3PartSizeGrowStep = 1000
+)
+
+// s3MultipartWriter streams sequential writes to s3 with multipart upload.
+// At most concurrency parts are uploaded at the same time, so the memory
+// used by one writer is bounded by (concurrency + 1) * partSize.
+type s3MultipartWriter struct {
+	fs       *s3FileSystem
+	key      string
+	partSize int64
+	offset   int64
+	buf      []byte
+	uploadID *string
+	parts    []*s3.CompletedPart
+	sem      chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	err      error
+	closed   bool
+}
+
+func newS3MultipartWriter(fs *s3FileSystem, key string) *s3MultipartWriter {
+	return &s3MultipartWriter{
+		fs:       fs,
+		key:      key,
+		partSize: fs.partSize,
+		sem:      make(chan struct{}, fs.uploadConcurrency),
+	}
+}
+
+// Offset returns the number of bytes accepted by the writer, the next sequential write must start here.
+func (w *s3MultipartWriter) Offset() int64 {
+	return w.offset
+}
+
+func (w *s3MultipartWriter) getErr() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+func (w *s3MultipartWriter) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+func (w *s3MultipartWriter) Write(data []byte) error {
+	if w.closed {
+		return fmt.Errorf("s3 writer of %s is closed", w.key)
+	}
+	if err := w.getErr(); err != nil {
+		return err
+	}
+	for len(data) > 0 {
+		n := int(w.partSize) - len(w.buf)
+		if n > len(data) {
+			n = len(data)
+		}
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.partSize)
+		}
+		w.buf = append(w.buf, data[:n]...)
+		data = data[n:]
+		w.offset += int64(n)
+		if int64(len(w.buf)) == w.partSize {
+			if err := w.uploadPart(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// uploadPart uploads the buffered data as the next part in background, it blocks
+// when there are already concurrency parts in flight.
+func (w *s3MultipartWriter) uploadPart() error {
+	if w.uploadID == nil {
+		resp, err := w.fs.s3.CreateMultipartUpload(&s3.CreateMultipartUploadInput{
+			Bucket: &w.fs.bucket,
+			Key:    &w.key,
+		})
+		if err != nil {
+			log.Errorf("s3 create multipart upload[%s] failed: %v", w.key, err)
+			w.setErr(err)
+			return err
+		}
+		w.uploadID = resp.UploadId
+	}
+
+	part := &s3.CompletedPart{PartNumber: aws.Int64(int64(len(w.parts) + 1))}
+	w.parts = append(w.parts, part)
+	data := w.buf
+	w.buf = nil
+
+	w.sem <- struct{}{}
+	w.wg.Add(1)
+	go func() {
+		defer func() {
+			<-w.sem
+			w.wg.Done()
+		}()
+		resp, err := w.fs.s3.UploadPart(&s3.UploadPartInput{
+			Bucket:        &w.fs.bucket,
+			Key:           &w.key,
+			UploadId:      w.uploadID,
+			PartNumber:    part.PartNumber,
+			Body:          bytes.NewReader(data),
+			ContentLength: aws.Int64(int64(len(data))),
+		})
+		if err != nil {
+			log.Errorf("s3 upload part[%d] of [%s] failed: %v", *part.PartNumber, w.key, err)
+			w.setErr(err)
+			return
+		}
+		w.mu.Lock()
+		part.ETag = resp.ETag
+		w.mu.Unlock()
+	}()
+
+	if len(w.parts)%s3PartSizeGrowStep == 0 && w.partSize*2 <= S3MaxPartSize {
+		w.partSize *= 2
+	}
+	return nil
+}
+
+// Close uploads the remaining data and completes the upload. Data smaller than
+// one part is uploaded with a single PutObject.
+func (w *s3MultipartWriter) Close() error {
+	if w.closed {
+		return w.getErr()
+	}
+	w.closed = true
+
+	if w.uploadID == nil {
+		if err := w.getErr(); err != nil {
+			return err
+		}
+		_, err := w.fs.s3.PutObject(&s3.PutObjectInput{
+			Bucket: &w.fs.bucket,
+			Key:    &w.key,
+			Body:   bytes.NewReader(w.buf),
+		})
+		w.buf = nil
+		if err != nil {
+			log.Errorf("s3 put object[%s] failed: %v", w.key, err)
+			w.setErr(err)
+		}
+		return err
+	}
+
+	if len(w.buf) > 0 && w.getErr() == nil {
+		w.uploadPart()
+	}
+	w.wg.Wait()
+	if err := w.getErr(); err != nil {
+		w.abort()
+		return err
+	}
+	_, err := w.fs.s3.CompleteMultipartUpload(&s3.CompleteMultipartUploadInput{
+		Bucket:          &w.fs.bucket,
+		Key:             &w.key,
+		UploadId:        w.uploadID,
+		MultipartUpload: &s3.CompletedMultipartUpload{Parts: w.parts},
+	})
+	if err != nil {
+		log.Errorf("s3 complete multipart upload[%s] failed: %v", w.key, err)
+		w.setErr(err)
+		w.abort()
+	}
+	return err
+}
+
+// Abort drops all the written data.
+func (w *s3MultipartWriter) Abort() {
+	w.closed = true
+	w.buf = nil
+	w.wg.Wait()
+	w.abort()
+}
+
+func (w *s3MultipartWriter) abort() {
+	if w.uploadID == nil {
+		return
+	}
+	_, err := w.fs.s3.AbortMultipartUpload(&s3.AbortMultipartUploadInput{
+		Bucket:   &w.fs.bucket,
+		Key:      &w.key,
+		UploadId: w.uploadID,
+	})
+	if err != nil {
+		log.Errorf("s3 abort multipart upload[%s] failed: %v", w.key, err)
+	}
+	w.uploadID = nil
+}
+
+// getIntProperty parses a positive integer property, properties come from fs meta as string.
+func getIntProperty(properties map[string]interface{}, key string, defaultValue int64) (int64, error) {
+	value, ok := properties[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	var result int64
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return defaultValue, nil
+		}
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s[%s]: %v", key, v, err)
+		}
+		result = n
+	case int:
+		result = int64(v)
+	case int64:
+		result = v
+	default:
+		return 0, fmt.Errorf("invalid %s[%v]", key, value)
+	}
+	if result <= 0 {
+		return 0, fmt.Errorf("%s[%d] must be positive", key, result)
+	}
+	return result, nil
+}
+
+// getS3WriteOptions returns the part size in bytes and the upload concurrency.
+func getS3WriteOptions(properties map[string]interface{}) (int64, int, error) {
+	partSizeMB, err := getIntProperty(properties, base.PartSize, S3DefaultPartSize/1024/1024)
+	if err != nil {
+		return 0, 0, err
+	}
+	partSize := partSizeMB * 1024 * 1024
+	if partSize < S3MinPartSize || partSize > S3MaxPartSize {
+		return 0, 0, fmt.Errorf("%s[%dMB] must be between %dMB and %dMB", base.PartSize, partSizeMB,
+			S3MinPartSize/1024/1024, S3MaxPartSize/1024/1024)
+	}
+	concurrency, err := getIntProperty(properties, base.UploadConcurrency, S3DefaultUploadConcurrency)
+	if err != nil {
+		return 0, 0, err
+	}
+	return partSize, int(concurrency), nil
+}
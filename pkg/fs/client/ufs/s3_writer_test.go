@@ -0,0 +1,185 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 serves the requests of the s3 client in memory instead of sending them
+type fakeS3 struct {
+	sync.Mutex
+	objects   map[string][]byte
+	uploads   map[string]map[int64][]byte
+	completed []*s3.CompletedPart
+	aborted   int
+	// failPart is the number of the part failed to upload
+	failPart int64
+}
+
+func newFakeS3FileSystem(t *testing.T, partSize int64) (*s3FileSystem, *fakeS3) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String("us-east-1"),
+		Endpoint:         aws.String("http://127.0.0.1:9000"),
+		Credentials:      credentials.NewStaticCredentials("ak", "sk", ""),
+		S3ForcePathStyle: aws.Bool(true),
+		MaxRetries:       aws.Int(0),
+	})
+	assert.NoError(t, err)
+	fake := &fakeS3{objects: make(map[string][]byte), uploads: make(map[string]map[int64][]byte)}
+	client := s3.New(sess)
+	client.Handlers.Send.Clear()
+	client.Handlers.Send.PushBack(fake.serve)
+	fs := &s3FileSystem{bucket: "bucket", s3: client, partSize: partSize, uploadConcurrency: 2}
+	return fs, fake
+}
+
+func (f *fakeS3) serve(r *request.Request) {
+	f.Lock()
+	defer f.Unlock()
+	// the output is filled here instead of unmarshaled from the response, the handlers of the
+	// operations, e.g. the one of CompleteMultipartUpload, are added to the request
+	r.Handlers.ValidateResponse.Clear()
+	r.Handlers.UnmarshalMeta.Clear()
+	r.Handlers.Unmarshal.Clear()
+	r.Handlers.UnmarshalError.Clear()
+	switch input := r.Params.(type) {
+	case *s3.PutObjectInput:
+		data, _ := ioutil.ReadAll(input.Body)
+		f.objects[*input.Key] = data
+	case *s3.CreateMultipartUploadInput:
+		uploadID := fmt.Sprintf("upload-%d", len(f.uploads)+1)
+		f.uploads[uploadID] = make(map[int64][]byte)
+		r.Data.(*s3.CreateMultipartUploadOutput).UploadId = aws.String(uploadID)
+	case *s3.UploadPartInput:
+		if *input.PartNumber == f.failPart {
+			r.Error = awserr.New("InvalidPart", "fake upload failure", nil)
+			return
+		}
+		data, _ := ioutil.ReadAll(input.Body)
+		f.uploads[*input.UploadId][*input.PartNumber] = data
+		r.Data.(*s3.UploadPartOutput).ETag = aws.String(fmt.Sprintf("etag-%d", *input.PartNumber))
+	case *s3.CompleteMultipartUploadInput:
+		parts := f.uploads[*input.UploadId]
+		var data []byte
+		for _, part := range input.MultipartUpload.Parts {
+			data = append(data, parts[*part.PartNumber]...)
+		}
+		f.objects[*input.Key] = data
+		f.completed = input.MultipartUpload.Parts
+		delete(f.uploads, *input.UploadId)
+	case *s3.AbortMultipartUploadInput:
+		f.aborted++
+		delete(f.uploads, *input.UploadId)
+	default:
+		r.Error = fmt.Errorf("operation[%s] not supported", r.Operation.Name)
+	}
+}
+
+// uploadedParts returns the numbers of the parts uploaded and not completed
+func (f *fakeS3) uploadedParts() []int64 {
+	f.Lock()
+	defer f.Unlock()
+	var numbers []int64
+	for _, parts := range f.uploads {
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Slice(numbers, func(i, j int) bool { return numbers[i] < numbers[j] })
+	return numbers
+}
+
+func TestS3MultipartWriterBuffer(t *testing.T) {
+	fs, fake := newFakeS3FileSystem(t, 4)
+	w := newS3MultipartWriter(fs, "small")
+	assert.NoError(t, w.Write([]byte("ab")))
+	assert.NoError(t, w.Write([]byte("c")))
+	// the data smaller than one part is buffered
+	assert.Equal(t, int64(3), w.Offset())
+	assert.Equal(t, []byte("abc"), w.buf)
+	assert.Nil(t, w.uploadID)
+	assert.Equal(t, 0, len(fake.uploads))
+
+	// and put with a single request
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []byte("abc"), fake.objects["small"])
+	assert.Equal(t, 0, len(fake.uploads))
+	assert.Error(t, w.Write([]byte("d")))
+}
+
+func TestS3MultipartWriterParts(t *testing.T) {
+	fs, fake := newFakeS3FileSystem(t, 4)
+	w := newS3MultipartWriter(fs, "large")
+	assert.NoError(t, w.Write([]byte("abcdef")))
+	assert.NoError(t, w.Write([]byte("ghij")))
+	// a new part is started when the part size is reached
+	w.wg.Wait()
+	assert.Equal(t, int64(10), w.Offset())
+	assert.Equal(t, 2, len(w.parts))
+	assert.Equal(t, []int64{1, 2}, fake.uploadedParts())
+	assert.Equal(t, []byte("ij"), w.buf)
+
+	// the remaining data is uploaded as the last part
+	assert.NoError(t, w.Close())
+	assert.Equal(t, []byte("abcdefghij"), fake.objects["large"])
+	assert.Equal(t, []*s3.CompletedPart{
+		{PartNumber: aws.Int64(1), ETag: aws.String("etag-1")},
+		{PartNumber: aws.Int64(2), ETag: aws.String("etag-2")},
+		{PartNumber: aws.Int64(3), ETag: aws.String("etag-3")},
+	}, fake.completed)
+	assert.Equal(t, 0, fake.aborted)
+}
+
+func TestS3MultipartWriterAbort(t *testing.T) {
+	// the upload is aborted when a part failed
+	fs, fake := newFakeS3FileSystem(t, 4)
+	fake.failPart = 2
+	w := newS3MultipartWriter(fs, "failed")
+	assert.NoError(t, w.Write(bytes.Repeat([]byte("a"), 8)))
+	w.wg.Wait()
+	assert.Error(t, w.Write([]byte("b")))
+	assert.Error(t, w.Close())
+	assert.Equal(t, 1, fake.aborted)
+	assert.Equal(t, 0, len(fake.uploads))
+	_, ok := fake.objects["failed"]
+	assert.False(t, ok)
+
+	// the upload is aborted when the writer is dropped
+	fs, fake = newFakeS3FileSystem(t, 4)
+	w = newS3MultipartWriter(fs, "dropped")
+	assert.NoError(t, w.Write(bytes.Repeat([]byte("a"), 6)))
+	w.Abort()
+	assert.Equal(t, 1, fake.aborted)
+	assert.Equal(t, 0, len(fake.uploads))
+	_, ok = fake.objects["dropped"]
+	assert.False(t, ok)
+	assert.Error(t, w.Write([]byte("b")))
+}
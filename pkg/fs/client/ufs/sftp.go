@@ -341,7 +341,7 @@ func (fh *sftpFileHandle) Fsync(flags int) (code fuse.Status) {
 	return fuse.ToStatus(fh.f.Sync())
 }
 
-// not support
+// the file locks are kept by vfs for all the ufs types, see vfs.SetLk
 func (fh *sftpFileHandle) GetLk(owner uint64, lk *fuse.FileLock, flags uint32, out *fuse.FileLock) (code fuse.Status) {
 	return fuse.ENOSYS
 }
@@ -354,10 +354,6 @@ func (fh *sftpFileHandle) SetLkw(owner uint64, lk *fuse.FileLock, flags uint32)
 	return fuse.ENOSYS
 }
 
-func (fh *sftpFileHandle) setLock(owner uint64, lk *fuse.FileLock, flags uint32, blocking bool) (code fuse.Status) {
-	return fuse.ENOSYS
-}
-
 func (fh *sftpFileHandle) Truncate(size uint64) fuse.Status {
 	return fuse.ToStatus(fh.fs.Truncate(fh.name, size))
 }
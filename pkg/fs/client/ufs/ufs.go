@@ -68,3 +68,15 @@ func (u *FileHandle) WriteAt(buf []byte, off int64) (int, error) {
 	}
 	return int(n), nil
 }
+
+func (u *FileHandle) Flush() error {
+	code := u.fd.Flush()
+	if utils.IsError(syscall.Errno(code)) {
+		return syscall.Errno(code)
+	}
+	return nil
+}
+
+func (u *FileHandle) Release() {
+	u.fd.Release()
+}
@@ -0,0 +1,305 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+const (
+	methodPropfind = "PROPFIND"
+	methodMkcol    = "MKCOL"
+	methodCopy     = "COPY"
+	methodMove     = "MOVE"
+
+	propfindBody = `<?xml version="1.0" encoding="utf-8"?>` +
+		`<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+)
+
+var errWriterAborted = errors.New("writer aborted")
+
+type davMultistatus struct {
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	ResourceType struct {
+		Collection *struct{} `xml:"DAV: collection"`
+	} `xml:"DAV: resourcetype"`
+	ContentLength string `xml:"DAV: getcontentlength"`
+	LastModified  string `xml:"DAV: getlastmodified"`
+}
+
+// webdavStorage keeps the directories as the collections, whose keys are suffixed with "/"
+type webdavStorage struct {
+	*httpEndpoint
+}
+
+func (s *webdavStorage) String() string {
+	return base.WebDAVType
+}
+
+// propfind returns key and its members if depth is "1"
+func (s *webdavStorage) propfind(key, depth string) ([]objectInfo, error) {
+	resp, err := s.do(methodPropfind, key, strings.NewReader(propfindBody), map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, httpError(methodPropfind, key, resp.StatusCode)
+	}
+	var ms davMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		log.Errorf("decode propfind response of [%s] failed: %v", key, err)
+		return nil, err
+	}
+
+	infos := make([]objectInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, ok := s.keyOfURL(key, r.Href)
+		if !ok {
+			continue
+		}
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			info := objectInfo{key: href, mtime: time.Now()}
+			if ps.Prop.ResourceType.Collection != nil {
+				info.isPrefix = true
+				if info.key != "" && !strings.HasSuffix(info.key, Delimiter) {
+					info.key += Delimiter
+				}
+			} else {
+				info.size, _ = strconv.ParseInt(ps.Prop.ContentLength, 10, 64)
+			}
+			if mtime, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				info.mtime = mtime
+			}
+			infos = append(infos, info)
+			break
+		}
+	}
+	return infos, nil
+}
+
+func (s *webdavStorage) head(key string) (objectInfo, error) {
+	infos, err := s.propfind(key, "0")
+	if err != nil {
+		return objectInfo{}, err
+	}
+	// a file key of a collection or the other way around does not exist
+	if len(infos) == 0 || infos[0].isPrefix != (key == "" || strings.HasSuffix(key, Delimiter)) {
+		return objectInfo{}, syscall.ENOENT
+	}
+	return infos[0], nil
+}
+
+// list walks the collections by depth 1 propfind, as depth infinity is disabled by most servers.
+// The collection of prefix is listed as the directory objects of object storages.
+func (s *webdavStorage) list(prefix string, recursive bool, limit int) ([]objectInfo, error) {
+	var result []objectInfo
+	dirs := []string{prefix}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		infos, err := s.propfind(dir, "1")
+		if err == syscall.ENOENT {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			if !strings.HasPrefix(info.key, dir) {
+				// dir is a file
+				continue
+			}
+			if info.key == dir {
+				if dir != prefix {
+					// listed by the parent already
+					continue
+				}
+			} else if info.isPrefix && recursive {
+				dirs = append(dirs, info.key)
+			}
+			result = append(result, info)
+			if limit > 0 && len(result) >= limit {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *webdavStorage) get(key string, off, size int64) (io.ReadCloser, error) {
+	return s.httpEndpoint.get(key, off, size)
+}
+
+func (s *webdavStorage) put(key string, data io.Reader) error {
+	if strings.HasSuffix(key, Delimiter) {
+		return s.mkcolAll(key)
+	}
+	return s.doAndClose(http.MethodPut, key, data, nil, http.StatusOK, http.StatusCreated, http.StatusNoContent)
+}
+
+func (s *webdavStorage) mkcol(key string) (int, error) {
+	resp, err := s.do(methodMkcol, key, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// mkcolAll creates the collection key and its parents, it is ok if key exists already
+func (s *webdavStorage) mkcolAll(key string) error {
+	status, err := s.mkcol(key)
+	if err == nil && status == http.StatusConflict {
+		// the parent does not exist
+		if parent := path.Dir(strings.TrimSuffix(key, Delimiter)); parent != "." {
+			if err := s.mkcolAll(parent + Delimiter); err != nil {
+				return err
+			}
+			status, err = s.mkcol(key)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if status != http.StatusCreated && status != http.StatusMethodNotAllowed {
+		return httpError(methodMkcol, key, status)
+	}
+	return nil
+}
+
+func (s *webdavStorage) newWriter(key string) objectWriter {
+	pr, pw := io.Pipe()
+	w := &webdavWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := s.put(key, pr)
+		// unblock the writes if the put fails early
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+func (s *webdavStorage) copy(src, dst string) error {
+	return s.doAndClose(methodCopy, src, nil, map[string]string{
+		"Destination": s.objectURL(dst),
+		"Overwrite":   "T",
+	}, http.StatusCreated, http.StatusNoContent)
+}
+
+// move moves the file or collection src to dst
+func (s *webdavStorage) move(src, dst string) error {
+	return s.doAndClose(methodMove, src, nil, map[string]string{
+		"Destination": s.objectURL(dst),
+		"Overwrite":   "T",
+	}, http.StatusCreated, http.StatusNoContent)
+}
+
+func (s *webdavStorage) delete(key string) error {
+	return s.doAndClose(http.MethodDelete, key, nil, nil, http.StatusOK, http.StatusNoContent)
+}
+
+// webdavWriter streams the data to the body of put
+type webdavWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *webdavWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+func (w *webdavWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+func (w *webdavWriter) Abort() {
+	w.pw.CloseWithError(errWriterAborted)
+	<-w.done
+}
+
+// webdavFileSystem supports renaming directories, which object storages don't, by MOVE
+type webdavFileSystem struct {
+	*objectFileSystem
+	storage *webdavStorage
+}
+
+var _ UnderFileStorage = &webdavFileSystem{}
+var _ DirAttrReader = &webdavFileSystem{}
+
+func (fs *webdavFileSystem) Rename(oldName string, newName string) error {
+	finfo, err := fs.GetAttr(oldName)
+	if err != nil {
+		return err
+	}
+	oldPath, newPath := fs.getFullPath(oldName), fs.getFullPath(newName)
+	if finfo.IsDir {
+		oldPath += Delimiter
+		newPath += Delimiter
+	}
+	return fs.storage.move(oldPath, newPath)
+}
+
+// NewWebDAVFileSystem uses the collection scheme://address/subpath as the root of fs
+func NewWebDAVFileSystem(properties map[string]interface{}) (UnderFileStorage, error) {
+	endpoint, err := newHTTPEndpoint(properties)
+	if err != nil {
+		return nil, err
+	}
+	s := &webdavStorage{httpEndpoint: endpoint}
+	fs, err := newObjectFileSystem(s, getStringProperty(properties, base.SubPath), properties)
+	if err != nil {
+		return nil, err
+	}
+	return &webdavFileSystem{objectFileSystem: fs, storage: s}, nil
+}
+
+func init() {
+	RegisterUFS(base.WebDAVType, NewWebDAVFileSystem)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ufs
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/webdav"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestWebDAV(t *testing.T) {
+	server := httptest.NewServer(&webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer server.Close()
+	HTTPAllowPrivateAddress = true
+	defer func() { HTTPAllowPrivateAddress = false }()
+
+	properties := map[string]interface{}{
+		base.Address: strings.TrimPrefix(server.URL, "http://"),
+		base.Scheme:  "http",
+		base.SubPath: "/ufs/test",
+	}
+	fs, err := NewUFS(base.WebDAVType, properties)
+	assert.NoError(t, err)
+	finfo, err := fs.GetAttr("")
+	assert.NoError(t, err)
+	assert.True(t, finfo.IsDir)
+
+	assert.NoError(t, fs.Mkdir("dir", 0755))
+	assert.Equal(t, syscall.EEXIST, fs.Mkdir("dir", 0755))
+
+	fh, err := fs.Create("dir/hello", uint32(os.O_WRONLY|os.O_CREATE), 0644)
+	assert.NoError(t, err)
+	_, code := fh.Write([]byte("hello "), 0)
+	assert.Equal(t, fuse.OK, code)
+	_, code = fh.Write([]byte("world"), 6)
+	assert.Equal(t, fuse.OK, code)
+	assert.Equal(t, fuse.OK, fh.Flush())
+	fh.Release()
+	assert.Equal(t, "hello world", readAll(t, fs, "dir/hello"))
+
+	// a file key of a directory does not exist and vice versa
+	_, err = fs.GetAttr("dir/hello/")
+	assert.Equal(t, syscall.ENOENT, err)
+	finfo, err = fs.GetAttr("dir")
+	assert.NoError(t, err)
+	assert.True(t, finfo.IsDir)
+
+	fh, err = fs.Open("dir/hello", uint32(os.O_WRONLY))
+	assert.NoError(t, err)
+	_, code = fh.Write([]byte("W"), 6)
+	assert.Equal(t, fuse.OK, code)
+	fh.Release()
+	assert.Equal(t, "hello World", readAll(t, fs, "dir/hello"))
+
+	infos, err := fs.(DirAttrReader).ReadDirPlus("dir")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(infos))
+	assert.Equal(t, "dir/hello", infos[0].Name)
+	assert.Equal(t, int64(11), infos[0].Size)
+
+	// directories are renamed by MOVE
+	assert.NoError(t, fs.Rename("dir", "dir2"))
+	_, err = fs.GetAttr("dir")
+	assert.Equal(t, syscall.ENOENT, err)
+	assert.Equal(t, "hello World", readAll(t, fs, "dir2/hello"))
+	assert.NoError(t, fs.Rename("dir2/hello", "dir2/world"))
+
+	assert.Equal(t, syscall.ENOTEMPTY, fs.Rmdir("dir2"))
+	assert.NoError(t, fs.Unlink("dir2/world"))
+	assert.NoError(t, fs.Rmdir("dir2"))
+	entries, err := fs.ReadDir("")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+}
@@ -0,0 +1,81 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	pathlib "path"
+	"syscall"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/meta"
+)
+
+// The access of the user to the fs shared by others is enforced by the mount, the paths not shared
+// are hidden and the paths shared read-only can not be modified. The access is nil for the owner.
+
+func (v *VFS) entryPath(parent Ino, name string) string {
+	return pathlib.Join(v.Meta.InoToPath(parent), name)
+}
+
+// readable reports whether the entry can be looked up.
+func (v *VFS) readable(parent Ino, name string) bool {
+	if v.access == nil {
+		return true
+	}
+	return v.access.CanRead(v.entryPath(parent, name))
+}
+
+// checkWrite returns EROFS if the path is shared read-only, EACCES if the path is not shared.
+func (v *VFS) checkWrite(path string) syscall.Errno {
+	switch v.access.Permission(path) {
+	case base.AccessReadWrite:
+		return syscall.F_OK
+	case base.AccessReadOnly:
+		return syscall.EROFS
+	}
+	return syscall.EACCES
+}
+
+// checkWriteIno checks the file or dir of ino can be modified.
+func (v *VFS) checkWriteIno(ino Ino) syscall.Errno {
+	if v.access == nil {
+		return syscall.F_OK
+	}
+	return v.checkWrite(v.Meta.InoToPath(ino))
+}
+
+// checkWriteEntry checks the entry can be created or removed in the dir of parent.
+func (v *VFS) checkWriteEntry(parent Ino, name string) syscall.Errno {
+	if v.access == nil {
+		return syscall.F_OK
+	}
+	return v.checkWrite(v.entryPath(parent, name))
+}
+
+// filterReadable drops the entries of dir which can not be looked up.
+func (v *VFS) filterReadable(parent Ino, entries []*meta.Entry) []*meta.Entry {
+	if v.access == nil {
+		return entries
+	}
+	readable := entries[:0]
+	for _, e := range entries {
+		if e.Name == "." || e.Name == ".." || v.readable(parent, e.Name) {
+			readable = append(readable, e)
+		}
+	}
+	return readable
+}
@@ -0,0 +1,39 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestCheckWrite(t *testing.T) {
+	v := &VFS{access: base.FsAccess{"/datasets": base.AccessReadOnly, "/datasets/output": base.AccessReadWrite}}
+	assert.Equal(t, syscall.EROFS, v.checkWrite("/datasets/imagenet/a.jpg"))
+	assert.Equal(t, syscall.Errno(0), v.checkWrite("/datasets/output/model"))
+	assert.Equal(t, syscall.EACCES, v.checkWrite("/home"))
+
+	// the owner has the full access
+	owner := &VFS{}
+	assert.Equal(t, syscall.Errno(0), owner.checkWriteIno(1))
+	assert.Equal(t, syscall.Errno(0), owner.checkWriteEntry(1, "a"))
+	assert.True(t, owner.readable(1, "a"))
+}
@@ -0,0 +1,133 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"time"
+
+	"github.com/bluele/gcache"
+)
+
+// 假设一个entry加attr占用500字节，102400个约50MB
+const defaultEntryCacheSize = 102400
+
+type entryKey struct {
+	parent Ino
+	name   string
+}
+
+// entryCache caches the dir entries and attributes in vfs, which are filled by lookups and
+// readdirplus, so that walking a dir costs a single listing of the ufs. A cached inode of 0
+// is a negative entry, i.e. the lookup returned ENOENT. The entries are invalidated on local
+// mutations, the changes by other clients are seen after expire.
+type entryCache struct {
+	entries        gcache.Cache
+	attrs          gcache.Cache
+	expire         time.Duration
+	negativeExpire time.Duration
+}
+
+// newEntryCache returns nil if expire is not positive, the methods of nil cache are no-ops.
+func newEntryCache(expire, negativeExpire time.Duration) *entryCache {
+	if expire <= 0 {
+		return nil
+	}
+	return &entryCache{
+		entries:        gcache.New(defaultEntryCacheSize).LRU().Build(),
+		attrs:          gcache.New(defaultEntryCacheSize).LRU().Build(),
+		expire:         expire,
+		negativeExpire: negativeExpire,
+	}
+}
+
+// lookup returns the cached entry, ino is 0 if the entry is negative.
+func (c *entryCache) lookup(parent Ino, name string) (ino Ino, attr *Attr, ok bool) {
+	if c == nil {
+		return 0, nil, false
+	}
+	value, err := c.entries.Get(entryKey{parent: parent, name: name})
+	if err != nil {
+		return 0, nil, false
+	}
+	ino = value.(Ino)
+	if ino == 0 {
+		return 0, nil, true
+	}
+	if attr, ok = c.getAttr(ino); !ok {
+		return 0, nil, false
+	}
+	return ino, attr, true
+}
+
+func (c *entryCache) getAttr(ino Ino) (*Attr, bool) {
+	if c == nil {
+		return nil, false
+	}
+	value, err := c.attrs.Get(ino)
+	if err != nil {
+		return nil, false
+	}
+	attr := value.(Attr)
+	return &attr, true
+}
+
+func (c *entryCache) setEntry(parent Ino, name string, ino Ino, attr *Attr) {
+	if c == nil || ino == 0 {
+		return
+	}
+	_ = c.entries.SetWithExpire(entryKey{parent: parent, name: name}, ino, c.expire)
+	c.setAttr(ino, attr)
+}
+
+func (c *entryCache) setNegative(parent Ino, name string) {
+	if c == nil || c.negativeExpire <= 0 {
+		return
+	}
+	_ = c.entries.SetWithExpire(entryKey{parent: parent, name: name}, Ino(0), c.negativeExpire)
+}
+
+func (c *entryCache) setAttr(ino Ino, attr *Attr) {
+	if c == nil || attr == nil {
+		return
+	}
+	_ = c.attrs.SetWithExpire(ino, *attr, c.expire)
+}
+
+func (c *entryCache) invalidateAttr(ino Ino) {
+	if c == nil {
+		return
+	}
+	c.attrs.Remove(ino)
+}
+
+// invalidateEntry drops the entry and the attr of its inode.
+func (c *entryCache) invalidateEntry(parent Ino, name string) {
+	if c == nil {
+		return
+	}
+	key := entryKey{parent: parent, name: name}
+	if value, err := c.entries.Get(key); err == nil {
+		c.invalidateAttr(value.(Ino))
+	}
+	c.entries.Remove(key)
+}
+
+// removeEntry is called after the entry is removed locally, it's cached as negative.
+func (c *entryCache) removeEntry(parent Ino, name string) {
+	c.invalidateEntry(parent, name)
+	c.setNegative(parent, name)
+}
@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryCache(t *testing.T) {
+	var disabled *entryCache
+	assert.Nil(t, newEntryCache(0, time.Second))
+	disabled.setEntry(1, "a", 2, &Attr{Size: 1})
+	_, _, ok := disabled.lookup(1, "a")
+	assert.False(t, ok)
+
+	c := newEntryCache(time.Minute, time.Minute)
+	c.setEntry(1, "a", 2, &Attr{Size: 1})
+	ino, attr, ok := c.lookup(1, "a")
+	assert.True(t, ok)
+	assert.Equal(t, Ino(2), ino)
+	assert.Equal(t, uint64(1), attr.Size)
+
+	// the returned attr is a copy
+	attr.Size = 2
+	attr, _ = c.getAttr(2)
+	assert.Equal(t, uint64(1), attr.Size)
+
+	// entry without attr is a miss
+	c.invalidateAttr(2)
+	_, _, ok = c.lookup(1, "a")
+	assert.False(t, ok)
+
+	c.setEntry(1, "a", 2, &Attr{Size: 1})
+	c.removeEntry(1, "a")
+	ino, _, ok = c.lookup(1, "a")
+	assert.True(t, ok)
+	assert.Equal(t, Ino(0), ino)
+	_, ok = c.getAttr(2)
+	assert.False(t, ok)
+
+	c.setEntry(1, "a", 3, &Attr{Size: 3})
+	ino, _, _ = c.lookup(1, "a")
+	assert.Equal(t, Ino(3), ino)
+	c.invalidateEntry(1, "a")
+	_, _, ok = c.lookup(1, "a")
+	assert.False(t, ok)
+}
+
+func TestEntryCacheNegativeExpire(t *testing.T) {
+	c := newEntryCache(time.Minute, 0)
+	c.setNegative(1, "a")
+	_, _, ok := c.lookup(1, "a")
+	assert.False(t, ok)
+
+	c = newEntryCache(time.Minute, 10*time.Millisecond)
+	c.setNegative(1, "a")
+	_, _, ok = c.lookup(1, "a")
+	assert.True(t, ok)
+	time.Sleep(20 * time.Millisecond)
+	_, _, ok = c.lookup(1, "a")
+	assert.False(t, ok)
+}
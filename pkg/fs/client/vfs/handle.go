@@ -31,8 +31,20 @@ type handle struct {
 	reader   FileReader
 	writer   FileWriter
 	children []*meta.Entry
+	// locks are the kinds of locks ever put by the handle, they are released on close
+	locks      uint8
+	flockOwner uint64
+	// quota is true if the writes of the handle are limited by the quota of fs, length is
+	// the size of file known by the handle to count the bytes appended
+	quota  bool
+	length uint64
 }
 
+const (
+	lockFlock = 1 << iota
+	lockPosix
+)
+
 func (v *VFS) newHandle(inode Ino) *handle {
 	v.handleLock.Lock()
 	defer v.handleLock.Unlock()
@@ -95,18 +107,24 @@ func (v *VFS) releaseFileHandle(ino Ino, fh uint64) {
 //  O_RDONLY<00>：只读打开
 //  O_WRONLY<01>：只写打开
 //  O_RDWR<02>：读写打开
-func (v *VFS) newFileHandle(inode Ino, length uint64, flags uint32, ufs ufslib.UnderFileStorage, path string) (uint64, error) {
+func (v *VFS) newFileHandle(inode Ino, attr *Attr, flags uint32, ufs ufslib.UnderFileStorage, path string) (uint64, error) {
+	length := attr.Size
 	h := v.newHandle(inode)
 	var err error
 	h.Lock()
 	defer h.Unlock()
+	if v.quota != nil && flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		_, isLink, _, _ := v.getUFS(v.Meta.InoToPath(inode))
+		h.quota = !isLink
+		h.length = length
+	}
 	switch flags & syscall.O_ACCMODE {
 	case syscall.O_RDONLY:
-		h.reader, err = v.reader.Open(inode, length, ufs, path)
+		h.reader, err = v.reader.Open(inode, attr, ufs, path)
 	case syscall.O_WRONLY:
 		h.writer, err = v.writer.Open(inode, length, ufs, path)
 	case syscall.O_RDWR:
-		h.reader, err = v.reader.Open(inode, length, ufs, path)
+		h.reader, err = v.reader.Open(inode, attr, ufs, path)
 		h.writer, err = v.writer.Open(inode, length, ufs, path)
 	}
 	return h.fh, err
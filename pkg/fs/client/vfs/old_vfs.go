@@ -17,7 +17,6 @@ limitations under the License.
 package vfs
 
 import (
-	"encoding/json"
 	"os"
 	pathlib "path"
 	"sync"
@@ -26,7 +25,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	apicommon "paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/client/meta"
 	ufslib "paddleflow/pkg/fs/client/ufs"
@@ -40,7 +38,6 @@ type OldVFS struct {
 	links      []base.FSMeta
 	ufsMap     *ufsMap
 	ufsMapLock sync.RWMutex
-	ufsMapUT   int64
 	reader     DataReader
 	writer     DataWriter
 	handleMap  map[Ino][]*handle
@@ -74,7 +71,7 @@ func InitOldVFS(fsMeta base.FSMeta, links map[string]base.FSMeta, global bool) (
 	vfs.handleMap = make(map[Ino][]*handle)
 	vfs.nextfh = 1
 	inodeHandle := meta.NewInodeHandle()
-	vfs.meta, err = meta.NewDefaultMeta(fsMeta, links, inodeHandle)
+	vfs.meta, err = meta.NewDefaultMeta(fsMeta, links, inodeHandle, nil)
 	if err != nil {
 		log.Errorf("NewDefaultMeta failed: %v", err)
 		return nil, err
@@ -85,67 +82,8 @@ func InitOldVFS(fsMeta base.FSMeta, links map[string]base.FSMeta, global bool) (
 	return vfs, nil
 }
 
-func (v *OldVFS) LinksMetaUpdateHandler(stopChan chan struct{}, interval int, linkMetaDirPrefix string) error {
-	for {
-		v.linksMetaUpdate(linkMetaDirPrefix)
-		select {
-		case <-stopChan:
-			log.Errorf("links meta update handler stopped")
-			return nil
-		default:
-			time.Sleep(time.Duration(interval) * time.Second)
-		}
-	}
-}
-
-func (v *OldVFS) linksMetaUpdate(linkMetaDirPrefix string) error {
-	filePath := pathlib.Join(linkMetaDirPrefix, base.LinkMetaDir, base.LinkMetaFile)
-	info, err := v.defaultUfs.GetAttr(filePath)
-	if err != nil {
-		log.Errorf("GetAttr file[%s] failed: %v", filePath, err)
-		return err
-	}
-	attr := &Attr{}
-	attr.FromFileInfo(info)
-	if attr.Mtime <= v.ufsMapUT {
-		return nil
-	}
-
-	flags := uint32(syscall.O_RDONLY)
-	fileHandle, err := v.defaultUfs.Open(filePath, flags)
-	if err != nil {
-		log.Errorf("open file[%s] failed: %v", filePath, err)
-		return err
-	}
-	buf := make([]byte, attr.Size)
-	r, status := fileHandle.Read(buf, 0)
-	if utils.IsError(syscall.Errno(status)) {
-		log.Errorf("fileHandle Read err[%v]", syscall.Errno(status))
-		return syscall.Errno(status)
-	}
-	content, status := r.Bytes(buf)
-	if utils.IsError(syscall.Errno(status)) {
-		log.Errorf("r.Bytes err[%v]", syscall.Errno(status))
-		return syscall.Errno(status)
-	}
-
-	decodedLinksMeta, err := apicommon.AesDecrypt(string(content), apicommon.AESEncryptKey)
-	if err != nil {
-		log.Errorf("aes decrypt links meta json string err[%v]", err)
-		return err
-	}
-
-	var result map[string]base.FSMeta
-	if err := json.Unmarshal([]byte(decodedLinksMeta), &result); err != nil {
-		log.Errorf("json unmarshal links meta err[%v]", err)
-		return err
-	}
-
-	if err := v.UpdateUFSMap(result); err != nil {
-		log.Errorf("update ufs map err[%v]", err)
-		return err
-	}
-	v.ufsMapUT = attr.Mtime
+func (v *OldVFS) LinksMetaUpdateHandler(stopChan chan struct{}, interval int, watcher meta.LinkWatcher) error {
+	meta.WatchLinks(stopChan, interval, watcher, v.UpdateUFSMap)
 	return nil
 }
 
@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/cache"
+	ufslib "paddleflow/pkg/fs/client/ufs"
+)
+
+// prefetcher loads the blocks ahead of a sequential reader into the cache store concurrently.
+// Every worker opens its own ufs handle, because ReadAt of some ufs handles (e.g. hdfs) is not
+// safe for concurrent use.
+type prefetcher struct {
+	name        string
+	path        string
+	version     cache.FileVersion
+	ufs         ufslib.UnderFileStorage
+	store       cache.Store
+	concurrency int
+	blocks      chan int
+	once        sync.Once
+	closed      bool
+	sync.Mutex
+}
+
+func newPrefetcher(name, path string, version cache.FileVersion, ufs ufslib.UnderFileStorage, store cache.Store,
+	window, concurrency int) *prefetcher {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &prefetcher{
+		name:        name,
+		path:        path,
+		version:     version,
+		ufs:         ufs,
+		store:       store,
+		concurrency: concurrency,
+		blocks:      make(chan int, window),
+	}
+}
+
+// submit queues the block for prefetching, it returns false if the queue is full or closed.
+func (p *prefetcher) submit(index int) bool {
+	p.Lock()
+	defer p.Unlock()
+	if p.closed {
+		return false
+	}
+	p.once.Do(func() {
+		for i := 0; i < p.concurrency; i++ {
+			go p.worker()
+		}
+	})
+	select {
+	case p.blocks <- index:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *prefetcher) isClosed() bool {
+	p.Lock()
+	defer p.Unlock()
+	return p.closed
+}
+
+func (p *prefetcher) worker() {
+	var fd base.FileHandle
+	defer func() {
+		if fd != nil {
+			fd.Release()
+		}
+	}()
+	for index := range p.blocks {
+		// the queued blocks are useless after the file is closed
+		if p.isClosed() {
+			continue
+		}
+		if fd == nil {
+			var err error
+			fd, err = p.ufs.Open(p.path, syscall.O_RDONLY)
+			if err != nil {
+				log.Errorf("prefetch open file[%s] failed: %v", p.path, err)
+				fd = nil
+				continue
+			}
+		}
+		reader := p.store.NewReader(p.name, p.version, ufslib.NewFileHandle(fd))
+		if err := reader.Prefetch(index); err != nil {
+			log.Errorf("prefetch block[%d] of file[%s] failed: %v", index, p.path, err)
+		}
+	}
+}
+
+func (p *prefetcher) close() {
+	p.Lock()
+	defer p.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.blocks)
+	}
+}
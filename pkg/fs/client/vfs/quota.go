@@ -0,0 +1,140 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// QuotaRefreshInterval is the interval to refresh the usage of fs scanned by pfs server
+var QuotaRefreshInterval = time.Minute
+
+const quotaBlockSize = 4096
+
+// UsageFunc returns the bytes used by fs and the time they are scanned
+type UsageFunc func() (int64, string, error)
+
+// quota enforces the hard quota of fs on the writes of the mount. The usage is the usage
+// scanned by server, plus the bytes appended by the mount since the scan.
+type quota struct {
+	sync.Mutex
+	limit   int64
+	scanned int64
+	// appended is counted per mount, the bytes appended by the other mounts of the fs are not known
+	// until the next scan, so the fs may exceed the quota by them within a scan interval
+	appended int64
+	scanTime string
+	getUsage UsageFunc
+	done     chan struct{}
+}
+
+func newQuota(limit int64, getUsage UsageFunc) *quota {
+	q := &quota{
+		limit:    limit,
+		getUsage: getUsage,
+		done:     make(chan struct{}),
+	}
+	if getUsage != nil {
+		q.refresh()
+		go q.refreshLoop()
+	}
+	return q
+}
+
+// serverUsage gets the usage of fs from pfs server
+func serverUsage() (int64, string, error) {
+	usage, err := base.Client.GetUsage()
+	if err != nil {
+		return 0, "", err
+	}
+	return usage.Bytes, usage.ScanTime, nil
+}
+
+func (q *quota) refreshLoop() {
+	ticker := time.NewTicker(QuotaRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-ticker.C:
+			q.refresh()
+		}
+	}
+}
+
+func (q *quota) refresh() {
+	used, scanTime, err := q.getUsage()
+	if err != nil {
+		log.Errorf("refresh usage of quota failed: %v", err)
+		return
+	}
+	q.Lock()
+	defer q.Unlock()
+	if scanTime != q.scanTime {
+		// the bytes appended before are counted by the new scan
+		q.appended = 0
+		q.scanTime = scanTime
+	}
+	q.scanned = used
+}
+
+func (q *quota) used() int64 {
+	return q.scanned + q.appended
+}
+
+// reserve counts the bytes appended, EDQUOT if the quota is exceeded
+func (q *quota) reserve(size int64) syscall.Errno {
+	q.Lock()
+	defer q.Unlock()
+	if q.used()+size > q.limit {
+		return syscall.EDQUOT
+	}
+	q.appended += size
+	return syscall.F_OK
+}
+
+// statFs reports the quota as the capacity of fs
+func (q *quota) statFs(st *base.StatfsOut) {
+	q.Lock()
+	defer q.Unlock()
+	if st.Bsize == 0 {
+		st.Bsize = quotaBlockSize
+	}
+	bsize := int64(st.Bsize)
+	var avail uint64
+	if left := (q.limit - q.used()) / bsize; left > 0 {
+		avail = uint64(left)
+	}
+	// the ufs may have less space than the quota left
+	if st.Blocks > 0 && st.Bavail < avail {
+		avail = st.Bavail
+	}
+	st.Blocks = uint64(q.limit / bsize)
+	st.Bavail = avail
+	st.Bfree = avail
+}
+
+func (q *quota) close() {
+	close(q.done)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestQuota(t *testing.T) {
+	scanned, scanTime := int64(6000), "2022-01-01 00:00:00"
+	getUsage := func() (int64, string, error) {
+		return scanned, scanTime, nil
+	}
+	q := newQuota(10000, getUsage)
+	defer q.close()
+	assert.Equal(t, int64(6000), q.used())
+
+	assert.Equal(t, syscall.Errno(0), q.reserve(3000))
+	assert.Equal(t, syscall.EDQUOT, q.reserve(2000))
+	assert.Equal(t, int64(9000), q.used())
+
+	// the bytes appended are kept until a new scan
+	scanned = 6500
+	q.refresh()
+	assert.Equal(t, int64(9500), q.used())
+	scanned, scanTime = 9000, "2022-01-01 00:10:00"
+	q.refresh()
+	assert.Equal(t, int64(9000), q.used())
+
+	st := &base.StatfsOut{Bsize: 1000, Blocks: 1 << 30, Bavail: 1 << 30}
+	q.statFs(st)
+	assert.Equal(t, uint64(10), st.Blocks)
+	assert.Equal(t, uint64(1), st.Bavail)
+	assert.Equal(t, uint64(1), st.Bfree)
+
+	// the ufs is fuller than the quota
+	st = &base.StatfsOut{Bsize: 1000, Blocks: 100, Bavail: 0}
+	q.statFs(st)
+	assert.Equal(t, uint64(10), st.Blocks)
+	assert.Equal(t, uint64(0), st.Bavail)
+
+	st = &base.StatfsOut{}
+	q.statFs(st)
+	assert.Equal(t, uint32(quotaBlockSize), st.Bsize)
+	assert.Equal(t, uint64(10000/quotaBlockSize), st.Blocks)
+}
+
+func TestReserveQuota(t *testing.T) {
+	v := &VFS{quota: newQuota(100, nil)}
+	defer v.quota.close()
+	h := &handle{quota: true, length: 10}
+	// overwrite is not counted
+	assert.Equal(t, syscall.Errno(0), v.reserveQuota(h, 10))
+	assert.Equal(t, int64(0), v.quota.used())
+	assert.Equal(t, syscall.Errno(0), v.reserveQuota(h, 60))
+	assert.Equal(t, int64(50), v.quota.used())
+	assert.Equal(t, syscall.EDQUOT, v.reserveQuota(h, 111))
+	assert.Equal(t, uint64(60), h.length)
+
+	// the writes to links are not limited
+	link := &handle{}
+	assert.Equal(t, syscall.Errno(0), v.reserveQuota(link, 1000))
+	assert.Equal(t, int64(50), v.quota.used())
+}
+
+type bytesReader []byte
+
+func (r bytesReader) Read(buf []byte, off uint64) (int, syscall.Errno) {
+	if off >= uint64(len(r)) {
+		return 0, syscall.F_OK
+	}
+	return copy(buf, r[off:]), syscall.F_OK
+}
+
+func (r bytesReader) Close() {}
+
+type bytesWriter struct {
+	FileWriter
+	data []byte
+}
+
+func (w *bytesWriter) Write(data []byte, off uint64) syscall.Errno {
+	if end := int(off) + len(data); end > len(w.data) {
+		w.data = append(w.data, make([]byte, end-len(w.data))...)
+	}
+	copy(w.data[off:], data)
+	return syscall.F_OK
+}
+
+func TestCopyDataQuota(t *testing.T) {
+	v := &VFS{quota: newQuota(100, nil)}
+	defer v.quota.close()
+	hIn := &handle{reader: bytesReader(make([]byte, 80))}
+	writer := &bytesWriter{}
+	hOut := &handle{writer: writer, quota: true}
+
+	copied, err := v.copyData(hIn, hOut, 0, 0, 60)
+	assert.Equal(t, syscall.Errno(0), err)
+	assert.Equal(t, uint64(60), copied)
+	assert.Equal(t, int64(60), v.quota.used())
+
+	// the copy beyond the quota fails before writing
+	copied, err = v.copyData(hIn, hOut, 0, 60, 80)
+	assert.Equal(t, syscall.EDQUOT, err)
+	assert.Equal(t, uint64(0), copied)
+	assert.Equal(t, 60, len(writer.data))
+	assert.Equal(t, int64(60), v.quota.used())
+}
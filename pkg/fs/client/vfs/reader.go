@@ -19,6 +19,7 @@ package vfs
 import (
 	"sync"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -26,6 +27,7 @@ import (
 	"paddleflow/pkg/fs/client/cache"
 	"paddleflow/pkg/fs/client/meta"
 	ufslib "paddleflow/pkg/fs/client/ufs"
+	"paddleflow/pkg/fs/client/utils"
 )
 
 type FileReader interface {
@@ -34,15 +36,17 @@ type FileReader interface {
 }
 
 type DataReader interface {
-	Open(inode Ino, length uint64, ufs ufslib.UnderFileStorage, path string) (FileReader, error)
+	Open(inode Ino, attr *Attr, ufs ufslib.UnderFileStorage, path string) (FileReader, error)
 }
 
-func NewDataReader(m meta.Meta, blockSize int, store cache.Store) DataReader {
+func NewDataReader(m meta.Meta, blockSize int, store cache.Store, readAhead, readAheadConcurrency int) DataReader {
 	r := &dataReader{
-		m:         m,
-		files:     make(map[Ino]*fileReader),
-		store:     store,
-		blockSize: blockSize,
+		m:                    m,
+		files:                make(map[Ino]*fileReader),
+		store:                store,
+		blockSize:            blockSize,
+		readAhead:            readAhead,
+		readAheadConcurrency: readAheadConcurrency,
 	}
 	return r
 }
@@ -54,10 +58,19 @@ type fileReader struct {
 	name   string
 	path   string
 	length uint64
-	ufs    ufslib.UnderFileStorage
-	reader *dataReader
+	// version is the version of file when opened, which identifies the cached blocks
+	version cache.FileVersion
+	ufs     ufslib.UnderFileStorage
+	reader  *dataReader
 	sync.Mutex
 
+	// sequential read detection, window is the number of blocks to prefetch,
+	// it doubles on sequential reads and is reset on random reads
+	lastEnd    int64
+	window     int
+	ahead      int
+	prefetcher *prefetcher
+
 	// TODO: 先用base.FileHandle跑通流程，后续修改ufs接口
 	fd base.FileHandle
 }
@@ -69,6 +82,9 @@ type dataReader struct {
 	ufsMap    *ufsMap
 	store     cache.Store
 	blockSize int
+	// readAhead is the max read ahead window in blocks
+	readAhead            int
+	readAheadConcurrency int
 }
 
 func (f *fileReader) Read(buf []byte, off uint64) (int, syscall.Errno) {
@@ -77,11 +93,12 @@ func (f *fileReader) Read(buf []byte, off uint64) (int, syscall.Errno) {
 	var err error
 	if f.reader.store != nil {
 		log.Debugf("len[%d] off[%d] blockName[%s]", len(buf), off, f.name)
-		n, err = f.reader.Read(f.name, buf, int(off), ufsHandle)
+		n, err = f.reader.Read(f.name, f.version, buf, int(off), ufsHandle)
 		if err != nil {
 			log.Errorf("fileReader read err: %v", err)
 			return 0, syscall.EBADF
 		}
+		f.readAhead(int64(off), n)
 	} else {
 		n, err = ufsHandle.ReadAt(buf, int64(off))
 		if err != nil {
@@ -93,6 +110,51 @@ func (f *fileReader) Read(buf []byte, off uint64) (int, syscall.Errno) {
 	return n, syscall.F_OK
 }
 
+// readAhead detects sequential reads and submits the following blocks to prefetcher.
+// Reads within one block of the last read end are regarded as sequential, since the
+// kernel may reorder the concurrent reads a little.
+func (f *fileReader) readAhead(off int64, n int) {
+	if f.prefetcher == nil || n <= 0 || f.length == 0 {
+		return
+	}
+	blockSize := int64(f.reader.blockSize)
+	end := off + int64(n)
+
+	f.Lock()
+	defer f.Unlock()
+	if off >= f.lastEnd-blockSize && off <= f.lastEnd+blockSize {
+		if f.window == 0 {
+			f.window = 1
+		} else if f.window < f.reader.readAhead {
+			f.window = utils.Min(f.window*2, f.reader.readAhead)
+		}
+		if end > f.lastEnd {
+			f.lastEnd = end
+		}
+	} else {
+		f.window = 0
+		f.ahead = -1
+		f.lastEnd = end
+		return
+	}
+
+	current := int((end - 1) / blockSize)
+	last := current + f.window
+	if maxIndex := int((int64(f.length) - 1) / blockSize); last > maxIndex {
+		last = maxIndex
+	}
+	start := current + 1
+	if f.ahead >= start {
+		start = f.ahead + 1
+	}
+	for i := start; i <= last; i++ {
+		if !f.prefetcher.submit(i) {
+			break
+		}
+		f.ahead = i
+	}
+}
+
 func (f *fileReader) Close() {
 	f.Lock()
 	f.release()
@@ -102,23 +164,35 @@ func (f *fileReader) Close() {
 func (f *fileReader) release() {
 	// todo:: 硬链接的情况下，需要增加refer判断，不能直接删除
 	delete(f.reader.files, f.inode)
+	if f.prefetcher != nil {
+		f.prefetcher.close()
+	}
 	f.fd.Release()
 }
 
-func (d *dataReader) Open(inode Ino, length uint64, ufs ufslib.UnderFileStorage, path string) (FileReader, error) {
+func fileVersion(attr *Attr) cache.FileVersion {
+	return cache.FileVersion{Mtime: attr.Mtime*int64(time.Second) + int64(attr.Mtimensec), Size: int64(attr.Size)}
+}
+
+func (d *dataReader) Open(inode Ino, attr *Attr, ufs ufslib.UnderFileStorage, path string) (FileReader, error) {
 	name := d.m.InoToPath(inode)
 	fd, err := ufs.Open(path, syscall.O_RDONLY)
 	if err != nil {
 		return nil, err
 	}
 	f := &fileReader{
-		reader: d,
-		inode:  inode,
-		name:   name,
-		path:   path,
-		length: length,
-		ufs:    ufs,
-		fd:     fd,
+		reader:  d,
+		inode:   inode,
+		name:    name,
+		path:    path,
+		length:  attr.Size,
+		version: fileVersion(attr),
+		ufs:     ufs,
+		fd:      fd,
+		ahead:   -1,
+	}
+	if d.store != nil && d.readAhead > 0 && d.blockSize > 0 {
+		f.prefetcher = newPrefetcher(name, path, f.version, ufs, d.store, d.readAhead, d.readAheadConcurrency)
 	}
 	d.Lock()
 	d.files[inode] = f
@@ -126,10 +200,10 @@ func (d *dataReader) Open(inode Ino, length uint64, ufs ufslib.UnderFileStorage,
 	return f, nil
 }
 
-func (d *dataReader) Read(name string, buf []byte, off int, ufs ufslib.FileHandle) (int, error) {
+func (d *dataReader) Read(name string, version cache.FileVersion, buf []byte, off int, ufs ufslib.FileHandle) (int, error) {
 	read := 0
 	bufSize := len(buf)
-	reader := d.store.NewReader(name, ufs)
+	reader := d.store.NewReader(name, version, ufs)
 	var err error
 	var n int
 	for read < len(buf) {
@@ -0,0 +1,71 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/cache"
+)
+
+func drainBlocks(p *prefetcher) []int {
+	var blocks []int
+	for {
+		select {
+		case index := <-p.blocks:
+			blocks = append(blocks, index)
+		default:
+			return blocks
+		}
+	}
+}
+
+func TestReadAhead(t *testing.T) {
+	blockSize := 1024
+	d := &dataReader{blockSize: blockSize, readAhead: 4}
+	p := newPrefetcher("a", "a", cache.FileVersion{}, nil, nil, 4, 1)
+	// do not start workers, blocks are checked in the queue
+	p.once.Do(func() {})
+	f := &fileReader{reader: d, length: uint64(20 * blockSize), ahead: -1, prefetcher: p}
+
+	// sequential reads, window grows 1, 2, 4
+	f.readAhead(0, blockSize)
+	assert.Equal(t, []int{1}, drainBlocks(p))
+	f.readAhead(int64(blockSize), blockSize)
+	assert.Equal(t, []int{2, 3}, drainBlocks(p))
+	f.readAhead(int64(2*blockSize), blockSize)
+	assert.Equal(t, []int{4, 5, 6}, drainBlocks(p))
+	assert.Equal(t, 4, f.window)
+
+	// random read resets the window
+	f.readAhead(int64(15*blockSize), blockSize)
+	assert.Equal(t, 0, f.window)
+	assert.Empty(t, drainBlocks(p))
+
+	// sequential again from the new position, prefetch stops at the end of file
+	f.readAhead(int64(16*blockSize), blockSize)
+	assert.Equal(t, []int{17}, drainBlocks(p))
+	f.readAhead(int64(17*blockSize), blockSize)
+	assert.Equal(t, []int{18, 19}, drainBlocks(p))
+	f.readAhead(int64(18*blockSize), blockSize)
+	assert.Empty(t, drainBlocks(p))
+
+	p.close()
+	assert.False(t, p.submit(1))
+}
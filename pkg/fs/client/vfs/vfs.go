@@ -17,12 +17,15 @@ limitations under the License.
 package vfs
 
 import (
+	"math"
 	"os"
+	pathlib "path"
 	"sync"
 	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
 
 	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/client/cache"
@@ -41,10 +44,23 @@ type VFS struct {
 	nextfh     uint64
 	Meta       meta.Meta
 	Store      cache.Store
+	locker     meta.Locker
+	entries    *entryCache
+	// quota is not nil if the fs has a hard quota
+	quota *quota
+	// access is the access of the user to the fs shared by others, nil for the owner
+	access base.FsAccess
 }
 
 type Config struct {
 	Cache *cache.Config
+	// ServerLock coordinates the file locks by pfs server so that they hold across mounts
+	ServerLock bool
+	Meta       *meta.Config
+	// EntryExpire is the ttl of the dir entries and attributes cached in vfs, 0 disables the cache
+	EntryExpire time.Duration
+	// NegativeEntryExpire is the ttl of the entries not found, 0 disables the negative cache
+	NegativeEntryExpire time.Duration
 }
 
 type Ino = meta.Ino
@@ -62,7 +78,8 @@ func InitConfig(options ...Option) *Config {
 		Cache: &cache.Config{
 			Mem: &cache.MemConfig{},
 			Disk: &cache.DiskConfig{
-				Expire: 60 * time.Second,
+				Expire:    60 * time.Second,
+				FreeRatio: 0.1,
 			},
 		},
 	}
@@ -96,6 +113,59 @@ func WithBlockSize(size int) Option {
 	}
 }
 
+func WithReadAhead(window, concurrency int) Option {
+	return func(config *Config) {
+		config.Cache.ReadAheadWindow = window
+		config.Cache.ReadAheadConcurrency = concurrency
+	}
+}
+
+func WithWriteBack(dir string, dirtyLimit int64) Option {
+	return func(config *Config) {
+		config.Cache.WriteBack = &cache.WriteBackConfig{
+			Dir:        dir,
+			DirtyLimit: dirtyLimit,
+		}
+	}
+}
+
+func WithPeerCache(peer *cache.PeerConfig) Option {
+	return func(config *Config) {
+		config.Cache.Peer = peer
+	}
+}
+
+func WithServerLock(enable bool) Option {
+	return func(config *Config) {
+		config.ServerLock = enable
+	}
+}
+
+func WithMeta(m *meta.Config) Option {
+	return func(config *Config) {
+		config.Meta = m
+	}
+}
+
+func WithEntryCache(expire, negativeExpire time.Duration) Option {
+	return func(config *Config) {
+		config.EntryExpire = expire
+		config.NegativeEntryExpire = negativeExpire
+	}
+}
+
+func WithDiskMaxSize(size int64) Option {
+	return func(config *Config) {
+		config.Cache.Disk.MaxSize = size
+	}
+}
+
+func WithDiskFreeRatio(ratio float64) Option {
+	return func(config *Config) {
+		config.Cache.Disk.FreeRatio = ratio
+	}
+}
+
 func WithDiskCachePath(path string) Option {
 	return func(config *Config) {
 		config.Cache.Disk.Dir = path
@@ -105,27 +175,69 @@ func WithDiskCachePath(path string) Option {
 func InitVFS(fsMeta base.FSMeta, links map[string]base.FSMeta, global bool, config *Config) (*VFS, error) {
 	vfs := &VFS{
 		fsMeta: fsMeta,
+		access: fsMeta.Access,
+	}
+	vfs.locker = meta.NewLocalLocker()
+	if config != nil && config.ServerLock {
+		if base.Client != nil {
+			vfs.locker = meta.NewServerLocker(base.Client)
+		} else {
+			log.Warnf("server lock needs pfs server, the file locks are local to the mount")
+		}
 	}
-	inodeHandle := meta.NewInodeHandle()
-	inodeHandle.InitRootNode()
-
-	vfsMeta, err := meta.NewDefaultMeta(fsMeta, links, inodeHandle)
+	var metaConfig *meta.Config
+	if config != nil {
+		metaConfig = config.Meta
+	}
+	vfsMeta, err := meta.NewMeta(fsMeta, links, vfs.locker, metaConfig)
 	if err != nil {
-		log.Errorf("new default meta failed: %v", err)
+		log.Errorf("new meta failed: %v", err)
 		return nil, err
 	}
 	vfs.Meta = vfsMeta
+	if config != nil {
+		vfs.entries = newEntryCache(config.EntryExpire, config.NegativeEntryExpire)
+	}
 	var store cache.Store
-	var blockSize int
+	var blockSize, readAhead, readAheadConcurrency int
 	if config != nil && config.Cache != nil {
 		store = cache.NewCacheStore(config.Cache)
 		blockSize = config.Cache.BlockSize
+		readAhead = config.Cache.ReadAheadWindow
+		readAheadConcurrency = config.Cache.ReadAheadConcurrency
 	}
 	vfs.Store = store
-	vfs.reader = NewDataReader(vfs.Meta, blockSize, store)
+	if store != nil {
+		// upload the dirty data left by the last run before serving
+		err = store.Recover(func(name string) (ufslib.FileHandle, error) {
+			ufs, _, _, path := vfs.getUFS(name)
+			if ufs == nil {
+				return ufslib.FileHandle{}, syscall.ENOENT
+			}
+			fd, err := ufs.Open(path, syscall.O_WRONLY)
+			if err != nil {
+				return ufslib.FileHandle{}, err
+			}
+			return ufslib.NewFileHandle(fd), nil
+		})
+		if err != nil {
+			log.Errorf("recover write back journals failed: %v", err)
+		}
+	}
+	vfs.reader = NewDataReader(vfs.Meta, blockSize, store, readAhead, readAheadConcurrency)
 	vfs.writer = NewDataWriter(vfs.Meta, blockSize, store)
 	vfs.handleMap = make(map[Ino][]*handle)
 	vfs.nextfh = 1
+	// the hard quota is enforced by the mount, whose usage is scanned by pfs server
+	if limit, hard := base.GetQuota(fsMeta.Properties); hard && global {
+		var getUsage UsageFunc
+		if base.Client != nil {
+			getUsage = serverUsage
+		} else {
+			log.Warnf("quota needs pfs server, only the bytes written by the mount are counted")
+		}
+		vfs.quota = newQuota(limit, getUsage)
+	}
 
 	if global {
 		vfsop = vfs
@@ -134,6 +246,22 @@ func InitVFS(fsMeta base.FSMeta, links map[string]base.FSMeta, global bool, conf
 	return vfs, nil
 }
 
+// Close releases the resources of vfs on umount.
+func (v *VFS) Close() {
+	if v.quota != nil {
+		v.quota.close()
+	}
+	if v.locker != nil {
+		v.locker.Close()
+	}
+	if v.Store != nil {
+		v.Store.Close()
+	}
+	if err := v.Meta.Shutdown(); err != nil {
+		log.Errorf("shutdown meta failed: %v", err)
+	}
+}
+
 func GetVFS() *VFS {
 	if vfsop == nil {
 		log.Errorf("vfs is not initialized")
@@ -153,23 +281,55 @@ func (v *VFS) getUFS(name string) (ufslib.UnderFileStorage, bool, string, string
 func (v *VFS) Lookup(ctx *meta.Context, parent Ino, name string) (entry *meta.Entry, err syscall.Errno) {
 	var attr = &Attr{}
 	var inode Ino
+	var cached bool
+	if !v.readable(parent, name) {
+		return nil, syscall.ENOENT
+	}
+	if inode, attr, cached = v.entries.lookup(parent, name); cached {
+		if inode == 0 {
+			return nil, syscall.ENOENT
+		}
+		v.fixDirtyLength(inode, attr)
+		return &meta.Entry{Ino: inode, Attr: attr}, syscall.F_OK
+	}
 	inode, attr, err = v.Meta.Lookup(ctx, parent, name)
 	log.Debugf("Lookup from meta %+v inode %v", attr, inode)
+	if err == syscall.ENOENT {
+		v.entries.setNegative(parent, name)
+	}
 	if utils.IsError(err) {
 		return nil, err
 	}
+	v.entries.setEntry(parent, name, inode, attr)
+	v.fixDirtyLength(inode, attr)
 	entry = &meta.Entry{Ino: inode, Attr: attr}
 	return entry, err
 }
 
+// fixDirtyLength corrects the file size in ufs which is stale if the write back data is not uploaded yet.
+func (v *VFS) fixDirtyLength(ino Ino, attr *Attr) {
+	if v.Store == nil {
+		return
+	}
+	if length, ok := v.Store.DirtyLength(v.Meta.InoToPath(ino)); ok && uint64(length) > attr.Size {
+		attr.Size = uint64(length)
+	}
+}
+
 // Attributes.
 func (v *VFS) GetAttr(ctx *meta.Context, ino Ino) (entry *meta.Entry, err syscall.Errno) {
+	if attr, ok := v.entries.getAttr(ino); ok {
+		v.fixDirtyLength(ino, attr)
+		return &meta.Entry{Ino: ino, Attr: attr}, syscall.F_OK
+	}
 	var attr = &Attr{}
 	err = v.Meta.GetAttr(ctx, ino, attr)
 	log.Debugf("GetAttr attr %+v", *attr)
 	if utils.IsError(err) {
 		return nil, err
 	}
+	v.entries.setAttr(ino, attr)
+	v.fixDirtyLength(ino, attr)
 	entry = &meta.Entry{Ino: ino, Attr: attr}
 	return entry, err
 }
@@ -185,10 +345,15 @@ func (v *VFS) SetAttr(ctx *meta.Context, ino Ino, set uint32, mode, uid, gid uin
 		Mtimensec: mtimensec,
 		Size:      size,
 	}
+	if err = v.checkWriteIno(ino); utils.IsError(err) {
+		return nil, err
+	}
 	err = v.Meta.SetAttr(ctx, ino, set, attr)
 	if utils.IsError(err) {
+		v.entries.invalidateAttr(ino)
 		return entry, err
 	}
+	v.entries.setAttr(ino, attr)
 
 	entry = &meta.Entry{Ino: ino, Attr: attr}
 
@@ -208,38 +373,83 @@ func (v *VFS) SetAttr(ctx *meta.Context, ino Ino, set uint32, mode, uid, gid uin
 
 // Modifying structure.
 func (v *VFS) Mknod(ctx *meta.Context, parent Ino, name string, mode uint32, rdev uint32) (entry *meta.Entry, err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return nil, err
+	}
 	var ino Ino
 	attr := &Attr{}
 	err = v.Meta.Mknod(ctx, parent, name, mode, rdev, &ino, attr)
+	v.newEntry(parent, name, ino, attr, err)
 	entry = &meta.Entry{Ino: ino, Attr: attr}
 	return
 }
 
 func (v *VFS) Mkdir(ctx *meta.Context, parent Ino, name string, mode uint32) (entry *meta.Entry, err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return nil, err
+	}
 	var ino Ino
 	attr := &Attr{}
 	err = v.Meta.Mkdir(ctx, parent, name, mode, &ino, attr)
+	v.newEntry(parent, name, ino, attr, err)
 	entry = &meta.Entry{Ino: ino, Attr: attr}
 	return
 }
 
+// newEntry caches the entry created locally, the entry is dropped if the creation failed.
+func (v *VFS) newEntry(parent Ino, name string, ino Ino, attr *Attr, err syscall.Errno) {
+	if utils.IsError(err) {
+		v.entries.invalidateEntry(parent, name)
+		return
+	}
+	v.entries.setEntry(parent, name, ino, attr)
+}
+
 func (v *VFS) Unlink(ctx *meta.Context, parent Ino, name string) (err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return err
+	}
 	err = v.Meta.Unlink(ctx, parent, name)
+	v.removeEntry(parent, name, err)
 	return err
 }
 
 func (v *VFS) Rmdir(ctx *meta.Context, parent Ino, name string) (err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return err
+	}
 	err = v.Meta.Rmdir(ctx, parent, name)
+	v.removeEntry(parent, name, err)
 	return err
 }
 
+// removeEntry caches the entry removed locally as negative, the entry is dropped if the removal failed.
+func (v *VFS) removeEntry(parent Ino, name string, err syscall.Errno) {
+	if utils.IsError(err) {
+		v.entries.invalidateEntry(parent, name)
+		return
+	}
+	v.entries.removeEntry(parent, name)
+}
+
 func (v *VFS) Rename(ctx *meta.Context, parent Ino, name string, newparent Ino, newname string, flags uint32) (err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return err
+	}
+	if err = v.checkWriteEntry(newparent, newname); utils.IsError(err) {
+		return err
+	}
 	var ino Ino
 	attr := &Attr{}
 	err = v.Meta.Rename(ctx, parent, name, newparent, newname, flags, &ino, attr)
 	if utils.IsError(err) {
+		v.entries.invalidateEntry(parent, name)
+		v.entries.invalidateEntry(newparent, newname)
 		return err
 	}
+	v.entries.invalidateEntry(newparent, newname)
+	v.entries.removeEntry(parent, name)
+	v.entries.setEntry(newparent, newname, ino, attr)
 	if v.Store != nil {
 		delCacheErr := v.Store.InvalidateCache(v.Meta.InoToPath(parent)+"/"+name, int(attr.Size))
 		if delCacheErr != nil {
@@ -268,6 +478,11 @@ func (v *VFS) Readlink(ctx *meta.Context, ino Ino) (path []byte, err syscall.Err
 }
 
 func (v *VFS) Access(ctx *meta.Context, ino Ino, mask uint32) (err syscall.Errno) {
+	if mask&unix.W_OK != 0 {
+		if err = v.checkWriteIno(ino); utils.IsError(err) {
+			return err
+		}
+	}
 	attr := &Attr{}
 	err = v.Meta.Access(ctx, ino, mask, attr)
 	return err
@@ -305,26 +520,36 @@ func (v *VFS) ListXAttr(ctx *meta.Context, ino Ino, size uint32) (data []byte, e
 
 // SetAttr writes an extended attribute.
 func (v *VFS) SetXAttr(ctx *meta.Context, ino Ino, name string, value []byte, flags uint32) (err syscall.Errno) {
+	if err = v.checkWriteIno(ino); utils.IsError(err) {
+		return err
+	}
 	err = v.Meta.SetXattr(ctx, ino, name, value, flags)
 	return
 }
 
 // RemoveXAttr removes an extended attribute.
 func (v *VFS) RemoveXAttr(ctx *meta.Context, ino Ino, name string) (err syscall.Errno) {
+	if err = v.checkWriteIno(ino); utils.IsError(err) {
+		return err
+	}
 	err = v.Meta.RemoveXattr(ctx, ino, name)
 	return
 }
 
 // File handling.
 func (v *VFS) Create(ctx *meta.Context, parent Ino, name string, mode uint32, cumask uint16, flags uint32) (entry *meta.Entry, fh uint64, err syscall.Errno) {
+	if err = v.checkWriteEntry(parent, name); utils.IsError(err) {
+		return nil, 0, err
+	}
 	var ino Ino
 	attr := &Attr{}
 	ufs, path, err := v.Meta.Create(ctx, parent, name, mode, cumask, flags, &ino, attr)
+	v.newEntry(parent, name, ino, attr, err)
 	if utils.IsError(err) {
 		return
 	}
 	entry = &meta.Entry{Ino: ino, Attr: attr}
-	fh, errHandle := v.newFileHandle(ino, attr.Size, flags, ufs, path)
+	fh, errHandle := v.newFileHandle(ino, attr, flags, ufs, path)
 	if errHandle != nil {
 		log.Errorf("new file handle err:%v", err)
 		return nil, 0, utils.ToSyscallErrno(errHandle)
@@ -340,13 +565,20 @@ func (v *VFS) Create(ctx *meta.Context, parent Ino, name string, mode uint32, cu
 }
 
 func (v *VFS) Open(ctx *meta.Context, ino Ino, flags uint32) (entry *meta.Entry, fh uint64, err syscall.Errno) {
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY || flags&syscall.O_TRUNC != 0 {
+		if err = v.checkWriteIno(ino); utils.IsError(err) {
+			return nil, 0, err
+		}
+	}
 	var attr = &Attr{}
 	ufs, path, err := v.Meta.Open(ctx, ino, flags, attr)
 	if utils.IsError(err) {
+		v.entries.invalidateAttr(ino)
 		return
 	}
+	v.entries.setAttr(ino, attr)
 	var errOpen error
-	fh, errOpen = v.newFileHandle(ino, attr.Size, flags, ufs, path)
+	fh, errOpen = v.newFileHandle(ino, attr, flags, ufs, path)
 	if errOpen != nil {
 		return entry, fh, utils.ToSyscallErrno(errOpen)
 	}
@@ -372,16 +604,39 @@ func (v *VFS) Read(ctx *meta.Context, ino Ino, buf []byte, off uint64, fh uint64
 }
 
 // File locking
-func (v *VFS) GetLk(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, len *uint64, typ *uint32, pid *uint32) (err syscall.Errno) {
-	return syscall.ENOSYS
+func (v *VFS) GetLk(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, end *uint64, typ *uint32, pid *uint32) (err syscall.Errno) {
+	if v.findHandle(ino, fh) == nil {
+		return syscall.EBADF
+	}
+	return v.Meta.Getlk(ctx, ino, owner, typ, start, end, pid)
 }
 
-func (v *VFS) SetLk(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, end uint64, typ uint32, pid uint32, block bool) (err syscall.Errno) {
-	return syscall.ENOSYS
+// SetLk puts a flock lock if flock is true, otherwise a posix range lock.
+func (v *VFS) SetLk(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, end uint64, typ uint32, pid uint32, flock, block bool) (err syscall.Errno) {
+	h := v.findHandle(ino, fh)
+	if h == nil {
+		return syscall.EBADF
+	}
+	if flock {
+		err = v.Meta.Flock(ctx, ino, owner, typ, block)
+	} else {
+		err = v.Meta.Setlk(ctx, ino, owner, block, typ, start, end, pid)
+	}
+	if err == syscall.F_OK && typ != syscall.F_UNLCK {
+		h.Lock()
+		if flock {
+			h.locks |= lockFlock
+			h.flockOwner = owner
+		} else {
+			h.locks |= lockPosix
+		}
+		h.Unlock()
+	}
+	return err
 }
 
-func (v *VFS) SetLkw(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, end uint64, typ uint32, pid uint32, block bool) (err syscall.Errno) {
-	return syscall.ENOSYS
+func (v *VFS) SetLkw(ctx *meta.Context, ino Ino, fh uint64, owner uint64, start, end uint64, typ uint32, pid uint32, flock bool) (err syscall.Errno) {
+	return v.SetLk(ctx, ino, fh, owner, start, end, typ, pid, flock, true)
 }
 
 func (v *VFS) Write(ctx *meta.Context, ino Ino, buf []byte, off, fh uint64) (err syscall.Errno) {
@@ -394,14 +649,190 @@ func (v *VFS) Write(ctx *meta.Context, ino Ino, buf []byte, off, fh uint64) (err
 		err = syscall.EACCES
 		return
 	}
-	// todo:: 对写入的文件大小加上限制
 	// todo:: 限制并发写的情况
+	if err = v.reserveQuota(h, off+uint64(len(buf))); utils.IsError(err) {
+		return err
+	}
 	err = h.writer.Write(buf, off)
+	v.entries.invalidateAttr(ino)
 	return err
 }
 
+// reserveQuota counts the bytes appended to the file of h by a write ending at end, the writes
+// to the links are not limited by the quota of fs.
+func (v *VFS) reserveQuota(h *handle, end uint64) syscall.Errno {
+	if v.quota == nil || !h.quota {
+		return syscall.F_OK
+	}
+	h.Lock()
+	defer h.Unlock()
+	if end <= h.length {
+		return syscall.F_OK
+	}
+	if err := v.quota.reserve(int64(end - h.length)); utils.IsError(err) {
+		return err
+	}
+	h.length = end
+	return syscall.F_OK
+}
+
+// CopyFileRange copies the data by the ufs if both files are in the same ufs supporting
+// server side copy, otherwise the data is streamed through the client.
 func (v *VFS) CopyFileRange(ctx *meta.Context, nodeIn Ino, fhIn, offIn uint64, nodeOut Ino, fhOut, offOut, size uint64, flags uint32) (copied uint64, err syscall.Errno) {
-	return 0, syscall.ENOSYS
+	hIn := v.findHandle(nodeIn, fhIn)
+	hOut := v.findHandle(nodeOut, fhOut)
+	if hIn == nil || hOut == nil || hIn.reader == nil || hOut.writer == nil {
+		return 0, syscall.EBADF
+	}
+	if flags != 0 {
+		return 0, syscall.EINVAL
+	}
+	if nodeIn == nodeOut && offIn < offOut+size && offOut < offIn+size {
+		return 0, syscall.EINVAL
+	}
+	defer v.entries.invalidateAttr(nodeOut)
+	// the data written by the open handles must be visible to the ufs
+	for _, ino := range []Ino{nodeIn, nodeOut} {
+		for _, h := range v.findAllHandle(ino) {
+			if h.writer == nil {
+				continue
+			}
+			if err = h.writer.Fsync(int(h.fh)); utils.IsError(err) {
+				return 0, err
+			}
+		}
+	}
+
+	var oldSize uint64
+	attr := &Attr{}
+	if v.Meta.GetAttr(ctx, nodeOut, attr) == syscall.F_OK {
+		oldSize = attr.Size
+	}
+	// the bytes copied beyond the end of the output are reserved before the ufs copies them
+	if v.quota != nil && hOut.quota {
+		if err = v.reserveQuota(hOut, offOut+v.copySize(ctx, nodeIn, offIn, size)); utils.IsError(err) {
+			return 0, err
+		}
+	}
+	var n uint64
+	err = v.Meta.CopyFileRange(ctx, nodeIn, offIn, nodeOut, offOut, size, flags, &n)
+	if err == syscall.ENOTSUP {
+		return v.copyData(hIn, hOut, offIn, offOut, size)
+	}
+	nameOut := v.Meta.InoToPath(nodeOut)
+	if utils.IsError(err) {
+		log.Errorf("copy file range from [%s] to [%s] failed: %v", v.Meta.InoToPath(nodeIn), nameOut, err)
+		return 0, err
+	}
+	length := offOut + n
+	if oldSize > length {
+		length = oldSize
+	}
+	if v.Store != nil {
+		if delCacheErr := v.Store.InvalidateCache(nameOut, int(length)); delCacheErr != nil {
+			log.Errorf("copy file range: invalidate cache of [%s] failed: %v", nameOut, delCacheErr)
+		}
+	}
+	// the handles writing the file cache its size or content, which are changed by the ufs
+	for _, h := range v.findAllHandle(nodeOut) {
+		if h.writer == nil {
+			continue
+		}
+		if err = h.writer.Refresh(length); utils.IsError(err) {
+			return 0, err
+		}
+	}
+	return n, syscall.F_OK
+}
+
+// copySize returns the bytes copied from offIn of nodeIn, which is limited by the size of the file
+func (v *VFS) copySize(ctx *meta.Context, nodeIn Ino, offIn, size uint64) uint64 {
+	attr := &Attr{}
+	if v.Meta.GetAttr(ctx, nodeIn, attr) != syscall.F_OK {
+		return size
+	}
+	if offIn >= attr.Size {
+		return 0
+	}
+	if attr.Size-offIn < size {
+		return attr.Size - offIn
+	}
+	return size
+}
+
+// copyChunkSize is the buffer size of copying data through the client
+const copyChunkSize = 4 * 1024 * 1024
+
+func (v *VFS) copyData(hIn, hOut *handle, offIn, offOut, size uint64) (copied uint64, err syscall.Errno) {
+	bufSize := uint64(copyChunkSize)
+	if size < bufSize {
+		bufSize = size
+	}
+	buf := make([]byte, bufSize)
+	for copied < size {
+		n := bufSize
+		if size-copied < n {
+			n = size - copied
+		}
+		var read int
+		read, err = hIn.reader.Read(buf[:n], offIn+copied)
+		if utils.IsError(err) {
+			return copied, err
+		}
+		if read == 0 {
+			break
+		}
+		if err = v.reserveQuota(hOut, offOut+copied+uint64(read)); utils.IsError(err) {
+			return copied, err
+		}
+		if err = hOut.writer.Write(buf[:read], offOut+copied); utils.IsError(err) {
+			return copied, err
+		}
+		copied += uint64(read)
+	}
+	return copied, syscall.F_OK
+}
+
+// CopyFile copies the whole file by the ufs without moving the data through the client,
+// ENOTSUP is returned if the files are not in the same ufs supporting server side copy.
+func (v *VFS) CopyFile(ctx *meta.Context, src, dst string) syscall.Errno {
+	if v.access != nil {
+		if !v.access.CanRead(src) {
+			return syscall.ENOENT
+		}
+		if err := v.checkWrite(dst); utils.IsError(err) {
+			return err
+		}
+	}
+	ufsSrc, _, _, pathSrc := v.getUFS(src)
+	ufsDst, _, _, pathDst := v.getUFS(dst)
+	copier, ok := ufsSrc.(ufslib.ServerSideCopier)
+	if !ok || ufsSrc != ufsDst {
+		return syscall.ENOTSUP
+	}
+	if v.Store != nil {
+		// the dirty data of write back is only visible through the client
+		if _, dirty := v.Store.DirtyLength(src); dirty {
+			return syscall.ENOTSUP
+		}
+	}
+	if err := copier.CopyFile(pathSrc, pathDst); err != nil {
+		if err != syscall.ENOTSUP {
+			log.Errorf("copy file from [%s] to [%s] failed: %v", src, dst, err)
+		}
+		return utils.ToSyscallErrno(err)
+	}
+	if parent := v.Meta.PathToIno(pathlib.Dir(dst)); parent != 0 {
+		v.entries.invalidateEntry(parent, pathlib.Base(dst))
+	}
+	if v.Store != nil {
+		if info, err := ufsDst.GetAttr(pathDst); err == nil {
+			if delCacheErr := v.Store.InvalidateCache(dst, int(info.Size)); delCacheErr != nil {
+				log.Errorf("copy file: invalidate cache of [%s] failed: %v", dst, delCacheErr)
+			}
+		}
+	}
+	return syscall.F_OK
 }
 
 func (v *VFS) Flush(ctx *meta.Context, ino Ino, fh uint64, lockOwner uint64) (err syscall.Errno) {
@@ -412,6 +843,17 @@ func (v *VFS) Flush(ctx *meta.Context, ino Ino, fh uint64, lockOwner uint64) (er
 	}
 	if h.writer != nil {
 		err = h.writer.Flush()
+		v.entries.invalidateAttr(ino)
+	}
+	// closing any fd releases the posix locks of the process on the file
+	for _, f := range v.findAllHandle(ino) {
+		f.Lock()
+		locked := f.locks&lockPosix != 0
+		f.Unlock()
+		if locked {
+			v.Meta.Setlk(ctx, ino, lockOwner, false, syscall.F_UNLCK, 0, math.MaxInt64, 0)
+			break
+		}
 	}
 	return err
 }
@@ -424,6 +866,7 @@ func (v *VFS) Fsync(ctx *meta.Context, ino Ino, datasync int, fh uint64) (err sy
 	}
 	if h.writer != nil {
 		err = h.writer.Fsync(int(fh))
+		v.entries.invalidateAttr(ino)
 	}
 	return err
 }
@@ -437,17 +880,25 @@ func (v *VFS) OpenDir(ctx *meta.Context, ino Ino) (fh uint64, err syscall.Errno)
 	return v.newHandle(ino).fh, syscall.F_OK
 }
 
-func (v *VFS) ReadDir(ctx *meta.Context, ino Ino, fh uint64, offset uint64) (entries []*meta.Entry, err syscall.Errno) {
+// ReadDir lists the dir, the entries with Ino set have their attributes filled if plus is true,
+// which are cached so that the following lookups don't go to the ufs.
+func (v *VFS) ReadDir(ctx *meta.Context, ino Ino, fh uint64, offset uint64, plus bool) (entries []*meta.Entry, err syscall.Errno) {
 	h := v.findHandle(ino, fh)
 	if h == nil {
 		return nil, syscall.EBADF
 	}
 	if h.children == nil || offset == 0 {
-		err = v.Meta.Readdir(ctx, ino, &entries)
+		err = v.Meta.Readdir(ctx, ino, plus, &entries)
 		if utils.IsError(err) {
 			log.Errorf("Readdir Err %v", err)
 			return nil, err
 		}
+		entries = v.filterReadable(ino, entries)
+		for _, e := range entries {
+			if e.Ino != 0 {
+				v.entries.setEntry(ino, e.Name, e.Ino, e.Attr)
+			}
+		}
 		h.children = entries
 	}
 	if int(offset) < len(h.children) {
@@ -464,6 +915,16 @@ func (v *VFS) ReleaseDir(ctx *meta.Context, ino Ino, fh uint64) {
 }
 
 func (v *VFS) Release(ctx *meta.Context, ino Ino, fh uint64) {
+	if h := v.findHandle(ino, fh); h != nil {
+		if h.locks&lockFlock != 0 {
+			v.Meta.Flock(ctx, ino, h.flockOwner, syscall.F_UNLCK, false)
+		}
+		// the meta caching attrs is told that the file is not written by the handle any more
+		if h.writer != nil {
+			v.Meta.Close(ctx, ino)
+			v.entries.invalidateAttr(ino)
+		}
+	}
 	if fh > 0 {
 		v.releaseFileHandle(ino, fh)
 		log.Debugf("release inode %v", ino)
@@ -476,9 +937,19 @@ func (v *VFS) StatFs(ctx *meta.Context) (*base.StatfsOut, syscall.Errno) {
 	if utils.IsError(err) {
 		return &base.StatfsOut{}, err
 	}
+	if v.quota != nil {
+		if statFs == nil {
+			statFs = &base.StatfsOut{}
+		}
+		v.quota.statFs(statFs)
+	}
 	return statFs, syscall.F_OK
 }
 
 func (v *VFS) Truncate(ctx *meta.Context, ino Ino, size uint64) syscall.Errno {
+	if err := v.checkWriteIno(ino); utils.IsError(err) {
+		return err
+	}
+	defer v.entries.invalidateAttr(ino)
 	return v.Meta.Truncate(ctx, ino, size)
 }
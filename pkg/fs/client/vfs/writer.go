@@ -35,6 +35,8 @@ type FileWriter interface {
 	Fsync(fd int) syscall.Errno
 	Close() syscall.Errno
 	Truncate(size uint64) syscall.Errno
+	// Refresh reloads the file changed in ufs not through the writer, length is the new size of file
+	Refresh(length uint64) syscall.Errno
 	// GetSize() uint64
 }
 
@@ -66,9 +68,19 @@ type fileWriter struct {
 
 	// TODO: 先用base.FileHandle跑通流程，后续修改ufs接口
 	fd base.FileHandle
+	// cacheWriter is not nil if write back is enabled
+	cacheWriter cache.Writer
 }
 
 func (f *fileWriter) Write(data []byte, offset uint64) syscall.Errno {
+	if f.cacheWriter != nil {
+		_, err := f.cacheWriter.WriteAt(data, int64(offset))
+		if err != nil {
+			log.Errorf("cache write err: %v", err)
+			return syscall.EIO
+		}
+		return syscall.F_OK
+	}
 	ufsHandle := ufslib.NewFileHandle(f.fd)
 	var err error
 	if f.writer.store != nil {
@@ -91,6 +103,13 @@ func (f *fileWriter) Write(data []byte, offset uint64) syscall.Errno {
 func (f *fileWriter) Flush() syscall.Errno {
 	f.Lock()
 	defer f.Unlock()
+	if f.cacheWriter != nil {
+		if err := f.cacheWriter.Flush(); err != nil {
+			log.Errorf("flush: upload %s err: %v", f.name, err)
+			return syscall.EIO
+		}
+		return syscall.F_OK
+	}
 	if f.writer.store != nil {
 		log.Debugf("flush: delete cache is %s", f.name)
 		delErr := f.writer.store.InvalidateCache(f.name, int(f.length))
@@ -107,12 +126,28 @@ func (f *fileWriter) Flush() syscall.Errno {
 func (f *fileWriter) Fsync(fd int) syscall.Errno {
 	f.Lock()
 	defer f.Unlock()
+	if f.cacheWriter != nil {
+		if err := f.cacheWriter.Fsync(); err != nil {
+			log.Errorf("fsync: upload %s err: %v", f.name, err)
+			return syscall.EIO
+		}
+	}
 	// todo:: 需要加一个超时和重试
 	err := f.fd.Fsync(fd)
 	return syscall.Errno(err)
 }
 
 func (f *fileWriter) Close() syscall.Errno {
+	if f.cacheWriter != nil {
+		// the ufs handle is released by cache writer after uploading
+		err := f.cacheWriter.Close()
+		delete(f.writer.files, f.inode)
+		if err != nil {
+			log.Errorf("close: upload %s err: %v", f.name, err)
+			return syscall.EIO
+		}
+		return syscall.F_OK
+	}
 	err := f.Flush()
 	if utils.IsError(err) {
 		return err
@@ -127,9 +162,31 @@ func (f *fileWriter) release() {
 }
 
 func (f *fileWriter) Truncate(size uint64) syscall.Errno {
+	if f.cacheWriter != nil {
+		// dirty data must be uploaded before truncating
+		if err := f.cacheWriter.Fsync(); err != nil {
+			log.Errorf("truncate: upload %s err: %v", f.name, err)
+			return syscall.EIO
+		}
+	}
 	return syscall.Errno(f.fd.Truncate(size))
 }
 
+func (f *fileWriter) Refresh(length uint64) syscall.Errno {
+	f.Lock()
+	defer f.Unlock()
+	if length > f.length {
+		f.length = length
+	}
+	if refresher, ok := f.fd.(ufslib.Refresher); ok {
+		if err := refresher.Refresh(); err != nil {
+			log.Errorf("refresh: reload %s err: %v", f.name, err)
+			return utils.ToSyscallErrno(err)
+		}
+	}
+	return syscall.F_OK
+}
+
 type dataWriter struct {
 	sync.Mutex
 	m         meta.Meta
@@ -154,6 +211,11 @@ func (w *dataWriter) Open(inode Ino, length uint64, ufs ufslib.UnderFileStorage,
 		ufs:    ufs,
 		fd:     fd,
 	}
+	if w.store != nil {
+		if cacheWriter := w.store.NewWriter(name, int(length), ufslib.NewFileHandle(fd)); cacheWriter != nil {
+			f.cacheWriter = cacheWriter
+		}
+	}
 	w.Lock()
 	w.files[inode] = f
 	w.Unlock()
@@ -37,6 +37,11 @@ type K8SInterface interface {
 	GetPersistentVolumeClaim(namespace, name string,
 		getOptions metav1.GetOptions) (*v1.PersistentVolumeClaim, error)
 	ListPersistentVolume(listOptions metav1.ListOptions) (*v1.PersistentVolumeList, error)
+	GetPod(namespace, name string) (*v1.Pod, error)
+	ListPods(namespace string, listOptions metav1.ListOptions) (*v1.PodList, error)
+	CreatePod(pod *v1.Pod) (*v1.Pod, error)
+	UpdatePod(pod *v1.Pod) (*v1.Pod, error)
+	DeletePod(namespace, name string) error
 }
 
 type K8SClient struct {
@@ -91,3 +96,23 @@ func (c *K8SClient) GetPersistentVolumeClaim(namespace, name string,
 func (c *K8SClient) ListPersistentVolume(listOptions metav1.ListOptions) (*v1.PersistentVolumeList, error) {
 	return c.Clientset.CoreV1().PersistentVolumes().List(context.TODO(), listOptions)
 }
+
+func (c *K8SClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	return c.Clientset.CoreV1().Pods(namespace).Get(context.TODO(), name, metav1.GetOptions{})
+}
+
+func (c *K8SClient) ListPods(namespace string, listOptions metav1.ListOptions) (*v1.PodList, error) {
+	return c.Clientset.CoreV1().Pods(namespace).List(context.TODO(), listOptions)
+}
+
+func (c *K8SClient) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
+	return c.Clientset.CoreV1().Pods(pod.Namespace).Create(context.TODO(), pod, metav1.CreateOptions{})
+}
+
+func (c *K8SClient) UpdatePod(pod *v1.Pod) (*v1.Pod, error) {
+	return c.Clientset.CoreV1().Pods(pod.Namespace).Update(context.TODO(), pod, metav1.UpdateOptions{})
+}
+
+func (c *K8SClient) DeletePod(namespace, name string) error {
+	return c.Clientset.CoreV1().Pods(namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+}
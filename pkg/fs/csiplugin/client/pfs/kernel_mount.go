@@ -0,0 +1,63 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pfs
+
+import (
+	"strings"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+const mountCmdName = "mount"
+
+type KernelMount struct {
+	Type      string
+	Source    string
+	Options   []string
+	LocalPath string
+}
+
+// GetKernelMount returns the kernel mount in attributes, nil if the volume is mounted by pfs-fuse
+func GetKernelMount(attributes map[string]string) *KernelMount {
+	if attributes[base.KernelVolumeType] == "" || attributes[base.KernelVolumeSource] == "" {
+		return nil
+	}
+	kernelMount := &KernelMount{
+		Type:      attributes[base.KernelVolumeType],
+		Source:    attributes[base.KernelVolumeSource],
+		LocalPath: attributes[base.KernelVolumeLocalPath],
+	}
+	if options := attributes[base.KernelVolumeOptions]; options != "" {
+		kernelMount.Options = strings.Split(options, ",")
+	}
+	return kernelMount
+}
+
+// getMountCmd returns the mount(8) command to mount the fs at mountPoint, the fs mounted on the
+// node already is bind mounted from the local path
+func (k *KernelMount) getMountCmd(mountPoint string, options []string) (string, []string) {
+	source, args := k.Source, []string{"-t", k.Type}
+	if k.LocalPath != "" {
+		source, args = k.LocalPath, []string{"--bind"}
+	} else {
+		options = append(append([]string{}, k.Options...), options...)
+	}
+	if len(options) > 0 {
+		args = append(args, "-o", strings.Join(options, ","))
+	}
+	return mountCmdName, append(args, source, mountPoint)
+}
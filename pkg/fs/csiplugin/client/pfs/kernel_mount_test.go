@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestKernelMount(t *testing.T) {
+	fsMeta := base.FSMeta{
+		UfsType:       base.NFSType,
+		ServerAddress: "192.168.1.2",
+		SubPath:       "/export/data",
+		Properties:    map[string]string{base.KernelMountOptions: "vers=4.1,nolock"},
+	}
+	attributes := base.KernelVolumeAttributes(fsMeta)
+	assert.Equal(t, "192.168.1.2:/export/data", attributes[base.KernelVolumeSource])
+
+	mountInfo := GetMountInfo("fs-root-nfs", "127.0.0.1:8082", "root", true, nil)
+	mountInfo.KernelMount = GetKernelMount(attributes)
+	mountInfo.LocalPath = "/mnt/staging"
+	cmd, args := mountInfo.GetMountCmd()
+	assert.Equal(t, "mount", cmd)
+	assert.Equal(t, []string{"-t", "nfs", "-o", "vers=4.1,nolock,ro", "192.168.1.2:/export/data", "/mnt/staging"}, args)
+
+	fsMeta.Properties[base.LocalMountPath] = "/mnt/nfs"
+	mountInfo.KernelMount = GetKernelMount(base.KernelVolumeAttributes(fsMeta))
+	_, args = mountInfo.GetMountCmd()
+	assert.Equal(t, []string{"--bind", "-o", "ro", "/mnt/nfs", "/mnt/staging"}, args)
+
+	fsMeta.UfsType = base.S3Type
+	assert.Nil(t, base.KernelVolumeAttributes(fsMeta))
+	assert.Nil(t, GetKernelMount(map[string]string{base.MountSubPath: "data"}))
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package pfs
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strings"
 
+	"paddleflow/pkg/fs/client/base"
 	csiCommon "paddleflow/pkg/fs/utils/common"
 )
 
@@ -38,9 +41,17 @@ const (
 )
 
 type FSMountParameter struct {
-	FSID   string
-	Server string
-	UserID string
+	FSID         string
+	Server       string
+	UserID       string
+	MountOptions map[string]string
+	KernelMount  *KernelMount
+}
+
+func (p FSMountParameter) GetMountInfo(readOnly bool) MountInfo {
+	mountInfo := GetMountInfo(p.FSID, p.Server, p.UserID, readOnly, p.MountOptions)
+	mountInfo.KernelMount = p.KernelMount
+	return mountInfo
 }
 
 type MountInfo struct {
@@ -51,10 +62,21 @@ type MountInfo struct {
 	UID       int
 	GID       int
 	Options   []string
+	// FuseOptions are the mount options in volume attributes, see mount_options.go
+	FuseOptions map[string]string
+	// KernelMount is set if the fs is mounted by the kernel instead of pfs-fuse
+	KernelMount *KernelMount
 }
 
 func (m *MountInfo) GetMountCmd() (string, []string) {
-	cmdName := pfsMountCmdName
+	if m.KernelMount != nil {
+		return m.KernelMount.getMountCmd(m.LocalPath, m.Options)
+	}
+	return pfsMountCmdName, m.GetFuseArgs()
+}
+
+// GetFuseArgs returns the arguments of pfs-fuse, which are passed through by mount.sh
+func (m *MountInfo) GetFuseArgs() []string {
 	var args []string
 
 	if len(m.Options) > 0 {
@@ -67,18 +89,34 @@ func (m *MountInfo) GetMountCmd() (string, []string) {
 	args = append(args, fmt.Sprintf(UIDOption, m.UID))
 	args = append(args, fmt.Sprintf(GIDOption, m.GID))
 	args = append(args, fmt.Sprintf(MountPoint, m.LocalPath))
+	args = append(args, mountOptionsToFlags(m.FuseOptions)...)
+
+	return args
+}
+
+// SharedMountKey identifies the mounts which can be shared on a node, i.e. the mounts of the
+// same fs by the same user with the same mount args
+func (m MountInfo) SharedMountKey() string {
+	m.LocalPath = ""
+	_, args := m.GetMountCmd()
+	sum := sha256.Sum256([]byte(strings.Join(append([]string{m.UserName}, args...), " ")))
+	return hex.EncodeToString(sum[:])[:16]
+}
 
-	return cmdName, args
+// GetSubPath returns the path in fs to publish, "" means the root of fs
+func (m *MountInfo) GetSubPath() string {
+	return m.FuseOptions[base.MountSubPath]
 }
 
-func GetMountInfo(id, server, userName string, readOnly bool) MountInfo {
+func GetMountInfo(id, server, userName string, readOnly bool, fuseOptions map[string]string) MountInfo {
 	return MountInfo{
-		Server:   server,
-		FSID:     id,
-		UserName: userName,
-		UID:      csiCommon.GetDefaultUID(),
-		GID:      csiCommon.GetDefaultGID(),
-		Options:  GetOptions(readOnly),
+		Server:      server,
+		FSID:        id,
+		UserName:    userName,
+		UID:         csiCommon.GetDefaultUID(),
+		GID:         csiCommon.GetDefaultGID(),
+		Options:     GetOptions(readOnly),
+		FuseOptions: fuseOptions,
 	}
 }
 
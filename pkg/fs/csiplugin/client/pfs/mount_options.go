@@ -0,0 +1,53 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pfs
+
+import (
+	"fmt"
+	"sort"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+// mountOptionFlags are the flags of pfs-fuse the mount options are passed as
+var mountOptionFlags = map[string]string{
+	base.MountBlockSize:         "block-size",
+	base.MountMemorySize:        "mem-size",
+	base.MountMemoryCacheExpire: "mem-cache-expire",
+	base.MountDiskCacheExpire:   "disk-cache-expire",
+	base.MountDiskCacheMaxSize:  "disk-cache-max-size",
+	base.MountAttrTimeout:       "attr-timeout",
+	base.MountEntryTimeout:      "entry-timeout",
+	base.MountEntryCacheExpire:  "entry-cache-expire",
+	base.MountReadAheadWindow:   "read-ahead-window",
+	base.MountAllowOther:        "allow-other",
+	base.MountAccessUser:        "access-user",
+}
+
+// mountOptionsToFlags converts the mount options to the flags of pfs-fuse in a stable order
+func mountOptionsToFlags(options map[string]string) []string {
+	var flags []string
+	for key, value := range options {
+		flag, ok := mountOptionFlags[key]
+		if !ok {
+			continue
+		}
+		flags = append(flags, fmt.Sprintf("--%s=%s", flag, value))
+	}
+	sort.Strings(flags)
+	return flags
+}
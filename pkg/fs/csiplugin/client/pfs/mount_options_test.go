@@ -0,0 +1,37 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pfs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestMountOptionsToFlags(t *testing.T) {
+	flags := mountOptionsToFlags(map[string]string{
+		base.MountMemorySize: "100",
+		base.MountBlockSize:  "4194304",
+		base.MountSubPath:    "data",
+		base.MountReadOnly:   "true",
+		base.MountAccessUser: "alice",
+		"pfs.unsupported":    "1",
+	})
+	assert.Equal(t, []string{"--access-user=alice", "--block-size=4194304", "--mem-size=100"}, flags)
+}
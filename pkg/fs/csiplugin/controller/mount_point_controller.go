@@ -18,6 +18,8 @@ package controller
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -33,6 +35,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
+	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/csiplugin/client/k8s"
 	"paddleflow/pkg/fs/csiplugin/client/pfs"
 	"paddleflow/pkg/fs/utils/common"
@@ -64,6 +67,21 @@ type MountPointController struct {
 
 	queue                workqueue.RateLimitingInterface
 	fsMountParametersMap map[string]pfs.FSMountParameter
+	// volumeHandles are the volume ids of the pvs, by which the staging paths are recorded
+	volumeHandles map[string]string
+	// sharedMounts counts the pod volumes by the shared mounts staging them, built with podMap
+	sharedMounts map[string]*sharedMount
+	// unusedSharedMounts are found unused by the last check, which are unmounted if still unused, so
+	// that the ones being staged are not unmounted
+	unusedSharedMounts map[string]bool
+}
+
+// sharedMount is the fuse mount shared by the volumes with the same fs, user and mount options on the node
+type sharedMount struct {
+	params       pfs.FSMountParameter
+	volumeMounts []k8s.VolumeMount
+	// stagingPaths are the staging paths of the volumes
+	stagingPaths map[string]string
 }
 
 func GetMountPointController(nodeID string) *MountPointController {
@@ -94,6 +112,8 @@ func Initialize(nodeID string, masterNodesAware bool) *MountPointController {
 		pvLister:             pvInformer.Lister(),
 		pvSynced:             pvInformer.Informer().HasSynced,
 		fsMountParametersMap: make(map[string]pfs.FSMountParameter),
+		volumeHandles:        make(map[string]string),
+		sharedMounts:         make(map[string]*sharedMount),
 	}
 	pvInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: mountPointController.pvAddedUpdated,
@@ -152,6 +172,7 @@ func (m *MountPointController) Start(stopCh <-chan struct{}) {
 			}(pod)
 		}
 		wg.Wait()
+		m.checkSharedMounts()
 
 		select {
 		case <-checkerUpdateChan:
@@ -201,7 +222,128 @@ func (m *MountPointController) UpdatePodMap() error {
 	for _, pv := range pvs.Items {
 		if pv.Spec.CSI != nil && pv.Spec.CSI.Driver == "paddleflowstorage" {
 			m.fsMountParametersMap[pv.Name] = getPFSParameters(pv.Spec.CSI.VolumeAttributes)
+			m.volumeHandles[pv.Name] = pv.Spec.CSI.VolumeHandle
+		}
+	}
+	m.updateSharedMounts()
+	return nil
+}
+
+// updateSharedMounts groups the volume mounts of the running pods by the shared mounts
+func (m *MountPointController) updateSharedMounts() {
+	sharedMounts := make(map[string]*sharedMount)
+	for _, pod := range m.podMap {
+		if !isRunning(pod) {
+			continue
 		}
+		for _, volumeMount := range k8s.GetVolumeMounts(&pod) {
+			params, ok := m.fsMountParametersMap[volumeMount.VolumeName]
+			if !ok {
+				continue
+			}
+			mountInfo := params.GetMountInfo(false)
+			path := common.GetSharedMountPath(mountInfo.SharedMountKey())
+			if _, ok := sharedMounts[path]; !ok {
+				sharedMounts[path] = &sharedMount{params: params, stagingPaths: make(map[string]string)}
+			}
+			sharedMounts[path].volumeMounts = append(sharedMounts[path].volumeMounts, volumeMount)
+			sharedMounts[path].stagingPaths[volumeMount.VolumeName] = common.GetVolumeStagingPath(
+				m.volumeHandles[volumeMount.VolumeName], volumeMount.VolumeName)
+		}
+	}
+	m.sharedMounts = sharedMounts
+}
+
+// checkSharedMounts remounts the disconnected shared mounts used by pods, then binds them to the staging
+// paths and the target paths of the pods again. The shared mounts neither used by pods nor staged are
+// unmounted.
+func (m *MountPointController) checkSharedMounts() {
+	for path, shared := range m.sharedMounts {
+		if !m.CheckIfNeedRemount(path) {
+			continue
+		}
+		log.Infof("shared mount[%s] with %d pod volumes is disconnected, remount it", path, len(shared.volumeMounts))
+		if err := remountShared(path, shared); err != nil {
+			log.Errorf("remount shared mount[%s] failed: %v", path, err)
+		}
+	}
+
+	entries, err := os.ReadDir(common.GetSharedMountRoot())
+	if err != nil {
+		return
+	}
+	unused := make(map[string]bool)
+	defer func() {
+		m.unusedSharedMounts = unused
+	}()
+	for _, entry := range entries {
+		path := filepath.Join(common.GetSharedMountRoot(), entry.Name())
+		if _, ok := m.sharedMounts[path]; ok {
+			continue
+		}
+		isMountPoint, err := mount.IsMountPoint(path)
+		if !isMountPoint {
+			continue
+		}
+		if err == nil {
+			if refs, err := mount.GetMountRefs(path); err != nil || len(refs) > 0 {
+				continue
+			}
+		}
+		if !m.unusedSharedMounts[path] {
+			unused[path] = true
+			continue
+		}
+		log.Infof("shared mount[%s] is not used any more, unmount it", path)
+		if err := mount.CleanUpMountPoint(path); err != nil {
+			log.Errorf("cleanup shared mount[%s] failed: %v", path, err)
+		}
+	}
+}
+
+func remountShared(path string, shared *sharedMount) error {
+	if err := mount.ForceUnmount(path); err != nil {
+		return err
+	}
+	params := shared.params
+	mountInfo := params.GetMountInfo(false)
+	mountInfo.LocalPath = path
+	command, args := mountInfo.GetMountCmd()
+	output, err := mount.ExecCmdWithTimeout(command, args)
+	if err != nil {
+		log.Errorf("exec cmd[%s %v] failed: %v, output[%v]", command, args, err, string(output))
+		return err
+	}
+
+	// the bind mounts of the former fuse are disconnected as well
+	staged := make(map[string]bool)
+	for _, volumeMount := range shared.volumeMounts {
+		stagingPath := shared.stagingPaths[volumeMount.VolumeName]
+		if !staged[stagingPath] {
+			staged[stagingPath] = true
+			if err := rebind(path, stagingPath, false); err != nil {
+				return err
+			}
+		}
+		sourcePath := filepath.Join(stagingPath, params.MountOptions[base.MountSubPath])
+		bindMountPath := common.GetVolumeBindMountPathByPod(volumeMount.PodUID, volumeMount.VolumeName)
+		if err := rebind(sourcePath, bindMountPath, volumeMount.ReadOnly); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func rebind(sourcePath, mountPath string, readOnly bool) error {
+	if ok, _ := mount.IsMountPoint(mountPath); ok {
+		if err := mount.ForceUnmount(mountPath); err != nil {
+			return err
+		}
+	}
+	output, err := mount.ExecMountBind(sourcePath, mountPath, readOnly)
+	if err != nil {
+		log.Errorf("exec mount bind[%s] to [%s] failed: %v, output[%s]", sourcePath, mountPath, err, string(output))
+		return err
 	}
 	return nil
 }
@@ -258,7 +400,8 @@ func (m *MountPointController) CheckAndRemountVolumeMount(volumeMount k8s.Volume
 		}
 		bindMountPath := common.GetVolumeBindMountPathByPod(volumeMount.PodUID, volumeMount.VolumeName)
 		if ok, err := mount.IsMountPoint(bindMountPath); ok && err != nil {
-			if err := remountBind(sourceMountPath, bindMountPath, volumeMount); err != nil {
+			bindSourcePath := filepath.Join(sourceMountPath, fsMountParams.MountOptions[base.MountSubPath])
+			if err := remountBind(bindSourcePath, bindMountPath, volumeMount); err != nil {
 				log.Errorf("remount bind[%s] failed: %v", bindMountPath, err)
 				return fmt.Errorf("remount bind[%s] failed: %v\n", bindMountPath, err)
 			}
@@ -293,7 +436,7 @@ func (m *MountPointController) Remount(params pfs.FSMountParameter, volumeMount
 	}
 
 	// remount source dir
-	mountInfo := pfs.GetMountInfo(params.FSID, params.Server, params.UserID, false)
+	mountInfo := params.GetMountInfo(false)
 	mountInfo.LocalPath = mountPath
 	command, args := mountInfo.GetMountCmd()
 	log.Debugf("begin to exec cmd [%s %v]", command, args)
@@ -355,6 +498,7 @@ func (c *MountPointController) pvAddedUpdated(obj interface{}) {
 	// update pv
 	if pv.Spec.StorageClassName == "paddleflowstorage" {
 		c.fsMountParametersMap[pv.Name] = getPFSParameters(pv.Spec.CSI.VolumeAttributes)
+		c.volumeHandles[pv.Name] = pv.Spec.CSI.VolumeHandle
 	}
 }
 
@@ -363,8 +507,10 @@ func getPFSParameters(params map[string]string) pfs.FSMountParameter {
 	server := params["pfs.server"]
 	userID := params["pfs.user.name"]
 	return pfs.FSMountParameter{
-		FSID:   fsID,
-		Server: server,
-		UserID: userID,
+		FSID:         fsID,
+		Server:       server,
+		UserID:       userID,
+		MountOptions: base.GetMountOptions(params),
+		KernelMount:  pfs.GetKernelMount(params),
 	}
 }
@@ -24,10 +24,13 @@ import (
 	"golang.org/x/net/context"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+
+	"paddleflow/pkg/fs/client/base"
 )
 
 type controllerServer struct {
 	*csicommon.DefaultControllerServer
+	provisioner *provisioner
 }
 
 func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVolumeRequest) (
@@ -43,14 +46,50 @@ func (cs *controllerServer) CreateVolume(ctx context.Context, req *csi.CreateVol
 		return nil, status.Error(codes.InvalidArgument, "Volume Capabilities missing in request")
 	}
 
+	// the mount options in the parameters of storage class are passed to the volume attributes
+	if err := base.ValidateMountOptions(req.GetParameters(), false); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	capacityBytes := req.GetCapacityRange().GetRequiredBytes()
+	volumeContext := req.GetParameters()
+
+	// a storage class referencing a fs provisions a directory in the fs for each volume
+	if _, ok := req.GetParameters()[pfsFSID]; ok {
+		if len(req.GetName()) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "Volume name missing in request")
+		}
+		volume, err := newProvisionedVolume(req.GetName(), req.GetParameters())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		opts, err := getProvisionOptions(req.GetParameters())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		fsMeta, err := cs.provisioner.create(volume, opts, capacityBytes)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "create dir of volume %s failed: %v", req.GetName(), err)
+		}
+
+		volumeID = volume.volumeID()
+		volumeContext = make(map[string]string, len(req.GetParameters())+1)
+		for k, v := range req.GetParameters() {
+			volumeContext[k] = v
+		}
+		volumeContext[base.MountSubPath] = volume.SubPath
+		// the fs passed through to the kernel is mounted by the node plugin directly
+		for k, v := range base.KernelVolumeAttributes(fsMeta) {
+			volumeContext[k] = v
+		}
+	}
 
 	log.Infof("Creating volume %s", volumeID)
 	return &csi.CreateVolumeResponse{
 		Volume: &csi.Volume{
 			VolumeId:      volumeID,
 			CapacityBytes: capacityBytes,
-			VolumeContext: req.GetParameters(),
+			VolumeContext: volumeContext,
 		},
 	}, nil
 }
@@ -70,6 +109,14 @@ func (cs *controllerServer) DeleteVolume(ctx context.Context, req *csi.DeleteVol
 	}
 	log.Infof("Deleting volume %s", volumeID)
 
+	// nothing to delete for the volumes not provisioned by the driver
+	volume, ok := parseVolumeID(volumeID)
+	if !ok {
+		return &csi.DeleteVolumeResponse{}, nil
+	}
+	if err := cs.provisioner.delete(volume); err != nil {
+		return nil, status.Errorf(codes.Internal, "delete dir of volume %s failed: %v", volumeID, err)
+	}
 	return &csi.DeleteVolumeResponse{}, nil
 }
 
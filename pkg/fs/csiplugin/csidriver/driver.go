@@ -17,9 +17,14 @@ limitations under the License.
 package csidriver
 
 import (
+	"time"
+
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/drivers/pkg/csi-common"
 	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/fs/utils/common"
 )
 
 const (
@@ -30,6 +35,7 @@ const (
 type driver struct {
 	csiDriver        *csicommon.CSIDriver
 	nodeId, endpoint string
+	mountPods        *mountPodManager
 }
 
 func NewDriver(nodeID, endpoint string) *driver {
@@ -47,15 +53,30 @@ func NewDriver(nodeID, endpoint string) *driver {
 	}
 }
 
+// EnableMountPod runs pfs-fuse in dedicated mount pods instead of the plugin container, the bind
+// mounts disconnected by the crashed mount pods are recovered in background
+func (d *driver) EnableMountPod(conf config.CSIPlugin) error {
+	mountPods, err := newMountPodManager(d.nodeId, conf)
+	if err != nil {
+		log.Errorf("init mount pod manager failed: %v", err)
+		return err
+	}
+	d.mountPods = mountPods
+	go mountPods.run(time.Duration(common.GetMountPointCheckIntervalTime()) * time.Second)
+	return nil
+}
+
 func (d *driver) newControllerServer() *controllerServer {
 	return &controllerServer{
 		DefaultControllerServer: csicommon.NewDefaultControllerServer(d.csiDriver),
+		provisioner:             newProvisioner(),
 	}
 }
 
 func (d *driver) newNodeServer() *nodeServer {
 	return &nodeServer{
 		nodeId:            d.nodeId,
+		mountPods:         d.mountPods,
 		DefaultNodeServer: csicommon.NewDefaultNodeServer(d.csiDriver),
 	}
 }
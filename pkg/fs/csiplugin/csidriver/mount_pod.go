@@ -0,0 +1,441 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csidriver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/fs/csiplugin/client/k8s"
+	"paddleflow/pkg/fs/csiplugin/client/pfs"
+	"paddleflow/pkg/fs/utils/common"
+	mountUtil "paddleflow/pkg/fs/utils/mount"
+)
+
+const (
+	mountPodLabelApp       = "app"
+	mountPodApp            = "pfs-mount"
+	mountPodContainerName  = "pfs-fuse"
+	mountPodFSIDAnnotation = "pfs.fs.id"
+	// each consumer of the mount pod is recorded as an annotation keyed by the hash of its target path
+	mountPodRefPrefix = "pfs-ref-"
+
+	mountPodPollInterval = time.Second
+)
+
+var (
+	// the env of the plugin passed through to the mount pods, which are read by mount.sh as well
+	mountPodEnvs = []string{"ATTR_VALID_TIME", "ENTRY_VALID_TIME", common.FuseRootEnv}
+	// the env of the mount pods referring to the keys of the mount pod secret, which are kept out of the pod spec
+	mountPodSecretEnvs = []string{common.FusePasswordEnv, common.PeerCacheTokenEnv}
+)
+
+// mountPodManager runs pfs-fuse in a pod per (node, fs) instead of the plugin container, the
+// consumers are reference counted by the annotations of the pod, so that the state survives
+// plugin restarts. The fs is mounted at a host path under the kubelet plugin dir, which is
+// bind mounted to the target paths.
+type mountPodManager struct {
+	client       k8s.K8SInterface
+	nodeID       string
+	namespace    string
+	image        string
+	resources    v1.ResourceRequirements
+	readyTimeout time.Duration
+	secret       string
+	// serializes the updates of the mount pods on this node
+	lock sync.Mutex
+}
+
+func newMountPodManager(nodeID string, conf config.CSIPlugin) (*mountPodManager, error) {
+	if conf.MountPodImage == "" {
+		return nil, fmt.Errorf("mount pod image is not set")
+	}
+	if _, ok := os.LookupEnv(common.FusePasswordEnv); ok && conf.MountPodSecret == "" {
+		return nil, fmt.Errorf("mount pod secret is not set, which passes %s to the mount pods", common.FusePasswordEnv)
+	}
+	resources, err := getMountPodResources(conf)
+	if err != nil {
+		return nil, err
+	}
+	client, err := k8s.GetK8sClient()
+	if err != nil {
+		return nil, err
+	}
+	return &mountPodManager{
+		client:       client,
+		nodeID:       nodeID,
+		namespace:    conf.MountPodNamespace,
+		image:        conf.MountPodImage,
+		resources:    resources,
+		readyTimeout: conf.MountPodReadyTimeout,
+		secret:       conf.MountPodSecret,
+	}, nil
+}
+
+func getMountPodResources(conf config.CSIPlugin) (v1.ResourceRequirements, error) {
+	resources := v1.ResourceRequirements{
+		Requests: v1.ResourceList{},
+		Limits:   v1.ResourceList{},
+	}
+	for _, r := range []struct {
+		list  v1.ResourceList
+		name  v1.ResourceName
+		value string
+	}{
+		{resources.Requests, v1.ResourceCPU, conf.MountPodCPURequest},
+		{resources.Limits, v1.ResourceCPU, conf.MountPodCPULimit},
+		{resources.Requests, v1.ResourceMemory, conf.MountPodMemoryRequest},
+		{resources.Limits, v1.ResourceMemory, conf.MountPodMemoryLimit},
+	} {
+		if r.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(r.value)
+		if err != nil {
+			return resources, fmt.Errorf("parse mount pod %s resource[%s] failed: %v", r.name, r.value, err)
+		}
+		r.list[r.name] = quantity
+	}
+	return resources, nil
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// mountPodName is unique for each node and shared mount key, the volumes of the fs with different
+// mount options are served by different mount pods. They are hashed to fit the pod name.
+func mountPodName(nodeID string, mountInfo pfs.MountInfo) string {
+	return fmt.Sprintf("%s-%s", mountPodApp, hashString(nodeID + "/" + mountInfo.SharedMountKey())[:16])
+}
+
+// mountPodRefKey is the annotation key recording that targetPath consumes the mount pod
+func mountPodRefKey(targetPath string) string {
+	return mountPodRefPrefix + hashString(targetPath)[:32]
+}
+
+// mountPodPath is the host path where the mount pod mounts the fs
+func mountPodPath(name string) string {
+	return filepath.Join(common.GetKubeletDataPath(), "plugins", driverName, "mnt", name)
+}
+
+func mountPodRefs(pod *v1.Pod) int {
+	refs := 0
+	for key := range pod.Annotations {
+		if strings.HasPrefix(key, mountPodRefPrefix) {
+			refs++
+		}
+	}
+	return refs
+}
+
+// mount makes sure the mount pod of the fs is running on the node, and bind mounts the fs to targetPath,
+// which is the staging path of the volume.
+func (m *mountPodManager) mount(mountInfo pfs.MountInfo, targetPath string) error {
+	name := mountPodName(m.nodeID, mountInfo)
+	mountPath := mountPodPath(name)
+	if err := m.addRef(name, mountInfo, mountPath, targetPath); err != nil {
+		log.Errorf("add reference of target[%s] to mount pod[%s] failed: %v", targetPath, name, err)
+		return err
+	}
+	if err := m.waitReady(name, mountPath); err != nil {
+		log.Errorf("wait mount pod[%s] ready failed: %v", name, err)
+		return err
+	}
+	return bindMountVolume(mountPath, targetPath, false)
+}
+
+func (m *mountPodManager) addRef(name string, mountInfo pfs.MountInfo, mountPath, targetPath string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	refKey := mountPodRefKey(targetPath)
+	pod, err := m.client.GetPod(m.namespace, name)
+	if k8serrors.IsNotFound(err) {
+		// the fuse of the former mount pod may be gone without unmounting
+		if ok, err := mountUtil.IsMountPoint(mountPath); ok && err != nil {
+			if err := mountUtil.ForceUnmount(mountPath); err != nil {
+				return err
+			}
+		}
+		pod = m.newMountPod(name, mountInfo, mountPath)
+		pod.Annotations[refKey] = targetPath
+		log.Infof("create mount pod[%s] of fs[%s] on node[%s]", name, mountInfo.FSID, m.nodeID)
+		_, err = m.client.CreatePod(pod)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if pod.DeletionTimestamp != nil {
+		// the kubelet retries the publish after the pod is gone
+		return fmt.Errorf("mount pod[%s] is terminating", name)
+	}
+	if _, ok := pod.Annotations[refKey]; ok {
+		return nil
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[refKey] = targetPath
+	_, err = m.client.UpdatePod(pod)
+	return err
+}
+
+func (m *mountPodManager) waitReady(name, mountPath string) error {
+	deadline := time.Now().Add(m.readyTimeout)
+	for {
+		pod, err := m.client.GetPod(m.namespace, name)
+		if err != nil {
+			return err
+		}
+		if pod.Status.Phase == v1.PodFailed {
+			return fmt.Errorf("mount pod[%s] failed: %s", name, pod.Status.Message)
+		}
+		if isPodReady(pod) {
+			if ok, err := mountUtil.IsMountPoint(mountPath); ok && err == nil {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("mount pod[%s] is not ready in %v", name, m.readyTimeout)
+		}
+		time.Sleep(mountPodPollInterval)
+	}
+}
+
+func isPodReady(pod *v1.Pod) bool {
+	if pod.Status.Phase != v1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == v1.PodReady {
+			return cond.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// unmount drops the reference of targetPath, the mount pod is deleted if it is not used any more.
+// targetPath should have been cleaned up by the caller.
+func (m *mountPodManager) unmount(targetPath string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pods, err := m.listMountPods()
+	if err != nil {
+		return err
+	}
+	refKey := mountPodRefKey(targetPath)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, ok := pod.Annotations[refKey]; !ok {
+			continue
+		}
+		delete(pod.Annotations, refKey)
+		if mountPodRefs(pod) > 0 {
+			_, err = m.client.UpdatePod(pod)
+			return err
+		}
+		log.Infof("delete mount pod[%s] of fs[%s] not used any more", pod.Name, pod.Annotations[mountPodFSIDAnnotation])
+		if err = m.client.DeletePod(pod.Namespace, pod.Name); err != nil && !k8serrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	return nil
+}
+
+func (m *mountPodManager) listMountPods() (*v1.PodList, error) {
+	pods, err := m.client.ListPods(m.namespace, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", mountPodLabelApp, mountPodApp),
+		FieldSelector: fmt.Sprintf("spec.nodeName=%s", m.nodeID),
+	})
+	if err != nil {
+		log.Errorf("list mount pods on node[%s] failed: %v", m.nodeID, err)
+	}
+	return pods, err
+}
+
+// run recovers the mounts of the mount pods every interval until the plugin exits
+func (m *mountPodManager) run(interval time.Duration) {
+	for {
+		m.recoverMounts()
+		time.Sleep(interval)
+	}
+}
+
+// recoverMounts binds the staging paths and the target paths published from them again, which are left
+// disconnected after the fuse of a mount pod crashed, once the fs is mounted by the restarted container.
+func (m *mountPodManager) recoverMounts() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pods, err := m.listMountPods()
+	if err != nil {
+		return
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			continue
+		}
+		mountPath := mountPodPath(pod.Name)
+		if ok, err := mountUtil.IsMountPoint(mountPath); !ok || err != nil {
+			continue
+		}
+		for key, stagingPath := range pod.Annotations {
+			if !strings.HasPrefix(key, mountPodRefPrefix) {
+				continue
+			}
+			if ok, err := mountUtil.IsMountPoint(stagingPath); !ok || err == nil {
+				continue
+			}
+			log.Infof("staging path[%s] of mount pod[%s] is disconnected, bind it again", stagingPath, pod.Name)
+			if err := rebindDeviceMounts(mountPath, stagingPath); err != nil {
+				log.Errorf("recover mounts of staging path[%s] failed: %v", stagingPath, err)
+			}
+		}
+	}
+}
+
+// rebindDeviceMounts binds the mounts sharing the fuse gone with path again from sourcePath, which are
+// path itself and the target paths bind mounted from it.
+func rebindDeviceMounts(sourcePath, path string) error {
+	mounts, err := mountUtil.GetDeviceMounts(path)
+	if err != nil {
+		return err
+	}
+	for _, mnt := range mounts {
+		if err := mountUtil.ForceUnmount(mnt.MountPoint); err != nil {
+			return err
+		}
+		output, err := mountUtil.ExecMountBind(filepath.Join(sourcePath, mnt.Root), mnt.MountPoint, mnt.ReadOnly)
+		if err != nil {
+			log.Errorf("exec mount bind[%s] to [%s] failed: %v, output[%s]", mnt.Root, mnt.MountPoint, err, string(output))
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mountPodManager) newMountPod(name string, mountInfo pfs.MountInfo, mountPath string) *v1.Pod {
+	mountInfo.LocalPath = mountPath
+	privileged := true
+	propagation := v1.MountPropagationBidirectional
+	hostPathType := v1.HostPathDirectoryOrCreate
+	var env []v1.EnvVar
+	for _, key := range mountPodEnvs {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, v1.EnvVar{Name: key, Value: value})
+		}
+	}
+	if m.secret != "" {
+		optional := true
+		for _, key := range mountPodSecretEnvs {
+			env = append(env, v1.EnvVar{Name: key, ValueFrom: &v1.EnvVarSource{
+				SecretKeyRef: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: m.secret},
+					Key:                  key,
+					Optional:             &optional,
+				},
+			}})
+		}
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   m.namespace,
+			Labels:      map[string]string{mountPodLabelApp: mountPodApp},
+			Annotations: map[string]string{mountPodFSIDAnnotation: mountInfo.FSID},
+		},
+		Spec: v1.PodSpec{
+			NodeName:      m.nodeID,
+			RestartPolicy: v1.RestartPolicyAlways,
+			// runs wherever the plugin runs
+			Tolerations: []v1.Toleration{{Operator: v1.TolerationOpExists}},
+			Containers: []v1.Container{{
+				Name:      mountPodContainerName,
+				Image:     m.image,
+				Command:   []string{"sh", "-c", mountPodCmd(mountPath, mountInfo.GetFuseArgs())},
+				Env:       env,
+				Resources: m.resources,
+				SecurityContext: &v1.SecurityContext{
+					Privileged: &privileged,
+				},
+				VolumeMounts: []v1.VolumeMount{{
+					Name:             "mnt",
+					MountPath:        mountPath,
+					MountPropagation: &propagation,
+				}},
+				ReadinessProbe: &v1.Probe{
+					Handler: v1.Handler{
+						Exec: &v1.ExecAction{Command: []string{mountUtil.MountPointCmdName, "-q", mountPath}},
+					},
+					PeriodSeconds: 2,
+				},
+				// pfs-fuse exits after unmounted, otherwise the host path is left disconnected
+				Lifecycle: &v1.Lifecycle{
+					PreStop: &v1.Handler{
+						Exec: &v1.ExecAction{Command: []string{mountUtil.UMountCmdName, mountPath}},
+					},
+				},
+			}},
+			Volumes: []v1.Volume{{
+				Name: "mnt",
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{
+						Path: mountPath,
+						Type: &hostPathType,
+					},
+				},
+			}},
+		},
+	}
+}
+
+// mountPodCmd runs pfs-fuse with the same defaults as mount.sh, the mount point left disconnected
+// by the crashed container is unmounted before restarting.
+func mountPodCmd(mountPath string, args []string) string {
+	cmd := []string{
+		"./pfs-fuse",
+		"--attr-timeout=${ATTR_VALID_TIME:-1}",
+		"--entry-timeout=${ENTRY_VALID_TIME:-1}",
+		"--user-name=\"${FUSE_ROOT:-root}\"",
+		"--password=\"${FUSE_PASSWORD:-paddleflow}\"",
+	}
+	for _, arg := range args {
+		cmd = append(cmd, shellQuote(arg))
+	}
+	return fmt.Sprintf("%s -l %s 2>/dev/null; exec %s", mountUtil.UMountCmdName, shellQuote(mountPath),
+		strings.Join(cmd, " "))
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
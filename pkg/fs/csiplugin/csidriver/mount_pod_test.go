@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csidriver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"paddleflow/pkg/fs/csiplugin/client/k8s"
+	"paddleflow/pkg/fs/csiplugin/client/pfs"
+	"paddleflow/pkg/fs/utils/common"
+)
+
+// fakeK8SClient keeps the pods in memory, the other resources are not used by the mount pods
+type fakeK8SClient struct {
+	k8s.K8SInterface
+	pods map[string]*v1.Pod
+}
+
+func (c *fakeK8SClient) GetPod(namespace, name string) (*v1.Pod, error) {
+	pod, ok := c.pods[namespace+"/"+name]
+	if !ok {
+		return nil, k8serrors.NewNotFound(v1.Resource("pods"), name)
+	}
+	return pod.DeepCopy(), nil
+}
+
+func (c *fakeK8SClient) ListPods(namespace string, listOptions metav1.ListOptions) (*v1.PodList, error) {
+	pods := &v1.PodList{}
+	for _, pod := range c.pods {
+		if pod.Namespace == namespace {
+			pods.Items = append(pods.Items, *pod.DeepCopy())
+		}
+	}
+	return pods, nil
+}
+
+func (c *fakeK8SClient) CreatePod(pod *v1.Pod) (*v1.Pod, error) {
+	c.pods[pod.Namespace+"/"+pod.Name] = pod.DeepCopy()
+	return pod, nil
+}
+
+func (c *fakeK8SClient) UpdatePod(pod *v1.Pod) (*v1.Pod, error) {
+	c.pods[pod.Namespace+"/"+pod.Name] = pod.DeepCopy()
+	return pod, nil
+}
+
+func (c *fakeK8SClient) DeletePod(namespace, name string) error {
+	delete(c.pods, namespace+"/"+name)
+	return nil
+}
+
+func TestMountPodRefs(t *testing.T) {
+	client := &fakeK8SClient{pods: make(map[string]*v1.Pod)}
+	m := &mountPodManager{client: client, nodeID: "node1", namespace: "paddleflow", image: "pfs-fuse", secret: "pfs-mount"}
+	mountInfo := pfs.GetMountInfo("fs-root-test", "127.0.0.1:8082", "root", false, nil)
+	name := mountPodName(m.nodeID, mountInfo)
+	mountPath := mountPodPath(name)
+
+	// the first volume creates the mount pod
+	assert.Nil(t, m.addRef(name, mountInfo, mountPath, "/staging/pv1"))
+	pod, err := client.GetPod(m.namespace, name)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, mountPodRefs(pod))
+	assert.Equal(t, "fs-root-test", pod.Annotations[mountPodFSIDAnnotation])
+	// the secrets are referred by the pod instead of kept in the spec
+	container := pod.Spec.Containers[0]
+	for _, env := range container.Env {
+		if env.Name == common.FusePasswordEnv || env.Name == common.PeerCacheTokenEnv {
+			assert.Empty(t, env.Value)
+			assert.Equal(t, "pfs-mount", env.ValueFrom.SecretKeyRef.Name)
+			assert.Equal(t, env.Name, env.ValueFrom.SecretKeyRef.Key)
+		}
+	}
+	assert.True(t, strings.Contains(container.Command[2], "${FUSE_PASSWORD:-paddleflow}"))
+
+	// the other volumes reuse it, and adding the same volume again is a no-op
+	assert.Nil(t, m.addRef(name, mountInfo, mountPath, "/staging/pv2"))
+	assert.Nil(t, m.addRef(name, mountInfo, mountPath, "/staging/pv2"))
+	assert.Equal(t, 1, len(client.pods))
+	pod, _ = client.GetPod(m.namespace, name)
+	assert.Equal(t, 2, mountPodRefs(pod))
+
+	// the mount pod is deleted after the last volume released
+	assert.Nil(t, m.unmount("/staging/pv1"))
+	pod, _ = client.GetPod(m.namespace, name)
+	assert.Equal(t, 1, mountPodRefs(pod))
+	assert.Nil(t, m.unmount("/staging/pv1"))
+	assert.Nil(t, m.unmount("/staging/pv2"))
+	assert.Equal(t, 0, len(client.pods))
+
+	// the terminating mount pod is not reused
+	assert.Nil(t, m.addRef(name, mountInfo, mountPath, "/staging/pv1"))
+	client.pods[m.namespace+"/"+name].DeletionTimestamp = &metav1.Time{}
+	assert.NotNil(t, m.addRef(name, mountInfo, mountPath, "/staging/pv2"))
+}
@@ -19,6 +19,9 @@ package csidriver
 import (
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/container-storage-interface/spec/lib/go/csi"
 	"github.com/kubernetes-csi/drivers/pkg/csi-common"
@@ -27,6 +30,8 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"paddleflow/pkg/fs/client/base"
+	fsclient "paddleflow/pkg/fs/client/fs"
 	"paddleflow/pkg/fs/csiplugin/client/pfs"
 	"paddleflow/pkg/fs/utils/common"
 	"paddleflow/pkg/fs/utils/io"
@@ -37,10 +42,23 @@ const (
 	pfsFSID     = "pfs.fs.id"
 	pfsServer   = "pfs.server"
 	pfsUserName = "pfs.user.name"
+	// the path in fs to warm up before the container starts
+	pfsWarmupPath        = "pfs.warmup.path"
+	pfsWarmupConcurrency = "pfs.warmup.concurrency"
+	// maxWarmups is the number of volumes warmed up at the same time on the node
+	maxWarmups = 4
+)
+
+var (
+	// warmupSlots bounds the running warmups, and warmups holds the target paths being warmed up
+	warmupSlots = make(chan struct{}, maxWarmups)
+	warmups     sync.Map
 )
 
 type nodeServer struct {
 	nodeId string
+	// mountPods is set if pfs-fuse runs in mount pods rather than the plugin
+	mountPods *mountPodManager
 	*csicommon.DefaultNodeServer
 }
 
@@ -70,20 +88,57 @@ func (ns *nodeServer) NodePublishVolume(ctx context.Context,
 	}
 
 	volumeContext := req.GetVolumeContext()
-	fsId := volumeContext[pfsFSID]
-	server := volumeContext[pfsServer]
-	userName := volumeContext[pfsUserName]
-
-	mountInfo := pfs.GetMountInfo(fsId, server, userName, req.GetReadonly())
-	pathPrefix := filepath.Dir(targetPath)
-	if err := mountVolume(pathPrefix, mountInfo, req.GetReadonly()); err != nil {
-		log.Errorf("mount filesystem[%s] with server[%s] failed: %v", fsId, server, err)
+	// the volume of a fs shared read-only is published read-only whatever the pod asks for
+	readOnly := req.GetReadonly() || base.IsReadOnly(volumeContext)
+	mountInfo, err := getMountInfo(volumeContext, readOnly)
+	if err != nil {
+		log.Errorf("validate mount options of volume[%s] failed: %v", req.GetVolumeId(), err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the fs is mounted once on the node by NodeStageVolume, the pods get bind mounts of the sub path
+	stagingPath := req.GetStagingTargetPath()
+	if stagingPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
+	sourcePath := filepath.Join(stagingPath, mountInfo.GetSubPath())
+	if err := bindMountVolume(sourcePath, targetPath, readOnly); err != nil {
+		log.Errorf("bind mount volume[%s] from staging path[%s] failed: %v", req.GetVolumeId(), sourcePath, err)
 		return &csi.NodePublishVolumeResponse{}, status.Error(codes.Internal, err.Error())
 	}
 
+	if warmupPath := volumeContext[pfsWarmupPath]; warmupPath != "" {
+		concurrency, _ := strconv.Atoi(volumeContext[pfsWarmupConcurrency])
+		// warmup runs in background, the pod starts without waiting for the data loaded
+		go warmupVolume(targetPath, warmupPath, concurrency)
+	}
+
 	return &csi.NodePublishVolumeResponse{}, nil
 }
 
+// warmupVolume loads the data under warmupPath into the cache of the mount, failure of warmup
+// does not block the pod from starting. A warmup of the mount already running or waiting is skipped.
+func warmupVolume(mountPath, warmupPath string, concurrency int) {
+	root := filepath.Join(mountPath, filepath.Clean("/"+warmupPath))
+	if _, running := warmups.LoadOrStore(mountPath, struct{}{}); running {
+		log.Infof("warmup of mount[%s] is running, skip path[%s]", mountPath, root)
+		return
+	}
+	defer warmups.Delete(mountPath)
+	warmupSlots <- struct{}{}
+	defer func() { <-warmupSlots }()
+
+	log.Infof("warmup path[%s] start", root)
+	start := time.Now()
+	stats, err := fsclient.WarmupLocal(root, concurrency, nil)
+	if err != nil {
+		log.Errorf("warmup path[%s] failed: %v", root, err)
+		return
+	}
+	log.Infof("warmup path[%s] finished in %v: files[%d] bytes[%d] failed[%d]",
+		root, time.Since(start), stats.Files, stats.Bytes, stats.Failed)
+}
+
 func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context,
 	req *csi.NodeUnpublishVolumeRequest) (*csi.NodeUnpublishVolumeResponse, error) {
 	targetPath := req.GetTargetPath()
@@ -103,16 +158,75 @@ func (ns *nodeServer) NodeUnpublishVolume(ctx context.Context,
 	return &csi.NodeUnpublishVolumeResponse{}, nil
 }
 
+// NodeStageVolume mounts the fs at the staging path, which is shared by the pods of the volume on
+// the node. The fuse mount itself is shared by the volumes with the same fs, user and mount options.
 func (ns *nodeServer) NodeStageVolume(ctx context.Context,
 	req *csi.NodeStageVolumeRequest) (*csi.NodeStageVolumeResponse, error) {
-	log.Debugf("start to stage volume[%s], do nothing...", req.GetVolumeId())
-	return &csi.NodeStageVolumeResponse{}, nil
+	log.Debugf("Node stage volume request [%+v]", *req)
+	stagingPath := req.GetStagingTargetPath()
+	if stagingPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
+	mountInfo, err := getMountInfo(req.GetVolumeContext(), false)
+	if err != nil {
+		log.Errorf("validate mount options of volume[%s] failed: %v", req.GetVolumeId(), err)
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	// the staging path is recorded for the mount point controller to recover the volume mounts,
+	// which differs across kubelet versions
+	if err := common.RecordVolumeStagingPath(req.GetVolumeId(), stagingPath); err != nil {
+		log.Errorf("record staging path[%s] of volume[%s] failed: %v", stagingPath, req.GetVolumeId(), err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if ok, err := mountUtil.IsMountPoint(stagingPath); ok && err == nil {
+		log.Debugf("volume[%s] is staged at [%s] already", req.GetVolumeId(), stagingPath)
+		return &csi.NodeStageVolumeResponse{}, nil
+	} else if ok {
+		// the staging path is left disconnected by the fuse gone
+		if err := mountUtil.ForceUnmount(stagingPath); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	if err := os.MkdirAll(stagingPath, 0750); err != nil {
+		log.Errorf("create staging path[%s] failed: %v", stagingPath, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 
+	// the fs passed through to the kernel needs no mount pod as no process serves the mount
+	if ns.mountPods != nil && mountInfo.KernelMount == nil {
+		err = ns.mountPods.mount(mountInfo, stagingPath)
+	} else {
+		err = mountSharedVolume(mountInfo, stagingPath)
+	}
+	if err != nil {
+		log.Errorf("stage volume[%s] of filesystem[%s] failed: %v", req.GetVolumeId(), mountInfo.FSID, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &csi.NodeStageVolumeResponse{}, nil
 }
 
 func (ns *nodeServer) NodeUnstageVolume(ctx context.Context,
 	req *csi.NodeUnstageVolumeRequest) (*csi.NodeUnstageVolumeResponse, error) {
-	log.Debugf("start to unstage volume[%s], do nothing...", req.GetVolumeId())
+	log.Debugf("Node unstage volume request [%+v]", *req)
+	stagingPath := req.GetStagingTargetPath()
+	if stagingPath == "" {
+		return nil, status.Error(codes.InvalidArgument, "staging target path missing in request")
+	}
+	// the kernel mounts are shared by the plugin itself even if pfs-fuse runs in mount pods
+	if err := unmountSharedVolume(stagingPath); err != nil {
+		log.Errorf("[UnstageVolume]: unmount staging path[%s] err: %v", stagingPath, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if ns.mountPods != nil {
+		if err := ns.mountPods.unmount(stagingPath); err != nil {
+			log.Errorf("[UnstageVolume]: release mount pod of staging path[%s] err: %v", stagingPath, err)
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+	if err := common.RemoveVolumeStagingPath(req.GetVolumeId()); err != nil {
+		log.Errorf("[UnstageVolume]: remove staging path record of volume[%s] err: %v", req.GetVolumeId(), err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
 	return &csi.NodeUnstageVolumeResponse{}, nil
 }
 
@@ -121,25 +235,69 @@ func (ns *nodeServer) NodeExpandVolume(ctx context.Context,
 	return nil, status.Error(codes.Unimplemented, "NodeExpandVolume is not implemented")
 }
 
-func mountVolume(mountPathPrefix string, mountInfo pfs.MountInfo, readOnly bool) error {
-	// business pods use a separate source path
-	volumeSourceMountPath := common.GetVolumeSourceMountPath(mountPathPrefix)
-	if err := os.MkdirAll(volumeSourceMountPath, 0750); err != nil {
-		log.Errorf("mkdir [%s] failed: %v", volumeSourceMountPath, err)
-		return err
+func getMountInfo(volumeContext map[string]string, readOnly bool) (pfs.MountInfo, error) {
+	if err := base.ValidateMountOptions(volumeContext, false); err != nil {
+		return pfs.MountInfo{}, err
+	}
+	mountInfo := pfs.GetMountInfo(volumeContext[pfsFSID], volumeContext[pfsServer], volumeContext[pfsUserName], readOnly,
+		base.GetMountOptions(volumeContext))
+	mountInfo.KernelMount = pfs.GetKernelMount(volumeContext)
+	return mountInfo, nil
+}
+
+// mountSharedVolume mounts the fs by pfs-fuse or the kernel at the shared mount path of the node if
+// not mounted yet, and bind mounts it to the staging path
+func mountSharedVolume(mountInfo pfs.MountInfo, stagingPath string) error {
+	sharedPath := common.GetSharedMountPath(mountInfo.SharedMountKey())
+	ok, err := mountUtil.IsMountPoint(sharedPath)
+	if ok && err != nil {
+		log.Infof("shared mount[%s] is disconnected, mount it again", sharedPath)
+		if err := mountUtil.ForceUnmount(sharedPath); err != nil {
+			return err
+		}
 	}
-	mountInfo.LocalPath = volumeSourceMountPath
+	if !ok || err != nil {
+		if err := os.MkdirAll(sharedPath, 0750); err != nil {
+			log.Errorf("mkdir [%s] failed: %v", sharedPath, err)
+			return err
+		}
+		mountInfo.LocalPath = sharedPath
+		cmdName, args := mountInfo.GetMountCmd()
+		log.Infof("mountInfo GetMountCmd[%s %v] filesystem ID[%v] in pfs server[%v]", cmdName, args, mountInfo.FSID, mountInfo.Server)
+		output, err := mountUtil.ExecCmdWithTimeout(cmdName, args)
+		if err != nil {
+			log.Errorf("exec mount failed: [%v], output[%v]", err, string(output))
+			return err
+		}
+	}
+	return bindMountVolume(sharedPath, stagingPath, false)
+}
 
-	cmdName, args := mountInfo.GetMountCmd()
-	log.Infof("mountInfo GetMountCmd[%s %v] filesystem ID[%v] in pfs server[%v]", cmdName, args, mountInfo.FSID, mountInfo.Server)
-	output, err := mountUtil.ExecCmdWithTimeout(cmdName, args)
+// unmountSharedVolume unmounts the staging path, and the shared mount behind it if no other volume
+// is staged from it
+func unmountSharedVolume(stagingPath string) error {
+	refs, err := mountUtil.GetMountRefs(stagingPath)
 	if err != nil {
-		log.Errorf("exec mount failed: [%v], output[%v]", err, string(output))
+		// the shared mount is collected by the mount point controller later
+		log.Warnf("get mount refs of staging path[%s] failed: %v", stagingPath, err)
+	}
+	if err := cleanUpMountPoints([]string{stagingPath}); err != nil {
 		return err
 	}
-
-	volumeBindMountPath := common.GetVolumeMountPath(mountPathPrefix)
-	return bindMountVolume(volumeSourceMountPath, volumeBindMountPath, readOnly)
+	var sharedPath string
+	var others int
+	for _, ref := range refs {
+		if filepath.Dir(ref) == common.GetSharedMountRoot() {
+			sharedPath = ref
+		} else if ref != stagingPath {
+			others++
+		}
+	}
+	if sharedPath == "" || others > 0 {
+		return nil
+	}
+	log.Infof("shared mount[%s] is not used any more, unmount it", sharedPath)
+	return mountUtil.CleanUpMountPoint(sharedPath)
 }
 
 func bindMountVolume(sourcePath, mountPath string, readOnly bool) error {
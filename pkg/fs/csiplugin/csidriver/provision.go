@@ -0,0 +1,309 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csidriver
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/client"
+	"paddleflow/pkg/common/http/api"
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/fs"
+	csiCommon "paddleflow/pkg/fs/utils/common"
+)
+
+const (
+	// the owner and mode of the directory created for each volume
+	pfsProvisionUID  = "pfs.provision.uid"
+	pfsProvisionGID  = "pfs.provision.gid"
+	pfsProvisionMode = "pfs.provision.mode"
+	// if true, the capacity of the volume is set as the quota xattr of its directory
+	pfsProvisionQuota = "pfs.provision.quota"
+	// the directory is kept or deleted by the reclaim policy of the pv, the former parameter is
+	// refused so that the volumes of the storage classes asking for archive are not deleted silently
+	pfsProvisionOnDelete = "pfs.provision.on.delete"
+
+	quotaXAttr           = "user.pfs.quota"
+	defaultProvisionMode = 0755
+	volumeIDSeparator    = "#"
+	// maxVolumeIDLength is the limit of volume id recommended by the csi spec
+	maxVolumeIDLength = 128
+	// provisionClientExpire is the time the fs clients are cached, after which the meta and links of
+	// the fs are fetched again
+	provisionClientExpire = 10 * time.Minute
+)
+
+// provisionedVolume is a directory created in a fs for a dynamically provisioned volume,
+// all that is needed to delete it is encoded in the volume id
+type provisionedVolume struct {
+	FSID   string
+	Server string
+	// SubPath is the directory of the volume relative to the fs root
+	SubPath string
+}
+
+func (v provisionedVolume) volumeID() string {
+	return strings.Join([]string{v.FSID, v.Server, v.SubPath}, volumeIDSeparator)
+}
+
+// parseVolumeID returns false for the volumes that are not provisioned by the driver
+func parseVolumeID(volumeID string) (provisionedVolume, bool) {
+	parts := strings.SplitN(volumeID, volumeIDSeparator, 3)
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return provisionedVolume{}, false
+	}
+	return provisionedVolume{FSID: parts[0], Server: parts[1], SubPath: parts[2]}, true
+}
+
+// newProvisionedVolume returns the volume of name in the fs from the parameters of storage class,
+// the directory of the volume is created under the sub path in the parameters if set
+func newProvisionedVolume(name string, parameters map[string]string) (provisionedVolume, error) {
+	volume := provisionedVolume{
+		FSID:    parameters[pfsFSID],
+		Server:  parameters[pfsServer],
+		SubPath: path.Join(parameters[base.MountSubPath], name),
+	}
+	if volume.FSID == "" || volume.Server == "" {
+		return volume, fmt.Errorf("%s and %s are required", pfsFSID, pfsServer)
+	}
+	if strings.Contains(volume.FSID, volumeIDSeparator) || strings.Contains(volume.Server, volumeIDSeparator) {
+		return volume, fmt.Errorf("%s and %s must not contain %s", pfsFSID, pfsServer, volumeIDSeparator)
+	}
+	if _, ok := parameters[pfsProvisionOnDelete]; ok {
+		return volume, fmt.Errorf("%s is not supported, set the reclaimPolicy of the storage class to Retain to keep "+
+			"the directories of the deleted volumes", pfsProvisionOnDelete)
+	}
+	if id := volume.volumeID(); len(id) > maxVolumeIDLength {
+		return volume, fmt.Errorf("volume id[%s] is longer than %d bytes, shorten %s or %s", id, maxVolumeIDLength,
+			pfsServer, base.MountSubPath)
+	}
+	return volume, nil
+}
+
+type provisionOptions struct {
+	uid, gid int
+	mode     os.FileMode
+	quota    bool
+}
+
+func getProvisionOptions(parameters map[string]string) (provisionOptions, error) {
+	opts := provisionOptions{
+		uid:  csiCommon.GetDefaultUID(),
+		gid:  csiCommon.GetDefaultGID(),
+		mode: defaultProvisionMode,
+	}
+	var err error
+	if value, ok := parameters[pfsProvisionUID]; ok {
+		if opts.uid, err = strconv.Atoi(value); err != nil || opts.uid < 0 {
+			return opts, fmt.Errorf("invalid %s[%s]", pfsProvisionUID, value)
+		}
+	}
+	if value, ok := parameters[pfsProvisionGID]; ok {
+		if opts.gid, err = strconv.Atoi(value); err != nil || opts.gid < 0 {
+			return opts, fmt.Errorf("invalid %s[%s]", pfsProvisionGID, value)
+		}
+	}
+	if value, ok := parameters[pfsProvisionMode]; ok {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil || mode > 0777 {
+			return opts, fmt.Errorf("invalid %s[%s]", pfsProvisionMode, value)
+		}
+		opts.mode = os.FileMode(mode)
+	}
+	if value, ok := parameters[pfsProvisionQuota]; ok {
+		if opts.quota, err = strconv.ParseBool(value); err != nil {
+			return opts, fmt.Errorf("invalid %s[%s]", pfsProvisionQuota, value)
+		}
+	}
+	return opts, nil
+}
+
+// provisionClient is a fs client cached until expire, it is closed when it is evicted and no longer used
+type provisionClient struct {
+	fsClient fs.FSClient
+	fsMeta   base.FSMeta
+	expire   time.Time
+	users    int
+	evicted  bool
+}
+
+// provisioner creates and deletes the directories of volumes through the fs clients, which are
+// cached as creating one builds the whole vfs of the fs. The clients are created again after
+// expire or failure, so that the changes of the fs, e.g. the rotated credentials, are picked up.
+type provisioner struct {
+	sync.Mutex
+	clients map[string]*provisionClient
+	// newClient logs in to the server and creates the fs client
+	newClient func(server, fsID string) (fs.FSClient, base.FSMeta, error)
+}
+
+func newProvisioner() *provisioner {
+	return &provisioner{clients: make(map[string]*provisionClient), newClient: newProvisionClient}
+}
+
+// getClient returns the cached client of the fs, which must be released after use
+func (p *provisioner) getClient(server, fsID string) (*provisionClient, base.FSMeta, error) {
+	p.Lock()
+	defer p.Unlock()
+	key := server + volumeIDSeparator + fsID
+	if c, ok := p.clients[key]; ok {
+		if time.Now().Before(c.expire) {
+			c.users++
+			return c, c.fsMeta, nil
+		}
+		p.evict(key, c)
+	}
+	fsClient, fsMeta, err := p.newClient(server, fsID)
+	if err != nil {
+		return nil, fsMeta, err
+	}
+	c := &provisionClient{fsClient: fsClient, fsMeta: fsMeta, expire: time.Now().Add(provisionClientExpire), users: 1}
+	p.clients[key] = c
+	return c, fsMeta, nil
+}
+
+// release returns the client got by getClient, the client is dropped if failed is true, so that it is
+// created again by the next request
+func (p *provisioner) release(server, fsID string, c *provisionClient, failed bool) {
+	p.Lock()
+	defer p.Unlock()
+	c.users--
+	if failed {
+		p.evict(server+volumeIDSeparator+fsID, c)
+	} else if c.evicted && c.users == 0 {
+		c.fsClient.Close()
+	}
+}
+
+// evict drops the client from the cache, and closes it if it is not used, otherwise it is closed by the
+// last release
+func (p *provisioner) evict(key string, c *provisionClient) {
+	if p.clients[key] == c {
+		delete(p.clients, key)
+	}
+	if c.evicted {
+		return
+	}
+	c.evicted = true
+	if c.users == 0 {
+		c.fsClient.Close()
+	}
+}
+
+func newProvisionClient(server, fsID string) (fs.FSClient, base.FSMeta, error) {
+	userName, password := csiCommon.GetFuseRootEnv()
+	httpClient := client.NewHttpClient(server, client.DefaultTimeOut)
+	loginResponse, err := api.LoginRequest(api.LoginParams{UserName: userName, Password: password}, httpClient)
+	if err != nil {
+		log.Errorf("login to server[%s] failed: %v", server, err)
+		return nil, base.FSMeta{}, err
+	}
+	baseClient, err := base.NewClient(fsID, httpClient, userName, loginResponse.Authorization)
+	if err != nil {
+		log.Errorf("init client with fs[%s] and server[%s] failed: %v", fsID, server, err)
+		return nil, base.FSMeta{}, err
+	}
+	fsMeta, err := baseClient.GetFSMeta()
+	if err != nil {
+		log.Errorf("get fs[%s] meta from server[%s] failed: %v", fsID, server, err)
+		return nil, base.FSMeta{}, err
+	}
+	baseClient.FsName = fsMeta.Name
+	links, err := baseClient.GetLinks()
+	if err != nil {
+		log.Errorf("get fs[%s] links from server[%s] failed: %v", fsID, server, err)
+		return nil, fsMeta, err
+	}
+	fsClient, err := fs.NewFSClient(fsMeta, links)
+	if err != nil {
+		log.Errorf("new fs client of fs[%s] failed: %v", fsID, err)
+		return nil, fsMeta, err
+	}
+	return fsClient, fsMeta, nil
+}
+
+// create makes the directory of volume and returns the meta of its fs, it is idempotent as the request
+// may be retried
+func (p *provisioner) create(volume provisionedVolume, opts provisionOptions, capacityBytes int64) (base.FSMeta, error) {
+	c, fsMeta, err := p.getClient(volume.Server, volume.FSID)
+	if err != nil {
+		return fsMeta, err
+	}
+	err = makeVolumeDir(c.fsClient, volume, opts, capacityBytes)
+	p.release(volume.Server, volume.FSID, c, err != nil)
+	return fsMeta, err
+}
+
+func makeVolumeDir(fsClient fs.FSClient, volume provisionedVolume, opts provisionOptions, capacityBytes int64) error {
+	dir := "/" + volume.SubPath
+	if err := fsClient.MkdirAll(dir, opts.mode); err != nil {
+		log.Errorf("create dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+		return err
+	}
+	if err := fsClient.Chmod(dir, opts.mode); err != nil {
+		log.Errorf("chmod dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+		return err
+	}
+	if err := fsClient.Chown(dir, opts.uid, opts.gid); err != nil {
+		log.Errorf("chown dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+		return err
+	}
+	if opts.quota && capacityBytes > 0 {
+		if err := fsClient.SetXAttr(dir, quotaXAttr, []byte(strconv.FormatInt(capacityBytes, 10))); err != nil {
+			log.Errorf("set quota of dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// delete removes the directory of volume, which is called only for the pvs with the Delete reclaim policy
+func (p *provisioner) delete(volume provisionedVolume) error {
+	c, _, err := p.getClient(volume.Server, volume.FSID)
+	if err != nil {
+		return err
+	}
+	err = removeVolumeDir(c.fsClient, volume)
+	p.release(volume.Server, volume.FSID, c, err != nil)
+	return err
+}
+
+func removeVolumeDir(fsClient fs.FSClient, volume provisionedVolume) error {
+	dir := "/" + volume.SubPath
+	exist, err := fsClient.Exist(dir)
+	if err != nil {
+		log.Errorf("check dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+		return err
+	}
+	if !exist {
+		log.Infof("dir[%s] in fs[%s] not exist, skip", dir, volume.FSID)
+		return nil
+	}
+	if err := fsClient.RemoveAll(dir); err != nil {
+		log.Errorf("remove dir[%s] in fs[%s] failed: %v", dir, volume.FSID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package csidriver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/client/fs"
+)
+
+func TestProvisionedVolumeID(t *testing.T) {
+	parameters := map[string]string{pfsFSID: "fs-root-test", pfsServer: "127.0.0.1:8082", base.MountSubPath: "volumes"}
+	volume, err := newProvisionedVolume("pvc-1", parameters)
+	assert.Nil(t, err)
+	assert.Equal(t, "fs-root-test#127.0.0.1:8082#volumes/pvc-1", volume.volumeID())
+	parsed, ok := parseVolumeID(volume.volumeID())
+	assert.True(t, ok)
+	assert.Equal(t, volume, parsed)
+
+	// the volumes not provisioned by the driver
+	_, ok = parseVolumeID("0a6c3a5e-0fd5-4b34-8ec9-3b8e8e3a1d52")
+	assert.False(t, ok)
+
+	// the volume id is limited as the csi spec recommends
+	parameters[base.MountSubPath] = strings.Repeat("a", maxVolumeIDLength)
+	_, err = newProvisionedVolume("pvc-1", parameters)
+	assert.NotNil(t, err)
+
+	// the directories are kept by the reclaim policy instead
+	parameters[base.MountSubPath] = "volumes"
+	parameters[pfsProvisionOnDelete] = "archive"
+	_, err = newProvisionedVolume("pvc-1", parameters)
+	assert.NotNil(t, err)
+
+	_, err = newProvisionedVolume("pvc-1", map[string]string{pfsFSID: "fs-root-test"})
+	assert.NotNil(t, err)
+	_, err = newProvisionedVolume("pvc-1", map[string]string{pfsFSID: "fs#root", pfsServer: "127.0.0.1:8082"})
+	assert.NotNil(t, err)
+}
+
+func TestProvisionOptions(t *testing.T) {
+	opts, err := getProvisionOptions(map[string]string{pfsProvisionUID: "1000", pfsProvisionMode: "0750",
+		pfsProvisionQuota: "true"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, opts.uid)
+	assert.Equal(t, os.FileMode(0750), opts.mode)
+	assert.True(t, opts.quota)
+
+	for _, parameters := range []map[string]string{
+		{pfsProvisionUID: "-1"},
+		{pfsProvisionGID: "a"},
+		{pfsProvisionMode: "0999"},
+		{pfsProvisionQuota: "yes"},
+	} {
+		_, err = getProvisionOptions(parameters)
+		assert.NotNil(t, err)
+	}
+}
+
+// closeCountingClient counts the clients closed
+type closeCountingClient struct {
+	fs.FSClient
+	closed *int
+}
+
+func (c closeCountingClient) Close() {
+	*c.closed++
+	c.FSClient.Close()
+}
+
+func TestProvisioner(t *testing.T) {
+	root := t.TempDir()
+	fsMeta := base.FSMeta{
+		ID:         "fs-root-test",
+		UfsType:    base.LocalType,
+		Properties: map[string]string{base.RootKey: root},
+		SubPath:    root,
+	}
+	fs.DiskCachePath = filepath.Join(t.TempDir(), "cache")
+	// the memory cache is allocated by its size
+	memCacheSize, memCacheExpire := fs.MemCacheSize, fs.MemCacheExpire
+	fs.SetMemCache(1<<10, memCacheExpire)
+	defer fs.SetMemCache(memCacheSize, memCacheExpire)
+	logins, closed := 0, 0
+	p := newProvisioner()
+	p.newClient = func(server, fsID string) (fs.FSClient, base.FSMeta, error) {
+		logins++
+		if fsID != fsMeta.ID {
+			return nil, base.FSMeta{}, fmt.Errorf("fs[%s] not found", fsID)
+		}
+		fsClient, err := fs.NewFSClientForTest(fsMeta)
+		if err != nil {
+			return nil, fsMeta, err
+		}
+		return closeCountingClient{FSClient: fsClient, closed: &closed}, fsMeta, nil
+	}
+
+	volume := provisionedVolume{FSID: fsMeta.ID, Server: "127.0.0.1:8082", SubPath: "volumes/pvc-1"}
+	opts := provisionOptions{uid: os.Getuid(), gid: os.Getgid(), mode: 0750}
+	meta, err := p.create(volume, opts, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, fsMeta.ID, meta.ID)
+	info, err := os.Stat(filepath.Join(root, volume.SubPath))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0750), info.Mode().Perm())
+	// creating again is idempotent
+	_, err = p.create(volume, opts, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, logins)
+
+	assert.Equal(t, 0, closed)
+
+	// the client is created again after expire, and the expired one is closed
+	key := volume.Server + volumeIDSeparator + volume.FSID
+	p.clients[key].expire = time.Now()
+	assert.Nil(t, p.delete(volume))
+	assert.Equal(t, 2, logins)
+	assert.Equal(t, 1, closed)
+	_, err = os.Stat(filepath.Join(root, volume.SubPath))
+	assert.True(t, os.IsNotExist(err))
+	// deleting again is a no-op
+	assert.Nil(t, p.delete(volume))
+
+	// the client used by a request is closed by its release after expire
+	c, _, err := p.getClient(volume.Server, volume.FSID)
+	assert.Nil(t, err)
+	p.clients[key].expire = time.Now()
+	assert.Nil(t, p.delete(volume))
+	assert.Equal(t, 3, logins)
+	assert.Equal(t, 1, closed)
+	p.release(volume.Server, volume.FSID, c, false)
+	assert.Equal(t, 2, closed)
+
+	// the client failed is closed and created again
+	c, _, err = p.getClient(volume.Server, volume.FSID)
+	assert.Nil(t, err)
+	p.release(volume.Server, volume.FSID, c, true)
+	assert.Equal(t, 3, closed)
+	_, ok := p.clients[key]
+	assert.False(t, ok)
+
+	// the failed client is not cached
+	volume.FSID = "fs-root-missing"
+	_, err = p.create(volume, opts, 0)
+	assert.NotNil(t, err)
+	_, ok = p.clients[volume.Server+volumeIDSeparator+volume.FSID]
+	assert.False(t, ok)
+}
@@ -53,7 +53,12 @@ const (
 	InvalidPVClaimsParams       = "InvalidPVClaimsParams"
 	NamespaceNotFound           = "NamespaceNotFound"
 	GetNamespaceFail            = "GetNamespaceFail"
-	LinkMetaPersistError        = "LinkMetaPersistError"
+	InvalidFileLock             = "InvalidFileLock"
+	InvalidMountOptions         = "InvalidMountOptions"
+	InvalidLinkWatch            = "InvalidLinkWatch"
+	InvalidFsShare              = "InvalidFsShare"
+	FsShareNotExist             = "FsShareNotExist"
+	FsAccessDenied              = "FsAccessDenied"
 )
 
 var errorHTTPStatus = map[string]int{
@@ -87,7 +92,12 @@ var errorHTTPStatus = map[string]int{
 	InvalidPVClaimsParams:       http.StatusBadRequest,
 	NamespaceNotFound:           http.StatusBadRequest,
 	GetNamespaceFail:            http.StatusInternalServerError,
-	LinkMetaPersistError:        http.StatusBadRequest,
+	InvalidFileLock:             http.StatusBadRequest,
+	InvalidMountOptions:         http.StatusBadRequest,
+	InvalidLinkWatch:            http.StatusBadRequest,
+	InvalidFsShare:              http.StatusBadRequest,
+	FsShareNotExist:             http.StatusBadRequest,
+	FsAccessDenied:              http.StatusForbidden,
 }
 
 var errorMessage = map[string]string{
@@ -122,6 +132,12 @@ var errorMessage = map[string]string{
 	InvalidPVClaimsParams:      "Invalid persistent volume claims params",
 	NamespaceNotFound:          "Namespace not found",
 	GetNamespaceFail:           "Get namespace fail",
+	InvalidFileLock:            "Invalid file lock params",
+	InvalidMountOptions:        "File system mount options wrong.",
+	InvalidLinkWatch:           "Invalid link watch params",
+	InvalidFsShare:             "Invalid file system share params",
+	FsShareNotExist:            "File system share not exist",
+	FsAccessDenied:             "No access to the file system",
 }
 
 type ErrorResponse struct {
@@ -158,6 +174,10 @@ func DbDataNotExitError(errMsg string) error {
 	return errors.New(errMsg)
 }
 
+func FsAccessError(userName, fsID string) error {
+	return fmt.Errorf("User[%s] has no access to the file system[%s]", userName, fsID)
+}
+
 func PVCNotFountError(pvc, namespace string) error {
 	return fmt.Errorf("The pvc[%s] in the namespace[%s] does not exist", pvc, namespace)
 }
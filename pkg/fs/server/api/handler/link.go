@@ -22,6 +22,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	log "github.com/sirupsen/logrus"
@@ -51,14 +52,27 @@ func (lr *LinkRouter) AddRouter(r chi.Router) {
 	r.Post("/link", lr.CreateLink)
 	r.Delete("/link/{fsName}", lr.DeleteLink)
 	r.Get("/link/{fsName}", lr.GetLink)
-
+	r.Get("/link/{fsName}/watch", lr.WatchLink)
 }
 
+const (
+	DefaultLinkWatchTimeout = 60 * time.Second
+	MaxLinkWatchTimeout     = 180 * time.Second
+)
+
 var SupportLinkURLPrefix = map[string]bool{
-	fs.HDFS:  true,
-	fs.Local: true,
-	fs.S3:    true,
-	fs.SFTP:  true,
+	fs.HDFS:   true,
+	fs.Local:  true,
+	fs.S3:     true,
+	fs.SFTP:   true,
+	fs.GCS:    true,
+	fs.Azblob: true,
+	fs.NFS:    true,
+	fs.CephFS: true,
+	fs.Lustre: true,
+	fs.WebDAV: true,
+	fs.HTTP:   true,
+	fs.HTTPS:  true,
 }
 
 // CreateLink the function that handle the create Link request
@@ -97,27 +111,13 @@ func (lr *LinkRouter) CreateLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	linkModel, err := linkService.CreateLink(&ctx, &linkRequest)
+	_, err = linkService.CreateLink(&ctx, &linkRequest)
 	if err != nil {
 		ctx.Logging().Errorf("create link with error[%v]", err)
 		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
 		return
 	}
 
-	if errPersist := linkService.PersistLinksMeta(linkModel.FsID); errPersist != nil {
-		ctx.Logging().Errorf("persist links meta with err[%v]", errPersist)
-		ctx.ErrorCode = common.LinkMetaPersistError
-		err := models.DeleteLinkWithFsIDAndFsPath(fs.ID(linkRequest.Username, linkRequest.FsName), linkRequest.FsPath)
-		if err != nil {
-			ctx.Logging().Errorf("delete link err with fsID[%s] and fsPath[%s]", fs.ID(linkRequest.Username, linkRequest.FsName), linkRequest.FsPath)
-			ctx.ErrorCode = common.LinkModelError
-			common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
-			return
-		}
-		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, errPersist.Error())
-		return
-	}
-
 	common.Render(w, http.StatusOK, nil)
 }
 
@@ -176,8 +176,9 @@ func validateCreateLink(ctx *logger.RequestContext, req *request.CreateLinkReque
 		ctx.ErrorCode = common.LinkFileSystemNotExist
 		return common.InvalidField("fsName", fmt.Sprintf("user[%s] fsName[%s] is not exist", req.Username, req.FsName))
 	}
-	// local的文件系统不支持link其他文件系统，其他文件系统支持link local类型的文件系统
-	if !SupportLinkURLPrefix[fileSystemModel.Type] || fileSystemModel.Type == base.LocalType {
+	// local的文件系统不支持link其他文件系统，其他文件系统支持link local类型的文件系统，只读的http文件系统不支持link其他文件系统
+	if !SupportLinkURLPrefix[fileSystemModel.Type] || fileSystemModel.Type == base.LocalType ||
+		fileSystemModel.Type == base.HTTPType {
 		ctx.Logging().Errorf("fs name[%s] type[%s] can not support link feature", req.FsName, fileSystemModel.Type)
 		ctx.ErrorCode = common.InvalidFileSystemFsName
 		return common.InvalidField("fsName", fmt.Sprintf("fs name[%s] type[%s] can not support link feature", req.FsName, fileSystemModel.Type))
@@ -239,6 +240,7 @@ func checkFsPathIsExist(ctx *logger.RequestContext, fsMeta base.FSMeta, fsPath s
 		ctx.ErrorCode = common.FuseClientError
 		return err
 	}
+	defer client.Close()
 	isDir, err := client.IsDir(filepath.Dir(fsPath))
 	if err != nil {
 		ctx.Logging().Errorf("fuse client path[%s] exist err[%v]", fsPath, err)
@@ -271,7 +273,7 @@ func checkFsPathIsExist(ctx *logger.RequestContext, fsMeta base.FSMeta, fsPath s
 func checkLinkURLFormat(fsType, url string, properties map[string]string) error {
 	urlSplit := strings.Split(url, "/")
 	switch fsType {
-	case fs.HDFS, fs.SFTP:
+	case fs.HDFS, fs.SFTP, fs.NFS, fs.CephFS, fs.Lustre, fs.WebDAV, fs.HTTP, fs.HTTPS:
 		if len(urlSplit) < 4 {
 			log.Errorf("%s url split error", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s url format is wrong", fsType))
@@ -285,7 +287,7 @@ func checkLinkURLFormat(fsType, url string, properties map[string]string) error
 			log.Errorf("%s path can not be empty or use root path", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s path can not be empty or use root path", fsType))
 		}
-	case fs.S3:
+	case fs.S3, fs.GCS, fs.Azblob:
 		if len(urlSplit) < fs.S3SplitLen {
 			log.Errorf("%s url split error", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s url format is wrong", fsType))
@@ -328,29 +330,15 @@ func checkLinkProperties(fsType string, req *request.CreateLinkRequest) error {
 			return common.InvalidField("debug", "properties key[debug] must true")
 		}
 		return nil
-	case fs.S3:
-		if req.Properties[base.AccessKey] == "" || req.Properties[base.SecretKey] == "" {
-			log.Error("s3 ak or sk is empty")
-			return common.InvalidField("properties", fmt.Sprintf("key %s or %s is empty", base.AccessKey, base.SecretKey))
-		}
-		if req.Properties[base.Endpoint] == "" {
-			log.Error("endpoint is empty")
-			return common.InvalidField("properties", "key[endpoint] is empty")
-		}
-		if req.Properties[base.Bucket] == "" {
-			log.Error("bucket is empty")
-			return common.InvalidField("properties", "url bucket is empty")
-		}
-		if req.Properties[base.Region] == "" {
-			req.Properties[base.Region] = ""
-		}
-		encodedSk, err := apicommon.AesEncrypt(req.Properties[base.SecretKey], apicommon.AESEncryptKey)
-		if err != nil {
-			log.Errorf("encrypt s3 sk failed: %v", err)
-			return err
+	case fs.S3, fs.GCS, fs.Azblob:
+		return fs.CheckObjectStorageProperties(fsType, req.Properties)
+	case fs.NFS, fs.CephFS, fs.Lustre:
+		return fs.CheckKernelMountProperties(req.Properties)
+	case fs.WebDAV, fs.HTTP, fs.HTTPS:
+		if req.Properties == nil {
+			req.Properties = make(map[string]string)
 		}
-		req.Properties[base.SecretKey] = encodedSk
-		return nil
+		return checkHTTPProperties(fsType, req.Url, req.Properties)
 	case fs.SFTP:
 		if req.Properties[base.UserKey] == "" {
 			return common.InvalidField(base.UserKey, "key[user] cannot be empty")
@@ -421,10 +409,6 @@ func (lr *LinkRouter) DeleteLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fsID := fs.ID(deleteRequest.Username, deleteRequest.FsName)
-	if errPersist := linkService.PersistLinksMeta(fsID); errPersist != nil {
-		log.Errorf("persist links meta with err[%v]", errPersist)
-	}
 	common.Render(w, http.StatusOK, nil)
 }
 
@@ -488,12 +472,8 @@ func (lr *LinkRouter) GetLink(w http.ResponseWriter, r *http.Request) {
 
 	linkService := service.GetLinkService()
 
-	if getRequest.Username == "" {
-		getRequest.Username = ctx.UserName
-	}
-	if getRequest.Username == "" {
-		ctx.Logging().Error("userName is empty")
-		common.RenderErrWithMessage(w, ctx.RequestID, common.AuthFailed, "userName is empty")
+	if err := validateRequestUser(&ctx, &getRequest.Username); err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
 		return
 	}
 
@@ -506,8 +486,20 @@ func (lr *LinkRouter) GetLink(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// trans fsName to real fsID, for user they only use fsName，grpc client may be use fsID
-	getRequest.FsID = fs.NameToFsID(getRequest.FsID, getRequest.Username)
+	getRequest.FsID = fs.SharedNameToFsID(getRequest.FsID, getRequest.Username)
+	if err := checkFsAccess(&ctx, getRequest.Username, getRequest.FsID); err != nil {
+		ctx.Logging().Errorf("check access of fs with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
 
+	// the version is got before the links, so that the links changed meanwhile are watched again
+	version, err := linkService.GetLinkVersion(getRequest.FsID)
+	if err != nil {
+		ctx.Logging().Errorf("get link version with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
 	listLinks, nextMarker, err := linkService.GetLink(getRequest)
 	if err != nil {
 		ctx.Logging().Errorf("list link with error[%v]", err)
@@ -516,10 +508,76 @@ func (lr *LinkRouter) GetLink(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := *getLinkListResult(listLinks, nextMarker, getRequest.Marker)
+	response.Version = version
 	ctx.Logging().Debugf("GetLink Link:%v", string(config.PrettyFormat(response)))
 	common.Render(w, http.StatusOK, response)
 }
 
+// WatchLink the function that handle the watch file system links request
+// @Summary WatchLink
+// @Description 长轮询文件系统的link，link的版本与version不同时返回全部link，超时未变化时返回的changed为false
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param fsName path string true "文件系统名称"
+// @Param version query int false "客户端已知的link版本"
+// @Param timeout query int false "最长等待秒数"
+// @Success 200 {object} response.WatchLinkResponse
+// @Router /api/paddleflow/v1/link/{fsName}/watch [get]
+func (lr *LinkRouter) WatchLink(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+
+	username := r.URL.Query().Get(util.QueryKeyUserName)
+	if err := validateRequestUser(&ctx, &username); err != nil {
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	var version int64
+	var err error
+	if versionStr := r.URL.Query().Get(util.QueryLinkVersion); versionStr != "" {
+		version, err = strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			ctx.Logging().Errorf("link version[%s] is invalid", versionStr)
+			common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidLinkWatch, fmt.Sprintf("version[%s] is invalid", versionStr))
+			return
+		}
+	}
+	timeout := DefaultLinkWatchTimeout
+	if timeoutStr := r.URL.Query().Get(util.QueryWatchTimeout); timeoutStr != "" {
+		seconds, err := strconv.Atoi(timeoutStr)
+		if err != nil || seconds <= 0 {
+			ctx.Logging().Errorf("watch timeout[%s] is invalid", timeoutStr)
+			common.RenderErrWithMessage(w, ctx.RequestID, common.InvalidLinkWatch, fmt.Sprintf("timeout[%s] is invalid", timeoutStr))
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+		if timeout > MaxLinkWatchTimeout {
+			timeout = MaxLinkWatchTimeout
+		}
+	}
+	// trans fsName to real fsID, for user they only use fsName，grpc client may be use fsID
+	fsID := fs.SharedNameToFsID(chi.URLParam(r, util.QueryFsName), username)
+	if err := checkFsAccess(&ctx, username, fsID); err != nil {
+		ctx.Logging().Errorf("check access of fs with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	log.Debugf("watch links of fs[%s] from version[%d]", fsID, version)
+
+	links, current, err := service.GetLinkService().WatchLinks(r.Context(), fsID, version, timeout)
+	if err != nil {
+		ctx.Logging().Errorf("watch links with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, common.LinkModelError, err.Error())
+		return
+	}
+	response := response.WatchLinkResponse{Version: current}
+	if current != version {
+		response.Changed = true
+		response.LinkList = getLinkListResult(links, "", "").LinkList
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
 func linkResponseFromModel(link models.Link) *response.LinkResponse {
 	fsName := fs.FSIDToName(link.FsID)
 	return &response.LinkResponse{
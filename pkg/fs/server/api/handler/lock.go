@@ -0,0 +1,147 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"net/http"
+	"syscall"
+
+	"github.com/go-chi/chi"
+
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/server/api/common"
+	"paddleflow/pkg/fs/server/api/request"
+	"paddleflow/pkg/fs/server/service"
+)
+
+const lockSessionParam = "session"
+
+// FileLock the function that handle the file lock request of fuse client
+// @Summary FileLock
+// @Description 加锁、解锁或查询文件锁，用于多个挂载点之间协调flock/fcntl锁
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param request body request.FileLockRequest true "request body"
+// @Success 200 {object} response.FileLockResponse
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 403 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/lock [post]
+func (pr *PFSRouter) FileLock(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+
+	var lockRequest request.FileLockRequest
+	err := common.BindJSON(r, &lockRequest)
+	if err != nil {
+		ctx.Logging().Errorf("FileLock bindjson failed. err:%s", err.Error())
+		common.RenderErr(w, ctx.RequestID, common.MalformedJSON)
+		return
+	}
+	ctx.Logging().Debugf("file lock with req[%+v]", lockRequest)
+
+	if err = validateFileLock(&ctx, &lockRequest); err != nil {
+		ctx.Logging().Errorf("file lock params error: %v", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	if err = checkFsAccess(&ctx, ctx.UserName, lockRequest.FsID); err != nil {
+		ctx.Logging().Errorf("check access of fs[%s] with error[%v]", lockRequest.FsID, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, lockErrorCode(&ctx), err.Error())
+		return
+	}
+	response, err := service.GetFileLockService().FileLock(&ctx, ctx.UserName, &lockRequest)
+	if err != nil {
+		ctx.Logging().Errorf("file lock with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, lockErrorCode(&ctx), err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response)
+}
+
+func validateFileLock(ctx *logger.RequestContext, req *request.FileLockRequest) error {
+	if req.FsID == "" {
+		ctx.ErrorCode = common.InvalidFileLock
+		return common.InvalidField("fsID", "must not be empty")
+	}
+	if req.Path == "" {
+		ctx.ErrorCode = common.InvalidFileLock
+		return common.InvalidField("path", "must not be empty")
+	}
+	if req.Session == "" {
+		ctx.ErrorCode = common.InvalidFileLock
+		return common.InvalidField("session", "must not be empty")
+	}
+	switch req.Type {
+	case syscall.F_RDLCK, syscall.F_WRLCK, syscall.F_UNLCK:
+	default:
+		ctx.ErrorCode = common.InvalidFileLock
+		return common.InvalidField("type", "must be F_RDLCK, F_WRLCK or F_UNLCK")
+	}
+	if !req.Flock && req.Start > req.End {
+		ctx.ErrorCode = common.InvalidFileLock
+		return common.InvalidField("end", "must not be less than start")
+	}
+	return nil
+}
+
+// KeepLockSession the function that handle the heartbeat of the fuse client holding locks
+// @Summary KeepLockSession
+// @Description 刷新挂载点的锁会话，超时未刷新的会话持有的锁会被释放
+// @tag fs
+// @Param session path string true "会话ID"
+// @Success 200
+// @Failure 403 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/lock/session/{session} [put]
+func (pr *PFSRouter) KeepLockSession(w http.ResponseWriter, r *http.Request) {
+	session := chi.URLParam(r, lockSessionParam)
+	ctx := common.GetRequestContext(r)
+	if err := service.GetFileLockService().KeepSession(&ctx, ctx.UserName, session); err != nil {
+		ctx.Logging().Errorf("keep lock session[%s] with error[%v]", session, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, lockErrorCode(&ctx), err.Error())
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// ReleaseLockSession the function that handle the umount of the fuse client
+// @Summary ReleaseLockSession
+// @Description 释放挂载点的锁会话持有的所有锁
+// @tag fs
+// @Param session path string true "会话ID"
+// @Success 200
+// @Failure 403 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/lock/session/{session} [delete]
+func (pr *PFSRouter) ReleaseLockSession(w http.ResponseWriter, r *http.Request) {
+	session := chi.URLParam(r, lockSessionParam)
+	ctx := common.GetRequestContext(r)
+	ctx.Logging().Infof("release lock session[%s]", session)
+	if err := service.GetFileLockService().ReleaseSession(&ctx, ctx.UserName, session); err != nil {
+		ctx.Logging().Errorf("release lock session[%s] with error[%v]", session, err)
+		common.RenderErrWithMessage(w, ctx.RequestID, lockErrorCode(&ctx), err.Error())
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// lockErrorCode returns the error code set by the checks, access is denied if the fs is not found
+func lockErrorCode(ctx *logger.RequestContext) string {
+	if ctx.ErrorCode == "" {
+		return common.FsAccessDenied
+	}
+	return ctx.ErrorCode
+}
@@ -19,6 +19,7 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -32,6 +33,7 @@ import (
 	"paddleflow/pkg/apiserver/models"
 	"paddleflow/pkg/apiserver/router/util"
 	"paddleflow/pkg/common/config"
+	httputil "paddleflow/pkg/common/http/util"
 	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/fs/client/base"
 	fuse "paddleflow/pkg/fs/client/fs"
@@ -54,16 +56,31 @@ func (pr *PFSRouter) AddRouter(r chi.Router) {
 	r.Post("/fs", pr.CreateFileSystem)
 	r.Get("/fs", pr.ListFileSystem)
 	r.Get("/fs/{fsName}", pr.GetFileSystem)
+	r.Get("/fs/{fsName}/usage", pr.GetFileSystemUsage)
 	r.Delete("/fs/{fsName}", pr.DeleteFileSystem)
+	r.Post("/fs/{fsName}/share", pr.CreateFsShare)
+	r.Get("/fs/{fsName}/share", pr.ListFsShare)
+	r.Delete("/fs/{fsName}/share/{shareID}", pr.DeleteFsShare)
 	r.Post("/fs/claims", pr.CreateFileSystemClaims)
+	r.Post("/fs/lock", pr.FileLock)
+	r.Put("/fs/lock/session/{session}", pr.KeepLockSession)
+	r.Delete("/fs/lock/session/{session}", pr.ReleaseLockSession)
 }
 
 var URLPrefix = map[string]bool{
-	fs.HDFS:  true,
-	fs.Local: true,
-	fs.S3:    true,
-	fs.SFTP:  true,
-	fs.Mock:  true,
+	fs.HDFS:   true,
+	fs.Local:  true,
+	fs.S3:     true,
+	fs.SFTP:   true,
+	fs.GCS:    true,
+	fs.Azblob: true,
+	fs.NFS:    true,
+	fs.CephFS: true,
+	fs.Lustre: true,
+	fs.WebDAV: true,
+	fs.HTTP:   true,
+	fs.HTTPS:  true,
+	fs.Mock:   true,
 }
 
 const (
@@ -171,6 +188,20 @@ func validateCreateFileSystem(ctx *logger.RequestContext, req *request.CreateFil
 		return err
 	}
 
+	if req.Properties != nil {
+		if err = fs.CheckQuotaProperties(req.Properties); err != nil {
+			ctx.Logging().Errorf("check quota err[%v] with properties[%v]", err, req.Properties)
+			ctx.ErrorCode = common.InvalidFileSystemProperties
+			return err
+		}
+	}
+
+	if err = base.ValidateMountOptions(req.MountOptions, true); err != nil {
+		ctx.Logging().Errorf("check mount options err[%v] with mountOptions[%v]", err, req.MountOptions)
+		ctx.ErrorCode = common.InvalidMountOptions
+		return common.InvalidField("mountOptions", err.Error())
+	}
+
 	err = checkFsDir(fileSystemType, req.Url, req.Properties)
 	if err != nil {
 		ctx.Logging().Errorf("check fs dir err[%v] with url[%s]", err, req.Url)
@@ -203,7 +234,7 @@ func validateCreateFileSystem(ctx *logger.RequestContext, req *request.CreateFil
 }
 
 func checkStorageConnectivity(fsMeta base.FSMeta) error {
-	_, err := fuse.NewFileSystem(fsMeta, nil, true, false, config.GlobalServerConfig.Fs.LinkMetaDirPrefix, nil)
+	_, err := fuse.NewFileSystem(fsMeta, nil, true, false, nil, nil)
 	if err != nil {
 		log.Errorf("new a fileSystem with fsMeta [%+v] failed: %v", fsMeta, err)
 		return err
@@ -236,29 +267,15 @@ func checkProperties(fsType string, req *request.CreateFileSystemRequest) error
 			return common.InvalidField("debug", "properties key[debug] must true")
 		}
 		return nil
-	case fs.S3:
-		if req.Properties[base.AccessKey] == "" || req.Properties[base.SecretKey] == "" {
-			log.Error("s3 ak or sk is empty")
-			return common.InvalidField("properties", fmt.Sprintf("key %s or %s is empty", base.AccessKey, base.SecretKey))
-		}
-		if req.Properties[base.Endpoint] == "" {
-			log.Error("endpoint is empty")
-			return common.InvalidField("properties", "key[endpoint] is empty")
-		}
-		if req.Properties[base.Bucket] == "" {
-			log.Error("bucket is empty")
-			return common.InvalidField("properties", "url bucket is empty")
-		}
-		if req.Properties[base.Region] == "" {
-			req.Properties[base.Region] = ""
+	case fs.S3, fs.GCS, fs.Azblob:
+		return fs.CheckObjectStorageProperties(fsType, req.Properties)
+	case fs.NFS, fs.CephFS, fs.Lustre:
+		return fs.CheckKernelMountProperties(req.Properties)
+	case fs.WebDAV, fs.HTTP, fs.HTTPS:
+		if req.Properties == nil {
+			req.Properties = make(map[string]string)
 		}
-		encodedSk, err := apicommon.AesEncrypt(req.Properties[base.SecretKey], apicommon.AESEncryptKey)
-		if err != nil {
-			log.Errorf("encrypt s3 sk failed: %v", err)
-			return err
-		}
-		req.Properties[base.SecretKey] = encodedSk
-		return nil
+		return checkHTTPProperties(fsType, req.Url, req.Properties)
 	case fs.SFTP:
 		if req.Properties[base.UserKey] == "" {
 			return common.InvalidField(base.UserKey, "key[user] cannot be empty")
@@ -304,7 +321,7 @@ func checkURLFormat(fsType, url string, properties map[string]string) error {
 	urlSplit := strings.Split(url, "/")
 	// check fs url correct
 	switch fsType {
-	case fs.HDFS, fs.SFTP:
+	case fs.HDFS, fs.SFTP, fs.NFS, fs.CephFS, fs.Lustre, fs.WebDAV, fs.HTTP, fs.HTTPS:
 		if len(urlSplit) < 4 {
 			log.Errorf("%s url split error", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s url format is wrong", fsType))
@@ -318,7 +335,7 @@ func checkURLFormat(fsType, url string, properties map[string]string) error {
 			log.Errorf("%s path can not be empty or use root path", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s path can not be empty or use root path", fsType))
 		}
-	case fs.S3:
+	case fs.S3, fs.GCS, fs.Azblob:
 		if len(urlSplit) < fs.S3SplitLen {
 			log.Errorf("%s url split error", fsType)
 			return common.InvalidField("url", fmt.Sprintf("%s url format is wrong", fsType))
@@ -336,6 +353,45 @@ func checkURLFormat(fsType, url string, properties map[string]string) error {
 	return nil
 }
 
+// checkHTTPProperties sets the scheme of webdav and http, checks that the server of fsURL is public
+// unless private addresses are allowed, and encrypts the password
+func checkHTTPProperties(fsType, fsURL string, properties map[string]string) error {
+	if fsType != fs.WebDAV {
+		// the scheme of http is the one of url
+		properties[base.Scheme] = fsType
+	} else {
+		switch properties[base.Scheme] {
+		case "":
+			properties[base.Scheme] = fs.HTTPS
+		case fs.HTTP, fs.HTTPS:
+		default:
+			return common.InvalidField("properties", fmt.Sprintf("key[%s] must be %s or %s", base.Scheme, fs.HTTP, fs.HTTPS))
+		}
+	}
+	if !allowPrivateAddress() {
+		u, err := url.Parse(fsURL)
+		if err != nil {
+			return common.InvalidField("url", err.Error())
+		}
+		if err := httputil.CheckPublicURL(properties[base.Scheme] + "://" + u.Host); err != nil {
+			return common.InvalidField("url", err.Error())
+		}
+	}
+	if properties[base.Password] != "" {
+		encodePassword, err := apicommon.AesEncrypt(properties[base.Password], apicommon.AESEncryptKey)
+		if err != nil {
+			log.Errorf("encrypt %s password failed: %v", fsType, err)
+			return err
+		}
+		properties[base.Password] = encodePassword
+	}
+	return nil
+}
+
+func allowPrivateAddress() bool {
+	return config.GlobalServerConfig != nil && config.GlobalServerConfig.Fs.AllowPrivateAddress
+}
+
 // checkFsDir duplicate and nesting of the same storage source directory is not supported
 func checkFsDir(fsType, url string, properties map[string]string) error {
 	var inputIPs []string
@@ -343,12 +399,12 @@ func checkFsDir(fsType, url string, properties map[string]string) error {
 	switch fsType {
 	case fs.Local, fs.Mock:
 		subPath = strings.SplitAfterN(url, "/", 2)[1]
-	case fs.HDFS, fs.SFTP:
+	case fs.HDFS, fs.SFTP, fs.NFS, fs.CephFS, fs.Lustre, fs.WebDAV, fs.HTTP, fs.HTTPS:
 		urlSplit := strings.Split(url, "/")
 		urlRaw := urlSplit[2]
 		inputIPs = strings.Split(urlRaw, ",")
 		subPath = "/" + strings.SplitAfterN(url, "/", 4)[3]
-	case fs.S3:
+	case fs.S3, fs.GCS, fs.Azblob:
 		inputIPs = strings.Split(properties[base.Endpoint], ",")
 		subPath = "/" + strings.SplitAfterN(url, "/", 4)[3]
 	}
@@ -467,16 +523,13 @@ func (pr *PFSRouter) GetFileSystem(w http.ResponseWriter, r *http.Request) {
 
 	fileSystemService := service.GetFileSystemService()
 
-	if getRequest.Username == "" {
-		getRequest.Username = ctx.UserName
-	}
 	err := validateGetFs(&ctx, &getRequest, &fsName)
 	if err != nil {
 		ctx.Logging().Errorf("validateGetFs error[%v]", err)
 		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
 		return
 	}
-	fsModel, err := fileSystemService.GetFileSystem(&getRequest, fsName)
+	fsModel, access, err := fileSystemService.GetFileSystemWithAccess(&ctx, &getRequest, fsName)
 	if err != nil {
 		ctx.Logging().Errorf("get file system with error[%v]", err)
 		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
@@ -484,10 +537,48 @@ func (pr *PFSRouter) GetFileSystem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	response := *fsResponseFromModel(fsModel)
+	// the access of the user to the fs shared by others, which is enforced by pfs-fuse
+	response.Access = access
 	ctx.Logging().Debugf("GetFileSystem Fs:%v", string(config.PrettyFormat(response)))
 	common.Render(w, http.StatusOK, response)
 }
 
+// GetFileSystemUsage the function that handle the get file system usage request
+// @Summary GetFileSystemUsage
+// @Description 获取指定文件系统及其link的用量和配额，用量由后台扫描得到
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param id path string true "文件系统ID"
+// @Success 200 {object} response.FileSystemUsageResponse
+// @Router /api/paddleflow/v1/fs/{fsName}/usage [get]
+func (pr *PFSRouter) GetFileSystemUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+
+	fsName := chi.URLParam(r, util.QueryFsName)
+	getRequest := request.GetFileSystemRequest{
+		Username: r.URL.Query().Get(util.QueryKeyUserName),
+	}
+	log.Debugf("get file system usage with req[%v] and fileSystemID[%s]", getRequest, fsName)
+
+	err := validateGetFs(&ctx, &getRequest, &fsName)
+	if err != nil {
+		ctx.Logging().Errorf("validateGetFs error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	fsModel, _, err := service.GetFileSystemService().GetFileSystemWithAccess(&ctx, &getRequest, fsName)
+	if err != nil {
+		ctx.Logging().Errorf("get file system with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	response := service.GetUsageService().GetUsage(fsModel)
+	ctx.Logging().Debugf("GetFileSystemUsage Fs:%v", string(config.PrettyFormat(response)))
+	common.Render(w, http.StatusOK, response)
+}
+
 func fsResponseFromModel(fsModel models.FileSystem) *response.FileSystemResponse {
 	return &response.FileSystemResponse{
 		Id:            fsModel.ID,
@@ -497,26 +588,22 @@ func fsResponseFromModel(fsModel models.FileSystem) *response.FileSystemResponse
 		SubPath:       fsModel.SubPath,
 		Username:      fsModel.UserName,
 		Properties:    fsModel.PropertiesMap,
+		MountOptions:  fsModel.MountOptionsMap,
 	}
 }
 
 func validateGetFs(ctx *logger.RequestContext, req *request.GetFileSystemRequest, fsID *string) error {
-	if req.Username == "" {
-		req.Username = ctx.UserName
-	}
-
-	if req.Username == "" {
-		ctx.Logging().Error("UserName is empty")
-		ctx.ErrorCode = common.AuthFailed
-		return common.InvalidField("userName", "userName is empty")
+	if err := validateRequestUser(ctx, &req.Username); err != nil {
+		return err
 	}
 	if *fsID == "" {
 		ctx.Logging().Error("FsID or FsName is empty")
 		// response to user use fsName not fsID
 		return common.InvalidField("fsName", "fsName is empty")
 	}
-	// trans fsName to real fsID, for user they only use fsName，grpc client may be use fsID
-	*fsID = fs.NameToFsID(*fsID, req.Username)
+	// trans fsName to real fsID, for user they only use fsName，grpc client may be use fsID,
+	// the fsID of other users is kept as their fs may be shared with the user
+	*fsID = fs.SharedNameToFsID(*fsID, req.Username)
 
 	return nil
 }
@@ -629,6 +716,9 @@ func (pr *PFSRouter) CreateFileSystemClaims(w http.ResponseWriter, r *http.Reque
 
 	fileSystemService := service.GetFileSystemService()
 
+	if createRequest.Username == "" {
+		createRequest.Username = ctx.UserName
+	}
 	err = validateCreateFileSystemClaims(&ctx, &createRequest)
 	if err != nil {
 		ctx.Logging().Errorf("create file system claims params error: %v", err)
@@ -396,3 +396,34 @@ func Test_getListResult(t *testing.T) {
 		})
 	}
 }
+
+func Test_validateRequestUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctxUser  string
+		userName string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default to the user of request", ctxUser: "user1", want: "user1"},
+		{name: "the user itself", ctxUser: "user1", userName: "user1", want: "user1"},
+		{name: "root acts as others", ctxUser: fs.UserRoot, userName: "user1", want: "user1"},
+		{name: "user acts as others", ctxUser: "user1", userName: "user2", wantErr: true},
+		{name: "user acts as root", ctxUser: "user1", userName: fs.UserRoot, wantErr: true},
+		{name: "empty user", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := &logger.RequestContext{UserName: tt.ctxUser}
+			userName := tt.userName
+			err := validateRequestUser(ctx, &userName)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRequestUser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && userName != tt.want {
+				t.Errorf("validateRequestUser() userName = %v, want %v", userName, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+
+	"github.com/go-chi/chi"
+	log "github.com/sirupsen/logrus"
+
+	apicommon "paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/apiserver/router/util"
+	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/server/api/common"
+	"paddleflow/pkg/fs/server/api/request"
+	"paddleflow/pkg/fs/server/api/response"
+	"paddleflow/pkg/fs/server/service"
+	"paddleflow/pkg/fs/server/utils/fs"
+)
+
+// CreateFsShare the function that handle the create fs share request
+// @Summary CreateFsShare
+// @Description 将文件系统的某个目录以只读或读写权限共享给其他用户或用户组，仅文件系统的创建者和root用户可以共享，nfs、cephfs、lustre等内核挂载的文件系统只能整体共享
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param fsName path string true "文件系统名称"
+// @Param request body request.CreateFsShareRequest true "request body"
+// @Success 200 {object} response.CreateFsShareResponse
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/{fsName}/share [post]
+func (pr *PFSRouter) CreateFsShare(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+
+	var shareRequest request.CreateFsShareRequest
+	err := common.BindJSON(r, &shareRequest)
+	if err != nil {
+		ctx.Logging().Errorf("CreateFsShare bindjson failed. err:%s", err.Error())
+		common.RenderErr(w, ctx.RequestID, common.MalformedJSON)
+		return
+	}
+	shareRequest.FsName = chi.URLParam(r, util.QueryFsName)
+	log.Debugf("create fs share with req[%v]", shareRequest)
+
+	err = validateCreateFsShare(&ctx, &shareRequest)
+	if err != nil {
+		ctx.Logging().Errorf("validateCreateFsShare error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	share, err := service.GetFsShareService().CreateFsShare(&ctx, &shareRequest)
+	if err != nil {
+		ctx.Logging().Errorf("create fs share with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.Render(w, http.StatusOK, response.CreateFsShareResponse{ShareID: share.ID})
+}
+
+func validateCreateFsShare(ctx *logger.RequestContext, req *request.CreateFsShareRequest) error {
+	fsModel, err := validateFsOwner(ctx, &req.Username, req.FsName)
+	if err != nil {
+		return err
+	}
+
+	if req.FsPath == "" {
+		req.FsPath = "/"
+	}
+	if !path.IsAbs(req.FsPath) || path.Clean(req.FsPath) != req.FsPath {
+		ctx.ErrorCode = common.InvalidFsShare
+		return common.InvalidField("fsPath", fmt.Sprintf("fsPath[%s] must be a clean absolute path", req.FsPath))
+	}
+	// the access to the paths is enforced by pfs-fuse, which does not serve the fs mounted by the kernel
+	if _, ok := base.KernelFSType(fsModel.Type); ok && req.FsPath != "/" {
+		ctx.ErrorCode = common.InvalidFsShare
+		return common.InvalidField("fsPath", fmt.Sprintf("fs[%s] of type %s can only be shared as a whole",
+			req.FsName, fsModel.Type))
+	}
+	if req.Permission != base.AccessReadOnly && req.Permission != base.AccessReadWrite {
+		ctx.ErrorCode = common.InvalidFsShare
+		return common.InvalidField("permission", fmt.Sprintf("permission must be %s or %s",
+			base.AccessReadOnly, base.AccessReadWrite))
+	}
+
+	switch req.ShareType {
+	case models.FsShareToUser:
+		if req.ShareTo == fsModel.UserName || req.ShareTo == fs.UserRoot {
+			ctx.ErrorCode = common.InvalidFsShare
+			return common.InvalidField("shareTo", fmt.Sprintf("user[%s] has full access to fs[%s] already",
+				req.ShareTo, req.FsName))
+		}
+		if _, err := models.GetUserByName(ctx, req.ShareTo); err != nil {
+			ctx.ErrorCode = common.InvalidFsShare
+			return common.InvalidField("shareTo", fmt.Sprintf("user[%s] is not exist", req.ShareTo))
+		}
+	case models.FsShareToGroup:
+		if !regexp.MustCompile(apicommon.RegPatternGroupName).MatchString(req.ShareTo) {
+			ctx.ErrorCode = common.InvalidFsShare
+			return common.InvalidField("shareTo", fmt.Sprintf("group name[%s] must match %s",
+				req.ShareTo, apicommon.RegPatternGroupName))
+		}
+	default:
+		ctx.ErrorCode = common.InvalidFsShare
+		return common.InvalidField("shareType", fmt.Sprintf("shareType must be %s or %s",
+			models.FsShareToUser, models.FsShareToGroup))
+	}
+	return nil
+}
+
+// ListFsShare the function that handle the list fs shares request
+// @Summary ListFsShare
+// @Description 获取文件系统的全部共享，仅文件系统的创建者和root用户可以获取
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param fsName path string true "文件系统名称"
+// @Success 200 {object} response.ListFsShareResponse
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/{fsName}/share [get]
+func (pr *PFSRouter) ListFsShare(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	listRequest := &request.ListFsShareRequest{
+		FsName:   chi.URLParam(r, util.QueryFsName),
+		Username: r.URL.Query().Get(util.QueryKeyUserName),
+	}
+	log.Debugf("list fs shares with req[%v]", listRequest)
+
+	if _, err := validateFsOwner(&ctx, &listRequest.Username, listRequest.FsName); err != nil {
+		ctx.Logging().Errorf("validateFsOwner error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	shares, err := service.GetFsShareService().ListFsShares(&ctx, listRequest)
+	if err != nil {
+		ctx.Logging().Errorf("list fs shares with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	response := response.ListFsShareResponse{ShareList: []*response.FsShareResponse{}}
+	for _, share := range shares {
+		response.ShareList = append(response.ShareList, fsShareResponseFromModel(share, listRequest.FsName))
+	}
+	ctx.Logging().Debugf("ListFsShare shares:%v", string(config.PrettyFormat(response)))
+	common.Render(w, http.StatusOK, response)
+}
+
+// DeleteFsShare the function that handle the delete fs share request
+// @Summary DeleteFsShare
+// @Description 取消文件系统的某个共享，仅文件系统的创建者和root用户可以取消
+// @tag fs
+// @Accept   json
+// @Produce  json
+// @Param fsName path string true "文件系统名称"
+// @Param shareID path string true "共享ID"
+// @Success 200
+// @Failure 400 {object} common.ErrorResponse
+// @Failure 500 {object} common.ErrorResponse
+// @Router /api/paddleflow/v1/fs/{fsName}/share/{shareID} [delete]
+func (pr *PFSRouter) DeleteFsShare(w http.ResponseWriter, r *http.Request) {
+	ctx := common.GetRequestContext(r)
+	deleteRequest := &request.DeleteFsShareRequest{
+		FsName:   chi.URLParam(r, util.QueryFsName),
+		ShareID:  chi.URLParam(r, util.QueryShareID),
+		Username: r.URL.Query().Get(util.QueryKeyUserName),
+	}
+	log.Debugf("delete fs share with req[%v]", deleteRequest)
+
+	if _, err := validateFsOwner(&ctx, &deleteRequest.Username, deleteRequest.FsName); err != nil {
+		ctx.Logging().Errorf("validateFsOwner error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+
+	if err := service.GetFsShareService().DeleteFsShare(&ctx, deleteRequest); err != nil {
+		ctx.Logging().Errorf("delete fs share with error[%v]", err)
+		common.RenderErrWithMessage(w, ctx.RequestID, ctx.ErrorCode, err.Error())
+		return
+	}
+	common.RenderStatus(w, http.StatusOK)
+}
+
+// validateRequestUser sets the user name of request to the user of ctx if empty, only root can act as
+// other users
+func validateRequestUser(ctx *logger.RequestContext, userName *string) error {
+	if *userName == "" {
+		*userName = ctx.UserName
+	}
+	if *userName == "" {
+		ctx.Logging().Error("UserName is empty")
+		ctx.ErrorCode = common.AuthFailed
+		return common.InvalidField("userName", "userName is empty")
+	}
+	if ctx.UserName != "" && ctx.UserName != fs.UserRoot && ctx.UserName != *userName {
+		ctx.ErrorCode = common.AuthFailed
+		return fmt.Errorf("user[%s] can not act as user[%s]", ctx.UserName, *userName)
+	}
+	return nil
+}
+
+// validateFsOwner checks the fs exists and the user of request is root or the owner of fs, only root can
+// manage the fs of other users
+func validateFsOwner(ctx *logger.RequestContext, userName *string, fsName string) (models.FileSystem, error) {
+	if err := validateRequestUser(ctx, userName); err != nil {
+		return models.FileSystem{}, err
+	}
+	if fsName == "" {
+		ctx.ErrorCode = common.InvalidFsShare
+		return models.FileSystem{}, common.InvalidField("fsName", "fsName is empty")
+	}
+
+	fsID := fs.ID(*userName, fsName)
+	fsModel, err := models.GetFileSystemWithFsID(fsID)
+	if err != nil {
+		ctx.Logging().Errorf("get file system[%s] error[%v]", fsID, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return models.FileSystem{}, err
+	}
+	if fsModel.Name == "" {
+		ctx.ErrorCode = common.FileSystemNotExist
+		return models.FileSystem{}, common.DbDataNotExitError(fmt.Sprintf("userName[%s] not created file system[%s]",
+			*userName, fsName))
+	}
+	return fsModel, nil
+}
+
+// checkFsAccess checks the fs of other users is shared with the user, which is not checked for root
+// and the owner of fs
+func checkFsAccess(ctx *logger.RequestContext, userName, fsID string) error {
+	if userName == fs.UserRoot || fs.FsIDOwner(fsID) == userName {
+		return nil
+	}
+	_, _, err := service.GetFileSystemService().GetFileSystemWithAccess(ctx,
+		&request.GetFileSystemRequest{Username: userName}, fsID)
+	return err
+}
+
+func fsShareResponseFromModel(share models.FsShare, fsName string) *response.FsShareResponse {
+	return &response.FsShareResponse{
+		ShareID:    share.ID,
+		FsName:     fsName,
+		FsPath:     share.FsPath,
+		ShareType:  share.ShareType,
+		ShareTo:    share.ShareTo,
+		Permission: share.Permission,
+		CreateTime: share.CreatedAt.Format(service.TimeFormat),
+	}
+}
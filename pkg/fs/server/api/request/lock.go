@@ -0,0 +1,34 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package request
+
+// FileLockRequest puts or tests a file lock on the path of fs, the lock is held by
+// the owner in the session of a mount.
+type FileLockRequest struct {
+	FsID    string `json:"fsID"`
+	Path    string `json:"path"`
+	Session string `json:"session"`
+	Owner   uint64 `json:"owner"`
+	// Flock means a whole file lock by flock(2), otherwise a posix range lock by fcntl(2)
+	Flock bool `json:"flock"`
+	// Test returns the conflicting lock without putting the lock, e.g. F_GETLK
+	Test  bool   `json:"test"`
+	Type  uint32 `json:"type"`
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Pid   uint32 `json:"pid"`
+}
@@ -21,6 +21,8 @@ type CreateFileSystemRequest struct {
 	Url        string            `json:"url"`
 	Properties map[string]string `json:"properties"`
 	Username   string            `json:"username"`
+	// MountOptions the default mount options of the pvs of the fs, e.g. pfs.block.size
+	MountOptions map[string]string `json:"mountOptions"`
 }
 
 type ListFileSystemRequest struct {
@@ -42,4 +44,6 @@ type DeleteFileSystemRequest struct {
 type CreateFileSystemClaimsRequest struct {
 	Namespaces []string `json:"namespaces"`
 	FsIDs      []string `json:"fsIDs"`
+	// Username is the user who mounts the fs, the fs shared by others is mounted with the access of the user
+	Username string `json:"username"`
 }
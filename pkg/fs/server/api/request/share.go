@@ -0,0 +1,37 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package request
+
+type CreateFsShareRequest struct {
+	FsName     string `json:"fsName"`
+	Username   string `json:"username"`
+	FsPath     string `json:"fsPath"`
+	ShareType  string `json:"shareType"`
+	ShareTo    string `json:"shareTo"`
+	Permission string `json:"permission"`
+}
+
+type DeleteFsShareRequest struct {
+	FsName   string `json:"fsName"`
+	Username string `json:"username"`
+	ShareID  string `json:"shareID"`
+}
+
+type ListFsShareRequest struct {
+	FsName   string `json:"fsName"`
+	Username string `json:"username"`
+}
@@ -21,6 +21,15 @@ type GetLinkResponse struct {
 	Truncated  bool            `json:"truncated"`
 	NextMarker string          `json:"nextMarker"`
 	LinkList   []*LinkResponse `json:"linkList"`
+	// Version is the version of the links of fs, which is used to watch the links changed after it
+	Version int64 `json:"version"`
+}
+
+// WatchLinkResponse is the links of fs if they are changed from the version watched
+type WatchLinkResponse struct {
+	Version  int64           `json:"version"`
+	Changed  bool            `json:"changed"`
+	LinkList []*LinkResponse `json:"linkList"`
 }
 
 type LinkResponse struct {
@@ -36,8 +36,41 @@ type FileSystemResponse struct {
 	SubPath       string            `json:"subPath"`
 	Username      string            `json:"username"`
 	Properties    map[string]string `json:"properties"`
+	MountOptions  map[string]string `json:"mountOptions"`
+	// Access is the access of the user to the fs shared by others, which is empty for the owner
+	Access map[string]string `json:"access,omitempty"`
 }
 
 type CreateFileSystemClaimsResponse struct {
 	Message string `json:"message"`
 }
+
+// FileSystemUsageResponse is the usage of a fs scanned in background, the usage of fs excludes its links.
+// Scanning is true if a scan is in progress, and the usage is empty until the first scan is done.
+type FileSystemUsageResponse struct {
+	FsName string `json:"fsName"`
+	Usage
+	Quota     int64               `json:"quota,omitempty"`
+	QuotaType string              `json:"quotaType,omitempty"`
+	TopDirs   []DirUsageResponse  `json:"topDirs"`
+	Links     []LinkUsageResponse `json:"links"`
+	ScanTime  string              `json:"scanTime"`
+	Scanning  bool                `json:"scanning"`
+	ScanError string              `json:"scanError,omitempty"`
+}
+
+type Usage struct {
+	Bytes int64 `json:"bytes"`
+	Files int64 `json:"files"`
+	Dirs  int64 `json:"dirs"`
+}
+
+type DirUsageResponse struct {
+	Path string `json:"path"`
+	Usage
+}
+
+type LinkUsageResponse struct {
+	FsPath string `json:"fsPath"`
+	Usage
+}
@@ -17,39 +17,53 @@ limitations under the License.
 package service
 
 import (
-	"encoding/json"
-	"os"
-	"path/filepath"
-	"strings"
+	"context"
 	"sync"
 	"time"
 
-	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 
-	apicommon "paddleflow/pkg/apiserver/common"
 	"paddleflow/pkg/apiserver/models"
-	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/common/logger"
-	"paddleflow/pkg/fs/client/base"
-	fuse "paddleflow/pkg/fs/client/fs"
 	"paddleflow/pkg/fs/server/api/common"
 	"paddleflow/pkg/fs/server/api/request"
 	utils "paddleflow/pkg/fs/server/utils/fs"
 )
 
-// LinkService the service which contains the operation of link
+// LinkWatchPollInterval is the interval to check the link version changed by other servers
+const LinkWatchPollInterval = time.Second
+
+// LinkService the service which contains the operation of link. The links are watched by pfs-fuse
+// through the server, they are not written to .config/links_meta of the fs any more, so pfs-fuse
+// reading the file must be upgraded together with the server.
 type LinkService struct {
-	mutexes sync.Map
+	sync.Mutex
+	// watches are the polls of the link version shared by the watchers of each fs
+	watches map[string]*linkWatch
+}
+
+// linkWatch polls the link version of a fs for all its watchers on this server, it is stopped
+// when the last watcher leaves
+type linkWatch struct {
+	watchers int
+	// version is -1 until polled, changed is closed to wake up the watchers once version is changed
+	version int64
+	changed chan struct{}
+	// poke asks for a poll at once after the links are changed by this server
+	poke chan struct{}
+	stop chan struct{}
 }
 
-var linkService *LinkService
+var (
+	linkService *LinkService
+	linkOnce    sync.Once
+)
 
 // GetLinkService returns the instance of link service
 func GetLinkService() *LinkService {
-	if linkService == nil {
-		linkService = &LinkService{}
-	}
+	linkOnce.Do(func() {
+		linkService = &LinkService{watches: make(map[string]*linkWatch)}
+	})
 	return linkService
 }
 
@@ -67,25 +81,38 @@ func (s *LinkService) CreateLink(ctx *logger.RequestContext, req *request.Create
 		UserName:      req.Username,
 	}
 
-	err := models.CreateLink(&link)
+	// the links may be created by other servers after validated, so the nesting is checked again in transaction
+	checkNested := func(links []models.Link) error {
+		for _, existed := range links {
+			if utils.CheckFsNested(link.FsPath, existed.FsPath) {
+				return common.LinkPathError(link.FsPath)
+			}
+		}
+		return nil
+	}
+	version, err := models.CreateLinkWithVersion(&link, checkNested)
 	if err != nil {
 		ctx.Logging().Errorf("create link[%v] in db failed: %v", link, err)
 		ctx.ErrorCode = common.LinkModelError
 		return models.Link{}, err
 	}
+	log.Infof("link[%s] of fs[%s] created with version[%d]", link.FsPath, fsID, version)
+	s.notify(fsID)
 	return link, nil
 }
 
 // DeleteLink the function which performs the operation of delete file system link
 func (s *LinkService) DeleteLink(ctx *logger.RequestContext, req *request.DeleteLinkRequest) error {
-	err := models.DeleteLinkWithFsIDAndFsPath(utils.ID(req.Username, req.FsName), req.FsPath)
+	fsID := utils.ID(req.Username, req.FsName)
+	version, err := models.DeleteLinkWithVersion(fsID, req.FsPath)
 	if err != nil {
 		ctx.Logging().Errorf("delete link failed error[%v]", err)
 		ctx.ErrorCode = common.FileSystemDataBaseError
 		return err
 	}
-
-	return err
+	log.Infof("link[%s] of fs[%s] deleted with version[%d]", req.FsPath, fsID, version)
+	s.notify(fsID)
+	return nil
 }
 
 // GetLink the function which performs the operation of list file system links
@@ -127,107 +154,115 @@ func (s *LinkService) GetLink(req *request.GetLinkRequest) ([]models.Link, strin
 	return items, "", err
 }
 
-func (s *LinkService) PersistLinksMeta(fsID string) error {
-	unlock := s.FsLock(fsID)
-	defer unlock()
-
-	links, err := models.FsNameLinks(fsID)
-	if err != nil {
-		log.Errorf("get links err[%v] with fsID[%s]", err, fsID)
-		return err
-	}
-
-	linksMeta := make(map[string]base.FSMeta)
-	for _, link := range links {
-		linksMeta[link.FsPath] = base.FSMeta{
-			ID:            link.ID,
-			Name:          utils.FSIDToName(link.ID),
-			UfsType:       link.Type,
-			ServerAddress: link.ServerAddress,
-			SubPath:       link.SubPath,
-			Properties:    link.PropertiesMap,
-			Type:          base.LinkType,
-		}
-	}
-
-	linksMetaJson, err := json.Marshal(linksMeta)
+// GetLinkVersion returns the version of the links of fs
+func (s *LinkService) GetLinkVersion(fsID string) (int64, error) {
+	version, err := models.GetLinkVersion(fsID)
 	if err != nil {
-		log.Errorf("json marshal links meta err[%v]", err)
-		return err
-	}
-
-	encodedLinksMeta, err := apicommon.AesEncrypt(string(linksMetaJson), apicommon.AESEncryptKey)
-	if err != nil {
-		log.Errorf("aes encrypt links meta json string err[%v]", err)
-		return err
-	}
-
-	if err = writeLinksMeta(encodedLinksMeta, fsID); err != nil {
-		log.Errorf("write links meta err[%v] with fsID[%s]", err, fsID)
-		return err
+		log.Errorf("get link version of fs[%s] err[%v]", fsID, err)
+		return 0, err
 	}
-	return nil
+	return version, nil
 }
 
-func writeLinksMeta(encodedLinksMeta string, fsID string) error {
-	fs, err := models.GetFileSystemWithFsID(fsID)
+// WatchLinks waits until the link version of fs is different from version, and returns the links
+// with their version. The links are nil if the version is not changed before timeout or ctx is done.
+func (s *LinkService) WatchLinks(ctx context.Context, fsID string, version int64, timeout time.Duration) ([]models.Link, int64, error) {
+	// the channel is got before the version is read, so that no change is missed
+	w, changed := s.joinWatch(fsID)
+	defer s.leaveWatch(fsID, w)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	current, err := s.GetLinkVersion(fsID)
 	if err != nil {
-		log.Errorf("GetFileSystemWithFsID error[%v]", err)
-		return err
+		return nil, 0, err
 	}
-	fsMeta := base.FSMeta{
-		ID:            fsID,
-		Name:          fs.Name,
-		UfsType:       fs.Type,
-		ServerAddress: fs.ServerAddress,
-		SubPath:       fs.SubPath,
-		Properties:    fs.PropertiesMap,
-		Type:          base.FSType,
+	for current == version {
+		select {
+		case <-ctx.Done():
+			return nil, version, nil
+		case <-timer.C:
+			return nil, version, nil
+		case <-changed:
+		}
+		current, changed = s.watchedVersion(w)
 	}
-	client, err := fuse.NewFSClient(fsMeta, nil)
+	links, err := models.FsNameLinks(fsID)
 	if err != nil {
-		return err
+		log.Errorf("get links err[%v] with fsID[%s]", err, fsID)
+		return nil, 0, err
 	}
+	return links, current, nil
+}
 
-	dirPath := filepath.Join(config.GlobalServerConfig.Fs.LinkMetaDirPrefix, base.LinkMetaDir)
-	if err := client.MkdirAll(dirPath, os.ModePerm); err != nil {
-		log.Errorf("client mkdirAll file err:%v", err)
-		return err
+// joinWatch adds a watcher to the poll of fs, which is started by the first watcher
+func (s *LinkService) joinWatch(fsID string) (*linkWatch, <-chan struct{}) {
+	s.Lock()
+	defer s.Unlock()
+	w, ok := s.watches[fsID]
+	if !ok {
+		w = &linkWatch{
+			version: -1,
+			changed: make(chan struct{}),
+			poke:    make(chan struct{}, 1),
+			stop:    make(chan struct{}),
+		}
+		s.watches[fsID] = w
+		go s.pollLinkVersion(fsID, w)
 	}
+	w.watchers++
+	return w, w.changed
+}
 
-	tempSrcFile := uuid.NewString()
-	if err := client.SaveFile(strings.NewReader(encodedLinksMeta), dirPath, tempSrcFile); err != nil {
-		log.Errorf("client save file err:%v", err)
-		return err
+// leaveWatch removes a watcher from the poll of fs, which is stopped with the last watcher
+func (s *LinkService) leaveWatch(fsID string, w *linkWatch) {
+	s.Lock()
+	defer s.Unlock()
+	w.watchers--
+	if w.watchers == 0 {
+		close(w.stop)
+		delete(s.watches, fsID)
 	}
+}
 
-	srcPath := filepath.Join(dirPath, tempSrcFile)
-	dstPath := filepath.Join(dirPath, base.LinkMetaFile)
-	dstExit, _ := client.Exist(dstPath)
-	var tempDstPath string
-	if dstExit {
-		tempDstPath = filepath.Join(dirPath, uuid.NewString())
-		if err := client.Rename(dstPath, tempDstPath); err != nil {
-			return err
-		}
-		defer client.Remove(tempDstPath)
-	}
+func (s *LinkService) watchedVersion(w *linkWatch) (int64, <-chan struct{}) {
+	s.Lock()
+	defer s.Unlock()
+	return w.version, w.changed
+}
 
-	err = client.Rename(srcPath, dstPath)
-	if err != nil {
-		if tempDstPath != "" {
-			client.Rename(tempDstPath, dstPath)
+func (s *LinkService) pollLinkVersion(fsID string, w *linkWatch) {
+	ticker := time.NewTicker(LinkWatchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.poke:
 		}
-		return err
+		version, err := s.GetLinkVersion(fsID)
+		if err != nil {
+			continue
+		}
+		s.Lock()
+		if version != w.version {
+			w.version = version
+			close(w.changed)
+			w.changed = make(chan struct{})
+		}
+		s.Unlock()
 	}
-	return nil
 }
 
-// todo 考虑多server并发控制
-func (s *LinkService) FsLock(fsID string) func() {
-	value, _ := s.mutexes.LoadOrStore(fsID, &sync.Mutex{})
-	mtx := value.(*sync.Mutex)
-	mtx.Lock()
-
-	return func() { mtx.Unlock() }
+// notify polls the link version of fs at once for the watchers on this server, the watchers on
+// other servers find the change by their polls
+func (s *LinkService) notify(fsID string) {
+	s.Lock()
+	defer s.Unlock()
+	if w, ok := s.watches[fsID]; ok {
+		select {
+		case w.poke <- struct{}{}:
+		default:
+		}
+	}
 }
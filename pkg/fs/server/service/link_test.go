@@ -0,0 +1,86 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/database"
+	"paddleflow/pkg/common/database/db_fake"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/server/api/request"
+)
+
+func TestWatchLinks(t *testing.T) {
+	db_fake.InitFakeDB()
+	// each connection to the memory db of sqlite is a new db, the watchers must share one
+	sqlDB, err := database.DB.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	fsID := "fs-root-test"
+	assert.NoError(t, models.CreatFileSystem(&models.FileSystem{Model: models.Model{ID: fsID}, Name: "test", UserName: "root"}))
+	s := &LinkService{watches: make(map[string]*linkWatch)}
+
+	// the watch returns at timeout if the version is not changed
+	links, version, err := s.WatchLinks(context.Background(), fsID, 0, 10*time.Millisecond)
+	assert.NoError(t, err)
+	assert.Nil(t, links)
+	assert.Equal(t, int64(0), version)
+
+	type result struct {
+		links   []models.Link
+		version int64
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			links, version, err := s.WatchLinks(context.Background(), fsID, 0, time.Minute)
+			assert.NoError(t, err)
+			results <- result{links, version}
+		}()
+	}
+	// the watchers of the fs share one poll
+	assert.Eventually(t, func() bool {
+		s.Lock()
+		defer s.Unlock()
+		return len(s.watches) == 1 && s.watches[fsID].watchers == 2
+	}, time.Second, time.Millisecond)
+
+	ctx := &logger.RequestContext{}
+	_, err = s.CreateLink(ctx, &request.CreateLinkRequest{FsName: "test", Username: "root", FsPath: "/data",
+		Url: "local://tmp/link"})
+	assert.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-results:
+			assert.Equal(t, int64(1), r.version)
+			assert.Equal(t, 1, len(r.links))
+		case <-time.After(LinkWatchPollInterval / 2):
+			t.Fatal("the watchers are not woken up by the change of this server")
+		}
+	}
+
+	// the poll is stopped with the last watcher
+	s.Lock()
+	defer s.Unlock()
+	assert.Equal(t, 0, len(s.watches))
+}
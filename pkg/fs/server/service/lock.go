@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/server/api/common"
+	"paddleflow/pkg/fs/server/api/request"
+	"paddleflow/pkg/fs/server/api/response"
+	utils "paddleflow/pkg/fs/server/utils/fs"
+	"paddleflow/pkg/fs/utils/lock"
+)
+
+// LockSessionTTL is the time a session of mount keeps its locks without heartbeat
+const LockSessionTTL = 60 * time.Second
+
+// FileLockService coordinates the file locks across the mounts of the same fs, the locks and sessions
+// are kept in db, so that they are shared by the servers and survive restarts.
+type FileLockService struct{}
+
+var (
+	fileLockService *FileLockService
+	fileLockOnce    sync.Once
+)
+
+// GetFileLockService returns the instance of file lock service
+func GetFileLockService() *FileLockService {
+	fileLockOnce.Do(func() {
+		fileLockService = &FileLockService{}
+		go fileLockService.expire()
+	})
+	return fileLockService
+}
+
+// expire releases the locks of the crashed mounts
+func (s *FileLockService) expire() {
+	ticker := time.NewTicker(LockSessionTTL / 2)
+	defer ticker.Stop()
+	for range ticker.C {
+		sessions, err := models.ListExpiredLockSessions(time.Now().Add(-LockSessionTTL))
+		if err != nil {
+			log.Errorf("list expired lock sessions failed: %v", err)
+			continue
+		}
+		for _, session := range sessions {
+			if err := s.releaseSession(session); err != nil {
+				log.Errorf("release expired lock session[%s] failed: %v", session.ID, err)
+				continue
+			}
+			log.Infof("lock session[%s] expired, its locks are released", session.ID)
+		}
+	}
+}
+
+// updateLocks loads the locks of the path into a table, calls update with it and saves the locks left
+func updateLocks(fsID, path string, update func(table *lock.Table, key string)) error {
+	return models.UpdateFileLocks(fsID, path, func(data string) (string, error) {
+		var held []lock.Held
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), &held); err != nil {
+				return "", err
+			}
+		}
+		table := lock.NewTable()
+		table.Restore(path, held)
+		update(table, path)
+		held = table.Locks(path)
+		if len(held) == 0 {
+			return "", nil
+		}
+		result, err := json.Marshal(held)
+		return string(result), err
+	})
+}
+
+// checkSession checks the session is of the user on the fs, a session not existed is owned by nobody
+func checkSession(ctx *logger.RequestContext, userName string, session models.LockSession) error {
+	if session.ID == "" || userName == utils.UserRoot || session.UserName == userName {
+		return nil
+	}
+	ctx.ErrorCode = common.FsAccessDenied
+	return fmt.Errorf("lock session[%s] is not of user[%s]", session.ID, userName)
+}
+
+func (s *FileLockService) getSession(ctx *logger.RequestContext, userName, id string) (models.LockSession, error) {
+	session, err := models.GetLockSession(id)
+	if err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return session, err
+	}
+	return session, checkSession(ctx, userName, session)
+}
+
+// FileLock puts, releases or tests the lock in request for the user
+func (s *FileLockService) FileLock(ctx *logger.RequestContext, userName string,
+	req *request.FileLockRequest) (response.FileLockResponse, error) {
+	session, err := s.getSession(ctx, userName, req.Session)
+	if err != nil {
+		return response.FileLockResponse{}, err
+	}
+	if session.ID != "" && session.FsID != req.FsID {
+		ctx.ErrorCode = common.InvalidFileLock
+		return response.FileLockResponse{}, fmt.Errorf("lock session[%s] is of fs[%s]", session.ID, session.FsID)
+	}
+	if session.ID == "" {
+		session = models.LockSession{ID: req.Session, FsID: req.FsID, UserName: userName}
+	}
+	if err = models.TouchLockSession(&session); err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return response.FileLockResponse{}, err
+	}
+
+	var resp response.FileLockResponse
+	owner := lock.Owner{Session: req.Session, ID: req.Owner}
+	err = updateLocks(req.FsID, req.Path, func(table *lock.Table, key string) {
+		if req.Flock {
+			resp = response.FileLockResponse{Granted: table.Flock(key, owner, req.Type)}
+			return
+		}
+		r := lock.Range{Type: req.Type, Start: req.Start, End: req.End, Pid: req.Pid}
+		if !req.Test && table.Setlk(key, owner, r) {
+			resp = response.FileLockResponse{Granted: true}
+			return
+		}
+		held := table.Getlk(key, owner, r)
+		resp = response.FileLockResponse{
+			Granted: held.Type == syscall.F_UNLCK && req.Test,
+			Type:    held.Type,
+			Start:   held.Start,
+			End:     held.End,
+			Pid:     held.Pid,
+		}
+	})
+	if err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return response.FileLockResponse{}, err
+	}
+	return resp, nil
+}
+
+// KeepSession refreshes the session of a mount
+func (s *FileLockService) KeepSession(ctx *logger.RequestContext, userName, id string) error {
+	session, err := s.getSession(ctx, userName, id)
+	if err != nil || session.ID == "" {
+		return err
+	}
+	if err = models.TouchLockSession(&session); err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+	}
+	return err
+}
+
+// ReleaseSession releases all the locks of a mount, e.g. on umount
+func (s *FileLockService) ReleaseSession(ctx *logger.RequestContext, userName, id string) error {
+	session, err := s.getSession(ctx, userName, id)
+	if err != nil || session.ID == "" {
+		return err
+	}
+	if err = s.releaseSession(session); err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+	}
+	return err
+}
+
+func (s *FileLockService) releaseSession(session models.LockSession) error {
+	fileLocks, err := models.ListFileLocksOfSession(session.FsID, session.ID)
+	if err != nil {
+		return err
+	}
+	for _, fileLock := range fileLocks {
+		err = updateLocks(fileLock.FsID, fileLock.Path, func(table *lock.Table, key string) {
+			table.ReleaseSession(session.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return models.DeleteLockSession(session.ID)
+}
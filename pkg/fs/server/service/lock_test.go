@@ -0,0 +1,68 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/database/db_fake"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/server/api/request"
+)
+
+func TestFileLockService(t *testing.T) {
+	db_fake.InitFakeDB()
+	ctx := &logger.RequestContext{}
+	req := &request.FileLockRequest{FsID: "fs-root-test", Path: "/a", Session: "s1", Owner: 1,
+		Flock: true, Type: syscall.F_WRLCK}
+	resp, err := (&FileLockService{}).FileLock(ctx, "root", req)
+	assert.NoError(t, err)
+	assert.True(t, resp.Granted)
+
+	// the locks are kept in db, another server instance sees them
+	other := &FileLockService{}
+	req2 := *req
+	req2.Session = "s2"
+	resp, err = other.FileLock(ctx, "user1", &req2)
+	assert.NoError(t, err)
+	assert.False(t, resp.Granted)
+
+	// the session is of root, other users can not use or release it
+	_, err = other.FileLock(ctx, "user1", req)
+	assert.Error(t, err)
+	assert.Error(t, other.ReleaseSession(ctx, "user1", "s1"))
+	assert.NoError(t, other.KeepSession(ctx, "root", "s1"))
+
+	assert.NoError(t, other.ReleaseSession(ctx, "root", "s1"))
+	resp, err = other.FileLock(ctx, "user1", &req2)
+	assert.NoError(t, err)
+	assert.True(t, resp.Granted)
+
+	// the locks of the expired sessions are released
+	sessions, err := models.ListExpiredLockSessions(time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sessions))
+	assert.NoError(t, other.releaseSession(sessions[0]))
+	fileLocks, err := models.ListFileLocksOfSession("fs-root-test", "s2")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(fileLocks))
+}
@@ -18,6 +18,7 @@ package service
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
@@ -69,6 +70,8 @@ func (s *FileSystemService) CreateFileSystem(ctx *logger.RequestContext, req *re
 		Type:          fsType,
 		SubPath:       subPath,
 		UserName:      req.Username,
+
+		MountOptionsMap: req.MountOptions,
 	}
 	fs.ID = utils.ID(req.Username, req.Name)
 
@@ -83,24 +86,52 @@ func (s *FileSystemService) CreateFileSystem(ctx *logger.RequestContext, req *re
 
 // GetFileSystem the function which performs the operation of getting file system detail
 func (s *FileSystemService) GetFileSystem(req *request.GetFileSystemRequest, fsID string) (models.FileSystem, error) {
-	if req.Username == utils.UserRoot {
-		req.Username = ""
-	}
-	modelsFs, err := models.GetFileSystemWithFsIDAndUserName(fsID, req.Username)
+	modelsFs, _, err := s.GetFileSystemWithAccess(&logger.RequestContext{}, req, fsID)
+	return modelsFs, err
+}
+
+// GetFileSystemWithAccess gets the file system detail with the access of the user, the access is nil
+// for root and the owner of fs, and the file system shared by others can be got if it is shared with the user
+func (s *FileSystemService) GetFileSystemWithAccess(ctx *logger.RequestContext, req *request.GetFileSystemRequest,
+	fsID string) (models.FileSystem, base.FsAccess, error) {
+	modelsFs, err := models.GetFsWithID(fsID)
 	if err != nil {
 		log.Errorf("get file system err[%v]", err)
-		return models.FileSystem{}, err
+		return models.FileSystem{}, nil, err
 	}
 	if modelsFs.ID == "" {
 		log.Errorf("get file system empty with username[%s] fsid[%s]", req.Username, fsID)
-		return models.FileSystem{}, common.New("Get file system is empty")
+		return models.FileSystem{}, nil, common.New("Get file system is empty")
 	}
-	return modelsFs, err
+	access, err := GetFsShareService().GetAccess(req.Username, modelsFs)
+	if err != nil {
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return models.FileSystem{}, nil, err
+	}
+	if access != nil && len(access) == 0 {
+		log.Errorf("file system[%s] is not shared with username[%s]", fsID, req.Username)
+		ctx.ErrorCode = common.FsAccessDenied
+		return models.FileSystem{}, nil, common.FsAccessError(req.Username, fsID)
+	}
+	return modelsFs, access, nil
 }
 
 // DeleteFileSystem the function which performs the operation of delete file system
 func (s *FileSystemService) DeleteFileSystem(ctx *logger.RequestContext, fsID string) error {
-	err := deletePVC(fsID)
+	sharedUsers, err := GetFsShareService().sharedUsers(fsID)
+	if err != nil {
+		ctx.Logging().Errorf("list shared users of fs[%s] error[%v]", fsID, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return err
+	}
+	for _, userName := range sharedUsers {
+		if err = deleteClaims(ClaimFsID(fsID, userName, base.FsAccess{})); err != nil {
+			ctx.Logging().Errorf("delete pv and pvc of shared user[%s] error[%v]", userName, err)
+			ctx.ErrorCode = common.K8sOperatorError
+			return err
+		}
+	}
+	err = deletePVC(fsID)
 	if err != nil {
 		ctx.Logging().Errorf("delete pvc error[%v]", err)
 		ctx.ErrorCode = common.K8sOperatorError
@@ -112,6 +143,7 @@ func (s *FileSystemService) DeleteFileSystem(ctx *logger.RequestContext, fsID st
 		ctx.ErrorCode = common.FileSystemDataBaseError
 		return err
 	}
+	GetUsageService().DeleteUsage(fsID)
 	return err
 }
 
@@ -173,19 +205,45 @@ func (s *FileSystemService) CreateFileSystemClaims(ctx *logger.RequestContext, r
 	}
 
 	for k, fsID := range req.FsIDs {
-		for _, ns := range req.Namespaces {
-			if fsmodelss[k].Type == base.MockType {
-				continue
+		if fsmodelss[k].Type == base.MockType {
+			continue
+		}
+		access, err := GetFsShareService().GetAccess(req.Username, fsmodelss[k])
+		if err != nil {
+			ctx.Logging().Errorf("get access of user[%s] to fs[%s] failed: %v", req.Username, fsID, err)
+			ctx.ErrorCode = common.FileSystemDataBaseError
+			return err
+		}
+		if access != nil && len(access) == 0 {
+			ctx.ErrorCode = common.FsAccessDenied
+			return common.FsAccessError(req.Username, fsID)
+		}
+		claimFsID := ClaimFsID(fsID, req.Username, access)
+		// the fs shared by others is mounted with the access of the user, which cannot be overridden
+		attributes := kernelMountAttributes(fsmodelss[k])
+		if attributes != nil && !kernelMountAccess(access) {
+			ctx.ErrorCode = common.FsAccessDenied
+			return fmt.Errorf("the paths of fs[%s] mounted by the kernel are shared with user[%s], "+
+				"which can only be shared as a whole", fsID, req.Username)
+		}
+		if options := shareMountOptions(req.Username, access); options != nil {
+			if attributes == nil {
+				attributes = make(map[string]string, len(options))
+			}
+			for key, value := range options {
+				attributes[key] = value
 			}
+		}
+		for _, ns := range req.Namespaces {
 			var pv string
 			userName := fsmodelss[k].UserName
-			if pv, err = createPV(ns, fsID, userName); err != nil {
+			if pv, err = createPV(ns, fsID, claimFsID, userName, fsmodelss[k].MountOptionsMap, attributes); err != nil {
 				ctx.Logging().Errorf("create PV with file system[%v] in namespace[%v] failed: %v",
 					fsID, ns, err)
 				ctx.ErrorCode = common.K8sOperatorError
 				return err
 			}
-			if err = createPVC(ns, fsID, pv); err != nil {
+			if err = createPVC(ns, claimFsID, pv); err != nil {
 				ctx.Logging().Errorf("create PVC with file system[%v] in namespace[%v] failed: %v",
 					fsID, ns, err)
 				ctx.ErrorCode = common.K8sOperatorError
@@ -227,18 +285,69 @@ func deletePVC(fsID string) error {
 	return nil
 }
 
-func createPV(namespace, fsId, userName string) (string, error) {
+// deleteClaims deletes the pvcs and pvs of the claim fs id in all namespaces, e.g. the claims of the user
+// whose access to the shared fs is changed
+func deleteClaims(claimFsID string) error {
+	nsList, err := k8s.GetK8sOperator().ListNamespaces(metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("list namespaces when clean claims of [%s] failed: %v", claimFsID, err)
+		return err
+	}
+	for _, item := range nsList.Items {
+		if err := deleteClaim(item.Name, claimFsID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteClaim deletes the pvc and pv of the claim fs id in namespace, the pods using them keep the mounts
+// until they are stopped
+func deleteClaim(namespace, claimFsID string) error {
+	k8sOperator := k8s.GetK8sOperator()
+	propagationPolicy := metav1.DeletePropagationBackground
+	deleteOptions := &metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}
+	pvcName := claimPVCName(claimFsID)
+	if err := k8sOperator.DeletePersistentVolumeClaim(namespace, pvcName, deleteOptions); err != nil && !k8serrors.IsNotFound(err) {
+		log.Errorf("delete pvc[%s/%s] failed: %v", namespace, pvcName, err)
+		return err
+	}
+	pvName := claimPVName(namespace, claimFsID)
+	if err := k8sOperator.DeletePersistentVolume(pvName, deleteOptions); err != nil && !k8serrors.IsNotFound(err) {
+		log.Errorf("delete pv[%s] failed: %v", pvName, err)
+		return err
+	}
+	return nil
+}
+
+func claimPVName(namespace, claimFsID string) string {
+	pvName := strings.Replace(config.DefaultPV.Name, FSIDFormat, claimFsID, -1)
+	return strings.Replace(pvName, NameSpaceFormat, namespace, -1)
+}
+
+func claimPVCName(claimFsID string) string {
+	return strings.Replace(config.DefaultPVC.Name, FSIDFormat, claimFsID, -1)
+}
+
+// kernelMountAttributes returns the volume attributes for the csi plugin to mount the fs by the kernel,
+// nil if the fs is mounted by pfs-fuse
+func kernelMountAttributes(fsModel models.FileSystem) map[string]string {
+	return base.KernelVolumeAttributes(base.FSMeta{
+		UfsType:       fsModel.Type,
+		ServerAddress: fsModel.ServerAddress,
+		SubPath:       fsModel.SubPath,
+		Properties:    fsModel.PropertiesMap,
+	})
+}
+
+// createPV creates the pv of fs named by the claim fs id, the attributes override the ones in the pv template.
+// The pv existing with other attributes, e.g. the access of the user to the shared fs is changed, is deleted
+// with its pvc and created again, as the csi source of pv can not be updated.
+func createPV(namespace, fsId, claimFsID, userName string, mountOptions, attributes map[string]string) (string, error) {
 	k8sOperator := k8s.GetK8sOperator()
 	pv := config.DefaultPV
 	// format pvname to fsid
-	pvName := strings.Replace(pv.Name, FSIDFormat, fsId, -1)
-	pvName = strings.Replace(pvName, NameSpaceFormat, namespace, -1)
-	// check pv existence
-	if _, err := k8sOperator.GetPersistentVolume(pvName, metav1.GetOptions{}); err == nil {
-		return "", nil
-	} else if !k8serrors.IsNotFound(err) {
-		return "", err
-	}
+	pvName := claimPVName(namespace, claimFsID)
 	// construct a new pv
 	newPV := &apiv1.PersistentVolume{}
 	if err := copier.Copy(newPV, pv); err != nil {
@@ -247,6 +356,13 @@ func createPV(namespace, fsId, userName string) (string, error) {
 	newPV.Name = pvName
 	csi := newPV.Spec.CSI
 	if csi != nil && csi.VolumeAttributes != nil {
+		// the csi source is shared with the template by copier, which must not be modified
+		csiSource := *csi
+		csiSource.VolumeAttributes = make(map[string]string, len(csi.VolumeAttributes)+len(mountOptions)+len(attributes))
+		for key, value := range csi.VolumeAttributes {
+			csiSource.VolumeAttributes[key] = value
+		}
+		newPV.Spec.CSI = &csiSource
 		if _, ok := csi.VolumeAttributes[FSID]; ok {
 			newPV.Spec.CSI.VolumeAttributes[FSID] = fsId
 			newPV.Spec.CSI.VolumeHandle = pvName
@@ -257,19 +373,50 @@ func createPV(namespace, fsId, userName string) (string, error) {
 		if _, ok := csi.VolumeAttributes[PFSServer]; ok {
 			newPV.Spec.CSI.VolumeAttributes[PFSServer] = fmt.Sprintf("%s:%d", config.GlobalServerConfig.Fs.K8sServiceName, config.GlobalServerConfig.Fs.K8sServicePort)
 		}
+		// the default mount options of fs, which are overridden by the ones in the pv template
+		for key, value := range mountOptions {
+			if _, ok := csi.VolumeAttributes[key]; !ok {
+				newPV.Spec.CSI.VolumeAttributes[key] = value
+			}
+		}
+		for key, value := range attributes {
+			newPV.Spec.CSI.VolumeAttributes[key] = value
+		}
+	}
+	// check pv existence
+	if existed, err := k8sOperator.GetPersistentVolume(pvName, metav1.GetOptions{}); err == nil {
+		if existed.DeletionTimestamp != nil {
+			return "", fmt.Errorf("pv[%s] is being deleted, retry after the pods using it are stopped", pvName)
+		}
+		if existed.Spec.CSI == nil || newPV.Spec.CSI == nil ||
+			reflect.DeepEqual(existed.Spec.CSI.VolumeAttributes, newPV.Spec.CSI.VolumeAttributes) {
+			return pvName, nil
+		}
+		log.Infof("the attributes of pv[%s] are changed, recreate it", pvName)
+		if err := deleteClaim(namespace, claimFsID); err != nil {
+			return "", err
+		}
+	} else if !k8serrors.IsNotFound(err) {
+		return "", err
 	}
 	// create pv in k8s
 	if _, err := k8sOperator.CreatePersistentVolume(newPV); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("pv[%s] is being deleted, retry after the pods using it are stopped", pvName)
+		}
 		return "", err
 	}
 	return pvName, nil
 }
-func createPVC(namespace, fsId, pv string) error {
+func createPVC(namespace, claimFsID, pv string) error {
 	k8sOperator := k8s.GetK8sOperator()
 	pvc := config.DefaultPVC
-	pvcName := strings.Replace(pvc.Name, FSIDFormat, fsId, -1)
+	pvcName := claimPVCName(claimFsID)
 	// check pvc existence
-	if _, err := k8sOperator.GetPersistentVolumeClaim(namespace, pvcName, metav1.GetOptions{}); err == nil {
+	if existed, err := k8sOperator.GetPersistentVolumeClaim(namespace, pvcName, metav1.GetOptions{}); err == nil {
+		if existed.DeletionTimestamp != nil {
+			return fmt.Errorf("pvc[%s/%s] is being deleted, retry after the pods using it are stopped", namespace, pvcName)
+		}
 		return nil
 	} else if !k8serrors.IsNotFound(err) {
 		return err
@@ -0,0 +1,202 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/common/logger"
+	"paddleflow/pkg/fs/client/base"
+	"paddleflow/pkg/fs/server/api/common"
+	"paddleflow/pkg/fs/server/api/request"
+	utils "paddleflow/pkg/fs/server/utils/fs"
+)
+
+// FsShareService the service which contains the operation of fs shares
+type FsShareService struct{}
+
+var (
+	fsShareService *FsShareService
+	fsShareOnce    sync.Once
+)
+
+// GetFsShareService returns the instance of fs share service
+func GetFsShareService() *FsShareService {
+	fsShareOnce.Do(func() {
+		fsShareService = &FsShareService{}
+	})
+	return fsShareService
+}
+
+// CreateFsShare shares the path of fs with the user or group, the permission is updated if it is shared already
+func (s *FsShareService) CreateFsShare(ctx *logger.RequestContext, req *request.CreateFsShareRequest) (models.FsShare, error) {
+	share := models.FsShare{
+		FsID:       utils.ID(req.Username, req.FsName),
+		FsPath:     req.FsPath,
+		ShareType:  req.ShareType,
+		ShareTo:    req.ShareTo,
+		Permission: req.Permission,
+	}
+	if err := models.SaveFsShare(&share); err != nil {
+		ctx.Logging().Errorf("save fs share[%v] err[%v]", share, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return models.FsShare{}, err
+	}
+	ctx.Logging().Infof("path[%s] of fs[%s] shared with %s[%s] as %s", share.FsPath, share.FsID,
+		share.ShareType, share.ShareTo, share.Permission)
+	return share, nil
+}
+
+// DeleteFsShare stops sharing the fs by the share id
+func (s *FsShareService) DeleteFsShare(ctx *logger.RequestContext, req *request.DeleteFsShareRequest) error {
+	fsID := utils.ID(req.Username, req.FsName)
+	share, err := models.GetFsShare(fsID, req.ShareID)
+	if err != nil {
+		ctx.Logging().Errorf("get fs share[%s] of fs[%s] err[%v]", req.ShareID, fsID, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return err
+	}
+	if share.ID == "" {
+		ctx.ErrorCode = common.FsShareNotExist
+		return common.New("fs share not exist")
+	}
+	if err = models.DeleteFsShare(fsID, req.ShareID); err != nil {
+		ctx.Logging().Errorf("delete fs share[%s] of fs[%s] err[%v]", req.ShareID, fsID, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return err
+	}
+	// the claims mounted with the former access are deleted, the ones of the users still having access
+	// are created again with their access by the next CreatePVC
+	users := []string{share.ShareTo}
+	if share.ShareType == models.FsShareToGroup {
+		if users, err = models.ListUsersOfGroup(share.ShareTo); err != nil {
+			ctx.Logging().Errorf("list users of group[%s] err[%v]", share.ShareTo, err)
+			ctx.ErrorCode = common.FileSystemDataBaseError
+			return err
+		}
+	}
+	for _, userName := range users {
+		if err = deleteClaims(ClaimFsID(fsID, userName, base.FsAccess{})); err != nil {
+			ctx.Logging().Errorf("delete pv and pvc of user[%s] to fs[%s] err[%v]", userName, fsID, err)
+			ctx.ErrorCode = common.K8sOperatorError
+			return err
+		}
+	}
+	return nil
+}
+
+// ListFsShares lists the shares of fs
+func (s *FsShareService) ListFsShares(ctx *logger.RequestContext, req *request.ListFsShareRequest) ([]models.FsShare, error) {
+	fsID := utils.ID(req.Username, req.FsName)
+	shares, err := models.ListFsShares(fsID)
+	if err != nil {
+		ctx.Logging().Errorf("list fs shares of fs[%s] err[%v]", fsID, err)
+		ctx.ErrorCode = common.FileSystemDataBaseError
+		return nil, err
+	}
+	return shares, nil
+}
+
+// GetAccess returns the access of the user to the fs, which is nil for root and the owner of fs,
+// and empty if the fs is not shared with the user
+func (s *FsShareService) GetAccess(userName string, fsModel models.FileSystem) (base.FsAccess, error) {
+	if userName == "" || userName == utils.UserRoot || userName == fsModel.UserName {
+		return nil, nil
+	}
+	groups, err := models.ListGroupsOfUser(userName)
+	if err != nil {
+		log.Errorf("list groups of user[%s] err[%v]", userName, err)
+		return nil, err
+	}
+	shares, err := models.ListFsSharesTo(fsModel.ID, userName, groups)
+	if err != nil {
+		log.Errorf("list shares of fs[%s] to user[%s] err[%v]", fsModel.ID, userName, err)
+		return nil, err
+	}
+	access := base.FsAccess{}
+	for _, share := range shares {
+		access.Add(share.FsPath, share.Permission)
+	}
+	return access, nil
+}
+
+// sharedUsers returns the users the fs is shared with, including the users in the groups it is shared with
+func (s *FsShareService) sharedUsers(fsID string) ([]string, error) {
+	shares, err := models.ListFsShares(fsID)
+	if err != nil {
+		return nil, err
+	}
+	var users []string
+	seen := make(map[string]bool)
+	for _, share := range shares {
+		names := []string{share.ShareTo}
+		if share.ShareType == models.FsShareToGroup {
+			if names, err = models.ListUsersOfGroup(share.ShareTo); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names {
+			if !seen[name] {
+				seen[name] = true
+				users = append(users, name)
+			}
+		}
+	}
+	return users, nil
+}
+
+// ClaimFsID returns the fs id in the names of pv and pvc, the claims of the users the fs is shared with are
+// separated from the ones of the owner, as they are mounted with the access of the users. fs ids never contain
+// dots, so the claim ids of the users never conflict with the ones of other fs.
+func ClaimFsID(fsID, userName string, access base.FsAccess) string {
+	if access == nil {
+		return fsID
+	}
+	return fsID + "." + userName
+}
+
+// kernelMountAccess reports whether the access can be enforced on the fs mounted by the kernel, which is
+// not served by pfs-fuse, so only the access to the whole fs is enforced by the read-only mount
+func kernelMountAccess(access base.FsAccess) bool {
+	for dir := range access {
+		if dir != "/" {
+			return false
+		}
+	}
+	return true
+}
+
+// shareMountOptions returns the mount options for the user to mount the fs shared with the user, the fs is
+// mounted from the deepest dir containing all the shared paths, read-only if all of them are read-only
+func shareMountOptions(userName string, access base.FsAccess) map[string]string {
+	if access == nil {
+		return nil
+	}
+	options := map[string]string{base.MountAccessUser: userName}
+	root, readOnly := access.Root()
+	if root != "/" {
+		options[base.MountSubPath] = strings.TrimPrefix(root, "/")
+	}
+	if readOnly {
+		options[base.MountReadOnly] = "true"
+	}
+	return options
+}
@@ -0,0 +1,47 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"paddleflow/pkg/fs/client/base"
+)
+
+func TestShareMountOptions(t *testing.T) {
+	assert.Nil(t, shareMountOptions("user1", nil))
+	assert.True(t, kernelMountAccess(nil))
+
+	access := base.FsAccess{}
+	access.Add("/", base.AccessReadOnly)
+	assert.True(t, kernelMountAccess(access))
+	options := shareMountOptions("user1", access)
+	assert.Equal(t, "user1", options[base.MountAccessUser])
+	assert.Equal(t, "true", options[base.MountReadOnly])
+	assert.Equal(t, "", options[base.MountSubPath])
+
+	// the paths are only enforced by pfs-fuse
+	access = base.FsAccess{}
+	access.Add("/data/a", base.AccessReadOnly)
+	access.Add("/data/b", base.AccessReadWrite)
+	assert.False(t, kernelMountAccess(access))
+	options = shareMountOptions("user1", access)
+	assert.Equal(t, "data", options[base.MountSubPath])
+	assert.Equal(t, "", options[base.MountReadOnly])
+}
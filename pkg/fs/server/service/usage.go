@@ -0,0 +1,196 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"paddleflow/pkg/apiserver/models"
+	"paddleflow/pkg/fs/client/base"
+	fuse "paddleflow/pkg/fs/client/fs"
+	"paddleflow/pkg/fs/server/api/response"
+	utils "paddleflow/pkg/fs/server/utils/fs"
+)
+
+const (
+	// UsageScanInterval is the min interval between the scans of a fs, the usage changed by the
+	// server side clients between the scans is updated incrementally
+	UsageScanInterval = 10 * time.Minute
+	UsageTopDirs      = 10
+)
+
+// UsageService scans the usages of the file systems in background
+type UsageService struct {
+	sync.Mutex
+	usages map[string]*fsUsage
+}
+
+type fsUsage struct {
+	stats    *fuse.UsageStats
+	scanTime time.Time
+	scanning bool
+	err      error
+}
+
+var (
+	usageService *UsageService
+	usageOnce    sync.Once
+)
+
+// GetUsageService returns the instance of usage service
+func GetUsageService() *UsageService {
+	usageOnce.Do(func() {
+		usageService = &UsageService{usages: make(map[string]*fsUsage)}
+		fuse.SetUsageListener(usageService.update)
+	})
+	return usageService
+}
+
+// GetUsage returns the usage last scanned of fs, a scan is started in background if the
+// fs is never scanned or the usage is older than UsageScanInterval
+func (s *UsageService) GetUsage(fsModel models.FileSystem) response.FileSystemUsageResponse {
+	s.Lock()
+	defer s.Unlock()
+	usage, ok := s.usages[fsModel.ID]
+	if !ok {
+		usage = &fsUsage{}
+		s.usages[fsModel.ID] = usage
+	}
+	if !usage.scanning && time.Since(usage.scanTime) > UsageScanInterval {
+		usage.scanning = true
+		go s.scan(fsModel)
+	}
+
+	resp := response.FileSystemUsageResponse{
+		FsName:   fsModel.Name,
+		TopDirs:  []response.DirUsageResponse{},
+		Links:    []response.LinkUsageResponse{},
+		Scanning: usage.scanning,
+	}
+	if quota, hard := base.GetQuota(fsModel.PropertiesMap); quota > 0 {
+		resp.Quota, resp.QuotaType = quota, base.SoftQuota
+		if hard {
+			resp.QuotaType = base.HardQuota
+		}
+	}
+	if !usage.scanTime.IsZero() {
+		resp.ScanTime = usage.scanTime.Format(TimeFormat)
+	}
+	if usage.err != nil {
+		resp.ScanError = usage.err.Error()
+	}
+	if usage.stats == nil {
+		return resp
+	}
+	resp.Usage = usageResponse(usage.stats.Usage)
+	for _, dir := range usage.stats.TopDirs(UsageTopDirs) {
+		resp.TopDirs = append(resp.TopDirs, response.DirUsageResponse{Path: dir.Path, Usage: usageResponse(dir.Usage)})
+	}
+	for fsPath, linkUsage := range usage.stats.Links {
+		resp.Links = append(resp.Links, response.LinkUsageResponse{FsPath: fsPath, Usage: usageResponse(*linkUsage)})
+	}
+	sort.Slice(resp.Links, func(i, j int) bool {
+		return resp.Links[i].FsPath < resp.Links[j].FsPath
+	})
+	return resp
+}
+
+// DeleteUsage drops the usage of the fs deleted
+func (s *UsageService) DeleteUsage(fsID string) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.usages, fsID)
+}
+
+// scan walks the fs and its links, the usage changed during the scan may be missed or counted
+// twice until the next scan
+func (s *UsageService) scan(fsModel models.FileSystem) {
+	stats, err := scanUsage(fsModel)
+	s.Lock()
+	defer s.Unlock()
+	usage, ok := s.usages[fsModel.ID]
+	if !ok {
+		return
+	}
+	usage.scanning = false
+	usage.scanTime = time.Now()
+	usage.err = err
+	if err != nil {
+		log.Errorf("scan usage of fs[%s] failed: %v", fsModel.ID, err)
+		return
+	}
+	usage.stats = stats
+}
+
+func scanUsage(fsModel models.FileSystem) (*fuse.UsageStats, error) {
+	links, err := models.FsNameLinks(fsModel.ID)
+	if err != nil {
+		log.Errorf("get links err[%v] with fsID[%s]", err, fsModel.ID)
+		return nil, err
+	}
+	fsMeta := base.FSMeta{
+		ID:            fsModel.ID,
+		Name:          fsModel.Name,
+		UfsType:       fsModel.Type,
+		ServerAddress: fsModel.ServerAddress,
+		SubPath:       fsModel.SubPath,
+		Properties:    fsModel.PropertiesMap,
+		Type:          base.FSType,
+	}
+	linksMeta := make(map[string]base.FSMeta)
+	linkPaths := make([]string, 0, len(links))
+	for _, link := range links {
+		linksMeta[link.FsPath] = base.FSMeta{
+			ID:            link.ID,
+			Name:          utils.FSIDToName(link.ID),
+			UfsType:       link.Type,
+			ServerAddress: link.ServerAddress,
+			SubPath:       link.SubPath,
+			Properties:    link.PropertiesMap,
+			Type:          base.LinkType,
+		}
+		linkPaths = append(linkPaths, link.FsPath)
+	}
+	client, err := fuse.NewFSClient(fsMeta, linksMeta)
+	if err != nil {
+		log.Errorf("new fs client of fs[%s] failed: %v", fsModel.ID, err)
+		return nil, err
+	}
+	defer client.Close()
+	return fuse.ScanUsage(client, linkPaths)
+}
+
+// update applies the usage changed by the server side clients to the usage scanned
+func (s *UsageService) update(fsID, path string, delta fuse.Usage) {
+	s.Lock()
+	defer s.Unlock()
+	if usage, ok := s.usages[fsID]; ok && usage.stats != nil {
+		usage.stats.Add(path, delta)
+	}
+}
+
+func usageResponse(usage fuse.Usage) response.Usage {
+	return response.Usage{
+		Bytes: usage.Bytes,
+		Files: usage.Files,
+		Dirs:  usage.Dirs,
+	}
+}
@@ -19,11 +19,15 @@ package fs
 import (
 	"encoding/base64"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 
+	apicommon "paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/server/api/common"
 )
@@ -34,6 +38,14 @@ const (
 	S3                            = "s3"
 	Local                         = "local"
 	SFTP                          = "sftp"
+	GCS                           = "gcs"
+	Azblob                        = "azblob"
+	NFS                           = "nfs"
+	CephFS                        = "cephfs"
+	Lustre                        = "lustre"
+	WebDAV                        = "webdav"
+	HTTP                          = "http"
+	HTTPS                         = "https"
 	Mock                          = "mock"
 	IPDomainOrIPDomainPortPattern = "^([a-zA-Z0-9][-a-zA-Z0-9]{0,62}(\\.[a-zA-Z0-9][-a-zA-Z0-9]{0,62})+)" +
 		"(:([1-9]|[1-9]\\d{1,3}|[1-5]\\d{4}|6[0-4]\\d{3}|65[0-4]\\d{2}|655[0-2]\\d|6553[0-5]))?$"
@@ -77,10 +89,15 @@ func InformationFromURL(url string, properties map[string]string) (fileSystemTyp
 		if properties[base.KeyTabData] != "" {
 			fileSystemType = HDFSWithKerberos
 		}
-	case SFTP:
+	case SFTP, NFS, CephFS, Lustre, WebDAV:
 		serverAddress = urlSplit[ServerAddressSplit]
 		subPath = "/" + SubPathFromUrl(urlSplit, HDFSSplit)
-	case S3:
+	case HTTP, HTTPS:
+		// the scheme is kept in properties
+		fileSystemType = HTTP
+		serverAddress = urlSplit[ServerAddressSplit]
+		subPath = "/" + SubPathFromUrl(urlSplit, HDFSSplit)
+	case S3, GCS, Azblob:
 		serverAddress = properties[base.Endpoint]
 		subPath = "/" + SubPathFromUrl(urlSplit, S3Split)
 	default:
@@ -141,6 +158,120 @@ func CheckKerberosProperties(properties map[string]string) error {
 	return nil
 }
 
+// CheckObjectStorageProperties checks the properties of s3, gcs and azblob, the endpoint of gcs and azblob
+// is set to the public one if empty, and the secrets are encrypted
+func CheckObjectStorageProperties(fsType string, properties map[string]string) error {
+	var secret string
+	switch fsType {
+	case S3:
+		if properties[base.AccessKey] == "" || properties[base.SecretKey] == "" {
+			log.Error("s3 ak or sk is empty")
+			return common.InvalidField("properties", fmt.Sprintf("key %s or %s is empty", base.AccessKey, base.SecretKey))
+		}
+		if properties[base.Endpoint] == "" {
+			log.Error("endpoint is empty")
+			return common.InvalidField("properties", "key[endpoint] is empty")
+		}
+		if properties[base.Bucket] == "" {
+			log.Error("bucket is empty")
+			return common.InvalidField("properties", "url bucket is empty")
+		}
+		secret = base.SecretKey
+	case GCS:
+		if properties[base.Bucket] == "" {
+			log.Error("bucket is empty")
+			return common.InvalidField("properties", "url bucket is empty")
+		}
+		if properties[base.Endpoint] == "" {
+			properties[base.Endpoint] = base.GCSDefaultEndpoint
+		}
+		// without credentials, the application default credentials are used for google cloud
+		if properties[base.Credentials] == "" {
+			return nil
+		}
+		secret = base.Credentials
+	case Azblob:
+		if properties[base.AccountName] == "" || properties[base.AccountKey] == "" {
+			log.Error("azblob account name or key is empty")
+			return common.InvalidField("properties", fmt.Sprintf("key %s or %s is empty", base.AccountName, base.AccountKey))
+		}
+		if properties[base.Bucket] == "" {
+			log.Error("container is empty")
+			return common.InvalidField("properties", "url container is empty")
+		}
+		if properties[base.Endpoint] == "" {
+			properties[base.Endpoint] = fmt.Sprintf(base.AzblobDefaultEndpointFormat, properties[base.AccountName])
+		}
+		secret = base.AccountKey
+	default:
+		return nil
+	}
+	encoded, err := apicommon.AesEncrypt(properties[secret], apicommon.AESEncryptKey)
+	if err != nil {
+		log.Errorf("encrypt %s %s failed: %v", fsType, secret, err)
+		return err
+	}
+	properties[secret] = encoded
+	return nil
+}
+
+// CheckKernelMountProperties checks the properties of the fs mounted by the kernel, like nfs. The
+// local mount path must be under one of the local mount roots configured by the admin, as it is
+// used by the server and bind mounted into the pods.
+func CheckKernelMountProperties(properties map[string]string) error {
+	if localPath := properties[base.LocalMountPath]; localPath != "" {
+		if !filepath.IsAbs(localPath) {
+			return common.InvalidField(base.LocalMountPath, "must be an absolute path")
+		}
+		if !underLocalMountRoots(filepath.Clean(localPath)) {
+			return common.InvalidField(base.LocalMountPath, "must be under the local mount roots of the server")
+		}
+		properties[base.LocalMountPath] = filepath.Clean(localPath)
+	}
+	if strings.ContainsAny(properties[base.KernelMountOptions], " \t\n") {
+		return common.InvalidField(base.KernelMountOptions, "must be comma separated options without spaces")
+	}
+	if err := base.CheckKernelMountOptions(properties[base.KernelMountOptions]); err != nil {
+		return common.InvalidField(base.KernelMountOptions, err.Error())
+	}
+	return nil
+}
+
+func underLocalMountRoots(path string) bool {
+	if config.GlobalServerConfig == nil {
+		return false
+	}
+	for _, root := range config.GlobalServerConfig.Fs.LocalMountRoots {
+		root = filepath.Clean(root)
+		if root != "/" && (path == root || strings.HasPrefix(path, root+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckQuotaProperties checks the optional quota of the fs, whose type is hard by default
+func CheckQuotaProperties(properties map[string]string) error {
+	if properties[base.Quota] == "" {
+		if properties[base.QuotaType] != "" {
+			return common.InvalidField(base.QuotaType, "is set without quota")
+		}
+		return nil
+	}
+	quota, err := strconv.ParseInt(properties[base.Quota], 10, 64)
+	if err != nil || quota <= 0 {
+		return common.InvalidField(base.Quota, "must be a positive number of bytes")
+	}
+	switch properties[base.QuotaType] {
+	case "":
+		properties[base.QuotaType] = base.HardQuota
+	case base.SoftQuota, base.HardQuota:
+	default:
+		return common.InvalidField(base.QuotaType, fmt.Sprintf("must be %s or %s", base.SoftQuota, base.HardQuota))
+	}
+	return nil
+}
+
 func CheckFsNested(path1, path2 string) bool {
 	path1 = strings.TrimRight(path1, "/")
 	path2 = strings.TrimRight(path2, "/")
@@ -173,6 +304,23 @@ func NameToFsID(fsName, userName string) string {
 	return fsName
 }
 
+// SharedNameToFsID is like NameToFsID, but keeps the fsID of other users (ex. fsName=fs-alice-abc used by bob),
+// as the fs of other users can be shared with the user. The access of the user must be checked by the caller.
+func SharedNameToFsID(fsName, userName string) string {
+	fsTemp := strings.Split(fsName, "-")
+	if len(fsTemp) >= IDSliceLen && fsTemp[0] == "fs" {
+		return fsName
+	}
+	return ID(userName, fsName)
+}
+
+// FsIDOwner returns the name of the user who creates the fs
+func FsIDOwner(fsID string) string {
+	fsTemp := strings.Split(fsID, "-")
+	fsUserName := strings.TrimPrefix(fsID, fsTemp[0]+"-")
+	return strings.TrimSuffix(fsUserName, "-"+fsTemp[len(fsTemp)-1])
+}
+
 func FSIDToName(fsID string) string {
 	fsArr := strings.Split(fsID, "-")
 	return fsArr[len(fsArr)-1]
@@ -19,6 +19,10 @@ package fs
 import (
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+
+	apicommon "paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/fs/client/base"
 )
 
@@ -90,6 +94,48 @@ func TestInformationFromUrl(t *testing.T) {
 			wantServerAddress:  "192.168.1.4",
 			wantSubPath:        "/",
 		},
+		{
+			name:               "gcs",
+			args:               args{url: "gcs://bucket/path", properties: map[string]string{base.Endpoint: base.GCSDefaultEndpoint}},
+			wantFileSystemType: "gcs",
+			wantServerAddress:  base.GCSDefaultEndpoint,
+			wantSubPath:        "/path",
+		},
+		{
+			name:               "azblob",
+			args:               args{url: "azblob://container/path", properties: map[string]string{base.Endpoint: "http://127.0.0.1:10000/devstoreaccount1"}},
+			wantFileSystemType: "azblob",
+			wantServerAddress:  "http://127.0.0.1:10000/devstoreaccount1",
+			wantSubPath:        "/path",
+		},
+		{
+			name:               "nfs",
+			args:               args{url: "nfs://192.168.1.2/export/data"},
+			wantFileSystemType: "nfs",
+			wantServerAddress:  "192.168.1.2",
+			wantSubPath:        "/export/data",
+		},
+		{
+			name:               "cephfs",
+			args:               args{url: "cephfs://192.168.1.2:6789,192.168.1.3:6789/volumes/data"},
+			wantFileSystemType: "cephfs",
+			wantServerAddress:  "192.168.1.2:6789,192.168.1.3:6789",
+			wantSubPath:        "/volumes/data",
+		},
+		{
+			name:               "webdav",
+			args:               args{url: "webdav://192.168.1.2:8080/remote.php/dav/files"},
+			wantFileSystemType: "webdav",
+			wantServerAddress:  "192.168.1.2:8080",
+			wantSubPath:        "/remote.php/dav/files",
+		},
+		{
+			name:               "https",
+			args:               args{url: "https://example.com/datasets/mnist"},
+			wantFileSystemType: "http",
+			wantServerAddress:  "example.com",
+			wantSubPath:        "/datasets/mnist",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -305,3 +351,208 @@ func TestNameToFsID(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckQuotaProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]string
+		wantErr    bool
+		wantType   string
+	}{
+		{
+			name:       "no quota",
+			properties: map[string]string{},
+		},
+		{
+			name:       "default hard quota",
+			properties: map[string]string{base.Quota: "1073741824"},
+			wantType:   base.HardQuota,
+		},
+		{
+			name:       "soft quota",
+			properties: map[string]string{base.Quota: "1024", base.QuotaType: base.SoftQuota},
+			wantType:   base.SoftQuota,
+		},
+		{
+			name:       "invalid quota",
+			properties: map[string]string{base.Quota: "1G"},
+			wantErr:    true,
+		},
+		{
+			name:       "zero quota",
+			properties: map[string]string{base.Quota: "0"},
+			wantErr:    true,
+		},
+		{
+			name:       "invalid quota type",
+			properties: map[string]string{base.Quota: "1024", base.QuotaType: "strict"},
+			wantErr:    true,
+		},
+		{
+			name:       "quota type without quota",
+			properties: map[string]string{base.QuotaType: base.HardQuota},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckQuotaProperties(tt.properties)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckQuotaProperties() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && tt.properties[base.QuotaType] != tt.wantType {
+				t.Errorf("CheckQuotaProperties() quotaType = %v, want %v", tt.properties[base.QuotaType], tt.wantType)
+			}
+		})
+	}
+}
+
+func TestCheckKernelMountProperties(t *testing.T) {
+	config.GlobalServerConfig = &config.ServerConfig{}
+	config.GlobalServerConfig.Fs.LocalMountRoots = []string{"/mnt/nfs/"}
+	defer func() { config.GlobalServerConfig = nil }()
+
+	tests := []struct {
+		name       string
+		properties map[string]string
+		wantErr    bool
+		wantPath   string
+	}{
+		{
+			name:       "no properties",
+			properties: map[string]string{},
+		},
+		{
+			name:       "allowed options",
+			properties: map[string]string{base.KernelMountOptions: "vers=4.1,nolock,ro"},
+		},
+		{
+			name:       "option not allowed",
+			properties: map[string]string{base.KernelMountOptions: "vers=4.1,suid"},
+			wantErr:    true,
+		},
+		{
+			name:       "options with spaces",
+			properties: map[string]string{base.KernelMountOptions: "vers=4.1, nolock"},
+			wantErr:    true,
+		},
+		{
+			name:       "local path under roots",
+			properties: map[string]string{base.LocalMountPath: "/mnt/nfs/data/"},
+			wantPath:   "/mnt/nfs/data",
+		},
+		{
+			name:       "relative local path",
+			properties: map[string]string{base.LocalMountPath: "mnt/nfs/data"},
+			wantErr:    true,
+		},
+		{
+			name:       "local path out of roots",
+			properties: map[string]string{base.LocalMountPath: "/mnt/nfs/../../etc"},
+			wantErr:    true,
+		},
+		{
+			name:       "local path with root prefix",
+			properties: map[string]string{base.LocalMountPath: "/mnt/nfs2"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckKernelMountProperties(tt.properties)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckKernelMountProperties() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && tt.properties[base.LocalMountPath] != tt.wantPath {
+				t.Errorf("CheckKernelMountProperties() localMountPath = %v, want %v", tt.properties[base.LocalMountPath], tt.wantPath)
+			}
+		})
+	}
+
+	config.GlobalServerConfig.Fs.LocalMountRoots = nil
+	assert.Error(t, CheckKernelMountProperties(map[string]string{base.LocalMountPath: "/mnt/nfs/data"}))
+}
+
+func TestCheckObjectStorageProperties(t *testing.T) {
+	tests := []struct {
+		name         string
+		fsType       string
+		properties   map[string]string
+		wantErr      bool
+		wantEndpoint string
+		secret       string
+	}{
+		{
+			name:       "s3",
+			fsType:     S3,
+			properties: map[string]string{base.AccessKey: "ak", base.SecretKey: "sk", base.Endpoint: "s3.bj.bcebos.com", base.Bucket: "b"},
+			secret:     base.SecretKey,
+		},
+		{
+			name:       "s3 without endpoint",
+			fsType:     S3,
+			properties: map[string]string{base.AccessKey: "ak", base.SecretKey: "sk", base.Bucket: "b"},
+			wantErr:    true,
+		},
+		{
+			name:         "gcs with default credentials",
+			fsType:       GCS,
+			properties:   map[string]string{base.Bucket: "b"},
+			wantEndpoint: base.GCSDefaultEndpoint,
+		},
+		{
+			name:         "gcs with credentials",
+			fsType:       GCS,
+			properties:   map[string]string{base.Bucket: "b", base.Credentials: "{}", base.Endpoint: "http://127.0.0.1:4443/storage/v1/"},
+			wantEndpoint: "http://127.0.0.1:4443/storage/v1/",
+			secret:       base.Credentials,
+		},
+		{
+			name:       "gcs without bucket",
+			fsType:     GCS,
+			properties: map[string]string{},
+			wantErr:    true,
+		},
+		{
+			name:         "azblob",
+			fsType:       Azblob,
+			properties:   map[string]string{base.AccountName: "account", base.AccountKey: "key", base.Bucket: "c"},
+			wantEndpoint: "https://account.blob.core.windows.net",
+			secret:       base.AccountKey,
+		},
+		{
+			name:       "azblob without account key",
+			fsType:     Azblob,
+			properties: map[string]string{base.AccountName: "account", base.Bucket: "c"},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := tt.properties[tt.secret]
+			err := CheckObjectStorageProperties(tt.fsType, tt.properties)
+			assert.Equal(t, tt.wantErr, err != nil)
+			if err != nil {
+				return
+			}
+			if tt.wantEndpoint != "" {
+				assert.Equal(t, tt.wantEndpoint, tt.properties[base.Endpoint])
+			}
+			if tt.secret != "" {
+				decoded, err := apicommon.AesDecrypt(tt.properties[tt.secret], apicommon.AESEncryptKey)
+				assert.Nil(t, err)
+				assert.Equal(t, plain, decoded)
+			}
+		})
+	}
+}
+
+func TestSharedNameToFsID(t *testing.T) {
+	assert.Equal(t, "fs-alice-data", SharedNameToFsID("fs-alice-data", "bob"))
+	assert.Equal(t, "fs-bob-data", SharedNameToFsID("data", "bob"))
+	assert.Equal(t, "fs-bob-fs-data", SharedNameToFsID("fs-data", "bob"))
+	assert.Equal(t, "alice", FsIDOwner("fs-alice-data"))
+	assert.Equal(t, "alice-a", FsIDOwner("fs-alice-a-data"))
+}
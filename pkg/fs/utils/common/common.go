@@ -17,6 +17,8 @@ limitations under the License.
 package common
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -33,6 +35,10 @@ const (
 	VolumePluginName       = "kubernetes.io~csi"
 	volumePathLastDir      = "mount"
 	volumeMountPathLastDir = "source"
+	volumeStagingDir       = "plugins/kubernetes.io/csi/pv"
+	volumeStagingLastDir   = "globalmount"
+	sharedMountDir         = "plugins/paddleflowstorage/shared"
+	stagingRecordDir       = "plugins/paddleflowstorage/staging"
 
 	KubeletDataPathEnv        = "KUBELET_DATA_PATH"
 	NotRootUserEnableEnv      = "NOT_ROOT_USER_ENABLE"
@@ -43,6 +49,9 @@ const (
 	DefaultGIDEnv             = "DEFAULT_GID_ENV"
 	K8SConfigPathEnv          = "K8S_CONFIG_PATH"
 	K8SClientTimeoutEnv       = "K8S_CLIENT_TIMEOUT"
+	FuseRootEnv               = "FUSE_ROOT"
+	FusePasswordEnv           = "FUSE_PASSWORD"
+	PeerCacheTokenEnv         = "PFS_PEER_CACHE_TOKEN"
 
 	DefaultKubeletDataPath       = "/var/lib/kubelet"
 	DefaultNOTROOTUserEnable     = true
@@ -53,6 +62,8 @@ const (
 	DefaultUpdateIntervalTime    = 15
 	DefaultUID                   = 601
 	DefaultGID                   = 601
+	DefaultFuseRoot              = "root"
+	DefaultFusePassword          = "paddleflow"
 )
 
 // GetVolumeMountPath default value: /var/lib/kubelet/pods/{podUID}/volumes/{volumePluginName}/{volumeName}/mount
@@ -77,6 +88,49 @@ func GetVolumeBindMountPathByPod(podUID, volumeName string) string {
 		podUID, VolumePluginName, volumeName)
 }
 
+// GetVolumeStagingPath returns the staging path of the volume recorded by NodeStageVolume, as the path
+// differs across kubelet versions. The volumes staged before recorded fall back to the default value:
+// /var/lib/kubelet/plugins/kubernetes.io/csi/pv/{volumeName}/globalmount
+func GetVolumeStagingPath(volumeID, volumeName string) string {
+	if path, err := os.ReadFile(getStagingRecordPath(volumeID)); err == nil && len(path) > 0 {
+		return string(path)
+	}
+	return filepath.Join(GetKubeletDataPath(), volumeStagingDir, volumeName, volumeStagingLastDir)
+}
+
+// getStagingRecordPath default value: /var/lib/kubelet/plugins/paddleflowstorage/staging/{hash of volumeID}
+func getStagingRecordPath(volumeID string) string {
+	sum := sha256.Sum256([]byte(volumeID))
+	return filepath.Join(GetKubeletDataPath(), stagingRecordDir, hex.EncodeToString(sum[:]))
+}
+
+// RecordVolumeStagingPath keeps the staging path of the volume given by kubelet
+func RecordVolumeStagingPath(volumeID, stagingPath string) error {
+	recordPath := getStagingRecordPath(volumeID)
+	if err := os.MkdirAll(filepath.Dir(recordPath), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(recordPath, []byte(stagingPath), 0640)
+}
+
+// RemoveVolumeStagingPath drops the staging path recorded for the volume
+func RemoveVolumeStagingPath(volumeID string) error {
+	if err := os.Remove(getStagingRecordPath(volumeID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetSharedMountRoot default value: /var/lib/kubelet/plugins/paddleflowstorage/shared
+func GetSharedMountRoot() string {
+	return filepath.Join(GetKubeletDataPath(), sharedMountDir)
+}
+
+// GetSharedMountPath default value: /var/lib/kubelet/plugins/paddleflowstorage/shared/{key}
+func GetSharedMountPath(key string) string {
+	return filepath.Join(GetSharedMountRoot(), key)
+}
+
 func GetKubeletDataPath() string {
 	path := os.Getenv(KubeletDataPathEnv)
 	if len(path) == 0 {
@@ -123,6 +177,18 @@ func GetDefaultGID() int {
 	return gidInt
 }
 
+// GetFuseRootEnv returns the user and password that pfs-fuse logs in to the server with
+func GetFuseRootEnv() (string, string) {
+	userName, password := os.Getenv(FuseRootEnv), os.Getenv(FusePasswordEnv)
+	if len(userName) == 0 {
+		userName = DefaultFuseRoot
+	}
+	if len(password) == 0 {
+		password = DefaultFusePassword
+	}
+	return userName, password
+}
+
 func GetK8SConfigPathEnv() string {
 	path := os.Getenv(K8SConfigPathEnv)
 	if len(path) == 0 {
@@ -55,3 +55,20 @@ func TestGetDefaultGID(t *testing.T) {
 	uid := GetDefaultGID()
 	assert.Equal(t, uid, value)
 }
+
+func TestVolumeStagingPath(t *testing.T) {
+	dir := t.TempDir()
+	defer os.Setenv(KubeletDataPathEnv, os.Getenv(KubeletDataPathEnv))
+	os.Setenv(KubeletDataPathEnv, dir)
+
+	// the volumes staged before recorded use the default staging path
+	assert.Equal(t, dir+"/plugins/kubernetes.io/csi/pv/pv1/globalmount", GetVolumeStagingPath("fs-root-test/pv1", "pv1"))
+
+	stagingPath := dir + "/plugins/kubernetes.io/csi/paddleflowstorage/abc/globalmount"
+	assert.Nil(t, RecordVolumeStagingPath("fs-root-test/pv1", stagingPath))
+	assert.Equal(t, stagingPath, GetVolumeStagingPath("fs-root-test/pv1", "pv1"))
+
+	assert.Nil(t, RemoveVolumeStagingPath("fs-root-test/pv1"))
+	assert.Nil(t, RemoveVolumeStagingPath("fs-root-test/pv1"))
+	assert.Equal(t, dir+"/plugins/kubernetes.io/csi/pv/pv1/globalmount", GetVolumeStagingPath("fs-root-test/pv1", "pv1"))
+}
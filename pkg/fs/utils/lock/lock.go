@@ -0,0 +1,307 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Owner identifies the holder of a lock, Session distinguishes the mounts when the locks
+// are coordinated by server and ID is the lock owner passed by the kernel.
+type Owner struct {
+	Session string `json:"session"`
+	ID      uint64 `json:"id"`
+}
+
+// Range is a byte range lock, End is inclusive.
+type Range struct {
+	Type  uint32 `json:"type"`
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	Pid   uint32 `json:"pid"`
+}
+
+func (r Range) overlap(o Range) bool {
+	return r.Start <= o.End && o.Start <= r.End
+}
+
+func conflict(a, b uint32) bool {
+	return a == syscall.F_WRLCK || b == syscall.F_WRLCK
+}
+
+type fileLocks struct {
+	flocks map[Owner]uint32
+	plocks map[Owner][]Range
+}
+
+func (f *fileLocks) empty() bool {
+	return len(f.flocks) == 0 && len(f.plocks) == 0
+}
+
+// Table keeps the flock and posix locks of files, the files are identified by key.
+// Table never blocks, the waiters are woken up by Changed when any lock is released.
+type Table struct {
+	sync.Mutex
+	files   map[string]*fileLocks
+	changed chan struct{}
+	// seen is the last active time of the sessions
+	seen map[string]time.Time
+}
+
+func NewTable() *Table {
+	return &Table{
+		files:   make(map[string]*fileLocks),
+		changed: make(chan struct{}),
+		seen:    make(map[string]time.Time),
+	}
+}
+
+// Changed returns a channel closed on the next release of any lock.
+func (t *Table) Changed() <-chan struct{} {
+	t.Lock()
+	defer t.Unlock()
+	return t.changed
+}
+
+func (t *Table) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+func (t *Table) file(key string) *fileLocks {
+	f, ok := t.files[key]
+	if !ok {
+		f = &fileLocks{flocks: make(map[Owner]uint32), plocks: make(map[Owner][]Range)}
+		t.files[key] = f
+	}
+	return f
+}
+
+func (t *Table) gc(key string) {
+	if f, ok := t.files[key]; ok && f.empty() {
+		delete(t.files, key)
+	}
+}
+
+// Flock puts a whole file lock of typ on key, F_UNLCK releases it. It returns false
+// if the lock is held by others in conflict mode.
+func (t *Table) Flock(key string, owner Owner, typ uint32) bool {
+	t.Lock()
+	defer t.Unlock()
+	t.touch(owner.Session)
+	f := t.file(key)
+	defer t.gc(key)
+	if typ == syscall.F_UNLCK {
+		if _, ok := f.flocks[owner]; ok {
+			delete(f.flocks, owner)
+			t.notify()
+		}
+		return true
+	}
+	for o, held := range f.flocks {
+		if o != owner && conflict(held, typ) {
+			return false
+		}
+	}
+	old, ok := f.flocks[owner]
+	f.flocks[owner] = typ
+	if ok && old == syscall.F_WRLCK && typ == syscall.F_RDLCK {
+		t.notify()
+	}
+	return true
+}
+
+// Getlk returns the first lock of others conflicting with r, the Type of the returned
+// range is F_UNLCK if there is none.
+func (t *Table) Getlk(key string, owner Owner, r Range) Range {
+	t.Lock()
+	defer t.Unlock()
+	t.touch(owner.Session)
+	if f, ok := t.files[key]; ok {
+		if held, ok := f.getConflict(owner, r); ok {
+			return held
+		}
+	}
+	return Range{Type: syscall.F_UNLCK}
+}
+
+func (f *fileLocks) getConflict(owner Owner, r Range) (Range, bool) {
+	for o, ranges := range f.plocks {
+		if o == owner {
+			continue
+		}
+		for _, held := range ranges {
+			if held.overlap(r) && conflict(held.Type, r.Type) {
+				return held, true
+			}
+		}
+	}
+	return Range{}, false
+}
+
+// Setlk puts a posix lock on the range r of key, F_UNLCK releases the range. It returns
+// false if any part of the range is locked by others in conflict mode.
+func (t *Table) Setlk(key string, owner Owner, r Range) bool {
+	t.Lock()
+	defer t.Unlock()
+	t.touch(owner.Session)
+	f := t.file(key)
+	defer t.gc(key)
+	if r.Type != syscall.F_UNLCK {
+		if _, ok := f.getConflict(owner, r); ok {
+			return false
+		}
+	}
+	// the new lock replaces the locks of owner in the range, which may be split
+	var ranges []Range
+	released := false
+	for _, held := range f.plocks[owner] {
+		if !held.overlap(r) {
+			ranges = append(ranges, held)
+			continue
+		}
+		if held.Type == syscall.F_WRLCK || r.Type == syscall.F_UNLCK {
+			released = true
+		}
+		if held.Start < r.Start {
+			left := held
+			left.End = r.Start - 1
+			ranges = append(ranges, left)
+		}
+		if held.End > r.End {
+			right := held
+			right.Start = r.End + 1
+			ranges = append(ranges, right)
+		}
+	}
+	if r.Type != syscall.F_UNLCK {
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		delete(f.plocks, owner)
+	} else {
+		f.plocks[owner] = ranges
+	}
+	if released {
+		t.notify()
+	}
+	return true
+}
+
+// Held is a lock held on a file, it's a flock if Flock is true, otherwise a posix lock of the Range.
+type Held struct {
+	Owner Owner `json:"owner"`
+	Flock bool  `json:"flock,omitempty"`
+	Range
+}
+
+// Locks returns the locks held on key, which are kept by server between requests.
+func (t *Table) Locks(key string) []Held {
+	t.Lock()
+	defer t.Unlock()
+	f, ok := t.files[key]
+	if !ok {
+		return nil
+	}
+	var locks []Held
+	for owner, typ := range f.flocks {
+		locks = append(locks, Held{Owner: owner, Flock: true, Range: Range{Type: typ}})
+	}
+	for owner, ranges := range f.plocks {
+		for _, r := range ranges {
+			locks = append(locks, Held{Owner: owner, Range: r})
+		}
+	}
+	return locks
+}
+
+// Restore replaces the locks held on key with locks returned by Locks.
+func (t *Table) Restore(key string, locks []Held) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.files, key)
+	f := t.file(key)
+	for _, held := range locks {
+		if held.Flock {
+			f.flocks[held.Owner] = held.Type
+		} else {
+			f.plocks[held.Owner] = append(f.plocks[held.Owner], held.Range)
+		}
+	}
+	t.gc(key)
+}
+
+// Touch keeps the session alive.
+func (t *Table) Touch(session string) {
+	t.Lock()
+	defer t.Unlock()
+	t.touch(session)
+}
+
+func (t *Table) touch(session string) {
+	if session != "" {
+		t.seen[session] = time.Now()
+	}
+}
+
+// ReleaseSession drops all the locks of the session.
+func (t *Table) ReleaseSession(session string) {
+	t.Lock()
+	defer t.Unlock()
+	t.releaseSession(session)
+}
+
+func (t *Table) releaseSession(session string) {
+	delete(t.seen, session)
+	released := false
+	for key, f := range t.files {
+		for o := range f.flocks {
+			if o.Session == session {
+				delete(f.flocks, o)
+				released = true
+			}
+		}
+		for o := range f.plocks {
+			if o.Session == session {
+				delete(f.plocks, o)
+				released = true
+			}
+		}
+		t.gc(key)
+	}
+	if released {
+		t.notify()
+	}
+}
+
+// Expire drops the locks of the sessions not seen in ttl, e.g. the mount crashed.
+func (t *Table) Expire(ttl time.Duration) []string {
+	t.Lock()
+	defer t.Unlock()
+	var expired []string
+	for session, seen := range t.seen {
+		if time.Since(seen) > ttl {
+			expired = append(expired, session)
+		}
+	}
+	for _, session := range expired {
+		t.releaseSession(session)
+	}
+	return expired
+}
@@ -0,0 +1,118 @@
+/*
+Copyright (c) 2021 PaddlePaddle Authors. All Rights Reserve.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lock
+
+import (
+	"math"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlock(t *testing.T) {
+	table := NewTable()
+	a, b := Owner{ID: 1}, Owner{ID: 2}
+	assert.True(t, table.Flock("f", a, syscall.F_RDLCK))
+	assert.True(t, table.Flock("f", b, syscall.F_RDLCK))
+	assert.False(t, table.Flock("f", b, syscall.F_WRLCK))
+
+	changed := table.Changed()
+	assert.True(t, table.Flock("f", a, syscall.F_UNLCK))
+	select {
+	case <-changed:
+	default:
+		t.Fatal("waiters are not woken up")
+	}
+	assert.True(t, table.Flock("f", b, syscall.F_WRLCK))
+	assert.False(t, table.Flock("f", a, syscall.F_RDLCK))
+	// locks of different files are independent
+	assert.True(t, table.Flock("g", a, syscall.F_WRLCK))
+}
+
+func TestPosixLock(t *testing.T) {
+	table := NewTable()
+	a, b := Owner{ID: 1}, Owner{ID: 2}
+	assert.True(t, table.Setlk("f", a, Range{Type: syscall.F_WRLCK, Start: 0, End: 99, Pid: 10}))
+	assert.False(t, table.Setlk("f", b, Range{Type: syscall.F_RDLCK, Start: 50, End: 149}))
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_RDLCK, Start: 100, End: 149}))
+
+	held := table.Getlk("f", b, Range{Type: syscall.F_RDLCK, Start: 10, End: 10})
+	assert.Equal(t, Range{Type: syscall.F_WRLCK, Start: 0, End: 99, Pid: 10}, held)
+	held = table.Getlk("f", a, Range{Type: syscall.F_RDLCK, Start: 10, End: 10})
+	assert.Equal(t, uint32(syscall.F_UNLCK), held.Type)
+
+	// unlock the middle of the range, the lock is split
+	assert.True(t, table.Setlk("f", a, Range{Type: syscall.F_UNLCK, Start: 40, End: 59}))
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 40, End: 59}))
+	assert.False(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 39, End: 40}))
+	assert.False(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 60, End: 60}))
+
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_UNLCK, Start: 40, End: 59}))
+
+	// lock the whole file in read mode, the others can read
+	assert.True(t, table.Setlk("f", a, Range{Type: syscall.F_RDLCK, Start: 0, End: math.MaxInt64}))
+	assert.False(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 0, End: 0}))
+	c := Owner{ID: 3}
+	assert.True(t, table.Setlk("f", c, Range{Type: syscall.F_RDLCK, Start: 0, End: 0}))
+
+	all := Range{Type: syscall.F_UNLCK, Start: 0, End: math.MaxInt64}
+	assert.True(t, table.Setlk("f", a, all))
+	assert.True(t, table.Setlk("f", c, all))
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 0, End: 39}))
+	assert.True(t, table.Setlk("f", b, all))
+	assert.Equal(t, 0, len(table.files))
+}
+
+func TestRestoreLocks(t *testing.T) {
+	table := NewTable()
+	a, b := Owner{Session: "a", ID: 1}, Owner{Session: "b", ID: 2}
+	assert.True(t, table.Flock("f", a, syscall.F_RDLCK))
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_WRLCK, Start: 0, End: 9, Pid: 10}))
+	assert.True(t, table.Setlk("f", b, Range{Type: syscall.F_RDLCK, Start: 20, End: 29, Pid: 10}))
+	locks := table.Locks("f")
+	assert.Equal(t, 3, len(locks))
+	assert.Nil(t, table.Locks("g"))
+
+	restored := NewTable()
+	restored.Restore("f", locks)
+	assert.False(t, restored.Flock("f", b, syscall.F_WRLCK))
+	assert.False(t, restored.Setlk("f", a, Range{Type: syscall.F_RDLCK, Start: 5, End: 5}))
+	assert.True(t, restored.Setlk("f", a, Range{Type: syscall.F_RDLCK, Start: 25, End: 25}))
+	restored.Restore("f", nil)
+	assert.Equal(t, 0, len(restored.files))
+}
+
+func TestLockSession(t *testing.T) {
+	table := NewTable()
+	a := Owner{Session: "a", ID: 1}
+	b := Owner{Session: "b", ID: 1}
+	assert.True(t, table.Flock("f", a, syscall.F_WRLCK))
+	assert.True(t, table.Setlk("g", a, Range{Type: syscall.F_WRLCK, Start: 0, End: 9}))
+	// the same owner id in another session is another owner
+	assert.False(t, table.Flock("f", b, syscall.F_WRLCK))
+
+	table.ReleaseSession("a")
+	assert.True(t, table.Flock("f", b, syscall.F_WRLCK))
+	assert.True(t, table.Setlk("g", b, Range{Type: syscall.F_WRLCK, Start: 0, End: 9}))
+
+	time.Sleep(10 * time.Millisecond)
+	table.Touch("c")
+	assert.Equal(t, []string{"b"}, table.Expire(5*time.Millisecond))
+	assert.Equal(t, 0, len(table.files))
+}
@@ -47,6 +47,8 @@ const (
 	KillPGroupCmd            = "kill -9 -%d"
 
 	readOnly = "ro"
+
+	procMountInfoPath = "/proc/self/mountinfo"
 )
 
 func IsMountPoint(path string) (bool, error) {
@@ -91,6 +93,58 @@ func CleanUpMountPoint(path string) error {
 	return nil
 }
 
+// GetMountRefs returns the other mount points of the same device and root as path
+func GetMountRefs(path string) ([]string, error) {
+	return mount.New("").GetMountRefs(path)
+}
+
+// BindMount is a mount of the root path in the file system mounted by another mount point
+type BindMount struct {
+	Root       string
+	MountPoint string
+	ReadOnly   bool
+}
+
+// GetDeviceMounts returns the mounts of the same device as the mount at path, including path itself. Unlike
+// GetMountRefs, it reads the mount table only, so that the mounts of a disconnected fuse are found as well.
+func GetDeviceMounts(path string) ([]BindMount, error) {
+	infos, err := mount.ParseMountInfo(procMountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	var device *mount.MountInfo
+	for i := range infos {
+		// the mount stacked last is the one visible at path
+		if infos[i].MountPoint == path {
+			device = &infos[i]
+		}
+	}
+	if device == nil {
+		return nil, nil
+	}
+	var mounts []BindMount
+	for _, info := range infos {
+		if info.Major != device.Major || info.Minor != device.Minor {
+			continue
+		}
+		mounts = append(mounts, BindMount{
+			Root:       info.Root,
+			MountPoint: info.MountPoint,
+			ReadOnly:   hasOption(info.MountOptions, readOnly),
+		})
+	}
+	return mounts, nil
+}
+
+func hasOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
 func ForceUnmount(path string) error {
 	output, err := ExecCmdWithTimeout(UMountCmdName, []string{"-lf", path})
 	if err != nil {
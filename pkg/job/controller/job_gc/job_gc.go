@@ -22,6 +22,7 @@ import (
 	"sync"
 
 	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/runtime"
@@ -31,6 +32,7 @@ import (
 
 	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/common/k8s"
+	commonschema "paddleflow/pkg/common/schema"
 	"paddleflow/pkg/job/controller/framework"
 )
 
@@ -153,9 +155,25 @@ func (j *JobGarbageCollector) processWorkItem() bool {
 	}
 	log.Infof("auto clean [%s] job [%s/%s] succeed.",
 		info.GVK, info.Namespace, info.Name)
+	j.cleanJobSecret(info.Namespace, info.Name)
 	return true
 }
 
+// cleanJobSecret deletes the secret created for job, which holds the values of user secrets referenced by job env
+func (j *JobGarbageCollector) cleanJobSecret(namespace, jobName string) {
+	gvr, err := k8s.GetGVRByGVK(k8s.SecretGVK)
+	if err != nil {
+		log.Errorf("find the GroupVersionResource of GroupVersionKind[%s] failed.", k8s.SecretGVK)
+		return
+	}
+	secretName := commonschema.JobSecretName(jobName)
+	err = j.opt.DynamicClient.Resource(gvr).Namespace(namespace).Delete(context.TODO(),
+		secretName, metav1.DeleteOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		log.Errorf("clean secret [%s/%s] of job failed, error：%v", namespace, secretName, err.Error())
+	}
+}
+
 // preCleanFinishedJob is applied to clean job when server start
 func (j *JobGarbageCollector) preCleanFinishedJob() {
 	if j.vcJobLister != nil {
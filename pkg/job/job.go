@@ -28,9 +28,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 
 	"paddleflow/pkg/apiserver/common"
@@ -39,12 +42,14 @@ import (
 	"paddleflow/pkg/common/config"
 	"paddleflow/pkg/common/database"
 	"paddleflow/pkg/common/errors"
+	"paddleflow/pkg/common/k8s"
 	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/common/schema"
 	"paddleflow/pkg/common/uuid"
 	"paddleflow/pkg/fs/client/base"
 	"paddleflow/pkg/fs/server/api/request"
 	"paddleflow/pkg/fs/server/service"
+	"paddleflow/pkg/job/submitter"
 )
 
 type Interface interface {
@@ -141,7 +146,7 @@ func checkResource(conf *models.Conf) error {
 	conf.Env[schema.EnvJobNamespace] = namespace
 
 	fsID, _ := conf.Env[schema.EnvJobFsID]
-	if pvcName, err := createFSClaims(namespace, fsID); err != nil {
+	if pvcName, err := createFSClaims(namespace, fsID, conf.Env[schema.EnvJobUserName]); err != nil {
 		log.Errorf("create fs claims failed, err %v", err)
 		return err
 	} else {
@@ -276,8 +281,10 @@ func UpdateJob(jobID string, status schema.JobStatus, info interface{}, message
 	if err != nil {
 		return "", errors.JobIDNotFoundError(jobID)
 	}
+	finished := false
 	if status != "" && !IsImmutableJobStatus(job.Status) {
 		job.Status = status
+		finished = IsImmutableJobStatus(status)
 	}
 	if info != nil {
 		job.RuntimeInfo = info
@@ -294,6 +301,10 @@ func UpdateJob(jobID string, status schema.JobStatus, info interface{}, message
 		logger.LoggerForJob(jobID).Errorf("update job failed, err %v", err)
 		return "", err
 	}
+	// the secret is not needed after job finished, and it may outlive the job if job gc is disabled
+	if finished {
+		cleanJobSecret(&job)
+	}
 	return job.Status, nil
 }
 
@@ -304,15 +315,24 @@ func StopJobByID(jobID string) error {
 	}
 	jobType := schema.JobType(job.Type)
 	logger.LoggerForJob(jobID).Infof("stop %s job", jobType)
-	return JobMap[jobType].StopJobByID(jobID)
+	if err = JobMap[jobType].StopJobByID(jobID); err != nil {
+		return err
+	}
+	cleanJobSecret(&job)
+	return nil
 }
 
 func DeleteJobByID(jobID string) error {
+	job, err := GetJobByID(jobID)
+	if err != nil {
+		return nil
+	}
 	tx := database.DB.Table("job").Delete(&models.Job{}, "id = ?", jobID)
 	if tx.Error != nil {
 		logger.LoggerForJob(jobID).Errorf("delete job failed, err %v", tx.Error)
 		return tx.Error
 	}
+	cleanJobSecret(&job)
 	return nil
 }
 
@@ -350,7 +370,7 @@ func persistAndExecuteJob(job *models.Job, executeFunc func() error) error {
 	})
 }
 
-func createFSClaims(namespace string, fsID string) (string, error) {
+func createFSClaims(namespace, fsID, userName string) (string, error) {
 	// mock类型fs，pvc直接从记录中返回
 	fileSystem, err := models.GetFsWithID(fsID)
 	if err != nil {
@@ -365,6 +385,7 @@ func createFSClaims(namespace string, fsID string) (string, error) {
 	req := &request.CreateFileSystemClaimsRequest{
 		Namespaces: []string{namespace},
 		FsIDs:      []string{fsID},
+		Username:   userName,
 	}
 
 	fsService := service.GetFileSystemService()
@@ -372,7 +393,12 @@ func createFSClaims(namespace string, fsID string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	claimID := fmt.Sprintf("pfs-%s-pvc", fsID)
+	// the fs shared by others is claimed by the user separately from the owner
+	access, err := service.GetFsShareService().GetAccess(userName, fileSystem)
+	if err != nil {
+		return "", err
+	}
+	claimID := fmt.Sprintf("pfs-%s-pvc", service.ClaimFsID(fsID, userName, access))
 	return claimID, nil
 }
 
@@ -425,16 +451,100 @@ func generateVolumeMount(fsID string) corev1.VolumeMount {
 
 func generateEnvVars(conf *models.Conf) []corev1.EnvVar {
 	envs := make([]corev1.EnvVar, 0)
+	secretName := conf.Env[schema.EnvJobSecretName]
 	for key, value := range conf.Env {
 		env := corev1.EnvVar{
 			Name:  key,
 			Value: value,
 		}
+		// env referenced user secret is read from the job secret, instead of setting value in plaintext
+		if _, ok := schema.ParseSecretRef(value); ok && secretName != "" {
+			env.Value = ""
+			env.ValueFrom = &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  key,
+				},
+			}
+		}
 		envs = append(envs, env)
 	}
 	return envs
 }
 
+// prepareJobSecret resolves env which references user secret, such as ${secret:hf_token}, and builds the kubernetes
+// secret holding the decrypted values. The env in conf keeps the references, so values never persist in job config.
+func prepareJobSecret(jobID string, conf *models.Conf) (*corev1.Secret, error) {
+	userName := conf.Env[schema.EnvJobUserName]
+	ctx := &logger.RequestContext{UserName: userName}
+	data := make(map[string][]byte)
+	for key, value := range conf.Env {
+		name, ok := schema.ParseSecretRef(value)
+		if !ok {
+			continue
+		}
+		secret, err := models.GetSecret(ctx, userName, name)
+		if err != nil {
+			log.Errorf("get secret[%s] referenced by env[%s] failed, err %v", name, key, err)
+			return nil, fmt.Errorf("secret[%s] referenced by env[%s] not found", name, key)
+		}
+		value, err := common.DecryptSecret(secret.Value)
+		if err != nil {
+			log.Errorf("decrypt secret[%s] referenced by env[%s] failed, err %v", name, key, err)
+			return nil, fmt.Errorf("decrypt secret[%s] failed", name)
+		}
+		data[key] = []byte(value)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	secretName := schema.JobSecretName(jobID)
+	conf.Env[schema.EnvJobSecretName] = secretName
+	jobSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName,
+			Namespace: conf.Env[schema.EnvJobNamespace],
+			Labels: map[string]string{
+				schema.JobOwnerLabel: schema.JobOwnerValue,
+				schema.JobIDLabel:    jobID,
+			},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: data,
+	}
+	return jobSecret, nil
+}
+
+// startJobWithSecret creates the job secret before starting job, and deletes it if job failed to start
+func startJobWithSecret(jobSecret *corev1.Secret, jobApp interface{}, gvk k8sschema.GroupVersionKind) error {
+	if jobSecret != nil {
+		if err := submitter.JobExecutor.StartJob(jobSecret, k8s.SecretGVK); err != nil {
+			log.Errorf("create secret %s in namespace %s failed, err %v", jobSecret.Name, jobSecret.Namespace, err)
+			return err
+		}
+	}
+	err := submitter.JobExecutor.StartJob(jobApp, gvk)
+	if err != nil && jobSecret != nil {
+		deleteJobSecret(jobSecret.Namespace, jobSecret.Name)
+	}
+	return err
+}
+
+// cleanJobSecret deletes the kubernetes secret created for job env, if any
+func cleanJobSecret(job *models.Job) {
+	if secretName := job.Config.Env[schema.EnvJobSecretName]; secretName != "" {
+		deleteJobSecret(job.Config.Env[schema.EnvJobNamespace], secretName)
+	}
+}
+
+func deleteJobSecret(namespace, name string) {
+	err := submitter.JobExecutor.StopJob(namespace, name, k8s.SecretGVK)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		log.Errorf("delete secret %s in namespace %s failed, err %v", name, namespace, err)
+	}
+}
+
 func getPriorityClass(priority string) string {
 	switch priority {
 	case schema.EnvJobVeryLowPriority:
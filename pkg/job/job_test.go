@@ -22,7 +22,11 @@ import (
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 
+	"paddleflow/pkg/apiserver/common"
+	"paddleflow/pkg/apiserver/models"
 	"paddleflow/pkg/common/config"
+	"paddleflow/pkg/common/database/db_fake"
+	"paddleflow/pkg/common/logger"
 	"paddleflow/pkg/common/schema"
 )
 
@@ -30,6 +34,7 @@ func initConfigsForTest(confEnv map[string]string) {
 	config.GlobalServerConfig = &config.ServerConfig{}
 	config.GlobalServerConfig.Job.DefaultJobYamlDir = "../../config/server/default/job"
 	config.GlobalServerConfig.Job.SchedulerName = "testSchedulerName"
+	config.GlobalServerConfig.ApiServer.SecretKey = "job-test-key-016"
 
 	confEnv[schema.EnvJobType] = string(schema.TypeVcJob)
 	confEnv[schema.EnvJobNamespace] = "N1"
@@ -55,3 +60,43 @@ func TestAppendVolumeIfAbsent(t *testing.T) {
 	vls = appendVolumeIfAbsent(vls, corev1.Volume{Name: "vm1"})
 	assert.Equal(t, 1, len(vls))
 }
+
+func TestPrepareJobSecret(t *testing.T) {
+	db_fake.InitFakeDB()
+	confEnv := make(map[string]string)
+	initConfigsForTest(confEnv)
+	confEnv[schema.EnvJobUserName] = "root"
+	confEnv["HF_TOKEN"] = "${secret:hf_token}"
+	confEnv["PLAIN"] = "${secret:hf_token} plaintext"
+
+	conf := &models.Conf{Name: "secret-test", Env: confEnv}
+	// secret not exist
+	_, err := prepareJobSecret("job-secret-test", conf)
+	assert.Error(t, err)
+
+	encrypted, err := common.EncryptSecret("token-value")
+	assert.NoError(t, err)
+	err = models.CreateSecret(&logger.RequestContext{}, &models.Secret{Name: "hf_token", UserName: "root", Value: encrypted})
+	assert.NoError(t, err)
+
+	jobSecret, err := prepareJobSecret("job-secret-test", conf)
+	assert.NoError(t, err)
+	assert.Equal(t, schema.JobSecretName("job-secret-test"), jobSecret.Name)
+	assert.Equal(t, "N1", jobSecret.Namespace)
+	assert.Equal(t, map[string][]byte{"HF_TOKEN": []byte("token-value")}, jobSecret.Data)
+	assert.Equal(t, jobSecret.Name, conf.Env[schema.EnvJobSecretName])
+	// the reference is kept in job config
+	assert.Equal(t, "${secret:hf_token}", conf.Env["HF_TOKEN"])
+
+	for _, env := range generateEnvVars(conf) {
+		switch env.Name {
+		case "HF_TOKEN":
+			assert.Equal(t, "", env.Value)
+			assert.Equal(t, jobSecret.Name, env.ValueFrom.SecretKeyRef.Name)
+			assert.Equal(t, "HF_TOKEN", env.ValueFrom.SecretKeyRef.Key)
+		case "PLAIN":
+			assert.Equal(t, "${secret:hf_token} plaintext", env.Value)
+			assert.Nil(t, env.ValueFrom)
+		}
+	}
+}
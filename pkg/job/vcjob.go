@@ -94,6 +94,12 @@ func (vcJob *VCJob) CreateJob(conf *models.Conf) (string, error) {
 	jobID := generateJobID(conf.Name)
 	log.Debugf("begin create job jobID:[%s]", jobID)
 
+	jobSecret, err := prepareJobSecret(jobID, conf)
+	if err != nil {
+		log.Errorf("prepare secret for job failed, err %v", err)
+		return "", err
+	}
+
 	jobApp := &vcjob.Job{}
 	if err := createJobFromYaml(conf, jobApp); err != nil {
 		log.Errorf("create job failed, err %v", err)
@@ -109,8 +115,8 @@ func (vcJob *VCJob) CreateJob(conf *models.Conf) (string, error) {
 		Config:   *conf,
 	}
 	log.Debugf("begin submit job jobID:[%s] job:[%s]", jobID, config.PrettyFormat(job))
-	err := persistAndExecuteJob(job, func() error {
-		return submitter.JobExecutor.StartJob(jobApp, k8s.VCJobGVK)
+	err = persistAndExecuteJob(job, func() error {
+		return startJobWithSecret(jobSecret, jobApp, k8s.VCJobGVK)
 	})
 	if err != nil {
 		log.Errorf("create job %v failed, err %v", job, err)
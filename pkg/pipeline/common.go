@@ -17,7 +17,10 @@ limitations under the License.
 package pipeline
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -42,6 +45,11 @@ const (
 	ParamTypeString = "string"
 	ParamTypeFloat  = "float"
 	ParamTypePath   = "path"
+	ParamTypeInt    = "int"
+	ParamTypeBool   = "bool"
+	ParamTypeEnum   = "enum"
+	ParamTypeList   = "list"
+	ParamTypeDict   = "dict"
 
 	WfParallelismDefault = 10
 	WfParallelismMaximum = 20
@@ -57,9 +65,17 @@ const (
 	CacheExpiredTimeNever     = "-1"
 )
 
+// DictParam is the typed form of step parameter, e.g. {"type": "float", "default": 0.01, "min": 0, "max": 1}
 type DictParam struct {
-	Type    string
-	Default interface{}
+	Type        string        `json:"type"`
+	Default     interface{}   `json:"default,omitempty"`
+	Optional    bool          `json:"optional,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Min         *float64      `json:"min,omitempty"`                               // lower bound of int and float
+	Max         *float64      `json:"max,omitempty"`                               // upper bound of int and float
+	Regex       string        `json:"regex,omitempty"`                             // pattern of string
+	Values      []interface{} `json:"values,omitempty"`                            // allowed values of enum
+	ItemType    string        `json:"itemType,omitempty" mapstructure:"item_type"` // type of items in list
 }
 
 func (p *DictParam) From(origin interface{}) error {
@@ -69,6 +85,112 @@ func (p *DictParam) From(origin interface{}) error {
 	return nil
 }
 
+func (p DictParam) String() string {
+	res := fmt.Sprintf("{Type:%s Default:%v", p.Type, p.Default)
+	if p.Optional {
+		res += " Optional:true"
+	}
+	if p.Min != nil {
+		res += fmt.Sprintf(" Min:%v", *p.Min)
+	}
+	if p.Max != nil {
+		res += fmt.Sprintf(" Max:%v", *p.Max)
+	}
+	if p.Regex != "" {
+		res += fmt.Sprintf(" Regex:%s", p.Regex)
+	}
+	if len(p.Values) > 0 {
+		res += fmt.Sprintf(" Values:%v", p.Values)
+	}
+	if p.ItemType != "" {
+		res += fmt.Sprintf(" ItemType:%s", p.ItemType)
+	}
+	return res + "}"
+}
+
+// GetParamSpecs returns the parameter schema of every step, with which a client can render a form.
+// Plain parameters are treated as dict params that only have a default value
+func GetParamSpecs(wfs schema.WorkflowSource) map[string]map[string]DictParam {
+	specs := make(map[string]map[string]DictParam, len(wfs.EntryPoints))
+	for stepName, step := range wfs.EntryPoints {
+		stepSpecs := make(map[string]DictParam, len(step.Parameters))
+		for paramName, paramVal := range step.Parameters {
+			dictParam := DictParam{}
+			if _, ok := paramVal.(map[interface{}]interface{}); ok && dictParam.From(paramVal) == nil {
+				dictParam.Default = NormalizeParamValue(dictParam.Default)
+				for i, value := range dictParam.Values {
+					dictParam.Values[i] = NormalizeParamValue(value)
+				}
+			} else {
+				dictParam.Type = inferParamType(paramVal)
+				dictParam.Default = NormalizeParamValue(paramVal)
+			}
+			stepSpecs[paramName] = dictParam
+		}
+		specs[stepName] = stepSpecs
+	}
+	return specs
+}
+
+func inferParamType(param interface{}) string {
+	switch param.(type) {
+	case int, int32, int64:
+		return ParamTypeInt
+	case float32, float64:
+		return ParamTypeFloat
+	case bool:
+		return ParamTypeBool
+	case []interface{}:
+		return ParamTypeList
+	case map[string]interface{}, map[interface{}]interface{}:
+		return ParamTypeDict
+	default:
+		return ParamTypeString
+	}
+}
+
+// NormalizeParamValue converts map[interface{}]interface{} decoded by yaml to map[string]interface{},
+// so that it can be marshaled to json
+func NormalizeParamValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[fmt.Sprintf("%v", key)] = NormalizeParamValue(val)
+		}
+		return res
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			res[key] = NormalizeParamValue(val)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(v))
+		for i, val := range v {
+			res[i] = NormalizeParamValue(val)
+		}
+		return res
+	default:
+		return v
+	}
+}
+
+// ParamValueToString renders list and dict params as json, and others in their default format.
+// The optional params left unset are rendered as empty
+func ParamValueToString(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return ""
+	case []interface{}, map[string]interface{}, map[interface{}]interface{}:
+		res, err := json.Marshal(NormalizeParamValue(value))
+		if err == nil {
+			return string(res)
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
+
 func InvalidParamTypeError(param interface{}, expected string) error {
 	return fmt.Errorf("invalid type[%T] for param[%+v]. expect type[%s]", param, param, expected)
 }
@@ -89,6 +211,18 @@ func MismatchRegexError(param, regex string) error {
 	return fmt.Errorf("param[%s] mismatches regex pattern[%s]", param, regex)
 }
 
+func OutOfRangeParamError(param interface{}, paramName string, dict DictParam) error {
+	return fmt.Errorf("value[%v] of param[%s] is out of range, min[%s] max[%s]",
+		param, paramName, boundToString(dict.Min), boundToString(dict.Max))
+}
+
+func boundToString(bound *float64) string {
+	if bound == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%v", *bound)
+}
+
 func StringsContain(items []string, item string) bool {
 	for _, eachItem := range items {
 		if eachItem == item {
@@ -175,7 +309,10 @@ func (s *StepParamSolver) checkName(step, fieldType, name string) error {
 
 func (s *StepParamSolver) checkParamValue(step string, paramName string, param interface{}, fieldType string) (interface{}, error) {
 	switch param.(type) {
-	case float32, float64, int:
+	case nil:
+		// the optional dict param without value is left unset
+		return nil, nil
+	case float32, float64, int, bool, []interface{}, map[string]interface{}:
 		return param, nil
 	case string:
 		return s.resolveRefParam(step, param.(string), fieldType)
@@ -230,7 +367,7 @@ func (s *StepParamSolver) resolveRefParam(step, param, fieldType string) (interf
 						}
 					}
 				} else {
-					tmpVal = fmt.Sprintf("%v", tmpVal2)
+					tmpVal = ParamValueToString(tmpVal2)
 				}
 			}
 		} else {
@@ -238,7 +375,7 @@ func (s *StepParamSolver) resolveRefParam(step, param, fieldType string) (interf
 			if err != nil {
 				return nil, err
 			}
-			tmpVal = fmt.Sprintf("%v", tmpVal2)
+			tmpVal = ParamValueToString(tmpVal2)
 		}
 		if s.needReplace {
 			result = strings.Replace(result, row[0], tmpVal, -1)
@@ -296,6 +433,9 @@ func checkDictParam(dict DictParam, paramName string, realVal interface{}) (inte
 	}
 	if realVal == nil || realVal == "" {
 		if dict.Default == nil || dict.Default == "" {
+			if dict.Optional {
+				return nil, nil
+			}
 			return nil, fmt.Errorf("invalid value[%v] in dict param[name: %s, value: %+v]", dict.Default, paramName, dict)
 		}
 		realVal = dict.Default
@@ -303,22 +443,78 @@ func checkDictParam(dict DictParam, paramName string, realVal interface{}) (inte
 
 	switch dict.Type {
 	case ParamTypeString:
-		_, ok := realVal.(string)
-		if ok {
-			return realVal, nil
-		} else {
+		realValStr, ok := realVal.(string)
+		if !ok {
 			return nil, InvalidParamTypeError(realVal, ParamTypeString)
 		}
+		if dict.Regex != "" {
+			matched, err := regexp.MatchString(dict.Regex, realValStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex[%s] in dict param[%s]: %v", dict.Regex, paramName, err)
+			}
+			if !matched {
+				return nil, MismatchRegexError(realValStr, dict.Regex)
+			}
+		}
+		return realVal, nil
 	case ParamTypeFloat:
-		_, ok1 := realVal.(float32)
-		_, ok2 := realVal.(float64)
-		_, ok3 := realVal.(int32)
-		_, ok4 := realVal.(int64)
-		_, ok5 := realVal.(int)
-		if ok1 || ok2 || ok3 || ok4 || ok5 {
-			return realVal, nil
-		}
-		return nil, InvalidParamTypeError(realVal, ParamTypeFloat)
+		floatVal, ok := toFloat(realVal)
+		if !ok {
+			return nil, InvalidParamTypeError(realVal, ParamTypeFloat)
+		}
+		if !inRange(floatVal, dict) {
+			return nil, OutOfRangeParamError(realVal, paramName, dict)
+		}
+		return realVal, nil
+	case ParamTypeInt:
+		floatVal, ok := toFloat(realVal)
+		if !ok || floatVal != math.Trunc(floatVal) {
+			return nil, InvalidParamTypeError(realVal, ParamTypeInt)
+		}
+		if !inRange(floatVal, dict) {
+			return nil, OutOfRangeParamError(realVal, paramName, dict)
+		}
+		// numbers in json request are decoded as float64
+		return int(floatVal), nil
+	case ParamTypeBool:
+		if _, ok := realVal.(bool); !ok {
+			return nil, InvalidParamTypeError(realVal, ParamTypeBool)
+		}
+		return realVal, nil
+	case ParamTypeEnum:
+		if len(dict.Values) == 0 {
+			return nil, fmt.Errorf("values of enum dict param[%s] should not be empty", paramName)
+		}
+		for _, value := range dict.Values {
+			if enumValueEqual(value, realVal) {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("value[%v] of param[%s] is not in enum values%v", realVal, paramName, dict.Values)
+	case ParamTypeList:
+		list, ok := realVal.([]interface{})
+		if !ok {
+			return nil, InvalidParamTypeError(realVal, ParamTypeList)
+		}
+		if dict.ItemType == "" {
+			return NormalizeParamValue(list), nil
+		}
+		res := make([]interface{}, len(list))
+		for i, item := range list {
+			realItem, err := checkDictParam(DictParam{Type: dict.ItemType}, fmt.Sprintf("%s[%d]", paramName, i), item)
+			if err != nil {
+				return nil, err
+			}
+			res[i] = realItem
+		}
+		return res, nil
+	case ParamTypeDict:
+		switch realVal.(type) {
+		case map[interface{}]interface{}, map[string]interface{}:
+			return NormalizeParamValue(realVal), nil
+		default:
+			return nil, InvalidParamTypeError(realVal, ParamTypeDict)
+		}
 	case ParamTypePath:
 		realValStr, ok := realVal.(string)
 		if !ok {
@@ -335,3 +531,40 @@ func checkDictParam(dict DictParam, paramName string, realVal interface{}) (inte
 		return nil, UnsupportedDictParamTypeError(dict.Type, paramName, dict)
 	}
 }
+
+// enumValueEqual compares the enum value with the typed value, numbers are compared by their values
+// as 1 in yaml and 1.0 in json are both allowed, but "1" does not match 1
+func enumValueEqual(value, realVal interface{}) bool {
+	if floatVal, ok := toFloat(value); ok {
+		realFloatVal, ok := toFloat(realVal)
+		return ok && floatVal == realFloatVal
+	}
+	return reflect.DeepEqual(NormalizeParamValue(value), NormalizeParamValue(realVal))
+}
+
+func toFloat(val interface{}) (float64, bool) {
+	switch v := val.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func inRange(val float64, dict DictParam) bool {
+	if dict.Min != nil && val < *dict.Min {
+		return false
+	}
+	if dict.Max != nil && val > *dict.Max {
+		return false
+	}
+	return true
+}
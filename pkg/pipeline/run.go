@@ -172,6 +172,7 @@ func (wfr *WorkflowRuntime) processEvent(event WorkflowEvent) error {
 		return nil
 	}
 	wfr.wf.log().Infof("process event: [%+v]", event)
+	wfr.logEvent(&event)
 
 	wfr.updateStatus()
 
@@ -290,6 +291,7 @@ func (wfr *WorkflowRuntime) callback(event WorkflowEvent) {
 	}
 
 	wfEvent := NewWorkflowEvent(WfEventRunUpdate, message, extra)
+	wfr.logEvent(wfEvent)
 	for i := 0; i < 3; i++ {
 		wfr.wf.log().Infof("callback event [%+v]", wfEvent)
 		if success := wfr.wf.callbacks.UpdateRunCb(wfr.wf.RunID, wfEvent); success {
@@ -298,3 +300,11 @@ func (wfr *WorkflowRuntime) callback(event WorkflowEvent) {
 	}
 	// todo: how to handle retry failed
 }
+
+// logEvent records event to the event log of run, so that it can be streamed to and replayed by clients
+func (wfr *WorkflowRuntime) logEvent(event *WorkflowEvent) {
+	if wfr.wf.callbacks.LogEventCb == nil {
+		return
+	}
+	wfr.wf.callbacks.LogEventCb(wfr.wf.RunID, event)
+}
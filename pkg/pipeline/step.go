@@ -102,7 +102,11 @@ func (st *Step) updateJob() error {
 
 	var params = make(map[string]string)
 	for paramName, paramValue := range st.info.Parameters {
-		params[paramName] = fmt.Sprintf("%v", paramValue)
+		// the optional params left unset are not passed to job
+		if paramValue == nil {
+			continue
+		}
+		params[paramName] = ParamValueToString(paramValue)
 	}
 
 	artifacts := schema.Artifacts{Input: map[string]string{}, Output: map[string]string{}}
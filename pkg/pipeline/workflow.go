@@ -205,31 +205,39 @@ func (bwf *BaseWorkflow) replaceRunParam(param string, val interface{}) error {
 			errMsg := fmt.Sprintf("param[%s] not exit in step[%s]", paramName, stepName)
 			return errors.New(errMsg)
 		}
-		dictParam := DictParam{}
-		if err := dictParam.From(orgVal); err == nil {
-			if _, err := checkDictParam(dictParam, paramName, val); err != nil {
-				return err
-			}
+		realVal, err := checkRunParam(orgVal, paramName, val)
+		if err != nil {
+			return err
 		}
-		step.Parameters[paramName] = val
+		step.Parameters[paramName] = realVal
 		return nil
 	}
 
 	for _, step := range bwf.Source.EntryPoints {
 		if orgVal, ok := step.Parameters[paramName]; ok {
-			dictParam := DictParam{}
-			if err := dictParam.From(orgVal); err == nil {
-				if _, err := checkDictParam(dictParam, paramName, val); err != nil {
-					return err
-				}
+			realVal, err := checkRunParam(orgVal, paramName, val)
+			if err != nil {
+				return err
 			}
-			step.Parameters[paramName] = val
+			step.Parameters[paramName] = realVal
 			return nil
 		}
 	}
 	return fmt.Errorf("param[%s] not exist", param)
 }
 
+// checkRunParam 若 yaml 中定义的是 dict param，按照其类型和约束校验命令行参数，并返回转换后的值
+func checkRunParam(orgVal interface{}, paramName string, val interface{}) (interface{}, error) {
+	if _, ok := orgVal.(map[interface{}]interface{}); !ok {
+		return val, nil
+	}
+	dictParam := DictParam{}
+	if err := dictParam.From(orgVal); err != nil {
+		return val, nil
+	}
+	return checkDictParam(dictParam, paramName, val)
+}
+
 // checkSteps check env, command, parameters and artifacts in every step
 func (bwf *BaseWorkflow) checkSteps() error {
 	var sysParamNameMap = map[string]string{
@@ -290,6 +298,7 @@ type WorkflowCallbacks struct {
 	LogCacheCb    func(req schema.LogRunCacheRequest) (string, error)
 	ListCacheCb   func(firstFp, fsID, step, yamlPath string) ([]models.RunCache, error)
 	LogArtifactCb func(req schema.LogRunArtifactRequest) error
+	LogEventCb    func(runID string, event *WorkflowEvent)
 }
 
 // 实例化一个Workflow，并返回
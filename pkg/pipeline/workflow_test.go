@@ -417,6 +417,129 @@ func TestValidateWorkflow__DictParam(t *testing.T) {
 	assert.Equal(t, UnsupportedDictParamTypeError("unsupportedType", "dict", dictParam).Error(), err.Error())
 }
 
+func TestValidateWorkflow__TypedDictParam(t *testing.T) {
+	testCase := loadcase("./testcase/run.yaml")
+	wfs := parseWorkflowSource(testCase)
+	bwf := NewBaseWorkflow(wfs, "", "", nil, nil)
+
+	// int
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "int", "default": 3, "min": 1, "max": 5}
+	err := bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "int", "default": 1.5}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, InvalidParamTypeError(1.5, "int").Error(), err.Error())
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "int", "default": 3, "min": 1, "max": 5}
+	bwf.Params = map[string]interface{}{"dict": float64(4)}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "int", "default": 3, "min": 1, "max": 5}
+	bwf.Params = map[string]interface{}{"dict": 6}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "value[6] of param[dict] is out of range, min[1] max[5]", err.Error())
+	bwf.Params = nil
+
+	// float passed as string
+	bwf.Source.EntryPoints["main"].Parameters["lr"] = map[interface{}]interface{}{"type": "float", "default": 0.01, "max": 1}
+	bwf.Params = map[string]interface{}{"lr": "0.1"}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, InvalidParamTypeError("0.1", "float").Error(), err.Error())
+	bwf.Params = nil
+	delete(bwf.Source.EntryPoints["main"].Parameters, "lr")
+
+	// bool
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "bool", "default": "true"}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, InvalidParamTypeError("true", "bool").Error(), err.Error())
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "bool", "default": true}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, true, bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	// enum
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "enum", "values": []interface{}{"adam", "sgd"}, "default": "rmsprop"}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, "value[rmsprop] of param[dict] is not in enum values[adam sgd]", err.Error())
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "enum", "values": []interface{}{"adam", "sgd"}, "default": "sgd"}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, "sgd", bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	// enum values are compared by type, numbers by value
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "enum", "values": []interface{}{1, 2}, "default": "1"}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "enum", "values": []interface{}{1, 2}, "default": 2.0}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	// list
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "list", "item_type": "int", "default": []interface{}{1, "2"}}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, InvalidParamTypeError("2", "int").Error(), err.Error())
+
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "list", "item_type": "int", "default": []interface{}{1, 2}}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1, 2}, bwf.Source.EntryPoints["main"].Parameters["dict"])
+	assert.Equal(t, "[1,2]", ParamValueToString(bwf.Source.EntryPoints["main"].Parameters["dict"]))
+
+	// dict
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "dict", "default": map[interface{}]interface{}{"layers": 2}}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"layers": 2}, bwf.Source.EntryPoints["main"].Parameters["dict"])
+
+	// string with regex
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "string", "regex": "^v[0-9]+$", "default": "latest"}
+	err = bwf.validate()
+	assert.NotNil(t, err)
+	assert.Equal(t, MismatchRegexError("latest", "^v[0-9]+$").Error(), err.Error())
+
+	// optional param without default value
+	bwf.Source.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "string", "optional": true, "description": "tag of model"}
+	err = bwf.validate()
+	assert.Nil(t, err)
+	assert.Nil(t, bwf.Source.EntryPoints["main"].Parameters["dict"])
+	assert.Equal(t, "", ParamValueToString(bwf.Source.EntryPoints["main"].Parameters["dict"]))
+}
+
+func TestGetParamSpecs(t *testing.T) {
+	testCase := loadcase("./testcase/run.yaml")
+	wfs := parseWorkflowSource(testCase)
+	wfs.EntryPoints["main"].Parameters["dict"] = map[interface{}]interface{}{"type": "float", "default": 0.1, "min": 0, "description": "learning rate"}
+
+	specs := GetParamSpecs(wfs)
+	assert.Equal(t, 3, len(specs))
+	spec := specs["main"]["dict"]
+	assert.Equal(t, ParamTypeFloat, spec.Type)
+	assert.Equal(t, 0.1, spec.Default)
+	assert.Equal(t, float64(0), *spec.Min)
+	assert.Nil(t, spec.Max)
+	assert.Equal(t, "learning rate", spec.Description)
+	assert.Equal(t, DictParam{Type: ParamTypeInt, Default: 100}, specs["main"]["iteration"])
+	assert.Equal(t, DictParam{Type: ParamTypeString, Default: "./data/model"}, specs["main"]["model"])
+
+	assert.Equal(t, ParamTypeDict, inferParamType(map[interface{}]interface{}{"layers": 2}))
+	assert.Equal(t, ParamTypeDict, inferParamType(map[string]interface{}{"layers": 2}))
+	assert.Equal(t, ParamTypeList, inferParamType([]interface{}{1}))
+}
+
 func TestValidateWorkflowArtifacts(t *testing.T) {
 	testCase := loadcase("./testcase/run.yaml")
 	wfs := parseWorkflowSource(testCase)